@@ -0,0 +1,252 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/grpcclient"
+	"github.com/dstdfx/bookish-spork/httpclient"
+	"github.com/dstdfx/bookish-spork/internal/pkg/bench"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultGRPCEndpoint = "127.0.0.1:63102"
+
+// Protocols of the bench command.
+const (
+	protocolHTTP = "http"
+	protocolGRPC = "grpc"
+)
+
+// httpTarget sends the benchmark requests to the public API.
+type httpTarget struct {
+	cli     *httpclient.Client
+	timeout time.Duration
+}
+
+func (t *httpTarget) Get(ctx context.Context, key string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	_, resp, err := t.cli.Get(ctx, key)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (t *httpTarget) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	_, err := t.cli.Set(ctx, httpclient.SetBody{Key: key, Value: value, TTL: int(ttl / time.Second)})
+
+	return err
+}
+
+// grpcTarget sends the benchmark requests to the gRPC API.
+type grpcTarget struct {
+	cli     *grpcclient.Client
+	timeout time.Duration
+}
+
+func (t *grpcTarget) Get(ctx context.Context, key string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	_, err := t.cli.Get(ctx, &grpcclient.GetRequest{Key: key})
+	if status.Code(err) == codes.NotFound {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (t *grpcTarget) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	v, err := grpcclient.NewJSONValue(value)
+	if err != nil {
+		return err
+	}
+	_, err = t.cli.Set(ctx, &grpcclient.SetRequest{Key: key, Value: v, Ttl: int64(ttl / time.Second)})
+
+	return err
+}
+
+// parseTTLRange parses TTL in seconds given as a single value or "min-max" range.
+func parseTTLRange(s string) (time.Duration, time.Duration, error) {
+	minTTL, maxTTL := s, s
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		minTTL, maxTTL = s[:i], s[i+1:]
+	}
+
+	lower, err := strconv.Atoi(minTTL)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid TTL %q, must be seconds or min-max range of seconds", s)
+	}
+	upper, err := strconv.Atoi(maxTTL)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid TTL %q, must be seconds or min-max range of seconds", s)
+	}
+
+	return time.Duration(lower) * time.Second, time.Duration(upper) * time.Second, nil
+}
+
+// newBenchCmd returns the command to load test the server.
+func newBenchCmd() *cobra.Command {
+	opts := &clientOpts{}
+	var (
+		protocol     string
+		grpcEndpoint string
+		ttl          string
+		bopts        bench.Opts
+	)
+
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Load test the server",
+		Long: "Send a mix of get and set requests to the server from concurrent workers and report " +
+			"throughput and latency percentiles of the successful requests. The benchmark stops after " +
+			"--requests requests or after --duration if it's set, interrupting it prints the report so far.",
+		Args: cobra.NoArgs,
+
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	fs := cmd.Flags()
+	opts.register(fs)
+	fs.StringVar(&protocol, "protocol", protocolHTTP, "protocol to send requests: http or grpc")
+	fs.StringVar(&grpcEndpoint, "grpc-endpoint", defaultGRPCEndpoint, "gRPC API endpoint")
+	fs.IntVarP(&bopts.Concurrency, "concurrency", "c", 50, "number of concurrent workers")
+	fs.IntVarP(&bopts.Requests, "requests", "n", 100000, "total number of requests")
+	fs.DurationVarP(&bopts.Duration, "duration", "d", 0, "duration of the benchmark, it overrides --requests")
+	fs.IntVar(&bopts.Keys, "keys", 10000, "number of keys")
+	fs.StringVar(&bopts.KeyPrefix, "key-prefix", "bench:", "prefix of the keys")
+	fs.StringVar(&bopts.KeyDistribution, "key-distribution", bench.KeyDistributionUniform,
+		"distribution of the requested keys: uniform or zipf")
+	fs.IntVar(&bopts.ValueSize, "value-size", 100, "size of the written values in bytes")
+	fs.Float64Var(&bopts.ReadRatio, "read-ratio", 0.9, "share of get requests from 0 to 1")
+	fs.StringVar(&ttl, "ttl", "0", "TTL of the written keys in seconds or min-max range of seconds "+
+		"to distribute TTL uniformly, keys never expire if it's 0")
+	fs.BoolVar(&bopts.Preload, "preload", true, "write all keys before the benchmark")
+	fs.Int64Var(&bopts.Seed, "seed", 0, "seed of the random generators, it's random if it's 0")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if opts.output != outputJSON && opts.output != outputTable {
+			return fmt.Errorf("unknown output format %q, must be one of: json, table", opts.output)
+		}
+		if bopts.Duration > 0 {
+			bopts.Requests = 0
+		}
+
+		var err error
+		if bopts.TTLMin, bopts.TTLMax, err = parseTTLRange(ttl); err != nil {
+			return err
+		}
+
+		switch protocol {
+		case protocolHTTP:
+			cli, err := opts.client()
+			if err != nil {
+				return err
+			}
+			// Every worker keeps its own connection alive
+			if t, ok := cli.HTTPClient.Transport.(*http.Transport); ok {
+				t.MaxIdleConnsPerHost = bopts.Concurrency
+				if t.MaxIdleConns < bopts.Concurrency {
+					t.MaxIdleConns = bopts.Concurrency
+				}
+			}
+			bopts.Target = &httpTarget{cli: cli, timeout: opts.timeout}
+		case protocolGRPC:
+			if opts.hmacKeyID != "" || opts.caFile != "" || opts.certFile != "" || opts.keyFile != "" {
+				return errors.New("only token authentication is supported by grpc protocol")
+			}
+			dialOpts := []grpc.DialOption{grpc.WithInsecure()}
+			if opts.token != "" {
+				dialOpts = append(dialOpts, grpcclient.WithToken(opts.token))
+			}
+			cli, err := grpcclient.NewClient(grpcEndpoint, dialOpts...)
+			if err != nil {
+				return err
+			}
+			defer cli.Close()
+			bopts.Target = &grpcTarget{cli: cli, timeout: opts.timeout}
+		default:
+			return fmt.Errorf("unknown protocol %q, must be one of: http, grpc", protocol)
+		}
+
+		// Interruption stops the benchmark and prints the report so far
+		ctx, cancel := context.WithCancel(cmd.Context())
+		defer cancel()
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		defer signal.Stop(interrupt)
+		go func() {
+			select {
+			case <-interrupt:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		report, err := bench.Run(ctx, bopts)
+		if report == nil {
+			return err
+		}
+		for _, op := range report.Ops[:len(report.Ops)-1] {
+			if op.Error != "" {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%d of %d %s requests failed, first error: %s\n",
+					op.Errors, op.Count, op.Name, op.Error)
+			}
+		}
+
+		res := &result{
+			json: report,
+			header: []string{"OP", "COUNT", "ERRORS", "MISSES", "THROUGHPUT",
+				"MIN", "MEAN", "P50", "P90", "P99", "P99.9", "MAX"},
+		}
+		for _, op := range report.Ops {
+			row := []string{
+				op.Name,
+				strconv.FormatUint(op.Count, 10),
+				strconv.FormatUint(op.Errors, 10),
+				strconv.FormatUint(op.Misses, 10),
+				strconv.FormatFloat(op.Throughput, 'f', 1, 64) + "/s",
+			}
+			for _, d := range []time.Duration{op.Latency.Min, op.Latency.Mean, op.Latency.P50,
+				op.Latency.P90, op.Latency.P99, op.Latency.P999, op.Latency.Max} {
+				row = append(row, d.Round(time.Microsecond).String())
+			}
+			res.rows = append(res.rows, row)
+		}
+		if err := res.write(cmd.OutOrStdout(), opts.output); err != nil {
+			return err
+		}
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+
+		return err
+	}
+
+	return cmd
+}
+
+func init() {
+	RootCmd.AddCommand(newBenchCmd())
+}
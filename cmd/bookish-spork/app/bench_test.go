@@ -0,0 +1,76 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/bench"
+	"github.com/dstdfx/bookish-spork/internal/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTTLRange(t *testing.T) {
+	minTTL, maxTTL, err := parseTTLRange("60")
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, minTTL)
+	require.Equal(t, time.Minute, maxTTL)
+
+	minTTL, maxTTL, err = parseTTLRange("10-60")
+	require.NoError(t, err)
+	require.Equal(t, 10*time.Second, minTTL)
+	require.Equal(t, time.Minute, maxTTL)
+
+	for _, s := range []string{"", "1.5", "10-", "a-b"} {
+		_, _, err = parseTTLRange(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestBench(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	e := startTestServer(t)
+
+	for _, protocol := range []string{"http", "grpc"} {
+		t.Run(protocol, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			cmd := newBenchCmd()
+			cmd.SetArgs([]string{"--protocol", protocol, "--endpoint", e.public, "--grpc-endpoint", e.grpc,
+				"-c", "4", "-n", "200", "--keys", "20", "--key-prefix", protocol + ":", "--ttl", "60-120"})
+			cmd.SetOut(&out)
+			cmd.SetErr(&errOut)
+			require.NoError(t, cmd.Execute())
+			require.Empty(t, errOut.String())
+
+			var report bench.Report
+			require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+			require.Len(t, report.Ops, 3)
+
+			total := report.Ops[2]
+			require.Equal(t, uint64(200), total.Count)
+			require.Zero(t, total.Errors)
+			require.Zero(t, total.Misses)
+			require.True(t, total.Latency.P50 > 0)
+
+			ttl, err := runClient(t, e, "", "ttl", protocol+":0", "-o", "raw")
+			require.NoError(t, err)
+			require.Regexp(t, `^(5\d|[6-9]\d|1[01]\d|120)\n$`, ttl)
+		})
+	}
+
+	// Table is printed and failed requests are reported
+	var out, errOut bytes.Buffer
+	cmd := newBenchCmd()
+	cmd.SetArgs([]string{"--endpoint", "http://127.0.0.1:1/v1", "-n", "10", "--preload=false", "-o", "table"})
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+	err := cmd.Execute()
+	require.Equal(t, bench.ErrAllRequestsFailed, err)
+	require.Contains(t, out.String(), "OP     COUNT  ERRORS")
+	require.Contains(t, errOut.String(), "requests failed, first error:")
+}
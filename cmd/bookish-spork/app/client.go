@@ -0,0 +1,619 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dstdfx/bookish-spork/httpclient"
+	"github.com/dstdfx/bookish-spork/internal/pkg/info"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	defaultEndpoint        = "http://127.0.0.1:63100/v1"
+	defaultServiceEndpoint = "http://127.0.0.1:63101"
+	defaultClientTimeout   = 10 * time.Second
+)
+
+// Output formats of the client commands.
+const (
+	outputJSON  = "json"
+	outputTable = "table"
+	outputRaw   = "raw"
+)
+
+// Exit codes of the commands.
+const (
+	exitCodeError    = 1
+	exitCodeNotFound = 2
+)
+
+// errNotFound is returned by the client commands if the key is not found.
+var errNotFound = errors.New("key not found")
+
+// ExitCode returns the exit code of the process for the error returned by a command.
+func ExitCode(err error) int {
+	if errors.Is(err, errNotFound) {
+		return exitCodeNotFound
+	}
+
+	return exitCodeError
+}
+
+// clientOpts contains the options of the client commands.
+type clientOpts struct {
+	endpoint        string
+	serviceEndpoint string
+	output          string
+	timeout         time.Duration
+
+	token      string
+	hmacKeyID  string
+	hmacSecret string
+
+	caFile   string
+	certFile string
+	keyFile  string
+}
+
+// register registers the flags of the client options.
+func (o *clientOpts) register(fs *pflag.FlagSet) {
+	fs.StringVar(&o.endpoint, "endpoint", defaultEndpoint, "public API endpoint")
+	fs.StringVarP(&o.output, "output", "o", outputJSON, "output format: json, table or raw")
+	fs.DurationVar(&o.timeout, "timeout", defaultClientTimeout, "request timeout")
+	fs.StringVar(&o.token, "token", "", "bearer token to authenticate requests")
+	fs.StringVar(&o.hmacKeyID, "hmac-key-id", "", "HMAC key ID to sign requests")
+	fs.StringVar(&o.hmacSecret, "hmac-secret", "", "HMAC secret to sign requests")
+	fs.StringVar(&o.caFile, "ca-file", "", "path to PEM file with CA certificates to verify the server")
+	fs.StringVar(&o.certFile, "cert-file", "", "path to PEM file with client certificate")
+	fs.StringVar(&o.keyFile, "key-file", "", "path to PEM file with client private key")
+}
+
+// client returns the public API client configured by the options.
+func (o *clientOpts) client() (*httpclient.Client, error) {
+	cli := httpclient.NewClient(o.endpoint)
+	if o.caFile != "" || o.certFile != "" || o.keyFile != "" {
+		tlsConfig, err := httpclient.LoadTLSConfig(o.certFile, o.keyFile, o.caFile)
+		if err != nil {
+			return nil, err
+		}
+		cli = httpclient.NewClientTLS(tlsConfig, o.endpoint)
+	}
+
+	switch {
+	case o.token != "":
+		cli.Credentials = httpclient.BearerToken(o.token)
+	case o.hmacKeyID != "":
+		cli.Credentials = httpclient.HMACKey{KeyID: o.hmacKeyID, Secret: o.hmacSecret}
+	}
+
+	return cli, nil
+}
+
+// result is the output of a client command in all formats.
+type result struct {
+	// json is marshaled as is.
+	json interface{}
+
+	// raw is written as is.
+	raw []byte
+
+	// header and rows form the table.
+	header []string
+	rows   [][]string
+}
+
+// write writes the result in the given format.
+func (r *result) write(w io.Writer, format string) error {
+	switch format {
+	case outputJSON:
+		data, err := json.MarshalIndent(r.json, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+
+		return err
+	case outputRaw:
+		_, err := w.Write(r.raw)
+
+		return err
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, strings.Join(r.header, "\t"))
+		for _, row := range r.rows {
+			_, _ = fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+
+		return tw.Flush()
+	}
+}
+
+// clientCmdRun runs the client command, it returns the result to print
+// or nil if there is nothing to print.
+type clientCmdRun func(ctx context.Context, cmd *cobra.Command, cli *httpclient.Client, args []string) (*result, error)
+
+// newClientCmd sets up the common flags of the client command and
+// its function to run the command and print the result.
+func newClientCmd(cmd *cobra.Command, opts *clientOpts, run clientCmdRun) *cobra.Command {
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	opts.register(cmd.Flags())
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		switch opts.output {
+		case outputJSON, outputTable, outputRaw:
+		default:
+			return fmt.Errorf("unknown output format %q, must be one of: json, table, raw", opts.output)
+		}
+
+		cli, err := opts.client()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), opts.timeout)
+		defer cancel()
+
+		res, err := run(ctx, cmd, cli, args)
+		if err != nil {
+			return err
+		}
+		if res == nil {
+			return nil
+		}
+
+		return res.write(cmd.OutOrStdout(), opts.output)
+	}
+
+	return cmd
+}
+
+// checkNotFound returns errNotFound if the server responded with 404 status code.
+func checkNotFound(resp *httpclient.ResponseResult, err error) error {
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+
+	return err
+}
+
+// valueOpts contains the options of the commands that write values.
+type valueOpts struct {
+	file        string
+	json        bool
+	contentType string
+	ttl         int
+}
+
+// register registers the flags of the value options.
+func (o *valueOpts) register(cmd *cobra.Command, raw bool) {
+	fs := cmd.Flags()
+	fs.StringVarP(&o.file, "file", "f", "", "read the value from the file")
+	fs.BoolVar(&o.json, "json", false, "parse the value as JSON, otherwise it's stored as a string")
+	fs.IntVar(&o.ttl, "ttl", 0, "TTL in seconds, the key will never be expired if it's 0")
+	if raw {
+		fs.StringVar(&o.contentType, "content-type", "", "store the value as raw bytes with the content type")
+	}
+}
+
+// read returns the value given as argument, read from the file or stdin if
+// the argument is omitted or equal to "-".
+func (o *valueOpts) read(cmd *cobra.Command, args []string, n int) ([]byte, error) {
+	switch {
+	case o.file != "" && len(args) > n:
+		return nil, errors.New("the value and --file flag are mutually exclusive")
+	case o.file != "":
+		return ioutil.ReadFile(o.file)
+	case len(args) > n && args[n] != "-":
+		return []byte(args[n]), nil
+	default:
+		return ioutil.ReadAll(cmd.InOrStdin())
+	}
+}
+
+// value returns the value to write.
+func (o *valueOpts) value(cmd *cobra.Command, args []string, n int) (interface{}, error) {
+	data, err := o.read(cmd, args, n)
+	if err != nil {
+		return nil, err
+	}
+	if !o.json {
+		return string(data), nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("value is not valid JSON: %w", err)
+	}
+
+	return v, nil
+}
+
+// newGetCmd returns the command to get the value by key.
+func newGetCmd() *cobra.Command {
+	opts := &clientOpts{}
+
+	return newClientCmd(&cobra.Command{
+		Use:   "get <key>",
+		Short: "Get the value by key",
+		Args:  cobra.ExactArgs(1),
+	}, opts, func(ctx context.Context, cmd *cobra.Command, cli *httpclient.Client, args []string) (*result, error) {
+		key := args[0]
+		data, contentType, resp, err := cli.GetBytes(ctx, key)
+		if err != nil {
+			return nil, checkNotFound(resp, err)
+		}
+
+		// Raw values are written as is, JSON strings are written unquoted
+		if !strings.HasPrefix(contentType, "application/json") {
+			return &result{
+				json:   map[string]interface{}{"key": key, "value": data, "content_type": contentType},
+				raw:    data,
+				header: []string{"KEY", "VALUE", "CONTENT TYPE"},
+				rows:   [][]string{{key, text(data), contentType}},
+			}, nil
+		}
+
+		var v interface{}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		res := &result{
+			json:   map[string]interface{}{"key": key, "value": v},
+			raw:    []byte(text(v) + "\n"),
+			header: []string{"KEY", "VALUE"},
+			rows:   [][]string{{key, text(v)}},
+		}
+
+		return res, nil
+	})
+}
+
+// newSetCmd returns the command to set the value by key.
+func newSetCmd() *cobra.Command {
+	opts, vopts := &clientOpts{}, &valueOpts{}
+
+	cmd := newClientCmd(&cobra.Command{
+		Use:   "set <key> [value|-]",
+		Short: "Set the value by key",
+		Long: "Set the value by key. The value is read from the file given by --file flag or from stdin " +
+			"if it's omitted or equal to \"-\". It's stored as a string unless --json or --content-type flag is set.",
+		Args: cobra.RangeArgs(1, 2),
+	}, opts, func(ctx context.Context, cmd *cobra.Command, cli *httpclient.Client, args []string) (*result, error) {
+		if vopts.contentType != "" {
+			data, err := vopts.read(cmd, args, 1)
+			if err != nil {
+				return nil, err
+			}
+			_, err = cli.SetBytes(ctx, args[0], data, httpclient.SetBytesOpts{
+				ContentType: vopts.contentType,
+				TTL:         vopts.ttl,
+			})
+
+			return nil, err
+		}
+
+		v, err := vopts.value(cmd, args, 1)
+		if err != nil {
+			return nil, err
+		}
+		_, err = cli.Set(ctx, httpclient.SetBody{Key: args[0], Value: v, TTL: vopts.ttl})
+
+		return nil, err
+	})
+	vopts.register(cmd, true)
+
+	return cmd
+}
+
+// newDelCmd returns the command to remove the key.
+func newDelCmd() *cobra.Command {
+	opts := &clientOpts{}
+
+	return newClientCmd(&cobra.Command{
+		Use:   "del <key>",
+		Short: "Remove the key",
+		Args:  cobra.ExactArgs(1),
+	}, opts, func(ctx context.Context, cmd *cobra.Command, cli *httpclient.Client, args []string) (*result, error) {
+		_, err := cli.Remove(ctx, args[0])
+
+		return nil, err
+	})
+}
+
+// newKeysCmd returns the command to list the keys.
+func newKeysCmd() *cobra.Command {
+	opts := &clientOpts{}
+
+	return newClientCmd(&cobra.Command{
+		Use:   "keys [pattern]",
+		Short: "List the keys",
+		Long:  "List the keys matching the glob pattern in lexical order, all keys are listed if it's omitted.",
+		Args:  cobra.MaximumNArgs(1),
+	}, opts, func(ctx context.Context, cmd *cobra.Command, cli *httpclient.Client, args []string) (*result, error) {
+		pattern := "*"
+		if len(args) > 0 {
+			pattern = args[0]
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+
+		all, _, err := cli.Keys(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		keys := make([]string, 0, len(all))
+		for _, k := range all {
+			if ok, _ := path.Match(pattern, k); ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		res := &result{json: keys, header: []string{"KEY"}}
+		for _, k := range keys {
+			res.raw = append(res.raw, k+"\n"...)
+			res.rows = append(res.rows, []string{k})
+		}
+
+		return res, nil
+	})
+}
+
+// newRPushCmd returns the command to push the value to the list.
+func newRPushCmd() *cobra.Command {
+	opts, vopts := &clientOpts{}, &valueOpts{}
+
+	cmd := newClientCmd(&cobra.Command{
+		Use:   "rpush <key> [value|-]",
+		Short: "Add the value to the list or create a new one",
+		Long:  "Add the value to the list or create a new one. The value is read the same way as by set command.",
+		Args:  cobra.RangeArgs(1, 2),
+	}, opts, func(ctx context.Context, cmd *cobra.Command, cli *httpclient.Client, args []string) (*result, error) {
+		v, err := vopts.value(cmd, args, 1)
+		if err != nil {
+			return nil, err
+		}
+		_, err = cli.RPush(ctx, httpclient.RPushBody{Key: args[0], Value: v, TTL: vopts.ttl})
+
+		return nil, err
+	})
+	vopts.register(cmd, false)
+
+	return cmd
+}
+
+// newLIndexCmd returns the command to get the list value by index.
+func newLIndexCmd() *cobra.Command {
+	opts := &clientOpts{}
+
+	return newClientCmd(&cobra.Command{
+		Use:   "lindex <key> <index>",
+		Short: "Get the list value by index",
+		Args:  cobra.ExactArgs(2),
+	}, opts, func(ctx context.Context, cmd *cobra.Command, cli *httpclient.Client, args []string) (*result, error) {
+		index, err := strconv.Atoi(args[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid index %q", args[1])
+		}
+
+		v, resp, err := cli.LIndex(ctx, args[0], index)
+		if err != nil {
+			return nil, checkNotFound(resp, err)
+		}
+
+		return &result{
+			json:   map[string]interface{}{"key": args[0], "index": index, "value": v},
+			raw:    []byte(text(v) + "\n"),
+			header: []string{"KEY", "INDEX", "VALUE"},
+			rows:   [][]string{{args[0], args[1], text(v)}},
+		}, nil
+	})
+}
+
+// newHSetCmd returns the command to set the hash map field.
+func newHSetCmd() *cobra.Command {
+	opts, vopts := &clientOpts{}, &valueOpts{}
+
+	cmd := newClientCmd(&cobra.Command{
+		Use:   "hset <key> <field> [value|-]",
+		Short: "Set the hash map field or create a new one",
+		Long:  "Set the hash map field or create a new one. The value is read the same way as by set command.",
+		Args:  cobra.RangeArgs(2, 3),
+	}, opts, func(ctx context.Context, cmd *cobra.Command, cli *httpclient.Client, args []string) (*result, error) {
+		v, err := vopts.value(cmd, args, 2)
+		if err != nil {
+			return nil, err
+		}
+		_, err = cli.HSet(ctx, httpclient.HSetBody{
+			Key:   args[0],
+			Value: map[string]interface{}{args[1]: v},
+			TTL:   vopts.ttl,
+		})
+
+		return nil, err
+	})
+	vopts.register(cmd, false)
+
+	return cmd
+}
+
+// newHGetCmd returns the command to get the hash map field.
+func newHGetCmd() *cobra.Command {
+	opts := &clientOpts{}
+
+	return newClientCmd(&cobra.Command{
+		Use:   "hget <key> <field>",
+		Short: "Get the hash map field",
+		Args:  cobra.ExactArgs(2),
+	}, opts, func(ctx context.Context, cmd *cobra.Command, cli *httpclient.Client, args []string) (*result, error) {
+		v, resp, err := cli.HGet(ctx, args[0], args[1])
+		if err != nil {
+			return nil, checkNotFound(resp, err)
+		}
+
+		return &result{
+			json:   map[string]interface{}{"key": args[0], "field": args[1], "value": v},
+			raw:    []byte(text(v) + "\n"),
+			header: []string{"KEY", "FIELD", "VALUE"},
+			rows:   [][]string{{args[0], args[1], text(v)}},
+		}, nil
+	})
+}
+
+// newTTLCmd returns the command to get the remaining time to live of the key.
+func newTTLCmd() *cobra.Command {
+	opts := &clientOpts{}
+
+	return newClientCmd(&cobra.Command{
+		Use:   "ttl <key>",
+		Short: "Get the remaining time to live of the key",
+		Long:  "Get the remaining time to live of the key in seconds, -1 if the key will never be expired.",
+		Args:  cobra.ExactArgs(1),
+	}, opts, func(ctx context.Context, cmd *cobra.Command, cli *httpclient.Client, args []string) (*result, error) {
+		ttl, resp, err := cli.TTL(ctx, args[0])
+		if err != nil {
+			return nil, checkNotFound(resp, err)
+		}
+
+		return &result{
+			json:   map[string]interface{}{"key": args[0], "ttl": ttl},
+			raw:    []byte(strconv.Itoa(ttl) + "\n"),
+			header: []string{"KEY", "TTL"},
+			rows:   [][]string{{args[0], strconv.Itoa(ttl)}},
+		}, nil
+	})
+}
+
+// newInfoCmd returns the command to get the info report from the service API.
+func newInfoCmd() *cobra.Command {
+	opts := &clientOpts{}
+
+	cmd := newClientCmd(&cobra.Command{
+		Use:   "info [section]",
+		Short: "Get the report of cache internals",
+		Long:  "Get the report of cache internals from the service API, the report is limited to the section if it's given.",
+		Args:  cobra.MaximumNArgs(1),
+	}, opts, func(ctx context.Context, cmd *cobra.Command, cli *httpclient.Client, args []string) (*result, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+			strings.TrimSuffix(opts.serviceEndpoint, "/")+info.Path, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := cli.HTTPClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("got the %d status code from the server: %s", resp.StatusCode, body)
+		}
+
+		var report map[string]interface{}
+		if err := json.Unmarshal(body, &report); err != nil {
+			return nil, err
+		}
+
+		var v interface{} = report
+		prefix := ""
+		if len(args) > 0 {
+			section, ok := report[args[0]]
+			if !ok {
+				return nil, fmt.Errorf("unknown section %q", args[0])
+			}
+			v, prefix = section, args[0]
+		}
+
+		res := &result{json: v, header: []string{"KEY", "VALUE"}}
+		flatten(prefix, v, func(key string, value interface{}) {
+			res.raw = append(res.raw, key+":"+text(value)+"\n"...)
+			res.rows = append(res.rows, []string{key, text(value)})
+		})
+
+		return res, nil
+	})
+	cmd.Flags().StringVar(&opts.serviceEndpoint, "service-endpoint", defaultServiceEndpoint, "service API endpoint")
+
+	return cmd
+}
+
+// flatten calls fn for all leaf values of JSON objects with dot-separated keys
+// sorted in lexical order.
+func flatten(prefix string, v interface{}, fn func(key string, value interface{})) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		fn(prefix, v)
+
+		return
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if prefix != "" {
+			flatten(prefix+"."+k, obj[k], fn)
+		} else {
+			flatten(k, obj[k], fn)
+		}
+	}
+}
+
+// text returns the value as text: strings are returned as is, raw bytes are
+// returned as is if they are valid UTF-8, other values are returned as JSON.
+func text(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		if utf8.Valid(v) {
+			return string(v)
+		}
+
+		return fmt.Sprintf("(%d bytes)", len(v))
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+
+		return string(data)
+	}
+}
+
+// clientCommands returns new instances of the client commands.
+func clientCommands() []*cobra.Command {
+	return []*cobra.Command{
+		newGetCmd(), newSetCmd(), newDelCmd(), newKeysCmd(), newRPushCmd(),
+		newLIndexCmd(), newHSetCmd(), newHGetCmd(), newTTLCmd(), newInfoCmd(),
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(clientCommands()...)
+}
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/testutils"
+	"github.com/dstdfx/bookish-spork/server"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+// testEndpoints contains the endpoints of the server running on ephemeral ports.
+type testEndpoints struct {
+	public  string
+	service string
+	grpc    string
+}
+
+func startTestServer(t *testing.T) testEndpoints {
+	srv, err := server.New(server.DefaultConfig(), server.Opts{})
+	require.NoError(t, err)
+
+	var l server.Listeners
+	for _, p := range []*net.Listener{&l.PublicAPI, &l.ServiceAPI, &l.GRPCAPI} {
+		*p, err = net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(ctx, l)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-served)
+	})
+	<-srv.Ready()
+
+	return testEndpoints{
+		public:  "http://" + l.PublicAPI.Addr().String() + "/v1",
+		service: "http://" + l.ServiceAPI.Addr().String(),
+		grpc:    l.GRPCAPI.Addr().String(),
+	}
+}
+
+// runClient runs the client command with fresh flags and returns its output.
+func runClient(t *testing.T, e testEndpoints, stdin string, args ...string) (string, error) {
+	root := &cobra.Command{Use: "bookish-spork"}
+	root.AddCommand(clientCommands()...)
+
+	args = append(args, "--endpoint", e.public)
+	if args[0] == "info" {
+		args = append(args, "--service-endpoint", e.service)
+	}
+
+	var out bytes.Buffer
+	root.SetArgs(args)
+	root.SetOut(&out)
+	root.SetIn(strings.NewReader(stdin))
+	err := root.Execute()
+
+	return out.String(), err
+}
+
+func TestClientCommands(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	e := startTestServer(t)
+
+	file := filepath.Join(t.TempDir(), "value.json")
+	require.NoError(t, ioutil.WriteFile(file, []byte(`{"a": [1, 2]}`), 0600))
+
+	tests := []struct {
+		name     string
+		stdin    string
+		args     []string
+		expected string
+		exitCode int
+	}{
+		{name: "set", args: []string{"set", "str", "some value", "--ttl", "100"}},
+		{name: "set from stdin", stdin: "from stdin", args: []string{"set", "stdin"}},
+		{name: "set JSON from file", args: []string{"set", "json", "--json", "--file", file}},
+		{name: "set raw", stdin: "\x00\xff", args: []string{"set", "raw", "-", "--content-type", "application/octet-stream"}},
+		{name: "set invalid JSON", args: []string{"set", "str", "{", "--json"}, exitCode: exitCodeError},
+		{name: "get", args: []string{"get", "str"}, expected: "{\n  \"key\": \"str\",\n  \"value\": \"some value\"\n}\n"},
+		{name: "get raw", args: []string{"get", "stdin", "-o", "raw"}, expected: "from stdin\n"},
+		{name: "get raw JSON", args: []string{"get", "json", "-o", "raw"}, expected: `{"a":[1,2]}` + "\n"},
+		{name: "get raw bytes", args: []string{"get", "raw", "-o", "raw"}, expected: "\x00\xff"},
+		{name: "get table", args: []string{"get", "str", "-o", "table"}, expected: "KEY  VALUE\nstr  some value\n"},
+		{name: "get not found", args: []string{"get", "missing"}, exitCode: exitCodeNotFound},
+		{name: "get unknown format", args: []string{"get", "str", "-o", "xml"}, exitCode: exitCodeError},
+		{name: "rpush", args: []string{"rpush", "list", "1", "--json"}},
+		{name: "lindex", args: []string{"lindex", "list", "0", "-o", "raw"}, expected: "1\n"},
+		{name: "lindex not found", args: []string{"lindex", "missing", "0"}, exitCode: exitCodeNotFound},
+		{name: "hset", args: []string{"hset", "hash", "field", "value"}},
+		{name: "hget", args: []string{"hget", "hash", "field", "-o", "raw"}, expected: "value\n"},
+		{name: "hget not found", args: []string{"hget", "missing", "field"}, exitCode: exitCodeNotFound},
+		{name: "ttl", args: []string{"ttl", "str", "-o", "raw"}, expected: "100\n"},
+		{name: "ttl persistent", args: []string{"ttl", "json", "-o", "raw"}, expected: "-1\n"},
+		{name: "ttl not found", args: []string{"ttl", "missing"}, exitCode: exitCodeNotFound},
+		{name: "del", args: []string{"del", "stdin"}},
+		{name: "keys", args: []string{"keys", "-o", "raw"}, expected: "hash\njson\nlist\nraw\nstr\n"},
+		{name: "keys pattern", args: []string{"keys", "[hj]*", "-o", "table"}, expected: "KEY\nhash\njson\n"},
+		{name: "info", args: []string{"info", "keyspace", "-o", "raw"}},
+		{name: "info unknown section", args: []string{"info", "unknown"}, exitCode: exitCodeError},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := runClient(t, e, tc.stdin, tc.args...)
+			if tc.exitCode != 0 {
+				require.Error(t, err)
+				require.Equal(t, tc.exitCode, ExitCode(err))
+
+				return
+			}
+			require.NoError(t, err)
+			if tc.args[0] == "info" {
+				// Average TTL is decreasing, so it's not compared
+				require.True(t, strings.HasPrefix(out, "keyspace.avg_ttl_seconds:"))
+				require.Contains(t, out, "\nkeyspace.keys:5\n")
+
+				return
+			}
+			require.Equal(t, tc.expected, out)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// configCmd groups the commands to inspect application config.
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect application config",
+}
+
+// configPrintCmd prints the effective config.
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Print the effective config merged from defaults, config file, environment variables and flags",
+	Args:  cobra.NoArgs,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		src, err := configSources(cmd)
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Load(src)
+		if err != nil {
+			return err
+		}
+		data, err := config.Marshal(cfg)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprint(cmd.OutOrStdout(), string(data))
+
+		return err
+	},
+}
+
+// configValidateCmd validates the effective config.
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the effective config merged from defaults, config file, environment variables and flags",
+	Args:  cobra.NoArgs,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		src, err := configSources(cmd)
+		if err != nil {
+			return err
+		}
+		if _, err := config.Load(src); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), "config is valid")
+
+		return err
+	},
+}
+
+func init() {
+	registerConfigFlags(configCmd.PersistentFlags())
+	configCmd.AddCommand(configPrintCmd, configValidateCmd)
+	RootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,246 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/httpclient"
+	"github.com/dstdfx/bookish-spork/internal/pkg/dump"
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// serviceOpts contains the options of the commands that stream data to or from the service API.
+type serviceOpts struct {
+	endpoint string
+	timeout  time.Duration
+	token    string
+
+	caFile   string
+	certFile string
+	keyFile  string
+}
+
+// register registers the flags of the service options.
+func (o *serviceOpts) register(fs *pflag.FlagSet) {
+	fs.StringVar(&o.endpoint, "service-endpoint", defaultServiceEndpoint, "service API endpoint")
+	fs.DurationVar(&o.timeout, "timeout", 0, "timeout of the whole transfer, it's not limited if it's 0")
+	fs.StringVar(&o.token, "token", "", "bearer token of the service API")
+	fs.StringVar(&o.caFile, "ca-file", "", "path to PEM file with CA certificates to verify the server")
+	fs.StringVar(&o.certFile, "cert-file", "", "path to PEM file with client certificate")
+	fs.StringVar(&o.keyFile, "key-file", "", "path to PEM file with client private key")
+}
+
+// do sends the request to the service API and checks the response status code.
+func (o *serviceOpts) do(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Response, error) {
+	cli := &http.Client{}
+	if o.caFile != "" || o.certFile != "" || o.keyFile != "" {
+		tlsConfig, err := httpclient.LoadTLSConfig(o.certFile, o.keyFile, o.caFile)
+		if err != nil {
+			return nil, err
+		}
+		cli.Transport = &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsConfig}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method,
+		strings.TrimSuffix(o.endpoint, "/")+path+"?"+query.Encode(), body)
+	if err != nil {
+		return nil, err
+	}
+	if o.token != "" {
+		req.Header.Set("Authorization", "Bearer "+o.token)
+	}
+	resp, err := cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := ioutil.ReadAll(resp.Body)
+
+		return nil, fmt.Errorf("got the %d status code from the server: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	return resp, nil
+}
+
+// withTimeout returns the context of the command limited by the timeout.
+func (o *serviceOpts) withTimeout(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	if o.timeout > 0 {
+		return context.WithTimeout(cmd.Context(), o.timeout)
+	}
+
+	return context.WithCancel(cmd.Context())
+}
+
+// newDumpCmd returns the command to dump all keys to the file.
+func newDumpCmd() *cobra.Command {
+	opts := &serviceOpts{}
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "dump [file]",
+		Short: "Dump all keys to the file",
+		Long: "Stream all keys with their types and remaining TTL from the service API to the file, " +
+			"the dump is written to stdout if the file is omitted or equal to \"-\".",
+		Args: cobra.MaximumNArgs(1),
+
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	fs := cmd.Flags()
+	opts.register(fs)
+	fs.StringVar(&format, "format", dump.FormatJSONL, "dump format: jsonl or binary")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if format != dump.FormatJSONL && format != dump.FormatBinary {
+			return dump.ErrUnknownFormat
+		}
+
+		ctx, cancel := opts.withTimeout(cmd)
+		defer cancel()
+
+		resp, err := opts.do(ctx, http.MethodGet, dump.Path, url.Values{"format": {format}}, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		n := 0
+		write := func(w io.Writer) error {
+			// The dump is decoded to make sure it's complete
+			r, err := dump.NewReader(resp.Body)
+			if err != nil {
+				return err
+			}
+			dw, err := dump.NewWriter(w, format)
+			if err != nil {
+				return err
+			}
+			for {
+				e, err := r.Read()
+				if err == io.EOF {
+					return dw.Close()
+				}
+				if err != nil {
+					return err
+				}
+				if err := dw.Write(e); err != nil {
+					return err
+				}
+				n++
+			}
+		}
+
+		if len(args) == 0 || args[0] == "-" {
+			err = write(cmd.OutOrStdout())
+		} else {
+			err = writeFile(args[0], write)
+		}
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "dumped %d keys\n", n)
+
+		return nil
+	}
+
+	return cmd
+}
+
+// newRestoreCmd returns the command to restore the keys from the dump file.
+func newRestoreCmd() *cobra.Command {
+	opts := &serviceOpts{}
+	var mode string
+
+	cmd := &cobra.Command{
+		Use:   "restore [file]",
+		Short: "Restore the keys from the dump file",
+		Long: "Load the dump into the cache through the service API, the format of the dump is detected. " +
+			"The dump is read from stdin if the file is omitted or equal to \"-\". Existing keys are skipped, " +
+			"replaced or merged according to --mode flag, merge appends the items to the lists, sets the fields " +
+			"of the hashes and adds the members to the geo indexes, other keys are replaced.",
+		Args: cobra.MaximumNArgs(1),
+
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	fs := cmd.Flags()
+	opts.register(fs)
+	fs.StringVar(&mode, "mode", qqcache.RestoreSkip, "mode of restoring existing keys: skip, replace or merge")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		switch mode {
+		case qqcache.RestoreSkip, qqcache.RestoreReplace, qqcache.RestoreMerge:
+		default:
+			return qqcache.ErrInvalidRestoreMode
+		}
+
+		in := cmd.InOrStdin()
+		if len(args) > 0 && args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+
+		ctx, cancel := opts.withTimeout(cmd)
+		defer cancel()
+
+		resp, err := opts.do(ctx, http.MethodPost, dump.RestorePath, url.Values{"mode": {mode}}, in)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		var result dump.RestoreResponse
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "restored %d keys, skipped %d keys\n", result.Restored, result.Skipped)
+
+		return nil
+	}
+
+	return cmd
+}
+
+// writeFile writes the file with write function. The data is written to a temporary
+// file first, it's renamed to the file on success, so the file is not left incomplete.
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := write(f); err != nil {
+		_ = f.Close()
+
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+func init() {
+	RootCmd.AddCommand(newDumpCmd(), newRestoreCmd())
+}
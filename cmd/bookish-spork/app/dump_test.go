@@ -0,0 +1,122 @@
+package app
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/testutils"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+// runService runs the command of the service API and returns its output and errors output.
+func runService(t *testing.T, e testEndpoints, cmd *cobra.Command, args ...string) (string, string, error) {
+	var out, errOut bytes.Buffer
+	cmd.SetArgs(append(args, "--service-endpoint", e.service))
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+	err := cmd.Execute()
+
+	return out.String(), errOut.String(), err
+}
+
+func TestDumpRestore(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	src, dst := startTestServer(t), startTestServer(t)
+	for _, args := range [][]string{
+		{"set", "value", "some-value", "--ttl", "60"},
+		{"set", "json", "--json", `{"a": [1, 2.5]}`},
+		{"rpush", "list", "item"},
+		{"hset", "hash", "field", "value"},
+	} {
+		_, err := runClient(t, src, "", args...)
+		require.NoError(t, err)
+	}
+
+	for _, format := range []string{"jsonl", "binary"} {
+		t.Run(format, func(t *testing.T) {
+			for _, key := range []string{"json", "list", "hash"} {
+				_, _ = runClient(t, dst, "", "del", key)
+			}
+			_, err := runClient(t, dst, "", "set", "value", "old")
+			require.NoError(t, err)
+
+			file := filepath.Join(t.TempDir(), "dump")
+			_, errOut, err := runService(t, src, newDumpCmd(), file, "--format", format)
+			require.NoError(t, err)
+			require.Equal(t, "dumped 4 keys\n", errOut)
+
+			out, _, err := runService(t, dst, newRestoreCmd(), file)
+			require.NoError(t, err)
+			require.Equal(t, "restored 3 keys, skipped 1 keys\n", out)
+
+			out, err = runClient(t, dst, "", "get", "value", "-o", "raw")
+			require.NoError(t, err)
+			require.Equal(t, "old\n", out)
+
+			out, _, err = runService(t, dst, newRestoreCmd(), file, "--mode", "replace")
+			require.NoError(t, err)
+			require.Equal(t, "restored 4 keys, skipped 0 keys\n", out)
+
+			for _, key := range []string{"value", "json", "list", "hash"} {
+				expected, err := runClient(t, src, "", "get", key, "-o", "raw")
+				require.NoError(t, err)
+				out, err = runClient(t, dst, "", "get", key, "-o", "raw")
+				require.NoError(t, err)
+				require.Equal(t, expected, out)
+			}
+
+			out, err = runClient(t, dst, "", "ttl", "value", "-o", "raw")
+			require.NoError(t, err)
+			require.Regexp(t, "^(59|60)\n$", out)
+		})
+	}
+
+	// Dump is written to stdout
+	out, _, err := runService(t, src, newDumpCmd())
+	require.NoError(t, err)
+	require.Contains(t, out, `{"key":"hash","type":"hash","value":{"field":"value"}}`)
+
+	// Invalid dump is rejected
+	file := filepath.Join(t.TempDir(), "invalid")
+	require.NoError(t, ioutil.WriteFile(file, []byte("{"), 0600))
+	_, _, err = runService(t, dst, newRestoreCmd(), file)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "got the 400 status code from the server: invalid dump: line 1")
+
+	_, _, err = runService(t, dst, newRestoreCmd(), file, "--mode", "unknown")
+	require.Error(t, err)
+}
+
+func TestRestoreToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+
+			return
+		}
+		_, _ = w.Write([]byte(`{"format":"jsonl","restored":0,"skipped":0}`))
+	}))
+	defer srv.Close()
+	e := testEndpoints{service: srv.URL}
+
+	cmd := newRestoreCmd()
+	cmd.SetIn(strings.NewReader(""))
+	_, _, err := runService(t, e, cmd)
+	require.EqualError(t, err, "got the 401 status code from the server: authentication required")
+
+	cmd = newRestoreCmd()
+	cmd.SetIn(strings.NewReader(""))
+	out, _, err := runService(t, e, cmd, "--token", "secret")
+	require.NoError(t, err)
+	require.Equal(t, "restored 0 keys, skipped 0 keys\n", out)
+}
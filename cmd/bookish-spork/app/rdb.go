@@ -0,0 +1,235 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/dump"
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+	"github.com/dstdfx/bookish-spork/internal/pkg/rdb"
+	"github.com/spf13/cobra"
+)
+
+// newRDBCmd returns the command to convert Redis RDB files.
+func newRDBCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rdb",
+		Short: "Import from and export to Redis RDB files",
+	}
+	cmd.AddCommand(newRDBImportCmd(), newRDBExportCmd())
+
+	return cmd
+}
+
+// newRDBImportCmd returns the command to import the keys from RDB file.
+func newRDBImportCmd() *cobra.Command {
+	opts := &serviceOpts{}
+	var (
+		mode string
+		db   int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import [file]",
+		Short: "Import the keys from Redis RDB file",
+		Long: "Convert the keys of Redis RDB file and restore them through the service API. Strings are imported " +
+			"as values or as bytes if they aren't valid UTF-8, sets are imported as lists of the sorted members, " +
+			"sorted sets and expired keys are skipped. The file is read from stdin if it's omitted or equal to \"-\".",
+		Args: cobra.MaximumNArgs(1),
+
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	fs := cmd.Flags()
+	opts.register(fs)
+	fs.StringVar(&mode, "mode", qqcache.RestoreSkip, "mode of restoring existing keys: skip, replace or merge")
+	fs.IntVar(&db, "db", 0, "Redis database to import")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		switch mode {
+		case qqcache.RestoreSkip, qqcache.RestoreReplace, qqcache.RestoreMerge:
+		default:
+			return qqcache.ErrInvalidRestoreMode
+		}
+
+		in := cmd.InOrStdin()
+		if len(args) > 0 && args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+		r, err := rdb.NewReader(in, db)
+		if err != nil {
+			return err
+		}
+
+		// The keys are converted to the dump while it's sent
+		pr, pw := io.Pipe()
+		converted := make(chan error, 1)
+		go func() {
+			converted <- convertRDB(r, pw)
+		}()
+
+		ctx, cancel := opts.withTimeout(cmd)
+		defer cancel()
+
+		resp, err := opts.do(ctx, http.MethodPost, dump.RestorePath, url.Values{"mode": {mode}}, pr)
+		_ = pr.Close()
+		if convErr := <-converted; convErr != nil {
+			return convErr
+		}
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		var result dump.RestoreResponse
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "restored %d keys, skipped %d keys\n", result.Restored, result.Skipped)
+		if skipped := formatSkipped(r.Skipped()); skipped != "" {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "ignored keys of RDB file: %s\n", skipped)
+		}
+
+		return nil
+	}
+
+	return cmd
+}
+
+// convertRDB writes the keys of RDB file to the pipe as the binary dump.
+func convertRDB(r *rdb.Reader, pw *io.PipeWriter) error {
+	dw, err := dump.NewWriter(pw, dump.FormatBinary)
+	if err != nil {
+		_ = pw.CloseWithError(err)
+
+		return err
+	}
+
+	for {
+		e, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			err = dw.Write(e)
+		}
+		if err != nil {
+			_ = pw.CloseWithError(err)
+
+			// The error of the request is returned if it has been closed first
+			if errors.Is(err, io.ErrClosedPipe) {
+				return nil
+			}
+
+			return err
+		}
+	}
+
+	return pw.CloseWithError(dw.Close())
+}
+
+// newRDBExportCmd returns the command to export all keys to RDB file.
+func newRDBExportCmd() *cobra.Command {
+	opts := &serviceOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "export [file]",
+		Short: "Export all keys to Redis RDB file",
+		Long: "Dump all keys through the service API and write them to Redis RDB file. Values are written as " +
+			"strings, JSON values other than strings are written as JSON, geo indexes are written as sorted sets " +
+			"available to Redis GEO commands. The file is written to stdout if it's omitted or equal to \"-\".",
+		Args: cobra.MaximumNArgs(1),
+
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	opts.register(cmd.Flags())
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := opts.withTimeout(cmd)
+		defer cancel()
+
+		resp, err := opts.do(ctx, http.MethodGet, dump.Path, url.Values{"format": {dump.FormatBinary}}, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		n := 0
+		var skipped map[string]int
+		write := func(w io.Writer) error {
+			r, err := dump.NewReader(resp.Body)
+			if err != nil {
+				return err
+			}
+			rw := rdb.NewWriter(w)
+			for {
+				e, err := r.Read()
+				if err == io.EOF {
+					skipped = rw.Skipped()
+
+					return rw.Close()
+				}
+				if err != nil {
+					return err
+				}
+				if err := rw.Write(e); err != nil {
+					return err
+				}
+				n++
+			}
+		}
+
+		if len(args) == 0 || args[0] == "-" {
+			err = write(cmd.OutOrStdout())
+		} else {
+			err = writeFile(args[0], write)
+		}
+		if err != nil {
+			return err
+		}
+
+		n -= skipped[rdb.SkipEmpty]
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "exported %d keys\n", n)
+		if s := formatSkipped(skipped); s != "" {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "ignored keys: %s\n", s)
+		}
+
+		return nil
+	}
+
+	return cmd
+}
+
+// formatSkipped returns the numbers of the skipped keys by the reasons sorted by name.
+func formatSkipped(skipped map[string]int) string {
+	reasons := make([]string, 0, len(skipped))
+	for reason := range skipped {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	parts := make([]string, 0, len(reasons))
+	for _, reason := range reasons {
+		parts = append(parts, fmt.Sprintf("%d %s", skipped[reason], reason))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func init() {
+	RootCmd.AddCommand(newRDBCmd())
+}
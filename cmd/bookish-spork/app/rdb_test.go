@@ -0,0 +1,74 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+	"github.com/dstdfx/bookish-spork/internal/pkg/rdb"
+	"github.com/dstdfx/bookish-spork/internal/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRDBImportExport(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	src, dst := startTestServer(t), startTestServer(t)
+
+	file := filepath.Join(t.TempDir(), "dump.rdb")
+	f, err := os.Create(file)
+	require.NoError(t, err)
+	w := rdb.NewWriter(f)
+	for _, e := range []qqcache.Entry{
+		{Key: "value", Type: qqcache.TypeValue, Value: "some-value", TTL: time.Minute},
+		{Key: "list", Type: qqcache.TypeList, Value: []interface{}{"a", "b"}},
+		{Key: "hash", Type: qqcache.TypeHash, Value: map[string]interface{}{"field": "value"}},
+		{Key: "geo", Type: qqcache.TypeGeo, Value: []qqcache.GeoMember{{Name: "member"}}},
+	} {
+		require.NoError(t, w.Write(e))
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+
+	out, errOut, err := runService(t, src, newRDBImportCmd(), file)
+	require.NoError(t, err)
+	require.Equal(t, "restored 3 keys, skipped 0 keys\n", out)
+	require.Equal(t, "ignored keys of RDB file: 1 zset\n", errOut)
+
+	out, err = runClient(t, src, "", "hget", "hash", "field", "-o", "raw")
+	require.NoError(t, err)
+	require.Equal(t, "value\n", out)
+	out, err = runClient(t, src, "", "ttl", "value", "-o", "raw")
+	require.NoError(t, err)
+	require.Regexp(t, "^(59|60)\n$", out)
+
+	// Exported keys are imported to other server
+	exported := filepath.Join(t.TempDir(), "exported.rdb")
+	_, errOut, err = runService(t, src, newRDBExportCmd(), exported)
+	require.NoError(t, err)
+	require.Equal(t, "exported 3 keys\n", errOut)
+
+	out, _, err = runService(t, dst, newRDBImportCmd(), exported, "--mode", "replace")
+	require.NoError(t, err)
+	require.Equal(t, "restored 3 keys, skipped 0 keys\n", out)
+	for _, key := range []string{"value", "list"} {
+		expected, err := runClient(t, src, "", "get", key, "-o", "raw")
+		require.NoError(t, err)
+		out, err = runClient(t, dst, "", "get", key, "-o", "raw")
+		require.NoError(t, err)
+		require.Equal(t, expected, out)
+	}
+
+	// Invalid file is rejected
+	invalid := filepath.Join(t.TempDir(), "invalid.rdb")
+	require.NoError(t, ioutil.WriteFile(invalid, []byte("REDIS0009\x00\x03key"), 0600))
+	_, _, err = runService(t, dst, newRDBImportCmd(), invalid)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `failed to read key "key"`)
+}
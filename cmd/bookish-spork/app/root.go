@@ -9,9 +9,16 @@ import (
 	"github.com/dstdfx/bookish-spork/internal/pkg/config"
 	"github.com/dstdfx/bookish-spork/internal/pkg/log"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
 )
 
-const defaultCfgFile = "/etc/bookish-spork/bookish-spork.yaml"
+const (
+	defaultCfgFile = "/etc/bookish-spork/bookish-spork.yaml"
+
+	// cfgFileEnv is the environment variable with the path to application config.
+	cfgFileEnv = config.EnvPrefix + "CONFIG"
+)
 
 var cfgFile string
 
@@ -27,27 +34,36 @@ var (
 var RootCmd = &cobra.Command{
 	Use:   "bookish-spork",
 	Short: "bookish-spork represents a simple HTTP API interface to in-memory cache",
-	Run: func(_ *cobra.Command, _ []string) {
+	Run: func(cmd *cobra.Command, _ []string) {
 		// Initialize application config and log
-		if _, err := os.Stat(cfgFile); err != nil {
-			exitWithErr(fmt.Errorf("config file %s can't be read: %s", cfgFile, err))
+		src, err := configSources(cmd)
+		if err != nil {
+			exitWithErr(err)
 		}
-		if err := config.InitFromFile(cfgFile); err != nil {
+		cfg, err := config.Load(src)
+		if err != nil {
 			exitWithErr(err)
 		}
 
 		// Init logger
+		logLevel := zap.NewAtomicLevel()
 		logger, err := log.InitLogger(log.InitLoggerOpts{
-			File:      config.Config.Log.File,
-			UseStdout: config.Config.Log.UseStdout,
-			Debug:     config.Config.Log.Debug,
+			File:      cfg.Log.File,
+			UseStdout: cfg.Log.UseStdout,
+			Debug:     cfg.Log.Debug,
+			Level:     logLevel,
 		})
 		if err != nil {
 			exitWithErr(err)
 		}
+		if src.File != "" {
+			logger.Info("config loaded", zap.String("file", src.File))
+		}
 
 		opts := bookishspork.StartOpts{
 			Interrupt:      make(chan os.Signal, 1),
+			ConfigSources:  src,
+			LogLevel:       logLevel,
 			BuildGitCommit: buildGitCommit,
 			BuildGitTag:    buildGitTag,
 			BuildDate:      buildDate,
@@ -55,15 +71,48 @@ var RootCmd = &cobra.Command{
 		}
 
 		// Start main routine
-		if err := bookishspork.StartService(logger, opts); err != nil {
+		if err := bookishspork.StartService(cfg, logger, opts); err != nil {
 			exitWithErr(fmt.Errorf("error starting bookish-spork app: %w", err))
 		}
 	},
 }
 
 func init() {
-	RootCmd.PersistentFlags().StringVar(&cfgFile, "config",
-		defaultCfgFile, "path to application config")
+	registerConfigFlags(RootCmd.Flags())
+}
+
+// registerConfigFlags registers the flags of application config,
+// they are used by the server and config commands.
+func registerConfigFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&cfgFile, "config",
+		defaultCfgFile, "path to application config, it's optional if not set explicitly")
+	config.RegisterFlags(fs)
+}
+
+// configSources returns the sources of application config. The config file is
+// set by --config flag or BOOKISH_SPORK_CONFIG environment variable and must exist,
+// the default file is used only if it exists.
+func configSources(cmd *cobra.Command) (config.Sources, error) {
+	src := config.Sources{
+		Environ: os.Environ(),
+		Flags:   cmd.Flags(),
+	}
+
+	file, explicit := cfgFile, cmd.Flags().Changed("config")
+	if env, ok := os.LookupEnv(cfgFileEnv); ok && !explicit {
+		file, explicit = env, true
+	}
+
+	if _, err := os.Stat(file); err != nil {
+		if explicit || !os.IsNotExist(err) {
+			return src, fmt.Errorf("config file %s can't be read: %s", file, err)
+		}
+
+		return src, nil
+	}
+	src.File = file
+
+	return src, nil
 }
 
 // exitWithErr is a helper method to print errors in case of empty logger.
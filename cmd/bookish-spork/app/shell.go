@@ -0,0 +1,413 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dstdfx/bookish-spork/httpclient"
+	"github.com/dstdfx/bookish-spork/internal/pkg/lineedit"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// historyFileName is the name of the shell history file in the home directory.
+const historyFileName = ".bookish_spork_history"
+
+// Commands handled by the shell itself.
+const (
+	shellHelp = "help"
+	shellExit = "exit"
+	shellQuit = "quit"
+)
+
+// errIncomplete is returned by splitArgs if a quote or JSON value is not closed.
+var errIncomplete = errors.New("incomplete input")
+
+// errNoStdin is returned by the commands run in the shell on attempt to read a value from stdin.
+var errNoStdin = errors.New("value is required, stdin can't be read in the shell")
+
+// keyCommands contains the commands that take a key as the first argument.
+var keyCommands = map[string]bool{
+	"get": true, "set": true, "del": true, "keys": true, "rpush": true,
+	"lindex": true, "hset": true, "hget": true, "ttl": true,
+}
+
+// newShellCmd returns the command to run the interactive shell.
+func newShellCmd() *cobra.Command {
+	opts := &clientOpts{}
+	var historyFile string
+
+	cmd := &cobra.Command{
+		Use:   "shell",
+		Short: "Run the interactive shell",
+		Long: "Run the interactive shell to execute the client commands with history and tab completion of " +
+			"the commands, flags and keys. JSON objects and arrays could span multiple lines.",
+		Args: cobra.NoArgs,
+
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	fs := cmd.Flags()
+	opts.register(fs)
+	fs.StringVar(&opts.serviceEndpoint, "service-endpoint", defaultServiceEndpoint, "service API endpoint")
+	fs.StringVar(&historyFile, "history-file", defaultHistoryFile(), "path to history file, history is not saved if it's empty")
+
+	cmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		cli, err := opts.client()
+		if err != nil {
+			return err
+		}
+
+		sh := newShell(cmd.InOrStdin(), cmd.OutOrStdout(), cli, opts)
+
+		// Flags of the shell are passed to the commands
+		cmd.Flags().Visit(func(f *pflag.Flag) {
+			switch f.Name {
+			case "history-file":
+			case "service-endpoint":
+				sh.serviceFlags = append(sh.serviceFlags, "--"+f.Name+"="+f.Value.String())
+			default:
+				sh.flags = append(sh.flags, "--"+f.Name+"="+f.Value.String())
+			}
+		})
+
+		if historyFile != "" && sh.editor.IsTerminal() {
+			if err := loadHistory(sh.editor, historyFile); err != nil {
+				return err
+			}
+			defer func() {
+				if err := saveHistory(sh.editor, historyFile); err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "failed to save history: %s\n", err)
+				}
+			}()
+		}
+
+		return sh.run()
+	}
+
+	return cmd
+}
+
+// shell reads the commands and runs them.
+type shell struct {
+	editor *lineedit.Editor
+	out    io.Writer
+	cli    *httpclient.Client
+	opts   *clientOpts
+	prompt string
+
+	// flags are passed to the commands, serviceFlags are passed to info command only
+	flags        []string
+	serviceFlags []string
+}
+
+func newShell(in io.Reader, out io.Writer, cli *httpclient.Client, opts *clientOpts) *shell {
+	sh := &shell{out: out, cli: cli, opts: opts, prompt: opts.endpoint + "> "}
+	if u, err := url.Parse(opts.endpoint); err == nil && u.Host != "" {
+		sh.prompt = u.Host + "> "
+	}
+	sh.editor = lineedit.New(lineedit.Opts{In: in, Out: out, Complete: sh.complete})
+
+	return sh
+}
+
+// run reads and runs the commands until the input is closed or exit command is entered.
+func (sh *shell) run() error {
+	for {
+		args, line, err := sh.read()
+		switch {
+		case errors.Is(err, lineedit.ErrInterrupted):
+			continue
+		case errors.Is(err, io.EOF):
+			return nil
+		case err != nil:
+			return err
+		}
+		if len(args) == 0 {
+			continue
+		}
+		sh.editor.AddHistory(line)
+
+		switch args[0] {
+		case shellExit, shellQuit:
+			return nil
+		case shellHelp:
+			sh.help()
+		default:
+			sh.exec(args)
+		}
+	}
+}
+
+// read reads the command, the lines are read until quotes and JSON values are closed.
+// It returns the arguments and the command as a single line.
+func (sh *shell) read() ([]string, string, error) {
+	var lines []string
+	prompt := sh.prompt
+	for {
+		line, err := sh.editor.ReadLine(prompt)
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(lines) > 0 {
+				return nil, "", errIncomplete
+			}
+
+			return nil, "", err
+		}
+		lines = append(lines, line)
+
+		args, err := splitArgs(strings.Join(lines, "\n"))
+		if err == nil {
+			return args, strings.Join(lines, " "), nil
+		}
+		prompt = continuationPrompt(sh.prompt)
+	}
+}
+
+// continuationPrompt returns the prompt of the continuation lines aligned with the prompt.
+func continuationPrompt(prompt string) string {
+	const dots = "... "
+	if n := utf8.RuneCountInString(prompt) - len(dots); n > 0 {
+		return strings.Repeat(" ", n) + dots
+	}
+
+	return dots
+}
+
+// exec runs the client command with the arguments and prints its output.
+func (sh *shell) exec(args []string) {
+	root := &cobra.Command{Use: RootCmd.Use, SilenceUsage: true, SilenceErrors: true}
+	root.AddCommand(clientCommands()...)
+
+	cmdArgs := append([]string{args[0]}, sh.flags...)
+	if args[0] == "info" {
+		cmdArgs = append(cmdArgs, sh.serviceFlags...)
+	}
+	cmdArgs = append(cmdArgs, args[1:]...)
+
+	var out bytes.Buffer
+	root.SetArgs(cmdArgs)
+	root.SetOut(&out)
+	root.SetIn(errReader{err: errNoStdin})
+	err := root.ExecuteContext(context.Background())
+
+	switch {
+	case errors.Is(err, errNotFound):
+		_, _ = fmt.Fprintln(sh.out, "(nil)")
+	case err != nil:
+		_, _ = fmt.Fprintf(sh.out, "(error) %s\n", err)
+	case out.Len() == 0:
+		_, _ = fmt.Fprintln(sh.out, "OK")
+	default:
+		_, _ = sh.out.Write(out.Bytes())
+	}
+}
+
+// help prints the list of the commands.
+func (sh *shell) help() {
+	tw := tabwriter.NewWriter(sh.out, 0, 0, 2, ' ', 0)
+	for _, cmd := range clientCommands() {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\n", cmd.Use, cmd.Short)
+	}
+	_, _ = fmt.Fprintf(tw, "%s\t%s\n", shellHelp, "Show this help")
+	_, _ = fmt.Fprintf(tw, "%s, %s\t%s\n", shellExit, shellQuit, "Exit the shell")
+	_ = tw.Flush()
+	_, _ = fmt.Fprintln(sh.out, "\nType <command> --help for the flags of the command.")
+}
+
+// complete returns the candidates to complete the command names, flags of the commands and keys.
+func (sh *shell) complete(line string) (string, []string) {
+	args, err := splitArgs(line)
+	if err != nil {
+		return "", nil
+	}
+
+	word := ""
+	if r, _ := utf8.DecodeLastRuneInString(line); len(args) > 0 && !unicode.IsSpace(r) {
+		word, args = args[len(args)-1], args[:len(args)-1]
+		if !strings.HasSuffix(line, word) {
+			// Quoted words are not completed
+			return "", nil
+		}
+	}
+
+	var names []string
+	switch {
+	case len(args) == 0:
+		names = []string{shellHelp, shellExit, shellQuit}
+		for _, cmd := range clientCommands() {
+			names = append(names, cmd.Name())
+		}
+	case strings.HasPrefix(word, "-"):
+		for _, cmd := range clientCommands() {
+			if cmd.Name() == args[0] {
+				cmd.Flags().VisitAll(func(f *pflag.Flag) {
+					names = append(names, "--"+f.Name)
+				})
+			}
+		}
+	case len(args) == 1 && keyCommands[args[0]]:
+		ctx, cancel := context.WithTimeout(context.Background(), sh.opts.timeout)
+		defer cancel()
+		names, _, _ = sh.cli.Keys(ctx)
+	}
+
+	var candidates []string
+	for _, name := range names {
+		if strings.HasPrefix(name, word) {
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+
+	return word, candidates
+}
+
+// splitArgs splits the line into arguments separated by whitespaces. Arguments could be
+// quoted with single or double quotes, the latter support escape sequences.
+// JSON objects and arrays are kept as is, so they may contain whitespaces and quotes.
+func splitArgs(line string) ([]string, error) {
+	var (
+		args    []string
+		arg     strings.Builder
+		inArg   bool
+		quote   rune
+		depth   int
+		inStr   bool
+		escaped bool
+	)
+
+	for _, r := range line {
+		switch {
+		case depth > 0:
+			// JSON value is kept as is up to the closing bracket
+			arg.WriteRune(r)
+			switch {
+			case escaped:
+				escaped = false
+			case inStr && r == '\\':
+				escaped = true
+			case r == '"':
+				inStr = !inStr
+			case inStr:
+			case r == '{' || r == '[':
+				depth++
+			case r == '}' || r == ']':
+				depth--
+			}
+		case quote != 0:
+			switch {
+			case escaped:
+				arg.WriteRune(unescape(r))
+				escaped = false
+			case quote == '"' && r == '\\':
+				escaped = true
+			case r == quote:
+				quote = 0
+			default:
+				arg.WriteRune(r)
+			}
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		case r == '"' || r == '\'':
+			quote, inArg = r, true
+		case !inArg && (r == '{' || r == '['):
+			arg.WriteRune(r)
+			depth, inArg = 1, true
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if depth > 0 || quote != 0 {
+		return args, errIncomplete
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	return args, nil
+}
+
+// unescape returns the character of the escape sequence in double quotes.
+func unescape(r rune) rune {
+	switch r {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'r':
+		return '\r'
+	default:
+		return r
+	}
+}
+
+// errReader is a reader that always fails.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// defaultHistoryFile returns the path to the history file in the home directory.
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, historyFileName)
+}
+
+// loadHistory loads the history from the file if it exists.
+func loadHistory(editor *lineedit.Editor, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+	defer f.Close()
+
+	return editor.LoadHistory(f)
+}
+
+// saveHistory saves the history to the file, it's readable by the owner only.
+func saveHistory(editor *lineedit.Editor, path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := editor.SaveHistory(f); err != nil {
+		_ = f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
+
+func init() {
+	RootCmd.AddCommand(newShellCmd())
+}
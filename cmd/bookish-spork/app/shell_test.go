@@ -0,0 +1,121 @@
+package app
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []string
+		err      error
+	}{
+		{line: "", expected: nil},
+		{line: "  get   key  ", expected: []string{"get", "key"}},
+		{line: `set key "some value"`, expected: []string{"set", "key", "some value"}},
+		{line: `set key 'it''s' "a \"b\"\n"`, expected: []string{"set", "key", "its", "a \"b\"\n"}},
+		{line: `set key --json {"a": [1, "} ]"], "b": {}}`, expected: []string{"set", "key", "--json", `{"a": [1, "} ]"], "b": {}}`}},
+		{line: "set key --json [1,\n 2]", expected: []string{"set", "key", "--json", "[1,\n 2]"}},
+		{line: "set key a{b", expected: []string{"set", "key", "a{b"}},
+		{line: `set key {"a": [1, 2]`, expected: []string{"set", "key"}, err: errIncomplete},
+		{line: `set key "value`, expected: []string{"set", "key"}, err: errIncomplete},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.line, func(t *testing.T) {
+			args, err := splitArgs(tc.line)
+			require.Equal(t, tc.err, err)
+			require.Equal(t, tc.expected, args)
+		})
+	}
+}
+
+func TestShell(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	e := startTestServer(t)
+
+	input := strings.Join([]string{
+		"set key 'some value'",
+		"",
+		"get key -o raw",
+		`set json --json {"a": [1,`,
+		` 2]}`,
+		"get json -o raw",
+		"get missing",
+		"set",
+		"set key",
+		"unknown",
+		"exit",
+		"get key",
+	}, "\n")
+
+	var out bytes.Buffer
+	cmd := newShellCmd()
+	cmd.SetArgs([]string{"--endpoint", e.public, "--history-file", ""})
+	cmd.SetIn(strings.NewReader(input))
+	cmd.SetOut(&out)
+	require.NoError(t, cmd.Execute())
+
+	require.Equal(t, strings.Join([]string{
+		"OK",
+		"some value",
+		"OK",
+		`{"a":[1,2]}`,
+		"(nil)",
+		"(error) accepts between 1 and 2 arg(s), received 0",
+		"(error) " + errNoStdin.Error(),
+		`(error) unknown command "unknown" for "bookish-spork"`,
+	}, "\n")+"\n", out.String())
+}
+
+func TestShell_Complete(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	e := startTestServer(t)
+	for _, key := range []string{"apple", "apricot", "banana"} {
+		_, err := runClient(t, e, "", "set", key, "value")
+		require.NoError(t, err)
+	}
+
+	opts := &clientOpts{endpoint: e.public, timeout: defaultClientTimeout}
+	cli, err := opts.client()
+	require.NoError(t, err)
+	sh := newShell(strings.NewReader(""), &bytes.Buffer{}, cli, opts)
+	require.Equal(t, e.public[len("http://"):len(e.public)-len("/v1")]+"> ", sh.prompt)
+
+	tests := []struct {
+		line       string
+		word       string
+		candidates []string
+	}{
+		{line: "", word: "", candidates: []string{"del", "exit", "get", "help", "hget", "hset", "info",
+			"keys", "lindex", "quit", "rpush", "set", "ttl"}},
+		{line: "h", word: "h", candidates: []string{"help", "hget", "hset"}},
+		{line: "get ", word: "", candidates: []string{"apple", "apricot", "banana"}},
+		{line: "get ap", word: "ap", candidates: []string{"apple", "apricot"}},
+		{line: "get apple ", word: "", candidates: nil},
+		{line: "info ", word: "", candidates: nil},
+		{line: "get apple --o", word: "--o", candidates: []string{"--output"}},
+		{line: `get "ap`, word: "", candidates: nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.line, func(t *testing.T) {
+			word, candidates := sh.complete(tc.line)
+			require.Equal(t, tc.word, word)
+			require.Equal(t, tc.candidates, candidates)
+		})
+	}
+}
@@ -9,7 +9,7 @@ import (
 
 func main() {
 	if err := app.RootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(app.ExitCode(err))
 	}
 }
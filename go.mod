@@ -5,7 +5,11 @@ go 1.15
 require (
 	github.com/go-chi/chi v4.1.2+incompatible
 	github.com/spf13/cobra v1.0.0
+	github.com/spf13/pflag v1.0.3
 	github.com/stretchr/testify v1.6.1
 	go.uber.org/zap v1.16.0
+	golang.org/x/sys v0.0.0-20190412213103-97732733099d
+	google.golang.org/grpc v1.38.0
+	google.golang.org/protobuf v1.26.0
 	gopkg.in/yaml.v2 v2.3.0
 )
@@ -0,0 +1,81 @@
+// Package grpcclient contains gRPC client of bookish-spork CacheService.
+//
+// Client and messages are generated from cache.proto, regenerate them after
+// changing the service definition.
+package grpcclient
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative cache.proto
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Client stores connection to bookish-spork gRPC API.
+type Client struct {
+	CacheServiceClient
+
+	conn *grpc.ClientConn
+}
+
+// NewClient initializes a new client for bookish-spork gRPC API.
+// If dial options are omitted - insecure connection will be used.
+func NewClient(target string, opts ...grpc.DialOption) (*Client, error) {
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithInsecure()}
+	}
+
+	conn, err := grpc.Dial(target, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		CacheServiceClient: NewCacheServiceClient(conn),
+		conn:               conn,
+	}, nil
+}
+
+// Close closes client connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// WithToken returns dial option that passes the bearer token in the authorization
+// metadata of every call. The token is sent over insecure connections as well,
+// the same way as the HTTP API does.
+func WithToken(token string) grpc.DialOption {
+	return grpc.WithPerRPCCredentials(tokenCredentials(token))
+}
+
+// tokenCredentials implements credentials.PerRPCCredentials for bearer token.
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// NewJSONValue returns Value that holds JSON-like value.
+// Supported types are the same as for structpb.NewValue.
+func NewJSONValue(v interface{}) (*Value, error) {
+	pv, err := structpb.NewValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Value{Kind: &Value_Json{Json: pv}}, nil
+}
+
+// NewRawValue returns Value that holds binary-safe value with its content type.
+func NewRawValue(data []byte, contentType string) *Value {
+	return &Value{
+		Kind:        &Value_Raw{Raw: data},
+		ContentType: contentType,
+	}
+}
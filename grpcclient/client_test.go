@@ -0,0 +1,24 @@
+package grpcclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewJSONValue(t *testing.T) {
+	v, err := NewJSONValue(map[string]interface{}{"a": "b"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"a": "b"}, v.GetJson().AsInterface())
+	assert.Nil(t, v.GetRaw())
+
+	_, err = NewJSONValue(struct{}{})
+	assert.Error(t, err)
+}
+
+func TestNewRawValue(t *testing.T) {
+	v := NewRawValue([]byte("data"), "text/plain")
+	assert.Equal(t, []byte("data"), v.GetRaw())
+	assert.Equal(t, "text/plain", v.GetContentType())
+	assert.Nil(t, v.GetJson())
+}
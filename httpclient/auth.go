@@ -0,0 +1,117 @@
+package httpclient
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of HMAC-signed requests.
+const (
+	hmacKeyIDHeader     = "X-BS-Key-Id"
+	hmacTimestampHeader = "X-BS-Timestamp"
+	hmacNonceHeader     = "X-BS-Nonce"
+	hmacSignatureHeader = "X-BS-Signature"
+)
+
+// Credentials adds authentication data to the requests.
+type Credentials interface {
+	// Authenticate adds credentials to the request with the given body.
+	Authenticate(req *http.Request, body []byte) error
+}
+
+// BearerToken represents static token credentials.
+type BearerToken string
+
+// Authenticate sets Authorization header with the token.
+func (t BearerToken) Authenticate(req *http.Request, _ []byte) error {
+	req.Header.Set("Authorization", "Bearer "+string(t))
+
+	return nil
+}
+
+// HMACKey represents credentials to sign requests with HMAC-SHA256.
+type HMACKey struct {
+	KeyID  string
+	Secret string
+}
+
+// Authenticate signs the request with the current timestamp and a random nonce.
+func (k HMACKey) Authenticate(req *http.Request, body []byte) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	bodyHash := sha256.Sum256(body)
+
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.URL.RequestURI(),
+		timestamp,
+		hex.EncodeToString(nonce),
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+	mac := hmac.New(sha256.New, []byte(k.Secret))
+	_, _ = mac.Write([]byte(stringToSign))
+
+	req.Header.Set(hmacKeyIDHeader, k.KeyID)
+	req.Header.Set(hmacTimestampHeader, timestamp)
+	req.Header.Set(hmacNonceHeader, hex.EncodeToString(nonce))
+	req.Header.Set(hmacSignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+
+	return nil
+}
+
+// NewClientTLS initializes a new client for bookish-spork API using TLS.
+// Client certificate from the TLS config is used to authenticate requests
+// if the server has mTLS authentication enabled.
+func NewClientTLS(tlsConfig *tls.Config, endpoint string) *Client {
+	transport := newHTTPTransport()
+	transport.TLSClientConfig = tlsConfig
+
+	return &Client{
+		HTTPClient: &http.Client{
+			Timeout:   defaultHTTPTimeout * time.Second,
+			Transport: transport,
+		},
+		Endpoint: endpoint,
+	}
+}
+
+// LoadTLSConfig returns TLS config with client certificate and key loaded from PEM files.
+// If caFile is empty - system root CAs are used to verify the server.
+func LoadTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if caFile != "" {
+		data, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, errors.New("no certificates found in " + caFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,73 @@
+package httpclient
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dstdfx/bookish-spork/httpclient/testutils"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/auth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBearerToken(t *testing.T) {
+	endpointCalled := false
+	testEnv := testutils.SetupTestEnv()
+	defer testEnv.TearDownTestEnv()
+
+	testEnv.Mux.HandleFunc("/v1/keys", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"keys": []}`))
+		endpointCalled = true
+	})
+
+	testClient := NewClient(testEnv.Server.URL + "/v1")
+	testClient.Credentials = BearerToken("test-token")
+
+	_, _, err := testClient.Keys(context.Background())
+	require.NoError(t, err)
+	require.True(t, endpointCalled)
+}
+
+func TestHMACKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpclient")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	keysFile := filepath.Join(dir, "hmac_keys")
+	require.NoError(t, ioutil.WriteFile(keysFile, []byte("test-key-id:test-secret\n"), 0o600))
+
+	// Requests are verified by the server implementation
+	authenticator, err := auth.New(auth.Opts{HMACKeysFile: keysFile})
+	require.NoError(t, err)
+	defer authenticator.Close()
+
+	endpointCalled := false
+	testEnv := testutils.SetupTestEnv()
+	defer testEnv.TearDownTestEnv()
+
+	testEnv.Mux.HandleFunc("/v1/set", func(w http.ResponseWriter, r *http.Request) {
+		identity, err := authenticator.Authenticate(r)
+		require.NoError(t, err)
+		require.Equal(t, "test-key-id", identity.Name)
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"key": "test-key", "value": "test-value", "ttl": 10}`, string(body))
+
+		w.WriteHeader(http.StatusOK)
+		endpointCalled = true
+	})
+
+	testClient := NewClient(testEnv.Server.URL + "/v1")
+	testClient.Credentials = HMACKey{KeyID: "test-key-id", Secret: "test-secret"}
+
+	_, err = testClient.Set(context.Background(), SetBody{Key: testKey, Value: "test-value", TTL: 10})
+	require.NoError(t, err)
+	require.True(t, endpointCalled)
+}
@@ -0,0 +1,69 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultBytesContentType is the content type used for raw values if it's omitted.
+const DefaultBytesContentType = "application/octet-stream"
+
+// SetBytesOpts represents options of raw value to set.
+type SetBytesOpts struct {
+	// ContentType is the media type of the value, it's returned back by GetBytes.
+	// Supported types are application/octet-stream, text/plain and application/msgpack.
+	ContentType string
+
+	// TTL in seconds, the key will never be expired if it's equal or less than 0.
+	TTL int
+}
+
+// SetBytes sets raw value by key in cache.
+// The value is stored as is without any encoding.
+func (client *Client) SetBytes(ctx context.Context, key string, value []byte, opts SetBytesOpts) (*ResponseResult, error) {
+	url := strings.Join([]string{client.Endpoint, keysEndpoint, key}, "/")
+	if opts.TTL > 0 {
+		url += "?ttl=" + strconv.Itoa(opts.TTL)
+	}
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = DefaultBytesContentType
+	}
+
+	responseResult, err := client.doRequestWithHeaders(ctx, http.MethodPut, url, bytes.NewReader(value),
+		map[string]string{"Content-Type": contentType})
+	if err != nil {
+		return nil, err
+	}
+	if responseResult.Err != nil {
+		return responseResult, responseResult.Err
+	}
+
+	return responseResult, nil
+}
+
+// GetBytes returns raw value by key in cache with its content type.
+// Values that have been set as JSON are returned JSON-encoded.
+func (client *Client) GetBytes(ctx context.Context, key string) ([]byte, string, *ResponseResult, error) {
+	url := strings.Join([]string{client.Endpoint, keysEndpoint, key}, "/")
+	responseResult, err := client.doRequest(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if responseResult.Err != nil {
+		return nil, "", responseResult, responseResult.Err
+	}
+
+	// Read response body as is
+	defer responseResult.Body.Close()
+	value, err := ioutil.ReadAll(responseResult.Body)
+	if err != nil {
+		return nil, "", responseResult, err
+	}
+
+	return value, responseResult.Header.Get("Content-Type"), responseResult, nil
+}
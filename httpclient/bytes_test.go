@@ -0,0 +1,72 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/dstdfx/bookish-spork/httpclient/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+var testBytesValue = []byte{0x89, 0x50, 0x4e, 0x47, 0x00, 0xff}
+
+func TestSetBytes(t *testing.T) {
+	endpointCalled := false
+	testEnv := testutils.SetupTestEnv()
+	defer testEnv.TearDownTestEnv()
+
+	testEnv.Mux.HandleFunc(fmt.Sprintf("/v1/keys/%s", testKey), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPut, r.Method)
+		require.Equal(t, "application/msgpack", r.Header.Get("Content-Type"))
+		require.Equal(t, "10", r.URL.Query().Get("ttl"))
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.True(t, bytes.Equal(testBytesValue, body))
+
+		w.WriteHeader(http.StatusOK)
+		endpointCalled = true
+	})
+
+	ctx := context.Background()
+	testClient := NewClient(testEnv.Server.URL + "/v1")
+
+	httpResponse, err := testClient.SetBytes(ctx, testKey, testBytesValue, SetBytesOpts{
+		ContentType: "application/msgpack",
+		TTL:         10,
+	})
+	require.NoError(t, err)
+	require.True(t, endpointCalled)
+	require.NotNil(t, httpResponse)
+	require.Equal(t, http.StatusOK, httpResponse.StatusCode)
+}
+
+func TestGetBytes(t *testing.T) {
+	endpointCalled := false
+	testEnv := testutils.SetupTestEnv()
+	defer testEnv.TearDownTestEnv()
+
+	testEnv.Mux.HandleFunc(fmt.Sprintf("/v1/keys/%s", testKey), func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+
+		w.Header().Set("Content-Type", DefaultBytesContentType)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(testBytesValue)
+		endpointCalled = true
+	})
+
+	ctx := context.Background()
+	testClient := NewClient(testEnv.Server.URL + "/v1")
+
+	actual, contentType, httpResponse, err := testClient.GetBytes(ctx, testKey)
+	require.NoError(t, err)
+	require.True(t, endpointCalled)
+	require.NotNil(t, httpResponse)
+	require.Equal(t, http.StatusOK, httpResponse.StatusCode)
+	require.Equal(t, DefaultBytesContentType, contentType)
+	require.Equal(t, testBytesValue, actual)
+}
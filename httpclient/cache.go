@@ -193,3 +193,28 @@ func (client *Client) HGet(ctx context.Context, key, hkey string) (interface{},
 
 	return v.Value, responseResult, nil
 }
+
+// TTL returns the remaining time to live of the key in seconds,
+// -1 is returned if the key will never be expired.
+func (client *Client) TTL(ctx context.Context, key string) (int, *ResponseResult, error) {
+	url := strings.Join([]string{client.Endpoint, ttlEndpoint, key}, "/")
+	responseResult, err := client.doRequest(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	if responseResult.Err != nil {
+		return 0, responseResult, responseResult.Err
+	}
+
+	// Extract response body
+	var v struct {
+		TTL int `json:"ttl"`
+	}
+
+	err = responseResult.extractResult(&v)
+	if err != nil {
+		return 0, responseResult, err
+	}
+
+	return v.TTL, responseResult, nil
+}
@@ -21,6 +21,7 @@ const (
 	testLIndexRawResponse = `{"value": "test-value"}`
 	testHSetRawRequest    = `{"key": "test-key", "value": {"key0": "value0"}, "ttl": 10}`
 	testHGetRawResponse   = `{"value": "hvalue"}`
+	testTTLRawResponse    = `{"ttl": 10}`
 )
 
 var (
@@ -236,3 +237,28 @@ func TestHGet(t *testing.T) {
 	require.Equal(t, http.StatusOK, httpResponse.StatusCode)
 	require.Equal(t, expectedHValue, actual)
 }
+
+func TestTTL(t *testing.T) {
+	endpointCalled := false
+	testEnv := testutils.SetupTestEnv()
+	defer testEnv.TearDownTestEnv()
+
+	testutils.HandleReqWithoutBody(t, &testutils.HandleReqOpts{
+		Mux:         testEnv.Mux,
+		URL:         fmt.Sprintf("/v1/ttl/%s", testKey),
+		RawResponse: testTTLRawResponse,
+		Method:      http.MethodGet,
+		Status:      http.StatusOK,
+		CallFlag:    &endpointCalled,
+	})
+
+	ctx := context.Background()
+	testClient := NewClient(testEnv.Server.URL + "/v1")
+
+	actual, httpResponse, err := testClient.TTL(ctx, testKey)
+	require.NoError(t, err)
+	require.True(t, endpointCalled)
+	require.NotNil(t, httpResponse)
+	require.Equal(t, http.StatusOK, httpResponse.StatusCode)
+	require.Equal(t, 10, actual)
+}
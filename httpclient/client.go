@@ -1,6 +1,7 @@
 package httpclient
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -9,6 +10,8 @@ import (
 	"net"
 	"net/http"
 	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/tracing"
 )
 
 const (
@@ -45,6 +48,12 @@ const (
 	lindexEndpoint = "lindex"
 	hgetEndpoint   = "hget"
 	hsetEndpoint   = "hset"
+	ttlEndpoint    = "ttl"
+
+	geoaddEndpoint    = "geoadd"
+	geoposEndpoint    = "geopos"
+	geodistEndpoint   = "geodist"
+	geosearchEndpoint = "geosearch"
 )
 
 // Client stores details that are needed to work with bookish-spork.
@@ -54,6 +63,9 @@ type Client struct {
 
 	// Endpoint represents an endpoint that will be used in all requests.
 	Endpoint string
+
+	// Credentials are used to authenticate requests if they are set.
+	Credentials Credentials
 }
 
 // NewClient initializes a new client for bookish-spork API.
@@ -98,12 +110,30 @@ func newHTTPTransport() *http.Transport {
 		IdleConnTimeout:       defaultIdleConnTimeout * time.Second,
 		TLSHandshakeTimeout:   defaultTLSHandshakeTimeout * time.Second,
 		ExpectContinueTimeout: defaultExpectContinueTimeout * time.Second,
+		ForceAttemptHTTP2:     true,
 	}
 }
 
 // doRequest performs the HTTP request with the current Client's HTTPClient.
 // Authentication and optional headers will be added automatically.
 func (client *Client) doRequest(ctx context.Context, method, path string, body io.Reader) (*ResponseResult, error) {
+	return client.doRequestWithHeaders(ctx, method, path, body, nil)
+}
+
+// doRequestWithHeaders performs the HTTP request with the given headers.
+// JSON Content-Type will be set if body is provided and the header is omitted.
+func (client *Client) doRequestWithHeaders(ctx context.Context, method, path string, body io.Reader,
+	headers map[string]string) (*ResponseResult, error) {
+	// Read the body to sign it if credentials are set.
+	var rawBody []byte
+	if client.Credentials != nil && body != nil {
+		var err error
+		if rawBody, err = ioutil.ReadAll(body); err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(rawBody)
+	}
+
 	// Prepare an HTTP request with the provided context.
 	request, err := http.NewRequest(method, path, body)
 	if err != nil {
@@ -113,6 +143,15 @@ func (client *Client) doRequest(ctx context.Context, method, path string, body i
 	if body != nil {
 		request.Header.Set("Content-Type", "application/json")
 	}
+	for k, v := range headers {
+		request.Header.Set(k, v)
+	}
+	if client.Credentials != nil {
+		if err := client.Credentials.Authenticate(request, rawBody); err != nil {
+			return nil, err
+		}
+	}
+	tracing.Inject(ctx, request.Header)
 	request = request.WithContext(ctx)
 
 	// nolint
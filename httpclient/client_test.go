@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/dstdfx/bookish-spork/httpclient/testutils"
+	"github.com/dstdfx/bookish-spork/internal/pkg/tracing"
 	"github.com/stretchr/testify/require"
 )
 
@@ -36,6 +37,33 @@ func TestDoGetRequest(t *testing.T) {
 	require.Equal(t, http.StatusOK, response.StatusCode)
 }
 
+func TestDoRequestTraceparent(t *testing.T) {
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	testEnv := testutils.SetupTestEnv()
+	defer testEnv.TearDownTestEnv()
+	testEnv.Mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, "response")
+
+		require.Equal(t, traceparent, r.Header.Get(tracing.TraceparentHeader))
+	})
+
+	endpoint := testEnv.Server.URL + "/"
+	client := &Client{
+		HTTPClient: &http.Client{},
+		Endpoint:   endpoint,
+	}
+
+	sc, err := tracing.ParseTraceparent(traceparent)
+	require.NoError(t, err)
+
+	ctx := tracing.ContextWithRemoteSpanContext(context.Background(), sc)
+	response, err := client.doRequest(ctx, http.MethodGet, endpoint, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+}
+
 func TestDoPostRequest(t *testing.T) {
 	testEnv := testutils.SetupTestEnv()
 	defer testEnv.TearDownTestEnv()
@@ -0,0 +1,165 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GeoPoint represents geographic coordinates.
+type GeoPoint struct {
+	Longitude float64 `json:"longitude"`
+	Latitude  float64 `json:"latitude"`
+}
+
+// GeoMember represents a named point to add to geospatial index.
+type GeoMember struct {
+	Member    string  `json:"member"`
+	Longitude float64 `json:"longitude"`
+	Latitude  float64 `json:"latitude"`
+}
+
+// GeoAddBody represents geoadd request body.
+type GeoAddBody struct {
+	Key     string      `json:"key"`
+	Members []GeoMember `json:"members"`
+	TTL     int         `json:"ttl"`
+}
+
+// GeoAdd adds members to geospatial index or creates a new one.
+// It returns the number of new members added to the index.
+func (client *Client) GeoAdd(ctx context.Context, body GeoAddBody) (int, *ResponseResult, error) {
+	url := strings.Join([]string{client.Endpoint, geoaddEndpoint}, "/")
+	v, err := json.Marshal(body)
+	if err != nil {
+		return 0, nil, err
+	}
+	responseResult, err := client.doRequest(ctx, http.MethodPost, url, bytes.NewReader(v))
+	if err != nil {
+		return 0, nil, err
+	}
+	if responseResult.Err != nil {
+		return 0, responseResult, responseResult.Err
+	}
+
+	// Extract response body
+	var result struct {
+		Added int `json:"added"`
+	}
+
+	err = responseResult.extractResult(&result)
+	if err != nil {
+		return 0, responseResult, err
+	}
+
+	return result.Added, responseResult, nil
+}
+
+// GeoPos returns positions of members in geospatial index.
+// Nil is returned at the position of not existing member.
+func (client *Client) GeoPos(ctx context.Context, key string, members ...string) ([]*GeoPoint, *ResponseResult, error) {
+	query := url.Values{"member": members}
+	reqURL := strings.Join([]string{client.Endpoint, geoposEndpoint, key}, "/") + "?" + query.Encode()
+	responseResult, err := client.doRequest(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if responseResult.Err != nil {
+		return nil, responseResult, responseResult.Err
+	}
+
+	// Extract response body
+	var v struct {
+		Value []*GeoPoint `json:"value"`
+	}
+
+	err = responseResult.extractResult(&v)
+	if err != nil {
+		return nil, responseResult, err
+	}
+
+	return v.Value, responseResult, nil
+}
+
+// GeoDist returns the distance between two members of geospatial index in given unit.
+// Nil is returned if any of the members does not exist.
+func (client *Client) GeoDist(ctx context.Context, key, member1, member2, unit string) (*float64, *ResponseResult, error) {
+	query := url.Values{"member": []string{member1, member2}}
+	if unit != "" {
+		query.Set("unit", unit)
+	}
+	reqURL := strings.Join([]string{client.Endpoint, geodistEndpoint, key}, "/") + "?" + query.Encode()
+	responseResult, err := client.doRequest(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if responseResult.Err != nil {
+		return nil, responseResult, responseResult.Err
+	}
+
+	// Extract response body
+	var v struct {
+		Value *float64 `json:"value"`
+	}
+
+	err = responseResult.extractResult(&v)
+	if err != nil {
+		return nil, responseResult, err
+	}
+
+	return v.Value, responseResult, nil
+}
+
+// GeoSearchBody represents geosearch request body.
+// Search center is set either by FromMember or by FromLonLat,
+// search area is set either by Radius or by Width and Height.
+type GeoSearchBody struct {
+	Key        string    `json:"key"`
+	FromMember string    `json:"from_member,omitempty"`
+	FromLonLat *GeoPoint `json:"from_lonlat,omitempty"`
+	Radius     float64   `json:"radius,omitempty"`
+	Width      float64   `json:"width,omitempty"`
+	Height     float64   `json:"height,omitempty"`
+	Unit       string    `json:"unit,omitempty"`
+	Sort       string    `json:"sort,omitempty"`
+	Count      int       `json:"count,omitempty"`
+}
+
+// GeoSearchResult represents a single member found by geosearch.
+type GeoSearchResult struct {
+	Member    string  `json:"member"`
+	Distance  float64 `json:"distance"`
+	Longitude float64 `json:"longitude"`
+	Latitude  float64 `json:"latitude"`
+}
+
+// GeoSearch returns members of geospatial index within given area.
+func (client *Client) GeoSearch(ctx context.Context, body GeoSearchBody) ([]GeoSearchResult, *ResponseResult, error) {
+	url := strings.Join([]string{client.Endpoint, geosearchEndpoint}, "/")
+	v, err := json.Marshal(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	responseResult, err := client.doRequest(ctx, http.MethodPost, url, bytes.NewReader(v))
+	if err != nil {
+		return nil, nil, err
+	}
+	if responseResult.Err != nil {
+		return nil, responseResult, responseResult.Err
+	}
+
+	// Extract response body
+	var result struct {
+		Value []GeoSearchResult `json:"value"`
+	}
+
+	err = responseResult.extractResult(&result)
+	if err != nil {
+		return nil, responseResult, err
+	}
+
+	return result.Value, responseResult, nil
+}
@@ -0,0 +1,137 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/dstdfx/bookish-spork/httpclient/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testGeoAddRawRequest     = `{"key": "test-key", "members": [{"member": "Palermo", "longitude": 13.361389, "latitude": 38.115556}], "ttl": 0}`
+	testGeoAddRawResponse    = `{"added": 1}`
+	testGeoPosRawResponse    = `{"value": [{"longitude": 13.361389, "latitude": 38.115556}, null]}`
+	testGeoDistRawResponse   = `{"value": 166.2742}`
+	testGeoSearchRawRequest  = `{"key": "test-key", "from_member": "Palermo", "radius": 200, "unit": "km", "sort": "asc"}`
+	testGeoSearchRawResponse = `{"value": [{"member": "Palermo", "distance": 0, "longitude": 13.361389, "latitude": 38.115556},
+                                          {"member": "Catania", "distance": 166.2742, "longitude": 15.087269, "latitude": 37.502669}]}`
+)
+
+func TestGeoAdd(t *testing.T) {
+	endpointCalled := false
+	testEnv := testutils.SetupTestEnv()
+	defer testEnv.TearDownTestEnv()
+
+	testutils.HandleReqWithBody(t, &testutils.HandleReqOpts{
+		Mux:         testEnv.Mux,
+		URL:         "/v1/geoadd",
+		RawRequest:  testGeoAddRawRequest,
+		RawResponse: testGeoAddRawResponse,
+		Method:      http.MethodPost,
+		Status:      http.StatusOK,
+		CallFlag:    &endpointCalled,
+	})
+
+	ctx := context.Background()
+	testClient := NewClient(testEnv.Server.URL + "/v1")
+
+	actual, httpResponse, err := testClient.GeoAdd(ctx, GeoAddBody{
+		Key: testKey,
+		Members: []GeoMember{
+			{Member: "Palermo", Longitude: 13.361389, Latitude: 38.115556},
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, endpointCalled)
+	require.NotNil(t, httpResponse)
+	require.Equal(t, http.StatusOK, httpResponse.StatusCode)
+	require.Equal(t, 1, actual)
+}
+
+func TestGeoPos(t *testing.T) {
+	endpointCalled := false
+	testEnv := testutils.SetupTestEnv()
+	defer testEnv.TearDownTestEnv()
+
+	testutils.HandleReqWithoutBody(t, &testutils.HandleReqOpts{
+		Mux:         testEnv.Mux,
+		URL:         fmt.Sprintf("/v1/geopos/%s", testKey),
+		RawResponse: testGeoPosRawResponse,
+		Method:      http.MethodGet,
+		Status:      http.StatusOK,
+		CallFlag:    &endpointCalled,
+	})
+
+	ctx := context.Background()
+	testClient := NewClient(testEnv.Server.URL + "/v1")
+
+	actual, httpResponse, err := testClient.GeoPos(ctx, testKey, "Palermo", "Catania")
+	require.NoError(t, err)
+	require.True(t, endpointCalled)
+	require.NotNil(t, httpResponse)
+	require.Equal(t, http.StatusOK, httpResponse.StatusCode)
+	require.Equal(t, []*GeoPoint{{Longitude: 13.361389, Latitude: 38.115556}, nil}, actual)
+}
+
+func TestGeoDist(t *testing.T) {
+	endpointCalled := false
+	testEnv := testutils.SetupTestEnv()
+	defer testEnv.TearDownTestEnv()
+
+	testutils.HandleReqWithoutBody(t, &testutils.HandleReqOpts{
+		Mux:         testEnv.Mux,
+		URL:         fmt.Sprintf("/v1/geodist/%s", testKey),
+		RawResponse: testGeoDistRawResponse,
+		Method:      http.MethodGet,
+		Status:      http.StatusOK,
+		CallFlag:    &endpointCalled,
+	})
+
+	ctx := context.Background()
+	testClient := NewClient(testEnv.Server.URL + "/v1")
+
+	actual, httpResponse, err := testClient.GeoDist(ctx, testKey, "Palermo", "Catania", "km")
+	require.NoError(t, err)
+	require.True(t, endpointCalled)
+	require.NotNil(t, httpResponse)
+	require.Equal(t, http.StatusOK, httpResponse.StatusCode)
+	require.NotNil(t, actual)
+	require.Equal(t, 166.2742, *actual)
+}
+
+func TestGeoSearch(t *testing.T) {
+	endpointCalled := false
+	testEnv := testutils.SetupTestEnv()
+	defer testEnv.TearDownTestEnv()
+
+	testutils.HandleReqWithBody(t, &testutils.HandleReqOpts{
+		Mux:         testEnv.Mux,
+		URL:         "/v1/geosearch",
+		RawRequest:  testGeoSearchRawRequest,
+		RawResponse: testGeoSearchRawResponse,
+		Method:      http.MethodPost,
+		Status:      http.StatusOK,
+		CallFlag:    &endpointCalled,
+	})
+
+	ctx := context.Background()
+	testClient := NewClient(testEnv.Server.URL + "/v1")
+
+	actual, httpResponse, err := testClient.GeoSearch(ctx, GeoSearchBody{
+		Key:        testKey,
+		FromMember: "Palermo",
+		Radius:     200,
+		Unit:       "km",
+		Sort:       "asc",
+	})
+	require.NoError(t, err)
+	require.True(t, endpointCalled)
+	require.NotNil(t, httpResponse)
+	require.Equal(t, http.StatusOK, httpResponse.StatusCode)
+	require.Len(t, actual, 2)
+	require.Equal(t, "Catania", actual[1].Member)
+	require.Equal(t, 166.2742, actual[1].Distance)
+}
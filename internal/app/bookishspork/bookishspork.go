@@ -3,34 +3,28 @@ package bookishspork
 import (
 	"context"
 	"fmt"
-	"net/http"
-	"net/http/pprof"
 	"os"
 	"os/signal"
-	"strconv"
-	"strings"
 	"syscall"
-	"time"
 
-	"github.com/dstdfx/bookish-spork/internal/pkg/backend"
 	"github.com/dstdfx/bookish-spork/internal/pkg/config"
-	public "github.com/dstdfx/bookish-spork/internal/pkg/http"
+	"github.com/dstdfx/bookish-spork/server"
 	"go.uber.org/zap"
 )
 
-const (
-	pprofIndexPath   = "/debug/pprof/"
-	pprofCmdlinePath = "/debug/pprof/cmdline"
-	pprofProfilePath = "/debug/pprof/profile"
-	pprofSymbolPath  = "/debug/pprof/symbol"
-	pprofTracePath   = "/debug/pprof/trace"
-
-	gracefulShutdownTimeout = 5 * time.Second
-)
-
 // StartOpts represents options to be passed to main gorountine.
 type StartOpts struct {
-	Interrupt      chan os.Signal
+	Interrupt chan os.Signal
+
+	// Hangup receives the signals to reload the config, SIGHUP is relayed to it.
+	Hangup chan os.Signal
+
+	// ConfigSources are used to reload the config.
+	ConfigSources config.Sources
+
+	// LogLevel is the level of the logger changed on the config reload.
+	LogLevel zap.AtomicLevel
+
 	BuildGitCommit string
 	BuildGitTag    string
 	BuildDate      string
@@ -38,91 +32,55 @@ type StartOpts struct {
 }
 
 // StartService runs main service's goroutine.
-func StartService(log *zap.Logger, opts StartOpts) error {
-	if err := config.CheckConfig(); err != nil {
+func StartService(cfg *config.AppConfig, log *zap.Logger, opts StartOpts) error {
+	srv, err := server.New(cfg, server.Opts{
+		Log:      log,
+		LogLevel: opts.LogLevel,
+		ReloadConfig: func() (*config.AppConfig, error) {
+			return config.Load(opts.ConfigSources)
+		},
+		Build: server.BuildInfo{
+			GitCommit: opts.BuildGitCommit,
+			GitTag:    opts.BuildGitTag,
+			Date:      opts.BuildDate,
+			Compiler:  opts.BuildCompiler,
+		},
+	})
+	if err != nil {
 		return fmt.Errorf("failed to start service: %w", err)
 	}
 
-	// Init caching backend
-	b := backend.New(log)
-	defer b.Shutdown()
-
-	// Register service API handler
-	httpMux := http.NewServeMux()
-
-	// Register pprof handlers
-	httpMux.HandleFunc(pprofIndexPath, pprof.Index)
-	httpMux.HandleFunc(pprofCmdlinePath, pprof.Cmdline)
-	httpMux.HandleFunc(pprofProfilePath, pprof.Profile)
-	httpMux.HandleFunc(pprofSymbolPath, pprof.Symbol)
-	httpMux.HandleFunc(pprofTracePath, pprof.Trace)
-
-	// Configure Service API server
-	serviceAPIServer := &http.Server{
-		Addr: strings.Join([]string{
-			config.Config.ServiceAPI.ServerAddress,
-			strconv.Itoa(config.Config.ServiceAPI.ServerPort),
-		}, ":"),
-		ReadTimeout:  time.Duration(config.Config.ServiceAPI.ReadTimeout) * time.Second,
-		WriteTimeout: time.Duration(config.Config.ServiceAPI.WriteTimeout) * time.Second,
-		IdleTimeout:  time.Duration(config.Config.ServiceAPI.IdleTimeout) * time.Second,
-		Handler:      httpMux,
-	}
-
-	// Configure Public API server
-	publicAPIServer := &http.Server{
-		Addr: strings.Join([]string{
-			config.Config.PublicAPI.ServerAddress,
-			strconv.Itoa(config.Config.PublicAPI.ServerPort),
-		}, ":"),
-		ReadTimeout:  time.Duration(config.Config.PublicAPI.ReadTimeout) * time.Second,
-		WriteTimeout: time.Duration(config.Config.PublicAPI.WriteTimeout) * time.Second,
-		IdleTimeout:  time.Duration(config.Config.PublicAPI.IdleTimeout) * time.Second,
-		Handler:      public.InitAPIRouter(b),
-	}
-
 	log.Debug("wait for shutdown signals")
 	signal.Notify(opts.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 	defer signal.Stop(opts.Interrupt)
 
-	// Serve service API
-	go func() {
-		log.Info("running service API server", zap.String("addr", serviceAPIServer.Addr))
-		if err := serviceAPIServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatal("failed to serve service API", zap.Error(err))
-		}
-	}()
-
-	// Serve public API
-	go func() {
-		log.Info("running public API server", zap.String("addr", publicAPIServer.Addr))
-		if err := publicAPIServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatal("failed to serve public API", zap.Error(err))
-		}
-	}()
+	hangup := opts.Hangup
+	if hangup == nil {
+		hangup = make(chan os.Signal, 1)
+	}
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
 
-	sig := <-opts.Interrupt
-	log.Debug("got a signal", zap.Stringer("sig", sig))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
+	served := make(chan error, 1)
 	go func() {
-		// Context to shutdown service API-server
-		ctx, cancel := context.WithTimeout(context.Background(), gracefulShutdownTimeout)
-		defer cancel()
-
-		// Shutdown service API-server
-		if err := serviceAPIServer.Shutdown(ctx); err != nil {
-			log.Warn("service API server shutdown failed", zap.Error(err))
-		}
+		served <- srv.Serve(ctx, server.Listeners{})
 	}()
 
-	// Context to shutdown public API-server
-	ctx, cancel := context.WithTimeout(context.Background(), gracefulShutdownTimeout)
-	defer cancel()
-
-	// Shutdown public API-server
-	if err := publicAPIServer.Shutdown(ctx); err != nil {
-		log.Warn("public API server shutdown failed", zap.Error(err))
+	for {
+		select {
+		case <-hangup:
+			log.Info("reloading config")
+			_, _ = srv.Reload()
+		case sig := <-opts.Interrupt:
+			log.Debug("got a signal", zap.Stringer("sig", sig))
+			cancel()
+
+			return <-served
+		case err := <-served:
+			return err
+		}
 	}
-
-	return nil
 }
@@ -1,15 +1,26 @@
 package bookishspork
 
 import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
 	"sync"
 	"syscall"
 	"testing"
+	"time"
 
+	"github.com/dstdfx/bookish-spork/httpclient"
 	"github.com/dstdfx/bookish-spork/internal/pkg/config"
 	"github.com/dstdfx/bookish-spork/internal/pkg/log"
 	"github.com/dstdfx/bookish-spork/internal/pkg/testutils"
+	"github.com/dstdfx/bookish-spork/server"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 func TestStartService(t *testing.T) {
@@ -18,14 +29,15 @@ func TestStartService(t *testing.T) {
 		return
 	}
 
-	// Init global app configuration
-	testutils.InitTestConfig()
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
+	setFreePorts(t, cfg)
 
 	// Initialize logger
 	logger, err := log.InitLogger(log.InitLoggerOpts{
-		Debug:     config.Config.Log.Debug,
-		UseStdout: config.Config.Log.UseStdout,
-		File:      config.Config.Log.File,
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
 	})
 	assert.NoError(t, err)
 
@@ -35,7 +47,7 @@ func TestStartService(t *testing.T) {
 
 	go func(wg *sync.WaitGroup) {
 		defer wg.Done()
-		assert.NoError(t, StartService(logger, StartOpts{Interrupt: interrupt}))
+		assert.NoError(t, StartService(cfg, logger, StartOpts{Interrupt: interrupt}))
 	}(&wg)
 
 	// Send interrupt.
@@ -43,3 +55,257 @@ func TestStartService(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestStartService_TLS(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	dir, err := ioutil.TempDir("", "bookishspork")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := testutils.NewTestCA(t, dir)
+	serverCert := ca.IssueServerCert(t, dir, "server")
+	client := ca.IssueClientCert(t, dir, "test-app")
+
+	// Init app configuration with TLS and mTLS authentication of the public API
+	cfg := testutils.NewTestConfig()
+	setFreePorts(t, cfg)
+	cfg.PublicAPI.ServerAddress = "127.0.0.1"
+	cfg.PublicAPI.TLS.CertFile = serverCert.CertFile
+	cfg.PublicAPI.TLS.KeyFile = serverCert.KeyFile
+	cfg.PublicAPI.TLS.ClientCAFile = ca.CertFile
+	cfg.PublicAPI.TLS.ClientAuth = "verify_if_given"
+	cfg.PublicAPI.Auth.MTLS = true
+
+	// Initialize logger
+	logger, err := log.InitLogger(log.InitLoggerOpts{
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
+	})
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	interrupt := make(chan os.Signal, 1)
+
+	go func(wg *sync.WaitGroup) {
+		defer wg.Done()
+		assert.NoError(t, StartService(cfg, logger, StartOpts{Interrupt: interrupt}))
+	}(&wg)
+	defer wg.Wait()
+	defer func() {
+		interrupt <- syscall.SIGINT
+	}()
+
+	url := fmt.Sprintf("https://localhost:%d/v1/keys", cfg.PublicAPI.ServerPort)
+	get := func(cert *testutils.TestCert) (*http.Response, error) {
+		tlsConfig, err := httpclient.LoadTLSConfig("", "", ca.CertFile)
+		require.NoError(t, err)
+		if cert != nil {
+			c, err := tls.LoadX509KeyPair(cert.CertFile, cert.KeyFile)
+			require.NoError(t, err)
+			tlsConfig.Certificates = []tls.Certificate{c}
+		}
+
+		return httpclient.NewClientTLS(tlsConfig, "").HTTPClient.Get(url)
+	}
+
+	// Wait for the server to start
+	var resp *http.Response
+	for i := 0; i < 50; i++ {
+		if resp, err = get(client); err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, resp.ProtoMajor)
+
+	// Client without certificate is not authenticated
+	resp, err = get(nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+// setFreePorts sets free ports of all servers.
+func setFreePorts(t *testing.T, cfg *config.AppConfig) {
+	cfg.PublicAPI.ServerPort = freePort(t)
+	cfg.ServiceAPI.ServerPort = freePort(t)
+	cfg.GRPCAPI.ServerPort = freePort(t)
+}
+
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestStartService_Readiness(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
+	setFreePorts(t, cfg)
+	cfg.ServiceAPI.ShutdownDelay = 1
+
+	// Initialize logger
+	logger, err := log.InitLogger(log.InitLoggerOpts{
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
+	})
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	interrupt := make(chan os.Signal, 1)
+
+	go func(wg *sync.WaitGroup) {
+		defer wg.Done()
+		assert.NoError(t, StartService(cfg, logger, StartOpts{Interrupt: interrupt, BuildGitTag: "v1.0.0"}))
+	}(&wg)
+
+	serviceURL := fmt.Sprintf("http://127.0.0.1:%d", cfg.ServiceAPI.ServerPort)
+	publicURL := fmt.Sprintf("http://127.0.0.1:%d", cfg.PublicAPI.ServerPort)
+	statusOf := func(url string) int {
+		resp, err := http.Get(url)
+		if err != nil {
+			return 0
+		}
+		resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	// Wait for the service to become ready
+	for i := 0; i < 50 && statusOf(serviceURL+"/readyz") != http.StatusOK; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	require.Equal(t, http.StatusOK, statusOf(serviceURL+"/readyz"))
+	assert.Equal(t, http.StatusOK, statusOf(serviceURL+"/healthz"))
+
+	resp, err := http.Get(serviceURL + "/version")
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"git_tag":"v1.0.0"`)
+
+	resp, err = http.Get(serviceURL + "/info")
+	require.NoError(t, err)
+	body, err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"status":"ok"`)
+	assert.Contains(t, string(body), `"connected":{"grpc_api":0,"public_api":`)
+	assert.Contains(t, string(body), `"eviction_interval"`)
+
+	// Readiness fails during the shutdown delay while the public API is still served
+	interrupt <- syscall.SIGINT
+	for i := 0; i < 50 && statusOf(serviceURL+"/readyz") != http.StatusServiceUnavailable; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, http.StatusServiceUnavailable, statusOf(serviceURL+"/readyz"))
+	assert.Equal(t, http.StatusOK, statusOf(publicURL+"/v1/keys"))
+
+	wg.Wait()
+}
+
+func TestStartService_Reload(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	dir, err := ioutil.TempDir("", "reload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	servicePort, publicPort, grpcPort := freePort(t), freePort(t), freePort(t)
+	configFile := filepath.Join(dir, "bookish-spork.yaml")
+	writeConfig := func(debug bool, evictionInterval int) {
+		require.NoError(t, ioutil.WriteFile(configFile, []byte(fmt.Sprintf(`
+log:
+  use_stdout: true
+  debug: %t
+public_api:
+  server_port: %d
+service_api:
+  server_port: %d
+grpc_api:
+  server_port: %d
+cache:
+  eviction_interval: %d
+`, debug, publicPort, servicePort, grpcPort, evictionInterval)), 0600))
+	}
+	writeConfig(false, 60)
+
+	src := config.Sources{File: configFile}
+	cfg, err := config.Load(src)
+	require.NoError(t, err)
+
+	// Initialize logger
+	logLevel := zap.NewAtomicLevel()
+	logger, err := log.InitLogger(log.InitLoggerOpts{
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		Level:     logLevel,
+	})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	interrupt := make(chan os.Signal, 1)
+	hangup := make(chan os.Signal, 1)
+
+	go func(wg *sync.WaitGroup) {
+		defer wg.Done()
+		assert.NoError(t, StartService(cfg, logger, StartOpts{
+			Interrupt:     interrupt,
+			Hangup:        hangup,
+			ConfigSources: src,
+			LogLevel:      logLevel,
+		}))
+	}(&wg)
+	defer wg.Wait()
+	defer func() { interrupt <- syscall.SIGINT }()
+
+	serviceURL := fmt.Sprintf("http://127.0.0.1:%d", servicePort)
+	for i := 0; i < 50; i++ {
+		if resp, err := http.Get(serviceURL + "/readyz"); err == nil {
+			resp.Body.Close()
+
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// Reload on SIGHUP
+	writeConfig(true, 60)
+	hangup <- syscall.SIGHUP
+	require.Eventually(t, func() bool {
+		return logLevel.Level() == zap.DebugLevel
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Reload by the service API endpoint
+	writeConfig(true, 30)
+	resp, err := http.Post(serviceURL+server.ReloadPath, "", nil)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.JSONEq(t, `{"applied":["cache.eviction_interval"],"restart_required":[]}`, string(body))
+}
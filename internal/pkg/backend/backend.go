@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"errors"
 	"time"
 
 	"github.com/dstdfx/bookish-spork/internal/pkg/config"
@@ -8,16 +9,39 @@ import (
 	"go.uber.org/zap"
 )
 
+var ErrReadOnly = errors.New("writes are not allowed on replication follower, send them to the leader")
+
 // Backend contains common application dependencies.
 type Backend struct {
 	Log   *zap.Logger
 	Cache *qqcache.Cache
+
+	// ReadOnly reports whether the public APIs reject writes, it's set
+	// on replication followers as their cache is written by the leader.
+	ReadOnly bool
 }
 
 // New init new Backend instance.
-func New(log *zap.Logger) *Backend {
+func New(cfg config.CacheConfig, log *zap.Logger) *Backend {
 	opts := qqcache.Opts{
-		EvictionInterval: time.Duration(config.Config.Cache.EvictionInterval) * time.Second,
+		EvictionInterval: time.Duration(cfg.EvictionInterval) * time.Second,
+	}
+	if cfg := cfg.SlowLog; cfg.Enabled {
+		opts.SlowLog = qqcache.SlowLogOpts{
+			Threshold: time.Duration(cfg.Threshold) * time.Millisecond,
+			MaxLen:    cfg.MaxLen,
+		}
+		if cfg.Log {
+			opts.SlowLog.OnSlow = func(e qqcache.SlowLogEntry) {
+				log.Warn("slow cache command",
+					zap.String("command", e.Command),
+					zap.String("key", e.Key),
+					zap.Int("args", e.Args),
+					zap.Int64("args_bytes", e.ArgsBytes),
+					zap.Duration("duration", e.Duration),
+					zap.String("client", e.Client))
+			}
+		}
 	}
 
 	return &Backend{
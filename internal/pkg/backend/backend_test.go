@@ -3,7 +3,6 @@ package backend
 import (
 	"testing"
 
-	"github.com/dstdfx/bookish-spork/internal/pkg/config"
 	"github.com/dstdfx/bookish-spork/internal/pkg/log"
 	"github.com/dstdfx/bookish-spork/internal/pkg/testutils"
 	"github.com/stretchr/testify/assert"
@@ -15,18 +14,18 @@ func TestBackend(t *testing.T) {
 		return
 	}
 
-	// Init global app configuration
-	testutils.InitTestConfig()
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
 
 	// Initialize logger
 	logger, err := log.InitLogger(log.InitLoggerOpts{
-		Debug:     config.Config.Log.Debug,
-		UseStdout: config.Config.Log.UseStdout,
-		File:      config.Config.Log.File,
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
 	})
 	assert.NoError(t, err)
 
-	b := New(logger)
+	b := New(cfg.Cache, logger)
 	defer b.Shutdown()
 	assert.NotNil(t, b)
 }
@@ -0,0 +1,392 @@
+// Package bench provides a load generator that drives bookish-spork server
+// with a mix of reads and writes and reports throughput and latency percentiles.
+package bench
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Distributions of the keys.
+const (
+	KeyDistributionUniform = "uniform"
+	KeyDistributionZipf    = "zipf"
+)
+
+// Names of the operations in the report.
+const (
+	OpGet   = "get"
+	OpSet   = "set"
+	OpTotal = "total"
+)
+
+const (
+	// zipfS and zipfV are the parameters of Zipf distribution of the keys,
+	// about 2% of the keys get the half of requests with 10000 keys.
+	zipfS = 1.1
+	zipfV = 1
+
+	// valueAlphabet contains the characters of the generated values.
+	valueAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+var (
+	ErrNoTarget               = errors.New("target is required")
+	ErrInvalidConcurrency     = errors.New("concurrency must be positive")
+	ErrInvalidLimit           = errors.New("exactly one of requests or duration must be positive")
+	ErrInvalidKeys            = errors.New("number of keys must be positive")
+	ErrInvalidKeyDistribution = errors.New("unsupported key distribution, use one of: uniform, zipf")
+	ErrInvalidValueSize       = errors.New("value size must be non-negative")
+	ErrInvalidReadRatio       = errors.New("read ratio must be between 0 and 1")
+	ErrInvalidTTL             = errors.New("TTL must be non-negative and minimum TTL must not exceed maximum one")
+	ErrAllRequestsFailed      = errors.New("all requests failed")
+)
+
+// Target is the server under load.
+type Target interface {
+	// Get gets the value by key, found reports whether the key exists.
+	Get(ctx context.Context, key string) (found bool, err error)
+
+	// Set sets the value by key with TTL, the key is never expired if TTL is 0.
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+}
+
+// Opts represents the options of the benchmark.
+type Opts struct {
+	Target Target
+
+	// Concurrency is the number of workers sending requests one by one.
+	Concurrency int
+
+	// Requests is the total number of requests, Duration limits the time of the
+	// benchmark instead, exactly one of them must be set.
+	Requests int
+	Duration time.Duration
+
+	// Keys is the number of keys, they are named KeyPrefix followed by the number.
+	Keys      int
+	KeyPrefix string
+
+	// KeyDistribution is the distribution of the requested keys,
+	// KeyDistributionUniform is used if it's omitted.
+	KeyDistribution string
+
+	// ValueSize is the size of the written values in bytes.
+	ValueSize int
+
+	// ReadRatio is the share of reads in the requests from 0 to 1.
+	ReadRatio float64
+
+	// TTL of the written keys is uniformly distributed between TTLMin and TTLMax,
+	// keys are written without TTL if both are 0.
+	TTLMin time.Duration
+	TTLMax time.Duration
+
+	// Preload enables writing all keys before the benchmark, so the reads hit the keys.
+	Preload bool
+
+	// Seed initializes the random generators, the current time is used if it's 0.
+	Seed int64
+}
+
+// Report represents the results of the benchmark.
+type Report struct {
+	// Duration is the time of the benchmark without preloading.
+	Duration time.Duration `json:"duration"`
+
+	// Ops contains the results of the get and set operations and their total.
+	Ops []OpReport `json:"ops"`
+}
+
+// OpReport represents the results of the operation.
+type OpReport struct {
+	Name string `json:"name"`
+
+	// Count is the number of requests including the failed ones.
+	Count  uint64 `json:"count"`
+	Errors uint64 `json:"errors"`
+
+	// Misses is the number of reads of not existing keys.
+	Misses uint64 `json:"misses"`
+
+	// Throughput is the number of requests per second.
+	Throughput float64 `json:"throughput"`
+
+	// Latency is the summary of the latency of the successful requests.
+	Latency Latency `json:"latency"`
+
+	// Error is the first error of the requests.
+	Error string `json:"error,omitempty"`
+}
+
+// Latency represents the summary of the requests latency.
+type Latency struct {
+	Min  time.Duration `json:"min"`
+	Mean time.Duration `json:"mean"`
+	P50  time.Duration `json:"p50"`
+	P90  time.Duration `json:"p90"`
+	P99  time.Duration `json:"p99"`
+	P999 time.Duration `json:"p999"`
+	Max  time.Duration `json:"max"`
+}
+
+// validate method checks the options and sets the defaults.
+func (o *Opts) validate() error {
+	if o.KeyDistribution == "" {
+		o.KeyDistribution = KeyDistributionUniform
+	}
+	if o.Seed == 0 {
+		o.Seed = time.Now().UnixNano()
+	}
+
+	switch {
+	case o.Target == nil:
+		return ErrNoTarget
+	case o.Concurrency <= 0:
+		return ErrInvalidConcurrency
+	case o.Requests < 0 || o.Duration < 0 || (o.Requests > 0) == (o.Duration > 0):
+		return ErrInvalidLimit
+	case o.Keys <= 0:
+		return ErrInvalidKeys
+	case o.KeyDistribution != KeyDistributionUniform && o.KeyDistribution != KeyDistributionZipf:
+		return ErrInvalidKeyDistribution
+	case o.ValueSize < 0:
+		return ErrInvalidValueSize
+	case o.ReadRatio < 0 || o.ReadRatio > 1:
+		return ErrInvalidReadRatio
+	case o.TTLMin < 0 || o.TTLMin > o.TTLMax:
+		return ErrInvalidTTL
+	}
+
+	return nil
+}
+
+// Run runs the benchmark until the requests are sent, the duration
+// is passed or the context is done.
+// ErrAllRequestsFailed is returned with the report if no request has succeeded.
+func Run(ctx context.Context, opts Opts) (*Report, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	if opts.Preload {
+		if err := preload(ctx, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	workers := make([]*worker, opts.Concurrency)
+	for i := range workers {
+		workers[i] = newWorker(opts, opts.Seed+int64(i))
+	}
+
+	var (
+		wg      sync.WaitGroup
+		started = time.Now()
+		sent    int64
+	)
+	next := func() bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		if opts.Requests > 0 {
+			return atomic.AddInt64(&sent, 1) <= int64(opts.Requests)
+		}
+
+		return time.Since(started) < opts.Duration
+	}
+
+	wg.Add(len(workers))
+	for _, w := range workers {
+		go func(w *worker) {
+			defer wg.Done()
+
+			for next() {
+				w.do(ctx)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	report := newReport(time.Since(started), workers)
+	if total := report.Ops[len(report.Ops)-1]; total.Count > 0 && total.Errors == total.Count {
+		return report, ErrAllRequestsFailed
+	}
+
+	return report, ctx.Err()
+}
+
+// preload writes all keys with the workers.
+func preload(ctx context.Context, opts Opts) error {
+	var (
+		wg       sync.WaitGroup
+		next     int64 = -1
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	wg.Add(opts.Concurrency)
+	for i := 0; i < opts.Concurrency; i++ {
+		go func(w *worker) {
+			defer wg.Done()
+
+			for {
+				n := atomic.AddInt64(&next, 1)
+				if n >= int64(opts.Keys) || ctx.Err() != nil {
+					return
+				}
+				if err := opts.Target.Set(ctx, w.key(int(n)), w.value, w.ttl()); err != nil {
+					errOnce.Do(func() { firstErr = err })
+
+					return
+				}
+			}
+		}(newWorker(opts, opts.Seed-int64(i)-1))
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	return ctx.Err()
+}
+
+// worker sends the requests and records their results.
+type worker struct {
+	opts  Opts
+	rnd   *rand.Rand
+	zipf  *rand.Zipf
+	value string
+
+	get opStats
+	set opStats
+}
+
+// opStats contains the results of the operation recorded by the worker.
+type opStats struct {
+	latency  histogram
+	errors   uint64
+	misses   uint64
+	firstErr error
+}
+
+func newWorker(opts Opts, seed int64) *worker {
+	w := &worker{opts: opts, rnd: rand.New(rand.NewSource(seed))}
+	if opts.KeyDistribution == KeyDistributionZipf {
+		w.zipf = rand.NewZipf(w.rnd, zipfS, zipfV, uint64(opts.Keys-1))
+	}
+
+	value := make([]byte, opts.ValueSize)
+	for i := range value {
+		value[i] = valueAlphabet[w.rnd.Intn(len(valueAlphabet))]
+	}
+	w.value = string(value)
+
+	return w
+}
+
+// do method sends the request to the random key.
+func (w *worker) do(ctx context.Context) {
+	var key string
+	if w.zipf != nil {
+		key = w.key(int(w.zipf.Uint64()))
+	} else {
+		key = w.key(w.rnd.Intn(w.opts.Keys))
+	}
+
+	if w.rnd.Float64() < w.opts.ReadRatio {
+		started := time.Now()
+		found, err := w.opts.Target.Get(ctx, key)
+		w.get.record(time.Since(started), err)
+		if err == nil && !found {
+			w.get.misses++
+		}
+
+		return
+	}
+
+	started := time.Now()
+	err := w.opts.Target.Set(ctx, key, w.value, w.ttl())
+	w.set.record(time.Since(started), err)
+}
+
+func (w *worker) key(n int) string {
+	return w.opts.KeyPrefix + strconv.Itoa(n)
+}
+
+// ttl method returns random TTL of the written key.
+func (w *worker) ttl() time.Duration {
+	if w.opts.TTLMax == w.opts.TTLMin {
+		return w.opts.TTLMin
+	}
+
+	return w.opts.TTLMin + time.Duration(w.rnd.Int63n(int64(w.opts.TTLMax-w.opts.TTLMin)+1))
+}
+
+// record method records the result of the request, latency is recorded for successful requests.
+func (s *opStats) record(latency time.Duration, err error) {
+	if err != nil {
+		s.errors++
+		if s.firstErr == nil {
+			s.firstErr = err
+		}
+
+		return
+	}
+	s.latency.record(latency)
+}
+
+// merge method adds the results recorded by other worker.
+func (s *opStats) merge(other *opStats) {
+	s.latency.merge(&other.latency)
+	s.errors += other.errors
+	s.misses += other.misses
+	if s.firstErr == nil {
+		s.firstErr = other.firstErr
+	}
+}
+
+// report method returns the report of the operation.
+func (s *opStats) report(name string, duration time.Duration) OpReport {
+	r := OpReport{
+		Name:    name,
+		Count:   s.latency.count + s.errors,
+		Errors:  s.errors,
+		Misses:  s.misses,
+		Latency: s.latency.latency(),
+	}
+	if duration > 0 {
+		r.Throughput = float64(r.Count) / duration.Seconds()
+	}
+	if s.firstErr != nil {
+		r.Error = s.firstErr.Error()
+	}
+
+	return r
+}
+
+// newReport returns the report of the results recorded by the workers.
+func newReport(duration time.Duration, workers []*worker) *Report {
+	var get, set, total opStats
+	for _, w := range workers {
+		get.merge(&w.get)
+		set.merge(&w.set)
+	}
+	total.merge(&get)
+	total.merge(&set)
+
+	return &Report{
+		Duration: duration,
+		Ops: []OpReport{
+			get.report(OpGet, duration),
+			set.report(OpSet, duration),
+			total.report(OpTotal, duration),
+		},
+	}
+}
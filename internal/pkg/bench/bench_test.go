@@ -0,0 +1,163 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testTarget is in-memory target that records the requests.
+type testTarget struct {
+	mu     sync.Mutex
+	values map[string]string
+	ttls   map[string]time.Duration
+	gets   int
+	sets   int
+	err    error
+}
+
+func newTestTarget() *testTarget {
+	return &testTarget{values: make(map[string]string), ttls: make(map[string]time.Duration)}
+}
+
+func (t *testTarget) Get(_ context.Context, key string) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.gets++
+	_, ok := t.values[key]
+
+	return ok, t.err
+}
+
+func (t *testTarget) Set(_ context.Context, key, value string, ttl time.Duration) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sets++
+	if t.err != nil {
+		return t.err
+	}
+	t.values[key] = value
+	t.ttls[key] = ttl
+
+	return nil
+}
+
+func getTestOpts(target Target) Opts {
+	return Opts{
+		Target:      target,
+		Concurrency: 4,
+		Requests:    1000,
+		Keys:        100,
+		KeyPrefix:   "bench:",
+		ValueSize:   10,
+		ReadRatio:   0.8,
+		Seed:        1,
+	}
+}
+
+func TestRun(t *testing.T) {
+	target := newTestTarget()
+	opts := getTestOpts(target)
+	opts.Preload = true
+	opts.TTLMin = time.Second
+	opts.TTLMax = 10 * time.Second
+
+	report, err := Run(context.Background(), opts)
+	require.NoError(t, err)
+	require.Len(t, report.Ops, 3)
+
+	get, set, total := report.Ops[0], report.Ops[1], report.Ops[2]
+	require.Equal(t, OpGet, get.Name)
+	require.Equal(t, OpSet, set.Name)
+	require.Equal(t, OpTotal, total.Name)
+
+	// Preloaded keys are not counted
+	require.Equal(t, uint64(1000), total.Count)
+	require.Equal(t, total.Count, get.Count+set.Count)
+	require.Equal(t, uint64(target.gets), get.Count)
+	require.Equal(t, uint64(target.sets), set.Count+100)
+	require.InDelta(t, 800, get.Count, 100)
+	require.Zero(t, total.Errors)
+	require.Zero(t, get.Misses)
+	require.True(t, total.Throughput > 0)
+	require.True(t, total.Latency.Max >= total.Latency.P50)
+
+	require.Len(t, target.values, 100)
+	for key, value := range target.values {
+		require.Regexp(t, `^bench:\d+$`, key)
+		require.Len(t, value, 10)
+		require.True(t, target.ttls[key] >= time.Second && target.ttls[key] <= 10*time.Second)
+	}
+}
+
+func TestRun_Duration(t *testing.T) {
+	target := newTestTarget()
+	opts := getTestOpts(target)
+	opts.Requests = 0
+	opts.Duration = 50 * time.Millisecond
+	opts.KeyDistribution = KeyDistributionZipf
+	opts.ReadRatio = 1
+
+	report, err := Run(context.Background(), opts)
+	require.NoError(t, err)
+	require.True(t, report.Duration >= 50*time.Millisecond)
+	require.True(t, report.Ops[0].Count > 0)
+	require.Zero(t, report.Ops[1].Count)
+
+	// Nothing is written, so all reads are missed
+	require.Equal(t, report.Ops[0].Count, report.Ops[0].Misses)
+}
+
+func TestRun_Errors(t *testing.T) {
+	target := newTestTarget()
+	target.err = errors.New("connection refused")
+
+	report, err := Run(context.Background(), getTestOpts(target))
+	require.True(t, errors.Is(err, ErrAllRequestsFailed))
+	require.Equal(t, uint64(1000), report.Ops[2].Errors)
+	require.Equal(t, "connection refused", report.Ops[2].Error)
+	require.Equal(t, Latency{}, report.Ops[2].Latency)
+
+	// Preloading is stopped by the first error
+	opts := getTestOpts(target)
+	opts.Preload = true
+	_, err = Run(context.Background(), opts)
+	require.Equal(t, target.err, err)
+
+	// Cancelled context stops the benchmark
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err = Run(ctx, getTestOpts(newTestTarget()))
+	require.Equal(t, context.Canceled, err)
+	require.Zero(t, report.Ops[2].Count)
+}
+
+func TestRun_InvalidOpts(t *testing.T) {
+	tests := []struct {
+		modify   func(o *Opts)
+		expected error
+	}{
+		{modify: func(o *Opts) { o.Target = nil }, expected: ErrNoTarget},
+		{modify: func(o *Opts) { o.Concurrency = 0 }, expected: ErrInvalidConcurrency},
+		{modify: func(o *Opts) { o.Requests = 0 }, expected: ErrInvalidLimit},
+		{modify: func(o *Opts) { o.Duration = time.Second }, expected: ErrInvalidLimit},
+		{modify: func(o *Opts) { o.Keys = 0 }, expected: ErrInvalidKeys},
+		{modify: func(o *Opts) { o.KeyDistribution = "normal" }, expected: ErrInvalidKeyDistribution},
+		{modify: func(o *Opts) { o.ValueSize = -1 }, expected: ErrInvalidValueSize},
+		{modify: func(o *Opts) { o.ReadRatio = 1.5 }, expected: ErrInvalidReadRatio},
+		{modify: func(o *Opts) { o.TTLMin = time.Minute }, expected: ErrInvalidTTL},
+	}
+
+	for _, tc := range tests {
+		opts := getTestOpts(newTestTarget())
+		tc.modify(&opts)
+		_, err := Run(context.Background(), opts)
+		require.Equal(t, tc.expected, err)
+	}
+}
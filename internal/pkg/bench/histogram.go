@@ -0,0 +1,131 @@
+package bench
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// subBucketBits is the number of bits of the value kept by the histogram,
+// every power of two range is split into 2^subBucketBits linear buckets,
+// so the relative error of the recorded values is below 1/64.
+const (
+	subBucketBits = 6
+	subBuckets    = 1 << subBucketBits
+)
+
+// numBuckets is the number of buckets to record any non-negative int64.
+const numBuckets = (64-subBucketBits)*subBuckets + subBuckets
+
+// histogram records latencies in log-linear buckets, so memory doesn't
+// depend on the number of the recorded values.
+type histogram struct {
+	counts [numBuckets]uint64
+	count  uint64
+	sum    time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+// bucketIndex returns the index of the bucket of the value.
+func bucketIndex(v int64) int {
+	if v < subBuckets {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - subBucketBits - 1
+
+	return shift*subBuckets + int(v>>uint(shift))
+}
+
+// bucketValue returns the middle of the bucket range.
+func bucketValue(i int) int64 {
+	if i < subBuckets {
+		return int64(i)
+	}
+	shift := uint(i/subBuckets - 1)
+	lower := int64(i-int(shift)*subBuckets) << shift
+
+	return lower + (int64(1)<<shift)/2
+}
+
+// record method adds the value to the histogram.
+func (h *histogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.counts[bucketIndex(int64(d))]++
+	h.count++
+	h.sum += d
+}
+
+// merge method adds the values recorded by the other histogram.
+func (h *histogram) merge(other *histogram) {
+	if other.count == 0 {
+		return
+	}
+	if h.count == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+	h.count += other.count
+	h.sum += other.sum
+}
+
+// percentile method returns the value below which the given percent of values fall.
+func (h *histogram) percentile(p float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(p / 100 * float64(h.count)))
+	if rank == 0 {
+		rank = 1
+	}
+
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			// The bucket value is limited by the exact minimum and maximum
+			v := time.Duration(bucketValue(i))
+			if v < h.min {
+				v = h.min
+			}
+			if v > h.max {
+				v = h.max
+			}
+
+			return v
+		}
+	}
+
+	return h.max
+}
+
+// latency method returns the summary of the recorded values.
+func (h *histogram) latency() Latency {
+	if h.count == 0 {
+		return Latency{}
+	}
+
+	return Latency{
+		Min:  h.min,
+		Mean: h.sum / time.Duration(h.count),
+		P50:  h.percentile(50),
+		P90:  h.percentile(90),
+		P99:  h.percentile(99),
+		P999: h.percentile(99.9),
+		Max:  h.max,
+	}
+}
@@ -0,0 +1,69 @@
+package bench
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketIndex(t *testing.T) {
+	for _, v := range []int64{0, 1, 63, 64, 65, 127, 128, 1000, 123456789, 1<<62 + 12345, 1<<63 - 1} {
+		i := bucketIndex(v)
+		require.True(t, i >= 0 && i < numBuckets, v)
+
+		// The bucket value is close to the recorded value
+		require.InEpsilon(t, float64(v)+1, float64(bucketValue(i))+1, 1.0/subBuckets, v)
+	}
+
+	// Buckets are ordered as values
+	prev := -1
+	for v := int64(0); v < 100000; v++ {
+		i := bucketIndex(v)
+		require.True(t, i >= prev)
+		prev = i
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	var h histogram
+	require.Equal(t, Latency{}, h.latency())
+
+	for i := 1; i <= 1000; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+
+	l := h.latency()
+	require.Equal(t, time.Millisecond, l.Min)
+	require.Equal(t, time.Second, l.Max)
+	require.Equal(t, 500500*time.Microsecond, l.Mean)
+	require.InEpsilon(t, float64(500*time.Millisecond), float64(l.P50), 1.0/subBuckets)
+	require.InEpsilon(t, float64(900*time.Millisecond), float64(l.P90), 1.0/subBuckets)
+	require.InEpsilon(t, float64(990*time.Millisecond), float64(l.P99), 1.0/subBuckets)
+	require.InEpsilon(t, float64(999*time.Millisecond), float64(l.P999), 1.0/subBuckets)
+
+	// Percentiles are limited by the recorded values
+	var single histogram
+	single.record(1234567)
+	require.Equal(t, Latency{Min: 1234567, Mean: 1234567, P50: 1234567, P90: 1234567,
+		P99: 1234567, P999: 1234567, Max: 1234567}, single.latency())
+}
+
+func TestHistogram_Merge(t *testing.T) {
+	var a, b, expected histogram
+	for i := 1; i <= 100; i++ {
+		d := time.Duration(i) * time.Microsecond
+		if i%2 == 0 {
+			a.record(d)
+		} else {
+			b.record(d)
+		}
+		expected.record(d)
+	}
+
+	var merged histogram
+	merged.merge(&a)
+	merged.merge(&histogram{})
+	merged.merge(&b)
+	require.Equal(t, expected, merged)
+}
@@ -1,108 +1,304 @@
 package config
 
-import (
-	"errors"
-	"io/ioutil"
-	"log"
-
-	yaml "gopkg.in/yaml.v2"
-)
-
 const (
-	// errGlobalConfig represents error message in case of empty global config.
-	errGlobalConfig = "global configuration is not initialized"
-
 	defaultPublicAPIAddress = "127.0.0.1"
 	defaultPublicAPIPort    = 63100
 
 	defaultServiceAPIAddress = "127.0.0.1"
 	defaultServiceAPIPort    = 63101
 
+	defaultGRPCAPIAddress = "127.0.0.1"
+	defaultGRPCAPIPort    = 63102
+
 	defaultHTTPReadTimeout  = 60
 	defaultHTTPWriteTimeout = 120
 	defaultHTTPIdleTimeout  = 240
+	defaultMaxBodySize      = 8 << 20
 	defaultEvictionInterval = 60
-)
 
-// Config is a global container for all configuration options.
-var Config *AppConfig
+	defaultSlowLogThreshold = 10
+	defaultSlowLogMaxLen    = 128
+
+	defaultAuthReloadInterval   = 10
+	defaultAuthHMACMaxClockSkew = 300
+
+	defaultTLSMinVersion     = "1.2"
+	defaultTLSReloadInterval = 10
+
+	defaultRateLimitKeyBy = "identity"
+
+	defaultAccessLogLevel = "info"
+
+	defaultTracingServiceName   = "bookish-spork"
+	defaultTracingTimeout       = 10
+	defaultTracingFlushInterval = 5
+
+	defaultReplicationBacklogSize       = 1 << 20
+	defaultReplicationTimeout           = 10
+	defaultReplicationReconnectInterval = 1
+)
 
 // AppConfig contains all application parameters.
 type AppConfig struct {
-	Log        LogConfig              `yaml:"log"`
-	PublicAPI  PublicAPIServerConfig  `yaml:"public_api"`
-	ServiceAPI ServiceAPIServerConfig `yaml:"service_api"`
-	Cache      CacheConfig            `yaml:"cache"`
+	Log         LogConfig              `yaml:"log"`
+	PublicAPI   PublicAPIServerConfig  `yaml:"public_api"`
+	ServiceAPI  ServiceAPIServerConfig `yaml:"service_api"`
+	GRPCAPI     GRPCAPIServerConfig    `yaml:"grpc_api"`
+	Cache       CacheConfig            `yaml:"cache"`
+	Tracing     TracingConfig          `yaml:"tracing"`
+	Replication ReplicationConfig      `yaml:"replication"`
 }
 
 // LogConfig contains logger configuration.
 type LogConfig struct {
-	File      string `yaml:"file"`
-	UseStdout bool   `yaml:"use_stdout"`
-	Debug     bool   `yaml:"debug"`
+	File      string          `yaml:"file"`
+	UseStdout bool            `yaml:"use_stdout"`
+	Debug     bool            `yaml:"debug"`
+	Access    AccessLogConfig `yaml:"access"`
+}
+
+// AccessLogConfig contains configuration of the public API access log.
+type AccessLogConfig struct {
+	// Level is the level of the requests log entries: debug, info, warn,
+	// error or none to disable logging.
+	Level string `yaml:"level"`
+
+	// SampleRate is the fraction of the logged requests from 0 to 1,
+	// all requests are logged if it's 0. Requests failed with 5xx status
+	// are always logged.
+	SampleRate float64 `yaml:"sample_rate"`
+
+	// Routes contains levels by "<METHOD> <route pattern>", e.g. "GET /v1/keys".
+	Routes map[string]string `yaml:"routes"`
 }
 
 // PublicAPIServerConfig contains configuration to provide public REST API.
 type PublicAPIServerConfig struct {
-	ServerAddress string `yaml:"server_address"`
-	ServerPort    int    `yaml:"server_port"`
-	ReadTimeout   int    `yaml:"read_timeout"`
-	WriteTimeout  int    `yaml:"write_timeout"`
-	IdleTimeout   int    `yaml:"idle_timeout"`
+	ServerAddress string          `yaml:"server_address"`
+	ServerPort    int             `yaml:"server_port"`
+	ReadTimeout   int             `yaml:"read_timeout"`
+	WriteTimeout  int             `yaml:"write_timeout"`
+	IdleTimeout   int             `yaml:"idle_timeout"`
+	TLS           TLSConfig       `yaml:"tls"`
+	Auth          AuthConfig      `yaml:"auth"`
+	ACL           ACLConfig       `yaml:"acl"`
+	RateLimit     RateLimitConfig `yaml:"rate_limit"`
+
+	// MaxBodySize is the max size (in bytes) of the request bodies.
+	MaxBodySize int `yaml:"max_body_size"`
+}
+
+// TLSConfig contains TLS configuration of HTTP server.
+// TLS is disabled if the certificate file is not set.
+type TLSConfig struct {
+	// CertFile and KeyFile are the paths to PEM-encoded server certificate and key.
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+
+	// ClientCAFile is the path to PEM-encoded CA certificates to verify client certificates.
+	ClientCAFile string `yaml:"client_ca_file"`
+
+	// ClientAuth is the client certificate verification mode:
+	// none, request, verify_if_given or require.
+	ClientAuth string `yaml:"client_auth"`
+
+	// MinVersion is the minimum TLS version: 1.0, 1.1, 1.2 or 1.3.
+	MinVersion string `yaml:"min_version"`
+
+	// CipherSuites contains the names of allowed TLS 1.2 cipher suites.
+	CipherSuites []string `yaml:"cipher_suites"`
+
+	// ReloadInterval is how often (in seconds) the files are checked for changes.
+	ReloadInterval int `yaml:"reload_interval"`
+}
+
+// AuthConfig contains public API authentication configuration.
+// Authentication is disabled if none of the methods is configured.
+type AuthConfig struct {
+	// TokensFile is the path to the file with "<name>:<token>" bearer tokens.
+	TokensFile string `yaml:"tokens_file"`
+
+	// HMACKeysFile is the path to the file with "<key id>:<secret>" HMAC keys.
+	HMACKeysFile string `yaml:"hmac_keys_file"`
+
+	// HMACMaxClockSkew is the max difference (in seconds) between HMAC-signed
+	// request timestamp and the server time.
+	HMACMaxClockSkew int `yaml:"hmac_max_clock_skew"`
+
+	// MTLS enables authentication by verified client certificates.
+	MTLS bool `yaml:"mtls"`
+
+	// ReloadInterval is how often (in seconds) files are checked for changes.
+	ReloadInterval int `yaml:"reload_interval"`
+}
+
+// ACLConfig contains public API access control configuration.
+// Access control is disabled if no roles are defined.
+type ACLConfig struct {
+	// Roles contains access rules by role names.
+	Roles map[string][]ACLRuleConfig `yaml:"roles"`
+
+	// Users contains role names by names of tokens, HMAC key IDs
+	// or common names of client certificates.
+	Users map[string][]string `yaml:"users"`
+
+	// DefaultRoles are granted to the clients that are not listed in Users.
+	DefaultRoles []string `yaml:"default_roles"`
+}
+
+// ACLRuleConfig grants permissions (read, write, admin) and commands
+// on the keys that match any of the glob patterns.
+type ACLRuleConfig struct {
+	Keys        []string `yaml:"keys"`
+	Permissions []string `yaml:"permissions"`
+	Commands    []string `yaml:"commands"`
+}
+
+// RateLimitConfig contains public API rate limiting configuration.
+// Rate limiting is disabled if no limits are defined.
+type RateLimitConfig struct {
+	// KeyBy defines how the clients are identified: identity (falls back to IP
+	// for unauthenticated clients) or ip.
+	KeyBy string `yaml:"key_by"`
+
+	// Default limits all requests of the client.
+	Default RateLimit `yaml:"default"`
+
+	// Classes contains limits by command classes: read and write.
+	Classes map[string]RateLimit `yaml:"classes"`
+
+	// Routes contains limits by "<METHOD> <route pattern>", e.g. "POST /v1/set".
+	Routes map[string]RateLimit `yaml:"routes"`
+
+	// AuthFailures limits failed authentication attempts per remote IP,
+	// the default limit is used if it's not set.
+	AuthFailures RateLimit `yaml:"auth_failures"`
+}
+
+// RateLimit contains token bucket parameters, rate is the number of requests
+// per second and burst is the max number of requests at once.
+type RateLimit struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
 }
 
 // ServiceAPIServerConfig contains configuration to provide service REST API.
 type ServiceAPIServerConfig struct {
+	ServerAddress string    `yaml:"server_address"`
+	ServerPort    int       `yaml:"server_port"`
+	ReadTimeout   int       `yaml:"read_timeout"`
+	WriteTimeout  int       `yaml:"write_timeout"`
+	IdleTimeout   int       `yaml:"idle_timeout"`
+	TLS           TLSConfig `yaml:"tls"`
+
+	// Auth protects the endpoints that read or change all data: dump, restore,
+	// reload and replication sync.
+	Auth ServiceAPIAuthConfig `yaml:"auth"`
+
+	// ShutdownDelay is how long (in seconds) the servers keep serving requests
+	// after the readiness check starts failing on shutdown.
+	ShutdownDelay int `yaml:"shutdown_delay"`
+}
+
+// ServiceAPIAuthConfig contains service API authentication configuration.
+// Clients are authenticated by bearer tokens and by client certificates
+// verified with service_api.tls.client_ca_file.
+type ServiceAPIAuthConfig struct {
+	// TokensFile is the path to the file with "<name>:<token>" bearer tokens.
+	TokensFile string `yaml:"tokens_file"`
+
+	// ReloadInterval is how often (in seconds) the file is checked for changes.
+	ReloadInterval int `yaml:"reload_interval"`
+}
+
+// GRPCAPIServerConfig contains configuration to provide gRPC API.
+type GRPCAPIServerConfig struct {
 	ServerAddress string `yaml:"server_address"`
 	ServerPort    int    `yaml:"server_port"`
-	ReadTimeout   int    `yaml:"read_timeout"`
-	WriteTimeout  int    `yaml:"write_timeout"`
-	IdleTimeout   int    `yaml:"idle_timeout"`
 }
 
 // CacheConfig contains cache related configuration.
 type CacheConfig struct {
-	EvictionInterval int `yaml:"eviction_interval"`
+	EvictionInterval int           `yaml:"eviction_interval"`
+	SlowLog          SlowLogConfig `yaml:"slow_log"`
 }
 
-// CheckConfig helps to check if global application config is ready.
-func CheckConfig() error {
-	if Config == nil {
-		return errors.New(errGlobalConfig)
-	}
+// SlowLogConfig contains configuration of the log of slow cache commands.
+type SlowLogConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Threshold is the minimum duration (in milliseconds) of the commands to be recorded.
+	Threshold int `yaml:"threshold"`
+
+	// MaxLen is the maximum number of recorded commands, the oldest are dropped.
+	MaxLen int `yaml:"max_len"`
 
-	return nil
+	// Log enables logging of the recorded commands.
+	Log bool `yaml:"log"`
 }
 
-// InitFromFile reads config file and initializes global config.
-func InitFromFile(path string) error {
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return err
-	}
+// TracingConfig contains configuration of the spans export.
+// Tracing is disabled if the endpoint is not set.
+type TracingConfig struct {
+	// Endpoint is the base URL of OTLP/HTTP collector, e.g. http://localhost:4318.
+	Endpoint string `yaml:"endpoint"`
 
-	if err := initFromString(data); err != nil {
-		return err
-	}
+	// Headers are added to the export requests.
+	Headers map[string]string `yaml:"headers"`
 
-	log.Printf("Config loaded from: %s", path)
+	// ServiceName is the name of the service reported to the collector.
+	ServiceName string `yaml:"service_name"`
 
-	return nil
+	// Timeout is the timeout (in seconds) of the export requests.
+	Timeout int `yaml:"timeout"`
+
+	// FlushInterval is how often (in seconds) the spans are exported.
+	FlushInterval int `yaml:"flush_interval"`
 }
 
-// initFromString reads raw string and initializes global config.
-func initFromString(data []byte) error {
-	cfg := AppConfig{}
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return err
-	}
+// ReplicationConfig contains configuration of the leader-follower replication.
+// The server is the leader unless the leader is set.
+type ReplicationConfig struct {
+	// Leader is the service API URL of the leader, e.g. http://10.0.0.1:63101.
+	// The server runs as a read-only follower of the leader if it's set.
+	Leader string `yaml:"leader"`
 
-	Config = &cfg
+	// BacklogSize is the size (in bytes) of the latest write operations kept
+	// by the leader for partial resync of the reconnecting followers.
+	BacklogSize int `yaml:"backlog_size"`
+
+	// Timeout is how long (in seconds) the follower waits for data from
+	// the leader before reconnecting, the leader sends heartbeats every second.
+	Timeout int `yaml:"timeout"`
+
+	// ReconnectInterval is the delay (in seconds) before the follower
+	// reconnects to the leader after a failure.
+	ReconnectInterval int `yaml:"reconnect_interval"`
+
+	// CAFile is the path to PEM-encoded CA certificates to verify the leader,
+	// CertFile and KeyFile are the paths to PEM-encoded client certificate and key.
+	CAFile   string `yaml:"ca_file"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
 
+// setDefaults sets default values of the omitted parameters,
+// zero values are treated as omitted.
+func setDefaults(cfg *AppConfig) {
 	// Set default string parameters if omitted.
 	defaultStringParameters := map[*string]string{
-		&Config.PublicAPI.ServerAddress:  defaultPublicAPIAddress,
-		&Config.ServiceAPI.ServerAddress: defaultServiceAPIAddress,
+		&cfg.PublicAPI.ServerAddress:  defaultPublicAPIAddress,
+		&cfg.ServiceAPI.ServerAddress: defaultServiceAPIAddress,
+		&cfg.GRPCAPI.ServerAddress:    defaultGRPCAPIAddress,
+		// TLS defaults
+		&cfg.PublicAPI.TLS.MinVersion:  defaultTLSMinVersion,
+		&cfg.ServiceAPI.TLS.MinVersion: defaultTLSMinVersion,
+		// Rate limit defaults
+		&cfg.PublicAPI.RateLimit.KeyBy: defaultRateLimitKeyBy,
+		// Access log defaults
+		&cfg.Log.Access.Level: defaultAccessLogLevel,
+		// Tracing defaults
+		&cfg.Tracing.ServiceName: defaultTracingServiceName,
 	}
 	for currentValue, defaultValue := range defaultStringParameters {
 		setDefaultStringValue(currentValue, defaultValue)
@@ -111,27 +307,46 @@ func initFromString(data []byte) error {
 	// Set default int parameters if omitted.
 	defaultIntParameters := map[*int]int{
 		// Public API defaults
-		&Config.PublicAPI.ServerPort:   defaultPublicAPIPort,
-		&Config.PublicAPI.ReadTimeout:  defaultHTTPReadTimeout,
-		&Config.PublicAPI.WriteTimeout: defaultHTTPWriteTimeout,
-		&Config.PublicAPI.IdleTimeout:  defaultHTTPIdleTimeout,
+		&cfg.PublicAPI.ServerPort:   defaultPublicAPIPort,
+		&cfg.PublicAPI.ReadTimeout:  defaultHTTPReadTimeout,
+		&cfg.PublicAPI.WriteTimeout: defaultHTTPWriteTimeout,
+		&cfg.PublicAPI.IdleTimeout:  defaultHTTPIdleTimeout,
+		&cfg.PublicAPI.MaxBodySize:  defaultMaxBodySize,
+		// Public API auth defaults
+		&cfg.PublicAPI.Auth.ReloadInterval:   defaultAuthReloadInterval,
+		&cfg.PublicAPI.Auth.HMACMaxClockSkew: defaultAuthHMACMaxClockSkew,
+		// TLS defaults
+		&cfg.PublicAPI.TLS.ReloadInterval:  defaultTLSReloadInterval,
+		&cfg.ServiceAPI.TLS.ReloadInterval: defaultTLSReloadInterval,
 		// ServiceAPI defaults
-		&Config.ServiceAPI.ServerPort:   defaultServiceAPIPort,
-		&Config.ServiceAPI.ReadTimeout:  defaultHTTPReadTimeout,
-		&Config.ServiceAPI.WriteTimeout: defaultHTTPWriteTimeout,
-		&Config.ServiceAPI.IdleTimeout:  defaultHTTPIdleTimeout,
+		&cfg.ServiceAPI.ServerPort:   defaultServiceAPIPort,
+		&cfg.ServiceAPI.ReadTimeout:  defaultHTTPReadTimeout,
+		&cfg.ServiceAPI.WriteTimeout: defaultHTTPWriteTimeout,
+		&cfg.ServiceAPI.IdleTimeout:  defaultHTTPIdleTimeout,
+		// ServiceAPI auth defaults
+		&cfg.ServiceAPI.Auth.ReloadInterval: defaultAuthReloadInterval,
+		// gRPC API defaults
+		&cfg.GRPCAPI.ServerPort: defaultGRPCAPIPort,
 		// Cache defaults
-		&Config.Cache.EvictionInterval: defaultEvictionInterval,
+		&cfg.Cache.EvictionInterval:  defaultEvictionInterval,
+		&cfg.Cache.SlowLog.Threshold: defaultSlowLogThreshold,
+		&cfg.Cache.SlowLog.MaxLen:    defaultSlowLogMaxLen,
+		// Tracing defaults
+		&cfg.Tracing.Timeout:       defaultTracingTimeout,
+		&cfg.Tracing.FlushInterval: defaultTracingFlushInterval,
+		// Replication defaults
+		&cfg.Replication.BacklogSize:       defaultReplicationBacklogSize,
+		&cfg.Replication.Timeout:           defaultReplicationTimeout,
+		&cfg.Replication.ReconnectInterval: defaultReplicationReconnectInterval,
 	}
 	for currentValue, defaultValue := range defaultIntParameters {
 		setDefaultIntValue(currentValue, defaultValue)
 	}
 
-	return nil
 }
 
 func setDefaultIntValue(currentValue *int, defaultValue int) {
-	if *currentValue <= 0 {
+	if *currentValue == 0 {
 		*currentValue = defaultValue
 	}
 }
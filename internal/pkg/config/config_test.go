@@ -6,26 +6,108 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// loadString loads the config from YAML string.
+func loadString(data string) (*AppConfig, error) {
+	cfg := &AppConfig{}
+	if err := decode([]byte(data), cfg); err != nil {
+		return nil, err
+	}
+	setDefaults(cfg)
+
+	return cfg, Validate(cfg)
+}
+
 func TestConfigInitFromStringValues(t *testing.T) {
 	configString := `
 log:
   file: "/var/log/test/test.log"
   use_stdout: true
   debug: true
+  access:
+    level: warn
+    sample_rate: 0.5
+    routes:
+      GET /v1/keys: debug
 public_api:
   server_address: localhost
   server_port: 63100
   read_timeout: 15
   write_timeout: 20
   idle_timeout: 30
+  tls:
+    cert_file: /etc/bookish-spork/public.crt
+    key_file: /etc/bookish-spork/public.key
+    client_ca_file: /etc/bookish-spork/ca.crt
+    client_auth: verify_if_given
+    min_version: "1.3"
+    reload_interval: 30
+  auth:
+    tokens_file: /etc/bookish-spork/tokens
+    hmac_keys_file: /etc/bookish-spork/hmac_keys
+    hmac_max_clock_skew: 60
+    mtls: true
+    reload_interval: 5
+  acl:
+    roles:
+      reporting:
+        - keys: ["reports:*"]
+          commands: [get, keys]
+      admin:
+        - keys: ["*"]
+          permissions: [admin]
+    users:
+      reporting-service: [reporting]
+    default_roles: [admin]
+  rate_limit:
+    key_by: ip
+    default:
+      rate: 100
+      burst: 200
+    classes:
+      write:
+        rate: 10
+    routes:
+      "POST /v1/set":
+        rate: 0.5
+        burst: 2
 service_api:
   server_address: localhost
   server_port: 63101
   read_timeout: 15
   write_timeout: 20
   idle_timeout: 30
+  tls:
+    cert_file: /etc/bookish-spork/service.crt
+    key_file: /etc/bookish-spork/service.key
+    cipher_suites:
+      - TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+      - TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384
+  shutdown_delay: 5
+grpc_api:
+  server_address: localhost
+  server_port: 63102
 cache:
   eviction_interval: 30
+  slow_log:
+    enabled: true
+    threshold: 5
+    max_len: 64
+    log: true
+tracing:
+  endpoint: http://localhost:4318
+  headers:
+    Authorization: Bearer token
+  service_name: cache
+  timeout: 3
+  flush_interval: 1
+replication:
+  leader: https://leader:63101
+  backlog_size: 65536
+  timeout: 5
+  reconnect_interval: 2
+  ca_file: /etc/bookish-spork/ca.crt
+  cert_file: /etc/bookish-spork/follower.crt
+  key_file: /etc/bookish-spork/follower.key
 `
 
 	expected := &AppConfig{
@@ -33,6 +115,11 @@ cache:
 			File:      "/var/log/test/test.log",
 			UseStdout: true,
 			Debug:     true,
+			Access: AccessLogConfig{
+				Level:      "warn",
+				SampleRate: 0.5,
+				Routes:     map[string]string{"GET /v1/keys": "debug"},
+			},
 		},
 		PublicAPI: PublicAPIServerConfig{
 			ServerAddress: "localhost",
@@ -40,6 +127,36 @@ cache:
 			ReadTimeout:   15,
 			WriteTimeout:  20,
 			IdleTimeout:   30,
+			TLS: TLSConfig{
+				CertFile:       "/etc/bookish-spork/public.crt",
+				KeyFile:        "/etc/bookish-spork/public.key",
+				ClientCAFile:   "/etc/bookish-spork/ca.crt",
+				ClientAuth:     "verify_if_given",
+				MinVersion:     "1.3",
+				ReloadInterval: 30,
+			},
+			Auth: AuthConfig{
+				TokensFile:       "/etc/bookish-spork/tokens",
+				HMACKeysFile:     "/etc/bookish-spork/hmac_keys",
+				HMACMaxClockSkew: 60,
+				MTLS:             true,
+				ReloadInterval:   5,
+			},
+			ACL: ACLConfig{
+				Roles: map[string][]ACLRuleConfig{
+					"reporting": {{Keys: []string{"reports:*"}, Commands: []string{"get", "keys"}}},
+					"admin":     {{Keys: []string{"*"}, Permissions: []string{"admin"}}},
+				},
+				Users:        map[string][]string{"reporting-service": {"reporting"}},
+				DefaultRoles: []string{"admin"},
+			},
+			RateLimit: RateLimitConfig{
+				KeyBy:   "ip",
+				Default: RateLimit{Rate: 100, Burst: 200},
+				Classes: map[string]RateLimit{"write": {Rate: 10}},
+				Routes:  map[string]RateLimit{"POST /v1/set": {Rate: 0.5, Burst: 2}},
+			},
+			MaxBodySize: 8388608,
 		},
 		ServiceAPI: ServiceAPIServerConfig{
 			ServerAddress: "localhost",
@@ -47,26 +164,79 @@ cache:
 			ReadTimeout:   15,
 			WriteTimeout:  20,
 			IdleTimeout:   30,
+			TLS: TLSConfig{
+				CertFile: "/etc/bookish-spork/service.crt",
+				KeyFile:  "/etc/bookish-spork/service.key",
+				CipherSuites: []string{
+					"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+					"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+				},
+				MinVersion:     "1.2",
+				ReloadInterval: 10,
+			},
+			Auth:          ServiceAPIAuthConfig{ReloadInterval: 10},
+			ShutdownDelay: 5,
+		},
+		GRPCAPI: GRPCAPIServerConfig{
+			ServerAddress: "localhost",
+			ServerPort:    63102,
+		},
+		Cache: CacheConfig{
+			EvictionInterval: 30,
+			SlowLog: SlowLogConfig{
+				Enabled:   true,
+				Threshold: 5,
+				MaxLen:    64,
+				Log:       true,
+			},
+		},
+		Tracing: TracingConfig{
+			Endpoint:      "http://localhost:4318",
+			Headers:       map[string]string{"Authorization": "Bearer token"},
+			ServiceName:   "cache",
+			Timeout:       3,
+			FlushInterval: 1,
+		},
+		Replication: ReplicationConfig{
+			Leader:            "https://leader:63101",
+			BacklogSize:       65536,
+			Timeout:           5,
+			ReconnectInterval: 2,
+			CAFile:            "/etc/bookish-spork/ca.crt",
+			CertFile:          "/etc/bookish-spork/follower.crt",
+			KeyFile:           "/etc/bookish-spork/follower.key",
 		},
-		Cache: CacheConfig{EvictionInterval: 30},
 	}
 
-	err := initFromString([]byte(configString))
+	cfg, err := loadString(configString)
 
 	assert.Empty(t, err)
-	assert.Equal(t, expected, Config)
+	assert.Equal(t, expected, cfg)
 }
 
 func TestConfigInitFromStringDefaultValues(t *testing.T) {
 	configString := ""
 
 	expected := &AppConfig{
+		Log: LogConfig{
+			Access: AccessLogConfig{Level: "info"},
+		},
 		PublicAPI: PublicAPIServerConfig{
 			ServerAddress: "127.0.0.1",
 			ServerPort:    63100,
 			ReadTimeout:   60,
 			WriteTimeout:  120,
 			IdleTimeout:   240,
+			TLS: TLSConfig{
+				MinVersion:     "1.2",
+				ReloadInterval: 10,
+			},
+			Auth: AuthConfig{
+				HMACMaxClockSkew: 300,
+				ReloadInterval:   10,
+			},
+			RateLimit:   RateLimitConfig{KeyBy: "identity"},
+			MaxBodySize: 8388608,
 		},
 		ServiceAPI: ServiceAPIServerConfig{
 			ServerAddress: "127.0.0.1",
@@ -74,28 +244,37 @@ func TestConfigInitFromStringDefaultValues(t *testing.T) {
 			ReadTimeout:   60,
 			WriteTimeout:  120,
 			IdleTimeout:   240,
+			TLS: TLSConfig{
+				MinVersion:     "1.2",
+				ReloadInterval: 10,
+			},
+			Auth: ServiceAPIAuthConfig{ReloadInterval: 10},
+		},
+		GRPCAPI: GRPCAPIServerConfig{
+			ServerAddress: "127.0.0.1",
+			ServerPort:    63102,
+		},
+		Cache: CacheConfig{
+			EvictionInterval: defaultEvictionInterval,
+			SlowLog: SlowLogConfig{
+				Threshold: defaultSlowLogThreshold,
+				MaxLen:    defaultSlowLogMaxLen,
+			},
+		},
+		Tracing: TracingConfig{
+			ServiceName:   defaultTracingServiceName,
+			Timeout:       defaultTracingTimeout,
+			FlushInterval: defaultTracingFlushInterval,
+		},
+		Replication: ReplicationConfig{
+			BacklogSize:       defaultReplicationBacklogSize,
+			Timeout:           defaultReplicationTimeout,
+			ReconnectInterval: defaultReplicationReconnectInterval,
 		},
-		Cache: CacheConfig{EvictionInterval: defaultEvictionInterval},
 	}
 
-	err := initFromString([]byte(configString))
+	cfg, err := loadString(configString)
 
 	assert.Empty(t, err)
-	assert.Equal(t, expected, Config)
-}
-
-func TestCheckConfigErr(t *testing.T) {
-	Config = nil
-
-	err := CheckConfig()
-
-	assert.EqualError(t, err, errGlobalConfig)
-}
-
-func TestCheckConfig(t *testing.T) {
-	Config = &AppConfig{Log: LogConfig{Debug: true}}
-
-	err := CheckConfig()
-
-	assert.NoError(t, err)
+	assert.Equal(t, expected, cfg)
 }
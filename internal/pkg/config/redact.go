@@ -0,0 +1,80 @@
+package config
+
+import (
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Redacted replaces the values of the secret fields.
+const Redacted = "[redacted]"
+
+// secretFields contains the names of the config fields with secrets
+// or paths to them, all values of the maps named so are redacted,
+// e.g. the headers of the tracing exporter with credentials.
+var secretFields = map[string]bool{
+	"headers":        true,
+	"key_file":       true,
+	"tokens_file":    true,
+	"hmac_keys_file": true,
+}
+
+// Marshal returns YAML representation of the config, the values of the
+// secret fields are redacted. Fields keep the order of the config structs.
+func Marshal(cfg interface{}) ([]byte, error) {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	redactSecrets(doc)
+
+	return yaml.Marshal(doc)
+}
+
+// redactSecrets replaces the values of the secret fields in the YAML document.
+func redactSecrets(v interface{}) {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		for i, item := range v {
+			if key, ok := item.Key.(string); ok && secretFields[key] {
+				v[i].Value = redact(item.Value)
+
+				continue
+			}
+			redactSecrets(item.Value)
+		}
+	case []interface{}:
+		for _, item := range v {
+			redactSecrets(item)
+		}
+	}
+}
+
+// redact replaces all values that are set, so it's still seen which fields are configured.
+func redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		for i, item := range v {
+			v[i].Value = redact(item.Value)
+		}
+
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redact(item)
+		}
+
+		return v
+	case nil:
+		return nil
+	default:
+		if v == "" {
+			return v
+		}
+
+		return Redacted
+	}
+}
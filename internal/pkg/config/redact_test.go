@@ -0,0 +1,39 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestMarshal_RedactsSecrets(t *testing.T) {
+	cfg, err := Load(Sources{})
+	require.NoError(t, err)
+	cfg.Tracing.Headers = map[string]string{"Authorization": "Bearer secret-token"}
+	cfg.PublicAPI.TLS.CertFile = "/etc/bookish-spork/public.crt"
+	cfg.PublicAPI.TLS.KeyFile = "/etc/bookish-spork/public.key"
+	cfg.PublicAPI.Auth.TokensFile = "/etc/bookish-spork/tokens"
+
+	data, err := Marshal(cfg)
+	require.NoError(t, err)
+	for _, secret := range []string{"secret-token", "public.key", "/etc/bookish-spork/tokens"} {
+		require.NotContains(t, string(data), secret)
+	}
+
+	var redacted AppConfig
+	require.NoError(t, yaml.Unmarshal(data, &redacted))
+	require.Equal(t, map[string]string{"Authorization": Redacted}, redacted.Tracing.Headers)
+	require.Equal(t, Redacted, redacted.PublicAPI.TLS.KeyFile)
+	require.Equal(t, Redacted, redacted.PublicAPI.Auth.TokensFile)
+
+	// Not secret and not set fields are kept as is
+	require.Equal(t, "/etc/bookish-spork/public.crt", redacted.PublicAPI.TLS.CertFile)
+	require.Empty(t, redacted.ServiceAPI.TLS.KeyFile)
+
+	// Fields keep the order of the config structs
+	plain, err := yaml.Marshal(cfg)
+	require.NoError(t, err)
+	require.Equal(t, strings.SplitN(string(plain), "\n", 2)[0], strings.SplitN(string(data), "\n", 2)[0])
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/pflag"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// EnvPrefix is the prefix of the environment variables that override config fields,
+// e.g. BOOKISH_SPORK_PUBLIC_API_SERVER_PORT overrides public_api.server_port.
+const EnvPrefix = "BOOKISH_SPORK_"
+
+// Sources contains the sources of the config, the latter override the former:
+// defaults < file < environment variables < flags.
+type Sources struct {
+	// File is the path to YAML config file, it's optional.
+	File string
+
+	// Environ contains environment variables in "key=value" form, see os.Environ.
+	Environ []string
+
+	// Flags contains flags registered with RegisterFlags, only changed flags are applied.
+	Flags *pflag.FlagSet
+}
+
+// field is a leaf field of AppConfig.
+type field struct {
+	// path is the dot-separated YAML path of the field, e.g. "public_api.tls.cert_file".
+	path  string
+	value reflect.Value
+}
+
+// envName returns the name of the environment variable that overrides the field.
+func (f field) envName() string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(f.path, ".", "_"))
+}
+
+// flagName returns the name of the flag that overrides the field.
+func (f field) flagName() string {
+	return strings.ReplaceAll(f.path, "_", "-")
+}
+
+// set parses and sets the field value, strings are used as is and
+// the other types are parsed as YAML, e.g. "[a, b]" or "{a: b}".
+func (f field) set(value string) error {
+	if f.value.Kind() == reflect.String {
+		f.value.SetString(value)
+
+		return nil
+	}
+
+	v := reflect.New(f.value.Type())
+	if err := yaml.UnmarshalStrict([]byte(value), v.Interface()); err != nil {
+		if typeErr, ok := err.(*yaml.TypeError); ok {
+			// Values are single-line, so line numbers are omitted
+			return errors.New(strings.TrimPrefix(strings.Join(typeErr.Errors, "; "), "line 1: "))
+		}
+
+		return err
+	}
+	f.value.Set(v.Elem())
+
+	return nil
+}
+
+// fields returns the leaf fields of the config.
+func fields(cfg *AppConfig) []field {
+	var result []field
+
+	var walk func(prefix string, v reflect.Value)
+	walk = func(prefix string, v reflect.Value) {
+		for i := 0; i < v.NumField(); i++ {
+			name := strings.Split(v.Type().Field(i).Tag.Get("yaml"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+
+			fv := v.Field(i)
+			if fv.Kind() == reflect.Struct {
+				walk(name, fv)
+
+				continue
+			}
+			result = append(result, field{path: name, value: fv})
+		}
+	}
+	walk("", reflect.ValueOf(cfg).Elem())
+
+	return result
+}
+
+// RegisterFlags registers flags that override all config fields,
+// e.g. --public-api.server-port overrides public_api.server_port.
+func RegisterFlags(fs *pflag.FlagSet) {
+	for _, f := range fields(&AppConfig{}) {
+		value := &flagValue{typ: f.value.Kind().String()}
+		switch f.value.Kind() {
+		case reflect.Float64:
+			value.typ = "float"
+		case reflect.Slice, reflect.Map:
+			value.typ = "yaml"
+		}
+		flag := fs.VarPF(value, f.flagName(), "", "overrides "+f.path)
+		if f.value.Kind() == reflect.Bool {
+			flag.NoOptDefVal = "true"
+		}
+	}
+}
+
+// Load reads the config from the sources, sets default values of the omitted parameters
+// and validates the config. It returns ValidationError with all errors found.
+func Load(src Sources) (*AppConfig, error) {
+	cfg := &AppConfig{}
+
+	var errs errorList
+	if src.File != "" {
+		data, err := ioutil.ReadFile(src.File)
+		if err != nil {
+			return nil, err
+		}
+		if err := decode(data, cfg); err != nil {
+			errs = append(errs, err.(*ValidationError).Errors...)
+		}
+	}
+
+	// Unknown BOOKISH_SPORK_* variables are ignored, they may be set
+	// by the environment, e.g. Kubernetes service links
+	env := make(map[string]string, len(src.Environ))
+	for _, kv := range src.Environ {
+		if parts := strings.SplitN(kv, "=", 2); len(parts) == 2 && strings.HasPrefix(parts[0], EnvPrefix) {
+			env[parts[0]] = parts[1]
+		}
+	}
+
+	fs := src.Flags
+	if fs == nil {
+		fs = pflag.NewFlagSet("", pflag.ContinueOnError)
+	}
+
+	for _, f := range fields(cfg) {
+		if value, ok := env[f.envName()]; ok {
+			if err := f.set(value); err != nil {
+				errs.add(f.path, "invalid value of %s environment variable: %s", f.envName(), err)
+			}
+		}
+		if flag := fs.Lookup(f.flagName()); flag != nil && flag.Changed {
+			if err := f.set(flag.Value.String()); err != nil {
+				errs.add(f.path, "invalid value of --%s flag: %s", f.flagName(), err)
+			}
+		}
+	}
+
+	setDefaults(cfg)
+
+	if err := Validate(cfg); err != nil {
+		errs = append(errs, err.(*ValidationError).Errors...)
+	}
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Diff returns YAML paths of the parameters that differ in the configs,
+// nil and empty lists and maps are equal.
+func Diff(a, b *AppConfig) []string {
+	var changed []string
+
+	fieldsA, fieldsB := fields(a), fields(b)
+	for i, fa := range fieldsA {
+		va, vb := fa.value, fieldsB[i].value
+		switch va.Kind() {
+		case reflect.Map, reflect.Slice:
+			if va.Len() == 0 && vb.Len() == 0 {
+				continue
+			}
+		}
+		if !reflect.DeepEqual(va.Interface(), vb.Interface()) {
+			changed = append(changed, fa.path)
+		}
+	}
+
+	return changed
+}
+
+// flagValue is a pflag.Value that keeps the raw flag value,
+// it's parsed according to the field type on Load.
+type flagValue struct {
+	value string
+	typ   string
+}
+
+func (v *flagValue) String() string {
+	return v.value
+}
+
+func (v *flagValue) Set(s string) error {
+	v.value = s
+
+	return nil
+}
+
+func (v *flagValue) Type() string {
+	return v.typ
+}
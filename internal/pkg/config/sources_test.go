@@ -0,0 +1,131 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, data string) string {
+	dir, err := ioutil.TempDir("", "config")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "bookish-spork.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(data), 0600))
+
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfigFile(t, `
+log:
+  debug: true
+public_api:
+  server_address: 0.0.0.0
+  server_port: 1
+  read_timeout: 15
+service_api:
+  server_port: 2
+`)
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	RegisterFlags(fs)
+	require.NoError(t, fs.Parse([]string{
+		"--public-api.server-port", "3",
+		"--cache.slow-log.enabled",
+		"--public-api.acl.users", "{alice: [admin]}",
+		"--public-api.acl.roles", "{admin: [{keys: ['*'], permissions: [admin]}]}",
+	}))
+
+	cfg, err := Load(Sources{
+		File: path,
+		Environ: []string{
+			"BOOKISH_SPORK_PUBLIC_API_SERVER_PORT=4",
+			"BOOKISH_SPORK_SERVICE_API_SERVER_PORT=5",
+			"BOOKISH_SPORK_LOG_DEBUG=false",
+			"BOOKISH_SPORK_TRACING_ENDPOINT=http://localhost:4318",
+			"BOOKISH_SPORK_PUBLIC_API_RATE_LIMIT_DEFAULT_RATE=0.5",
+			"BOOKISH_SPORK_LOG_ACCESS_ROUTES={GET /v1/keys: debug}",
+			"BOOKISH_SPORK_UNKNOWN=1",
+			"PUBLIC_API_SERVER_PORT=6",
+		},
+		Flags: fs,
+	})
+	require.NoError(t, err)
+
+	// Flags override environment variables
+	require.Equal(t, 3, cfg.PublicAPI.ServerPort)
+	require.True(t, cfg.Cache.SlowLog.Enabled)
+	require.Equal(t, map[string][]string{"alice": {"admin"}}, cfg.PublicAPI.ACL.Users)
+
+	// Environment variables override the file
+	require.Equal(t, 5, cfg.ServiceAPI.ServerPort)
+	require.False(t, cfg.Log.Debug)
+	require.Equal(t, "http://localhost:4318", cfg.Tracing.Endpoint)
+	require.Equal(t, 0.5, cfg.PublicAPI.RateLimit.Default.Rate)
+	require.Equal(t, map[string]string{"GET /v1/keys": "debug"}, cfg.Log.Access.Routes)
+
+	// File overrides defaults
+	require.Equal(t, "0.0.0.0", cfg.PublicAPI.ServerAddress)
+	require.Equal(t, 15, cfg.PublicAPI.ReadTimeout)
+	require.Equal(t, defaultHTTPWriteTimeout, cfg.PublicAPI.WriteTimeout)
+}
+
+func TestLoadWithoutFile(t *testing.T) {
+	cfg, err := Load(Sources{Environ: []string{"BOOKISH_SPORK_GRPC_API_SERVER_PORT=7"}})
+	require.NoError(t, err)
+	require.Equal(t, 7, cfg.GRPCAPI.ServerPort)
+	require.Equal(t, defaultPublicAPIPort, cfg.PublicAPI.ServerPort)
+	require.Equal(t, defaultTracingServiceName, cfg.Tracing.ServiceName)
+}
+
+func TestLoadErrors(t *testing.T) {
+	_, err := Load(Sources{File: filepath.Join(os.TempDir(), "bookish-spork-missing.yaml")})
+	require.Error(t, err)
+
+	_, err = Load(Sources{File: writeConfigFile(t, "public_api: [")})
+	require.Error(t, err)
+
+	_, err = Load(Sources{Environ: []string{"BOOKISH_SPORK_PUBLIC_API_SERVER_PORT=port"}})
+	require.EqualError(t, err, "invalid config: public_api.server_port: invalid value of "+
+		"BOOKISH_SPORK_PUBLIC_API_SERVER_PORT environment variable: cannot unmarshal !!str `port` into int")
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	RegisterFlags(fs)
+	require.NoError(t, fs.Parse([]string{"--log.debug=maybe"}))
+	_, err = Load(Sources{Flags: fs})
+	require.EqualError(t, err, "invalid config: log.debug: invalid value of --log.debug flag: "+
+		"cannot unmarshal !!str `maybe` into bool")
+}
+
+func TestFieldNamesAreUnique(t *testing.T) {
+	envNames := map[string]bool{}
+	flagNames := map[string]bool{}
+	for _, f := range fields(&AppConfig{}) {
+		require.False(t, envNames[f.envName()], f.path)
+		require.False(t, flagNames[f.flagName()], f.path)
+		envNames[f.envName()] = true
+		flagNames[f.flagName()] = true
+	}
+	require.True(t, envNames["BOOKISH_SPORK_PUBLIC_API_TLS_CERT_FILE"])
+	require.True(t, flagNames["public-api.tls.cert-file"])
+}
+
+func TestDiff(t *testing.T) {
+	a, err := Load(Sources{})
+	require.NoError(t, err)
+	b, err := Load(Sources{Environ: []string{
+		"BOOKISH_SPORK_LOG_DEBUG=true",
+		"BOOKISH_SPORK_PUBLIC_API_RATE_LIMIT_DEFAULT_RATE=10",
+		"BOOKISH_SPORK_PUBLIC_API_ACL_USERS={}",
+	}})
+	require.NoError(t, err)
+
+	require.Empty(t, Diff(a, a))
+	require.Equal(t, []string{"log.debug", "public_api.rate_limit.default.rate"}, Diff(a, b))
+}
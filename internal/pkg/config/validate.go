@@ -0,0 +1,397 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// FieldError is an error of the config parameter.
+type FieldError struct {
+	// Path is the YAML path of the parameter, e.g. "public_api.tls.cert_file".
+	// It's empty for the errors that can't be attributed to a parameter, e.g. YAML syntax errors.
+	Path    string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+
+	return e.Path + ": " + e.Message
+}
+
+// ValidationError contains all errors found in the config.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return "invalid config: " + e.Errors[0].Error()
+	}
+
+	lines := make([]string, 0, len(e.Errors)+1)
+	lines = append(lines, fmt.Sprintf("invalid config, %d errors:", len(e.Errors)))
+	for _, err := range e.Errors {
+		lines = append(lines, "  "+err.Error())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// errorList collects config errors.
+type errorList []FieldError
+
+func (l *errorList) add(path, format string, args ...interface{}) {
+	*l = append(*l, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns ValidationError if there are errors.
+func (l errorList) err() error {
+	if len(l) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Errors: l}
+}
+
+// decode decodes YAML config and rejects unknown and duplicate fields.
+func decode(data []byte, cfg *AppConfig) error {
+	var raw interface{}
+	if err := yaml.UnmarshalStrict(data, &raw); err != nil {
+		return &ValidationError{Errors: []FieldError{{Message: err.Error()}}}
+	}
+
+	var errs errorList
+	unknownFields(&errs, "", raw, reflect.TypeOf(cfg).Elem())
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		if typeErr, ok := err.(*yaml.TypeError); ok {
+			for _, msg := range typeErr.Errors {
+				errs.add("", "%s", msg)
+			}
+		} else {
+			errs.add("", "%s", err)
+		}
+	}
+
+	return errs.err()
+}
+
+// unknownFields reports the keys of raw YAML value that are not defined in the type.
+func unknownFields(errs *errorList, path string, raw interface{}, typ reflect.Type) {
+	switch typ.Kind() {
+	case reflect.Struct:
+		m, ok := raw.(map[interface{}]interface{})
+		if !ok {
+			return
+		}
+
+		known := make(map[string]reflect.Type, typ.NumField())
+		for i := 0; i < typ.NumField(); i++ {
+			if name := strings.Split(typ.Field(i).Tag.Get("yaml"), ",")[0]; name != "" && name != "-" {
+				known[name] = typ.Field(i).Type
+			}
+		}
+
+		for _, key := range sortedKeys(m) {
+			fieldType, ok := known[key]
+			if !ok {
+				errs.add(joinPath(path, key), "unknown field")
+
+				continue
+			}
+			unknownFields(errs, joinPath(path, key), m[key], fieldType)
+		}
+	case reflect.Map:
+		m, ok := raw.(map[interface{}]interface{})
+		if !ok {
+			return
+		}
+		for _, key := range sortedKeys(m) {
+			unknownFields(errs, joinPath(path, key), m[key], typ.Elem())
+		}
+	case reflect.Slice:
+		s, ok := raw.([]interface{})
+		if !ok {
+			return
+		}
+		for i, item := range s {
+			unknownFields(errs, fmt.Sprintf("%s[%d]", path, i), item, typ.Elem())
+		}
+	}
+}
+
+func sortedKeys(m map[interface{}]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, fmt.Sprint(key))
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+var (
+	hostnameRe = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?)*$`)
+
+	logLevels          = []string{"debug", "info", "warn", "error", "none"}
+	tlsVersions        = []string{"1.0", "1.1", "1.2", "1.3"}
+	tlsClientAuthModes = []string{"", "none", "request", "verify_if_given", "require"}
+	rateLimitKeys      = []string{"identity", "ip"}
+	rateLimitClasses   = []string{"read", "write"}
+	tracingURLSchemes  = []string{"http", "https"}
+	leaderURLSchemes   = []string{"http", "https"}
+	aclPermissions     = []string{"read", "write", "admin"}
+)
+
+// Validate checks the config with default values set.
+// It returns ValidationError with all errors found.
+func Validate(cfg *AppConfig) error {
+	var errs errorList
+
+	// Servers
+	servers := []struct {
+		path    string
+		address string
+		port    int
+	}{
+		{"public_api", cfg.PublicAPI.ServerAddress, cfg.PublicAPI.ServerPort},
+		{"service_api", cfg.ServiceAPI.ServerAddress, cfg.ServiceAPI.ServerPort},
+		{"grpc_api", cfg.GRPCAPI.ServerAddress, cfg.GRPCAPI.ServerPort},
+	}
+	for i, s := range servers {
+		if !validAddress(s.address) {
+			errs.add(s.path+".server_address", "invalid address %q", s.address)
+		}
+		if s.port < 1 || s.port > 65535 {
+			errs.add(s.path+".server_port", "port must be from 1 to 65535, got %d", s.port)
+		}
+		for _, other := range servers[:i] {
+			if s.port == other.port && addressesOverlap(s.address, other.address) {
+				errs.add(s.path+".server_port", "port %d is already used by %s", s.port, other.path)
+			}
+		}
+	}
+
+	positive := map[string]int{
+		"public_api.read_timeout":             cfg.PublicAPI.ReadTimeout,
+		"public_api.write_timeout":            cfg.PublicAPI.WriteTimeout,
+		"public_api.idle_timeout":             cfg.PublicAPI.IdleTimeout,
+		"public_api.max_body_size":            cfg.PublicAPI.MaxBodySize,
+		"public_api.tls.reload_interval":      cfg.PublicAPI.TLS.ReloadInterval,
+		"public_api.auth.reload_interval":     cfg.PublicAPI.Auth.ReloadInterval,
+		"public_api.auth.hmac_max_clock_skew": cfg.PublicAPI.Auth.HMACMaxClockSkew,
+		"service_api.read_timeout":            cfg.ServiceAPI.ReadTimeout,
+		"service_api.write_timeout":           cfg.ServiceAPI.WriteTimeout,
+		"service_api.idle_timeout":            cfg.ServiceAPI.IdleTimeout,
+		"service_api.tls.reload_interval":     cfg.ServiceAPI.TLS.ReloadInterval,
+		"service_api.auth.reload_interval":    cfg.ServiceAPI.Auth.ReloadInterval,
+		"cache.eviction_interval":             cfg.Cache.EvictionInterval,
+		"cache.slow_log.threshold":            cfg.Cache.SlowLog.Threshold,
+		"cache.slow_log.max_len":              cfg.Cache.SlowLog.MaxLen,
+		"tracing.timeout":                     cfg.Tracing.Timeout,
+		"tracing.flush_interval":              cfg.Tracing.FlushInterval,
+		"replication.backlog_size":            cfg.Replication.BacklogSize,
+		"replication.timeout":                 cfg.Replication.Timeout,
+		"replication.reconnect_interval":      cfg.Replication.ReconnectInterval,
+	}
+	for path, value := range positive {
+		if value <= 0 {
+			errs.add(path, "must be positive, got %d", value)
+		}
+	}
+	if cfg.ServiceAPI.ShutdownDelay < 0 {
+		errs.add("service_api.shutdown_delay", "must not be negative, got %d", cfg.ServiceAPI.ShutdownDelay)
+	}
+
+	// TLS
+	validateTLS(&errs, "public_api.tls", cfg.PublicAPI.TLS)
+	validateTLS(&errs, "service_api.tls", cfg.ServiceAPI.TLS)
+	if cfg.PublicAPI.Auth.MTLS && cfg.PublicAPI.TLS.ClientCAFile == "" {
+		errs.add("public_api.auth.mtls", "requires public_api.tls.client_ca_file to be set")
+	}
+
+	// Dump, restore, reload and replication sync are served without authentication on loopback address only
+	serviceAPI := cfg.ServiceAPI
+	if !loopbackAddress(serviceAPI.ServerAddress) && serviceAPI.Auth.TokensFile == "" && serviceAPI.TLS.ClientCAFile == "" {
+		errs.add("service_api.auth", "tokens_file or service_api.tls.client_ca_file is required on non-loopback address %q",
+			serviceAPI.ServerAddress)
+	}
+
+	// Access control
+	acl := cfg.PublicAPI.ACL
+	for _, name := range sortedMapKeys(acl.Roles) {
+		for i, rule := range acl.Roles[name] {
+			for _, p := range rule.Permissions {
+				if !contains(aclPermissions, p) {
+					errs.add(fmt.Sprintf("public_api.acl.roles.%s[%d].permissions", name, i),
+						"unknown permission %q, must be one of: %s", p, strings.Join(aclPermissions, ", "))
+				}
+			}
+		}
+	}
+	for _, user := range sortedMapKeys(acl.Users) {
+		for _, role := range acl.Users[user] {
+			if _, ok := acl.Roles[role]; !ok {
+				errs.add("public_api.acl.users."+user, "unknown role %q", role)
+			}
+		}
+	}
+	for _, role := range acl.DefaultRoles {
+		if _, ok := acl.Roles[role]; !ok {
+			errs.add("public_api.acl.default_roles", "unknown role %q", role)
+		}
+	}
+
+	// Rate limiting
+	rateLimit := cfg.PublicAPI.RateLimit
+	if !contains(rateLimitKeys, rateLimit.KeyBy) {
+		errs.add("public_api.rate_limit.key_by", "unknown key %q, must be one of: %s",
+			rateLimit.KeyBy, strings.Join(rateLimitKeys, ", "))
+	}
+	validateRateLimit(&errs, "public_api.rate_limit.default", rateLimit.Default)
+	validateRateLimit(&errs, "public_api.rate_limit.auth_failures", rateLimit.AuthFailures)
+	for _, class := range sortedMapKeys(rateLimit.Classes) {
+		if !contains(rateLimitClasses, class) {
+			errs.add("public_api.rate_limit.classes."+class, "unknown command class, must be one of: %s",
+				strings.Join(rateLimitClasses, ", "))
+		}
+		validateRateLimit(&errs, "public_api.rate_limit.classes."+class, rateLimit.Classes[class])
+	}
+	for _, route := range sortedMapKeys(rateLimit.Routes) {
+		validateRoute(&errs, "public_api.rate_limit.routes."+route, route)
+		validateRateLimit(&errs, "public_api.rate_limit.routes."+route, rateLimit.Routes[route])
+	}
+
+	// Access log
+	access := cfg.Log.Access
+	if !contains(logLevels, access.Level) {
+		errs.add("log.access.level", "unknown level %q, must be one of: %s", access.Level, strings.Join(logLevels, ", "))
+	}
+	if access.SampleRate < 0 || access.SampleRate > 1 {
+		errs.add("log.access.sample_rate", "must be from 0 to 1, got %v", access.SampleRate)
+	}
+	for _, route := range sortedMapKeys(access.Routes) {
+		validateRoute(&errs, "log.access.routes."+route, route)
+		if !contains(logLevels, access.Routes[route]) {
+			errs.add("log.access.routes."+route, "unknown level %q, must be one of: %s",
+				access.Routes[route], strings.Join(logLevels, ", "))
+		}
+	}
+
+	// Tracing
+	if endpoint := cfg.Tracing.Endpoint; endpoint != "" {
+		if u, err := url.Parse(endpoint); err != nil || !contains(tracingURLSchemes, u.Scheme) || u.Host == "" {
+			errs.add("tracing.endpoint", "must be http or https URL, got %q", endpoint)
+		}
+	}
+
+	// Replication
+	replication := cfg.Replication
+	if leader := replication.Leader; leader != "" {
+		if u, err := url.Parse(leader); err != nil || !contains(leaderURLSchemes, u.Scheme) || u.Host == "" {
+			errs.add("replication.leader", "must be http or https URL, got %q", leader)
+		}
+	}
+	if (replication.CertFile == "") != (replication.KeyFile == "") {
+		errs.add("replication", "both cert_file and key_file are required")
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+
+	return errs.err()
+}
+
+func validateTLS(errs *errorList, path string, cfg TLSConfig) {
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		errs.add(path, "both cert_file and key_file are required")
+	}
+	if cfg.CertFile == "" && (cfg.ClientCAFile != "" || cfg.ClientAuth != "" || len(cfg.CipherSuites) > 0) {
+		errs.add(path, "cert_file is required to configure TLS")
+	}
+	if !contains(tlsVersions, cfg.MinVersion) {
+		errs.add(path+".min_version", "unknown TLS version %q, must be one of: %s",
+			cfg.MinVersion, strings.Join(tlsVersions, ", "))
+	}
+	if !contains(tlsClientAuthModes, cfg.ClientAuth) {
+		errs.add(path+".client_auth", "unknown client auth mode %q, must be one of: %s",
+			cfg.ClientAuth, strings.Join(tlsClientAuthModes[1:], ", "))
+	}
+}
+
+func validateRateLimit(errs *errorList, path string, limit RateLimit) {
+	if limit.Rate < 0 {
+		errs.add(path+".rate", "must not be negative, got %v", limit.Rate)
+	}
+	if limit.Burst < 0 {
+		errs.add(path+".burst", "must not be negative, got %d", limit.Burst)
+	}
+}
+
+func validateRoute(errs *errorList, path, route string) {
+	if parts := strings.Fields(route); len(parts) != 2 || !strings.HasPrefix(parts[1], "/") {
+		errs.add(path, `route must be in "<METHOD> <route pattern>" format`)
+	}
+}
+
+// validAddress checks if the address is IP address or hostname.
+func validAddress(address string) bool {
+	return net.ParseIP(address) != nil || hostnameRe.MatchString(address)
+}
+
+// loopbackAddress checks if the address is accessible from the local host only.
+func loopbackAddress(address string) bool {
+	ip := net.ParseIP(address)
+
+	return address == "localhost" || ip != nil && ip.IsLoopback()
+}
+
+// addressesOverlap checks if the servers listening the addresses may conflict.
+func addressesOverlap(a, b string) bool {
+	unspecified := func(address string) bool {
+		ip := net.ParseIP(address)
+
+		return ip != nil && ip.IsUnspecified()
+	}
+
+	return a == b || unspecified(a) || unspecified(b)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sortedMapKeys returns sorted keys of the map with string keys.
+func sortedMapKeys(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, key.String())
+	}
+	sort.Strings(result)
+
+	return result
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadExampleConfig(t *testing.T) {
+	_, err := Load(Sources{File: "../../../bookish-spork.example.yaml"})
+	require.NoError(t, err)
+}
+
+func TestDecodeUnknownFields(t *testing.T) {
+	_, err := loadString(`
+cache:
+  evicton_interval: 30
+public_api:
+  acl:
+    roles:
+      admin:
+        - keys: ["*"]
+          permission: [admin]
+  rate_limit:
+    classes:
+      write:
+        rat: 10
+loggg: {}
+`)
+	require.EqualError(t, err, `invalid config, 4 errors:
+  cache.evicton_interval: unknown field
+  loggg: unknown field
+  public_api.acl.roles.admin[0].permission: unknown field
+  public_api.rate_limit.classes.write.rat: unknown field`)
+	require.IsType(t, &ValidationError{}, err)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	_, err := loadString(`
+cache:
+  eviction_interval: 1
+  eviction_interval: 2
+`)
+	require.EqualError(t, err, `invalid config: yaml: unmarshal errors:
+  line 4: key "eviction_interval" already set in map`)
+
+	_, err = loadString(`
+cache:
+  eviction_interval: often
+`)
+	require.EqualError(t, err, "invalid config: line 3: cannot unmarshal !!str `often` into int")
+}
+
+func TestValidate(t *testing.T) {
+	_, err := loadString(`
+log:
+  access:
+    level: verbose
+    sample_rate: 2
+    routes:
+      /v1/keys: info
+public_api:
+  server_address: "bad address"
+  server_port: 70000
+  read_timeout: -1
+  tls:
+    key_file: /etc/bookish-spork/public.key
+    min_version: "1.4"
+    client_auth: always
+  auth:
+    mtls: true
+  acl:
+    roles:
+      reader:
+        - keys: ["*"]
+          permissions: [read, execute]
+    users:
+      alice: [writer]
+    default_roles: [reader, admin]
+  rate_limit:
+    key_by: user
+    default:
+      rate: -1
+    classes:
+      delete:
+        burst: -2
+service_api:
+  server_address: 0.0.0.0
+  server_port: 63102
+  shutdown_delay: -1
+grpc_api:
+  server_address: 127.0.0.1
+  server_port: 63102
+cache:
+  eviction_interval: -60
+tracing:
+  endpoint: localhost:4318
+replication:
+  leader: leader:63101
+  timeout: -1
+  cert_file: /etc/bookish-spork/follower.crt
+`)
+	require.EqualError(t, err, `invalid config, 26 errors:
+  cache.eviction_interval: must be positive, got -60
+  grpc_api.server_port: port 63102 is already used by service_api
+  log.access.level: unknown level "verbose", must be one of: debug, info, warn, error, none
+  log.access.routes./v1/keys: route must be in "<METHOD> <route pattern>" format
+  log.access.sample_rate: must be from 0 to 1, got 2
+  public_api.acl.default_roles: unknown role "admin"
+  public_api.acl.roles.reader[0].permissions: unknown permission "execute", must be one of: read, write, admin
+  public_api.acl.users.alice: unknown role "writer"
+  public_api.auth.mtls: requires public_api.tls.client_ca_file to be set
+  public_api.rate_limit.classes.delete: unknown command class, must be one of: read, write
+  public_api.rate_limit.classes.delete.burst: must not be negative, got -2
+  public_api.rate_limit.default.rate: must not be negative, got -1
+  public_api.rate_limit.key_by: unknown key "user", must be one of: identity, ip
+  public_api.read_timeout: must be positive, got -1
+  public_api.server_address: invalid address "bad address"
+  public_api.server_port: port must be from 1 to 65535, got 70000
+  public_api.tls: both cert_file and key_file are required
+  public_api.tls: cert_file is required to configure TLS
+  public_api.tls.client_auth: unknown client auth mode "always", must be one of: none, request, verify_if_given, require
+  public_api.tls.min_version: unknown TLS version "1.4", must be one of: 1.0, 1.1, 1.2, 1.3
+  replication: both cert_file and key_file are required
+  replication.leader: must be http or https URL, got "leader:63101"
+  replication.timeout: must be positive, got -1
+  service_api.auth: tokens_file or service_api.tls.client_ca_file is required on non-loopback address "0.0.0.0"
+  service_api.shutdown_delay: must not be negative, got -1
+  tracing.endpoint: must be http or https URL, got "localhost:4318"`)
+}
+
+func TestValidateSharedPort(t *testing.T) {
+	// Servers may share the port on different addresses
+	_, err := loadString(`
+public_api:
+  server_address: 10.0.0.1
+  server_port: 8080
+service_api:
+  server_address: 127.0.0.1
+  server_port: 8080
+`)
+	require.NoError(t, err)
+
+	_, err = loadString(`
+public_api:
+  server_port: 8080
+service_api:
+  server_port: 8080
+`)
+	require.EqualError(t, err, "invalid config: service_api.server_port: port 8080 is already used by public_api")
+}
+
+func TestValidateServiceAPIAuth(t *testing.T) {
+	// Authentication is optional on loopback address
+	for _, address := range []string{"127.0.0.1", "::1", "localhost"} {
+		_, err := loadString("service_api:\n  server_address: " + address)
+		require.NoError(t, err, address)
+	}
+
+	_, err := loadString(`
+service_api:
+  server_address: 10.0.0.1
+`)
+	require.EqualError(t, err,
+		`invalid config: service_api.auth: tokens_file or service_api.tls.client_ca_file is required on non-loopback address "10.0.0.1"`)
+
+	_, err = loadString(`
+service_api:
+  server_address: 0.0.0.0
+  auth:
+    tokens_file: /etc/bookish-spork/service_tokens
+`)
+	require.NoError(t, err)
+
+	_, err = loadString(`
+service_api:
+  server_address: 0.0.0.0
+  tls:
+    cert_file: /etc/bookish-spork/service.crt
+    key_file: /etc/bookish-spork/service.key
+    client_ca_file: /etc/bookish-spork/ca.crt
+`)
+	require.NoError(t, err)
+}
@@ -0,0 +1,539 @@
+package dump
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+)
+
+// Binary format starts with the magic and the version byte followed by the entries
+// and the end marker. Every entry consists of its type byte, the key, TTL in
+// milliseconds (0 for persistent keys) and the value encoded according to its type:
+//   - value: tagged value;
+//   - bytes: content type and data;
+//   - list: number of items and tagged values;
+//   - hash: number of fields and field names followed by tagged values;
+//   - geo: number of members and names followed by longitude and latitude.
+//
+// Lengths and TTL are unsigned varints, integer numbers are signed varints, floats are
+// 64-bit big-endian IEEE 754, strings are prefixed with their length.
+var binaryMagic = []byte("BSDUMP")
+
+const binaryVersion = 1
+
+// Types of the binary entries.
+const (
+	binaryEnd byte = iota
+	binaryValue
+	binaryBytes
+	binaryList
+	binaryHash
+	binaryGeo
+)
+
+// Tags of the binary values.
+const (
+	tagNull byte = iota
+	tagFalse
+	tagTrue
+	tagInt
+	tagNumber
+	tagFloat
+	tagString
+	tagArray
+	tagObject
+)
+
+const (
+	// maxDepth is the maximum nesting depth of the values.
+	maxDepth = 10000
+
+	// maxPrealloc limits the memory allocated before the data is read.
+	maxPrealloc = 64 << 10
+)
+
+// binaryTypes contains the types of the entries by their binary types.
+var binaryTypes = [...]string{
+	binaryValue: qqcache.TypeValue,
+	binaryBytes: qqcache.TypeBytes,
+	binaryList:  qqcache.TypeList,
+	binaryHash:  qqcache.TypeHash,
+	binaryGeo:   qqcache.TypeGeo,
+}
+
+// errEnd is returned by decodeEntry at the end marker.
+var errEnd = errors.New("end of dump")
+
+// entryWriter is the buffer the binary entries are written to.
+type entryWriter interface {
+	io.Writer
+	io.ByteWriter
+	io.StringWriter
+}
+
+// entryReader is the buffer the binary entries are read from.
+type entryReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// binaryWriter writes the entries in the binary format.
+type binaryWriter struct {
+	w   entryWriter
+	buf [binary.MaxVarintLen64]byte
+
+	// bw is flushed on close, it's nil for the entries embedded into other streams
+	bw *bufio.Writer
+}
+
+func newBinaryWriter(w io.Writer) *binaryWriter {
+	bw := bufio.NewWriter(w)
+	_, _ = bw.Write(binaryMagic)
+	_ = bw.WriteByte(binaryVersion)
+
+	return &binaryWriter{w: bw, bw: bw}
+}
+
+// EncodeEntry appends the entry in the binary format without the header and
+// the end marker of the dump to the buffer, it's used to embed the entries
+// into other streams. The buffer is not changed if the entry is invalid.
+func EncodeEntry(buf *bytes.Buffer, e qqcache.Entry) error {
+	n := buf.Len()
+	if err := (&binaryWriter{w: buf}).Write(e); err != nil {
+		buf.Truncate(n)
+
+		return err
+	}
+
+	return nil
+}
+
+// DecodeEntry reads the entry encoded by EncodeEntry.
+func DecodeEntry(r *bufio.Reader) (qqcache.Entry, error) {
+	e, err := (&binaryDecoder{r: r}).decodeEntry()
+	switch err {
+	case nil:
+		return e, nil
+	case errEnd:
+		err = errors.New("unexpected end marker")
+	case io.EOF:
+		err = io.ErrUnexpectedEOF
+	}
+
+	return qqcache.Entry{}, fmt.Errorf("%w: %s", ErrInvalidDump, err)
+}
+
+func (w *binaryWriter) Write(e qqcache.Entry) error {
+	kind := binaryEnd
+	for k, t := range binaryTypes {
+		if t != "" && t == e.Type {
+			kind = byte(k)
+		}
+	}
+	if kind == binaryEnd {
+		return qqcache.ErrInvalidEntry
+	}
+
+	_ = w.w.WriteByte(kind)
+	w.writeString(e.Key)
+	w.writeUvarint(uint64(ttlToMs(e.TTL)))
+
+	switch kind {
+	case binaryBytes:
+		data, ok := e.Value.([]byte)
+		if !ok {
+			return qqcache.ErrInvalidEntry
+		}
+		w.writeString(e.ContentType)
+		w.writeString(string(data))
+	case binaryList:
+		list, ok := e.Value.([]interface{})
+		if !ok {
+			return qqcache.ErrInvalidEntry
+		}
+		if err := w.writeValue(list, 0); err != nil {
+			return err
+		}
+	case binaryHash:
+		hm, ok := e.Value.(map[string]interface{})
+		if !ok {
+			return qqcache.ErrInvalidEntry
+		}
+		if err := w.writeValue(hm, 0); err != nil {
+			return err
+		}
+	case binaryGeo:
+		members, ok := e.Value.([]qqcache.GeoMember)
+		if !ok {
+			return qqcache.ErrInvalidEntry
+		}
+		w.writeUvarint(uint64(len(members)))
+		for _, m := range members {
+			w.writeString(m.Name)
+			w.writeFloat(m.Longitude)
+			w.writeFloat(m.Latitude)
+		}
+	default:
+		if err := w.writeValue(e.Value, 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *binaryWriter) Close() error {
+	_ = w.w.WriteByte(binaryEnd)
+
+	return w.bw.Flush()
+}
+
+// writeValue writes the tagged value. Lists and hashes are written without tags
+// at the top level as their type is known from the entry type.
+func (w *binaryWriter) writeValue(v interface{}, depth int) error {
+	if depth > maxDepth {
+		return errors.New("value is nested too deep")
+	}
+
+	switch v := v.(type) {
+	case nil:
+		_ = w.w.WriteByte(tagNull)
+	case bool:
+		if v {
+			_ = w.w.WriteByte(tagTrue)
+		} else {
+			_ = w.w.WriteByte(tagFalse)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil && strconv.FormatInt(i, 10) == v.String() {
+			_ = w.w.WriteByte(tagInt)
+			n := binary.PutVarint(w.buf[:], i)
+			_, _ = w.w.Write(w.buf[:n])
+		} else {
+			_ = w.w.WriteByte(tagNumber)
+			w.writeString(v.String())
+		}
+	case float64:
+		_ = w.w.WriteByte(tagFloat)
+		w.writeFloat(v)
+	case string:
+		_ = w.w.WriteByte(tagString)
+		w.writeString(v)
+	case []interface{}:
+		if depth > 0 {
+			_ = w.w.WriteByte(tagArray)
+		}
+		w.writeUvarint(uint64(len(v)))
+		for _, item := range v {
+			if err := w.writeValue(item, depth+1); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		if depth > 0 {
+			_ = w.w.WriteByte(tagObject)
+		}
+		w.writeUvarint(uint64(len(v)))
+		for k, item := range v {
+			w.writeString(k)
+			if err := w.writeValue(item, depth+1); err != nil {
+				return err
+			}
+		}
+	default:
+		// Values of other types are written as they are marshaled to JSON
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var normalized interface{}
+		if err := unmarshal(data, &normalized); err != nil {
+			return err
+		}
+
+		return w.writeValue(normalized, depth+1)
+	}
+
+	return nil
+}
+
+func (w *binaryWriter) writeUvarint(v uint64) {
+	n := binary.PutUvarint(w.buf[:], v)
+	_, _ = w.w.Write(w.buf[:n])
+}
+
+func (w *binaryWriter) writeString(s string) {
+	w.writeUvarint(uint64(len(s)))
+	_, _ = w.w.WriteString(s)
+}
+
+func (w *binaryWriter) writeFloat(f float64) {
+	binary.BigEndian.PutUint64(w.buf[:8], math.Float64bits(f))
+	_, _ = w.w.Write(w.buf[:8])
+}
+
+// binaryDecoder reads the entries in the binary format.
+type binaryDecoder struct {
+	r     entryReader
+	entry int
+	done  bool
+}
+
+func newBinaryDecoder(r *bufio.Reader) (*binaryDecoder, error) {
+	if _, err := r.Discard(len(binaryMagic)); err != nil {
+		return nil, err
+	}
+	version, err := r.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidDump, io.ErrUnexpectedEOF)
+	}
+	if version != binaryVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidDump, version)
+	}
+
+	return &binaryDecoder{r: r}, nil
+}
+
+func (d *binaryDecoder) decode() (qqcache.Entry, error) {
+	if d.done {
+		return qqcache.Entry{}, io.EOF
+	}
+	d.entry++
+
+	e, err := d.decodeEntry()
+	if err == errEnd {
+		d.done = true
+
+		return qqcache.Entry{}, io.EOF
+	}
+	if err != nil {
+		// The dump is truncated if it ends without the end marker
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return qqcache.Entry{}, fmt.Errorf("%w: entry %d: %s", ErrInvalidDump, d.entry, err)
+	}
+
+	return e, nil
+}
+
+// decodeEntry reads the entry, errEnd is returned at the end marker.
+func (d *binaryDecoder) decodeEntry() (qqcache.Entry, error) {
+	kind, err := d.r.ReadByte()
+	if err != nil {
+		return qqcache.Entry{}, err
+	}
+	if kind == binaryEnd {
+		return qqcache.Entry{}, errEnd
+	}
+	if int(kind) >= len(binaryTypes) {
+		return qqcache.Entry{}, fmt.Errorf("unsupported type %d", kind)
+	}
+
+	e := qqcache.Entry{Type: binaryTypes[kind]}
+
+	if e.Key, err = d.readString(); err != nil {
+		return qqcache.Entry{}, err
+	}
+	ttl, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return qqcache.Entry{}, err
+	}
+	if ttl > uint64(math.MaxInt64/time.Millisecond) {
+		return qqcache.Entry{}, errors.New("TTL is out of range")
+	}
+	e.TTL = time.Duration(ttl) * time.Millisecond
+
+	switch kind {
+	case binaryBytes:
+		if e.ContentType, err = d.readString(); err != nil {
+			return qqcache.Entry{}, err
+		}
+		var data string
+		data, err = d.readString()
+		e.Value = []byte(data)
+	case binaryList:
+		e.Value, err = d.readArray(0)
+	case binaryHash:
+		e.Value, err = d.readObject(0)
+	case binaryGeo:
+		e.Value, err = d.readGeoMembers()
+	default:
+		e.Value, err = d.readValue(0)
+	}
+	if err != nil {
+		return qqcache.Entry{}, err
+	}
+
+	return e, nil
+}
+
+func (d *binaryDecoder) readValue(depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, errors.New("value is nested too deep")
+	}
+
+	tag, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case tagNull:
+		return nil, nil
+	case tagFalse:
+		return false, nil
+	case tagTrue:
+		return true, nil
+	case tagInt:
+		i, err := binary.ReadVarint(d.r)
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Number(strconv.FormatInt(i, 10)), nil
+	case tagNumber:
+		s, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Number(s), nil
+	case tagFloat:
+		return d.readFloat()
+	case tagString:
+		return d.readString()
+	case tagArray:
+		return d.readArray(depth + 1)
+	case tagObject:
+		return d.readObject(depth + 1)
+	default:
+		return nil, fmt.Errorf("unsupported value tag %d", tag)
+	}
+}
+
+func (d *binaryDecoder) readArray(depth int) ([]interface{}, error) {
+	n, err := d.readLen()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]interface{}, 0, minInt(n, maxPrealloc))
+	for i := 0; i < n; i++ {
+		item, err := d.readValue(depth)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+
+	return list, nil
+}
+
+func (d *binaryDecoder) readObject(depth int) (map[string]interface{}, error) {
+	n, err := d.readLen()
+	if err != nil {
+		return nil, err
+	}
+
+	hm := make(map[string]interface{}, minInt(n, maxPrealloc))
+	for i := 0; i < n; i++ {
+		k, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		if hm[k], err = d.readValue(depth); err != nil {
+			return nil, err
+		}
+	}
+
+	return hm, nil
+}
+
+func (d *binaryDecoder) readGeoMembers() ([]qqcache.GeoMember, error) {
+	n, err := d.readLen()
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]qqcache.GeoMember, 0, minInt(n, maxPrealloc))
+	for i := 0; i < n; i++ {
+		var m qqcache.GeoMember
+		if m.Name, err = d.readString(); err != nil {
+			return nil, err
+		}
+		if m.Longitude, err = d.readFloat(); err != nil {
+			return nil, err
+		}
+		if m.Latitude, err = d.readFloat(); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+
+	return members, nil
+}
+
+func (d *binaryDecoder) readLen() (int, error) {
+	n, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxInt32 {
+		return 0, errors.New("length is out of range")
+	}
+
+	return int(n), nil
+}
+
+// readString reads the string, the memory is allocated as the data is read
+// to not allocate the length given in the corrupted dump.
+func (d *binaryDecoder) readString() (string, error) {
+	n, err := d.readLen()
+	if err != nil {
+		return "", err
+	}
+
+	if n <= maxPrealloc {
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(d.r, buf); err != nil {
+			return "", err
+		}
+
+		return string(buf), nil
+	}
+
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, d.r, int64(n)); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func (d *binaryDecoder) readFloat() (float64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(d.r, buf[:]); err != nil {
+		return 0, err
+	}
+
+	return math.Float64frombits(binary.BigEndian.Uint64(buf[:])), nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
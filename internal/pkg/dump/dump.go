@@ -0,0 +1,177 @@
+// Package dump provides the endpoints of the service API to stream the keyspace
+// of the cache to a portable dump and to load the dump into the cache.
+package dump
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/backend"
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+	"go.uber.org/zap"
+)
+
+// Paths of the endpoints on the service API.
+const (
+	Path        = "/dump"
+	RestorePath = "/restore"
+)
+
+// Query parameters of the endpoints.
+const (
+	formatQuery = "format"
+	modeQuery   = "mode"
+)
+
+// Opts represents the options to create new instance of Dump.
+type Opts struct {
+	// Cache is the cache to dump and restore.
+	Cache *qqcache.Cache
+
+	// Log is used to report the dumps interrupted by errors, it's optional.
+	Log *zap.Logger
+
+	// ReadOnly rejects the restore, it's set on replication followers.
+	ReadOnly bool
+}
+
+// RestoreResponse represents the body of the restore endpoint.
+type RestoreResponse struct {
+	// Format is the detected format of the dump.
+	Format string `json:"format"`
+
+	// Restored and Skipped are the numbers of the restored keys and
+	// the existing keys skipped in skip mode.
+	Restored int `json:"restored"`
+	Skipped  int `json:"skipped"`
+}
+
+// Dump streams the keyspace of the cache and loads it.
+type Dump struct {
+	opts Opts
+}
+
+// New returns new instance of Dump.
+func New(opts Opts) *Dump {
+	if opts.Log == nil {
+		opts.Log = zap.NewNop()
+	}
+
+	return &Dump{opts: opts}
+}
+
+// Register adds the endpoints to the mux.
+func (d *Dump) Register(mux *http.ServeMux) {
+	mux.HandleFunc(Path, d.Dump)
+	mux.HandleFunc(RestorePath, d.Restore)
+}
+
+// Dump streams all keys of the cache in the format given by the format query
+// parameter, JSON Lines are used by default.
+// The response is aborted if the dump fails after the headers have been sent.
+func (d *Dump) Dump(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	format := req.URL.Query().Get(formatQuery)
+	if format == "" {
+		format = FormatJSONL
+	}
+	dw, err := NewWriter(w, format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType(format))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	err = d.opts.Cache.Dump(dw.Write)
+	if err == nil {
+		err = dw.Close()
+	}
+	if err != nil {
+		// Abort the response to let the client know the dump is incomplete
+		d.opts.Log.Warn("dump failed", zap.Error(err))
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// Restore loads the dump from the request body, its format is detected.
+// Existing keys are handled according to the mode query parameter,
+// they are skipped by default.
+// Keys read before an invalid entry are restored.
+func (d *Dump) Restore(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+	if d.opts.ReadOnly {
+		http.Error(w, backend.ErrReadOnly.Error(), http.StatusForbidden)
+
+		return
+	}
+
+	mode := req.URL.Query().Get(modeQuery)
+	switch mode {
+	case "":
+		mode = qqcache.RestoreSkip
+	case qqcache.RestoreSkip, qqcache.RestoreReplace, qqcache.RestoreMerge:
+	default:
+		http.Error(w, qqcache.ErrInvalidRestoreMode.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	r, err := NewReader(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	resp := RestoreResponse{Format: r.Format()}
+	for {
+		e, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			restoreError(w, err, resp)
+
+			return
+		}
+
+		restored, err := d.opts.Cache.Restore(e, mode)
+		if err != nil {
+			restoreError(w, fmt.Errorf("failed to restore key %q: %w", e.Key, err), resp)
+
+			return
+		}
+		if restored {
+			resp.Restored++
+		} else {
+			resp.Skipped++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// restoreError responds with the error and the number of the keys restored before it.
+func restoreError(w http.ResponseWriter, err error, resp RestoreResponse) {
+	http.Error(w, fmt.Sprintf("%s, %d keys restored", err, resp.Restored), http.StatusBadRequest)
+}
@@ -0,0 +1,138 @@
+package dump
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMux(t *testing.T) (*http.ServeMux, *qqcache.Cache) {
+	c := qqcache.New(qqcache.Opts{EvictionInterval: time.Minute})
+	t.Cleanup(c.Shutdown)
+
+	mux := http.NewServeMux()
+	New(Opts{Cache: c}).Register(mux)
+
+	return mux, c
+}
+
+func do(mux *http.ServeMux, method, url string, body []byte) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(method, url, bytes.NewReader(body)))
+
+	return w
+}
+
+func TestDump(t *testing.T) {
+	mux, c := newTestMux(t)
+	c.Set("value", "some-value", time.Hour)
+	c.SetBytes("bytes", []byte{0, 1}, "image/png", 0)
+	require.NoError(t, c.HSet("hash", map[string]interface{}{"k": "v"}, 0))
+
+	for _, format := range []string{FormatJSONL, FormatBinary} {
+		t.Run(format, func(t *testing.T) {
+			w := do(mux, http.MethodGet, Path+"?format="+format, nil)
+			require.Equal(t, http.StatusOK, w.Code)
+			require.Equal(t, ContentType(format), w.Header().Get("Content-Type"))
+
+			detected, entries, err := readDump(w.Body.Bytes())
+			require.NoError(t, err)
+			require.Equal(t, format, detected)
+			require.Len(t, entries, 3)
+			require.Equal(t, qqcache.Entry{Key: "bytes", Type: qqcache.TypeBytes, Value: []byte{0, 1},
+				ContentType: "image/png"}, entries[0])
+			require.Equal(t, "hash", entries[1].Key)
+			require.Equal(t, "value", entries[2].Key)
+			require.True(t, entries[2].TTL > 59*time.Minute)
+		})
+	}
+
+	// JSON Lines are used by default
+	w := do(mux, http.MethodGet, Path, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, ContentTypeJSONL, w.Header().Get("Content-Type"))
+
+	require.Equal(t, http.StatusBadRequest, do(mux, http.MethodGet, Path+"?format=xml", nil).Code)
+	require.Equal(t, http.StatusMethodNotAllowed, do(mux, http.MethodPost, Path, nil).Code)
+}
+
+func TestRestore(t *testing.T) {
+	mux, c := newTestMux(t)
+
+	for _, format := range []string{FormatJSONL, FormatBinary} {
+		t.Run(format, func(t *testing.T) {
+			data := writeDump(t, format, getTestEntries())
+
+			tests := []struct {
+				mode     string
+				expected RestoreResponse
+				value    interface{}
+			}{
+				{mode: "", expected: RestoreResponse{Format: format, Restored: 6, Skipped: 1}, value: "old"},
+				{mode: qqcache.RestoreSkip, expected: RestoreResponse{Format: format, Restored: 6, Skipped: 1}, value: "old"},
+				{mode: qqcache.RestoreMerge, expected: RestoreResponse{Format: format, Restored: 7}, value: "some-value"},
+				{mode: qqcache.RestoreReplace, expected: RestoreResponse{Format: format, Restored: 7}, value: "some-value"},
+			}
+
+			for _, tc := range tests {
+				for _, key := range c.Keys() {
+					c.Remove(key)
+				}
+				c.Set("value", "old", 0)
+
+				w := do(mux, http.MethodPost, RestorePath+"?mode="+tc.mode, data)
+				require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+				var resp RestoreResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				require.Equal(t, tc.expected, resp)
+
+				value, _ := c.Get("value")
+				require.Equal(t, tc.value, value)
+			}
+		})
+	}
+}
+
+func TestRestore_Invalid(t *testing.T) {
+	mux, c := newTestMux(t)
+
+	data := writeDump(t, FormatJSONL, getTestEntries()[:2])
+	data = append(data, "{\n"...)
+
+	w := do(mux, http.MethodPost, RestorePath, data)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "line 3")
+	require.Contains(t, w.Body.String(), "2 keys restored")
+	require.Len(t, c.Keys(), 2)
+
+	data = writeDump(t, FormatJSONL, []qqcache.Entry{{Key: "geo", Type: qqcache.TypeGeo, Value: []qqcache.GeoMember{
+		{Name: "member", GeoPoint: qqcache.GeoPoint{Longitude: 200}},
+	}}})
+	w = do(mux, http.MethodPost, RestorePath, data)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), `failed to restore key "geo"`)
+
+	require.Equal(t, http.StatusBadRequest, do(mux, http.MethodPost, RestorePath+"?mode=unknown", nil).Code)
+	require.Equal(t, http.StatusMethodNotAllowed, do(mux, http.MethodGet, RestorePath, nil).Code)
+}
+
+func TestRestore_ReadOnly(t *testing.T) {
+	c := qqcache.New(qqcache.Opts{EvictionInterval: time.Minute})
+	defer c.Shutdown()
+	mux := http.NewServeMux()
+	New(Opts{Cache: c, ReadOnly: true}).Register(mux)
+
+	w := do(mux, http.MethodPost, RestorePath, writeDump(t, FormatJSONL, getTestEntries()))
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.Empty(t, c.Keys())
+
+	// Dump is allowed
+	require.Equal(t, http.StatusOK, do(mux, http.MethodGet, Path, nil).Code)
+}
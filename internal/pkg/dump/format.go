@@ -0,0 +1,290 @@
+package dump
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+)
+
+// Formats of the dump.
+const (
+	// FormatJSONL is JSON Lines, every line is a JSON object of an entry.
+	FormatJSONL = "jsonl"
+
+	// FormatBinary is a compact binary format.
+	FormatBinary = "binary"
+)
+
+// Content types of the formats.
+const (
+	ContentTypeJSONL  = "application/x-ndjson"
+	ContentTypeBinary = "application/octet-stream"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unsupported dump format, use one of: jsonl, binary")
+	ErrInvalidDump   = errors.New("invalid dump")
+)
+
+// ContentType returns the content type of the format.
+func ContentType(format string) string {
+	if format == FormatBinary {
+		return ContentTypeBinary
+	}
+
+	return ContentTypeJSONL
+}
+
+// Writer writes the entries of the dump.
+type Writer interface {
+	// Write writes the entry.
+	Write(e qqcache.Entry) error
+
+	// Close writes the end of the dump and flushes buffered data,
+	// the underlying writer is not closed.
+	Close() error
+}
+
+// NewWriter returns the writer of the dump in the format.
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatJSONL:
+		return newJSONLWriter(w), nil
+	case FormatBinary:
+		return newBinaryWriter(w), nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// entryDecoder reads the entries of the dump.
+type entryDecoder interface {
+	decode() (qqcache.Entry, error)
+}
+
+// Reader reads the entries of the dump, the format is detected by the header.
+type Reader struct {
+	format string
+	dec    entryDecoder
+}
+
+// NewReader returns the reader of the dump.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(len(binaryMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if bytes.Equal(header, binaryMagic) {
+		dec, err := newBinaryDecoder(br)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Reader{format: FormatBinary, dec: dec}, nil
+	}
+
+	return &Reader{format: FormatJSONL, dec: &jsonlDecoder{r: br}}, nil
+}
+
+// Format method returns the format of the dump.
+func (r *Reader) Format() string {
+	return r.format
+}
+
+// Read method returns the next entry, io.EOF is returned at the end of the dump.
+func (r *Reader) Read() (qqcache.Entry, error) {
+	return r.dec.decode()
+}
+
+// jsonlEntry represents an entry of the dump in JSON Lines format.
+type jsonlEntry struct {
+	Key         string          `json:"key"`
+	Type        string          `json:"type"`
+	Value       json.RawMessage `json:"value"`
+	ContentType string          `json:"content_type,omitempty"`
+
+	// TTLMs is the remaining TTL in milliseconds, it's omitted for persistent keys.
+	TTLMs int64 `json:"ttl_ms,omitempty"`
+}
+
+// jsonlGeoMember represents a member of geo index in JSON Lines format.
+type jsonlGeoMember struct {
+	Member    string  `json:"member"`
+	Longitude float64 `json:"longitude"`
+	Latitude  float64 `json:"latitude"`
+}
+
+// jsonlWriter writes the entries as JSON Lines.
+type jsonlWriter struct {
+	w *bufio.Writer
+}
+
+func newJSONLWriter(w io.Writer) *jsonlWriter {
+	return &jsonlWriter{w: bufio.NewWriter(w)}
+}
+
+func (w *jsonlWriter) Write(e qqcache.Entry) error {
+	var value interface{} = e.Value
+	if members, ok := e.Value.([]qqcache.GeoMember); ok {
+		geo := make([]jsonlGeoMember, 0, len(members))
+		for _, m := range members {
+			geo = append(geo, jsonlGeoMember{Member: m.Name, Longitude: m.Longitude, Latitude: m.Latitude})
+		}
+		value = geo
+	}
+
+	data, err := marshal(value)
+	if err != nil {
+		return err
+	}
+	line, err := marshal(jsonlEntry{
+		Key:         e.Key,
+		Type:        e.Type,
+		Value:       data,
+		ContentType: e.ContentType,
+		TTLMs:       ttlToMs(e.TTL),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := w.w.Write(line); err != nil {
+		return err
+	}
+
+	return w.w.WriteByte('\n')
+}
+
+func (w *jsonlWriter) Close() error {
+	return w.w.Flush()
+}
+
+// jsonlDecoder reads the entries from JSON Lines, empty lines are skipped.
+type jsonlDecoder struct {
+	r    *bufio.Reader
+	line int
+}
+
+func (d *jsonlDecoder) decode() (qqcache.Entry, error) {
+	for {
+		line, err := d.r.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return qqcache.Entry{}, err
+		}
+		d.line++
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		e, err := decodeJSONLEntry(line)
+		if err != nil {
+			return qqcache.Entry{}, fmt.Errorf("%w: line %d: %s", ErrInvalidDump, d.line, err)
+		}
+
+		return e, nil
+	}
+}
+
+// decodeJSONLEntry decodes the entry, numbers are decoded as json.Number
+// to keep their precision.
+func decodeJSONLEntry(line []byte) (qqcache.Entry, error) {
+	var raw jsonlEntry
+	if err := unmarshal(line, &raw); err != nil {
+		return qqcache.Entry{}, err
+	}
+	if raw.Key == "" {
+		return qqcache.Entry{}, errors.New("key is required")
+	}
+	if raw.TTLMs < 0 || raw.TTLMs > int64(math.MaxInt64/time.Millisecond) {
+		return qqcache.Entry{}, errors.New("ttl_ms is out of range")
+	}
+
+	e := qqcache.Entry{
+		Key:         raw.Key,
+		Type:        raw.Type,
+		ContentType: raw.ContentType,
+		TTL:         time.Duration(raw.TTLMs) * time.Millisecond,
+	}
+
+	var err error
+	switch raw.Type {
+	case qqcache.TypeBytes:
+		var data []byte
+		err = unmarshal(raw.Value, &data)
+		e.Value = data
+	case qqcache.TypeList:
+		var list []interface{}
+		err = unmarshal(raw.Value, &list)
+		e.Value = list
+	case qqcache.TypeHash:
+		var hm map[string]interface{}
+		err = unmarshal(raw.Value, &hm)
+		e.Value = hm
+	case qqcache.TypeGeo:
+		var geo []jsonlGeoMember
+		err = unmarshal(raw.Value, &geo)
+		members := make([]qqcache.GeoMember, 0, len(geo))
+		for _, m := range geo {
+			members = append(members, qqcache.GeoMember{
+				Name:     m.Member,
+				GeoPoint: qqcache.GeoPoint{Longitude: m.Longitude, Latitude: m.Latitude},
+			})
+		}
+		e.Value = members
+	case qqcache.TypeValue:
+		err = unmarshal(raw.Value, &e.Value)
+	default:
+		return qqcache.Entry{}, fmt.Errorf("unsupported type %q", raw.Type)
+	}
+	if err != nil {
+		return qqcache.Entry{}, fmt.Errorf("invalid value: %w", err)
+	}
+
+	return e, nil
+}
+
+// marshal returns JSON encoding of v without HTML escaping and trailing newline.
+func marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// unmarshal decodes JSON with numbers decoded as json.Number.
+func unmarshal(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected data after JSON value")
+	}
+
+	return nil
+}
+
+// ttlToMs returns TTL in milliseconds rounded up,
+// so the keys that are about to expire are not dumped as persistent.
+func ttlToMs(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+
+	return int64((ttl + time.Millisecond - 1) / time.Millisecond)
+}
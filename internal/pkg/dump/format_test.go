@@ -0,0 +1,194 @@
+package dump
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestEntries() []qqcache.Entry {
+	return []qqcache.Entry{
+		{Key: "value", Type: qqcache.TypeValue, Value: "some-value", TTL: 1500 * time.Millisecond},
+		{Key: "number", Type: qqcache.TypeValue, Value: json.Number("12345678901234567890.5")},
+		{Key: "null", Type: qqcache.TypeValue},
+		{Key: "bytes", Type: qqcache.TypeBytes, Value: []byte{0, 0xff, '\n'}, ContentType: "image/png"},
+		{Key: "list", Type: qqcache.TypeList, Value: []interface{}{
+			json.Number("1"), json.Number("-2"), "<a>", true, false, nil,
+			[]interface{}{}, map[string]interface{}{"k": []interface{}{json.Number("1e3")}},
+		}},
+		{Key: "hash", Type: qqcache.TypeHash, Value: map[string]interface{}{"k0": "v0", "k1": json.Number("1")}},
+		{Key: "geo", Type: qqcache.TypeGeo, Value: []qqcache.GeoMember{
+			{Name: "Palermo", GeoPoint: qqcache.GeoPoint{Longitude: 13.361389, Latitude: 38.115556}},
+		}},
+	}
+}
+
+func writeDump(t *testing.T, format string, entries []qqcache.Entry) []byte {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format)
+	require.NoError(t, err)
+	for _, e := range entries {
+		require.NoError(t, w.Write(e))
+	}
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func readDump(data []byte) (string, []qqcache.Entry, error) {
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", nil, err
+	}
+
+	var entries []qqcache.Entry
+	for {
+		e, err := r.Read()
+		if err == io.EOF {
+			return r.Format(), entries, nil
+		}
+		if err != nil {
+			return r.Format(), entries, err
+		}
+		entries = append(entries, e)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	for _, format := range []string{FormatJSONL, FormatBinary} {
+		t.Run(format, func(t *testing.T) {
+			data := writeDump(t, format, getTestEntries())
+
+			detected, entries, err := readDump(data)
+			require.NoError(t, err)
+			require.Equal(t, format, detected)
+			require.Equal(t, getTestEntries(), entries)
+
+			// Empty dump
+			detected, entries, err = readDump(writeDump(t, format, nil))
+			require.NoError(t, err)
+			require.Equal(t, format, detected)
+			require.Empty(t, entries)
+		})
+	}
+}
+
+func TestFormats_Size(t *testing.T) {
+	jsonl := writeDump(t, FormatJSONL, getTestEntries())
+	require.Equal(t, `{"key":"value","type":"value","value":"some-value","ttl_ms":1500}`,
+		strings.SplitN(string(jsonl), "\n", 2)[0])
+
+	binary := writeDump(t, FormatBinary, getTestEntries())
+	require.True(t, len(binary) < len(jsonl)/2)
+}
+
+func TestFormats_OtherTypes(t *testing.T) {
+	entries := []qqcache.Entry{
+		{Key: "int", Type: qqcache.TypeValue, Value: 42},
+		{Key: "float", Type: qqcache.TypeValue, Value: 0.5},
+		{Key: "strings", Type: qqcache.TypeValue, Value: []string{"a"}},
+	}
+
+	for _, format := range []string{FormatJSONL, FormatBinary} {
+		t.Run(format, func(t *testing.T) {
+			_, restored, err := readDump(writeDump(t, format, entries))
+			require.NoError(t, err)
+			require.Len(t, restored, 3)
+			require.Equal(t, json.Number("42"), restored[0].Value)
+			require.Equal(t, []interface{}{"a"}, restored[2].Value)
+		})
+	}
+}
+
+func TestNewWriter_UnknownFormat(t *testing.T) {
+	_, err := NewWriter(&bytes.Buffer{}, "xml")
+	require.Equal(t, ErrUnknownFormat, err)
+}
+
+func TestReader_InvalidJSONL(t *testing.T) {
+	tests := []string{
+		`{"key":"k","type":"value","value":"v"}` + "\n" + `{"key":"k"`,
+		`{"key":"k","type":"unknown","value":"v"}`,
+		`{"key":"","type":"value","value":"v"}`,
+		`{"key":"k","type":"list","value":"v"}`,
+		`{"key":"k","type":"value","value":"v","ttl_ms":-1}`,
+		`{"key":"k","type":"value","value":"v"} {}`,
+	}
+
+	for _, tc := range tests {
+		t.Run(tc, func(t *testing.T) {
+			_, _, err := readDump([]byte(tc))
+			require.True(t, errors.Is(err, ErrInvalidDump))
+		})
+	}
+
+	// Empty lines are skipped
+	_, entries, err := readDump([]byte("\n" + `{"key":"k","type":"value","value":"v"}` + "\n\n"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// Line number is reported
+	_, _, err = readDump([]byte(`{"key":"k","type":"value","value":"v"}` + "\n\n{"))
+	require.Contains(t, err.Error(), "line 3")
+}
+
+func TestReader_InvalidBinary(t *testing.T) {
+	data := writeDump(t, FormatBinary, getTestEntries())
+
+	// Truncated dump
+	for _, n := range []int{len(data) - 1, len(data) - 10, len(binaryMagic) + 1} {
+		_, _, err := readDump(data[:n])
+		require.True(t, errors.Is(err, ErrInvalidDump))
+	}
+
+	// Unsupported version
+	invalid := append([]byte(nil), data...)
+	invalid[len(binaryMagic)] = 2
+	_, _, err := readDump(invalid)
+	require.True(t, errors.Is(err, ErrInvalidDump))
+
+	// Unsupported type
+	invalid = append(append([]byte(nil), binaryMagic...), binaryVersion, 42)
+	_, _, err = readDump(invalid)
+	require.True(t, errors.Is(err, ErrInvalidDump))
+
+	// Data after the end marker is ignored
+	_, entries, err := readDump(append(data, 1, 2, 3))
+	require.NoError(t, err)
+	require.Len(t, entries, len(getTestEntries()))
+}
+
+func TestEncodeEntry(t *testing.T) {
+	var buf bytes.Buffer
+	for _, e := range getTestEntries() {
+		require.NoError(t, EncodeEntry(&buf, e))
+	}
+	size := buf.Len()
+
+	// Invalid entries are not written
+	require.Equal(t, qqcache.ErrInvalidEntry, EncodeEntry(&buf, qqcache.Entry{Key: "list", Type: qqcache.TypeList}))
+	require.Error(t, EncodeEntry(&buf, qqcache.Entry{Key: "func", Type: qqcache.TypeList,
+		Value: []interface{}{"item", func() {}}}))
+	require.Equal(t, size, buf.Len())
+
+	r := bufio.NewReader(&buf)
+	for _, expected := range getTestEntries() {
+		e, err := DecodeEntry(r)
+		require.NoError(t, err)
+		require.Equal(t, expected, e)
+	}
+	_, err := DecodeEntry(r)
+	require.True(t, errors.Is(err, ErrInvalidDump))
+	require.Contains(t, err.Error(), io.ErrUnexpectedEOF.Error())
+
+	_, err = DecodeEntry(bufio.NewReader(bytes.NewReader([]byte{binaryEnd})))
+	require.True(t, errors.Is(err, ErrInvalidDump))
+}
@@ -0,0 +1,169 @@
+package grpc
+
+import (
+	"context"
+	"path"
+	"strconv"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/auth"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/ratelimit"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const errPermissionDenied = "permission denied"
+
+// methodCommands contains the commands of the public API run by the methods
+// of CacheService, watching of the keys requires the get command.
+var methodCommands = map[string]string{
+	"Get":    auth.CommandGet,
+	"Set":    auth.CommandSet,
+	"Remove": auth.CommandRemove,
+	"Keys":   auth.CommandKeys,
+	"RPush":  auth.CommandRPush,
+	"LIndex": auth.CommandLIndex,
+	"HSet":   auth.CommandHSet,
+	"HGet":   auth.CommandHGet,
+	"Watch":  auth.CommandGet,
+}
+
+// AccessOpts represents the options of the access control of the calls,
+// the same authenticator, ACL and limiter as for the public API are used.
+type AccessOpts struct {
+	// Auth authenticates the calls by the bearer token passed in the authorization
+	// metadata or by the client certificate. HMAC signatures are not supported.
+	Auth *auth.Authenticator
+
+	// ACL checks the commands and the keys of the calls.
+	ACL *auth.ACL
+
+	// Limiter applies the default and command class limits and limits
+	// failed authentication attempts.
+	Limiter *ratelimit.Limiter
+
+	Log *zap.Logger
+}
+
+// access checks the calls of CacheService.
+type access struct {
+	opts AccessOpts
+}
+
+// WithAccess returns the server options with interceptors that authenticate
+// the calls, check ACL and rate limits before the methods are run.
+// Keys of the streams are checked by the methods as they are received.
+func WithAccess(opts AccessOpts) []grpc.ServerOption {
+	if opts.Log == nil {
+		opts.Log = zap.NewNop()
+	}
+	a := &access{opts: opts}
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(a.unary),
+		grpc.ChainStreamInterceptor(a.stream),
+	}
+}
+
+func (a *access) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	command := methodCommands[path.Base(info.FullMethod)]
+	ctx, err := a.check(ctx, command)
+	if err != nil {
+		return nil, err
+	}
+	if r, ok := req.(interface{ GetKey() string }); ok && !auth.IsAllowed(ctx, command, r.GetKey()) {
+		return nil, status.Error(codes.PermissionDenied, errPermissionDenied)
+	}
+
+	return handler(ctx, req)
+}
+
+func (a *access) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	ctx, err := a.check(ss.Context(), methodCommands[path.Base(info.FullMethod)])
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+// check method authenticates the call, checks that the command is allowed
+// on any key and the rate limits are not exceeded. It returns the context
+// with the identity and ACL.
+func (a *access) check(ctx context.Context, command string) (context.Context, error) {
+	remoteAddr := ""
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remoteAddr = p.Addr.String()
+	}
+
+	if a.opts.Auth.Enabled() {
+		// Failed authentication attempts are limited before authentication
+		if retryAfter, ok := a.opts.Limiter.AuthAllowed(ctx, remoteAddr); !ok {
+			return nil, rateLimited(ctx, retryAfter)
+		}
+		identity, err := a.authenticate(ctx)
+		if err != nil {
+			a.opts.Log.Debug("authentication failed",
+				zap.String("remote_addr", remoteAddr),
+				zap.Error(err))
+			a.opts.Limiter.AuthFailed(remoteAddr)
+
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		ctx = auth.WithIdentity(ctx, identity)
+	}
+	if a.opts.ACL != nil {
+		ctx = auth.WithACL(ctx, a.opts.ACL)
+	}
+
+	if !auth.IsCommandAllowed(ctx, command) {
+		return nil, status.Error(codes.PermissionDenied, errPermissionDenied)
+	}
+	if retryAfter, ok := a.opts.Limiter.AllowCall(ctx, remoteAddr, auth.CommandPermission(command)); !ok {
+		return nil, rateLimited(ctx, retryAfter)
+	}
+
+	return ctx, nil
+}
+
+// rateLimited returns ResourceExhausted error and sends the number of seconds
+// to wait before retrying in the retry-after header.
+func rateLimited(ctx context.Context, retryAfter int) error {
+	_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(retryAfter)))
+
+	return status.Error(codes.ResourceExhausted, ratelimit.ErrRateLimitExceeded.Error())
+}
+
+// authenticate method returns the identity of the client by the authorization
+// metadata or by the client certificate.
+func (a *access) authenticate(ctx context.Context) (auth.Identity, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("authorization"); len(values) > 0 {
+		return a.opts.Auth.AuthenticateToken(values[0])
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			return a.opts.Auth.AuthenticateTLS(&info.State)
+		}
+	}
+
+	return auth.Identity{}, auth.ErrAuthRequired
+}
+
+// serverStream replaces the context of the stream.
+type serverStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,370 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/grpcclient"
+	"github.com/dstdfx/bookish-spork/internal/pkg/backend"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/auth"
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// defaultRawContentType is used for raw values that are set without content type.
+const defaultRawContentType = "application/octet-stream"
+
+// NewServer initializes gRPC server with registered CacheService.
+// Calls are checked by the public API access control if WithAccess options are given.
+func NewServer(b *backend.Backend, opts ...grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(opts...)
+	grpcclient.RegisterCacheServiceServer(s, &cacheService{b: b})
+
+	return s
+}
+
+// cacheService implements CacheService on top of the backend.
+type cacheService struct {
+	grpcclient.UnimplementedCacheServiceServer
+
+	b *backend.Backend
+}
+
+// cache method returns the cache that reports the peer address
+// of the client in the slow log.
+func (s *cacheService) cache(ctx context.Context) *qqcache.Cache {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return s.b.Cache.WithClient(p.Addr.String())
+	}
+
+	return s.b.Cache
+}
+
+// writable method returns FailedPrecondition error if the backend is read-only.
+func (s *cacheService) writable() error {
+	if s.b.ReadOnly {
+		return status.Error(codes.FailedPrecondition, backend.ErrReadOnly.Error())
+	}
+
+	return nil
+}
+
+func (s *cacheService) Get(ctx context.Context, req *grpcclient.GetRequest) (*grpcclient.GetResponse, error) {
+	if req.GetKey() == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+
+	value, contentType, ok := s.cache(ctx).GetWithContentType(req.GetKey())
+	if !ok {
+		return nil, status.Error(codes.NotFound, "key not found")
+	}
+
+	// Raw values are returned as is
+	if raw, isRaw := value.([]byte); isRaw && contentType != "" {
+		return &grpcclient.GetResponse{
+			Value: &grpcclient.Value{
+				Kind:        &grpcclient.Value_Raw{Raw: raw},
+				ContentType: contentType,
+			},
+		}, nil
+	}
+
+	v, err := toProtoValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &grpcclient.GetResponse{
+		Value: &grpcclient.Value{Kind: &grpcclient.Value_Json{Json: v}},
+	}, nil
+}
+
+func (s *cacheService) Set(ctx context.Context, req *grpcclient.SetRequest) (*grpcclient.SetResponse, error) {
+	if err := s.writable(); err != nil {
+		return nil, err
+	}
+	if req.GetKey() == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+
+	ttl := time.Duration(req.GetTtl()) * time.Second
+	switch kind := req.GetValue().GetKind().(type) {
+	case *grpcclient.Value_Raw:
+		contentType := req.GetValue().GetContentType()
+		if contentType == "" {
+			contentType = defaultRawContentType
+		}
+		s.cache(ctx).SetBytes(req.GetKey(), kind.Raw, contentType, ttl)
+	case *grpcclient.Value_Json:
+		value, err := fromProtoValue(kind.Json)
+		if err != nil {
+			return nil, err
+		}
+		s.cache(ctx).Set(req.GetKey(), value, ttl)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "value is required")
+	}
+
+	return &grpcclient.SetResponse{}, nil
+}
+
+func (s *cacheService) Remove(ctx context.Context, req *grpcclient.RemoveRequest) (*grpcclient.RemoveResponse, error) {
+	if err := s.writable(); err != nil {
+		return nil, err
+	}
+	if req.GetKey() == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+
+	return &grpcclient.RemoveResponse{Removed: s.cache(ctx).Remove(req.GetKey())}, nil
+}
+
+func (s *cacheService) Keys(_ *grpcclient.KeysRequest, stream grpcclient.CacheService_KeysServer) error {
+	ctx := stream.Context()
+	for _, k := range s.cache(ctx).Keys() {
+		// Send only the keys the client is allowed to see
+		if !auth.IsAllowed(ctx, auth.CommandKeys, k) {
+			continue
+		}
+		if err := stream.Send(&grpcclient.KeysResponse{Key: k}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *cacheService) RPush(ctx context.Context, req *grpcclient.RPushRequest) (*grpcclient.RPushResponse, error) {
+	if err := s.writable(); err != nil {
+		return nil, err
+	}
+	if req.GetKey() == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+	if req.GetValue() == nil {
+		return nil, status.Error(codes.InvalidArgument, "value is required")
+	}
+
+	value, err := fromProtoValue(req.GetValue())
+	if err != nil {
+		return nil, err
+	}
+	err = s.cache(ctx).RPush(req.GetKey(), value, time.Duration(req.GetTtl())*time.Second)
+	if err != nil {
+		return nil, cacheErr(err)
+	}
+
+	return &grpcclient.RPushResponse{}, nil
+}
+
+func (s *cacheService) LIndex(ctx context.Context, req *grpcclient.LIndexRequest) (*grpcclient.LIndexResponse, error) {
+	if req.GetKey() == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+	if req.GetIndex() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "index must be a non-negative integer")
+	}
+
+	value, err := s.cache(ctx).LIndex(req.GetKey(), int(req.GetIndex()))
+	if err != nil {
+		return nil, cacheErr(err)
+	}
+	if value == nil {
+		return &grpcclient.LIndexResponse{}, nil
+	}
+
+	v, err := toProtoValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &grpcclient.LIndexResponse{Value: v}, nil
+}
+
+func (s *cacheService) HSet(ctx context.Context, req *grpcclient.HSetRequest) (*grpcclient.HSetResponse, error) {
+	if err := s.writable(); err != nil {
+		return nil, err
+	}
+	if req.GetKey() == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+	if len(req.GetFields()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "fields are required")
+	}
+
+	fields := make(map[string]interface{}, len(req.GetFields()))
+	for k, v := range req.GetFields() {
+		value, err := fromProtoValue(v)
+		if err != nil {
+			return nil, err
+		}
+		fields[k] = value
+	}
+
+	if err := s.cache(ctx).HSet(req.GetKey(), fields, time.Duration(req.GetTtl())*time.Second); err != nil {
+		return nil, cacheErr(err)
+	}
+
+	return &grpcclient.HSetResponse{}, nil
+}
+
+func (s *cacheService) HGet(ctx context.Context, req *grpcclient.HGetRequest) (*grpcclient.HGetResponse, error) {
+	if req.GetKey() == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+	if req.GetField() == "" {
+		return nil, status.Error(codes.InvalidArgument, "field is required")
+	}
+
+	value, err := s.cache(ctx).HGet(req.GetKey(), req.GetField())
+	if err != nil {
+		return nil, cacheErr(err)
+	}
+	if value == nil {
+		return &grpcclient.HGetResponse{}, nil
+	}
+
+	v, err := toProtoValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &grpcclient.HGetResponse{Value: v}, nil
+}
+
+func (s *cacheService) Watch(req *grpcclient.WatchRequest, stream grpcclient.CacheService_WatchServer) error {
+	ctx := stream.Context()
+	for _, k := range req.GetKeys() {
+		if !auth.IsAllowed(ctx, auth.CommandGet, k) {
+			return status.Error(codes.PermissionDenied, errPermissionDenied)
+		}
+	}
+
+	events, stop := s.b.Cache.Watch(req.GetKeys()...)
+	defer stop()
+
+	// Send headers to let the client know that subscription is active
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-events:
+			if !ok {
+				return status.Error(codes.Aborted, "watch has been interrupted, subscribe again")
+			}
+			// Events of all keys are sent only for the keys the client is allowed to get
+			if !auth.IsAllowed(ctx, auth.CommandGet, e.Key) {
+				continue
+			}
+
+			err := stream.Send(&grpcclient.WatchEvent{
+				Type: toProtoEventType(e.Type),
+				Key:  e.Key,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// cacheErr converts cache error to gRPC status error.
+func cacheErr(err error) error {
+	switch err {
+	case qqcache.ErrNotFound:
+		return status.Error(codes.NotFound, "key not found")
+	case qqcache.ErrWrongTypeIndex,
+		qqcache.ErrWrongTypeLPush,
+		qqcache.ErrWrongTypeHSet,
+		qqcache.ErrWrongTypeHGet:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// toProtoValue converts cached value to protobuf value the same way
+// it's represented in JSON responses of the HTTP API.
+func toProtoValue(value interface{}) (*structpb.Value, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode value: %v", err)
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode value: %v", err)
+	}
+
+	pv, err := structpb.NewValue(v)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode value: %v", err)
+	}
+
+	return pv, nil
+}
+
+// fromProtoValue converts protobuf value to the value to cache. Numbers are
+// stored as json.Number the same way they're decoded from HTTP API requests.
+func fromProtoValue(v *structpb.Value) (interface{}, error) {
+	value, err := toJSONNumbers(v.AsInterface())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "value is invalid: %v", err)
+	}
+
+	return value, nil
+}
+
+// toJSONNumbers replaces float64 numbers of the decoded protobuf value with json.Number.
+func toJSONNumbers(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case float64:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Number(data), nil
+	case []interface{}:
+		for i := range v {
+			item, err := toJSONNumbers(v[i])
+			if err != nil {
+				return nil, err
+			}
+			v[i] = item
+		}
+	case map[string]interface{}:
+		for k := range v {
+			item, err := toJSONNumbers(v[k])
+			if err != nil {
+				return nil, err
+			}
+			v[k] = item
+		}
+	}
+
+	return v, nil
+}
+
+func toProtoEventType(t qqcache.EventType) grpcclient.WatchEvent_Type {
+	switch t {
+	case qqcache.EventSet:
+		return grpcclient.WatchEvent_TYPE_SET
+	case qqcache.EventRemove:
+		return grpcclient.WatchEvent_TYPE_REMOVE
+	case qqcache.EventExpire:
+		return grpcclient.WatchEvent_TYPE_EXPIRE
+	default:
+		return grpcclient.WatchEvent_TYPE_UNSPECIFIED
+	}
+}
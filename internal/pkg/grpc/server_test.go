@@ -0,0 +1,420 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/dstdfx/bookish-spork/grpcclient"
+	"github.com/dstdfx/bookish-spork/internal/pkg/backend"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/auth"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/ratelimit"
+	"github.com/dstdfx/bookish-spork/internal/pkg/log"
+	"github.com/dstdfx/bookish-spork/internal/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	testKey   = "test-key"
+	testValue = "test-value"
+)
+
+// initTestClient runs gRPC server on in-memory listener and returns client connected to it.
+func initTestClient(t *testing.T, opts ...grpc.ServerOption) (*backend.Backend, *grpcclient.Client, func()) {
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
+
+	// Initialize logger
+	logger, err := log.InitLogger(log.InitLoggerOpts{
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
+	})
+	require.NoError(t, err)
+
+	// Prepare backend and server
+	b := backend.New(cfg.Cache, logger)
+	srv := NewServer(b, opts...)
+	lis := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+
+	dialer := func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}
+	client, err := grpcclient.NewClient("bufnet", grpc.WithContextDialer(dialer), grpc.WithInsecure())
+	require.NoError(t, err)
+
+	return b, client, func() {
+		_ = client.Close()
+		srv.Stop()
+		b.Shutdown()
+	}
+}
+
+func TestServer_SetGetRemove(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	_, client, stop := initTestClient(t)
+	defer stop()
+	ctx := context.Background()
+
+	value, err := grpcclient.NewJSONValue(map[string]interface{}{"a": []interface{}{1.0, "b"}})
+	require.NoError(t, err)
+	_, err = client.Set(ctx, &grpcclient.SetRequest{Key: testKey, Value: value, Ttl: 10})
+	require.NoError(t, err)
+
+	resp, err := client.Get(ctx, &grpcclient.GetRequest{Key: testKey})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"a": []interface{}{1.0, "b"}}, resp.GetValue().GetJson().AsInterface())
+
+	removed, err := client.Remove(ctx, &grpcclient.RemoveRequest{Key: testKey})
+	require.NoError(t, err)
+	assert.True(t, removed.GetRemoved())
+
+	removed, err = client.Remove(ctx, &grpcclient.RemoveRequest{Key: testKey})
+	require.NoError(t, err)
+	assert.False(t, removed.GetRemoved())
+
+	_, err = client.Get(ctx, &grpcclient.GetRequest{Key: testKey})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_SetRaw(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	b, client, stop := initTestClient(t)
+	defer stop()
+	ctx := context.Background()
+
+	_, err := client.Set(ctx, &grpcclient.SetRequest{
+		Key:   testKey,
+		Value: grpcclient.NewRawValue([]byte{0, 1, 2}, "application/msgpack"),
+	})
+	require.NoError(t, err)
+
+	// Raw value is shared with HTTP API
+	v, contentType, ok := b.Cache.GetWithContentType(testKey)
+	require.True(t, ok)
+	assert.Equal(t, []byte{0, 1, 2}, v)
+	assert.Equal(t, "application/msgpack", contentType)
+
+	resp, err := client.Get(ctx, &grpcclient.GetRequest{Key: testKey})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 1, 2}, resp.GetValue().GetRaw())
+	assert.Equal(t, "application/msgpack", resp.GetValue().GetContentType())
+}
+
+func TestServer_Set_InvalidArgument(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	_, client, stop := initTestClient(t)
+	defer stop()
+	ctx := context.Background()
+
+	_, err := client.Set(ctx, &grpcclient.SetRequest{Key: testKey})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, "value is required", status.Convert(err).Message())
+
+	_, err = client.Get(ctx, &grpcclient.GetRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_Keys(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	b, client, stop := initTestClient(t)
+	defer stop()
+
+	b.Cache.Set("a", testValue, 0)
+	b.Cache.Set("b", testValue, 0)
+
+	stream, err := client.Keys(context.Background(), &grpcclient.KeysRequest{})
+	require.NoError(t, err)
+
+	keys := make([]string, 0)
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		keys = append(keys, resp.GetKey())
+	}
+	sort.Strings(keys)
+	assert.Equal(t, []string{"a", "b"}, keys)
+}
+
+func TestServer_RPushLIndex(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	b, client, stop := initTestClient(t)
+	defer stop()
+	ctx := context.Background()
+
+	_, err := client.RPush(ctx, &grpcclient.RPushRequest{Key: testKey, Value: structpb.NewStringValue(testValue)})
+	require.NoError(t, err)
+
+	resp, err := client.LIndex(ctx, &grpcclient.LIndexRequest{Key: testKey, Index: 0})
+	require.NoError(t, err)
+	assert.Equal(t, testValue, resp.GetValue().GetStringValue())
+
+	// Index out of range
+	resp, err = client.LIndex(ctx, &grpcclient.LIndexRequest{Key: testKey, Index: 1})
+	require.NoError(t, err)
+	assert.Nil(t, resp.GetValue())
+
+	_, err = client.LIndex(ctx, &grpcclient.LIndexRequest{Key: testKey, Index: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.LIndex(ctx, &grpcclient.LIndexRequest{Key: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// Pushing to not a list
+	b.Cache.Set("str", testValue, 0)
+	_, err = client.RPush(ctx, &grpcclient.RPushRequest{Key: "str", Value: structpb.NewStringValue(testValue)})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestServer_HSetHGet(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	_, client, stop := initTestClient(t)
+	defer stop()
+	ctx := context.Background()
+
+	_, err := client.HSet(ctx, &grpcclient.HSetRequest{
+		Key:    testKey,
+		Fields: map[string]*structpb.Value{"field": structpb.NewNumberValue(42)},
+	})
+	require.NoError(t, err)
+
+	resp, err := client.HGet(ctx, &grpcclient.HGetRequest{Key: testKey, Field: "field"})
+	require.NoError(t, err)
+	assert.Equal(t, float64(42), resp.GetValue().GetNumberValue())
+
+	resp, err = client.HGet(ctx, &grpcclient.HGetRequest{Key: testKey, Field: "missing"})
+	require.NoError(t, err)
+	assert.Nil(t, resp.GetValue())
+
+	_, err = client.HSet(ctx, &grpcclient.HSetRequest{Key: testKey})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_Watch(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	b, client, stop := initTestClient(t)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.Watch(ctx, &grpcclient.WatchRequest{Keys: []string{testKey}})
+	require.NoError(t, err)
+
+	// Wait for the subscription, headers are sent once it is active
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	b.Cache.Set("other-key", testValue, 0)
+	b.Cache.Set(testKey, testValue, 0)
+	b.Cache.Remove(testKey)
+
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, grpcclient.WatchEvent_TYPE_SET, event.GetType())
+	assert.Equal(t, testKey, event.GetKey())
+
+	event, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, grpcclient.WatchEvent_TYPE_REMOVE, event.GetType())
+	assert.Equal(t, testKey, event.GetKey())
+}
+
+func TestServer_ReadOnly(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	b, client, stop := initTestClient(t)
+	defer stop()
+	ctx := context.Background()
+
+	b.Cache.Set(testKey, testValue, 0)
+	b.ReadOnly = true
+
+	_, err := client.Set(ctx, &grpcclient.SetRequest{Key: testKey, Value: grpcclient.NewRawValue([]byte{0}, "")})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, backend.ErrReadOnly.Error(), status.Convert(err).Message())
+	_, err = client.Remove(ctx, &grpcclient.RemoveRequest{Key: testKey})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = client.RPush(ctx, &grpcclient.RPushRequest{Key: testKey, Value: structpb.NewStringValue("a")})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = client.HSet(ctx, &grpcclient.HSetRequest{Key: testKey,
+		Fields: map[string]*structpb.Value{"k": structpb.NewStringValue("v")}})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	resp, err := client.Get(ctx, &grpcclient.GetRequest{Key: testKey})
+	require.NoError(t, err)
+	assert.Equal(t, testValue, resp.GetValue().GetJson().AsInterface())
+}
+
+func TestServer_Numbers(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	b, client, stop := initTestClient(t)
+	defer stop()
+	ctx := context.Background()
+
+	// Numbers are cached the same way as they're set with HTTP API
+	value, err := grpcclient.NewJSONValue(map[string]interface{}{"a": []interface{}{1.5}})
+	require.NoError(t, err)
+	_, err = client.Set(ctx, &grpcclient.SetRequest{Key: testKey, Value: value})
+	require.NoError(t, err)
+	v, ok := b.Cache.Get(testKey)
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"a": []interface{}{json.Number("1.5")}}, v)
+
+	_, err = client.RPush(ctx, &grpcclient.RPushRequest{Key: "list", Value: structpb.NewNumberValue(2)})
+	require.NoError(t, err)
+	v, err = b.Cache.LIndex("list", 0)
+	require.NoError(t, err)
+	assert.Equal(t, json.Number("2"), v)
+
+	_, err = client.HSet(ctx, &grpcclient.HSetRequest{
+		Key:    "hash",
+		Fields: map[string]*structpb.Value{"field": structpb.NewNumberValue(42)},
+	})
+	require.NoError(t, err)
+	v, err = b.Cache.HGet("hash", "field")
+	require.NoError(t, err)
+	assert.Equal(t, json.Number("42"), v)
+}
+
+func TestServer_Access(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	dir, err := ioutil.TempDir("", "grpc-access")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tokensFile := filepath.Join(dir, "tokens")
+	require.NoError(t, ioutil.WriteFile(tokensFile, []byte("reporting:reporting-token\nadmin:admin-token\n"), 0o600))
+
+	authenticator, err := auth.New(auth.Opts{TokensFile: tokensFile})
+	require.NoError(t, err)
+	defer authenticator.Close()
+	acl, err := auth.NewACL(auth.ACLOpts{
+		Roles: map[string][]auth.ACLRule{
+			"reporting": {{Keys: []string{"reports:*"}, Permissions: []string{auth.PermissionRead}}},
+			"admin":     {{Keys: []string{"*"}, Permissions: []string{auth.PermissionAdmin}}},
+		},
+		Users: map[string][]string{"reporting": {"reporting"}, "admin": {"admin"}},
+	})
+	require.NoError(t, err)
+	limiter, err := ratelimit.New(ratelimit.Opts{Classes: map[string]ratelimit.Limit{
+		auth.PermissionWrite: {Rate: 0.001, Burst: 3},
+	}})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	b, client, stop := initTestClient(t, WithAccess(AccessOpts{Auth: authenticator, ACL: acl, Limiter: limiter})...)
+	defer stop()
+	b.Cache.Set("reports:daily", testValue, 0)
+	b.Cache.Set("secret", testValue, 0)
+
+	withToken := func(token string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	}
+	reporting, admin := withToken("reporting-token"), withToken("admin-token")
+
+	// Authentication is required
+	_, err = client.Get(context.Background(), &grpcclient.GetRequest{Key: "reports:daily"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.Get(withToken("invalid"), &grpcclient.GetRequest{Key: "reports:daily"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// Commands and keys are checked by ACL
+	_, err = client.Get(reporting, &grpcclient.GetRequest{Key: "reports:daily"})
+	assert.NoError(t, err)
+	_, err = client.Get(reporting, &grpcclient.GetRequest{Key: "secret"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	value, err := grpcclient.NewJSONValue(testValue)
+	require.NoError(t, err)
+	_, err = client.Set(reporting, &grpcclient.SetRequest{Key: "reports:daily", Value: value})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Streams are filtered by ACL
+	keys, err := client.Keys(reporting, &grpcclient.KeysRequest{})
+	require.NoError(t, err)
+	var received []string
+	for {
+		resp, err := keys.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		received = append(received, resp.GetKey())
+	}
+	assert.Equal(t, []string{"reports:daily"}, received)
+
+	watch, err := client.Watch(reporting, &grpcclient.WatchRequest{Keys: []string{"secret"}})
+	require.NoError(t, err)
+	_, err = watch.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Command class limits are applied
+	for i := 0; i < 3; i++ {
+		_, err = client.Set(admin, &grpcclient.SetRequest{Key: testKey, Value: value})
+		require.NoError(t, err)
+	}
+	var header metadata.MD
+	_, err = client.Set(admin, &grpcclient.SetRequest{Key: testKey, Value: value}, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.NotEmpty(t, header.Get("retry-after"))
+	_, err = client.Get(admin, &grpcclient.GetRequest{Key: testKey})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]uint64{"class:write": 1}, limiter.Throttled())
+}
@@ -0,0 +1,143 @@
+// Package health provides liveness, readiness and build info endpoints
+// of the service API.
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// Paths of the endpoints on the service API.
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+	VersionPath   = "/version"
+)
+
+// Readiness states.
+const (
+	StatusOK           = "ok"
+	StatusStarting     = "starting"
+	StatusShuttingDown = "shutting down"
+)
+
+const (
+	stateStarting int32 = iota
+	stateReady
+	stateShuttingDown
+)
+
+// BuildInfo contains build information injected at build time.
+type BuildInfo struct {
+	GitCommit string `json:"git_commit"`
+	GitTag    string `json:"git_tag"`
+	Date      string `json:"build_date"`
+	Compiler  string `json:"compiler"`
+}
+
+// VersionResponse represents the body of the version endpoint.
+type VersionResponse struct {
+	BuildInfo
+	GoVersion     string    `json:"go_version"`
+	StartedAt     time.Time `json:"started_at"`
+	UptimeSeconds int64     `json:"uptime_seconds"`
+}
+
+// StatusResponse represents the body of the liveness and readiness endpoints.
+type StatusResponse struct {
+	Status string `json:"status"`
+}
+
+// Health tracks the state of the service.
+// The service is not ready until SetReady is called and after SetShuttingDown
+// is called.
+type Health struct {
+	state     int32
+	build     BuildInfo
+	startedAt time.Time
+
+	// now is used to get current time, it's replaced in tests
+	now func() time.Time
+}
+
+// New returns new instance of Health in starting state.
+func New(build BuildInfo) *Health {
+	return &Health{
+		build:     build,
+		startedAt: time.Now().UTC(),
+		now:       time.Now,
+	}
+}
+
+// SetReady marks the service as ready to serve requests,
+// it should be called after the data is loaded and the servers are listening.
+// It has no effect after SetShuttingDown.
+func (h *Health) SetReady() {
+	atomic.CompareAndSwapInt32(&h.state, stateStarting, stateReady)
+}
+
+// SetShuttingDown marks the service as not ready, it should be called
+// at the start of graceful shutdown to let load balancers drain connections.
+func (h *Health) SetShuttingDown() {
+	atomic.StoreInt32(&h.state, stateShuttingDown)
+}
+
+// Status returns readiness status of the service.
+func (h *Health) Status() string {
+	switch atomic.LoadInt32(&h.state) {
+	case stateReady:
+		return StatusOK
+	case stateShuttingDown:
+		return StatusShuttingDown
+	default:
+		return StatusStarting
+	}
+}
+
+// Register adds the endpoints to the mux.
+func (h *Health) Register(mux *http.ServeMux) {
+	mux.HandleFunc(LivenessPath, h.Liveness)
+	mux.HandleFunc(ReadinessPath, h.Readiness)
+	mux.HandleFunc(VersionPath, h.Version)
+}
+
+// Liveness responds with 200 status code while the process is able to serve requests.
+func (h *Health) Liveness(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, StatusResponse{Status: StatusOK})
+}
+
+// Readiness responds with 200 status code if the service is ready and 503 otherwise.
+func (h *Health) Readiness(w http.ResponseWriter, _ *http.Request) {
+	status := h.Status()
+	code := http.StatusOK
+	if status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, code, StatusResponse{Status: status})
+}
+
+// Version responds with build info and uptime of the service.
+func (h *Health) Version(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.VersionInfo())
+}
+
+// VersionInfo returns build info and uptime of the service.
+func (h *Health) VersionInfo() VersionResponse {
+	return VersionResponse{
+		BuildInfo:     h.build,
+		GoVersion:     runtime.Version(),
+		StartedAt:     h.startedAt,
+		UptimeSeconds: int64(h.now().Sub(h.startedAt).Seconds()),
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,61 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func doRequest(t *testing.T, mux http.Handler, path string, v interface{}) int {
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	require.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), v))
+
+	return w.Code
+}
+
+func TestHealth_Readiness(t *testing.T) {
+	h := New(BuildInfo{})
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	var resp StatusResponse
+	require.Equal(t, http.StatusServiceUnavailable, doRequest(t, mux, ReadinessPath, &resp))
+	require.Equal(t, StatusStarting, resp.Status)
+
+	h.SetReady()
+	require.Equal(t, http.StatusOK, doRequest(t, mux, ReadinessPath, &resp))
+	require.Equal(t, StatusOK, resp.Status)
+
+	h.SetShuttingDown()
+	require.Equal(t, http.StatusServiceUnavailable, doRequest(t, mux, ReadinessPath, &resp))
+	require.Equal(t, StatusShuttingDown, resp.Status)
+
+	// Service can't become ready again after shutdown has been started
+	h.SetReady()
+	require.Equal(t, StatusShuttingDown, h.Status())
+
+	// Liveness doesn't depend on readiness
+	require.Equal(t, http.StatusOK, doRequest(t, mux, LivenessPath, &resp))
+	require.Equal(t, StatusOK, resp.Status)
+}
+
+func TestHealth_Version(t *testing.T) {
+	build := BuildInfo{GitCommit: "abc123", GitTag: "v1.2.3", Date: "2021-01-01", Compiler: "go1.15"}
+	h := New(build)
+	h.now = func() time.Time { return h.startedAt.Add(90 * time.Second) }
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	var resp VersionResponse
+	require.Equal(t, http.StatusOK, doRequest(t, mux, VersionPath, &resp))
+	require.Equal(t, build, resp.BuildInfo)
+	require.Equal(t, int64(90), resp.UptimeSeconds)
+	require.True(t, h.startedAt.Equal(resp.StartedAt))
+	require.NotEmpty(t, resp.GoVersion)
+}
@@ -0,0 +1,199 @@
+// Package accesslog provides request IDs and access logging of the public API requests.
+package accesslog
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/httputil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LevelNone disables logging of the requests.
+const LevelNone = "none"
+
+// Opts represents the options to create new instance of Logger.
+type Opts struct {
+	// Level is the level of the requests log entries, info by default.
+	// Requests are not logged if it's LevelNone.
+	Level string
+
+	// SampleRate is the fraction of the requests to be logged, from 0 to 1,
+	// all requests are logged if it's 0. Requests that failed with 5xx status
+	// are always logged.
+	SampleRate float64
+
+	// Routes contains levels by "<METHOD> <route pattern>", e.g. "GET /v1/keys".
+	Routes map[string]string
+
+	Log *zap.Logger
+}
+
+// Logger logs the requests.
+type Logger struct {
+	log        *zap.Logger
+	level      level
+	sampleRate float64
+	routes     map[string]level
+
+	// sample returns a random number in [0, 1), it's replaced in tests
+	sample func() float64
+	mux    sync.Mutex
+}
+
+// level is the log level, disabled level means requests are not logged.
+type level struct {
+	zapcore.Level
+	disabled bool
+}
+
+// entry contains request details set by the handlers.
+type entry struct {
+	mux    sync.Mutex
+	key    string
+	client string
+}
+
+// New returns new instance of Logger.
+func New(opts Opts) (*Logger, error) {
+	l := &Logger{
+		log:        opts.Log,
+		sampleRate: opts.SampleRate,
+		routes:     make(map[string]level, len(opts.Routes)),
+		sample:     rand.New(rand.NewSource(time.Now().UnixNano())).Float64,
+	}
+	if l.log == nil {
+		l.log = zap.NewNop()
+	}
+	if l.sampleRate < 0 || l.sampleRate > 1 {
+		return nil, fmt.Errorf("accesslog: sample rate must be from 0 to 1")
+	}
+	if l.sampleRate == 0 {
+		l.sampleRate = 1
+	}
+
+	var err error
+	if l.level, err = parseLevel(opts.Level); err != nil {
+		return nil, fmt.Errorf("accesslog: %w", err)
+	}
+	for route, lvl := range opts.Routes {
+		key, err := normalizeRoute(route)
+		if err != nil {
+			return nil, fmt.Errorf("accesslog: route %q: %w", route, err)
+		}
+		if l.routes[key], err = parseLevel(lvl); err != nil {
+			return nil, fmt.Errorf("accesslog: route %q: %w", route, err)
+		}
+	}
+
+	return l, nil
+}
+
+func parseLevel(s string) (level, error) {
+	switch s {
+	case "":
+		return level{Level: zapcore.InfoLevel}, nil
+	case LevelNone:
+		return level{disabled: true}, nil
+	}
+
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(s)); err != nil {
+		return level{}, err
+	}
+
+	return level{Level: lvl}, nil
+}
+
+func normalizeRoute(route string) (string, error) {
+	parts := strings.Fields(route)
+	if len(parts) != 2 || !strings.HasPrefix(parts[1], "/") {
+		return "", fmt.Errorf(`route must be in "<METHOD> <route pattern>" format`)
+	}
+
+	return strings.ToUpper(parts[0]) + " " + strings.TrimSuffix(parts[1], "/"), nil
+}
+
+// Middleware returns middleware that logs method, route pattern, key, status,
+// response size and latency of the requests.
+// It must be placed after RequestID middleware.
+func (l *Logger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		started := time.Now()
+		e := &entry{}
+		rw := httputil.NewResponseWriter(w)
+
+		next.ServeHTTP(rw, req.WithContext(context.WithValue(req.Context(), ctxEntry, e)))
+
+		route := httputil.Route(req)
+
+		lvl, ok := l.routes[req.Method+" "+route]
+		if !ok {
+			lvl = l.level
+		}
+		status := rw.Status()
+		if lvl.disabled || (status < http.StatusInternalServerError && !l.sampled()) {
+			return
+		}
+
+		ce := l.log.Check(lvl.Level, "request")
+		if ce == nil {
+			return
+		}
+
+		e.mux.Lock()
+		key, client := e.key, e.client
+		e.mux.Unlock()
+		if client == "" {
+			client = req.RemoteAddr
+		}
+
+		ce.Write(
+			zap.String("request_id", GetRequestID(req.Context())),
+			zap.String("method", req.Method),
+			zap.String("route", route),
+			zap.String("key", key),
+			zap.Int("status", status),
+			zap.Int64("bytes", rw.Bytes()),
+			zap.Duration("latency", time.Since(started)),
+			zap.String("client", client),
+		)
+	})
+}
+
+// sampled method reports whether the request should be logged.
+func (l *Logger) sampled() bool {
+	if l.sampleRate >= 1 {
+		return true
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	return l.sample() < l.sampleRate
+}
+
+// SetKey sets the key of the request to be logged.
+func SetKey(ctx context.Context, key string) {
+	if e, ok := ctx.Value(ctxEntry).(*entry); ok {
+		e.mux.Lock()
+		e.key = key
+		e.mux.Unlock()
+	}
+}
+
+// SetClient sets the name of the authenticated client to be logged
+// instead of the remote address.
+func SetClient(ctx context.Context, client string) {
+	if e, ok := ctx.Value(ctxEntry).(*entry); ok {
+		e.mux.Lock()
+		e.client = client
+		e.mux.Unlock()
+	}
+}
@@ -0,0 +1,138 @@
+package accesslog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/httputil"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newTestLogger(t *testing.T, opts Opts) (*Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	opts.Log = zap.New(core)
+	l, err := New(opts)
+	require.NoError(t, err)
+
+	return l, logs
+}
+
+func newTestRouter(t *testing.T, opts Opts) (http.Handler, *observer.ObservedLogs) {
+	l, logs := newTestLogger(t, opts)
+
+	return newRouter(l), logs
+}
+
+func newRouter(l *Logger) http.Handler {
+	r := chi.NewRouter()
+	r.Use(RequestID)
+	r.Use(l.Middleware)
+	r.Get("/v1/get/{key}", func(w http.ResponseWriter, req *http.Request) {
+		SetKey(req.Context(), chi.URLParam(req, "key"))
+		SetClient(req.Context(), "alice")
+		_, _ = w.Write([]byte("value"))
+	})
+	r.Get("/v1/keys", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	r.Get("/v1/fail", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	return r
+}
+
+func doRequest(router http.Handler, url string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	for k, v := range header {
+		req.Header.Set(k, v[0])
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestLogger_Middleware(t *testing.T) {
+	router, logs := newTestRouter(t, Opts{})
+
+	w := doRequest(router, "/v1/get/a", http.Header{RequestIDHeader: {"req-1"}})
+	require.Equal(t, "req-1", w.Header().Get(RequestIDHeader))
+
+	entries := logs.TakeAll()
+	require.Len(t, entries, 1)
+	require.Equal(t, zapcore.InfoLevel, entries[0].Level)
+	fields := entries[0].ContextMap()
+	require.Equal(t, "req-1", fields["request_id"])
+	require.Equal(t, http.MethodGet, fields["method"])
+	require.Equal(t, "/v1/get/{key}", fields["route"])
+	require.Equal(t, "a", fields["key"])
+	require.Equal(t, int64(http.StatusOK), fields["status"])
+	require.Equal(t, int64(len("value")), fields["bytes"])
+	require.Equal(t, "alice", fields["client"])
+	require.Contains(t, fields, "latency")
+
+	// Unknown routes are logged as unmatched with remote address of the client
+	doRequest(router, "/unknown", nil)
+	entries = logs.TakeAll()
+	require.Len(t, entries, 1)
+	require.Equal(t, httputil.RouteUnmatched, entries[0].ContextMap()["route"])
+	require.Equal(t, int64(http.StatusNotFound), entries[0].ContextMap()["status"])
+	require.Equal(t, "192.0.2.1:1234", entries[0].ContextMap()["client"])
+}
+
+func TestLogger_Routes(t *testing.T) {
+	router, logs := newTestRouter(t, Opts{
+		Level:  "warn",
+		Routes: map[string]string{"get /v1/keys/": "debug", "GET /v1/get/{key}": LevelNone},
+	})
+
+	doRequest(router, "/v1/keys", nil)
+	doRequest(router, "/v1/get/a", nil)
+	doRequest(router, "/unknown", nil)
+
+	entries := logs.TakeAll()
+	require.Len(t, entries, 2)
+	require.Equal(t, zapcore.DebugLevel, entries[0].Level)
+	require.Equal(t, zapcore.WarnLevel, entries[1].Level)
+}
+
+func TestLogger_Sampling(t *testing.T) {
+	l, logs := newTestLogger(t, Opts{SampleRate: 0.5})
+	samples := []float64{0.7, 0.2, 0.9}
+	l.sample = func() float64 {
+		v := samples[0]
+		samples = samples[1:]
+
+		return v
+	}
+	router := newRouter(l)
+
+	doRequest(router, "/v1/keys", nil)
+	doRequest(router, "/v1/keys", nil)
+	require.Equal(t, 1, logs.Len())
+
+	// Failed requests are always logged
+	doRequest(router, "/v1/fail", nil)
+	require.Equal(t, 2, logs.Len())
+	require.Len(t, samples, 1)
+}
+
+func TestNew_Errors(t *testing.T) {
+	for name, opts := range map[string]Opts{
+		"sample rate": {SampleRate: 1.5},
+		"level":       {Level: "verbose"},
+		"route":       {Routes: map[string]string{"/v1/keys": "debug"}},
+		"route level": {Routes: map[string]string{"GET /v1/keys": "verbose"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(opts)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package accesslog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader is the header of the request ID.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen is the max length of the incoming request ID,
+// longer IDs are replaced by the generated ones.
+const maxRequestIDLen = 128
+
+type ctxKey int
+
+const (
+	ctxRequestID ctxKey = iota
+	ctxEntry
+)
+
+// RequestID middleware puts the request ID into the request context and
+// the response header. Incoming request ID is used if it's valid,
+// otherwise new one is generated.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(RequestIDHeader)
+		if !isValidRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		next.ServeHTTP(w, req.WithContext(WithRequestID(req.Context(), id)))
+	})
+}
+
+// WithRequestID returns a copy of the context with the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxRequestID, id)
+}
+
+// GetRequestID returns the request ID from the context.
+func GetRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(ctxRequestID).(string)
+
+	return id
+}
+
+// RequestLogger returns the logger that adds the request ID
+// from the context to the log fields.
+func RequestLogger(ctx context.Context, log *zap.Logger) *zap.Logger {
+	if id := GetRequestID(ctx); id != "" {
+		return log.With(zap.String("request_id", id))
+	}
+
+	return log
+}
+
+// isValidRequestID reports whether the incoming request ID is not empty,
+// not too long and contains printable ASCII characters only.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newRequestID returns random 128-bit hex-encoded request ID.
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,60 @@
+package accesslog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRequestID(t *testing.T) {
+	var got string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = GetRequestID(req.Context())
+	}))
+
+	for name, tc := range map[string]struct {
+		incoming   string
+		isIncoming bool
+	}{
+		"incoming":  {incoming: "abc-123", isIncoming: true},
+		"missing":   {},
+		"too long":  {incoming: strings.Repeat("a", maxRequestIDLen+1)},
+		"non-ascii": {incoming: "abcé"},
+		"spaces":    {incoming: "abc 123"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.incoming != "" {
+				req.Header.Set(RequestIDHeader, tc.incoming)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			require.NotEmpty(t, got)
+			require.Equal(t, got, w.Header().Get(RequestIDHeader))
+			if tc.isIncoming {
+				require.Equal(t, tc.incoming, got)
+			} else {
+				require.Len(t, got, 32)
+			}
+		})
+	}
+}
+
+func TestRequestLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	log := zap.New(core)
+
+	RequestLogger(WithRequestID(httptest.NewRequest(http.MethodGet, "/", nil).Context(), "req-1"), log).Info("a")
+	RequestLogger(httptest.NewRequest(http.MethodGet, "/", nil).Context(), log).Info("b")
+
+	entries := logs.TakeAll()
+	require.Equal(t, map[string]interface{}{"request_id": "req-1"}, entries[0].ContextMap())
+	require.Empty(t, entries[1].ContextMap())
+}
@@ -1,21 +1,178 @@
 package http
 
 import (
+	"net/http"
+
 	"github.com/dstdfx/bookish-spork/internal/pkg/backend"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/accesslog"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/auth"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/openapi"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/ratelimit"
 	v1 "github.com/dstdfx/bookish-spork/internal/pkg/http/v1"
+	v2 "github.com/dstdfx/bookish-spork/internal/pkg/http/v2"
+	"github.com/dstdfx/bookish-spork/internal/pkg/metrics"
+	"github.com/dstdfx/bookish-spork/internal/pkg/tracing"
 	"github.com/go-chi/chi"
 )
 
 const (
 	groupV1 = "/v1"
+	groupV2 = "/v2"
 )
 
+// RouterOpt configures optional features of the API router.
+type RouterOpt func(opts *routerOpts)
+
+type routerOpts struct {
+	auth *auth.Authenticator
+	acl  *auth.ACL
+
+	limiter   *ratelimit.Limiter
+	metrics   *metrics.HTTPMetrics
+	accessLog *accesslog.Logger
+	tracer    *tracing.Tracer
+
+	maxBodySize int64
+}
+
+// WithAuth enables authentication of the API requests.
+// OpenAPI specification is available without authentication.
+func WithAuth(a *auth.Authenticator) RouterOpt {
+	return func(opts *routerOpts) {
+		opts.auth = a
+	}
+}
+
+// WithACL enables access control of the API requests.
+// Clients are identified by the authenticator, so ACL should be used along with WithAuth.
+func WithACL(a *auth.ACL) RouterOpt {
+	return func(opts *routerOpts) {
+		opts.acl = a
+	}
+}
+
+// WithRateLimit enables rate limiting of the API requests.
+// Clients are identified by the authenticator if WithAuth is used,
+// failed authentication attempts are limited by the remote IP.
+func WithRateLimit(l *ratelimit.Limiter) RouterOpt {
+	return func(opts *routerOpts) {
+		opts.limiter = l
+	}
+}
+
+// WithMetrics enables counting of the API requests and their duration.
+func WithMetrics(m *metrics.HTTPMetrics) RouterOpt {
+	return func(opts *routerOpts) {
+		opts.metrics = m
+	}
+}
+
+// WithAccessLog enables logging of the API requests.
+func WithAccessLog(l *accesslog.Logger) RouterOpt {
+	return func(opts *routerOpts) {
+		opts.accessLog = l
+	}
+}
+
+// WithTracing enables spans of the API requests and the cache commands.
+func WithTracing(t *tracing.Tracer) RouterOpt {
+	return func(opts *routerOpts) {
+		opts.tracer = t
+	}
+}
+
+// WithMaxBodySize limits the size of the request bodies.
+// Bodies are limited before authentication, so HMAC signatures are checked on bounded bodies as well.
+func WithMaxBodySize(n int64) RouterOpt {
+	return func(opts *routerOpts) {
+		opts.maxBodySize = n
+	}
+}
+
 // InitAPIRouter configures HTTP router.
-func InitAPIRouter(b *backend.Backend) chi.Router {
+// Every request gets the request ID that is returned in the response header.
+func InitAPIRouter(b *backend.Backend, opts ...RouterOpt) chi.Router {
+	o := &routerOpts{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	r := chi.NewRouter()
+	r.Use(accesslog.RequestID)
+	if o.tracer != nil {
+		r.Use(tracing.Middleware(o.tracer))
+	}
+	if o.accessLog != nil {
+		r.Use(o.accessLog.Middleware)
+	}
+	if o.metrics != nil {
+		r.Use(o.metrics.Middleware)
+	}
+	r.Get(openapi.Path, openapi.Handler)
 	r.Route(groupV1, func(r chi.Router) {
+		if o.maxBodySize > 0 {
+			r.Use(limitBody(o.maxBodySize, v1.WriteError))
+		}
+		if o.auth.Enabled() {
+			if o.limiter != nil {
+				r.Use(o.limiter.AuthMiddleware(v1.WriteError))
+			}
+			r.Use(o.auth.Middleware(v1.WriteError))
+		}
+		if o.acl != nil {
+			r.Use(o.acl.Middleware())
+		}
+		if o.limiter != nil {
+			r.Use(o.limiter.Middleware())
+		}
 		r.Mount("/", v1.Routes(b))
 	})
+	r.Route(groupV2, func(r chi.Router) {
+		if o.maxBodySize > 0 {
+			r.Use(limitBody(o.maxBodySize, writeV2TooLarge))
+		}
+		if o.auth.Enabled() {
+			if o.limiter != nil {
+				r.Use(o.limiter.AuthMiddleware(writeV2RateLimited))
+			}
+			r.Use(o.auth.Middleware(writeV2Unauthorized))
+		}
+		if o.acl != nil {
+			r.Use(o.acl.Middleware())
+		}
+		if o.limiter != nil {
+			r.Use(o.limiter.Middleware())
+		}
+		r.Mount("/", v2.Routes(b))
+	})
 
 	return r
 }
+
+// limitBody middleware rejects requests with the Content-Length greater than max
+// and limits reading of the bodies without the Content-Length.
+func limitBody(max int64, writeErr func(w http.ResponseWriter, status int, message string)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > max {
+				writeErr(w, http.StatusRequestEntityTooLarge, "request body is too large")
+
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, max)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeV2TooLarge(w http.ResponseWriter, status int, message string) {
+	v2.WriteError(w, status, v2.CodeRequestTooLarge, message)
+}
+
+func writeV2Unauthorized(w http.ResponseWriter, status int, message string) {
+	v2.WriteError(w, status, v2.CodeUnauthorized, message)
+}
+
+func writeV2RateLimited(w http.ResponseWriter, status int, message string) {
+	v2.WriteError(w, status, v2.CodeRateLimited, message)
+}
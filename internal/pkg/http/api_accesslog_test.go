@@ -0,0 +1,60 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/accesslog"
+	"github.com/dstdfx/bookish-spork/internal/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAccessLog(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	accessLog, err := accesslog.New(accesslog.Opts{Log: zap.New(core)})
+	require.NoError(t, err)
+
+	b, _ := initV2TestRouter(t)
+	defer b.Shutdown()
+	router := InitAPIRouter(b, WithAccessLog(accessLog))
+
+	// Incoming request ID is returned in the response and logged
+	req := httptest.NewRequest(http.MethodPost, "/v1/set", strings.NewReader(`{"key":"a","value":"1"}`))
+	req.Header.Set(accesslog.RequestIDHeader, "req-1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "req-1", w.Header().Get(accesslog.RequestIDHeader))
+
+	// Request ID is generated if it's missing
+	w = doV2Request(t, router, http.MethodGet, "/v2/keys/a", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	requestID := w.Header().Get(accesslog.RequestIDHeader)
+	assert.NotEmpty(t, requestID)
+
+	entries := logs.TakeAll()
+	require.Len(t, entries, 2)
+
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "req-1", fields["request_id"])
+	assert.Equal(t, "/v1/set", fields["route"])
+	assert.Equal(t, "a", fields["key"])
+	assert.Equal(t, int64(http.StatusOK), fields["status"])
+
+	fields = entries[1].ContextMap()
+	assert.Equal(t, requestID, fields["request_id"])
+	assert.Equal(t, "/v2/keys/{key}", fields["route"])
+	assert.Equal(t, "a", fields["key"])
+	assert.Equal(t, int64(w.Body.Len()), fields["bytes"])
+}
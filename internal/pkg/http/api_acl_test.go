@@ -0,0 +1,98 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/auth"
+	v2 "github.com/dstdfx/bookish-spork/internal/pkg/http/v2"
+	"github.com/dstdfx/bookish-spork/internal/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestACL_ReportingRole(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	dir, err := ioutil.TempDir("", "acl")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tokensFile := filepath.Join(dir, "tokens")
+	require.NoError(t, ioutil.WriteFile(tokensFile, []byte("reporting:reporting-token\nadmin:admin-token\n"), 0o600))
+
+	authenticator, err := auth.New(auth.Opts{TokensFile: tokensFile})
+	require.NoError(t, err)
+	defer authenticator.Close()
+
+	acl, err := auth.NewACL(auth.ACLOpts{
+		Roles: map[string][]auth.ACLRule{
+			"reporting": {{Keys: []string{"reports:*"}, Commands: []string{auth.CommandGet, auth.CommandKeys}}},
+			"admin":     {{Keys: []string{"*"}, Permissions: []string{auth.PermissionAdmin}}},
+		},
+		Users: map[string][]string{"reporting": {"reporting"}, "admin": {"admin"}},
+	})
+	require.NoError(t, err)
+
+	b, _ := initV2TestRouter(t)
+	defer b.Shutdown()
+	router := InitAPIRouter(b, WithAuth(authenticator), WithACL(acl))
+
+	do := func(token, method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, url, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		if body != "" {
+			r.Header.Set("Content-Type", "application/json")
+		}
+		router.ServeHTTP(w, r)
+
+		return w
+	}
+
+	// Admin may write any key
+	w := do("admin-token", http.MethodPost, "/v1/set", `{"key":"reports:daily","value":"ok"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = do("admin-token", http.MethodPut, "/v2/keys/users:1", `{"value":"user"}`)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	// Reporting service may only get and list keys under "reports:*"
+	w = do("reporting-token", http.MethodGet, "/v1/get/reports:daily", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = do("reporting-token", http.MethodGet, "/v2/keys/reports:daily", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = do("reporting-token", http.MethodGet, "/v1/get/users:1", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, testutils.RespToJSON(t, map[string]string{"error": "permission denied"}), w.Body.String())
+
+	w = do("reporting-token", http.MethodPost, "/v1/set", `{"key":"reports:daily","value":"changed"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = do("reporting-token", http.MethodDelete, "/v2/keys/reports:daily", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, v2ErrorJSON(t, v2.CodeForbidden, "permission denied"), w.Body.String())
+
+	w = do("reporting-token", http.MethodPatch, "/v2/keys/reports:list", `{"push":["a"]}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Keys are filtered by ACL
+	for _, url := range []string{"/v1/keys", "/v2/keys"} {
+		w = do("reporting-token", http.MethodGet, url, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, testutils.RespToJSON(t, map[string]interface{}{"keys": []string{"reports:daily"}}), w.Body.String())
+	}
+
+	// Value has not been changed by the denied requests
+	v, ok := b.Cache.Get("reports:daily")
+	require.True(t, ok)
+	assert.Equal(t, "ok", v)
+}
@@ -0,0 +1,66 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/auth"
+	v2 "github.com/dstdfx/bookish-spork/internal/pkg/http/v2"
+	"github.com/dstdfx/bookish-spork/internal/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuth_Token(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	dir, err := ioutil.TempDir("", "auth")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tokensFile := filepath.Join(dir, "tokens")
+	require.NoError(t, ioutil.WriteFile(tokensFile, []byte("test-app:test-token\n"), 0o600))
+
+	authenticator, err := auth.New(auth.Opts{TokensFile: tokensFile})
+	require.NoError(t, err)
+	defer authenticator.Close()
+
+	b, _ := initV2TestRouter(t)
+	defer b.Shutdown()
+	router := InitAPIRouter(b, WithAuth(authenticator))
+
+	// Requests without credentials are rejected
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/keys", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, `Bearer realm="bookish-spork"`, w.Header().Get("WWW-Authenticate"))
+	assert.Equal(t, testutils.RespToJSON(t, map[string]string{"error": "authentication required"}), w.Body.String())
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v2/keys", nil)
+	r.Header.Set("Authorization", "Bearer wrong-token")
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, v2ErrorJSON(t, v2.CodeUnauthorized, "invalid token"), w.Body.String())
+
+	// Requests with valid token are accepted
+	for _, url := range []string{"/v1/keys", "/v2/keys"} {
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest(http.MethodGet, url, nil)
+		r.Header.Set("Authorization", "Bearer test-token")
+		router.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	// OpenAPI specification is public
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
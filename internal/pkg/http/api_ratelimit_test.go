@@ -0,0 +1,102 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/auth"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/ratelimit"
+	v2 "github.com/dstdfx/bookish-spork/internal/pkg/http/v2"
+	"github.com/dstdfx/bookish-spork/internal/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	limiter, err := ratelimit.New(ratelimit.Opts{
+		Classes: map[string]ratelimit.Limit{auth.PermissionWrite: {Rate: 0.1, Burst: 3}},
+		Routes:  map[string]ratelimit.Limit{"POST /v1/set": {Rate: 0.1, Burst: 1}},
+	})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	b, _ := initV2TestRouter(t)
+	defer b.Shutdown()
+	router := InitAPIRouter(b, WithRateLimit(limiter))
+
+	w := doV2Request(t, router, http.MethodPost, "/v1/set", `{"key":"a","value":"1"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Route limit is exceeded
+	w = doV2Request(t, router, http.MethodPost, "/v1/set", `{"key":"a","value":"2"}`)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "10", w.Header().Get("Retry-After"))
+	assert.Equal(t, testutils.RespToJSON(t, map[string]string{"error": "rate limit exceeded"}), w.Body.String())
+
+	// Write class limit is shared by v1 and v2
+	for i := 0; i < 2; i++ {
+		w = doV2Request(t, router, http.MethodPut, "/v2/keys/b", `{"value":"1"}`)
+		require.Equal(t, http.StatusNoContent, w.Code)
+	}
+	w = doV2Request(t, router, http.MethodPut, "/v2/keys/b", `{"value":"2"}`)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, v2ErrorJSON(t, v2.CodeRateLimited, "rate limit exceeded"), w.Body.String())
+
+	// Reads are not limited
+	w = doV2Request(t, router, http.MethodGet, "/v2/keys/b", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, map[string]uint64{"route:POST /v1/set": 1, "class:write": 1}, limiter.Throttled())
+}
+
+func TestRateLimit_AuthFailures(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	dir, err := ioutil.TempDir("", "ratelimit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tokensFile := filepath.Join(dir, "tokens")
+	require.NoError(t, ioutil.WriteFile(tokensFile, []byte("app:app-token\n"), 0o600))
+
+	authenticator, err := auth.New(auth.Opts{TokensFile: tokensFile})
+	require.NoError(t, err)
+	defer authenticator.Close()
+	limiter, err := ratelimit.New(ratelimit.Opts{AuthFailures: ratelimit.Limit{Rate: 0.1, Burst: 1}})
+	require.NoError(t, err)
+	defer limiter.Close()
+
+	b, _ := initV2TestRouter(t)
+	defer b.Shutdown()
+	router := InitAPIRouter(b, WithAuth(authenticator), WithRateLimit(limiter))
+
+	doRequest := func(url, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, r)
+
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, doRequest("/v1/keys", "app-token").Code)
+	assert.Equal(t, http.StatusUnauthorized, doRequest("/v1/keys", "invalid").Code)
+
+	// Credentials are not checked while the limit is exceeded
+	w := doRequest("/v2/keys", "app-token")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "10", w.Header().Get("Retry-After"))
+	assert.Equal(t, v2ErrorJSON(t, v2.CodeRateLimited, "rate limit exceeded"), w.Body.String())
+	assert.Equal(t, map[string]uint64{"auth_failures": 1}, limiter.Throttled())
+}
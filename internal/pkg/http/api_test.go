@@ -7,10 +7,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/dstdfx/bookish-spork/internal/pkg/backend"
-	"github.com/dstdfx/bookish-spork/internal/pkg/config"
 	v1 "github.com/dstdfx/bookish-spork/internal/pkg/http/v1"
+	v2 "github.com/dstdfx/bookish-spork/internal/pkg/http/v2"
 	"github.com/dstdfx/bookish-spork/internal/pkg/log"
 	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
 	"github.com/dstdfx/bookish-spork/internal/pkg/testutils"
@@ -32,19 +33,19 @@ func TestSet_OK(t *testing.T) {
 		return
 	}
 
-	// Init global app configuration
-	testutils.InitTestConfig()
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
 
 	// Initialize logger
 	logger, err := log.InitLogger(log.InitLoggerOpts{
-		Debug:     config.Config.Log.Debug,
-		UseStdout: config.Config.Log.UseStdout,
-		File:      config.Config.Log.File,
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
 	})
 	assert.NoError(t, err)
 
 	// Prepare backend
-	b := backend.New(logger)
+	b := backend.New(cfg.Cache, logger)
 	defer b.Shutdown()
 	assert.NotEmpty(t, b)
 
@@ -74,19 +75,19 @@ func TestSet_BadRequest(t *testing.T) {
 		return
 	}
 
-	// Init global app configuration
-	testutils.InitTestConfig()
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
 
 	// Initialize logger
 	logger, err := log.InitLogger(log.InitLoggerOpts{
-		Debug:     config.Config.Log.Debug,
-		UseStdout: config.Config.Log.UseStdout,
-		File:      config.Config.Log.File,
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
 	})
 	assert.NoError(t, err)
 
 	// Prepare backend
-	b := backend.New(logger)
+	b := backend.New(cfg.Cache, logger)
 	defer b.Shutdown()
 	assert.NotEmpty(t, b)
 
@@ -116,19 +117,19 @@ func TestGet_OK(t *testing.T) {
 		return
 	}
 
-	// Init global app configuration
-	testutils.InitTestConfig()
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
 
 	// Initialize logger
 	logger, err := log.InitLogger(log.InitLoggerOpts{
-		Debug:     config.Config.Log.Debug,
-		UseStdout: config.Config.Log.UseStdout,
-		File:      config.Config.Log.File,
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
 	})
 	assert.NoError(t, err)
 
 	// Prepare backend
-	b := backend.New(logger)
+	b := backend.New(cfg.Cache, logger)
 	assert.NotEmpty(t, b)
 
 	// Set test value to cache
@@ -156,19 +157,19 @@ func TestGet_NotFound(t *testing.T) {
 		return
 	}
 
-	// Init global app configuration
-	testutils.InitTestConfig()
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
 
 	// Initialize logger
 	logger, err := log.InitLogger(log.InitLoggerOpts{
-		Debug:     config.Config.Log.Debug,
-		UseStdout: config.Config.Log.UseStdout,
-		File:      config.Config.Log.File,
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
 	})
 	assert.NoError(t, err)
 
 	// Prepare backend
-	b := backend.New(logger)
+	b := backend.New(cfg.Cache, logger)
 	defer b.Shutdown()
 	assert.NotEmpty(t, b)
 
@@ -192,19 +193,19 @@ func TestKeys_OK(t *testing.T) {
 		return
 	}
 
-	// Init global app configuration
-	testutils.InitTestConfig()
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
 
 	// Initialize logger
 	logger, err := log.InitLogger(log.InitLoggerOpts{
-		Debug:     config.Config.Log.Debug,
-		UseStdout: config.Config.Log.UseStdout,
-		File:      config.Config.Log.File,
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
 	})
 	assert.NoError(t, err)
 
 	// Prepare backend
-	b := backend.New(logger)
+	b := backend.New(cfg.Cache, logger)
 	defer b.Shutdown()
 	assert.NotEmpty(t, b)
 
@@ -235,19 +236,19 @@ func TestRemove_OK(t *testing.T) {
 		return
 	}
 
-	// Init global app configuration
-	testutils.InitTestConfig()
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
 
 	// Initialize logger
 	logger, err := log.InitLogger(log.InitLoggerOpts{
-		Debug:     config.Config.Log.Debug,
-		UseStdout: config.Config.Log.UseStdout,
-		File:      config.Config.Log.File,
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
 	})
 	assert.NoError(t, err)
 
 	// Prepare backend
-	b := backend.New(logger)
+	b := backend.New(cfg.Cache, logger)
 	defer b.Shutdown()
 	assert.NotEmpty(t, b)
 
@@ -274,19 +275,19 @@ func TestRPush_OK(t *testing.T) {
 		return
 	}
 
-	// Init global app configuration
-	testutils.InitTestConfig()
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
 
 	// Initialize logger
 	logger, err := log.InitLogger(log.InitLoggerOpts{
-		Debug:     config.Config.Log.Debug,
-		UseStdout: config.Config.Log.UseStdout,
-		File:      config.Config.Log.File,
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
 	})
 	assert.NoError(t, err)
 
 	// Prepare backend
-	b := backend.New(logger)
+	b := backend.New(cfg.Cache, logger)
 	defer b.Shutdown()
 	assert.NotEmpty(t, b)
 
@@ -316,19 +317,19 @@ func TestRPush_AppendValue(t *testing.T) {
 		return
 	}
 
-	// Init global app configuration
-	testutils.InitTestConfig()
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
 
 	// Initialize logger
 	logger, err := log.InitLogger(log.InitLoggerOpts{
-		Debug:     config.Config.Log.Debug,
-		UseStdout: config.Config.Log.UseStdout,
-		File:      config.Config.Log.File,
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
 	})
 	assert.NoError(t, err)
 
 	// Prepare backend
-	b := backend.New(logger)
+	b := backend.New(cfg.Cache, logger)
 	defer b.Shutdown()
 	assert.NotEmpty(t, b)
 
@@ -360,19 +361,19 @@ func TestRPush_BadRequest(t *testing.T) {
 		return
 	}
 
-	// Init global app configuration
-	testutils.InitTestConfig()
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
 
 	// Initialize logger
 	logger, err := log.InitLogger(log.InitLoggerOpts{
-		Debug:     config.Config.Log.Debug,
-		UseStdout: config.Config.Log.UseStdout,
-		File:      config.Config.Log.File,
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
 	})
 	assert.NoError(t, err)
 
 	// Prepare backend
-	b := backend.New(logger)
+	b := backend.New(cfg.Cache, logger)
 	defer b.Shutdown()
 	assert.NotEmpty(t, b)
 
@@ -406,19 +407,19 @@ func TestLindex_OK(t *testing.T) {
 		return
 	}
 
-	// Init global app configuration
-	testutils.InitTestConfig()
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
 
 	// Initialize logger
 	logger, err := log.InitLogger(log.InitLoggerOpts{
-		Debug:     config.Config.Log.Debug,
-		UseStdout: config.Config.Log.UseStdout,
-		File:      config.Config.Log.File,
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
 	})
 	assert.NoError(t, err)
 
 	// Prepare backend
-	b := backend.New(logger)
+	b := backend.New(cfg.Cache, logger)
 	assert.NotEmpty(t, b)
 
 	// Set test value to cache
@@ -446,19 +447,19 @@ func TestLindex_BadRequest(t *testing.T) {
 		return
 	}
 
-	// Init global app configuration
-	testutils.InitTestConfig()
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
 
 	// Initialize logger
 	logger, err := log.InitLogger(log.InitLoggerOpts{
-		Debug:     config.Config.Log.Debug,
-		UseStdout: config.Config.Log.UseStdout,
-		File:      config.Config.Log.File,
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
 	})
 	assert.NoError(t, err)
 
 	// Prepare backend
-	b := backend.New(logger)
+	b := backend.New(cfg.Cache, logger)
 	assert.NotEmpty(t, b)
 
 	// Set test value to cache
@@ -486,19 +487,19 @@ func TestLindex_BadIndex(t *testing.T) {
 		return
 	}
 
-	// Init global app configuration
-	testutils.InitTestConfig()
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
 
 	// Initialize logger
 	logger, err := log.InitLogger(log.InitLoggerOpts{
-		Debug:     config.Config.Log.Debug,
-		UseStdout: config.Config.Log.UseStdout,
-		File:      config.Config.Log.File,
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
 	})
 	assert.NoError(t, err)
 
 	// Prepare backend
-	b := backend.New(logger)
+	b := backend.New(cfg.Cache, logger)
 	assert.NotEmpty(t, b)
 
 	// Setup handlers
@@ -525,19 +526,19 @@ func TestHSet_OK(t *testing.T) {
 		return
 	}
 
-	// Init global app configuration
-	testutils.InitTestConfig()
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
 
 	// Initialize logger
 	logger, err := log.InitLogger(log.InitLoggerOpts{
-		Debug:     config.Config.Log.Debug,
-		UseStdout: config.Config.Log.UseStdout,
-		File:      config.Config.Log.File,
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
 	})
 	assert.NoError(t, err)
 
 	// Prepare backend
-	b := backend.New(logger)
+	b := backend.New(cfg.Cache, logger)
 	defer b.Shutdown()
 	assert.NotEmpty(t, b)
 
@@ -567,19 +568,19 @@ func TestHSet_BadRequest(t *testing.T) {
 		return
 	}
 
-	// Init global app configuration
-	testutils.InitTestConfig()
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
 
 	// Initialize logger
 	logger, err := log.InitLogger(log.InitLoggerOpts{
-		Debug:     config.Config.Log.Debug,
-		UseStdout: config.Config.Log.UseStdout,
-		File:      config.Config.Log.File,
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
 	})
 	assert.NoError(t, err)
 
 	// Prepare backend
-	b := backend.New(logger)
+	b := backend.New(cfg.Cache, logger)
 	defer b.Shutdown()
 	assert.NotEmpty(t, b)
 
@@ -607,19 +608,19 @@ func TestHSet_AppendKeys(t *testing.T) {
 		return
 	}
 
-	// Init global app configuration
-	testutils.InitTestConfig()
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
 
 	// Initialize logger
 	logger, err := log.InitLogger(log.InitLoggerOpts{
-		Debug:     config.Config.Log.Debug,
-		UseStdout: config.Config.Log.UseStdout,
-		File:      config.Config.Log.File,
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
 	})
 	assert.NoError(t, err)
 
 	// Prepare backend
-	b := backend.New(logger)
+	b := backend.New(cfg.Cache, logger)
 	defer b.Shutdown()
 	assert.NotEmpty(t, b)
 
@@ -652,19 +653,19 @@ func TestHGet_OK(t *testing.T) {
 		return
 	}
 
-	// Init global app configuration
-	testutils.InitTestConfig()
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
 
 	// Initialize logger
 	logger, err := log.InitLogger(log.InitLoggerOpts{
-		Debug:     config.Config.Log.Debug,
-		UseStdout: config.Config.Log.UseStdout,
-		File:      config.Config.Log.File,
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
 	})
 	assert.NoError(t, err)
 
 	// Prepare backend
-	b := backend.New(logger)
+	b := backend.New(cfg.Cache, logger)
 	assert.NotEmpty(t, b)
 
 	// Set test value to cache
@@ -692,19 +693,19 @@ func TestHGet_BadRequest(t *testing.T) {
 		return
 	}
 
-	// Init global app configuration
-	testutils.InitTestConfig()
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
 
 	// Initialize logger
 	logger, err := log.InitLogger(log.InitLoggerOpts{
-		Debug:     config.Config.Log.Debug,
-		UseStdout: config.Config.Log.UseStdout,
-		File:      config.Config.Log.File,
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
 	})
 	assert.NoError(t, err)
 
 	// Prepare backend
-	b := backend.New(logger)
+	b := backend.New(cfg.Cache, logger)
 	assert.NotEmpty(t, b)
 
 	// Set test value to cache
@@ -728,19 +729,19 @@ func TestHGet_NotFound(t *testing.T) {
 		return
 	}
 
-	// Init global app configuration
-	testutils.InitTestConfig()
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
 
 	// Initialize logger
 	logger, err := log.InitLogger(log.InitLoggerOpts{
-		Debug:     config.Config.Log.Debug,
-		UseStdout: config.Config.Log.UseStdout,
-		File:      config.Config.Log.File,
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
 	})
 	assert.NoError(t, err)
 
 	// Prepare backend
-	b := backend.New(logger)
+	b := backend.New(cfg.Cache, logger)
 	assert.NotEmpty(t, b)
 
 	// Setup handlers
@@ -754,3 +755,783 @@ func TestHGet_NotFound(t *testing.T) {
 
 	assert.Equal(t, http.StatusNotFound, w.Code)
 }
+
+// Tests for GET /v1/ttl/<key>
+
+func TestTTL_OK(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
+
+	// Initialize logger
+	logger, err := log.InitLogger(log.InitLoggerOpts{
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
+	})
+	assert.NoError(t, err)
+
+	// Prepare backend
+	b := backend.New(cfg.Cache, logger)
+	assert.NotEmpty(t, b)
+
+	// Set test values to cache
+	b.Cache.Set(testKey, testValue, 10*time.Second)
+	b.Cache.Set("persistent", testValue, 0)
+
+	// Setup handlers
+	router := InitAPIRouter(b)
+
+	// Test requests
+	for key, expected := range map[string]int{testKey: 10, "persistent": -1} {
+		w := httptest.NewRecorder()
+		r, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/v1/ttl/%s", key), nil)
+		assert.NoError(t, err)
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t,
+			testutils.RespToJSON(t,
+				map[string]int{"ttl": expected},
+			), w.Body.String())
+	}
+}
+
+func TestTTL_NotFound(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
+
+	// Initialize logger
+	logger, err := log.InitLogger(log.InitLoggerOpts{
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
+	})
+	assert.NoError(t, err)
+
+	// Prepare backend
+	b := backend.New(cfg.Cache, logger)
+	assert.NotEmpty(t, b)
+
+	// Setup handlers
+	router := InitAPIRouter(b)
+
+	// Test a request
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/v1/ttl/%s", testKey), nil)
+	assert.NoError(t, err)
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// Tests for POST /v1/geoadd
+
+func TestGeoAdd_OK(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
+
+	// Initialize logger
+	logger, err := log.InitLogger(log.InitLoggerOpts{
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
+	})
+	assert.NoError(t, err)
+
+	// Prepare backend
+	b := backend.New(cfg.Cache, logger)
+	defer b.Shutdown()
+	assert.NotEmpty(t, b)
+
+	geoAddBody := &v1.GeoAddRequestBody{
+		Key: testKey,
+		Members: []v1.GeoMember{
+			{Member: "Palermo", Longitude: 13.361389, Latitude: 38.115556},
+			{Member: "Catania", Longitude: 15.087269, Latitude: 37.502669},
+		},
+	}
+	reqBody, err := json.Marshal(geoAddBody)
+	assert.NoError(t, err)
+
+	// Setup handlers
+	router := InitAPIRouter(b)
+
+	// Test a request
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodPost, "/v1/geoadd", bytes.NewReader(reqBody))
+	assert.NoError(t, err)
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t,
+		testutils.RespToJSON(t,
+			map[string]int{"added": 2},
+		), w.Body.String())
+}
+
+func TestGeoAdd_BadRequest(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
+
+	// Initialize logger
+	logger, err := log.InitLogger(log.InitLoggerOpts{
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
+	})
+	assert.NoError(t, err)
+
+	// Prepare backend
+	b := backend.New(cfg.Cache, logger)
+	defer b.Shutdown()
+	assert.NotEmpty(t, b)
+
+	geoAddBody := &v1.GeoAddRequestBody{
+		Key: testKey,
+		Members: []v1.GeoMember{
+			{Member: "North Pole", Longitude: 0, Latitude: 90},
+		},
+	}
+	reqBody, err := json.Marshal(geoAddBody)
+	assert.NoError(t, err)
+
+	// Setup handlers
+	router := InitAPIRouter(b)
+
+	// Test a request
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodPost, "/v1/geoadd", bytes.NewReader(reqBody))
+	assert.NoError(t, err)
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t,
+		testutils.RespToJSON(t,
+			map[string]string{"error": qqcache.ErrInvalidGeoCoordinates.Error()},
+		), w.Body.String())
+}
+
+// Tests for GET /v1/geopos/<key>
+
+func TestGeoPos_OK(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
+
+	// Initialize logger
+	logger, err := log.InitLogger(log.InitLoggerOpts{
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
+	})
+	assert.NoError(t, err)
+
+	// Prepare backend
+	b := backend.New(cfg.Cache, logger)
+	defer b.Shutdown()
+	assert.NotEmpty(t, b)
+
+	// Set test value to cache
+	_, err = b.Cache.GeoAdd(testKey, []qqcache.GeoMember{
+		{Name: "Palermo", GeoPoint: qqcache.GeoPoint{Longitude: 13.361389, Latitude: 38.115556}},
+	}, 0)
+	assert.NoError(t, err)
+
+	// Setup handlers
+	router := InitAPIRouter(b)
+
+	// Test a request
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodGet,
+		fmt.Sprintf("/v1/geopos/%s?member=Palermo&member=Catania", testKey), nil)
+	assert.NoError(t, err)
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Value []*v1.GeoPoint `json:"value"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Value, 2)
+	assert.InDelta(t, 13.361389, resp.Value[0].Longitude, 0.00001)
+	assert.InDelta(t, 38.115556, resp.Value[0].Latitude, 0.00001)
+	assert.Nil(t, resp.Value[1])
+}
+
+func TestGeoPos_NotFound(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
+
+	// Initialize logger
+	logger, err := log.InitLogger(log.InitLoggerOpts{
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
+	})
+	assert.NoError(t, err)
+
+	// Prepare backend
+	b := backend.New(cfg.Cache, logger)
+	defer b.Shutdown()
+	assert.NotEmpty(t, b)
+
+	// Setup handlers
+	router := InitAPIRouter(b)
+
+	// Test a request
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/v1/geopos/%s?member=Palermo", testKey), nil)
+	assert.NoError(t, err)
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// Tests for GET /v1/geodist/<key>
+
+func TestGeoDist_OK(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
+
+	// Initialize logger
+	logger, err := log.InitLogger(log.InitLoggerOpts{
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
+	})
+	assert.NoError(t, err)
+
+	// Prepare backend
+	b := backend.New(cfg.Cache, logger)
+	defer b.Shutdown()
+	assert.NotEmpty(t, b)
+
+	// Set test value to cache
+	_, err = b.Cache.GeoAdd(testKey, []qqcache.GeoMember{
+		{Name: "Palermo", GeoPoint: qqcache.GeoPoint{Longitude: 13.361389, Latitude: 38.115556}},
+		{Name: "Catania", GeoPoint: qqcache.GeoPoint{Longitude: 15.087269, Latitude: 37.502669}},
+	}, 0)
+	assert.NoError(t, err)
+
+	// Setup handlers
+	router := InitAPIRouter(b)
+
+	// Test a request
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodGet,
+		fmt.Sprintf("/v1/geodist/%s?member=Palermo&member=Catania&unit=km", testKey), nil)
+	assert.NoError(t, err)
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Value float64 `json:"value"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.InDelta(t, 166.2742, resp.Value, 0.001)
+}
+
+func TestGeoDist_BadRequest(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
+
+	// Initialize logger
+	logger, err := log.InitLogger(log.InitLoggerOpts{
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
+	})
+	assert.NoError(t, err)
+
+	// Prepare backend
+	b := backend.New(cfg.Cache, logger)
+	defer b.Shutdown()
+	assert.NotEmpty(t, b)
+
+	// Setup handlers
+	router := InitAPIRouter(b)
+
+	// Test a request
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/v1/geodist/%s?member=Palermo", testKey), nil)
+	assert.NoError(t, err)
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// Tests for POST /v1/geosearch
+
+func TestGeoSearch_OK(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
+
+	// Initialize logger
+	logger, err := log.InitLogger(log.InitLoggerOpts{
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
+	})
+	assert.NoError(t, err)
+
+	// Prepare backend
+	b := backend.New(cfg.Cache, logger)
+	defer b.Shutdown()
+	assert.NotEmpty(t, b)
+
+	// Set test value to cache
+	_, err = b.Cache.GeoAdd(testKey, []qqcache.GeoMember{
+		{Name: "Palermo", GeoPoint: qqcache.GeoPoint{Longitude: 13.361389, Latitude: 38.115556}},
+		{Name: "Catania", GeoPoint: qqcache.GeoPoint{Longitude: 15.087269, Latitude: 37.502669}},
+	}, 0)
+	assert.NoError(t, err)
+
+	geoSearchBody := &v1.GeoSearchRequestBody{
+		Key:        testKey,
+		FromLonLat: &v1.GeoPoint{Longitude: 15, Latitude: 37},
+		Radius:     100,
+		Unit:       qqcache.GeoUnitKilometers,
+		Sort:       qqcache.GeoSortAsc,
+	}
+	reqBody, err := json.Marshal(geoSearchBody)
+	assert.NoError(t, err)
+
+	// Setup handlers
+	router := InitAPIRouter(b)
+
+	// Test a request
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodPost, "/v1/geosearch", bytes.NewReader(reqBody))
+	assert.NoError(t, err)
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Value []v1.GeoSearchResult `json:"value"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Value, 1)
+	assert.Equal(t, "Catania", resp.Value[0].Member)
+	assert.InDelta(t, 56.4413, resp.Value[0].Distance, 0.001)
+}
+
+func TestGeoSearch_BadRequest(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
+
+	// Initialize logger
+	logger, err := log.InitLogger(log.InitLoggerOpts{
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
+	})
+	assert.NoError(t, err)
+
+	// Prepare backend
+	b := backend.New(cfg.Cache, logger)
+	defer b.Shutdown()
+	assert.NotEmpty(t, b)
+
+	geoSearchBody := &v1.GeoSearchRequestBody{
+		Key:        testKey,
+		FromMember: "Palermo",
+	}
+	reqBody, err := json.Marshal(geoSearchBody)
+	assert.NoError(t, err)
+
+	// Setup handlers
+	router := InitAPIRouter(b)
+
+	// Test a request
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodPost, "/v1/geosearch", bytes.NewReader(reqBody))
+	assert.NoError(t, err)
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t,
+		testutils.RespToJSON(t,
+			map[string]string{"error": qqcache.ErrInvalidGeoShape.Error()},
+		), w.Body.String())
+}
+
+// Tests for PUT /v1/keys/<key> and GET /v1/keys/<key>
+
+func TestPutKey_Raw_OK(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
+
+	// Initialize logger
+	logger, err := log.InitLogger(log.InitLoggerOpts{
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
+	})
+	assert.NoError(t, err)
+
+	// Prepare backend
+	b := backend.New(cfg.Cache, logger)
+	defer b.Shutdown()
+	assert.NotEmpty(t, b)
+
+	// Setup handlers
+	router := InitAPIRouter(b)
+
+	value := []byte{0x89, 0x50, 0x4e, 0x47, 0x00, 0xff}
+
+	// Test a request
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/v1/keys/%s?ttl=10", testKey), bytes.NewReader(value))
+	assert.NoError(t, err)
+	r.Header.Set("Content-Type", "application/octet-stream")
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Get the value back
+	w = httptest.NewRecorder()
+	r, err = http.NewRequest(http.MethodGet, fmt.Sprintf("/v1/keys/%s", testKey), nil)
+	assert.NoError(t, err)
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/octet-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, value, w.Body.Bytes())
+}
+
+func TestPutKey_JSON_OK(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
+
+	// Initialize logger
+	logger, err := log.InitLogger(log.InitLoggerOpts{
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
+	})
+	assert.NoError(t, err)
+
+	// Prepare backend
+	b := backend.New(cfg.Cache, logger)
+	defer b.Shutdown()
+	assert.NotEmpty(t, b)
+
+	// Setup handlers
+	router := InitAPIRouter(b)
+
+	// Big integer must not lose precision
+	value := `{"id":12345678901234567890}`
+
+	// Test a request
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/v1/keys/%s", testKey), bytes.NewReader([]byte(value)))
+	assert.NoError(t, err)
+	r.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Get the value back
+	w = httptest.NewRecorder()
+	r, err = http.NewRequest(http.MethodGet, fmt.Sprintf("/v1/keys/%s", testKey), nil)
+	assert.NoError(t, err)
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, value+"\n", w.Body.String())
+}
+
+func TestPutKey_UnsupportedMediaType(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
+
+	// Initialize logger
+	logger, err := log.InitLogger(log.InitLoggerOpts{
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
+	})
+	assert.NoError(t, err)
+
+	// Prepare backend
+	b := backend.New(cfg.Cache, logger)
+	defer b.Shutdown()
+	assert.NotEmpty(t, b)
+
+	// Setup handlers
+	router := InitAPIRouter(b)
+
+	// Test a request
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/v1/keys/%s", testKey), bytes.NewReader([]byte("<a/>")))
+	assert.NoError(t, err)
+	r.Header.Set("Content-Type", "application/xml")
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
+
+func TestGetKey_NotAcceptable(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
+
+	// Initialize logger
+	logger, err := log.InitLogger(log.InitLoggerOpts{
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
+	})
+	assert.NoError(t, err)
+
+	// Prepare backend
+	b := backend.New(cfg.Cache, logger)
+	defer b.Shutdown()
+	assert.NotEmpty(t, b)
+
+	// Set test value to cache
+	b.Cache.SetBytes(testKey, []byte(testValue), "text/plain", 0)
+
+	// Setup handlers
+	router := InitAPIRouter(b)
+
+	// Test a request
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/v1/keys/%s", testKey), nil)
+	assert.NoError(t, err)
+	r.Header.Set("Accept", "application/json")
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+}
+
+func TestSet_Raw_OK(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
+
+	// Initialize logger
+	logger, err := log.InitLogger(log.InitLoggerOpts{
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
+	})
+	assert.NoError(t, err)
+
+	// Prepare backend
+	b := backend.New(cfg.Cache, logger)
+	defer b.Shutdown()
+	assert.NotEmpty(t, b)
+
+	// Setup handlers
+	router := InitAPIRouter(b)
+
+	// Test a request
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/v1/set?key=%s", testKey),
+		bytes.NewReader([]byte(testValue)))
+	assert.NoError(t, err)
+	r.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Get raw value back
+	w = httptest.NewRecorder()
+	r, err = http.NewRequest(http.MethodGet, fmt.Sprintf("/v1/get/%s", testKey), nil)
+	assert.NoError(t, err)
+	r.Header.Set("Accept", "text/plain")
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, testValue, w.Body.String())
+
+	// Get value wrapped into JSON
+	w = httptest.NewRecorder()
+	r, err = http.NewRequest(http.MethodGet, fmt.Sprintf("/v1/get/%s", testKey), nil)
+	assert.NoError(t, err)
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t,
+		testutils.RespToJSON(t,
+			map[string]interface{}{"value": []byte(testValue), "content_type": "text/plain; charset=utf-8"},
+		), w.Body.String())
+}
+
+func TestNumbers_SameType(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	b, router := initV2TestRouter(t)
+	defer b.Shutdown()
+
+	// Numbers are cached as json.Number whatever write route is used
+	for _, req := range []struct{ method, url, body string }{
+		{http.MethodPost, "/v1/set", `{"key": "set", "value": 1.5}`},
+		{http.MethodPost, "/v1/rpush", `{"key": "rpush", "value": 1.5}`},
+		{http.MethodPost, "/v1/hset", `{"key": "hset", "value": {"field": 1.5}}`},
+		{http.MethodPut, "/v2/keys/put", `{"value": 1.5}`},
+	} {
+		w := doV2Request(t, router, req.method, req.url, req.body)
+		assert.True(t, w.Code < http.StatusMultipleChoices, req.url)
+	}
+
+	v, ok := b.Cache.Get("set")
+	assert.True(t, ok)
+	assert.Equal(t, json.Number("1.5"), v)
+	v, err := b.Cache.LIndex("rpush", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, json.Number("1.5"), v)
+	v, err = b.Cache.HGet("hset", "field")
+	assert.NoError(t, err)
+	assert.Equal(t, json.Number("1.5"), v)
+	v, ok = b.Cache.Get("put")
+	assert.True(t, ok)
+	assert.Equal(t, json.Number("1.5"), v)
+}
+
+func TestMaxBodySize(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	b, _ := initV2TestRouter(t)
+	defer b.Shutdown()
+	router := InitAPIRouter(b, WithMaxBodySize(24))
+
+	w := doV2Request(t, router, http.MethodPost, "/v1/set", `{"key": "k", "value": "too-large-value"}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, testutils.RespToJSON(t, map[string]string{"error": "request body is too large"}), w.Body.String())
+
+	w = doV2Request(t, router, http.MethodPut, "/v2/keys/k", `{"value": "too-large-value"}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, v2ErrorJSON(t, v2.CodeRequestTooLarge, "request body is too large"), w.Body.String())
+
+	// Bodies without the Content-Length are not read beyond the limit
+	w = httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodPut, "/v1/keys/k", bytes.NewReader(bytes.Repeat([]byte("a"), 32)))
+	assert.NoError(t, err)
+	r.ContentLength = -1
+	r.Header.Set("Content-Type", "application/octet-stream")
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	_, ok := b.Cache.Get("k")
+	assert.False(t, ok)
+
+	w = doV2Request(t, router, http.MethodPost, "/v1/set", `{"key":"k","value":1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+// TestV1_JSONContentType is the regression test for the Content-Type header of v1 JSON
+// responses, which was set after the status code was written and so never sent.
+func TestV1_JSONContentType(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	b, router := initV2TestRouter(t)
+	defer b.Shutdown()
+	b.Cache.Set(testKey, testValue, 0)
+	assert.NoError(t, b.Cache.RPush("list", testValue, 0))
+	assert.NoError(t, b.Cache.HSet("hash", map[string]interface{}{testHkey: testHKeyValue}, 0))
+
+	for _, req := range []struct {
+		method, url, body string
+		status            int
+	}{
+		{http.MethodGet, "/v1/get/" + testKey, "", http.StatusOK},
+		{http.MethodGet, "/v1/keys", "", http.StatusOK},
+		{http.MethodGet, "/v1/lindex/list/0", "", http.StatusOK},
+		{http.MethodGet, "/v1/hget/hash/" + testHkey, "", http.StatusOK},
+		{http.MethodGet, "/v1/lindex/" + testKey + "/0", "", http.StatusBadRequest},
+		{http.MethodPost, "/v1/set", `{"key": ""}`, http.StatusBadRequest},
+	} {
+		w := doV2Request(t, router, req.method, req.url, req.body)
+		assert.Equal(t, req.status, w.Code, req.url)
+
+		// Result reports the headers as they were at the time the status code was written
+		assert.Equal(t, "application/json; charset=utf-8", w.Result().Header.Get("Content-Type"), req.url)
+	}
+}
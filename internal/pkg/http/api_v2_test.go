@@ -0,0 +1,241 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/backend"
+	v2 "github.com/dstdfx/bookish-spork/internal/pkg/http/v2"
+	"github.com/dstdfx/bookish-spork/internal/pkg/log"
+	"github.com/dstdfx/bookish-spork/internal/pkg/testutils"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+)
+
+// initV2TestRouter prepares backend and router for /v2 tests.
+func initV2TestRouter(t *testing.T) (*backend.Backend, chi.Router) {
+	// Init app configuration
+	cfg := testutils.NewTestConfig()
+
+	// Initialize logger
+	logger, err := log.InitLogger(log.InitLoggerOpts{
+		Debug:     cfg.Log.Debug,
+		UseStdout: cfg.Log.UseStdout,
+		File:      cfg.Log.File,
+	})
+	assert.NoError(t, err)
+
+	// Prepare backend
+	b := backend.New(cfg.Cache, logger)
+	assert.NotEmpty(t, b)
+
+	return b, InitAPIRouter(b)
+}
+
+// doV2Request performs a request to the router and returns the recorded response.
+func doV2Request(t *testing.T, router http.Handler, method, url, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest(method, url, bytes.NewReader([]byte(body)))
+	assert.NoError(t, err)
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	router.ServeHTTP(w, r)
+
+	return w
+}
+
+func v2ErrorJSON(t *testing.T, code, message string) string {
+	return testutils.RespToJSON(t, v2.ErrorResponse{Error: v2.Error{Code: code, Message: message}})
+}
+
+// Tests for /v2/keys/<key>
+
+func TestV2_PutGetDelete(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	b, router := initV2TestRouter(t)
+	defer b.Shutdown()
+
+	url := fmt.Sprintf("/v2/keys/%s", testKey)
+
+	w := doV2Request(t, router, http.MethodPut, url, `{"value": "test-value", "ttl": 10}`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = doV2Request(t, router, http.MethodGet, url, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, testutils.RespToJSON(t, map[string]string{"value": testValue}), w.Body.String())
+
+	w = doV2Request(t, router, http.MethodDelete, url, "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// Deleting not existing key
+	w = doV2Request(t, router, http.MethodDelete, url, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, v2ErrorJSON(t, v2.CodeKeyNotFound, "key not found"), w.Body.String())
+
+	w = doV2Request(t, router, http.MethodGet, url, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, v2ErrorJSON(t, v2.CodeKeyNotFound, "key not found"), w.Body.String())
+}
+
+func TestV2_Put_BadRequest(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	b, router := initV2TestRouter(t)
+	defer b.Shutdown()
+
+	w := doV2Request(t, router, http.MethodPut, fmt.Sprintf("/v2/keys/%s", testKey), `{"ttl": 10}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, v2ErrorJSON(t, v2.CodeInvalidRequest, "value is required"), w.Body.String())
+}
+
+func TestV2_PatchPushAndItems(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	b, router := initV2TestRouter(t)
+	defer b.Shutdown()
+
+	url := fmt.Sprintf("/v2/keys/%s", testKey)
+
+	w := doV2Request(t, router, http.MethodPatch, url, `{"push": ["a", "b"]}`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = doV2Request(t, router, http.MethodGet, url+"/items/1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, testutils.RespToJSON(t, map[string]string{"value": "b"}), w.Body.String())
+
+	w = doV2Request(t, router, http.MethodGet, url+"/items/2", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, v2ErrorJSON(t, v2.CodeItemNotFound, "item not found"), w.Body.String())
+
+	w = doV2Request(t, router, http.MethodGet, url+"/items/-1", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Stored null is found
+	w = doV2Request(t, router, http.MethodPatch, url, `{"push": [null]}`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = doV2Request(t, router, http.MethodGet, url+"/items/2", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, testutils.RespToJSON(t, map[string]interface{}{"value": nil}), w.Body.String())
+
+	// Key holds a list, not a hash map
+	w = doV2Request(t, router, http.MethodGet, url+"/fields/some", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), v2.CodeWrongType)
+}
+
+func TestV2_PatchFieldsAndFields(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	b, router := initV2TestRouter(t)
+	defer b.Shutdown()
+
+	url := fmt.Sprintf("/v2/keys/%s", testKey)
+
+	w := doV2Request(t, router, http.MethodPatch, url, `{"fields": {"k0": "v0"}}`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = doV2Request(t, router, http.MethodPut, url+"/fields/k1", `{"value": "v1"}`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = doV2Request(t, router, http.MethodGet, url+"/fields/k1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, testutils.RespToJSON(t, map[string]string{"value": "v1"}), w.Body.String())
+
+	w = doV2Request(t, router, http.MethodGet, url+"/fields/k2", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, v2ErrorJSON(t, v2.CodeFieldNotFound, "field not found"), w.Body.String())
+
+	// Stored null is found
+	w = doV2Request(t, router, http.MethodPatch, url, `{"fields": {"k2": null}}`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = doV2Request(t, router, http.MethodGet, url+"/fields/k2", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, testutils.RespToJSON(t, map[string]interface{}{"value": nil}), w.Body.String())
+
+	w = doV2Request(t, router, http.MethodPatch, url, `{"push": [1], "fields": {"k0": "v0"}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestV2_Keys(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	b, router := initV2TestRouter(t)
+	defer b.Shutdown()
+
+	b.Cache.Set(testKey, testValue, 0)
+
+	w := doV2Request(t, router, http.MethodGet, "/v2/keys", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, testutils.RespToJSON(t, map[string][]string{"keys": {testKey}}), w.Body.String())
+}
+
+func TestV2_UnknownRoute(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	b, router := initV2TestRouter(t)
+	defer b.Shutdown()
+
+	w := doV2Request(t, router, http.MethodGet, "/v2/unknown", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, v2ErrorJSON(t, v2.CodeRouteNotFound, "route not found"), w.Body.String())
+
+	w = doV2Request(t, router, http.MethodPost, fmt.Sprintf("/v2/keys/%s", testKey), "")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, v2ErrorJSON(t, v2.CodeMethodNotAllowed, "method not allowed"), w.Body.String())
+}
+
+func TestV2_ReadOnly(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	b, router := initV2TestRouter(t)
+	defer b.Shutdown()
+
+	b.Cache.Set(testKey, testValue, 0)
+	b.ReadOnly = true
+
+	url := fmt.Sprintf("/v2/keys/%s", testKey)
+	for _, req := range []struct{ method, url, body string }{
+		{http.MethodPut, url, `{"value": "other"}`},
+		{http.MethodPatch, url, `{"push": ["a"]}`},
+		{http.MethodDelete, url, ""},
+		{http.MethodPut, url + "/fields/k1", `{"value": "v1"}`},
+		{http.MethodPost, "/v1/set", fmt.Sprintf(`{"key": %q, "value": "other"}`, testKey)},
+		{http.MethodDelete, "/v1/remove/" + testKey, ""},
+	} {
+		w := doV2Request(t, router, req.method, req.url, req.body)
+		assert.Equal(t, http.StatusForbidden, w.Code, req.method+" "+req.url)
+		assert.Contains(t, w.Body.String(), backend.ErrReadOnly.Error())
+	}
+
+	// Reads are allowed
+	w := doV2Request(t, router, http.MethodGet, url, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, testutils.RespToJSON(t, map[string]string{"value": testValue}), w.Body.String())
+}
@@ -0,0 +1,310 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Permissions granted by ACL rules, each permission covers a category of commands.
+// Admin permission covers all commands.
+const (
+	PermissionRead  = "read"
+	PermissionWrite = "write"
+	PermissionAdmin = "admin"
+)
+
+// Commands of the public API.
+const (
+	CommandGet       = "get"
+	CommandKeys      = "keys"
+	CommandSet       = "set"
+	CommandRemove    = "remove"
+	CommandRPush     = "rpush"
+	CommandLIndex    = "lindex"
+	CommandHSet      = "hset"
+	CommandHGet      = "hget"
+	CommandGeoAdd    = "geoadd"
+	CommandGeoPos    = "geopos"
+	CommandGeoDist   = "geodist"
+	CommandGeoSearch = "geosearch"
+	CommandTTL       = "ttl"
+)
+
+// commandPermissions contains the category of each command.
+var commandPermissions = map[string]string{
+	CommandGet:       PermissionRead,
+	CommandKeys:      PermissionRead,
+	CommandLIndex:    PermissionRead,
+	CommandHGet:      PermissionRead,
+	CommandGeoPos:    PermissionRead,
+	CommandGeoDist:   PermissionRead,
+	CommandGeoSearch: PermissionRead,
+	CommandTTL:       PermissionRead,
+	CommandSet:       PermissionWrite,
+	CommandRemove:    PermissionWrite,
+	CommandRPush:     PermissionWrite,
+	CommandHSet:      PermissionWrite,
+	CommandGeoAdd:    PermissionWrite,
+}
+
+// CommandPermission returns the permission category of the command: read or write.
+func CommandPermission(command string) string {
+	return commandPermissions[command]
+}
+
+// ACLRule grants permissions and commands on the keys that match any of the glob patterns.
+type ACLRule struct {
+	// Keys contains glob patterns, * matches any sequence of characters,
+	// ? matches any single character, [abc] and [a-z] match character classes.
+	Keys []string
+
+	// Permissions contains the categories of allowed commands.
+	Permissions []string
+
+	// Commands contains allowed commands in addition to the permissions.
+	Commands []string
+}
+
+// ACLOpts represents the options to create new instance of ACL.
+type ACLOpts struct {
+	// Roles contains rules by role names.
+	Roles map[string][]ACLRule
+
+	// Users contains role names by identity names.
+	Users map[string][]string
+
+	// DefaultRoles are used for identities that are not listed in Users
+	// including unauthenticated clients.
+	DefaultRoles []string
+}
+
+// ACL authorizes commands of the clients by their roles.
+type ACL struct {
+	roles        map[string][]aclRule
+	users        map[string][]string
+	defaultRoles []string
+}
+
+type aclRule struct {
+	keys     []*regexp.Regexp
+	commands map[string]bool
+}
+
+// NewACL returns new instance of ACL.
+// It returns nil if no roles are defined, nil ACL allows everything.
+func NewACL(opts ACLOpts) (*ACL, error) {
+	if len(opts.Roles) == 0 {
+		if len(opts.Users) > 0 || len(opts.DefaultRoles) > 0 {
+			return nil, fmt.Errorf("acl: users and default roles require roles to be defined")
+		}
+
+		return nil, nil
+	}
+
+	a := &ACL{
+		roles:        make(map[string][]aclRule, len(opts.Roles)),
+		users:        opts.Users,
+		defaultRoles: opts.DefaultRoles,
+	}
+	for name, rules := range opts.Roles {
+		for i, r := range rules {
+			rule, err := newACLRule(r)
+			if err != nil {
+				return nil, fmt.Errorf("acl: role %q, rule %d: %w", name, i, err)
+			}
+			a.roles[name] = append(a.roles[name], rule)
+		}
+	}
+
+	for user, roles := range opts.Users {
+		for _, role := range roles {
+			if _, ok := a.roles[role]; !ok {
+				return nil, fmt.Errorf("acl: user %q: unknown role %q", user, role)
+			}
+		}
+	}
+	for _, role := range opts.DefaultRoles {
+		if _, ok := a.roles[role]; !ok {
+			return nil, fmt.Errorf("acl: default roles: unknown role %q", role)
+		}
+	}
+
+	return a, nil
+}
+
+func newACLRule(r ACLRule) (aclRule, error) {
+	if len(r.Keys) == 0 {
+		return aclRule{}, fmt.Errorf("keys are required")
+	}
+	if len(r.Permissions) == 0 && len(r.Commands) == 0 {
+		return aclRule{}, fmt.Errorf("permissions or commands are required")
+	}
+
+	rule := aclRule{commands: make(map[string]bool)}
+	for _, pattern := range r.Keys {
+		re, err := globToRegexp(pattern)
+		if err != nil {
+			return aclRule{}, fmt.Errorf("invalid key pattern %q", pattern)
+		}
+		rule.keys = append(rule.keys, re)
+	}
+
+	for _, p := range r.Permissions {
+		switch p {
+		case PermissionRead, PermissionWrite, PermissionAdmin:
+		default:
+			return aclRule{}, fmt.Errorf("unknown permission %q", p)
+		}
+		for command, permission := range commandPermissions {
+			if p == PermissionAdmin || p == permission {
+				rule.commands[command] = true
+			}
+		}
+	}
+
+	for _, c := range r.Commands {
+		if _, ok := commandPermissions[c]; !ok {
+			return aclRule{}, fmt.Errorf("unknown command %q", c)
+		}
+		rule.commands[c] = true
+	}
+
+	return rule, nil
+}
+
+// IsAllowed method checks if the client is allowed to run the command on the key.
+func (a *ACL) IsAllowed(identity Identity, command, key string) bool {
+	if a == nil {
+		return true
+	}
+
+	for _, rule := range a.rules(identity) {
+		if rule.commands[command] && rule.matchKey(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsCommandAllowed method checks if the client is allowed to run the command
+// on any key.
+func (a *ACL) IsCommandAllowed(identity Identity, command string) bool {
+	if a == nil {
+		return true
+	}
+
+	for _, rule := range a.rules(identity) {
+		if rule.commands[command] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rules method returns all rules of the roles granted to the client.
+func (a *ACL) rules(identity Identity) []aclRule {
+	roles, ok := a.users[identity.Name]
+	if !ok || identity.Name == "" {
+		roles = a.defaultRoles
+	}
+
+	var rules []aclRule
+	for _, role := range roles {
+		rules = append(rules, a.roles[role]...)
+	}
+
+	return rules
+}
+
+func (r aclRule) matchKey(key string) bool {
+	for _, re := range r.keys {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Middleware returns middleware that puts ACL into the request context.
+func (a *ACL) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			next.ServeHTTP(w, req.WithContext(WithACL(req.Context(), a)))
+		})
+	}
+}
+
+// WithACL returns a copy of the context with ACL.
+func WithACL(ctx context.Context, a *ACL) context.Context {
+	return context.WithValue(ctx, ctxACL, a)
+}
+
+// GetACL returns ACL from the context.
+// It returns nil if ACL is not set, nil ACL allows everything.
+func GetACL(ctx context.Context) *ACL {
+	a, _ := ctx.Value(ctxACL).(*ACL)
+
+	return a
+}
+
+// IsAllowed checks if the client that sent the request is allowed to run
+// the command on the key.
+func IsAllowed(ctx context.Context, command, key string) bool {
+	identity, _ := GetIdentity(ctx)
+
+	return GetACL(ctx).IsAllowed(identity, command, key)
+}
+
+// IsCommandAllowed checks if the client that sent the request is allowed to run
+// the command on any key.
+func IsCommandAllowed(ctx context.Context, command string) bool {
+	identity, _ := GetIdentity(ctx)
+
+	return GetACL(ctx).IsCommandAllowed(identity, command)
+}
+
+// globToRegexp converts glob pattern to regular expression.
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString(`^(?s:`)
+
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; c {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+			}
+			sb.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case '[':
+			end := i + 1
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			if end == len(runes) || end == i+1 {
+				return nil, fmt.Errorf("unclosed or empty character class")
+			}
+			class := string(runes[i+1 : end])
+			if class[0] == '!' {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i = end
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString(`)$`)
+
+	return regexp.Compile(sb.String())
+}
@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestACL(t *testing.T) *ACL {
+	a, err := NewACL(ACLOpts{
+		Roles: map[string][]ACLRule{
+			"reporting": {{Keys: []string{"reports:*"}, Commands: []string{CommandGet, CommandKeys}}},
+			"writer": {
+				{Keys: []string{"app:[a-c]?", `app:\*`}, Permissions: []string{PermissionWrite}},
+				{Keys: []string{"app:*"}, Permissions: []string{PermissionRead}},
+			},
+			"admin": {{Keys: []string{"*"}, Permissions: []string{PermissionAdmin}}},
+		},
+		Users: map[string][]string{
+			"reporting-service": {"reporting"},
+			"app":               {"writer"},
+		},
+		DefaultRoles: []string{"admin"},
+	})
+	require.NoError(t, err)
+
+	return a
+}
+
+func TestACL_IsAllowed(t *testing.T) {
+	a := newTestACL(t)
+
+	reporting := Identity{Name: "reporting-service", Method: MethodToken}
+	require.True(t, a.IsAllowed(reporting, CommandGet, "reports:daily"))
+	require.True(t, a.IsAllowed(reporting, CommandKeys, "reports:"))
+	require.False(t, a.IsAllowed(reporting, CommandGet, "users:1"))
+	require.False(t, a.IsAllowed(reporting, CommandHGet, "reports:daily"))
+	require.False(t, a.IsAllowed(reporting, CommandSet, "reports:daily"))
+
+	app := Identity{Name: "app", Method: MethodHMAC}
+	require.True(t, a.IsAllowed(app, CommandSet, "app:a1"))
+	require.True(t, a.IsAllowed(app, CommandRPush, "app:c2"))
+	require.True(t, a.IsAllowed(app, CommandSet, "app:*"))
+	require.False(t, a.IsAllowed(app, CommandSet, "app:d1"))
+	require.False(t, a.IsAllowed(app, CommandSet, "app:a12"))
+	require.True(t, a.IsAllowed(app, CommandGeoSearch, "app:d1"))
+
+	// Unknown and unauthenticated clients get default roles
+	require.True(t, a.IsAllowed(Identity{Name: "unknown"}, CommandRemove, "users:1"))
+	require.True(t, a.IsAllowed(Identity{}, CommandHSet, "users:1"))
+}
+
+func TestACL_IsCommandAllowed(t *testing.T) {
+	a := newTestACL(t)
+
+	reporting := Identity{Name: "reporting-service"}
+	require.True(t, a.IsCommandAllowed(reporting, CommandKeys))
+	require.False(t, a.IsCommandAllowed(reporting, CommandLIndex))
+}
+
+func TestACL_Nil(t *testing.T) {
+	a, err := NewACL(ACLOpts{})
+	require.NoError(t, err)
+	require.Nil(t, a)
+
+	// Nil ACL allows everything
+	require.True(t, a.IsAllowed(Identity{}, CommandSet, "key"))
+	require.True(t, a.IsCommandAllowed(Identity{}, CommandKeys))
+
+	// Context without ACL allows everything
+	require.True(t, IsAllowed(context.Background(), CommandSet, "key"))
+}
+
+func TestACL_Context(t *testing.T) {
+	ctx := WithACL(context.Background(), newTestACL(t))
+	ctx = WithIdentity(ctx, Identity{Name: "reporting-service"})
+
+	require.True(t, IsAllowed(ctx, CommandGet, "reports:1"))
+	require.False(t, IsAllowed(ctx, CommandGet, "users:1"))
+	require.True(t, IsCommandAllowed(ctx, CommandKeys))
+	require.False(t, IsCommandAllowed(ctx, CommandSet))
+}
+
+func TestNewACL_Errors(t *testing.T) {
+	rule := ACLRule{Keys: []string{"*"}, Permissions: []string{PermissionRead}}
+
+	testCases := []struct {
+		name string
+		opts ACLOpts
+		err  string
+	}{
+		{
+			name: "users without roles",
+			opts: ACLOpts{Users: map[string][]string{"app": {"reader"}}},
+			err:  "acl: users and default roles require roles to be defined",
+		},
+		{
+			name: "unknown user role",
+			opts: ACLOpts{
+				Roles: map[string][]ACLRule{"reader": {rule}},
+				Users: map[string][]string{"app": {"writer"}},
+			},
+			err: `acl: user "app": unknown role "writer"`,
+		},
+		{
+			name: "unknown default role",
+			opts: ACLOpts{
+				Roles:        map[string][]ACLRule{"reader": {rule}},
+				DefaultRoles: []string{"writer"},
+			},
+			err: `acl: default roles: unknown role "writer"`,
+		},
+		{
+			name: "no keys",
+			opts: ACLOpts{Roles: map[string][]ACLRule{"reader": {{Permissions: []string{PermissionRead}}}}},
+			err:  `acl: role "reader", rule 0: keys are required`,
+		},
+		{
+			name: "no permissions",
+			opts: ACLOpts{Roles: map[string][]ACLRule{"reader": {{Keys: []string{"*"}}}}},
+			err:  `acl: role "reader", rule 0: permissions or commands are required`,
+		},
+		{
+			name: "unknown permission",
+			opts: ACLOpts{Roles: map[string][]ACLRule{"reader": {{Keys: []string{"*"}, Permissions: []string{"execute"}}}}},
+			err:  `acl: role "reader", rule 0: unknown permission "execute"`,
+		},
+		{
+			name: "unknown command",
+			opts: ACLOpts{Roles: map[string][]ACLRule{"reader": {{Keys: []string{"*"}, Commands: []string{"flushall"}}}}},
+			err:  `acl: role "reader", rule 0: unknown command "flushall"`,
+		},
+		{
+			name: "invalid pattern",
+			opts: ACLOpts{Roles: map[string][]ACLRule{"reader": {{Keys: []string{"reports:[a-"}, Commands: []string{CommandGet}}}}},
+			err:  `acl: role "reader", rule 0: invalid key pattern "reports:[a-"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewACL(tc.opts)
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		match   []string
+		noMatch []string
+	}{
+		{pattern: "*", match: []string{"", "a", "a:b\nc"}},
+		{pattern: "reports:*", match: []string{"reports:", "reports:2021"}, noMatch: []string{"report:1", "xreports:1"}},
+		{pattern: "user:?", match: []string{"user:1", "user:ж"}, noMatch: []string{"user:", "user:12"}},
+		{pattern: "[!a-c]x", match: []string{"dx"}, noMatch: []string{"ax", "x"}},
+		{pattern: `a\?b`, match: []string{"a?b"}, noMatch: []string{"acb"}},
+		{pattern: "a.b+", match: []string{"a.b+"}, noMatch: []string{"axbb"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern, func(t *testing.T) {
+			re, err := globToRegexp(tc.pattern)
+			require.NoError(t, err)
+			for _, s := range tc.match {
+				require.True(t, re.MatchString(s), s)
+			}
+			for _, s := range tc.noMatch {
+				require.False(t, re.MatchString(s), s)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/accesslog"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrAuthRequired        = errors.New("authentication required")
+	ErrInvalidToken        = errors.New("invalid token")
+	ErrIncompleteSignature = errors.New("key id, timestamp, nonce and signature are required")
+	ErrInvalidTimestamp    = errors.New("request timestamp is invalid or outside of the allowed clock skew")
+	ErrInvalidNonce        = errors.New("nonce is too long")
+	ErrInvalidSignature    = errors.New("invalid signature")
+	ErrReplayedRequest     = errors.New("request has been already processed")
+	ErrMethodDisabled      = errors.New("authentication method is disabled")
+)
+
+// Supported authentication methods.
+const (
+	MethodToken = "token"
+	MethodHMAC  = "hmac"
+	MethodMTLS  = "mtls"
+)
+
+const (
+	defaultReloadInterval = 10 * time.Second
+	defaultMaxClockSkew   = 5 * time.Minute
+)
+
+type ctxKey int
+
+const (
+	ctxIdentity ctxKey = iota
+	ctxACL
+)
+
+// Identity represents authenticated client.
+type Identity struct {
+	// Name is the name of the token, HMAC key ID or common name of the client certificate.
+	Name string
+
+	// Method is the authentication method used by the client.
+	Method string
+}
+
+// WithIdentity returns a copy of the context with the identity.
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, ctxIdentity, identity)
+}
+
+// GetIdentity returns the identity of authenticated client from the context.
+func GetIdentity(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(ctxIdentity).(Identity)
+
+	return identity, ok
+}
+
+// ClientName returns the name of the client to be reported in logs,
+// it's the identity name of authenticated client and the remote address otherwise.
+func ClientName(req *http.Request) string {
+	if identity, ok := GetIdentity(req.Context()); ok && identity.Name != "" {
+		return identity.Name
+	}
+
+	return req.RemoteAddr
+}
+
+// Opts represents the options to create new instance of Authenticator.
+type Opts struct {
+	// TokensFile is the path to the file with bearer tokens in "<name>:<token>" format.
+	TokensFile string
+
+	// HMACKeysFile is the path to the file with HMAC keys in "<key id>:<secret>" format.
+	HMACKeysFile string
+
+	// HMACMaxClockSkew is the max difference between the request timestamp
+	// and the server time.
+	HMACMaxClockSkew time.Duration
+
+	// MTLS enables authentication by verified client certificates.
+	MTLS bool
+
+	// ReloadInterval is how often files are checked for changes.
+	ReloadInterval time.Duration
+
+	Log *zap.Logger
+}
+
+// ErrorWriter writes authentication error to the response.
+type ErrorWriter func(w http.ResponseWriter, status int, message string)
+
+// Authenticator authenticates requests to the public API.
+type Authenticator struct {
+	log *zap.Logger
+
+	tokens       *credentialsFile
+	hmacKeys     *credentialsFile
+	mtls         bool
+	maxClockSkew time.Duration
+	nonces       *nonceCache
+
+	// now is used to get current time, it's replaced in tests
+	now func() time.Time
+
+	stopReload chan struct{}
+	stopOnce   sync.Once
+}
+
+// New returns new instance of Authenticator.
+// Files are checked for changes and reloaded within the reload interval
+// until Close is called.
+func New(opts Opts) (*Authenticator, error) {
+	a := &Authenticator{
+		log:          opts.Log,
+		mtls:         opts.MTLS,
+		maxClockSkew: opts.HMACMaxClockSkew,
+		nonces:       newNonceCache(),
+		now:          time.Now,
+		stopReload:   make(chan struct{}),
+	}
+	if a.log == nil {
+		a.log = zap.NewNop()
+	}
+	if a.maxClockSkew <= 0 {
+		a.maxClockSkew = defaultMaxClockSkew
+	}
+
+	var err error
+	if opts.TokensFile != "" {
+		if a.tokens, err = newCredentialsFile(opts.TokensFile); err != nil {
+			return nil, err
+		}
+	}
+	if opts.HMACKeysFile != "" {
+		if a.hmacKeys, err = newCredentialsFile(opts.HMACKeysFile); err != nil {
+			return nil, err
+		}
+	}
+
+	reloadInterval := opts.ReloadInterval
+	if reloadInterval <= 0 {
+		reloadInterval = defaultReloadInterval
+	}
+	if a.tokens != nil || a.hmacKeys != nil {
+		go a.reloader(reloadInterval)
+	}
+
+	return a, nil
+}
+
+// Enabled method returns true if any authentication method is configured.
+func (a *Authenticator) Enabled() bool {
+	return a != nil && (a.tokens != nil || a.hmacKeys != nil || a.mtls)
+}
+
+// Close stops reloading of the files.
+func (a *Authenticator) Close() {
+	a.stopOnce.Do(func() {
+		close(a.stopReload)
+	})
+}
+
+// Reload method reads tokens and HMAC keys files if they have been changed.
+// Previous credentials are kept if the file could not be read.
+func (a *Authenticator) Reload() error {
+	for _, f := range []*credentialsFile{a.tokens, a.hmacKeys} {
+		if f == nil {
+			continue
+		}
+
+		isReloaded, err := f.reload(false)
+		if err != nil {
+			return err
+		}
+		if isReloaded {
+			a.log.Info("credentials reloaded", zap.String("file", f.path))
+		}
+	}
+
+	return nil
+}
+
+func (a *Authenticator) reloader(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			if err := a.Reload(); err != nil {
+				a.log.Warn("failed to reload credentials", zap.Error(err))
+			}
+		case <-a.stopReload:
+			return
+		}
+	}
+}
+
+// Authenticate method returns the identity of the client that sent the request.
+// Credentials are checked in order: bearer token, HMAC signature, client certificate.
+func (a *Authenticator) Authenticate(req *http.Request) (Identity, error) {
+	if authorization := req.Header.Get("Authorization"); authorization != "" {
+		return a.AuthenticateToken(authorization)
+	}
+
+	if isHMACRequest(req) {
+		if a.hmacKeys == nil {
+			return Identity{}, ErrMethodDisabled
+		}
+
+		keyID, err := a.verifyHMAC(req)
+		if err != nil {
+			return Identity{}, err
+		}
+
+		return Identity{Name: keyID, Method: MethodHMAC}, nil
+	}
+
+	return a.AuthenticateTLS(req.TLS)
+}
+
+// AuthenticateToken method returns the identity of the client by the value
+// of the Authorization header with the bearer token.
+func (a *Authenticator) AuthenticateToken(authorization string) (Identity, error) {
+	if a.tokens == nil {
+		return Identity{}, ErrMethodDisabled
+	}
+
+	const prefix = "bearer "
+	if len(authorization) <= len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return Identity{}, ErrInvalidToken
+	}
+	name, ok := a.tokens.nameBySecret(strings.TrimSpace(authorization[len(prefix):]))
+	if !ok {
+		return Identity{}, ErrInvalidToken
+	}
+
+	return Identity{Name: name, Method: MethodToken}, nil
+}
+
+// AuthenticateTLS method returns the identity of the client by the verified
+// client certificate of the connection, the state is nil for plain connections.
+func (a *Authenticator) AuthenticateTLS(state *tls.ConnectionState) (Identity, error) {
+	// Only the certificates verified by the server could be trusted
+	if a.mtls && state != nil && len(state.VerifiedChains) > 0 && len(state.VerifiedChains[0]) > 0 {
+		cert := state.VerifiedChains[0][0]
+		if cert.Subject.CommonName != "" {
+			return Identity{Name: cert.Subject.CommonName, Method: MethodMTLS}, nil
+		}
+	}
+
+	return Identity{}, ErrAuthRequired
+}
+
+// Middleware returns middleware that rejects unauthenticated requests
+// and puts the identity of the client into the request context.
+func (a *Authenticator) Middleware(writeErr ErrorWriter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			identity, err := a.Authenticate(req)
+			if err != nil {
+				accesslog.RequestLogger(req.Context(), a.log).Debug("authentication failed",
+					zap.String("remote_addr", req.RemoteAddr),
+					zap.Error(err))
+				if a.tokens != nil {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="bookish-spork"`)
+				}
+				writeErr(w, http.StatusUnauthorized, err.Error())
+
+				return
+			}
+
+			accesslog.SetClient(req.Context(), identity.Name)
+			next.ServeHTTP(w, req.WithContext(WithIdentity(req.Context(), identity)))
+		})
+	}
+}
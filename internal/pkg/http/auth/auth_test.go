@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAuthenticator(t *testing.T, opts Opts) (*Authenticator, func()) {
+	dir, err := ioutil.TempDir("", "auth")
+	require.NoError(t, err)
+
+	opts.TokensFile = writeTestFile(t, dir, "tokens", "app1:token1\n")
+	opts.HMACKeysFile = writeTestFile(t, dir, "hmac_keys", "key1:secret1\n")
+	a, err := New(opts)
+	require.NoError(t, err)
+
+	return a, func() {
+		a.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func signTestRequest(req *http.Request, keyID, secret, nonce string, ts time.Time, body []byte) {
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	req.Header.Set(HeaderKeyID, keyID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignature, Sign(secret, StringToSign(req.Method, req.URL.RequestURI(), timestamp, nonce, body)))
+}
+
+func TestAuthenticate_Token(t *testing.T) {
+	a, cleanup := newTestAuthenticator(t, Opts{})
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/keys", nil)
+	req.Header.Set("Authorization", "Bearer token1")
+	identity, err := a.Authenticate(req)
+	require.NoError(t, err)
+	require.Equal(t, Identity{Name: "app1", Method: MethodToken}, identity)
+
+	req.Header.Set("Authorization", "Bearer token2")
+	_, err = a.Authenticate(req)
+	require.Equal(t, ErrInvalidToken, err)
+
+	req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	_, err = a.Authenticate(req)
+	require.Equal(t, ErrInvalidToken, err)
+}
+
+func TestAuthenticate_HMAC(t *testing.T) {
+	a, cleanup := newTestAuthenticator(t, Opts{HMACMaxClockSkew: time.Minute})
+	defer cleanup()
+
+	body := []byte(`{"key": "k", "value": "v"}`)
+	newReq := func() *http.Request {
+		return httptest.NewRequest(http.MethodPost, "/v1/set?a=b", bytes.NewReader(body))
+	}
+
+	req := newReq()
+	signTestRequest(req, "key1", "secret1", "nonce1", time.Now(), body)
+	identity, err := a.Authenticate(req)
+	require.NoError(t, err)
+	require.Equal(t, Identity{Name: "key1", Method: MethodHMAC}, identity)
+
+	// Body is still available for the handlers
+	got, err := ioutil.ReadAll(req.Body)
+	require.NoError(t, err)
+	require.Equal(t, body, got)
+
+	// Replayed request
+	req = newReq()
+	signTestRequest(req, "key1", "secret1", "nonce1", time.Now(), body)
+	_, err = a.Authenticate(req)
+	require.Equal(t, ErrReplayedRequest, err)
+
+	// Outdated request
+	req = newReq()
+	signTestRequest(req, "key1", "secret1", "nonce2", time.Now().Add(-2*time.Minute), body)
+	_, err = a.Authenticate(req)
+	require.Equal(t, ErrInvalidTimestamp, err)
+
+	// Modified body
+	req = newReq()
+	signTestRequest(req, "key1", "secret1", "nonce3", time.Now(), []byte("{}"))
+	_, err = a.Authenticate(req)
+	require.Equal(t, ErrInvalidSignature, err)
+
+	// Unknown key
+	req = newReq()
+	signTestRequest(req, "key2", "secret1", "nonce4", time.Now(), body)
+	_, err = a.Authenticate(req)
+	require.Equal(t, ErrInvalidSignature, err)
+
+	// Missing headers
+	req = newReq()
+	req.Header.Set(HeaderSignature, "abc")
+	_, err = a.Authenticate(req)
+	require.Equal(t, ErrIncompleteSignature, err)
+}
+
+func TestAuthenticate_MTLS(t *testing.T) {
+	a, err := New(Opts{MTLS: true})
+	require.NoError(t, err)
+	defer a.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/keys", nil)
+	_, err = a.Authenticate(req)
+	require.Equal(t, ErrAuthRequired, err)
+
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "client1"}}
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	identity, err := a.Authenticate(req)
+	require.NoError(t, err)
+	require.Equal(t, Identity{Name: "client1", Method: MethodMTLS}, identity)
+
+	// Tokens are not configured
+	req.Header.Set("Authorization", "Bearer token1")
+	_, err = a.Authenticate(req)
+	require.Equal(t, ErrMethodDisabled, err)
+}
+
+func TestMiddleware(t *testing.T) {
+	a, cleanup := newTestAuthenticator(t, Opts{})
+	defer cleanup()
+
+	handler := a.Middleware(func(w http.ResponseWriter, status int, message string) {
+		http.Error(w, message, status)
+	})(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		identity, ok := GetIdentity(req.Context())
+		require.True(t, ok)
+		_, _ = w.Write([]byte(identity.Name))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/keys", nil))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Equal(t, `Bearer realm="bookish-spork"`, w.Header().Get("WWW-Authenticate"))
+	require.Equal(t, ErrAuthRequired.Error()+"\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/keys", nil)
+	req.Header.Set("Authorization", "Bearer token1")
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "app1", w.Body.String())
+}
+
+func TestEnabled(t *testing.T) {
+	var a *Authenticator
+	require.False(t, a.Enabled())
+
+	a, err := New(Opts{})
+	require.NoError(t, err)
+	defer a.Close()
+	require.False(t, a.Enabled())
+}
+
+func TestClientName(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	require.Equal(t, "10.0.0.1:5000", ClientName(req))
+
+	req = req.WithContext(WithIdentity(req.Context(), Identity{Name: "alice", Method: MethodToken}))
+	require.Equal(t, "alice", ClientName(req))
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// credentialsFile represents a file with "<name>:<secret>" pairs, one per line.
+// Empty lines and lines started with # are ignored.
+type credentialsFile struct {
+	path string
+
+	mux     sync.RWMutex
+	modTime time.Time
+	size    int64
+
+	// secrets contains secrets by names
+	secrets map[string]string
+
+	// names contains names by SHA-256 of the secrets
+	names map[[sha256.Size]byte]string
+}
+
+// newCredentialsFile reads credentials from the file.
+func newCredentialsFile(path string) (*credentialsFile, error) {
+	f := &credentialsFile{path: path}
+	if _, err := f.reload(true); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// reload method reads the file if it has been changed since the last read
+// or force is true.
+// It returns true if credentials have been reloaded.
+func (f *credentialsFile) reload(force bool) (bool, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return false, err
+	}
+
+	f.mux.RLock()
+	isChanged := !info.ModTime().Equal(f.modTime) || info.Size() != f.size
+	f.mux.RUnlock()
+	if !isChanged && !force {
+		return false, nil
+	}
+
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return false, err
+	}
+	secrets, err := parseCredentials(data)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", f.path, err)
+	}
+
+	names := make(map[[sha256.Size]byte]string, len(secrets))
+	for name, secret := range secrets {
+		hash := sha256.Sum256([]byte(secret))
+		if _, ok := names[hash]; ok {
+			return false, fmt.Errorf("%s: secret of %q is not unique", f.path, name)
+		}
+		names[hash] = name
+	}
+
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.modTime = info.ModTime()
+	f.size = info.Size()
+	f.secrets = secrets
+	f.names = names
+
+	return true, nil
+}
+
+// nameBySecret method returns the name the secret belongs to.
+func (f *credentialsFile) nameBySecret(secret string) (string, bool) {
+	f.mux.RLock()
+	defer f.mux.RUnlock()
+
+	// Secrets are looked up by hash to avoid timing attacks on the map lookup
+	name, ok := f.names[sha256.Sum256([]byte(secret))]
+	if !ok {
+		return "", false
+	}
+
+	return name, subtle.ConstantTimeCompare([]byte(f.secrets[name]), []byte(secret)) == 1
+}
+
+// secretByName method returns the secret by its name.
+func (f *credentialsFile) secretByName(name string) (string, bool) {
+	f.mux.RLock()
+	defer f.mux.RUnlock()
+
+	secret, ok := f.secrets[name]
+
+	return secret, ok
+}
+
+// parseCredentials parses "<name>:<secret>" pairs.
+func parseCredentials(data []byte) (map[string]string, error) {
+	secrets := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(text, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("line %d: expected <name>:<secret>", line)
+		}
+		if _, ok := secrets[parts[0]]; ok {
+			return nil, fmt.Errorf("line %d: duplicate name %q", line, parts[0])
+		}
+		secrets[parts[0]] = parts[1]
+	}
+
+	return secrets, scanner.Err()
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFile(t *testing.T, dir, name, data string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(data), 0o600))
+
+	return path
+}
+
+func TestParseCredentials(t *testing.T) {
+	secrets, err := parseCredentials([]byte("# comment\n\napp1:secret1\n  app2:sec:ret2  \n"))
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"app1": "secret1", "app2": "sec:ret2"}, secrets)
+}
+
+func TestParseCredentials_Invalid(t *testing.T) {
+	_, err := parseCredentials([]byte("app1:secret1\napp2\n"))
+	require.EqualError(t, err, "line 2: expected <name>:<secret>")
+
+	_, err = parseCredentials([]byte("app1:secret1\napp1:secret2\n"))
+	require.EqualError(t, err, `line 2: duplicate name "app1"`)
+}
+
+func TestCredentialsFile_Reload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := writeTestFile(t, dir, "tokens", "app1:secret1\n")
+	f, err := newCredentialsFile(path)
+	require.NoError(t, err)
+
+	name, ok := f.nameBySecret("secret1")
+	require.True(t, ok)
+	require.Equal(t, "app1", name)
+
+	// Not changed file is not reloaded
+	isReloaded, err := f.reload(false)
+	require.NoError(t, err)
+	require.False(t, isReloaded)
+
+	writeTestFile(t, dir, "tokens", "app2:secret2\n")
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	isReloaded, err = f.reload(false)
+	require.NoError(t, err)
+	require.True(t, isReloaded)
+
+	_, ok = f.nameBySecret("secret1")
+	require.False(t, ok)
+	secret, ok := f.secretByName("app2")
+	require.True(t, ok)
+	require.Equal(t, "secret2", secret)
+
+	// Invalid file doesn't replace loaded credentials
+	writeTestFile(t, dir, "tokens", "app3:secret2\napp4:secret2\n")
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(2*time.Minute)))
+	_, err = f.reload(false)
+	require.Error(t, err)
+	_, ok = f.secretByName("app2")
+	require.True(t, ok)
+}
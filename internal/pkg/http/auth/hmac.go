@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Headers of HMAC-signed requests.
+const (
+	HeaderKeyID     = "X-BS-Key-Id"
+	HeaderTimestamp = "X-BS-Timestamp"
+	HeaderNonce     = "X-BS-Nonce"
+	HeaderSignature = "X-BS-Signature"
+)
+
+// maxNonceLength limits the size of the nonce stored to prevent replays.
+const maxNonceLength = 128
+
+// StringToSign returns the string that must be signed with HMAC-SHA256 by the client:
+//
+//   <METHOD>\n<request URI>\n<timestamp>\n<nonce>\n<hex SHA-256 of the body>
+func StringToSign(method, requestURI, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+
+	return strings.Join([]string{
+		method,
+		requestURI,
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+}
+
+// Sign returns hex-encoded HMAC-SHA256 of the string with the secret.
+func Sign(secret, stringToSign string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(stringToSign))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// isHMACRequest checks if request contains HMAC signature.
+func isHMACRequest(req *http.Request) bool {
+	return req.Header.Get(HeaderSignature) != "" || req.Header.Get(HeaderKeyID) != ""
+}
+
+// verifyHMAC method checks the request signature and returns the key ID.
+func (a *Authenticator) verifyHMAC(req *http.Request) (string, error) {
+	keyID := req.Header.Get(HeaderKeyID)
+	timestamp := req.Header.Get(HeaderTimestamp)
+	nonce := req.Header.Get(HeaderNonce)
+	signature := req.Header.Get(HeaderSignature)
+	if keyID == "" || timestamp == "" || nonce == "" || signature == "" {
+		return "", ErrIncompleteSignature
+	}
+	if len(nonce) > maxNonceLength {
+		return "", ErrInvalidNonce
+	}
+
+	// Check that request is fresh enough
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", ErrInvalidTimestamp
+	}
+	now := a.now()
+	signedAt := time.Unix(ts, 0)
+	if signedAt.Before(now.Add(-a.maxClockSkew)) || signedAt.After(now.Add(a.maxClockSkew)) {
+		return "", ErrInvalidTimestamp
+	}
+
+	secret, ok := a.hmacKeys.secretByName(keyID)
+	if !ok {
+		return "", ErrInvalidSignature
+	}
+
+	// Read the body to sign and restore it for the next handlers
+	var body []byte
+	if req.Body != nil {
+		body, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return "", ErrInvalidSignature
+		}
+		_ = req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	expected := Sign(secret, StringToSign(req.Method, req.URL.RequestURI(), timestamp, nonce, body))
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return "", ErrInvalidSignature
+	}
+
+	// Signature is valid, make sure it's not a replay
+	if !a.nonces.add(keyID+":"+nonce, signedAt.Add(a.maxClockSkew), now) {
+		return "", ErrReplayedRequest
+	}
+
+	return keyID, nil
+}
+
+// nonceCache stores nonces of the accepted requests until the requests
+// signed with them get expired.
+type nonceCache struct {
+	mux       sync.Mutex
+	expiresAt map[string]time.Time
+	lastPurge time.Time
+}
+
+func newNonceCache() *nonceCache {
+	return &nonceCache{expiresAt: make(map[string]time.Time)}
+}
+
+// add method stores the nonce until the given time.
+// It returns false if nonce has been already used.
+func (c *nonceCache) add(nonce string, expiresAt, now time.Time) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	// Purge expired nonces at most once a second
+	if now.Sub(c.lastPurge) >= time.Second {
+		for n, t := range c.expiresAt {
+			if now.After(t) {
+				delete(c.expiresAt, n)
+			}
+		}
+		c.lastPurge = now
+	}
+
+	if t, ok := c.expiresAt[nonce]; ok && !now.After(t) {
+		return false
+	}
+	c.expiresAt[nonce] = expiresAt
+
+	return true
+}
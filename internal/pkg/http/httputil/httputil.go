@@ -0,0 +1,86 @@
+// Package httputil provides helpers shared by the middlewares of the public API.
+package httputil
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+)
+
+// RouteUnmatched is the route of the requests to unknown routes.
+const RouteUnmatched = "unmatched"
+
+// Route returns the route pattern of the request without trailing slash,
+// it's RouteUnmatched if the request has not matched any route.
+func Route(req *http.Request) string {
+	// Route pattern is complete only after the request has been routed,
+	// so it must be called after the next handler of the middleware
+	if rctx := chi.RouteContext(req.Context()); rctx != nil {
+		if pattern := strings.TrimSuffix(rctx.RoutePattern(), "/"); pattern != "" && !strings.HasSuffix(pattern, "*") {
+			return pattern
+		}
+	}
+
+	return RouteUnmatched
+}
+
+// ResponseWriter remembers the status code and the size of the response.
+// It implements http.Flusher, so the responses could be streamed through it.
+type ResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// NewResponseWriter returns ResponseWriter that wraps w.
+func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	return &ResponseWriter{ResponseWriter: w}
+}
+
+func (w *ResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+
+	return n, err
+}
+
+// Flush sends buffered data to the client if the wrapped writer supports it.
+func (w *ResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Unwrap returns the wrapped writer.
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Status method returns the status code of the response,
+// it's 200 if nothing has been written.
+func (w *ResponseWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+
+	return w.status
+}
+
+// Bytes method returns the number of bytes of the response body written so far.
+func (w *ResponseWriter) Bytes() int64 {
+	return w.bytes
+}
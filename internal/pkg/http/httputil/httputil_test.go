@@ -0,0 +1,60 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoute(t *testing.T) {
+	r := chi.NewRouter()
+	var route string
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			next.ServeHTTP(w, req)
+			route = Route(req)
+		})
+	})
+	r.Get("/v1/get/{key}", func(w http.ResponseWriter, req *http.Request) {})
+	r.Get("/v1/keys/", func(w http.ResponseWriter, req *http.Request) {})
+
+	for path, expected := range map[string]string{
+		"/v1/get/a": "/v1/get/{key}",
+		"/v1/keys/": "/v1/keys",
+		"/unknown":  RouteUnmatched,
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, expected, route, path)
+	}
+
+	require.Equal(t, RouteUnmatched, Route(httptest.NewRequest(http.MethodGet, "/", nil)))
+}
+
+func TestResponseWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := NewResponseWriter(rec)
+	require.Equal(t, http.StatusOK, w.Status())
+
+	w.WriteHeader(http.StatusNotFound)
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write([]byte("not found"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, w.Status())
+	require.Equal(t, int64(9), w.Bytes())
+	require.Equal(t, rec, w.Unwrap())
+}
+
+func TestResponseWriter_Flush(t *testing.T) {
+	// Streamed responses are flushed through the nested writers
+	rec := httptest.NewRecorder()
+	var w http.ResponseWriter = NewResponseWriter(NewResponseWriter(rec))
+	w.(http.Flusher).Flush()
+	require.True(t, rec.Flushed)
+	require.Equal(t, http.StatusOK, w.(*ResponseWriter).Status())
+
+	// Flush is ignored if the wrapped writer doesn't support it
+	NewResponseWriter(struct{ http.ResponseWriter }{httptest.NewRecorder()}).Flush()
+}
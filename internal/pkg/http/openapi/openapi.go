@@ -0,0 +1,22 @@
+package openapi
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Path is the path the specification is served at.
+const Path = "/openapi.json"
+
+// Spec returns OpenAPI 3 specification of the public API in JSON format.
+func Spec() []byte {
+	return []byte(spec)
+}
+
+// Handler serves OpenAPI specification.
+func Handler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(spec)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(spec))
+}
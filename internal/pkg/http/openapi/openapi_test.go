@@ -0,0 +1,26 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpecIsValidJSON(t *testing.T) {
+	var v map[string]interface{}
+	assert.NoError(t, json.Unmarshal(Spec(), &v))
+	assert.Equal(t, "3.0.3", v["openapi"])
+}
+
+func TestHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, Path, nil)
+	Handler(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, Spec(), w.Body.Bytes())
+}
@@ -0,0 +1,626 @@
+package openapi
+
+// spec contains OpenAPI 3 specification of the public API.
+// It must be updated along with any route added to the public API router,
+// contract tests in the http package check that.
+const spec = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "bookish-spork",
+    "description": "HTTP API to in-memory cache. If authentication is enabled, requests to /v1 and /v2 without valid credentials are rejected with 401 status code. If ACL is configured, requests to keys and commands not granted to the client are rejected with 403 status code and /keys returns only the keys the client may read. If rate limiting is configured, throttled requests are rejected with 429 status code and Retry-After header.",
+    "version": "2.0.0"
+  },
+  "security": [{}, {"bearerAuth": []}, {"hmacAuth": []}],
+  "paths": {
+    "/openapi.json": {
+      "get": {
+        "summary": "Get OpenAPI specification",
+        "operationId": "getOpenAPI",
+        "responses": {
+          "200": {
+            "description": "OpenAPI specification",
+            "content": {"application/json": {"schema": {"type": "object"}}}
+          }
+        }
+      }
+    },
+    "/v1/get/{key}": {
+      "get": {
+        "summary": "Get value by key",
+        "description": "Raw values are returned as is only if Accept header explicitly contains their content type.",
+        "operationId": "v1Get",
+        "parameters": [{"$ref": "#/components/parameters/Key"}],
+        "responses": {
+          "200": {
+            "description": "Value",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/V1ValueResponse"}},
+              "application/octet-stream": {"schema": {"type": "string", "format": "binary"}},
+              "text/plain": {"schema": {"type": "string"}},
+              "application/msgpack": {"schema": {"type": "string", "format": "binary"}}
+            }
+          },
+          "404": {"description": "Key not found"}
+        }
+      }
+    },
+    "/v1/set": {
+      "post": {
+        "summary": "Set value by key",
+        "description": "Raw values are passed as request body, key and TTL are passed as query parameters in that case.",
+        "operationId": "v1Set",
+        "parameters": [
+          {"name": "key", "in": "query", "schema": {"type": "string"}, "description": "Key of the raw value"},
+          {"$ref": "#/components/parameters/TTLQuery"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/V1SetRequest"}},
+            "application/octet-stream": {"schema": {"type": "string", "format": "binary"}},
+            "text/plain": {"schema": {"type": "string"}},
+            "application/msgpack": {"schema": {"type": "string", "format": "binary"}}
+          }
+        },
+        "responses": {
+          "200": {"description": "Value has been set"},
+          "400": {"$ref": "#/components/responses/V1BadRequest"}
+        }
+      }
+    },
+    "/v1/keys": {
+      "get": {
+        "summary": "Get list of all keys",
+        "operationId": "v1Keys",
+        "responses": {
+          "200": {
+            "description": "List of keys",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/KeysResponse"}}}
+          }
+        }
+      }
+    },
+    "/v1/keys/{key}": {
+      "get": {
+        "summary": "Get value by key as is",
+        "description": "Raw values are returned verbatim with the original content type, other values are returned as JSON.",
+        "operationId": "v1GetValue",
+        "parameters": [{"$ref": "#/components/parameters/Key"}],
+        "responses": {
+          "200": {
+            "description": "Value",
+            "content": {
+              "application/json": {"schema": {}},
+              "application/octet-stream": {"schema": {"type": "string", "format": "binary"}},
+              "text/plain": {"schema": {"type": "string"}},
+              "application/msgpack": {"schema": {"type": "string", "format": "binary"}}
+            }
+          },
+          "404": {"description": "Key not found"},
+          "406": {"$ref": "#/components/responses/V1Error"}
+        }
+      },
+      "put": {
+        "summary": "Set binary-safe value by key",
+        "operationId": "v1PutValue",
+        "parameters": [
+          {"$ref": "#/components/parameters/Key"},
+          {"$ref": "#/components/parameters/TTLQuery"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {}},
+            "application/octet-stream": {"schema": {"type": "string", "format": "binary"}},
+            "text/plain": {"schema": {"type": "string"}},
+            "application/msgpack": {"schema": {"type": "string", "format": "binary"}}
+          }
+        },
+        "responses": {
+          "200": {"description": "Value has been set"},
+          "400": {"$ref": "#/components/responses/V1BadRequest"},
+          "415": {"$ref": "#/components/responses/V1Error"}
+        }
+      }
+    },
+    "/v1/remove/{key}": {
+      "delete": {
+        "summary": "Remove key",
+        "operationId": "v1Remove",
+        "parameters": [{"$ref": "#/components/parameters/Key"}],
+        "responses": {
+          "204": {"description": "Key has been removed"}
+        }
+      }
+    },
+    "/v1/rpush": {
+      "post": {
+        "summary": "Add value to a list or create a new one",
+        "operationId": "v1RPush",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/V1SetRequest"}}}
+        },
+        "responses": {
+          "200": {"description": "Value has been added"},
+          "400": {"$ref": "#/components/responses/V1BadRequest"}
+        }
+      }
+    },
+    "/v1/lindex/{key}/{index}": {
+      "get": {
+        "summary": "Get value by the index in list",
+        "operationId": "v1LIndex",
+        "parameters": [
+          {"$ref": "#/components/parameters/Key"},
+          {"$ref": "#/components/parameters/Index"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Value, null if index is out of range",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/V1ValueResponse"}}}
+          },
+          "400": {"$ref": "#/components/responses/V1BadRequest"},
+          "404": {"description": "Key not found"}
+        }
+      }
+    },
+    "/v1/hset": {
+      "post": {
+        "summary": "Add key-value pairs to hash map or create a new one",
+        "operationId": "v1HSet",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/V1HSetRequest"}}}
+        },
+        "responses": {
+          "200": {"description": "Values have been set"},
+          "400": {"$ref": "#/components/responses/V1BadRequest"}
+        }
+      }
+    },
+    "/v1/hget/{key}/{hkey}": {
+      "get": {
+        "summary": "Get a value by hash map key",
+        "operationId": "v1HGet",
+        "parameters": [
+          {"$ref": "#/components/parameters/Key"},
+          {"name": "hkey", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "Value, null if hash map key does not exist",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/V1ValueResponse"}}}
+          },
+          "400": {"$ref": "#/components/responses/V1BadRequest"},
+          "404": {"description": "Key not found"}
+        }
+      }
+    },
+    "/v1/ttl/{key}": {
+      "get": {
+        "summary": "Get the remaining time to live of a key",
+        "operationId": "v1TTL",
+        "parameters": [{"$ref": "#/components/parameters/Key"}],
+        "responses": {
+          "200": {
+            "description": "Remaining seconds, -1 if the key will never be expired",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/TTLResponse"}}}
+          },
+          "404": {"description": "Key not found"}
+        }
+      }
+    },
+    "/v1/geoadd": {
+      "post": {
+        "summary": "Add members to geospatial index or create a new one",
+        "operationId": "v1GeoAdd",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/GeoAddRequest"}}}
+        },
+        "responses": {
+          "200": {
+            "description": "Number of added members",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/GeoAddResponse"}}}
+          },
+          "400": {"$ref": "#/components/responses/V1BadRequest"}
+        }
+      }
+    },
+    "/v1/geopos/{key}": {
+      "get": {
+        "summary": "Get positions of geospatial index members",
+        "operationId": "v1GeoPos",
+        "parameters": [
+          {"$ref": "#/components/parameters/Key"},
+          {"$ref": "#/components/parameters/Members"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Positions, null for not existing members",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/GeoPosResponse"}}}
+          },
+          "400": {"$ref": "#/components/responses/V1BadRequest"},
+          "404": {"description": "Key not found"}
+        }
+      }
+    },
+    "/v1/geodist/{key}": {
+      "get": {
+        "summary": "Get distance between two members of geospatial index",
+        "operationId": "v1GeoDist",
+        "parameters": [
+          {"$ref": "#/components/parameters/Key"},
+          {"$ref": "#/components/parameters/Members"},
+          {"name": "unit", "in": "query", "schema": {"$ref": "#/components/schemas/GeoUnit"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "Distance, null if any of members does not exist",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/GeoDistResponse"}}}
+          },
+          "400": {"$ref": "#/components/responses/V1BadRequest"},
+          "404": {"description": "Key not found"}
+        }
+      }
+    },
+    "/v1/geosearch": {
+      "post": {
+        "summary": "Find members of geospatial index within radius or box",
+        "operationId": "v1GeoSearch",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/GeoSearchRequest"}}}
+        },
+        "responses": {
+          "200": {
+            "description": "Found members",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/GeoSearchResponse"}}}
+          },
+          "400": {"$ref": "#/components/responses/V1BadRequest"},
+          "404": {"description": "Key not found"}
+        }
+      }
+    },
+    "/v2/keys": {
+      "get": {
+        "summary": "Get list of all keys",
+        "operationId": "v2Keys",
+        "responses": {
+          "200": {
+            "description": "List of keys",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/KeysResponse"}}}
+          }
+        }
+      }
+    },
+    "/v2/keys/{key}": {
+      "get": {
+        "summary": "Get value by key",
+        "operationId": "v2Get",
+        "parameters": [{"$ref": "#/components/parameters/Key"}],
+        "responses": {
+          "200": {
+            "description": "Value",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/V2ValueResponse"}}}
+          },
+          "404": {"$ref": "#/components/responses/V2Error"}
+        }
+      },
+      "put": {
+        "summary": "Set value by key",
+        "operationId": "v2Put",
+        "parameters": [{"$ref": "#/components/parameters/Key"}],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/V2ValueRequest"}}}
+        },
+        "responses": {
+          "204": {"description": "Value has been set"},
+          "400": {"$ref": "#/components/responses/V2Error"},
+          "415": {"$ref": "#/components/responses/V2Error"}
+        }
+      },
+      "patch": {
+        "summary": "Append items to list or set hash map fields",
+        "operationId": "v2Patch",
+        "parameters": [{"$ref": "#/components/parameters/Key"}],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/V2PatchRequest"}}}
+        },
+        "responses": {
+          "204": {"description": "Value has been modified"},
+          "400": {"$ref": "#/components/responses/V2Error"},
+          "409": {"$ref": "#/components/responses/V2Error"},
+          "415": {"$ref": "#/components/responses/V2Error"}
+        }
+      },
+      "delete": {
+        "summary": "Remove key",
+        "operationId": "v2Delete",
+        "parameters": [{"$ref": "#/components/parameters/Key"}],
+        "responses": {
+          "204": {"description": "Key has been removed"},
+          "404": {"$ref": "#/components/responses/V2Error"}
+        }
+      }
+    },
+    "/v2/keys/{key}/items/{index}": {
+      "get": {
+        "summary": "Get list item by index",
+        "operationId": "v2GetItem",
+        "parameters": [
+          {"$ref": "#/components/parameters/Key"},
+          {"$ref": "#/components/parameters/Index"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Item",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/V2ValueResponse"}}}
+          },
+          "400": {"$ref": "#/components/responses/V2Error"},
+          "404": {"$ref": "#/components/responses/V2Error"},
+          "409": {"$ref": "#/components/responses/V2Error"}
+        }
+      }
+    },
+    "/v2/keys/{key}/fields/{field}": {
+      "get": {
+        "summary": "Get hash map field",
+        "operationId": "v2GetField",
+        "parameters": [
+          {"$ref": "#/components/parameters/Key"},
+          {"$ref": "#/components/parameters/Field"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Field value",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/V2ValueResponse"}}}
+          },
+          "404": {"$ref": "#/components/responses/V2Error"},
+          "409": {"$ref": "#/components/responses/V2Error"}
+        }
+      },
+      "put": {
+        "summary": "Set hash map field",
+        "operationId": "v2PutField",
+        "parameters": [
+          {"$ref": "#/components/parameters/Key"},
+          {"$ref": "#/components/parameters/Field"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/V2ValueRequest"}}}
+        },
+        "responses": {
+          "204": {"description": "Field has been set"},
+          "400": {"$ref": "#/components/responses/V2Error"},
+          "409": {"$ref": "#/components/responses/V2Error"},
+          "415": {"$ref": "#/components/responses/V2Error"}
+        }
+      }
+    }
+  },
+  "components": {
+    "securitySchemes": {
+      "bearerAuth": {"type": "http", "scheme": "bearer"},
+      "hmacAuth": {
+        "type": "apiKey", "in": "header", "name": "X-BS-Signature",
+        "description": "Hex HMAC-SHA256 of METHOD, request URI, X-BS-Timestamp, X-BS-Nonce and hex SHA-256 of the body joined with new lines, signed with the secret of X-BS-Key-Id"
+      }
+    },
+    "parameters": {
+      "Key": {"name": "key", "in": "path", "required": true, "schema": {"type": "string"}},
+      "Index": {"name": "index", "in": "path", "required": true, "schema": {"type": "integer", "minimum": 0}},
+      "Field": {"name": "field", "in": "path", "required": true, "schema": {"type": "string"}},
+      "TTLQuery": {
+        "name": "ttl", "in": "query", "schema": {"type": "integer"},
+        "description": "TTL in seconds, the key never expires if it's omitted or less than 1"
+      },
+      "Members": {
+        "name": "member", "in": "query", "required": true, "style": "form", "explode": true,
+        "schema": {"type": "array", "items": {"type": "string"}}
+      }
+    },
+    "responses": {
+      "V1BadRequest": {
+        "description": "Invalid request",
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/V1Error"}}}
+      },
+      "V1Error": {
+        "description": "Error",
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/V1Error"}}}
+      },
+      "V2Error": {
+        "description": "Error",
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/V2Error"}}}
+      }
+    },
+    "schemas": {
+      "V1Error": {
+        "type": "object",
+        "required": ["error"],
+        "properties": {"error": {"type": "string"}}
+      },
+      "V1ValueResponse": {
+        "type": "object",
+        "required": ["value"],
+        "properties": {
+          "value": {"nullable": true},
+          "content_type": {"type": "string", "description": "Set only for raw values, the value is base64-encoded"}
+        },
+        "additionalProperties": false
+      },
+      "V1SetRequest": {
+        "type": "object",
+        "required": ["key", "value"],
+        "properties": {
+          "key": {"type": "string"},
+          "value": {},
+          "ttl": {"type": "integer"}
+        }
+      },
+      "V1HSetRequest": {
+        "type": "object",
+        "required": ["key", "value"],
+        "properties": {
+          "key": {"type": "string"},
+          "value": {"type": "object", "additionalProperties": true},
+          "ttl": {"type": "integer"}
+        }
+      },
+      "KeysResponse": {
+        "type": "object",
+        "required": ["keys"],
+        "properties": {"keys": {"type": "array", "items": {"type": "string"}}},
+        "additionalProperties": false
+      },
+      "GeoUnit": {"type": "string", "enum": ["m", "km", "mi", "ft"], "default": "m"},
+      "GeoPoint": {
+        "type": "object",
+        "required": ["longitude", "latitude"],
+        "properties": {
+          "longitude": {"type": "number", "minimum": -180, "maximum": 180},
+          "latitude": {"type": "number", "minimum": -85.05112878, "maximum": 85.05112878}
+        },
+        "additionalProperties": false
+      },
+      "GeoMember": {
+        "type": "object",
+        "required": ["member", "longitude", "latitude"],
+        "properties": {
+          "member": {"type": "string"},
+          "longitude": {"type": "number"},
+          "latitude": {"type": "number"}
+        }
+      },
+      "GeoAddRequest": {
+        "type": "object",
+        "required": ["key", "members"],
+        "properties": {
+          "key": {"type": "string"},
+          "members": {"type": "array", "items": {"$ref": "#/components/schemas/GeoMember"}},
+          "ttl": {"type": "integer"}
+        }
+      },
+      "TTLResponse": {
+        "type": "object",
+        "required": ["ttl"],
+        "properties": {"ttl": {"type": "integer", "minimum": -1}},
+        "additionalProperties": false
+      },
+      "GeoAddResponse": {
+        "type": "object",
+        "required": ["added"],
+        "properties": {"added": {"type": "integer"}},
+        "additionalProperties": false
+      },
+      "GeoPosResponse": {
+        "type": "object",
+        "required": ["value"],
+        "properties": {
+          "value": {
+            "type": "array",
+            "items": {"allOf": [{"$ref": "#/components/schemas/GeoPoint"}], "nullable": true}
+          }
+        },
+        "additionalProperties": false
+      },
+      "GeoDistResponse": {
+        "type": "object",
+        "required": ["value"],
+        "properties": {"value": {"type": "number", "nullable": true}},
+        "additionalProperties": false
+      },
+      "GeoSearchRequest": {
+        "type": "object",
+        "required": ["key"],
+        "properties": {
+          "key": {"type": "string"},
+          "from_member": {"type": "string"},
+          "from_lonlat": {"$ref": "#/components/schemas/GeoPoint"},
+          "radius": {"type": "number"},
+          "width": {"type": "number"},
+          "height": {"type": "number"},
+          "unit": {"$ref": "#/components/schemas/GeoUnit"},
+          "sort": {"type": "string", "enum": ["asc", "desc"]},
+          "count": {"type": "integer", "minimum": 0}
+        }
+      },
+      "GeoSearchResult": {
+        "type": "object",
+        "required": ["member", "distance", "longitude", "latitude"],
+        "properties": {
+          "member": {"type": "string"},
+          "distance": {"type": "number"},
+          "longitude": {"type": "number"},
+          "latitude": {"type": "number"}
+        },
+        "additionalProperties": false
+      },
+      "GeoSearchResponse": {
+        "type": "object",
+        "required": ["value"],
+        "properties": {
+          "value": {"type": "array", "items": {"$ref": "#/components/schemas/GeoSearchResult"}}
+        },
+        "additionalProperties": false
+      },
+      "V2Error": {
+        "type": "object",
+        "required": ["error"],
+        "properties": {
+          "error": {
+            "type": "object",
+            "required": ["code", "message"],
+            "properties": {
+              "code": {
+                "type": "string",
+                "enum": [
+                  "invalid_request", "invalid_index", "key_not_found", "item_not_found", "field_not_found",
+                  "wrong_type", "unsupported_media_type", "route_not_found", "method_not_allowed",
+                  "unauthorized", "forbidden", "rate_limited", "request_too_large",
+                  "internal_error"
+                ]
+              },
+              "message": {"type": "string"}
+            },
+            "additionalProperties": false
+          }
+        },
+        "additionalProperties": false
+      },
+      "V2ValueResponse": {
+        "type": "object",
+        "required": ["value"],
+        "properties": {
+          "value": {"nullable": true},
+          "content_type": {"type": "string", "description": "Set only for raw values, the value is base64-encoded"}
+        },
+        "additionalProperties": false
+      },
+      "V2ValueRequest": {
+        "type": "object",
+        "required": ["value"],
+        "properties": {
+          "value": {},
+          "ttl": {"type": "integer", "description": "TTL in seconds, applied only if the key is created by PATCH or field PUT"}
+        },
+        "additionalProperties": false
+      },
+      "V2PatchRequest": {
+        "type": "object",
+        "description": "Exactly one of push or fields must be set",
+        "properties": {
+          "push": {"type": "array", "items": {}, "minItems": 1},
+          "fields": {"type": "object", "additionalProperties": true},
+          "ttl": {"type": "integer"}
+        },
+        "additionalProperties": false
+      }
+    }
+  }
+}
+`
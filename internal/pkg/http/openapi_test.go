@@ -0,0 +1,434 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/openapi"
+	"github.com/dstdfx/bookish-spork/internal/pkg/testutils"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Contract tests check that the public API router matches OpenAPI specification.
+
+// loadSpec returns parsed OpenAPI specification.
+func loadSpec(t *testing.T) map[string]interface{} {
+	var spec map[string]interface{}
+	require.NoError(t, json.Unmarshal(openapi.Spec(), &spec))
+
+	return spec
+}
+
+// specOperations returns a set of "METHOD /path" operations described by the specification.
+func specOperations(spec map[string]interface{}) map[string]bool {
+	ops := make(map[string]bool)
+	for path, item := range spec["paths"].(map[string]interface{}) {
+		for method := range item.(map[string]interface{}) {
+			ops[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	return ops
+}
+
+// routerOperations returns a set of "METHOD /path" operations registered in the router.
+func routerOperations(t *testing.T, router chi.Router) map[string]bool {
+	ops := make(map[string]bool)
+	err := chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		// Mounted sub-routers produce patterns like /v1/*/keys/{key}/
+		for strings.Contains(route, "/*/") {
+			route = strings.ReplaceAll(route, "/*/", "/")
+		}
+		if len(route) > 1 {
+			route = strings.TrimSuffix(route, "/")
+		}
+		ops[method+" "+route] = true
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	return ops
+}
+
+func TestOpenAPI_AllRoutesDocumented(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	b, router := initV2TestRouter(t)
+	defer b.Shutdown()
+
+	specOps := specOperations(loadSpec(t))
+	routerOps := routerOperations(t, router)
+
+	for op := range routerOps {
+		assert.True(t, specOps[op], "route %q is not described in OpenAPI specification", op)
+	}
+	for op := range specOps {
+		assert.True(t, routerOps[op], "operation %q is described in OpenAPI specification but not routed", op)
+	}
+}
+
+func TestOpenAPI_Served(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	b, router := initV2TestRouter(t)
+	defer b.Shutdown()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, openapi.Path, nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, string(openapi.Spec()), w.Body.String())
+}
+
+// contractCase represents a single request that is checked against the specification.
+type contractCase struct {
+	method      string
+	url         string
+	contentType string
+	accept      string
+	body        string
+	status      int
+}
+
+func TestOpenAPI_ResponsesMatchSpec(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	b, router := initV2TestRouter(t)
+	defer b.Shutdown()
+
+	spec := loadSpec(t)
+
+	// Cases are executed in order, some of them depend on the previous ones
+	cases := []contractCase{
+		{method: http.MethodGet, url: "/openapi.json", status: http.StatusOK},
+
+		// v1
+		{method: http.MethodPost, url: "/v1/set", contentType: "application/json",
+			body: `{"key": "str", "value": "test-value", "ttl": 10}`, status: http.StatusOK},
+		{method: http.MethodPost, url: "/v1/set", contentType: "application/json",
+			body: `{"value": "test-value"}`, status: http.StatusBadRequest},
+		{method: http.MethodPost, url: "/v1/set?key=raw&ttl=10", contentType: "application/octet-stream",
+			body: "\x00\x01\x02", status: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/get/str", status: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/get/raw", status: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/get/raw", accept: "application/octet-stream", status: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/get/missing", status: http.StatusNotFound},
+		{method: http.MethodGet, url: "/v1/keys", status: http.StatusOK},
+		{method: http.MethodPut, url: "/v1/keys/text", contentType: "text/plain", body: "hello", status: http.StatusOK},
+		{method: http.MethodPut, url: "/v1/keys/text", contentType: "image/png", body: "png", status: http.StatusUnsupportedMediaType},
+		{method: http.MethodGet, url: "/v1/keys/text", status: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/keys/str", status: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/keys/text", accept: "application/json", status: http.StatusNotAcceptable},
+		{method: http.MethodGet, url: "/v1/keys/missing", status: http.StatusNotFound},
+		{method: http.MethodPost, url: "/v1/rpush", contentType: "application/json",
+			body: `{"key": "list", "value": "a"}`, status: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/lindex/list/0", status: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/lindex/list/10", status: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/lindex/list/x", status: http.StatusBadRequest},
+		{method: http.MethodGet, url: "/v1/lindex/missing/0", status: http.StatusNotFound},
+		{method: http.MethodPost, url: "/v1/hset", contentType: "application/json",
+			body: `{"key": "hash", "value": {"f": "v"}}`, status: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/hget/hash/f", status: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/hget/hash/missing", status: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/hget/missing/f", status: http.StatusNotFound},
+		{method: http.MethodGet, url: "/v1/ttl/hash", status: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/ttl/missing", status: http.StatusNotFound},
+		{method: http.MethodPost, url: "/v1/geoadd", contentType: "application/json",
+			body: `{"key": "geo", "members": [` +
+				`{"member": "Palermo", "longitude": 13.361389, "latitude": 38.115556},` +
+				`{"member": "Catania", "longitude": 15.087269, "latitude": 37.502669}]}`,
+			status: http.StatusOK},
+		{method: http.MethodPost, url: "/v1/geoadd", contentType: "application/json",
+			body:   `{"key": "geo", "members": [{"member": "x", "longitude": 200, "latitude": 0}]}`,
+			status: http.StatusBadRequest},
+		{method: http.MethodGet, url: "/v1/geopos/geo?member=Palermo&member=missing", status: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/geopos/missing?member=Palermo", status: http.StatusNotFound},
+		{method: http.MethodGet, url: "/v1/geodist/geo?member=Palermo&member=Catania&unit=km", status: http.StatusOK},
+		{method: http.MethodGet, url: "/v1/geodist/geo?member=Palermo&member=missing", status: http.StatusOK},
+		{method: http.MethodPost, url: "/v1/geosearch", contentType: "application/json",
+			body:   `{"key": "geo", "from_lonlat": {"longitude": 15, "latitude": 37}, "radius": 200, "unit": "km", "sort": "asc"}`,
+			status: http.StatusOK},
+		{method: http.MethodPost, url: "/v1/geosearch", contentType: "application/json",
+			body: `{"key": "geo", "radius": 200}`, status: http.StatusBadRequest},
+		{method: http.MethodDelete, url: "/v1/remove/str", status: http.StatusNoContent},
+
+		// v2
+		{method: http.MethodPut, url: "/v2/keys/v2str", contentType: "application/json",
+			body: `{"value": "test-value", "ttl": 10}`, status: http.StatusNoContent},
+		{method: http.MethodPut, url: "/v2/keys/v2str", contentType: "application/json",
+			body: `{"ttl": 10}`, status: http.StatusBadRequest},
+		{method: http.MethodPut, url: "/v2/keys/v2str", contentType: "text/plain",
+			body: `value`, status: http.StatusUnsupportedMediaType},
+		{method: http.MethodGet, url: "/v2/keys", status: http.StatusOK},
+		{method: http.MethodGet, url: "/v2/keys/v2str", status: http.StatusOK},
+		{method: http.MethodGet, url: "/v2/keys/raw", status: http.StatusOK},
+		{method: http.MethodGet, url: "/v2/keys/missing", status: http.StatusNotFound},
+		{method: http.MethodPatch, url: "/v2/keys/v2list", contentType: "application/json",
+			body: `{"push": ["a", "b"]}`, status: http.StatusNoContent},
+		{method: http.MethodPatch, url: "/v2/keys/v2list", contentType: "application/json",
+			body: `{"fields": {"f": "v"}}`, status: http.StatusConflict},
+		{method: http.MethodPatch, url: "/v2/keys/v2list", contentType: "application/json",
+			body: `{}`, status: http.StatusBadRequest},
+		{method: http.MethodGet, url: "/v2/keys/v2list/items/1", status: http.StatusOK},
+		{method: http.MethodGet, url: "/v2/keys/v2list/items/5", status: http.StatusNotFound},
+		{method: http.MethodGet, url: "/v2/keys/v2list/items/-1", status: http.StatusBadRequest},
+		{method: http.MethodGet, url: "/v2/keys/v2list/fields/f", status: http.StatusConflict},
+		{method: http.MethodPut, url: "/v2/keys/v2hash/fields/f", contentType: "application/json",
+			body: `{"value": {"nested": true}}`, status: http.StatusNoContent},
+		{method: http.MethodPut, url: "/v2/keys/v2list/fields/f", contentType: "application/json",
+			body: `{"value": 1}`, status: http.StatusConflict},
+		{method: http.MethodGet, url: "/v2/keys/v2hash/fields/f", status: http.StatusOK},
+		{method: http.MethodGet, url: "/v2/keys/v2hash/fields/missing", status: http.StatusNotFound},
+		{method: http.MethodGet, url: "/v2/keys/v2hash/items/0", status: http.StatusConflict},
+		{method: http.MethodDelete, url: "/v2/keys/v2str", status: http.StatusNoContent},
+		{method: http.MethodDelete, url: "/v2/keys/v2str", status: http.StatusNotFound},
+	}
+
+	for _, c := range cases {
+		name := c.method + " " + c.url
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(c.method, c.url, bytes.NewReader([]byte(c.body)))
+		if c.contentType != "" {
+			r.Header.Set("Content-Type", c.contentType)
+		}
+		if c.accept != "" {
+			r.Header.Set("Accept", c.accept)
+		}
+		router.ServeHTTP(w, r)
+
+		if !assert.Equal(t, c.status, w.Code, name) {
+			continue
+		}
+		for _, err := range checkResponse(spec, c.method, r.URL.Path, w) {
+			t.Errorf("%s: %s", name, err)
+		}
+	}
+}
+
+// checkResponse validates recorded response against the specification.
+func checkResponse(spec map[string]interface{}, method, path string, w *httptest.ResponseRecorder) []string {
+	template, ok := matchSpecPath(spec, path)
+	if !ok {
+		return []string{"path is not described"}
+	}
+	item := spec["paths"].(map[string]interface{})[template].(map[string]interface{})
+	op, ok := item[strings.ToLower(method)].(map[string]interface{})
+	if !ok {
+		return []string{"method is not described"}
+	}
+	responses := op["responses"].(map[string]interface{})
+	resp, ok := responses[strconv.Itoa(w.Code)].(map[string]interface{})
+	if !ok {
+		return []string{fmt.Sprintf("status %d is not described", w.Code)}
+	}
+	resp = resolveRef(spec, resp)
+
+	content, hasContent := resp["content"].(map[string]interface{})
+	if !hasContent {
+		if w.Body.Len() != 0 {
+			return []string{fmt.Sprintf("unexpected response body: %q", w.Body.String())}
+		}
+
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if err != nil {
+		return []string{fmt.Sprintf("invalid content type %q", w.Header().Get("Content-Type"))}
+	}
+	media, ok := content[mediaType].(map[string]interface{})
+	if !ok {
+		return []string{fmt.Sprintf("content type %q is not described", mediaType)}
+	}
+	if mediaType != "application/json" {
+		return nil
+	}
+
+	d := json.NewDecoder(w.Body)
+	d.UseNumber()
+	var body interface{}
+	if err := d.Decode(&body); err != nil {
+		return []string{fmt.Sprintf("invalid JSON body: %v", err)}
+	}
+
+	return validateSchema(spec, media["schema"].(map[string]interface{}), body, "body")
+}
+
+// matchSpecPath finds path template of the specification that matches the path.
+func matchSpecPath(spec map[string]interface{}, path string) (string, bool) {
+	parts := strings.Split(path, "/")
+	for template := range spec["paths"].(map[string]interface{}) {
+		templateParts := strings.Split(template, "/")
+		if len(templateParts) != len(parts) {
+			continue
+		}
+		matched := true
+		for i := range parts {
+			if strings.HasPrefix(templateParts[i], "{") {
+				continue
+			}
+			if templateParts[i] != parts[i] {
+				matched = false
+
+				break
+			}
+		}
+		if matched {
+			return template, true
+		}
+	}
+
+	return "", false
+}
+
+// resolveRef returns the object referenced by $ref or the object itself.
+func resolveRef(spec, obj map[string]interface{}) map[string]interface{} {
+	ref, ok := obj["$ref"].(string)
+	if !ok {
+		return obj
+	}
+	var cur interface{} = spec
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		cur = cur.(map[string]interface{})[part]
+	}
+
+	return resolveRef(spec, cur.(map[string]interface{}))
+}
+
+// validateSchema implements the subset of OpenAPI schema validation
+// that is used by the specification.
+func validateSchema(spec, schema map[string]interface{}, v interface{}, path string) []string {
+	schema = resolveRef(spec, schema)
+
+	if v == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable {
+			return nil
+		}
+
+		return []string{path + ": must not be null"}
+	}
+
+	var errs []string
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, s := range allOf {
+			errs = append(errs, validateSchema(spec, s.(map[string]interface{}), v, path)...)
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if fmt.Sprint(e) == fmt.Sprint(v) {
+				found = true
+
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s: %v is not one of %v", path, v, enum))
+		}
+	}
+
+	typ, _ := schema["type"].(string)
+	switch typ {
+	case "":
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return append(errs, path+": must be an object")
+		}
+		errs = append(errs, validateObject(spec, schema, obj, path)...)
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return append(errs, path+": must be an array")
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range arr {
+				errs = append(errs, validateSchema(spec, items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			errs = append(errs, path+": must be a string")
+		}
+	case "number":
+		if _, ok := v.(json.Number); !ok {
+			errs = append(errs, path+": must be a number")
+		}
+	case "integer":
+		if n, ok := v.(json.Number); !ok {
+			errs = append(errs, path+": must be an integer")
+		} else if _, err := n.Int64(); err != nil {
+			errs = append(errs, path+": must be an integer")
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			errs = append(errs, path+": must be a boolean")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("%s: unsupported schema type %q", path, typ))
+	}
+
+	return errs
+}
+
+func validateObject(spec, schema, obj map[string]interface{}, path string) []string {
+	var errs []string
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, r := range required {
+			if _, ok := obj[r.(string)]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: %s is required", path, r))
+			}
+		}
+	}
+
+	// Iterate in the stable order to get reproducible errors
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		propPath := path + "." + name
+		if prop, ok := properties[name].(map[string]interface{}); ok {
+			errs = append(errs, validateSchema(spec, prop, obj[name], propPath)...)
+
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				errs = append(errs, propPath+": unexpected property")
+			}
+		case map[string]interface{}:
+			errs = append(errs, validateSchema(spec, additional, obj[name], propPath)...)
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,578 @@
+// Package ratelimit provides token bucket rate limiting of the public API
+// requests per client.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/accesslog"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/auth"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/httputil"
+	"github.com/go-chi/chi"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrRateLimitExceeded = errors.New("rate limit exceeded")
+	ErrNoLimits          = errors.New("no limits are configured")
+)
+
+// Ways to identify the clients.
+const (
+	// KeyByIdentity uses the name of authenticated client and falls back
+	// to the remote IP for unauthenticated requests.
+	KeyByIdentity = "identity"
+
+	// KeyByIP uses the remote IP of the client.
+	KeyByIP = "ip"
+)
+
+// Names of the limits used in the throttled requests counters.
+const (
+	limitDefault      = "default"
+	limitAuthFailures = "auth_failures"
+	limitClassPrefix  = "class:"
+	limitRoutePrefix  = "route:"
+)
+
+const defaultCleanupInterval = time.Minute
+
+type ctxKey int
+
+const ctxLimiter ctxKey = iota
+
+// Limit represents token bucket parameters.
+type Limit struct {
+	// Rate is the number of requests per second.
+	Rate float64
+
+	// Burst is the max number of requests that could be done at once,
+	// it's equal to the rate (but not less than 1) by default.
+	Burst int
+}
+
+// Opts represents the options to create new instance of Limiter.
+type Opts struct {
+	// KeyBy defines how the clients are identified, KeyByIdentity by default.
+	KeyBy string
+
+	// Default limits all requests of the client.
+	Default Limit
+
+	// Classes contains limits by command classes: read and write.
+	Classes map[string]Limit
+
+	// Routes contains limits by "<METHOD> <route pattern>", e.g. "POST /v1/set".
+	Routes map[string]Limit
+
+	// AuthFailures limits failed authentication attempts per remote IP,
+	// the default limit is used if it's not set.
+	AuthFailures Limit
+
+	Log *zap.Logger
+}
+
+// Limiter limits requests rate of the clients.
+// Nil Limiter allows everything.
+type Limiter struct {
+	log *zap.Logger
+
+	// limits contains current *limits, they are replaced by Update
+	limits atomic.Value
+
+	mux       sync.Mutex
+	buckets   map[bucketKey]*bucket
+	throttled map[string]uint64
+
+	// now is used to get current time, it's replaced in tests
+	now func() time.Time
+
+	stopCleanup chan struct{}
+	stopOnce    sync.Once
+}
+
+// limits contains the parsed options of the limiter.
+type limits struct {
+	keyBy string
+
+	defaultLimit Limit
+	authFailures Limit
+	classes      map[string]Limit
+	routes       map[string]Limit
+}
+
+type bucketKey struct {
+	limit  string
+	client string
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+// New returns new instance of Limiter.
+// It returns nil if no limits are configured.
+func New(opts Opts) (*Limiter, error) {
+	lim, err := newLimits(opts)
+	if err != nil {
+		return nil, err
+	}
+	if lim == nil {
+		return nil, nil
+	}
+
+	l := &Limiter{
+		log:         opts.Log,
+		buckets:     make(map[bucketKey]*bucket),
+		throttled:   make(map[string]uint64),
+		now:         time.Now,
+		stopCleanup: make(chan struct{}),
+	}
+	if l.log == nil {
+		l.log = zap.NewNop()
+	}
+	l.limits.Store(lim)
+
+	go l.cleaner(defaultCleanupInterval)
+
+	return l, nil
+}
+
+// Validate checks the options the same way as New and Update do.
+// It returns ErrNoLimits if no limits are configured.
+func Validate(opts Opts) error {
+	lim, err := newLimits(opts)
+	if err != nil {
+		return err
+	}
+	if lim == nil {
+		return ErrNoLimits
+	}
+
+	return nil
+}
+
+// Update method replaces the limits of the running limiter, the clients
+// start with full buckets. Limiting can't be disabled by Update.
+func (l *Limiter) Update(opts Opts) error {
+	lim, err := newLimits(opts)
+	if err != nil {
+		return err
+	}
+	if lim == nil {
+		return ErrNoLimits
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.limits.Store(lim)
+	l.buckets = make(map[bucketKey]*bucket)
+
+	return nil
+}
+
+// newLimits parses the options, it returns nil if no limits are configured.
+func newLimits(opts Opts) (*limits, error) {
+	lim := &limits{
+		keyBy:   opts.KeyBy,
+		classes: make(map[string]Limit, len(opts.Classes)),
+		routes:  make(map[string]Limit, len(opts.Routes)),
+	}
+
+	switch lim.keyBy {
+	case "":
+		lim.keyBy = KeyByIdentity
+	case KeyByIdentity, KeyByIP:
+	default:
+		return nil, fmt.Errorf("ratelimit: unknown key %q", opts.KeyBy)
+	}
+
+	var err error
+	isEnabled := false
+	if opts.Default != (Limit{}) {
+		if lim.defaultLimit, err = normalizeLimit(opts.Default); err != nil {
+			return nil, fmt.Errorf("ratelimit: default: %w", err)
+		}
+		isEnabled = true
+	}
+	if opts.AuthFailures != (Limit{}) {
+		if lim.authFailures, err = normalizeLimit(opts.AuthFailures); err != nil {
+			return nil, fmt.Errorf("ratelimit: auth failures: %w", err)
+		}
+		isEnabled = true
+	} else {
+		lim.authFailures = lim.defaultLimit
+	}
+	for class, limit := range opts.Classes {
+		if class != auth.PermissionRead && class != auth.PermissionWrite {
+			return nil, fmt.Errorf("ratelimit: unknown command class %q", class)
+		}
+		if lim.classes[class], err = normalizeLimit(limit); err != nil {
+			return nil, fmt.Errorf("ratelimit: class %q: %w", class, err)
+		}
+		isEnabled = true
+	}
+	for route, limit := range opts.Routes {
+		key, err := normalizeRoute(route)
+		if err != nil {
+			return nil, fmt.Errorf("ratelimit: route %q: %w", route, err)
+		}
+		if lim.routes[key], err = normalizeLimit(limit); err != nil {
+			return nil, fmt.Errorf("ratelimit: route %q: %w", route, err)
+		}
+		isEnabled = true
+	}
+	if !isEnabled {
+		return nil, nil
+	}
+
+	return lim, nil
+}
+
+// current returns current limits.
+func (l *Limiter) current() *limits {
+	return l.limits.Load().(*limits)
+}
+
+func normalizeLimit(limit Limit) (Limit, error) {
+	if limit.Rate <= 0 {
+		return Limit{}, fmt.Errorf("rate must be positive")
+	}
+	if limit.Burst < 0 {
+		return Limit{}, fmt.Errorf("burst must not be negative")
+	}
+	if limit.Burst == 0 {
+		limit.Burst = int(math.Max(1, math.Ceil(limit.Rate)))
+	}
+
+	return limit, nil
+}
+
+func normalizeRoute(route string) (string, error) {
+	parts := strings.Fields(route)
+	if len(parts) != 2 || !strings.HasPrefix(parts[1], "/") {
+		return "", fmt.Errorf(`route must be in "<METHOD> <route pattern>" format`)
+	}
+
+	return strings.ToUpper(parts[0]) + " " + strings.TrimSuffix(parts[1], "/"), nil
+}
+
+// Close stops cleanup of the idle buckets.
+func (l *Limiter) Close() {
+	if l == nil {
+		return
+	}
+	l.stopOnce.Do(func() {
+		close(l.stopCleanup)
+	})
+}
+
+// Throttled method returns the number of throttled requests by the limit names:
+// "default", "auth_failures", "class:<class>" and "route:<METHOD> <route pattern>".
+func (l *Limiter) Throttled() map[string]uint64 {
+	result := make(map[string]uint64)
+	if l == nil {
+		return result
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	for name, count := range l.throttled {
+		result[name] = count
+	}
+
+	return result
+}
+
+// ErrorWriter writes rate limit error to the response.
+type ErrorWriter func(w http.ResponseWriter, status int, message string)
+
+// Middleware returns middleware that puts the limiter into the request context,
+// the limits are applied by Allow. It should be placed after authentication
+// to identify the clients.
+func (l *Limiter) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			next.ServeHTTP(w, req.WithContext(WithLimiter(req.Context(), l)))
+		})
+	}
+}
+
+// AuthMiddleware returns middleware that limits failed authentication attempts
+// by the remote IP, it should be placed before authentication. Requests from
+// the IP are rejected without authentication while the limit is exceeded,
+// so the credentials could not be brute forced.
+func (l *Limiter) AuthMiddleware(writeErr ErrorWriter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if retryAfter, ok := l.AuthAllowed(req.Context(), req.RemoteAddr); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				writeErr(w, http.StatusTooManyRequests, ErrRateLimitExceeded.Error())
+
+				return
+			}
+
+			rw := httputil.NewResponseWriter(w)
+			next.ServeHTTP(rw, req)
+			if rw.Status() == http.StatusUnauthorized {
+				l.AuthFailed(req.RemoteAddr)
+			}
+		})
+	}
+}
+
+// AuthAllowed method checks that the limit of failed authentication attempts
+// from the remote address is not exceeded, no token is taken. It returns
+// the number of seconds to wait before retrying if the attempts are throttled.
+func (l *Limiter) AuthAllowed(ctx context.Context, remoteAddr string) (int, bool) {
+	if l == nil {
+		return 0, true
+	}
+	limit := l.current().authFailures
+	if limit == (Limit{}) {
+		return 0, true
+	}
+
+	key := bucketKey{limit: limitAuthFailures, client: remoteIP(remoteAddr)}
+	now := l.now()
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		return 0, true
+	}
+	b.refill(now)
+	if b.tokens >= 1 {
+		return 0, true
+	}
+
+	l.throttled[limitAuthFailures]++
+	accesslog.RequestLogger(ctx, l.log).Debug("request throttled",
+		zap.String("limit", limitAuthFailures),
+		zap.String("client", key.client))
+
+	return retryAfter(b), false
+}
+
+// AuthFailed method takes a token from the bucket of failed authentication
+// attempts from the remote address.
+func (l *Limiter) AuthFailed(remoteAddr string) {
+	if l == nil {
+		return
+	}
+	limit := l.current().authFailures
+	if limit == (Limit{}) {
+		return
+	}
+
+	key := bucketKey{limit: limitAuthFailures, client: remoteIP(remoteAddr)}
+	now := l.now()
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	b := l.bucket(key, limit, now)
+	b.tokens = math.Max(0, b.tokens-1)
+}
+
+// WithLimiter returns a copy of the context with the limiter.
+func WithLimiter(ctx context.Context, l *Limiter) context.Context {
+	return context.WithValue(ctx, ctxLimiter, l)
+}
+
+// GetLimiter returns the limiter from the context.
+func GetLimiter(ctx context.Context) *Limiter {
+	l, _ := ctx.Value(ctxLimiter).(*Limiter)
+
+	return l
+}
+
+// Allow checks the default, route and command class limits of the request.
+// It must be called after routing to get the route pattern.
+// Tokens are taken only if none of the limits is exceeded.
+// Retry-After header is set if the request is throttled.
+func Allow(w http.ResponseWriter, req *http.Request, class string) bool {
+	l := GetLimiter(req.Context())
+	if l == nil {
+		return true
+	}
+
+	lim := l.current()
+	var limits []namedLimit
+	if lim.defaultLimit != (Limit{}) {
+		limits = append(limits, namedLimit{name: limitDefault, limit: lim.defaultLimit})
+	}
+	if rctx := chi.RouteContext(req.Context()); rctx != nil {
+		route := req.Method + " " + strings.TrimSuffix(rctx.RoutePattern(), "/")
+		if limit, ok := lim.routes[route]; ok {
+			limits = append(limits, namedLimit{name: limitRoutePrefix + route, limit: limit})
+		}
+	}
+	if limit, ok := lim.classes[class]; ok {
+		limits = append(limits, namedLimit{name: limitClassPrefix + class, limit: limit})
+	}
+
+	return l.allow(w, req, limits...)
+}
+
+// namedLimit is the limit with its name used in the bucket keys.
+type namedLimit struct {
+	name  string
+	limit Limit
+}
+
+// allow method takes tokens from the buckets of the client that sent the request.
+func (l *Limiter) allow(w http.ResponseWriter, req *http.Request, limits ...namedLimit) bool {
+	retryAfter, ok := l.take(req.Context(), l.clientKey(req.Context(), req.RemoteAddr), limits)
+	if !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	}
+
+	return ok
+}
+
+// AllowCall method checks the default and command class limits of the gRPC call,
+// route limits are applied to HTTP requests only. The client is identified
+// the same way as for HTTP requests. It returns the number of seconds to wait
+// before retrying if the call is throttled.
+func (l *Limiter) AllowCall(ctx context.Context, remoteAddr, class string) (int, bool) {
+	if l == nil {
+		return 0, true
+	}
+
+	lim := l.current()
+	var limits []namedLimit
+	if lim.defaultLimit != (Limit{}) {
+		limits = append(limits, namedLimit{name: limitDefault, limit: lim.defaultLimit})
+	}
+	if limit, ok := lim.classes[class]; ok {
+		limits = append(limits, namedLimit{name: limitClassPrefix + class, limit: limit})
+	}
+
+	return l.take(ctx, l.clientKey(ctx, remoteAddr), limits)
+}
+
+// take method takes a token from every bucket of the client if all of them
+// have one. Otherwise, nothing is taken and it returns the number of seconds
+// to wait for the token of the first exceeded limit.
+func (l *Limiter) take(ctx context.Context, client string, limits []namedLimit) (int, bool) {
+	if len(limits) == 0 {
+		return 0, true
+	}
+	now := l.now()
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	buckets := make([]*bucket, len(limits))
+	for i, limit := range limits {
+		b := l.bucket(bucketKey{limit: limit.name, client: client}, limit.limit, now)
+		if b.tokens < 1 {
+			l.throttled[limit.name]++
+			accesslog.RequestLogger(ctx, l.log).Debug("request throttled",
+				zap.String("limit", limit.name),
+				zap.String("client", client))
+
+			return retryAfter(b), false
+		}
+		buckets[i] = b
+	}
+	for _, b := range buckets {
+		b.tokens--
+	}
+
+	return 0, true
+}
+
+// bucket method returns the refilled bucket, new bucket is full.
+// It must be called under the lock.
+func (l *Limiter) bucket(key bucketKey, limit Limit, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now, limit: limit}
+		l.buckets[key] = b
+	}
+	b.refill(now)
+
+	return b
+}
+
+// retryAfter returns the number of seconds to wait for the next token of the bucket.
+func retryAfter(b *bucket) int {
+	return int(math.Max(1, math.Ceil((1-b.tokens)/b.limit.Rate)))
+}
+
+// clientKey method returns the key of the client buckets, it's the identity
+// from the context or the IP of the remote address.
+func (l *Limiter) clientKey(ctx context.Context, remoteAddr string) string {
+	if l.current().keyBy == KeyByIdentity {
+		if identity, ok := auth.GetIdentity(ctx); ok && identity.Name != "" {
+			return identity.Method + ":" + identity.Name
+		}
+	}
+
+	return remoteIP(remoteAddr)
+}
+
+// remoteIP returns the IP of the remote address.
+func remoteIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+
+	return host
+}
+
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate)
+		b.last = now
+	}
+}
+
+// cleaner removes the buckets that have been refilled, they are equal to new ones.
+func (l *Limiter) cleaner(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			l.cleanup()
+		case <-l.stopCleanup:
+			return
+		}
+	}
+}
+
+func (l *Limiter) cleanup() {
+	now := l.now()
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
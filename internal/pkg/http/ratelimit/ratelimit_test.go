@@ -0,0 +1,323 @@
+package ratelimit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/auth"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRouter returns router with the limiter, the route and class limits
+// are checked by the handlers like command middlewares of the API do.
+func newTestRouter(l *Limiter) http.Handler {
+	writeErr := func(w http.ResponseWriter, status int, message string) {
+		http.Error(w, message, status)
+	}
+	handler := func(class string) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			if !Allow(w, req, class) {
+				writeErr(w, http.StatusTooManyRequests, ErrRateLimitExceeded.Error())
+
+				return
+			}
+		}
+	}
+
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if name := req.Header.Get("X-Test-Identity"); name != "" {
+				req = req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{Name: name, Method: auth.MethodToken}))
+			}
+			next.ServeHTTP(w, req)
+		})
+	})
+	r.Use(l.Middleware())
+	r.Get("/get/{key}", handler(auth.PermissionRead))
+	r.Post("/set", handler(auth.PermissionWrite))
+	r.Post("/rpush", handler(auth.PermissionWrite))
+
+	return r
+}
+
+func doTestRequest(router http.Handler, method, url, remoteAddr, identity string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, url, nil)
+	r.RemoteAddr = remoteAddr
+	if identity != "" {
+		r.Header.Set("X-Test-Identity", identity)
+	}
+	router.ServeHTTP(w, r)
+
+	return w
+}
+
+func newTestLimiter(t *testing.T, opts Opts) (*Limiter, *time.Time) {
+	l, err := New(opts)
+	require.NoError(t, err)
+	t.Cleanup(l.Close)
+
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	return l, &now
+}
+
+func TestLimiter_Default(t *testing.T) {
+	l, now := newTestLimiter(t, Opts{Default: Limit{Rate: 0.5, Burst: 2}})
+	router := newTestRouter(l)
+
+	for i := 0; i < 2; i++ {
+		w := doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.1:1000", "")
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	w := doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.1:1001", "")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "2", w.Header().Get("Retry-After"))
+
+	// Other clients are not affected
+	w = doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.2:1000", "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Tokens are refilled with the rate
+	*now = now.Add(time.Second)
+	w = doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.1:1000", "")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	*now = now.Add(time.Second)
+	w = doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.1:1000", "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.Equal(t, map[string]uint64{"default": 2}, l.Throttled())
+}
+
+func TestLimiter_RoutesAndClasses(t *testing.T) {
+	l, _ := newTestLimiter(t, Opts{
+		Classes: map[string]Limit{auth.PermissionWrite: {Rate: 3}},
+		Routes:  map[string]Limit{"post /set/": {Rate: 1}},
+	})
+	router := newTestRouter(l)
+
+	require.Equal(t, http.StatusOK, doTestRequest(router, http.MethodPost, "/set", "10.0.0.1:1000", "").Code)
+	require.Equal(t, http.StatusTooManyRequests, doTestRequest(router, http.MethodPost, "/set", "10.0.0.1:1000", "").Code)
+
+	// Write class allows 3 requests, one of them has been used by /set
+	require.Equal(t, http.StatusOK, doTestRequest(router, http.MethodPost, "/rpush", "10.0.0.1:1000", "").Code)
+	require.Equal(t, http.StatusOK, doTestRequest(router, http.MethodPost, "/rpush", "10.0.0.1:1000", "").Code)
+	require.Equal(t, http.StatusTooManyRequests, doTestRequest(router, http.MethodPost, "/rpush", "10.0.0.1:1000", "").Code)
+
+	// Read class is not limited
+	for i := 0; i < 10; i++ {
+		require.Equal(t, http.StatusOK, doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.1:1000", "").Code)
+	}
+
+	require.Equal(t, map[string]uint64{"route:POST /set": 1, "class:write": 1}, l.Throttled())
+}
+
+func TestLimiter_AllOrNothing(t *testing.T) {
+	l, now := newTestLimiter(t, Opts{
+		Classes: map[string]Limit{auth.PermissionWrite: {Rate: 1, Burst: 1}},
+		Routes:  map[string]Limit{"POST /set": {Rate: 0.001, Burst: 1}},
+	})
+	router := newTestRouter(l)
+
+	require.Equal(t, http.StatusOK, doTestRequest(router, http.MethodPost, "/rpush", "10.0.0.1:1000", "").Code)
+
+	// Request rejected by the class limit does not drain the route limit
+	require.Equal(t, http.StatusTooManyRequests, doTestRequest(router, http.MethodPost, "/set", "10.0.0.1:1000", "").Code)
+	*now = now.Add(time.Second)
+	require.Equal(t, http.StatusOK, doTestRequest(router, http.MethodPost, "/set", "10.0.0.1:1000", "").Code)
+
+	require.Equal(t, map[string]uint64{"class:write": 1}, l.Throttled())
+}
+
+func TestLimiter_DefaultAllOrNothing(t *testing.T) {
+	l, _ := newTestLimiter(t, Opts{
+		Default: Limit{Rate: 0.001, Burst: 3},
+		Routes:  map[string]Limit{"POST /set": {Rate: 0.001, Burst: 1}},
+	})
+	router := newTestRouter(l)
+
+	require.Equal(t, http.StatusOK, doTestRequest(router, http.MethodPost, "/set", "10.0.0.1:1000", "").Code)
+
+	// Requests rejected by the route limit do not drain the default limit
+	for i := 0; i < 5; i++ {
+		require.Equal(t, http.StatusTooManyRequests, doTestRequest(router, http.MethodPost, "/set", "10.0.0.1:1000", "").Code)
+	}
+	require.Equal(t, http.StatusOK, doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.1:1000", "").Code)
+	require.Equal(t, http.StatusOK, doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.1:1000", "").Code)
+	require.Equal(t, http.StatusTooManyRequests, doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.1:1000", "").Code)
+
+	require.Equal(t, map[string]uint64{"route:POST /set": 5, "default": 1}, l.Throttled())
+}
+
+func TestLimiter_AuthFailures(t *testing.T) {
+	l, now := newTestLimiter(t, Opts{
+		Default:      Limit{Rate: 100},
+		AuthFailures: Limit{Rate: 1, Burst: 2},
+	})
+	writeErr := func(w http.ResponseWriter, status int, message string) {
+		http.Error(w, message, status)
+	}
+
+	// Only the requests with the identity are authenticated
+	authenticated := 0
+	router := l.AuthMiddleware(writeErr)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Test-Identity") == "" {
+			writeErr(w, http.StatusUnauthorized, auth.ErrAuthRequired.Error())
+
+			return
+		}
+		authenticated++
+	}))
+
+	// Successful authentication is not limited
+	for i := 0; i < 5; i++ {
+		require.Equal(t, http.StatusOK, doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.1:1000", "app").Code)
+	}
+
+	for i := 0; i < 2; i++ {
+		require.Equal(t, http.StatusUnauthorized, doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.1:1000", "").Code)
+	}
+
+	// Requests from the IP are not authenticated while the limit is exceeded
+	w := doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.1:1001", "app")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "1", w.Header().Get("Retry-After"))
+	require.Equal(t, 5, authenticated)
+	require.Equal(t, http.StatusOK, doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.2:1000", "app").Code)
+
+	*now = now.Add(time.Second)
+	require.Equal(t, http.StatusOK, doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.1:1000", "app").Code)
+	require.Equal(t, map[string]uint64{"auth_failures": 1}, l.Throttled())
+
+	// The default limit is used if the limit of failures is not set
+	l, _ = newTestLimiter(t, Opts{Default: Limit{Rate: 1, Burst: 1}})
+	router = l.AuthMiddleware(writeErr)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeErr(w, http.StatusUnauthorized, auth.ErrAuthRequired.Error())
+	}))
+	require.Equal(t, http.StatusUnauthorized, doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.1:1000", "").Code)
+	require.Equal(t, http.StatusTooManyRequests, doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.1:1000", "").Code)
+}
+
+func TestLimiter_KeyBy(t *testing.T) {
+	l, _ := newTestLimiter(t, Opts{Default: Limit{Rate: 1}})
+	router := newTestRouter(l)
+
+	// Authenticated client is limited regardless of the address
+	require.Equal(t, http.StatusOK, doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.1:1000", "app").Code)
+	require.Equal(t, http.StatusTooManyRequests, doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.2:1000", "app").Code)
+	require.Equal(t, http.StatusOK, doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.1:1000", "").Code)
+
+	l, _ = newTestLimiter(t, Opts{KeyBy: KeyByIP, Default: Limit{Rate: 1}})
+	router = newTestRouter(l)
+
+	require.Equal(t, http.StatusOK, doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.1:1000", "app1").Code)
+	require.Equal(t, http.StatusTooManyRequests, doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.1:1000", "app2").Code)
+}
+
+func TestLimiter_Cleanup(t *testing.T) {
+	l, now := newTestLimiter(t, Opts{Default: Limit{Rate: 1, Burst: 10}})
+	router := newTestRouter(l)
+
+	doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.1:1000", "")
+	doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.2:1000", "")
+	require.Len(t, l.buckets, 2)
+
+	// Not refilled buckets are kept
+	l.cleanup()
+	require.Len(t, l.buckets, 2)
+
+	*now = now.Add(time.Second)
+	doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.2:1000", "")
+	l.cleanup()
+	require.Len(t, l.buckets, 1)
+}
+
+func TestLimiter_Update(t *testing.T) {
+	l, _ := newTestLimiter(t, Opts{Default: Limit{Rate: 1, Burst: 1}})
+	router := newTestRouter(l)
+
+	require.Equal(t, http.StatusOK, doTestRequest(router, http.MethodPost, "/set", "10.0.0.1:1000", "").Code)
+	require.Equal(t, http.StatusTooManyRequests, doTestRequest(router, http.MethodPost, "/set", "10.0.0.1:1000", "").Code)
+
+	// Buckets are reset on update
+	require.NoError(t, l.Update(Opts{
+		Default: Limit{Rate: 10},
+		Classes: map[string]Limit{auth.PermissionWrite: {Rate: 1, Burst: 2}},
+	}))
+	for i := 0; i < 2; i++ {
+		require.Equal(t, http.StatusOK, doTestRequest(router, http.MethodPost, "/set", "10.0.0.1:1000", "").Code)
+	}
+	require.Equal(t, http.StatusTooManyRequests, doTestRequest(router, http.MethodPost, "/set", "10.0.0.1:1000", "").Code)
+	require.Equal(t, http.StatusOK, doTestRequest(router, http.MethodGet, "/get/a", "10.0.0.1:1000", "").Code)
+
+	// Invalid options are not applied
+	require.EqualError(t, l.Update(Opts{KeyBy: "user", Default: Limit{Rate: 1}}), `ratelimit: unknown key "user"`)
+	require.Equal(t, ErrNoLimits, l.Update(Opts{}))
+	require.Equal(t, http.StatusTooManyRequests, doTestRequest(router, http.MethodPost, "/set", "10.0.0.1:1000", "").Code)
+}
+
+func TestNew(t *testing.T) {
+	l, err := New(Opts{})
+	require.NoError(t, err)
+	require.Nil(t, l)
+
+	require.True(t, errors.Is(Validate(Opts{}), ErrNoLimits))
+
+	// Nil limiter is safe to use
+	l.Close()
+	require.Empty(t, l.Throttled())
+
+	testCases := []struct {
+		name string
+		opts Opts
+		err  string
+	}{
+		{
+			name: "unknown key",
+			opts: Opts{KeyBy: "token", Default: Limit{Rate: 1}},
+			err:  `ratelimit: unknown key "token"`,
+		},
+		{
+			name: "no rate",
+			opts: Opts{Default: Limit{Burst: 1}},
+			err:  "ratelimit: default: rate must be positive",
+		},
+		{
+			name: "invalid auth failures",
+			opts: Opts{AuthFailures: Limit{Rate: -1}},
+			err:  "ratelimit: auth failures: rate must be positive",
+		},
+		{
+			name: "negative burst",
+			opts: Opts{Classes: map[string]Limit{auth.PermissionRead: {Rate: 1, Burst: -1}}},
+			err:  `ratelimit: class "read": burst must not be negative`,
+		},
+		{
+			name: "unknown class",
+			opts: Opts{Classes: map[string]Limit{auth.PermissionAdmin: {Rate: 1}}},
+			err:  `ratelimit: unknown command class "admin"`,
+		},
+		{
+			name: "invalid route",
+			opts: Opts{Routes: map[string]Limit{"/v1/set": {Rate: 1}}},
+			err:  `ratelimit: route "/v1/set": route must be in "<METHOD> <route pattern>" format`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(tc.opts)
+			require.EqualError(t, err, tc.err)
+			require.EqualError(t, Validate(tc.opts), tc.err)
+		})
+	}
+}
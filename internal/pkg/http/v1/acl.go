@@ -0,0 +1,100 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/backend"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/accesslog"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/auth"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/ratelimit"
+)
+
+const errPermissionDenied = "permission denied"
+
+// RequirePermission middleware checks that the client is allowed to run the command
+// on the key of the request and the rate limits of the route and command class
+// are not exceeded.
+// It must be placed after the middlewares that extract the key.
+func RequirePermission(command string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := requestKey(r.Context())
+			accesslog.SetKey(r.Context(), key)
+			if !auth.IsAllowed(r.Context(), command, key) {
+				WriteError(w, http.StatusForbidden, errPermissionDenied)
+
+				return
+			}
+			if !ratelimit.Allow(w, r, auth.CommandPermission(command)) {
+				WriteError(w, http.StatusTooManyRequests, ratelimit.ErrRateLimitExceeded.Error())
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireCommand middleware checks that the client is allowed to run the command
+// on any key and the rate limits are not exceeded, it's used for commands
+// that are not addressed to a single key.
+func RequireCommand(command string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !auth.IsCommandAllowed(r.Context(), command) {
+				WriteError(w, http.StatusForbidden, errPermissionDenied)
+
+				return
+			}
+			if !ratelimit.Allow(w, r, auth.CommandPermission(command)) {
+				WriteError(w, http.StatusTooManyRequests, ratelimit.ErrRateLimitExceeded.Error())
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireWritable middleware rejects the writes if the backend is read-only.
+func RequireWritable(b *backend.Backend) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if b.ReadOnly {
+				WriteError(w, http.StatusForbidden, backend.ErrReadOnly.Error())
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requestKey returns the key the request is addressed to, either from URL
+// or from the request body.
+func requestKey(ctx context.Context) string {
+	if key := GetKeyName(ctx); key != "" {
+		return key
+	}
+	if body := GetSetBody(ctx); body != nil {
+		return body.Key
+	}
+	if body := GetRPushBody(ctx); body != nil {
+		return body.Key
+	}
+	if body := GetHSetBody(ctx); body != nil {
+		return body.Key
+	}
+	if body := GetGeoAddBody(ctx); body != nil {
+		return body.Key
+	}
+	if body := GetGeoSearchBody(ctx); body != nil {
+		return body.Key
+	}
+
+	return ""
+}
@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"mime"
+	"strings"
+)
+
+// Media types supported by the API.
+const (
+	mediaTypeJSON        = "application/json"
+	mediaTypeOctetStream = "application/octet-stream"
+	mediaTypeText        = "text/plain"
+	mediaTypeMsgpack     = "application/msgpack"
+)
+
+// rawMediaTypes contains media types of the values that are stored
+// as raw bytes and returned verbatim.
+var rawMediaTypes = map[string]struct{}{
+	mediaTypeOctetStream: {},
+	mediaTypeText:        {},
+	mediaTypeMsgpack:     {},
+}
+
+// parseMediaType returns lowercased media type of the Content-Type header value
+// without parameters.
+// Empty string is returned if the value can't be parsed.
+func parseMediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+
+	return mediaType
+}
+
+// isRawMediaType reports whether the Content-Type header value describes
+// a value that must be stored as raw bytes.
+func isRawMediaType(contentType string) bool {
+	_, ok := rawMediaTypes[parseMediaType(contentType)]
+
+	return ok
+}
+
+// acceptsMediaType reports whether the Accept header value allows the media type.
+// If exact is true, wildcard '*/*' is not considered as a match.
+// Empty Accept header allows any media type.
+func acceptsMediaType(accept, mediaType string, exact bool) bool {
+	if accept == "" {
+		return !exact
+	}
+
+	mediaType = parseMediaType(mediaType)
+	for _, part := range strings.Split(accept, ",") {
+		accepted, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+
+		switch {
+		case accepted == mediaType:
+			return true
+		case accepted == "*/*":
+			if !exact {
+				return true
+			}
+		case strings.HasSuffix(accepted, "/*"):
+			if strings.HasPrefix(mediaType, strings.TrimSuffix(accepted, "*")) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,26 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRawMediaType(t *testing.T) {
+	assert.True(t, isRawMediaType("application/octet-stream"))
+	assert.True(t, isRawMediaType("text/plain; charset=utf-8"))
+	assert.True(t, isRawMediaType("application/msgpack"))
+	assert.False(t, isRawMediaType("application/json"))
+	assert.False(t, isRawMediaType(""))
+}
+
+func TestAcceptsMediaType(t *testing.T) {
+	assert.True(t, acceptsMediaType("", "text/plain", false))
+	assert.False(t, acceptsMediaType("", "text/plain", true))
+	assert.True(t, acceptsMediaType("*/*", "text/plain", false))
+	assert.False(t, acceptsMediaType("*/*", "text/plain", true))
+	assert.True(t, acceptsMediaType("text/*", "text/plain; charset=utf-8", true))
+	assert.True(t, acceptsMediaType("application/json, application/msgpack;q=0.9", "application/msgpack", true))
+	assert.False(t, acceptsMediaType("application/msgpack;q=0", "application/msgpack", false))
+	assert.False(t, acceptsMediaType("application/json", "image/png", false))
+}
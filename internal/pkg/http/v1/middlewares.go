@@ -3,6 +3,7 @@ package v1
 import (
 	"context"
 	"encoding/json"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 
@@ -10,9 +11,12 @@ import (
 )
 
 const (
-	keyParam   = "key"
-	indexParam = "index"
-	hkeyParam  = "hkey"
+	keyParam    = "key"
+	indexParam  = "index"
+	hkeyParam   = "hkey"
+	memberQuery = "member"
+	unitQuery   = "unit"
+	ttlQuery    = "ttl"
 )
 
 type ctxKey int
@@ -24,6 +28,9 @@ const (
 	ctxRPushBody
 	ctxIndex
 	ctxHKeyName
+	ctxGeoAddBody
+	ctxGeoSearchBody
+	ctxGeoMembers
 )
 
 // RequireKeyName middleware checks that 'key' parameter is set.
@@ -31,8 +38,7 @@ func RequireKeyName(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		key := chi.URLParam(r, keyParam)
 		if key == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			JSON(w, map[string]string{"error": "key is required"})
+			WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "key is required"})
 
 			return
 		}
@@ -57,8 +63,7 @@ func RequireHKeyName(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		key := chi.URLParam(r, hkeyParam)
 		if key == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			JSON(w, map[string]string{"error": "hkey is required"})
+			WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "hkey is required"})
 
 			return
 		}
@@ -83,8 +88,7 @@ func RequireIndex(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		index := chi.URLParam(r, indexParam)
 		if index == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			JSON(w, map[string]string{"error": "index is required"})
+			WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "index is required"})
 
 			return
 		}
@@ -92,8 +96,7 @@ func RequireIndex(next http.Handler) http.Handler {
 		// Validate index
 		v, err := strconv.Atoi(index)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			JSON(w, map[string]string{"error": "index is invalid"})
+			WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "index is invalid"})
 
 			return
 		}
@@ -101,8 +104,7 @@ func RequireIndex(next http.Handler) http.Handler {
 		// FIXME: current implementation of lindex does not allow negative indexes
 		//       fix when available
 		if v < 0 {
-			w.WriteHeader(http.StatusBadRequest)
-			JSON(w, map[string]string{"error": "index can't be negative"})
+			WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "index can't be negative"})
 
 			return
 		}
@@ -127,6 +129,9 @@ type SetRequestBody struct {
 	Key   string      `json:"key"`
 	Value interface{} `json:"value"`
 	TTL   int         `json:"ttl"`
+
+	// ContentType is set only for raw bytes values.
+	ContentType string `json:"-"`
 }
 
 func (b *SetRequestBody) IsValid() bool {
@@ -134,23 +139,32 @@ func (b *SetRequestBody) IsValid() bool {
 }
 
 // RequireSetParams validates request body for 'set' operation.
+// JSON body contains key, value and TTL. Values of raw media types are passed
+// as request body as is, while key and TTL are passed as query parameters.
 func RequireSetParams(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
 		setBody := SetRequestBody{}
-		err := json.NewDecoder(r.Body).Decode(&setBody)
+		var err error
+		if contentType := r.Header.Get("Content-Type"); isRawMediaType(contentType) {
+			setBody.Key = r.URL.Query().Get(keyParam)
+			setBody.ContentType = contentType
+			setBody.Value, setBody.TTL, err = readRawValue(r)
+		} else {
+			dec := json.NewDecoder(r.Body)
+			dec.UseNumber()
+			err = dec.Decode(&setBody)
+		}
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			JSON(w, map[string]string{"error": "set body is invalid"})
+			WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "set body is invalid"})
 
 			return
 		}
 
 		// Validate set body
 		if !setBody.IsValid() {
-			w.WriteHeader(http.StatusBadRequest)
-			JSON(w, map[string]string{"error": "set body is invalid"})
+			WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "set body is invalid"})
 
 			return
 		}
@@ -161,6 +175,71 @@ func RequireSetParams(next http.Handler) http.Handler {
 	})
 }
 
+// RequireValueParams validates request body for 'set' operation
+// with the key passed as URL parameter.
+// The whole request body is the value, TTL is passed as query parameter.
+// JSON values are decoded, values of raw media types are kept as is.
+// RequireKeyName middleware must be used before.
+func RequireValueParams(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		setBody := SetRequestBody{Key: GetKeyName(ctx)}
+		var err error
+		contentType := r.Header.Get("Content-Type")
+		switch {
+		case isRawMediaType(contentType):
+			setBody.ContentType = contentType
+			setBody.Value, setBody.TTL, err = readRawValue(r)
+		case parseMediaType(contentType) == mediaTypeJSON:
+			setBody.TTL, err = getTTL(r)
+			if err == nil {
+				dec := json.NewDecoder(r.Body)
+				dec.UseNumber()
+				err = dec.Decode(&setBody.Value)
+			}
+		default:
+			WriteJSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "content type is not supported"})
+
+			return
+		}
+		if err != nil || !setBody.IsValid() {
+			WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "value is invalid"})
+
+			return
+		}
+
+		ctx = context.WithValue(ctx, ctxSetBody, setBody)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// readRawValue reads raw value from the request body and TTL from query parameters.
+func readRawValue(r *http.Request) ([]byte, int, error) {
+	ttl, err := getTTL(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	value, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return value, ttl, nil
+}
+
+// getTTL returns TTL passed as query parameter, 0 is returned if it's omitted.
+func getTTL(r *http.Request) (int, error) {
+	ttl := r.URL.Query().Get(ttlQuery)
+	if ttl == "" {
+		return 0, nil
+	}
+
+	return strconv.Atoi(ttl)
+}
+
 // GetSetBody retrieves set body from context.
 func GetSetBody(ctx context.Context) *SetRequestBody {
 	v, ok := ctx.Value(ctxSetBody).(SetRequestBody)
@@ -188,18 +267,18 @@ func RequireRPushParams(next http.Handler) http.Handler {
 		ctx := r.Context()
 
 		rpush := RPushRequestBody{}
-		err := json.NewDecoder(r.Body).Decode(&rpush)
+		dec := json.NewDecoder(r.Body)
+		dec.UseNumber()
+		err := dec.Decode(&rpush)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			JSON(w, map[string]string{"error": "rpush body is invalid"})
+			WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "rpush body is invalid"})
 
 			return
 		}
 
 		// Validate set body
 		if !rpush.IsValid() {
-			w.WriteHeader(http.StatusBadRequest)
-			JSON(w, map[string]string{"error": "rpush body is invalid"})
+			WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "rpush body is invalid"})
 
 			return
 		}
@@ -237,18 +316,18 @@ func RequireHSetParams(next http.Handler) http.Handler {
 		ctx := r.Context()
 
 		hsetBody := HSetRequestBody{}
-		err := json.NewDecoder(r.Body).Decode(&hsetBody)
+		dec := json.NewDecoder(r.Body)
+		dec.UseNumber()
+		err := dec.Decode(&hsetBody)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			JSON(w, map[string]string{"error": "hset body is invalid"})
+			WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "hset body is invalid"})
 
 			return
 		}
 
 		// Validate set body
 		if !hsetBody.IsValid() {
-			w.WriteHeader(http.StatusBadRequest)
-			JSON(w, map[string]string{"error": "hset body is invalid"})
+			WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "hset body is invalid"})
 
 			return
 		}
@@ -269,6 +348,167 @@ func GetHSetBody(ctx context.Context) *HSetRequestBody {
 	return &v
 }
 
+// RequireGeoMembers middleware checks that at least one 'member' query parameter is set.
+func RequireGeoMembers(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		members := r.URL.Query()[memberQuery]
+		if len(members) == 0 {
+			WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "member is required"})
+
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxGeoMembers, members)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetGeoMembers retrieves geo members names from context.
+func GetGeoMembers(ctx context.Context) []string {
+	v, ok := ctx.Value(ctxGeoMembers).([]string)
+	if !ok {
+		return nil
+	}
+
+	return v
+}
+
+// GeoPoint represents geographic coordinates in request and response bodies.
+type GeoPoint struct {
+	Longitude float64 `json:"longitude"`
+	Latitude  float64 `json:"latitude"`
+}
+
+// GeoMember represents a named point of geoadd request body.
+type GeoMember struct {
+	Member    string  `json:"member"`
+	Longitude float64 `json:"longitude"`
+	Latitude  float64 `json:"latitude"`
+}
+
+// GeoAddRequestBody represents geoadd request body.
+type GeoAddRequestBody struct {
+	Key     string      `json:"key"`
+	Members []GeoMember `json:"members"`
+	TTL     int         `json:"ttl"`
+}
+
+func (b *GeoAddRequestBody) IsValid() bool {
+	if b.Key == "" || len(b.Members) == 0 {
+		return false
+	}
+	for _, m := range b.Members {
+		if m.Member == "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RequireGeoAddParams validates request body for 'geoadd' operation.
+func RequireGeoAddParams(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		geoAddBody := GeoAddRequestBody{}
+		err := json.NewDecoder(r.Body).Decode(&geoAddBody)
+		if err != nil {
+			WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "geoadd body is invalid"})
+
+			return
+		}
+
+		// Validate geoadd body
+		if !geoAddBody.IsValid() {
+			WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "geoadd body is invalid"})
+
+			return
+		}
+
+		ctx = context.WithValue(ctx, ctxGeoAddBody, geoAddBody)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetGeoAddBody retrieves geoadd body from context.
+func GetGeoAddBody(ctx context.Context) *GeoAddRequestBody {
+	v, ok := ctx.Value(ctxGeoAddBody).(GeoAddRequestBody)
+	if !ok {
+		return nil
+	}
+
+	return &v
+}
+
+// GeoSearchRequestBody represents geosearch request body.
+// Search center is set either by FromMember or by FromLonLat,
+// search area is set either by Radius or by Width and Height.
+type GeoSearchRequestBody struct {
+	Key        string    `json:"key"`
+	FromMember string    `json:"from_member,omitempty"`
+	FromLonLat *GeoPoint `json:"from_lonlat,omitempty"`
+	Radius     float64   `json:"radius,omitempty"`
+	Width      float64   `json:"width,omitempty"`
+	Height     float64   `json:"height,omitempty"`
+	Unit       string    `json:"unit,omitempty"`
+	Sort       string    `json:"sort,omitempty"`
+	Count      int       `json:"count,omitempty"`
+}
+
+func (b *GeoSearchRequestBody) IsValid() bool {
+	return b.Key != "" && b.Count >= 0
+}
+
+// RequireGeoSearchParams validates request body for 'geosearch' operation.
+func RequireGeoSearchParams(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		geoSearchBody := GeoSearchRequestBody{}
+		err := json.NewDecoder(r.Body).Decode(&geoSearchBody)
+		if err != nil {
+			WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "geosearch body is invalid"})
+
+			return
+		}
+
+		// Validate geosearch body
+		if !geoSearchBody.IsValid() {
+			WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "geosearch body is invalid"})
+
+			return
+		}
+
+		ctx = context.WithValue(ctx, ctxGeoSearchBody, geoSearchBody)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetGeoSearchBody retrieves geosearch body from context.
+func GetGeoSearchBody(ctx context.Context) *GeoSearchRequestBody {
+	v, ok := ctx.Value(ctxGeoSearchBody).(GeoSearchRequestBody)
+	if !ok {
+		return nil
+	}
+
+	return &v
+}
+
+// WriteJSON writes status code and marshals 'v' to JSON setting the Content-Type as application/json.
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	JSON(w, v)
+}
+
+// WriteError writes error response with the given status code.
+func WriteError(w http.ResponseWriter, status int, message string) {
+	WriteJSON(w, status, map[string]string{"error": message})
+}
+
 // JSON marshals 'v' to JSON, automatically escaping HTML and setting the Content-Type as application/json.
 // It will call http.Error in case of failures.
 func JSON(w http.ResponseWriter, v interface{}) {
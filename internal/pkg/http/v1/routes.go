@@ -3,10 +3,13 @@ package v1
 import (
 	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/dstdfx/bookish-spork/internal/pkg/backend"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/auth"
 	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+	"github.com/dstdfx/bookish-spork/internal/pkg/tracing"
 	"github.com/go-chi/chi"
 )
 
@@ -17,43 +20,103 @@ func Routes(b *backend.Backend) http.Handler {
 	// GET /v1/get/<key>
 	r.
 		With(RequireKeyName).
+		With(RequirePermission(auth.CommandGet)).
 		Get("/get/{key}", getHandler(b))
 
 	// POST /v1/set
 	r.
+		With(RequireWritable(b)).
 		With(RequireSetParams).
+		With(RequirePermission(auth.CommandSet)).
 		Post("/set", setHandler(b))
 
 	// GET /v1/keys
-	r.Get("/keys", keysHandler(b))
+	r.
+		With(RequireCommand(auth.CommandKeys)).
+		Get("/keys", keysHandler(b))
+
+	// PUT /v1/keys/<key>
+	r.
+		With(RequireWritable(b)).
+		With(RequireKeyName).
+		With(RequireValueParams).
+		With(RequirePermission(auth.CommandSet)).
+		Put("/keys/{key}", setHandler(b))
+
+	// GET /v1/keys/<key>
+	r.
+		With(RequireKeyName).
+		With(RequirePermission(auth.CommandGet)).
+		Get("/keys/{key}", valueHandler(b))
 
 	// DELETE /v1/remove/<key>
 	r.
+		With(RequireWritable(b)).
 		With(RequireKeyName).
+		With(RequirePermission(auth.CommandRemove)).
 		Delete("/remove/{key}", removeHandler(b))
 
 	// POST /v1/rpush
 	r.
+		With(RequireWritable(b)).
 		With(RequireRPushParams).
+		With(RequirePermission(auth.CommandRPush)).
 		Post("/rpush", rpushHandler(b))
 
 	// GET /v1/lindex/<key>/<index>
 	r.
 		With(RequireKeyName).
 		With(RequireIndex).
+		With(RequirePermission(auth.CommandLIndex)).
 		Get("/lindex/{key}/{index}", lindexHandler(b))
 
 	// POST /v1/hset
 	r.
+		With(RequireWritable(b)).
 		With(RequireHSetParams).
+		With(RequirePermission(auth.CommandHSet)).
 		Post("/hset", hsetHandler(b))
 
 	// GET /v1/hget/<key>/<hkey>
 	r.
 		With(RequireKeyName).
 		With(RequireHKeyName).
+		With(RequirePermission(auth.CommandHGet)).
 		Get("/hget/{key}/{hkey}", hgetHandler(b))
 
+	// GET /v1/ttl/<key>
+	r.
+		With(RequireKeyName).
+		With(RequirePermission(auth.CommandTTL)).
+		Get("/ttl/{key}", ttlHandler(b))
+
+	// POST /v1/geoadd
+	r.
+		With(RequireWritable(b)).
+		With(RequireGeoAddParams).
+		With(RequirePermission(auth.CommandGeoAdd)).
+		Post("/geoadd", geoaddHandler(b))
+
+	// GET /v1/geopos/<key>?member=<member>
+	r.
+		With(RequireKeyName).
+		With(RequireGeoMembers).
+		With(RequirePermission(auth.CommandGeoPos)).
+		Get("/geopos/{key}", geoposHandler(b))
+
+	// GET /v1/geodist/<key>?member=<member1>&member=<member2>&unit=<unit>
+	r.
+		With(RequireKeyName).
+		With(RequireGeoMembers).
+		With(RequirePermission(auth.CommandGeoDist)).
+		Get("/geodist/{key}", geodistHandler(b))
+
+	// POST /v1/geosearch
+	r.
+		With(RequireGeoSearchParams).
+		With(RequirePermission(auth.CommandGeoSearch)).
+		Post("/geosearch", geosearchHandler(b))
+
 	return r
 }
 
@@ -63,15 +126,27 @@ func getHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Reques
 		key := GetKeyName(req.Context())
 
 		// Get value from cache
-		k, ok := b.Cache.Get(key)
+		k, contentType, ok := clientCache(b, req).GetWithContentType(key)
 		if !ok {
 			w.WriteHeader(http.StatusNotFound)
 
 			return
 		}
 
-		w.WriteHeader(http.StatusOK)
-		JSON(w, map[string]interface{}{"value": k})
+		// Raw values are returned as is only if the caller explicitly asked for it
+		if contentType != "" {
+			if acceptsMediaType(req.Header.Get("Accept"), contentType, true) {
+				writeRaw(w, k, contentType)
+
+				return
+			}
+
+			WriteJSON(w, http.StatusOK, map[string]interface{}{"value": k, "content_type": contentType})
+
+			return
+		}
+
+		WriteJSON(w, http.StatusOK, map[string]interface{}{"value": k})
 	}
 }
 
@@ -81,25 +156,80 @@ func setHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Reques
 		body := GetSetBody(req.Context())
 
 		// Set new entity
-		b.Cache.Set(body.Key, body.Value, time.Duration(body.TTL)*time.Second)
+		ttl := time.Duration(body.TTL) * time.Second
+		if raw, ok := body.Value.([]byte); ok && body.ContentType != "" {
+			clientCache(b, req).SetBytes(body.Key, raw, body.ContentType, ttl)
+		} else {
+			clientCache(b, req).Set(body.Key, body.Value, ttl)
+		}
 		w.WriteHeader(http.StatusOK)
 	}
 }
 
 func keysHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Request) {
 	return func(w http.ResponseWriter, req *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		JSON(w, map[string]interface{}{"keys": b.Cache.Keys()})
+		// Return only the keys the client is allowed to see
+		keys := make([]string, 0)
+		for _, k := range clientCache(b, req).Keys() {
+			if auth.IsAllowed(req.Context(), auth.CommandKeys, k) {
+				keys = append(keys, k)
+			}
+		}
+
+		WriteJSON(w, http.StatusOK, map[string]interface{}{"keys": keys})
 	}
 }
 
+func valueHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Get key from router's context
+		key := GetKeyName(req.Context())
+
+		// Get value from cache
+		k, contentType, ok := clientCache(b, req).GetWithContentType(key)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		// Values set without content type are represented as JSON
+		if contentType == "" {
+			contentType = mediaTypeJSON
+		}
+		if !acceptsMediaType(req.Header.Get("Accept"), contentType, false) {
+			WriteJSON(w, http.StatusNotAcceptable, map[string]string{"error": "value can't be represented in accepted media type"})
+
+			return
+		}
+
+		writeRaw(w, k, contentType)
+	}
+}
+
+// writeRaw writes raw bytes value with its content type,
+// any other value is written as JSON.
+func writeRaw(w http.ResponseWriter, v interface{}, contentType string) {
+	raw, ok := v.([]byte)
+	if !ok {
+		WriteJSON(w, http.StatusOK, v)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(raw)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(raw)
+}
+
 func removeHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Request) {
 	return func(w http.ResponseWriter, req *http.Request) {
 		// Get key from router's context
 		key := GetKeyName(req.Context())
 
 		// Remove key from the cache
-		b.Cache.Remove(key)
+		clientCache(b, req).Remove(key)
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -109,10 +239,9 @@ func rpushHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Requ
 		// Get rpush body from router's context
 		body := GetRPushBody(req.Context())
 
-		err := b.Cache.RPush(body.Key, body.Value, time.Duration(body.TTL)*time.Second)
+		err := clientCache(b, req).RPush(body.Key, body.Value, time.Duration(body.TTL)*time.Second)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			JSON(w, map[string]string{"error": err.Error()})
+			WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 
 			return
 		}
@@ -126,21 +255,19 @@ func lindexHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Req
 		key := GetKeyName(req.Context())
 		index := GetIndex(req.Context())
 
-		v, err := b.Cache.LIndex(key, index)
+		v, err := clientCache(b, req).LIndex(key, index)
 		if err != nil {
 			if errors.Is(err, qqcache.ErrNotFound) {
 				w.WriteHeader(http.StatusNotFound)
 			}
 			if errors.Is(err, qqcache.ErrWrongTypeIndex) {
-				w.WriteHeader(http.StatusBadRequest)
-				JSON(w, map[string]string{"error": err.Error()})
+				WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 			}
 
 			return
 		}
 
-		w.WriteHeader(http.StatusOK)
-		JSON(w, map[string]interface{}{"value": v})
+		WriteJSON(w, http.StatusOK, map[string]interface{}{"value": v})
 	}
 }
 
@@ -149,10 +276,9 @@ func hsetHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Reque
 		// Get hset body from router's context
 		body := GetHSetBody(req.Context())
 
-		err := b.Cache.HSet(body.Key, body.Value, time.Duration(body.TTL)*time.Second)
+		err := clientCache(b, req).HSet(body.Key, body.Value, time.Duration(body.TTL)*time.Second)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			JSON(w, map[string]string{"error": err.Error()})
+			WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 
 			return
 		}
@@ -166,20 +292,180 @@ func hgetHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Reque
 		key := GetKeyName(req.Context())
 		hkey := GetHKeyName(req.Context())
 
-		v, err := b.Cache.HGet(key, hkey)
+		v, err := clientCache(b, req).HGet(key, hkey)
 		if err != nil {
 			if errors.Is(err, qqcache.ErrNotFound) {
 				w.WriteHeader(http.StatusNotFound)
 			}
 			if errors.Is(err, qqcache.ErrWrongTypeHGet) {
-				w.WriteHeader(http.StatusBadRequest)
-				JSON(w, map[string]string{"error": err.Error()})
+				WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 			}
 
 			return
 		}
 
-		w.WriteHeader(http.StatusOK)
-		JSON(w, map[string]interface{}{"value": v})
+		WriteJSON(w, http.StatusOK, map[string]interface{}{"value": v})
 	}
 }
+
+func ttlHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Get key from router's context
+		key := GetKeyName(req.Context())
+
+		ttl, ok := clientCache(b, req).TTL(key)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		// Remaining seconds are rounded up, -1 is returned for the persistent keys
+		seconds := -1
+		if ttl > 0 {
+			seconds = int((ttl + time.Second - 1) / time.Second)
+		}
+
+		WriteJSON(w, http.StatusOK, map[string]interface{}{"ttl": seconds})
+	}
+}
+
+func geoaddHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Get geoadd body from router's context
+		body := GetGeoAddBody(req.Context())
+
+		members := make([]qqcache.GeoMember, len(body.Members))
+		for i, m := range body.Members {
+			members[i] = qqcache.GeoMember{
+				Name:     m.Member,
+				GeoPoint: qqcache.GeoPoint{Longitude: m.Longitude, Latitude: m.Latitude},
+			}
+		}
+
+		added, err := clientCache(b, req).GeoAdd(body.Key, members, time.Duration(body.TTL)*time.Second)
+		if err != nil {
+			WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+
+			return
+		}
+
+		WriteJSON(w, http.StatusOK, map[string]interface{}{"added": added})
+	}
+}
+
+func geoposHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Get key and members from router's context
+		key := GetKeyName(req.Context())
+		members := GetGeoMembers(req.Context())
+
+		points, err := clientCache(b, req).GeoPos(key, members...)
+		if err != nil {
+			writeGeoErr(w, err)
+
+			return
+		}
+
+		value := make([]*GeoPoint, len(points))
+		for i, p := range points {
+			if p != nil {
+				value[i] = &GeoPoint{Longitude: p.Longitude, Latitude: p.Latitude}
+			}
+		}
+
+		WriteJSON(w, http.StatusOK, map[string]interface{}{"value": value})
+	}
+}
+
+func geodistHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Get key and members from router's context
+		key := GetKeyName(req.Context())
+		members := GetGeoMembers(req.Context())
+		if len(members) != 2 {
+			WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "exactly two members are required"})
+
+			return
+		}
+
+		distance, ok, err := clientCache(b, req).GeoDist(key, members[0], members[1], req.URL.Query().Get(unitQuery))
+		if err != nil {
+			writeGeoErr(w, err)
+
+			return
+		}
+
+		var value interface{}
+		if ok {
+			value = distance
+		}
+
+		WriteJSON(w, http.StatusOK, map[string]interface{}{"value": value})
+	}
+}
+
+// GeoSearchResult represents a single member in geosearch response body.
+type GeoSearchResult struct {
+	Member   string  `json:"member"`
+	Distance float64 `json:"distance"`
+	GeoPoint
+}
+
+func geosearchHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Get geosearch body from router's context
+		body := GetGeoSearchBody(req.Context())
+
+		query := qqcache.GeoSearchQuery{
+			FromMember: body.FromMember,
+			Radius:     body.Radius,
+			Width:      body.Width,
+			Height:     body.Height,
+			Unit:       body.Unit,
+			Sort:       body.Sort,
+			Count:      body.Count,
+		}
+		if body.FromLonLat != nil {
+			query.FromPoint = &qqcache.GeoPoint{
+				Longitude: body.FromLonLat.Longitude,
+				Latitude:  body.FromLonLat.Latitude,
+			}
+		}
+
+		found, err := clientCache(b, req).GeoSearch(body.Key, query)
+		if err != nil {
+			writeGeoErr(w, err)
+
+			return
+		}
+
+		value := make([]GeoSearchResult, len(found))
+		for i, f := range found {
+			value[i] = GeoSearchResult{
+				Member:   f.Name,
+				Distance: f.Distance,
+				GeoPoint: GeoPoint{Longitude: f.Longitude, Latitude: f.Latitude},
+			}
+		}
+
+		WriteJSON(w, http.StatusOK, map[string]interface{}{"value": value})
+	}
+}
+
+// writeGeoErr writes response for errors returned by geo operations.
+func writeGeoErr(w http.ResponseWriter, err error) {
+	if errors.Is(err, qqcache.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+
+	WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+}
+
+// clientCache returns the cache that reports the client of the request in the slow log
+// and records the commands in the trace of the request.
+func clientCache(b *backend.Backend, req *http.Request) *qqcache.Cache {
+	return b.Cache.WithClient(auth.ClientName(req)).WithObserver(tracing.CacheObserver(req.Context()))
+}
@@ -0,0 +1,88 @@
+package v2
+
+import (
+	"net/http"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/backend"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/accesslog"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/auth"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/ratelimit"
+)
+
+const errPermissionDenied = "permission denied"
+
+// RequirePermission middleware checks that the client is allowed to run the command
+// on the key of the request and the rate limits of the route and command class
+// are not exceeded.
+// It must be placed after RequireKeyName middleware.
+func RequirePermission(command string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := GetKeyName(r.Context())
+			accesslog.SetKey(r.Context(), key)
+			if !auth.IsAllowed(r.Context(), command, key) {
+				WriteError(w, http.StatusForbidden, CodeForbidden, errPermissionDenied)
+
+				return
+			}
+			if !ratelimit.Allow(w, r, auth.CommandPermission(command)) {
+				WriteError(w, http.StatusTooManyRequests, CodeRateLimited, ratelimit.ErrRateLimitExceeded.Error())
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequirePatchPermission middleware checks that the client is allowed to run
+// the command of PATCH request, hset for fields and rpush for push.
+// It must be placed after RequirePatchParams middleware.
+func RequirePatchPermission(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		command := auth.CommandRPush
+		if len(GetPatchBody(r.Context()).Fields) > 0 {
+			command = auth.CommandHSet
+		}
+
+		RequirePermission(command)(next).ServeHTTP(w, r)
+	})
+}
+
+// RequireCommand middleware checks that the client is allowed to run the command
+// on any key and the rate limits are not exceeded, it's used for commands
+// that are not addressed to a single key.
+func RequireCommand(command string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !auth.IsCommandAllowed(r.Context(), command) {
+				WriteError(w, http.StatusForbidden, CodeForbidden, errPermissionDenied)
+
+				return
+			}
+			if !ratelimit.Allow(w, r, auth.CommandPermission(command)) {
+				WriteError(w, http.StatusTooManyRequests, CodeRateLimited, ratelimit.ErrRateLimitExceeded.Error())
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireWritable middleware rejects the writes if the backend is read-only.
+func RequireWritable(b *backend.Backend) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if b.ReadOnly {
+				WriteError(w, http.StatusForbidden, CodeReadOnly, backend.ErrReadOnly.Error())
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package v2
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+)
+
+// Machine-readable error codes.
+const (
+	CodeInvalidRequest       = "invalid_request"
+	CodeInvalidIndex         = "invalid_index"
+	CodeKeyNotFound          = "key_not_found"
+	CodeItemNotFound         = "item_not_found"
+	CodeFieldNotFound        = "field_not_found"
+	CodeWrongType            = "wrong_type"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeRouteNotFound        = "route_not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeRateLimited          = "rate_limited"
+	CodeReadOnly             = "read_only"
+	CodeRequestTooLarge      = "request_too_large"
+	CodeInternal             = "internal_error"
+)
+
+// Error represents an error returned by API.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ErrorResponse represents the body of all error responses.
+type ErrorResponse struct {
+	Error Error `json:"error"`
+}
+
+// JSON writes status code and marshals 'v' to JSON setting the Content-Type as application/json.
+func JSON(w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(append(body, '\n'))
+}
+
+// WriteError writes error response with the given status code.
+func WriteError(w http.ResponseWriter, status int, code, message string) {
+	JSON(w, status, ErrorResponse{Error: Error{Code: code, Message: message}})
+}
+
+// writeCacheErr writes response for errors returned by cache operations.
+func writeCacheErr(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, qqcache.ErrNotFound):
+		WriteError(w, http.StatusNotFound, CodeKeyNotFound, "key not found")
+	case errors.Is(err, qqcache.ErrWrongTypeIndex),
+		errors.Is(err, qqcache.ErrWrongTypeLPush),
+		errors.Is(err, qqcache.ErrWrongTypeHSet),
+		errors.Is(err, qqcache.ErrWrongTypeHGet):
+		WriteError(w, http.StatusConflict, CodeWrongType, err.Error())
+	default:
+		WriteError(w, http.StatusInternalServerError, CodeInternal, err.Error())
+	}
+}
+
+// notFoundHandler is used for requests to unknown routes.
+func notFoundHandler(w http.ResponseWriter, _ *http.Request) {
+	WriteError(w, http.StatusNotFound, CodeRouteNotFound, "route not found")
+}
+
+// methodNotAllowedHandler is used for requests with unsupported methods.
+func methodNotAllowedHandler(w http.ResponseWriter, _ *http.Request) {
+	WriteError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+}
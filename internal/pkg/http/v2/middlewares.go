@@ -0,0 +1,255 @@
+package v2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+const (
+	keyParam   = "key"
+	indexParam = "index"
+	fieldParam = "field"
+)
+
+type ctxKey int
+
+const (
+	ctxKeyName ctxKey = iota
+	ctxIndex
+	ctxFieldName
+	ctxPutBody
+	ctxPatchBody
+	ctxFieldBody
+)
+
+// RequireKeyName middleware checks that 'key' parameter is set.
+func RequireKeyName(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := chi.URLParam(r, keyParam)
+		if key == "" {
+			WriteError(w, http.StatusBadRequest, CodeInvalidRequest, "key is required")
+
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxKeyName, key)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetKeyName retrieves key name value from context.
+func GetKeyName(ctx context.Context) string {
+	v, ok := ctx.Value(ctxKeyName).(string)
+	if !ok {
+		return ""
+	}
+
+	return v
+}
+
+// RequireIndex middleware checks that 'index' parameter is a non-negative integer.
+func RequireIndex(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v, err := strconv.Atoi(chi.URLParam(r, indexParam))
+		if err != nil || v < 0 {
+			WriteError(w, http.StatusBadRequest, CodeInvalidIndex, "index must be a non-negative integer")
+
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxIndex, v)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetIndex retrieves index value from context.
+func GetIndex(ctx context.Context) int {
+	v, ok := ctx.Value(ctxIndex).(int)
+	if !ok {
+		return 0
+	}
+
+	return v
+}
+
+// RequireFieldName middleware checks that 'field' parameter is set.
+func RequireFieldName(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		field := chi.URLParam(r, fieldParam)
+		if field == "" {
+			WriteError(w, http.StatusBadRequest, CodeInvalidRequest, "field is required")
+
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxFieldName, field)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetFieldName retrieves hash map field name from context.
+func GetFieldName(ctx context.Context) string {
+	v, ok := ctx.Value(ctxFieldName).(string)
+	if !ok {
+		return ""
+	}
+
+	return v
+}
+
+// PutRequestBody represents the body to replace the value of the key.
+type PutRequestBody struct {
+	Value interface{} `json:"value"`
+	TTL   int         `json:"ttl"`
+}
+
+func (b *PutRequestBody) validate() error {
+	if b.Value == nil {
+		return fmt.Errorf("value is required")
+	}
+
+	return nil
+}
+
+// RequirePutParams validates request body to replace the value of the key.
+func RequirePutParams(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := PutRequestBody{}
+		if !decodeBody(w, r, &body, body.validate) {
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxPutBody, body)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetPutBody retrieves put body from context.
+func GetPutBody(ctx context.Context) *PutRequestBody {
+	v, ok := ctx.Value(ctxPutBody).(PutRequestBody)
+	if !ok {
+		return nil
+	}
+
+	return &v
+}
+
+// PatchRequestBody represents the body to modify the value of the key.
+// Exactly one of Push or Fields must be set.
+type PatchRequestBody struct {
+	// Push contains items to append to the list.
+	Push []interface{} `json:"push,omitempty"`
+
+	// Fields contains fields to set in the hash map.
+	Fields map[string]interface{} `json:"fields,omitempty"`
+
+	// TTL is applied only if the key is created.
+	TTL int `json:"ttl"`
+}
+
+func (b *PatchRequestBody) validate() error {
+	if (len(b.Push) == 0) == (len(b.Fields) == 0) {
+		return fmt.Errorf("exactly one of push or fields must be set")
+	}
+
+	return nil
+}
+
+// RequirePatchParams validates request body to modify the value of the key.
+func RequirePatchParams(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := PatchRequestBody{}
+		if !decodeBody(w, r, &body, body.validate) {
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxPatchBody, body)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetPatchBody retrieves patch body from context.
+func GetPatchBody(ctx context.Context) *PatchRequestBody {
+	v, ok := ctx.Value(ctxPatchBody).(PatchRequestBody)
+	if !ok {
+		return nil
+	}
+
+	return &v
+}
+
+// FieldRequestBody represents the body to set a single hash map field.
+type FieldRequestBody struct {
+	Value interface{} `json:"value"`
+
+	// TTL is applied only if the key is created.
+	TTL int `json:"ttl"`
+}
+
+func (b *FieldRequestBody) validate() error {
+	if b.Value == nil {
+		return fmt.Errorf("value is required")
+	}
+
+	return nil
+}
+
+// RequireFieldParams validates request body to set a single hash map field.
+func RequireFieldParams(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := FieldRequestBody{}
+		if !decodeBody(w, r, &body, body.validate) {
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxFieldBody, body)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetFieldBody retrieves field body from context.
+func GetFieldBody(ctx context.Context) *FieldRequestBody {
+	v, ok := ctx.Value(ctxFieldBody).(FieldRequestBody)
+	if !ok {
+		return nil
+	}
+
+	return &v
+}
+
+// decodeBody decodes JSON request body into 'v' and validates it.
+// Error response is written if it returns false.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}, validate func() error) bool {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != "application/json" {
+			WriteError(w, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType,
+				"request body must be application/json")
+
+			return false
+		}
+	}
+
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		WriteError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("invalid request body: %s", err))
+
+		return false
+	}
+
+	if err := validate(); err != nil {
+		WriteError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,55 @@
+package v2
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetKeyNameOk(t *testing.T) {
+	expected := "some-key"
+
+	ctx := context.Background()
+	ctx = context.WithValue(ctx, ctxKeyName, expected)
+
+	actual := GetKeyName(ctx)
+	assert.Equal(t, expected, actual)
+}
+
+func TestGetKeyNameEmpty(t *testing.T) {
+	ctx := context.Background()
+
+	actual := GetKeyName(ctx)
+	assert.Equal(t, "", actual)
+}
+
+func TestRequirePatchParams_UnknownField(t *testing.T) {
+	handler := RequirePatchParams(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler must not be called")
+	}))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"rpush": [1]}`))
+	handler.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"invalid_request"`)
+}
+
+func TestRequirePatchParams_UnsupportedMediaType(t *testing.T) {
+	handler := RequirePatchParams(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler must not be called")
+	}))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`push=1`))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+}
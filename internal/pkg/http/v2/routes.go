@@ -0,0 +1,231 @@
+package v2
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/backend"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/auth"
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+	"github.com/dstdfx/bookish-spork/internal/pkg/tracing"
+	"github.com/go-chi/chi"
+)
+
+// Routes initializes v2 handler.
+func Routes(b *backend.Backend) http.Handler {
+	r := chi.NewRouter()
+	r.NotFound(notFoundHandler)
+	r.MethodNotAllowed(methodNotAllowedHandler)
+
+	// GET /v2/keys
+	r.
+		With(RequireCommand(auth.CommandKeys)).
+		Get("/keys", keysHandler(b))
+
+	r.Route("/keys/{key}", func(r chi.Router) {
+		r.Use(RequireKeyName)
+
+		// GET /v2/keys/<key>
+		r.
+			With(RequirePermission(auth.CommandGet)).
+			Get("/", getHandler(b))
+
+		// PUT /v2/keys/<key>
+		r.
+			With(RequireWritable(b)).
+			With(RequirePutParams).
+			With(RequirePermission(auth.CommandSet)).
+			Put("/", putHandler(b))
+
+		// PATCH /v2/keys/<key>
+		r.
+			With(RequireWritable(b)).
+			With(RequirePatchParams).
+			With(RequirePatchPermission).
+			Patch("/", patchHandler(b))
+
+		// DELETE /v2/keys/<key>
+		r.
+			With(RequireWritable(b)).
+			With(RequirePermission(auth.CommandRemove)).
+			Delete("/", deleteHandler(b))
+
+		// GET /v2/keys/<key>/items/<index>
+		r.
+			With(RequireIndex).
+			With(RequirePermission(auth.CommandLIndex)).
+			Get("/items/{index}", itemHandler(b))
+
+		// GET /v2/keys/<key>/fields/<field>
+		r.
+			With(RequireFieldName).
+			With(RequirePermission(auth.CommandHGet)).
+			Get("/fields/{field}", fieldHandler(b))
+
+		// PUT /v2/keys/<key>/fields/<field>
+		r.
+			With(RequireWritable(b)).
+			With(RequireFieldName).
+			With(RequireFieldParams).
+			With(RequirePermission(auth.CommandHSet)).
+			Put("/fields/{field}", putFieldHandler(b))
+	})
+
+	return r
+}
+
+func keysHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Return only the keys the client is allowed to see
+		keys := make([]string, 0)
+		for _, k := range clientCache(b, req).Keys() {
+			if auth.IsAllowed(req.Context(), auth.CommandKeys, k) {
+				keys = append(keys, k)
+			}
+		}
+
+		JSON(w, http.StatusOK, map[string]interface{}{"keys": keys})
+	}
+}
+
+func getHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Get key from router's context
+		key := GetKeyName(req.Context())
+
+		v, contentType, ok := clientCache(b, req).GetWithContentType(key)
+		if !ok {
+			WriteError(w, http.StatusNotFound, CodeKeyNotFound, "key not found")
+
+			return
+		}
+
+		resp := map[string]interface{}{"value": v}
+		if contentType != "" {
+			resp["content_type"] = contentType
+		}
+		JSON(w, http.StatusOK, resp)
+	}
+}
+
+func putHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Get key and body from router's context
+		key := GetKeyName(req.Context())
+		body := GetPutBody(req.Context())
+
+		clientCache(b, req).Set(key, body.Value, time.Duration(body.TTL)*time.Second)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func patchHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Get key and body from router's context
+		key := GetKeyName(req.Context())
+		body := GetPatchBody(req.Context())
+		ttl := time.Duration(body.TTL) * time.Second
+		cache := clientCache(b, req)
+
+		if len(body.Fields) > 0 {
+			if err := cache.HSet(key, body.Fields, ttl); err != nil {
+				writeCacheErr(w, err)
+
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+			return
+		}
+
+		for _, item := range body.Push {
+			if err := cache.RPush(key, item, ttl); err != nil {
+				writeCacheErr(w, err)
+
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func deleteHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Get key from router's context
+		key := GetKeyName(req.Context())
+
+		if !clientCache(b, req).Remove(key) {
+			WriteError(w, http.StatusNotFound, CodeKeyNotFound, "key not found")
+
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func itemHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Get key and index from router's context
+		key := GetKeyName(req.Context())
+		index := GetIndex(req.Context())
+
+		v, isExist, err := clientCache(b, req).LIndexLookup(key, index)
+		if err != nil {
+			writeCacheErr(w, err)
+
+			return
+		}
+		if !isExist {
+			WriteError(w, http.StatusNotFound, CodeItemNotFound, "item not found")
+
+			return
+		}
+
+		JSON(w, http.StatusOK, map[string]interface{}{"value": v})
+	}
+}
+
+func fieldHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Get key and field from router's context
+		key := GetKeyName(req.Context())
+		field := GetFieldName(req.Context())
+
+		v, isExist, err := clientCache(b, req).HGetLookup(key, field)
+		if err != nil {
+			writeCacheErr(w, err)
+
+			return
+		}
+		if !isExist {
+			WriteError(w, http.StatusNotFound, CodeFieldNotFound, "field not found")
+
+			return
+		}
+
+		JSON(w, http.StatusOK, map[string]interface{}{"value": v})
+	}
+}
+
+func putFieldHandler(b *backend.Backend) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Get key, field and body from router's context
+		key := GetKeyName(req.Context())
+		field := GetFieldName(req.Context())
+		body := GetFieldBody(req.Context())
+
+		err := clientCache(b, req).HSet(key, map[string]interface{}{field: body.Value}, time.Duration(body.TTL)*time.Second)
+		if err != nil {
+			writeCacheErr(w, err)
+
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// clientCache returns the cache that reports the client of the request in the slow log
+// and records the commands in the trace of the request.
+func clientCache(b *backend.Backend, req *http.Request) *qqcache.Cache {
+	return b.Cache.WithClient(auth.ClientName(req)).WithObserver(tracing.CacheObserver(req.Context()))
+}
@@ -0,0 +1,3 @@
+package v2
+
+/* API tests are located in api_v2_test.go */
@@ -0,0 +1,80 @@
+package info
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	"google.golang.org/grpc/stats"
+)
+
+// ConnCounter counts client connections of a server.
+type ConnCounter struct {
+	active int64
+	total  uint64
+}
+
+// NewConnCounter returns new instance of ConnCounter.
+func NewConnCounter() *ConnCounter {
+	return &ConnCounter{}
+}
+
+// Active returns the number of connected clients.
+func (c *ConnCounter) Active() int64 {
+	return atomic.LoadInt64(&c.active)
+}
+
+// Total returns the number of accepted connections.
+func (c *ConnCounter) Total() uint64 {
+	return atomic.LoadUint64(&c.total)
+}
+
+// ConnState is a hook of http.Server that is called when a client
+// connection changes state.
+func (c *ConnCounter) ConnState(_ net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		c.open()
+	case http.StateClosed, http.StateHijacked:
+		c.close()
+	}
+}
+
+// GRPCStatsHandler returns gRPC stats handler that counts connections of gRPC server.
+func (c *ConnCounter) GRPCStatsHandler() stats.Handler {
+	return grpcStatsHandler{c: c}
+}
+
+func (c *ConnCounter) open() {
+	atomic.AddInt64(&c.active, 1)
+	atomic.AddUint64(&c.total, 1)
+}
+
+func (c *ConnCounter) close() {
+	atomic.AddInt64(&c.active, -1)
+}
+
+// grpcStatsHandler implements stats.Handler and counts connections only.
+type grpcStatsHandler struct {
+	c *ConnCounter
+}
+
+func (h grpcStatsHandler) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (h grpcStatsHandler) HandleRPC(context.Context, stats.RPCStats) {}
+
+func (h grpcStatsHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (h grpcStatsHandler) HandleConn(_ context.Context, s stats.ConnStats) {
+	switch s.(type) {
+	case *stats.ConnBegin:
+		h.c.open()
+	case *stats.ConnEnd:
+		h.c.close()
+	}
+}
@@ -0,0 +1,254 @@
+// Package info provides Redis INFO-like endpoint of the service API
+// that reports cache internals.
+package info
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/config"
+	"github.com/dstdfx/bookish-spork/internal/pkg/health"
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+	"github.com/dstdfx/bookish-spork/internal/pkg/replication"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Path is the path of the endpoint on the service API.
+const Path = "/info"
+
+// Opts represents the options to create new instance of Info.
+type Opts struct {
+	// Cache is the cache to report.
+	Cache *qqcache.Cache
+
+	// Health provides build info and readiness status, it's optional.
+	Health *health.Health
+
+	// Clients contains connection counters by server names.
+	Clients map[string]*ConnCounter
+
+	// Replication returns the replication status, it's optional.
+	Replication func() replication.Status
+
+	// Config returns configuration in effect, it's optional.
+	// The configuration is reported as it's marshaled to YAML,
+	// the values of the secret fields are redacted.
+	Config func() interface{}
+}
+
+// Report represents the body of the info endpoint.
+type Report struct {
+	Server      *Server                `json:"server,omitempty"`
+	Clients     Clients                `json:"clients"`
+	Memory      Memory                 `json:"memory"`
+	Keyspace    Keyspace               `json:"keyspace"`
+	Cleaner     Cleaner                `json:"cleaner"`
+	Stats       Stats                  `json:"stats"`
+	Replication *replication.Status    `json:"replication,omitempty"`
+	Config      map[string]interface{} `json:"config,omitempty"`
+}
+
+// Server contains build info, uptime and readiness status of the service.
+type Server struct {
+	health.VersionResponse
+	Status string `json:"status"`
+}
+
+// Clients contains the numbers of connected clients.
+type Clients struct {
+	// Connected and Total contain the numbers of connected clients and
+	// accepted connections by server names.
+	Connected map[string]int64  `json:"connected"`
+	Total     map[string]uint64 `json:"total"`
+
+	// Watchers is the number of clients watching cache changes.
+	Watchers int `json:"watchers"`
+}
+
+// Memory contains memory usage.
+type Memory struct {
+	// CacheApproxBytes is approximate size of cache keys and values.
+	CacheApproxBytes int64 `json:"cache_approx_bytes"`
+
+	// HeapAllocBytes and SysBytes are the Go runtime memory stats.
+	HeapAllocBytes uint64 `json:"heap_alloc_bytes"`
+	SysBytes       uint64 `json:"sys_bytes"`
+}
+
+// Keyspace contains the numbers of keys.
+type Keyspace struct {
+	Keys          int            `json:"keys"`
+	KeysWithTTL   int            `json:"keys_with_ttl"`
+	AvgTTLSeconds float64        `json:"avg_ttl_seconds"`
+	KeysByType    map[string]int `json:"keys_by_type"`
+}
+
+// Cleaner contains the state of the cache cleaner.
+type Cleaner struct {
+	EvictionIntervalSeconds float64    `json:"eviction_interval_seconds"`
+	LastRun                 *time.Time `json:"last_run,omitempty"`
+	LastRunDurationSeconds  float64    `json:"last_run_duration_seconds"`
+	LastRunRemovedKeys      uint64     `json:"last_run_removed_keys"`
+	Rounds                  uint64     `json:"rounds"`
+	ExpiredKeys             uint64     `json:"expired_keys"`
+	EvictedKeys             uint64     `json:"evicted_keys"`
+}
+
+// Stats contains command counters.
+type Stats struct {
+	Commands map[string]uint64 `json:"commands"`
+	Hits     map[string]uint64 `json:"hits"`
+	Misses   map[string]uint64 `json:"misses"`
+
+	// SlowLogLen is the number of entries in the slow log.
+	SlowLogLen int `json:"slow_log_len"`
+}
+
+// Info reports cache internals.
+type Info struct {
+	opts Opts
+}
+
+// New returns new instance of Info.
+func New(opts Opts) *Info {
+	return &Info{opts: opts}
+}
+
+// Register adds the endpoints to the mux.
+func (i *Info) Register(mux *http.ServeMux) {
+	mux.Handle(Path, i)
+	mux.HandleFunc(SlowLogPath, i.SlowLog)
+}
+
+// ServeHTTP responds with the report.
+func (i *Info) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	report, err := i.Report()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// Report returns the report of cache internals.
+func (i *Info) Report() (Report, error) {
+	s := i.opts.Cache.Stats()
+
+	report := Report{
+		Clients: Clients{
+			Connected: make(map[string]int64, len(i.opts.Clients)),
+			Total:     make(map[string]uint64, len(i.opts.Clients)),
+			Watchers:  s.Watchers,
+		},
+		Memory: Memory{
+			CacheApproxBytes: s.ApproxMemoryBytes,
+		},
+		Keyspace: Keyspace{
+			Keys:          s.Keys,
+			KeysWithTTL:   s.KeysWithTTL,
+			AvgTTLSeconds: s.AvgTTL.Seconds(),
+			KeysByType:    s.KeysByType,
+		},
+		Cleaner: Cleaner{
+			EvictionIntervalSeconds: s.EvictionInterval.Seconds(),
+			LastRunDurationSeconds:  s.LastCleanerDuration.Seconds(),
+			LastRunRemovedKeys:      s.LastCleanerRemoved,
+			Rounds:                  s.CleanerRounds,
+			ExpiredKeys:             s.ExpiredKeys,
+			EvictedKeys:             s.EvictedKeys,
+		},
+		Stats: Stats{
+			Commands:   s.Calls,
+			Hits:       s.Hits,
+			Misses:     s.Misses,
+			SlowLogLen: i.opts.Cache.SlowLogLen(),
+		},
+	}
+	if !s.LastCleanerRun.IsZero() {
+		report.Cleaner.LastRun = &s.LastCleanerRun
+	}
+
+	if i.opts.Health != nil {
+		report.Server = &Server{
+			VersionResponse: i.opts.Health.VersionInfo(),
+			Status:          i.opts.Health.Status(),
+		}
+	}
+
+	for name, c := range i.opts.Clients {
+		report.Clients.Connected[name] = c.Active()
+		report.Clients.Total[name] = c.Total()
+	}
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	report.Memory.HeapAllocBytes = m.HeapAlloc
+	report.Memory.SysBytes = m.Sys
+
+	if i.opts.Replication != nil {
+		s := i.opts.Replication()
+		report.Replication = &s
+	}
+
+	if i.opts.Config != nil {
+		cfg, err := configMap(i.opts.Config())
+		if err != nil {
+			return Report{}, fmt.Errorf("failed to report configuration: %w", err)
+		}
+		report.Config = cfg
+	}
+
+	return report, nil
+}
+
+// configMap converts the configuration to the map with the keys
+// named as in the configuration file, secrets are redacted.
+func configMap(cfg interface{}) (map[string]interface{}, error) {
+	data, err := config.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	return stringKeys(m).(map[string]interface{}), nil
+}
+
+// stringKeys converts YAML maps to the maps with string keys
+// to be able to marshal them to JSON.
+func stringKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[fmt.Sprint(k)] = stringKeys(item)
+		}
+
+		return m
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = stringKeys(item)
+		}
+
+		return v
+	case []interface{}:
+		for k, item := range v {
+			v[k] = stringKeys(item)
+		}
+
+		return v
+	default:
+		return v
+	}
+}
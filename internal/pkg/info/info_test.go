@@ -0,0 +1,174 @@
+package info
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/config"
+	"github.com/dstdfx/bookish-spork/internal/pkg/health"
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+	"github.com/dstdfx/bookish-spork/internal/pkg/replication"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/stats"
+)
+
+type testConfig struct {
+	Cache struct {
+		EvictionInterval int `yaml:"eviction_interval"`
+	} `yaml:"cache"`
+	Log struct {
+		Debug bool `yaml:"debug"`
+	} `yaml:"log"`
+}
+
+func TestInfo_ServeHTTP(t *testing.T) {
+	c := qqcache.New(qqcache.Opts{EvictionInterval: time.Minute})
+	defer c.Shutdown()
+
+	c.Set("key", "value", time.Hour)
+	require.NoError(t, c.RPush("list", "value", 0))
+	c.Get("key")
+	c.Get("unknown")
+
+	h := health.New(health.BuildInfo{GitTag: "v1.2.3"})
+	h.SetReady()
+
+	public := NewConnCounter()
+	public.ConnState(nil, http.StateNew)
+
+	var cfg testConfig
+	cfg.Cache.EvictionInterval = 60
+
+	mux := http.NewServeMux()
+	New(Opts{
+		Cache:   c,
+		Health:  h,
+		Clients: map[string]*ConnCounter{"public_api": public},
+		Replication: func() replication.Status {
+			return replication.Status{Role: replication.RoleLeader, Offset: 2}
+		},
+		Config: func() interface{} { return cfg },
+	}).Register(mux)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, Path, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+
+	var report Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+
+	require.NotNil(t, report.Server)
+	require.Equal(t, "v1.2.3", report.Server.GitTag)
+	require.Equal(t, health.StatusOK, report.Server.Status)
+
+	require.Equal(t, map[string]int64{"public_api": 1}, report.Clients.Connected)
+	require.Equal(t, map[string]uint64{"public_api": 1}, report.Clients.Total)
+
+	require.Equal(t, 2, report.Keyspace.Keys)
+	require.Equal(t, 1, report.Keyspace.KeysWithTTL)
+	require.InDelta(t, time.Hour.Seconds(), report.Keyspace.AvgTTLSeconds, 60)
+	require.Equal(t, 1, report.Keyspace.KeysByType[qqcache.TypeValue])
+	require.Equal(t, 1, report.Keyspace.KeysByType[qqcache.TypeList])
+
+	require.Greater(t, report.Memory.CacheApproxBytes, int64(0))
+	require.Greater(t, report.Memory.HeapAllocBytes, uint64(0))
+
+	require.Equal(t, time.Minute.Seconds(), report.Cleaner.EvictionIntervalSeconds)
+	require.Nil(t, report.Cleaner.LastRun)
+
+	require.Equal(t, uint64(2), report.Stats.Commands[qqcache.CommandGet])
+	require.Equal(t, uint64(1), report.Stats.Commands[qqcache.CommandSet])
+	require.Equal(t, uint64(1), report.Stats.Hits[qqcache.CommandGet])
+	require.Equal(t, uint64(1), report.Stats.Misses[qqcache.CommandGet])
+
+	require.Equal(t, &replication.Status{Role: replication.RoleLeader, Offset: 2}, report.Replication)
+
+	require.Equal(t, map[string]interface{}{
+		"cache": map[string]interface{}{"eviction_interval": float64(60)},
+		"log":   map[string]interface{}{"debug": false},
+	}, report.Config)
+}
+
+func TestInfo_Report_Optional(t *testing.T) {
+	c := qqcache.New(qqcache.Opts{EvictionInterval: time.Minute})
+	defer c.Shutdown()
+
+	report, err := New(Opts{Cache: c}).Report()
+	require.NoError(t, err)
+	require.Nil(t, report.Server)
+	require.Nil(t, report.Replication)
+	require.Nil(t, report.Config)
+	require.Empty(t, report.Clients.Connected)
+	require.Zero(t, report.Keyspace.Keys)
+}
+
+func TestConnCounter(t *testing.T) {
+	c := NewConnCounter()
+
+	c.ConnState(nil, http.StateNew)
+	c.ConnState(nil, http.StateNew)
+	c.ConnState(nil, http.StateActive)
+	c.ConnState(nil, http.StateIdle)
+	c.ConnState(nil, http.StateClosed)
+	require.Equal(t, int64(1), c.Active())
+	require.Equal(t, uint64(2), c.Total())
+
+	h := c.GRPCStatsHandler()
+	h.HandleConn(context.Background(), &stats.ConnBegin{})
+	require.Equal(t, int64(2), c.Active())
+	h.HandleConn(context.Background(), &stats.ConnEnd{})
+	c.ConnState(nil, http.StateHijacked)
+	require.Equal(t, int64(0), c.Active())
+	require.Equal(t, uint64(3), c.Total())
+}
+
+func TestInfo_ServeHTTP_RedactsSecrets(t *testing.T) {
+	c := qqcache.New(qqcache.Opts{EvictionInterval: time.Minute})
+	defer c.Shutdown()
+
+	secrets := []string{
+		"Bearer collector-token",
+		"/etc/bookish-spork/public.key",
+		"/etc/bookish-spork/service.key",
+		"/etc/bookish-spork/tokens",
+		"/etc/bookish-spork/hmac_keys",
+		"/etc/bookish-spork/replication.key",
+	}
+	cfg := &config.AppConfig{}
+	cfg.Tracing.Endpoint = "http://localhost:4318"
+	cfg.Tracing.Headers = map[string]string{"Authorization": secrets[0]}
+	cfg.PublicAPI.TLS.CertFile = "/etc/bookish-spork/public.crt"
+	cfg.PublicAPI.TLS.KeyFile = secrets[1]
+	cfg.ServiceAPI.TLS.KeyFile = secrets[2]
+	cfg.PublicAPI.Auth.TokensFile = secrets[3]
+	cfg.PublicAPI.Auth.HMACKeysFile = secrets[4]
+	cfg.Replication.KeyFile = secrets[5]
+
+	mux := http.NewServeMux()
+	New(Opts{Cache: c, Config: func() interface{} { return cfg }}).Register(mux)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, Path, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	for _, secret := range secrets {
+		require.NotContains(t, w.Body.String(), secret)
+	}
+
+	var report Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	tracing := report.Config["tracing"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{"Authorization": config.Redacted}, tracing["headers"])
+	require.Equal(t, "http://localhost:4318", tracing["endpoint"])
+
+	// Not secret and not set fields are reported as is
+	tls := report.Config["public_api"].(map[string]interface{})["tls"].(map[string]interface{})
+	require.Equal(t, config.Redacted, tls["key_file"])
+	require.Equal(t, "/etc/bookish-spork/public.crt", tls["cert_file"])
+	require.Equal(t, "", report.Config["grpc_api"].(map[string]interface{})["server_address"])
+	require.Equal(t, "", report.Config["replication"].(map[string]interface{})["cert_file"])
+}
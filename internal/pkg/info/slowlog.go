@@ -0,0 +1,85 @@
+package info
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+)
+
+// SlowLogPath is the path of the slow log endpoint on the service API.
+const SlowLogPath = "/slowlog"
+
+// limitQuery is the query parameter of the maximum number of returned entries.
+const limitQuery = "limit"
+
+// SlowLogResponse represents the body of the slow log endpoint.
+type SlowLogResponse struct {
+	// Len is the number of entries in the slow log.
+	Len     int            `json:"len"`
+	Entries []SlowLogEntry `json:"entries"`
+}
+
+// SlowLogEntry represents a slow cache command.
+type SlowLogEntry struct {
+	ID              uint64    `json:"id"`
+	Time            time.Time `json:"time"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Command         string    `json:"command"`
+	Key             string    `json:"key,omitempty"`
+	Args            int       `json:"args"`
+	ArgsBytes       int64     `json:"args_bytes"`
+	Client          string    `json:"client,omitempty"`
+}
+
+// SlowLog responds with the latest slow log entries on GET, newest first,
+// and deletes all entries on DELETE.
+func (i *Info) SlowLog(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		limit := 0
+		if v := req.URL.Query().Get(limitQuery); v != "" {
+			var err error
+			if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+				http.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+
+				return
+			}
+		}
+
+		entries := i.opts.Cache.SlowLog(limit)
+		resp := SlowLogResponse{
+			Len:     i.opts.Cache.SlowLogLen(),
+			Entries: make([]SlowLogEntry, 0, len(entries)),
+		}
+		for _, e := range entries {
+			resp.Entries = append(resp.Entries, slowLogEntry(e))
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(resp)
+	case http.MethodDelete:
+		i.opts.Cache.ResetSlowLog()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func slowLogEntry(e qqcache.SlowLogEntry) SlowLogEntry {
+	return SlowLogEntry{
+		ID:              e.ID,
+		Time:            e.Time,
+		DurationSeconds: e.Duration.Seconds(),
+		Command:         e.Command,
+		Key:             e.Key,
+		Args:            e.Args,
+		ArgsBytes:       e.ArgsBytes,
+		Client:          e.Client,
+	}
+}
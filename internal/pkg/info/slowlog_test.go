@@ -0,0 +1,58 @@
+package info
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInfo_SlowLog(t *testing.T) {
+	c := qqcache.New(qqcache.Opts{
+		EvictionInterval: time.Minute,
+		SlowLog:          qqcache.SlowLogOpts{Threshold: time.Nanosecond},
+	})
+	defer c.Shutdown()
+
+	c.WithClient("alice").Set("key", "value", 0)
+	c.Get("key")
+
+	mux := http.NewServeMux()
+	New(Opts{Cache: c}).Register(mux)
+
+	do := func(method, url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+
+		return w
+	}
+
+	w := do(http.MethodGet, SlowLogPath)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp SlowLogResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, 2, resp.Len)
+	require.Len(t, resp.Entries, 2)
+	require.Equal(t, qqcache.CommandGet, resp.Entries[0].Command)
+	require.Equal(t, qqcache.CommandSet, resp.Entries[1].Command)
+	require.Equal(t, "key", resp.Entries[1].Key)
+	require.Equal(t, "alice", resp.Entries[1].Client)
+	require.Equal(t, 1, resp.Entries[1].Args)
+
+	w = do(http.MethodGet, SlowLogPath+"?limit=1")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, 2, resp.Len)
+	require.Len(t, resp.Entries, 1)
+
+	require.Equal(t, http.StatusBadRequest, do(http.MethodGet, SlowLogPath+"?limit=a").Code)
+	require.Equal(t, http.StatusMethodNotAllowed, do(http.MethodPost, SlowLogPath).Code)
+
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, SlowLogPath).Code)
+	require.Zero(t, c.SlowLogLen())
+}
@@ -0,0 +1,419 @@
+// Package lineedit provides a minimal line editor for interactive terminals
+// with history and tab completion.
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// DefaultHistoryLimit is the maximum number of history entries used if it's omitted.
+const DefaultHistoryLimit = 1000
+
+// ErrInterrupted is returned by ReadLine if the input is interrupted with Ctrl-C.
+var ErrInterrupted = errors.New("lineedit: interrupted")
+
+// Control keys.
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlH     = 8
+	keyTab       = 9
+	keyLF        = 10
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyCR        = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyBackspace = 127
+)
+
+// Completer returns the completion candidates for the line before the cursor.
+// Word is the part of the line that is replaced by the candidates,
+// all candidates must start with it.
+type Completer func(line string) (word string, candidates []string)
+
+// Opts represents the options to create new instance of Editor.
+type Opts struct {
+	// In is the input, the line editing is enabled only if it's a terminal,
+	// otherwise lines are read as is without the prompts.
+	In io.Reader
+
+	// Out is the output the prompts and the edited lines are written to.
+	Out io.Writer
+
+	// Complete provides the candidates of tab completion, it's optional.
+	Complete Completer
+
+	// HistoryLimit is the maximum number of history entries,
+	// DefaultHistoryLimit is used if it's not set.
+	HistoryLimit int
+}
+
+// Editor reads the lines from the terminal.
+type Editor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	term     terminal
+	complete Completer
+
+	history      []string
+	historyLimit int
+}
+
+// New returns new instance of Editor.
+func New(opts Opts) *Editor {
+	e := &Editor{
+		in:           bufio.NewReader(opts.In),
+		out:          opts.Out,
+		complete:     opts.Complete,
+		historyLimit: opts.HistoryLimit,
+	}
+	if e.historyLimit <= 0 {
+		e.historyLimit = DefaultHistoryLimit
+	}
+	if f, ok := opts.In.(*os.File); ok && isTerminal(int(f.Fd())) {
+		e.term = &fdTerminal{fd: int(f.Fd())}
+	}
+
+	return e
+}
+
+// IsTerminal method returns true if the input is a terminal and the lines are edited.
+func (e *Editor) IsTerminal() bool {
+	return e.term != nil
+}
+
+// ReadLine method reads the line with the prompt. It returns io.EOF if the input
+// is closed or Ctrl-D is pressed on the empty line and ErrInterrupted
+// if Ctrl-C is pressed.
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	if e.term == nil {
+		line, err := e.in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	if err := e.term.makeRaw(); err != nil {
+		return "", err
+	}
+	defer e.term.restore()
+
+	s := &state{editor: e, prompt: []rune(prompt), historyPos: len(e.history)}
+	s.refresh()
+
+	return s.edit()
+}
+
+// AddHistory method adds the line to the history, empty lines and
+// repeats of the last line are skipped.
+func (e *Editor) AddHistory(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if n := len(e.history); n > 0 && e.history[n-1] == line {
+		return
+	}
+
+	e.history = append(e.history, line)
+	if len(e.history) > e.historyLimit {
+		e.history = e.history[len(e.history)-e.historyLimit:]
+	}
+}
+
+// History method returns the history entries, the oldest first.
+func (e *Editor) History() []string {
+	return append([]string(nil), e.history...)
+}
+
+// LoadHistory method reads the history entries, one per line.
+func (e *Editor) LoadHistory(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		e.AddHistory(scanner.Text())
+	}
+
+	return scanner.Err()
+}
+
+// SaveHistory method writes the history entries, one per line.
+func (e *Editor) SaveHistory(w io.Writer) error {
+	for _, line := range e.history {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// state is the state of the line being edited.
+type state struct {
+	editor *Editor
+	prompt []rune
+	line   []rune
+	pos    int
+
+	// historyPos is the index of the history entry being edited,
+	// it's equal to the length of the history for the new line
+	historyPos int
+
+	// pending keeps the new line while the history is browsed
+	pending []rune
+
+	// lastKeyTab is set if the previous key was Tab
+	lastKeyTab bool
+}
+
+// edit reads the keys until the line is submitted.
+func (s *state) edit() (string, error) {
+	for {
+		r, _, err := s.editor.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		isTab := r == keyTab
+		switch r {
+		case keyCR, keyLF:
+			s.write("\r\n")
+
+			return string(s.line), nil
+		case keyCtrlC:
+			s.write("^C\r\n")
+
+			return "", ErrInterrupted
+		case keyCtrlD:
+			if len(s.line) == 0 {
+				s.write("\r\n")
+
+				return "", io.EOF
+			}
+			s.deleteRune()
+		case keyTab:
+			s.completeWord()
+		case keyBackspace, keyCtrlH:
+			if s.pos > 0 {
+				s.pos--
+				s.deleteRune()
+			}
+		case keyCtrlA:
+			s.moveTo(0)
+		case keyCtrlE:
+			s.moveTo(len(s.line))
+		case keyCtrlB:
+			s.moveTo(s.pos - 1)
+		case keyCtrlF:
+			s.moveTo(s.pos + 1)
+		case keyCtrlK:
+			s.line = s.line[:s.pos]
+			s.refresh()
+		case keyCtrlU:
+			s.line = append([]rune(nil), s.line[s.pos:]...)
+			s.pos = 0
+			s.refresh()
+		case keyCtrlW:
+			s.deleteWord()
+		case keyCtrlL:
+			s.write("\x1b[H\x1b[2J")
+			s.refresh()
+		case keyCtrlP:
+			s.browseHistory(-1)
+		case keyCtrlN:
+			s.browseHistory(1)
+		case keyEscape:
+			if err := s.escape(); err != nil {
+				return "", err
+			}
+		default:
+			if unicode.IsPrint(r) {
+				s.insert([]rune{r})
+			}
+		}
+		s.lastKeyTab = isTab
+	}
+}
+
+// escape handles the escape sequence of the special keys.
+func (s *state) escape() error {
+	r, _, err := s.editor.in.ReadRune()
+	if err != nil {
+		return err
+	}
+	if r != '[' && r != 'O' {
+		return nil
+	}
+
+	// Read parameters up to the final byte of the sequence
+	var params []rune
+	for {
+		r, _, err = s.editor.in.ReadRune()
+		if err != nil {
+			return err
+		}
+		if r >= 0x40 && r <= 0x7e {
+			break
+		}
+		params = append(params, r)
+	}
+
+	switch {
+	case r == 'A':
+		s.browseHistory(-1)
+	case r == 'B':
+		s.browseHistory(1)
+	case r == 'C':
+		s.moveTo(s.pos + 1)
+	case r == 'D':
+		s.moveTo(s.pos - 1)
+	case r == 'H', r == '~' && (string(params) == "1" || string(params) == "7"):
+		s.moveTo(0)
+	case r == 'F', r == '~' && (string(params) == "4" || string(params) == "8"):
+		s.moveTo(len(s.line))
+	case r == '~' && string(params) == "3":
+		s.deleteRune()
+	}
+
+	return nil
+}
+
+// insert inserts the runes at the cursor.
+func (s *state) insert(runes []rune) {
+	line := make([]rune, 0, len(s.line)+len(runes))
+	line = append(line, s.line[:s.pos]...)
+	line = append(line, runes...)
+	s.line = append(line, s.line[s.pos:]...)
+	s.pos += len(runes)
+	s.refresh()
+}
+
+// deleteRune deletes the rune under the cursor.
+func (s *state) deleteRune() {
+	if s.pos >= len(s.line) {
+		return
+	}
+	s.line = append(s.line[:s.pos], s.line[s.pos+1:]...)
+	s.refresh()
+}
+
+// deleteWord deletes the word before the cursor.
+func (s *state) deleteWord() {
+	start := s.pos
+	for start > 0 && unicode.IsSpace(s.line[start-1]) {
+		start--
+	}
+	for start > 0 && !unicode.IsSpace(s.line[start-1]) {
+		start--
+	}
+	s.line = append(s.line[:start], s.line[s.pos:]...)
+	s.pos = start
+	s.refresh()
+}
+
+// moveTo moves the cursor to the position within the line.
+func (s *state) moveTo(pos int) {
+	if pos < 0 || pos > len(s.line) {
+		return
+	}
+	s.pos = pos
+	s.refresh()
+}
+
+// browseHistory replaces the line with the previous (-1) or the next (1) history entry.
+func (s *state) browseHistory(delta int) {
+	history := s.editor.history
+	pos := s.historyPos + delta
+	if pos < 0 || pos > len(history) {
+		s.write("\a")
+
+		return
+	}
+
+	if s.historyPos == len(history) {
+		s.pending = s.line
+	}
+	s.historyPos = pos
+	if pos == len(history) {
+		s.line = s.pending
+	} else {
+		s.line = []rune(history[pos])
+	}
+	s.pos = len(s.line)
+	s.refresh()
+}
+
+// completeWord completes the word before the cursor. The word is extended to the common
+// prefix of the candidates, the candidates are listed on the second Tab.
+func (s *state) completeWord() {
+	if s.editor.complete == nil {
+		return
+	}
+
+	word, candidates := s.editor.complete(string(s.line[:s.pos]))
+	switch len(candidates) {
+	case 0:
+		s.write("\a")
+	case 1:
+		s.insert(append([]rune(strings.TrimPrefix(candidates[0], word)), ' '))
+	default:
+		if prefix := commonPrefix(candidates); len(prefix) > len(word) {
+			s.insert([]rune(strings.TrimPrefix(prefix, word)))
+
+			return
+		}
+		if !s.lastKeyTab {
+			s.write("\a")
+
+			return
+		}
+		s.write("\r\n" + strings.Join(candidates, "  ") + "\r\n")
+		s.refresh()
+	}
+}
+
+// refresh redraws the line and places the cursor.
+func (s *state) refresh() {
+	var b strings.Builder
+	b.WriteString("\r")
+	b.WriteString(string(s.prompt))
+	b.WriteString(string(s.line))
+	b.WriteString("\x1b[K")
+	if n := len(s.line) - s.pos; n > 0 {
+		fmt.Fprintf(&b, "\x1b[%dD", n)
+	}
+	s.write(b.String())
+}
+
+func (s *state) write(str string) {
+	_, _ = io.WriteString(s.editor.out, str)
+}
+
+// commonPrefix returns the longest common prefix of the strings.
+func commonPrefix(strs []string) string {
+	prefix := []rune(strs[0])
+	for _, str := range strs[1:] {
+		runes := []rune(str)
+		n := 0
+		for n < len(prefix) && n < len(runes) && prefix[n] == runes[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+
+	return string(prefix)
+}
@@ -0,0 +1,178 @@
+package lineedit
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testTerminal counts the switches to the raw mode.
+type testTerminal struct {
+	raw int
+}
+
+func (t *testTerminal) makeRaw() error {
+	t.raw++
+
+	return nil
+}
+
+func (t *testTerminal) restore() {
+	t.raw--
+}
+
+// newTestEditor returns the editor that reads the keys as if they are typed in a terminal.
+func newTestEditor(keys string, complete Completer) (*Editor, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	e := New(Opts{In: strings.NewReader(keys), Out: out, Complete: complete})
+	e.term = &testTerminal{}
+
+	return e, out
+}
+
+func TestEditor_ReadLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     string
+		expected string
+	}{
+		{name: "plain", keys: "get key\r", expected: "get key"},
+		{name: "unicode", keys: "set ключ значение\r", expected: "set ключ значение"},
+		{name: "backspace", keys: "gett\x7f key\r", expected: "get key"},
+		{name: "arrows", keys: "gt key\x1b[D\x1b[D\x1b[D\x1b[D\x1b[De\x1b[C\x1b[C\x1b[C\x1b[C\x1b[C!\r", expected: "get key!"},
+		{name: "home and end", keys: "et\x1b[Hg\x1b[F key\r", expected: "get key"},
+		{name: "home and end keys", keys: "et\x1b[1~g\x1b[4~ key\r", expected: "get key"},
+		{name: "ctrl keys", keys: "et\x01g\x05 key\x02\x02\x02\x04\x06x\r", expected: "get exy"},
+		{name: "delete", keys: "gxet\x1b[D\x1b[D\x1b[D\x1b[3~\r", expected: "get"},
+		{name: "kill line", keys: "get key\x01\x06\x06\x06\x0b\r", expected: "get"},
+		{name: "kill line before cursor", keys: "xxx get\x01\x06\x06\x06\x06\x15\r", expected: "get"},
+		{name: "delete word", keys: "get key  \x17\r", expected: "get "},
+		{name: "control keys are ignored", keys: "get\x07\x1bx key\r", expected: "get key"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e, _ := newTestEditor(tc.keys, nil)
+			line, err := e.ReadLine("> ")
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, line)
+			require.Equal(t, 0, e.term.(*testTerminal).raw)
+		})
+	}
+}
+
+func TestEditor_ReadLine_Refresh(t *testing.T) {
+	e, out := newTestEditor("ab\x1b[D\r", nil)
+	line, err := e.ReadLine("> ")
+	require.NoError(t, err)
+	require.Equal(t, "ab", line)
+	require.Equal(t, "\r> \x1b[K\r> a\x1b[K\r> ab\x1b[K\r> ab\x1b[K\x1b[1D\r\n", out.String())
+}
+
+func TestEditor_ReadLine_Interrupt(t *testing.T) {
+	e, out := newTestEditor("get\x03\x04", nil)
+
+	_, err := e.ReadLine("> ")
+	require.Equal(t, ErrInterrupted, err)
+	require.True(t, strings.HasSuffix(out.String(), "^C\r\n"))
+
+	_, err = e.ReadLine("> ")
+	require.Equal(t, io.EOF, err)
+
+	_, err = e.ReadLine("> ")
+	require.Equal(t, io.EOF, err)
+}
+
+func TestEditor_History(t *testing.T) {
+	e, _ := newTestEditor("\x1b[A\x1b[A\r"+"new\x1b[A\x1b[B\r"+"\x10\x10\x10\x0e\r", nil)
+	require.NoError(t, e.LoadHistory(strings.NewReader("first\nsecond\n\nsecond\n")))
+	require.Equal(t, []string{"first", "second"}, e.History())
+
+	// Up arrow browses back
+	line, err := e.ReadLine("> ")
+	require.NoError(t, err)
+	require.Equal(t, "first", line)
+
+	// Down arrow returns the new line
+	line, err = e.ReadLine("> ")
+	require.NoError(t, err)
+	require.Equal(t, "new", line)
+
+	// Ctrl-P stops at the oldest entry
+	e.AddHistory(line)
+	line, err = e.ReadLine("> ")
+	require.NoError(t, err)
+	require.Equal(t, "second", line)
+
+	var saved bytes.Buffer
+	require.NoError(t, e.SaveHistory(&saved))
+	require.Equal(t, "first\nsecond\nnew\n", saved.String())
+}
+
+func TestEditor_HistoryLimit(t *testing.T) {
+	e := New(Opts{In: strings.NewReader(""), HistoryLimit: 2})
+	for _, line := range []string{"a", "b", "c"} {
+		e.AddHistory(line)
+	}
+	require.Equal(t, []string{"b", "c"}, e.History())
+}
+
+func TestEditor_Complete(t *testing.T) {
+	complete := func(line string) (string, []string) {
+		fields := strings.Split(line, " ")
+		word := fields[len(fields)-1]
+
+		var candidates []string
+		for _, c := range []string{"get", "geoadd", "geopos", "set"} {
+			if strings.HasPrefix(c, word) {
+				candidates = append(candidates, c)
+			}
+		}
+
+		return word, candidates
+	}
+
+	tests := []struct {
+		name     string
+		keys     string
+		expected string
+	}{
+		{name: "single candidate", keys: "s\tkey\r", expected: "set key"},
+		{name: "common prefix", keys: "g\t\r", expected: "ge"},
+		{name: "no candidates", keys: "x\t\r", expected: "x"},
+		{name: "in the middle", keys: " key\x01s\t\r", expected: "set  key"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e, _ := newTestEditor(tc.keys, complete)
+			line, err := e.ReadLine("> ")
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, line)
+		})
+	}
+
+	// Candidates are listed on the second Tab
+	e, out := newTestEditor("ge\t\t\r", complete)
+	line, err := e.ReadLine("> ")
+	require.NoError(t, err)
+	require.Equal(t, "ge", line)
+	require.Contains(t, out.String(), "\r\nget  geoadd  geopos\r\n")
+}
+
+func TestEditor_NotTerminal(t *testing.T) {
+	e := New(Opts{In: strings.NewReader("get key\r\nset key\nlast"), Out: &bytes.Buffer{}})
+	require.False(t, e.IsTerminal())
+
+	for _, expected := range []string{"get key", "set key", "last"} {
+		line, err := e.ReadLine("> ")
+		require.NoError(t, err)
+		require.Equal(t, expected, line)
+	}
+
+	_, err := e.ReadLine("> ")
+	require.Equal(t, io.EOF, err)
+}
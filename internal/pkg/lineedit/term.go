@@ -0,0 +1,7 @@
+package lineedit
+
+// terminal switches the terminal to the raw mode to read the keys.
+type terminal interface {
+	makeRaw() error
+	restore()
+}
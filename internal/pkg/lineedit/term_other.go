@@ -0,0 +1,19 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package lineedit
+
+// fdTerminal is not supported, the lines are read without editing.
+type fdTerminal struct {
+	fd int
+}
+
+func isTerminal(int) bool {
+	return false
+}
+
+func (t *fdTerminal) makeRaw() error {
+	return nil
+}
+
+func (t *fdTerminal) restore() {}
@@ -0,0 +1,46 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package lineedit
+
+import "golang.org/x/sys/unix"
+
+// fdTerminal is the terminal of the file descriptor.
+type fdTerminal struct {
+	fd    int
+	state *unix.Termios
+}
+
+// isTerminal returns true if the file descriptor is a terminal.
+func isTerminal(fd int) bool {
+	_, err := unix.IoctlGetTermios(fd, ioctlReadTermios)
+
+	return err == nil
+}
+
+// makeRaw method disables echo, canonical mode and signals of the terminal,
+// the output processing is kept.
+func (t *fdTerminal) makeRaw() error {
+	state, err := unix.IoctlGetTermios(t.fd, ioctlReadTermios)
+	if err != nil {
+		return err
+	}
+	t.state = state
+
+	raw := *state
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+
+	return unix.IoctlSetTermios(t.fd, ioctlWriteTermios, &raw)
+}
+
+// restore method restores the state of the terminal changed by makeRaw.
+func (t *fdTerminal) restore() {
+	if t.state != nil {
+		_ = unix.IoctlSetTermios(t.fd, ioctlWriteTermios, t.state)
+	}
+}
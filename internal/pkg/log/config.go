@@ -19,15 +19,20 @@ type InitLoggerOpts struct {
 	File      string
 	UseStdout bool
 	Debug     bool
+
+	// Level is used as the logger level if it's set, so the level could be
+	// changed at runtime. It's set according to Debug.
+	Level zap.AtomicLevel
 }
 
 // InitLogger initializes the Logger from the provided options.
 func InitLogger(opts InitLoggerOpts) (*zap.Logger, error) {
 	// Configure loglevel.
-	loglevel := zap.NewAtomicLevelAt(zap.InfoLevel)
-	if opts.Debug {
-		loglevel = zap.NewAtomicLevelAt(zap.DebugLevel)
+	loglevel := opts.Level
+	if loglevel == (zap.AtomicLevel{}) {
+		loglevel = zap.NewAtomicLevel()
 	}
+	loglevel.SetLevel(Level(opts.Debug))
 
 	// Configure output paths.
 	outputPaths, errPaths, err := outputConfig(
@@ -53,6 +58,15 @@ func InitLogger(opts InitLoggerOpts) (*zap.Logger, error) {
 	return logger, nil
 }
 
+// Level returns the logger level: debug if debug logging is enabled and info otherwise.
+func Level(debug bool) zapcore.Level {
+	if debug {
+		return zap.DebugLevel
+	}
+
+	return zap.InfoLevel
+}
+
 func outputConfig(file string, useStdout bool) ([]string, []string, error) {
 	var outputPaths []string
 	errPaths := []string{stderr}
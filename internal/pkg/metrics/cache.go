@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+)
+
+// CacheCollector returns collector of the cache metrics.
+func CacheCollector(c *qqcache.Cache) Collector {
+	return CollectorFunc(func(w *Writer) {
+		m := c.Metrics()
+
+		hits := make(map[string]float64, len(m.Hits))
+		for command, v := range m.Hits {
+			hits[command] = float64(v)
+		}
+		misses := make(map[string]float64, len(m.Misses))
+		for command, v := range m.Misses {
+			misses[command] = float64(v)
+		}
+		calls := make(map[string]float64, len(m.Calls))
+		for command, v := range m.Calls {
+			calls[command] = float64(v)
+		}
+		keys := make(map[string]float64, len(m.KeysByType))
+		for typ, v := range m.KeysByType {
+			keys[typ] = float64(v)
+		}
+
+		w.Map("bookish_spork_cache_commands_total", TypeCounter,
+			"Number of cache commands calls.", "command", calls)
+		w.Map("bookish_spork_cache_hits_total", TypeCounter,
+			"Number of cache lookups that found the value.", "command", hits)
+		w.Map("bookish_spork_cache_misses_total", TypeCounter,
+			"Number of cache lookups that did not find the value.", "command", misses)
+		w.Map("bookish_spork_cache_keys", TypeGauge,
+			"Number of not expired keys by value type.", "type", keys)
+		w.Counter("bookish_spork_cache_expired_keys_total",
+			"Number of expired keys dropped from cache by the cleaner or by writes to the same keys.",
+			float64(m.ExpiredKeys))
+		w.Counter("bookish_spork_cache_evicted_keys_total",
+			"Number of expired keys deleted by the cleaner.", float64(m.EvictedKeys))
+
+		w.Header("bookish_spork_cache_cleaner_round_duration_seconds", TypeSummary,
+			"Duration of the cache cleaner rounds.")
+		w.Sample("bookish_spork_cache_cleaner_round_duration_seconds_sum", m.CleanerDuration.Seconds())
+		w.Sample("bookish_spork_cache_cleaner_round_duration_seconds_count", float64(m.CleanerRounds))
+		w.Gauge("bookish_spork_cache_cleaner_last_round_duration_seconds",
+			"Duration of the last cache cleaner round.", m.LastCleanerDuration.Seconds())
+	})
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+	"github.com/dstdfx/bookish-spork/internal/pkg/replication"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheCollector(t *testing.T) {
+	c := qqcache.New(qqcache.Opts{EvictionInterval: time.Minute})
+	defer c.Shutdown()
+
+	c.Set("a", "b", 0)
+	c.Get("a")
+	c.Get("b")
+
+	r := NewRegistry()
+	r.Register(CacheCollector(c))
+	body := scrape(t, r)
+
+	require.Contains(t, body, "# TYPE bookish_spork_cache_hits_total counter\n")
+	require.Contains(t, body, `bookish_spork_cache_commands_total{command="get"} 2`)
+	require.Contains(t, body, `bookish_spork_cache_hits_total{command="get"} 1`)
+	require.Contains(t, body, `bookish_spork_cache_misses_total{command="get"} 1`)
+	require.Contains(t, body, `bookish_spork_cache_keys{type="value"} 1`)
+	require.Contains(t, body, "bookish_spork_cache_evicted_keys_total 0\n")
+	require.Contains(t, body, "bookish_spork_cache_cleaner_round_duration_seconds_count 0\n")
+}
+
+func TestRuntimeCollector(t *testing.T) {
+	r := NewRegistry()
+	r.Register(RuntimeCollector())
+	body := scrape(t, r)
+
+	require.Contains(t, body, "# TYPE go_goroutines gauge\n")
+	require.Contains(t, body, "go_memstats_heap_alloc_bytes ")
+}
+
+func TestThrottledCollector(t *testing.T) {
+	r := NewRegistry()
+	r.Register(ThrottledCollector(func() map[string]uint64 {
+		return map[string]uint64{"route:POST /v1/set": 2, "default": 1}
+	}))
+
+	require.Equal(t, `# HELP bookish_spork_http_throttled_requests_total Number of requests rejected by the rate limits.
+# TYPE bookish_spork_http_throttled_requests_total counter
+bookish_spork_http_throttled_requests_total{limit="default"} 1
+bookish_spork_http_throttled_requests_total{limit="route:POST /v1/set"} 2
+`, scrape(t, r))
+}
+
+func TestReplicationCollector(t *testing.T) {
+	lag, lastFrame := uint64(3), 0.5
+	status := replication.Status{Role: replication.RoleFollower, Offset: 42, State: replication.StateConnected,
+		LagOperations: &lag, LastFrameSeconds: &lastFrame, FullSyncs: 1, PartialSyncs: 3}
+	r := NewRegistry()
+	r.Register(ReplicationCollector(func() replication.Status { return status }))
+	body := scrape(t, r)
+
+	require.Contains(t, body, "bookish_spork_replication_offset 42\n")
+	require.Contains(t, body, "bookish_spork_replication_connected 1\n")
+	require.Contains(t, body, `bookish_spork_replication_syncs_total{mode="partial"} 3`)
+	require.Contains(t, body, "bookish_spork_replication_lag_operations 3\n")
+	require.Contains(t, body, "bookish_spork_replication_last_frame_seconds 0.5\n")
+
+	status = replication.Status{Role: replication.RoleLeader, Offset: 42, BacklogBytes: 100,
+		Followers: []replication.FollowerStatus{{Address: "127.0.0.1:1234"}}}
+	body = scrape(t, r)
+	require.Contains(t, body, "bookish_spork_replication_connected_followers 1\n")
+	require.Contains(t, body, "bookish_spork_replication_backlog_bytes 100\n")
+	require.NotContains(t, body, "bookish_spork_replication_lag_operations")
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/httputil"
+)
+
+// HTTPMetrics contains metrics of HTTP requests.
+type HTTPMetrics struct {
+	requests *CounterVec
+	duration *HistogramVec
+}
+
+// NewHTTPMetrics returns new instance of HTTPMetrics.
+func NewHTTPMetrics() *HTTPMetrics {
+	return &HTTPMetrics{
+		requests: NewCounterVec("bookish_spork_http_requests_total",
+			"Number of HTTP requests by route and status code.",
+			"method", "route", "status"),
+		duration: NewHistogramVec("bookish_spork_http_request_duration_seconds",
+			"Duration of HTTP requests by route and status code.", nil,
+			"method", "route", "status"),
+	}
+}
+
+// Collect writes HTTP requests metrics.
+func (m *HTTPMetrics) Collect(w *Writer) {
+	m.requests.Collect(w)
+	m.duration.Collect(w)
+}
+
+// Middleware returns middleware that counts requests and their duration
+// by route pattern and status code.
+func (m *HTTPMetrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		started := time.Now()
+		rw := httputil.NewResponseWriter(w)
+
+		next.ServeHTTP(rw, req)
+
+		route := httputil.Route(req)
+		status := strconv.Itoa(rw.Status())
+
+		m.requests.Inc(req.Method, route, status)
+		m.duration.Observe(time.Since(started).Seconds(), req.Method, route, status)
+	})
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPMetrics_Middleware(t *testing.T) {
+	m := NewHTTPMetrics()
+
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Route("/v1", func(r chi.Router) {
+		r.Mount("/", func() http.Handler {
+			sub := chi.NewRouter()
+			sub.Get("/get/{key}", func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte("ok"))
+			})
+			sub.Delete("/remove/{key}", func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			})
+
+			return sub
+		}())
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/v1/get/a", nil),
+		httptest.NewRequest(http.MethodGet, "/v1/get/b", nil),
+		httptest.NewRequest(http.MethodDelete, "/v1/remove/a", nil),
+		httptest.NewRequest(http.MethodGet, "/v1/unknown", nil),
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	registry := NewRegistry()
+	registry.Register(m)
+	body := scrape(t, registry)
+
+	require.Contains(t, body, `bookish_spork_http_requests_total{method="GET",route="/v1/get/{key}",status="200"} 2`)
+	require.Contains(t, body, `bookish_spork_http_requests_total{method="DELETE",route="/v1/remove/{key}",status="404"} 1`)
+	require.Contains(t, body, `bookish_spork_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	require.Contains(t, body,
+		`bookish_spork_http_request_duration_seconds_count{method="GET",route="/v1/get/{key}",status="200"} 2`)
+	require.Contains(t, body,
+		`bookish_spork_http_request_duration_seconds_bucket{method="GET",route="/v1/get/{key}",status="200",le="+Inf"} 2`)
+}
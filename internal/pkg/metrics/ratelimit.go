@@ -0,0 +1,15 @@
+package metrics
+
+// ThrottledCollector returns collector of the number of throttled requests
+// by the rate limits.
+func ThrottledCollector(throttled func() map[string]uint64) Collector {
+	return CollectorFunc(func(w *Writer) {
+		values := make(map[string]float64)
+		for limit, v := range throttled() {
+			values[limit] = float64(v)
+		}
+
+		w.Map("bookish_spork_http_throttled_requests_total", TypeCounter,
+			"Number of requests rejected by the rate limits.", "limit", values)
+	})
+}
@@ -0,0 +1,161 @@
+// Package metrics provides metrics of the service in the Prometheus text
+// exposition format.
+package metrics
+
+import (
+	"bufio"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Path is the path of metrics endpoint on the service API.
+const Path = "/metrics"
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Metric types.
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+	TypeSummary   = "summary"
+)
+
+// Collector writes metrics on every scrape.
+type Collector interface {
+	Collect(w *Writer)
+}
+
+// CollectorFunc is an adapter to use functions as collectors.
+type CollectorFunc func(w *Writer)
+
+// Collect calls f(w).
+func (f CollectorFunc) Collect(w *Writer) {
+	f(w)
+}
+
+// Registry contains collectors of the service.
+type Registry struct {
+	mux        sync.RWMutex
+	collectors []Collector
+}
+
+// NewRegistry returns new instance of Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds collectors to the registry.
+func (r *Registry) Register(collectors ...Collector) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.collectors = append(r.collectors, collectors...)
+}
+
+// ServeHTTP writes metrics of all registered collectors.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+
+	bw := bufio.NewWriter(w)
+	mw := &Writer{w: bw}
+
+	r.mux.RLock()
+	for _, c := range r.collectors {
+		c.Collect(mw)
+	}
+	r.mux.RUnlock()
+
+	_ = bw.Flush()
+}
+
+// Writer writes metrics in the text exposition format.
+type Writer struct {
+	w *bufio.Writer
+}
+
+// Header writes HELP and TYPE lines of the metric family,
+// it must be called once before the samples of the family.
+func (w *Writer) Header(name, typ, help string) {
+	w.w.WriteString("# HELP " + name + " " + escapeHelp(help) + "\n")
+	w.w.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+// Sample writes a sample of the metric, labels are pairs of names and values.
+func (w *Writer) Sample(name string, value float64, labels ...string) {
+	w.w.WriteString(name)
+	if len(labels) > 0 {
+		w.w.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				w.w.WriteByte(',')
+			}
+			w.w.WriteString(labels[i] + `="` + escapeLabelValue(labels[i+1]) + `"`)
+		}
+		w.w.WriteByte('}')
+	}
+	w.w.WriteByte(' ')
+	w.w.WriteString(formatFloat(value))
+	w.w.WriteByte('\n')
+}
+
+// Gauge writes a family with a single gauge sample.
+func (w *Writer) Gauge(name, help string, value float64) {
+	w.Header(name, TypeGauge, help)
+	w.Sample(name, value)
+}
+
+// Counter writes a family with a single counter sample.
+func (w *Writer) Counter(name, help string, value float64) {
+	w.Header(name, TypeCounter, help)
+	w.Sample(name, value)
+}
+
+// Map writes a family with a sample for every value of the label.
+// Samples are sorted by the label values.
+func (w *Writer) Map(name, typ, help, label string, values map[string]float64) {
+	w.Header(name, typ, help)
+	for _, k := range sortedKeys(values) {
+		w.Sample(name, values[k], label, k)
+	}
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var (
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelValueReplacer.Replace(s)
+}
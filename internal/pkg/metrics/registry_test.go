@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func scrape(t *testing.T, r *Registry) string {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, Path, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, contentType, w.Header().Get("Content-Type"))
+
+	return w.Body.String()
+}
+
+func TestRegistry(t *testing.T) {
+	counter := NewCounterVec("test_requests_total", "Number of requests.", "method", "path")
+	counter.Inc("GET", "/a")
+	counter.Add(2, "GET", "/a")
+	counter.Inc("POST", `/"b"`)
+
+	histogram := NewHistogramVec("test_duration_seconds", "Duration\nof requests.", []float64{1, 0.1}, "method")
+	histogram.Observe(0.05, "GET")
+	histogram.Observe(0.5, "GET")
+	histogram.Observe(5, "GET")
+
+	r := NewRegistry()
+	r.Register(counter, histogram, CollectorFunc(func(w *Writer) {
+		w.Gauge("test_up", "Test gauge.", 1)
+	}))
+
+	expected := `# HELP test_requests_total Number of requests.
+# TYPE test_requests_total counter
+test_requests_total{method="GET",path="/a"} 3
+test_requests_total{method="POST",path="/\"b\""} 1
+# HELP test_duration_seconds Duration\nof requests.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{method="GET",le="0.1"} 1
+test_duration_seconds_bucket{method="GET",le="1"} 2
+test_duration_seconds_bucket{method="GET",le="+Inf"} 3
+test_duration_seconds_sum{method="GET"} 5.55
+test_duration_seconds_count{method="GET"} 3
+# HELP test_up Test gauge.
+# TYPE test_up gauge
+test_up 1
+`
+	require.Equal(t, expected, scrape(t, r))
+}
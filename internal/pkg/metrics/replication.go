@@ -0,0 +1,42 @@
+package metrics
+
+import "github.com/dstdfx/bookish-spork/internal/pkg/replication"
+
+// ReplicationCollector returns collector of the replication metrics.
+func ReplicationCollector(status func() replication.Status) Collector {
+	return CollectorFunc(func(w *Writer) {
+		s := status()
+
+		w.Gauge("bookish_spork_replication_offset",
+			"Offset of the last write operation of the leader or applied by the follower.", float64(s.Offset))
+
+		if s.Role == replication.RoleLeader {
+			w.Gauge("bookish_spork_replication_connected_followers",
+				"Number of followers connected to the leader.", float64(len(s.Followers)))
+			w.Gauge("bookish_spork_replication_backlog_bytes",
+				"Size of the write operations kept for partial resync.", float64(s.BacklogBytes))
+
+			return
+		}
+
+		connected := 0.0
+		if s.State == replication.StateConnected {
+			connected = 1
+		}
+		w.Gauge("bookish_spork_replication_connected",
+			"Whether the follower is connected to the leader and has completed the sync.", connected)
+		w.Map("bookish_spork_replication_syncs_total", TypeCounter,
+			"Number of syncs of the follower with the leader.", "mode", map[string]float64{
+				replication.SyncFull:    float64(s.FullSyncs),
+				replication.SyncPartial: float64(s.PartialSyncs),
+			})
+		if s.LagOperations != nil {
+			w.Gauge("bookish_spork_replication_lag_operations",
+				"Number of the operations of the leader not applied by the follower yet.", float64(*s.LagOperations))
+		}
+		if s.LastFrameSeconds != nil {
+			w.Gauge("bookish_spork_replication_last_frame_seconds",
+				"Time since the follower has received the last frame from the leader.", *s.LastFrameSeconds)
+		}
+	})
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"runtime"
+	"time"
+)
+
+// RuntimeCollector returns collector of Go runtime metrics.
+func RuntimeCollector() Collector {
+	return CollectorFunc(func(w *Writer) {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+
+		w.Header("go_info", TypeGauge, "Information about the Go environment.")
+		w.Sample("go_info", 1, "version", runtime.Version())
+		w.Gauge("go_goroutines", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine()))
+
+		w.Gauge("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", float64(ms.Alloc))
+		w.Counter("go_memstats_alloc_bytes_total", "Total number of bytes allocated, even if freed.",
+			float64(ms.TotalAlloc))
+		w.Gauge("go_memstats_sys_bytes", "Number of bytes obtained from system.", float64(ms.Sys))
+		w.Counter("go_memstats_mallocs_total", "Total number of mallocs.", float64(ms.Mallocs))
+		w.Counter("go_memstats_frees_total", "Total number of frees.", float64(ms.Frees))
+		w.Gauge("go_memstats_heap_alloc_bytes", "Number of heap bytes allocated and still in use.",
+			float64(ms.HeapAlloc))
+		w.Gauge("go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", float64(ms.HeapInuse))
+		w.Gauge("go_memstats_heap_idle_bytes", "Number of heap bytes waiting to be used.", float64(ms.HeapIdle))
+		w.Gauge("go_memstats_heap_objects", "Number of allocated objects.", float64(ms.HeapObjects))
+		w.Gauge("go_memstats_stack_inuse_bytes", "Number of bytes in use by the stack allocator.",
+			float64(ms.StackInuse))
+		w.Gauge("go_memstats_next_gc_bytes", "Number of heap bytes when next garbage collection will take place.",
+			float64(ms.NextGC))
+		w.Gauge("go_memstats_last_gc_time_seconds", "Number of seconds since 1970 of last garbage collection.",
+			float64(ms.LastGC)/float64(time.Second))
+		w.Counter("go_gc_cycles_total", "Number of completed GC cycles.", float64(ms.NumGC))
+		w.Counter("go_gc_pause_seconds_total", "Total duration of GC stop-the-world pauses.",
+			float64(ms.PauseTotalNs)/float64(time.Second))
+	})
+}
@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the default histogram buckets in seconds.
+var DefaultBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// labelSep separates label values in the keys of the series.
+const labelSep = "\xff"
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mux    sync.Mutex
+	series map[string]float64
+}
+
+// NewCounterVec returns new instance of CounterVec.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		series: make(map[string]float64),
+	}
+}
+
+// Add adds the value to the counter with the label values.
+// Label values must be passed in the order of the label names.
+func (c *CounterVec) Add(value float64, labelValues ...string) {
+	key := strings.Join(labelValues, labelSep)
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.series[key] += value
+}
+
+// Inc increases the counter with the label values by 1.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Collect writes all series of the counter.
+func (c *CounterVec) Collect(w *Writer) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	w.Header(c.name, TypeCounter, c.help)
+	for _, key := range sortedKeys(c.series) {
+		w.Sample(c.name, c.series[key], labelPairs(c.labels, key)...)
+	}
+}
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mux    sync.Mutex
+	series map[string]*histogram
+}
+
+type histogram struct {
+	// counts contains non-cumulative counts by buckets
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec returns new instance of HistogramVec.
+// DefaultBuckets are used if buckets are empty.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	return &HistogramVec{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: sorted,
+		series:  make(map[string]*histogram),
+	}
+}
+
+// Observe adds the value to the histogram with the label values.
+// Label values must be passed in the order of the label names.
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := strings.Join(labelValues, labelSep)
+
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+
+	i := sort.SearchFloat64s(h.buckets, value)
+	if i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += value
+}
+
+// Collect writes all series of the histogram.
+func (h *HistogramVec) Collect(w *Writer) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	w.Header(h.name, TypeHistogram, h.help)
+	for _, key := range keys {
+		s := h.series[key]
+		labels := labelPairs(h.labels, key)
+
+		var cumulative uint64
+		for i, le := range h.buckets {
+			cumulative += s.counts[i]
+			w.Sample(h.name+"_bucket", float64(cumulative), append(labels, "le", formatFloat(le))...)
+		}
+		w.Sample(h.name+"_bucket", float64(s.count), append(labels, "le", "+Inf")...)
+		w.Sample(h.name+"_sum", s.sum, labels...)
+		w.Sample(h.name+"_count", float64(s.count), labels...)
+	}
+}
+
+// labelPairs returns pairs of label names and values of the series key.
+func labelPairs(names []string, key string) []string {
+	if len(names) == 0 {
+		return nil
+	}
+
+	values := strings.Split(key, labelSep)
+	pairs := make([]string, 0, 2*len(names))
+	for i, name := range names {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs = append(pairs, name, value)
+	}
+
+	return pairs
+}
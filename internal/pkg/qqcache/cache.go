@@ -18,58 +18,148 @@ var (
 type Opts struct {
 	// EvictionInterval is how often cache-cleaner will delete expired keys.
 	EvictionInterval time.Duration
+
+	// SlowLog contains the options of the slow log.
+	SlowLog SlowLogOpts
 }
 
 // Cache represents in-memory cache container.
+// Copies of Cache returned by WithClient and WithObserver share the same data.
 type Cache struct {
+	*store
+
+	// client is the name of the client reported by the slow log
+	client string
+
+	// observer is notified about executed commands
+	observer func(Operation)
+}
+
+// store contains the data of the cache.
+type store struct {
 	mux              sync.RWMutex
 	data             map[string]entity
 	evictionInterval time.Duration
 	stopCleaner      chan struct{}
+	resetCleaner     chan struct{}
+	watchers         watchers
+	counters         *counters
+	slowLog          *slowLog
+
+	// writeLog is called with every write operation, writeOffset
+	// is the offset of the last operation
+	writeLog    func(WriteOp)
+	writeOffset uint64
 }
 
 // New returns new instance of Cache.
 // If eviction interval is equal or less that 0 - default eviction will be used.
 func New(opts Opts) *Cache {
 	c := &Cache{
-		mux:              sync.RWMutex{},
-		data:             make(map[string]entity),
-		evictionInterval: opts.EvictionInterval,
-		stopCleaner:      make(chan struct{}),
+		store: &store{
+			mux:              sync.RWMutex{},
+			data:             make(map[string]entity),
+			evictionInterval: opts.EvictionInterval,
+			stopCleaner:      make(chan struct{}),
+			resetCleaner:     make(chan struct{}, 1),
+			counters:         newCounters(),
+			slowLog:          newSlowLog(opts.SlowLog),
+		},
 	}
 
 	// Run cache cleaner
-	go c.cacheCleaner()
+	go c.cacheCleaner(opts.EvictionInterval)
 
 	return c
 }
 
-// Shutdown stops cache cleaner.
+// WithClient method returns the cache that reports the given client name
+// in the slow log entries of its commands.
+func (c *Cache) WithClient(client string) *Cache {
+	return &Cache{store: c.store, client: client, observer: c.observer}
+}
+
+// Shutdown stops cache cleaner and all watchers.
 func (c *Cache) Shutdown() {
 	c.stopCleaner <- struct{}{}
+
+	c.watchers.mux.Lock()
+	defer c.watchers.mux.Unlock()
+
+	for w := range c.watchers.list {
+		c.watchers.remove(w)
+	}
 }
 
 // Set method sets value to cache by key with specific TTL.
 // If given TTL <=0 then the key will never be expired.
 func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
+	op := c.startOp(CommandSet, key, value)
+	defer op.end()
+
 	c.mux.Lock()
 	defer c.mux.Unlock()
 
-	c.data[key] = entity{
+	v, isExist := c.data[key]
+	c.countExpired(v, isExist)
+	e := entity{
 		value:        value,
 		expiredAfter: validateExpiredAfter(ttl),
 	}
+	c.data[key] = e
+	c.logWrite(WriteOp{
+		Command:  CommandSet,
+		Entry:    Entry{Key: key, Type: e.typeName(), Value: value},
+		ExpireAt: e.expiredAfter,
+	})
+	c.notify(EventSet, key)
+}
+
+// SetBytes method sets raw bytes value with its content type to cache by key
+// with specific TTL.
+// If given TTL <=0 then the key will never be expired.
+func (c *Cache) SetBytes(key string, value []byte, contentType string, ttl time.Duration) {
+	op := c.startOp(CommandSet, key, value)
+	defer op.end()
+
+	// Copy value to make sure it won't be changed by the caller
+	data := make([]byte, len(value))
+	copy(data, value)
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	v, isExist := c.data[key]
+	c.countExpired(v, isExist)
+	e := entity{
+		value:        data,
+		expiredAfter: validateExpiredAfter(ttl),
+		contentType:  contentType,
+	}
+	c.data[key] = e
+	c.logWrite(WriteOp{
+		Command:  CommandSet,
+		Entry:    Entry{Key: key, Type: TypeBytes, Value: data, ContentType: contentType},
+		ExpireAt: e.expiredAfter,
+	})
+	c.notify(EventSet, key)
 }
 
 // Get method returns value in cache by key.
 // The second param in return will indicate if value by key exists or not.
 func (c *Cache) Get(key string) (interface{}, bool) {
+	op := c.startOp(CommandGet, key, nil)
+	defer op.end()
+
 	c.mux.RLock()
 	defer c.mux.RUnlock()
 
 	// Look up for the value by key
 	v, isExist := c.data[key]
-	if isExist && !v.isExpired() {
+	isHit := isExist && !v.isExpired()
+	countLookup(&c.counters.getHits, &c.counters.getMisses, isHit)
+	op.lookup(isHit, v.value)
+	if isHit {
 		// If value exists and not expired return the value
 		return v.value, isExist
 	}
@@ -77,16 +167,56 @@ func (c *Cache) Get(key string) (interface{}, bool) {
 	return nil, false
 }
 
+// GetWithContentType method returns value in cache by key with its content type.
+// Content type is empty for the values that have not been set by SetBytes.
+// The third param in return will indicate if value by key exists or not.
+func (c *Cache) GetWithContentType(key string) (interface{}, string, bool) {
+	op := c.startOp(CommandGet, key, nil)
+	defer op.end()
+
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	v, isExist := c.data[key]
+	isHit := isExist && !v.isExpired()
+	countLookup(&c.counters.getHits, &c.counters.getMisses, isHit)
+	op.lookup(isHit, v.value)
+	if isHit {
+		return v.value, v.contentType, isExist
+	}
+
+	return nil, "", false
+}
+
 // Remove method removes the value in cache by key.
-func (c *Cache) Remove(key string) {
+// It returns true if the key existed and has not been expired.
+func (c *Cache) Remove(key string) bool {
+	op := c.startOp(CommandRemove, key, nil)
+	defer op.end()
+
 	c.mux.Lock()
 	defer c.mux.Unlock()
 
+	v, isExist := c.data[key]
+	c.countExpired(v, isExist)
 	delete(c.data, key)
+	if isExist {
+		c.logWrite(WriteOp{Command: CommandRemove, Entry: Entry{Key: key}})
+	}
+
+	removed := isExist && !v.isExpired()
+	if removed {
+		c.notify(EventRemove, key)
+	}
+
+	return removed
 }
 
 // Keys returns a list of all keys in cache.
 func (c *Cache) Keys() []string {
+	op := c.startOp(CommandKeys, "", nil)
+	defer op.end()
+
 	c.mux.RLock()
 	defer c.mux.RUnlock()
 
@@ -105,11 +235,16 @@ func (c *Cache) Keys() []string {
 // TTL param could be omitted if it's adding to the existing list.
 // If given TTL <=0 then the key will never be expired.
 func (c *Cache) RPush(key string, value interface{}, ttl time.Duration) error {
+	op := c.startOp(CommandRPush, key, value)
+	defer op.end()
+
 	c.mux.Lock()
 	defer c.mux.Unlock()
 
 	v, isExist := c.data[key]
 	if !isExist || v.isExpired() {
+		c.countExpired(v, isExist)
+
 		// Add new entity with list value
 		e := entity{
 			expiredAfter: validateExpiredAfter(ttl),
@@ -118,6 +253,12 @@ func (c *Cache) RPush(key string, value interface{}, ttl time.Duration) error {
 		list = append(list, value)
 		e.value = list
 		c.data[key] = e
+		c.logWrite(WriteOp{
+			Command:  CommandRPush,
+			Entry:    Entry{Key: key, Type: TypeList, Value: list},
+			ExpireAt: e.expiredAfter,
+		})
+		c.notify(EventSet, key)
 
 		return nil
 	}
@@ -132,6 +273,13 @@ func (c *Cache) RPush(key string, value interface{}, ttl time.Duration) error {
 	sl = append(sl, value)
 	v.value = sl
 	c.data[key] = v
+	c.logWrite(WriteOp{
+		Command:  CommandRPush,
+		Entry:    Entry{Key: key, Type: TypeList, Value: []interface{}{value}},
+		Merge:    true,
+		ExpireAt: v.expiredAfter,
+	})
+	c.notify(EventSet, key)
 
 	return nil
 }
@@ -141,6 +289,18 @@ func (c *Cache) RPush(key string, value interface{}, ttl time.Duration) error {
 // When the value at key is not a list, an error is returned.
 // When index is not exist in the list - nil value is returned.
 func (c *Cache) LIndex(key string, index int) (interface{}, error) {
+	v, _, err := c.LIndexLookup(key, index)
+
+	return v, err
+}
+
+// LIndexLookup method works like LIndex, the second param in return
+// will indicate if the element exists, so nil elements could be told
+// from the missing ones.
+func (c *Cache) LIndexLookup(key string, index int) (interface{}, bool, error) {
+	op := c.startOp(CommandLIndex, key, nil)
+	defer op.end()
+
 	c.mux.RLock()
 	defer c.mux.RUnlock()
 
@@ -149,18 +309,25 @@ func (c *Cache) LIndex(key string, index int) (interface{}, error) {
 		// Check if type is slice
 		sl, ok := v.value.([]interface{})
 		if !ok {
-			return nil, ErrWrongTypeIndex
+			return nil, false, ErrWrongTypeIndex
 		}
 
 		// Check if index is exist and return nil value if it's not
 		if len(sl)-1 < index {
-			return nil, nil
+			countLookup(&c.counters.lindexHits, &c.counters.lindexMisses, false)
+			op.lookup(false, nil)
+
+			return nil, false, nil
 		}
+		countLookup(&c.counters.lindexHits, &c.counters.lindexMisses, true)
+		op.lookup(true, sl[index])
 
-		return sl[index], nil
+		return sl[index], true, nil
 	}
+	countLookup(&c.counters.lindexHits, &c.counters.lindexMisses, false)
+	op.lookup(false, nil)
 
-	return nil, ErrNotFound
+	return nil, false, ErrNotFound
 }
 
 // HSet method sets value in the hash stored at key to value.
@@ -168,17 +335,28 @@ func (c *Cache) LIndex(key string, index int) (interface{}, error) {
 // If field already exists in the hash, it is overwritten.
 // TTL param could be omitted if it's adding to the existing hash map.
 func (c *Cache) HSet(key string, value map[string]interface{}, ttl time.Duration) error {
+	op := c.startOp(CommandHSet, key, value)
+	defer op.end()
+
 	c.mux.Lock()
 	defer c.mux.Unlock()
 
 	v, isExist := c.data[key]
 	if !isExist || v.isExpired() {
+		c.countExpired(v, isExist)
+
 		// Add new entity with hm value
 		e := entity{
 			value:        value,
 			expiredAfter: validateExpiredAfter(ttl),
 		}
 		c.data[key] = e
+		c.logWrite(WriteOp{
+			Command:  CommandHSet,
+			Entry:    Entry{Key: key, Type: TypeHash, Value: value},
+			ExpireAt: e.expiredAfter,
+		})
+		c.notify(EventSet, key)
 
 		return nil
 	}
@@ -202,6 +380,13 @@ func (c *Cache) HSet(key string, value map[string]interface{}, ttl time.Duration
 	// Update entity in cache
 	v.value = hm
 	c.data[key] = v
+	c.logWrite(WriteOp{
+		Command:  CommandHSet,
+		Entry:    Entry{Key: key, Type: TypeHash, Value: value},
+		Merge:    true,
+		ExpireAt: v.expiredAfter,
+	})
+	c.notify(EventSet, key)
 
 	return nil
 }
@@ -210,6 +395,18 @@ func (c *Cache) HSet(key string, value map[string]interface{}, ttl time.Duration
 // When the value at key is not a hash map, an error is returned.
 // When key in hash map value is not exist - nil value is returned.
 func (c *Cache) HGet(key, hkey string) (interface{}, error) {
+	v, _, err := c.HGetLookup(key, hkey)
+
+	return v, err
+}
+
+// HGetLookup method works like HGet, the second param in return
+// will indicate if the field exists, so nil values could be told
+// from the missing fields.
+func (c *Cache) HGetLookup(key, hkey string) (interface{}, bool, error) {
+	op := c.startOp(CommandHGet, key, nil)
+	defer op.end()
+
 	c.mux.RLock()
 	defer c.mux.RUnlock()
 
@@ -218,13 +415,39 @@ func (c *Cache) HGet(key, hkey string) (interface{}, error) {
 		// Check if type is map
 		hm, ok := v.value.(map[string]interface{})
 		if !ok {
-			return nil, ErrWrongTypeHGet
+			return nil, false, ErrWrongTypeHGet
 		}
+		value, isHit := hm[hkey]
+		countLookup(&c.counters.hgetHits, &c.counters.hgetMisses, isHit)
+		op.lookup(isHit, value)
+
+		return value, isHit, nil
+	}
+	countLookup(&c.counters.hgetHits, &c.counters.hgetMisses, false)
+	op.lookup(false, nil)
+
+	return nil, false, ErrNotFound
+}
 
-		return hm[hkey], nil
+// TTL method returns the remaining time to live of the key.
+// TTL is 0 if the key will never be expired.
+// The second param in return will indicate if value by key exists or not.
+func (c *Cache) TTL(key string) (time.Duration, bool) {
+	op := c.startOp(CommandTTL, key, nil)
+	defer op.end()
+
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	v, isExist := c.data[key]
+	if !isExist || v.isExpired() {
+		return 0, false
+	}
+	if v.expiredAfter <= 0 {
+		return 0, true
 	}
 
-	return nil, ErrNotFound
+	return time.Duration(v.expiredAfter - time.Now().UTC().UnixNano()), true
 }
 
 func validateExpiredAfter(ttl time.Duration) int64 {
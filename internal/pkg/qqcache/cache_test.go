@@ -75,6 +75,31 @@ func TestCache_SetNil(t *testing.T) {
 	require.Nil(t, got)
 }
 
+func TestCache_SetBytes(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	value := []byte{0x00, 0xff, 0x10}
+
+	// Set raw value to the cache
+	c.SetBytes(testKey, value, "application/octet-stream", 0)
+
+	// Make sure the stored value is not affected by the caller
+	value[0] = 0x01
+
+	got, contentType, ok := c.GetWithContentType(testKey)
+	require.True(t, ok)
+	require.Equal(t, "application/octet-stream", contentType)
+	require.Equal(t, []byte{0x00, 0xff, 0x10}, got)
+
+	// Overwrite with the regular value resets content type
+	c.Set(testKey, testValue, 0)
+	got, contentType, ok = c.GetWithContentType(testKey)
+	require.True(t, ok)
+	require.Empty(t, contentType)
+	require.Equal(t, testValue, got)
+}
+
 func TestCache_Remove(t *testing.T) {
 	c := New(getCommonCacheOpts())
 	defer c.Shutdown()
@@ -83,7 +108,7 @@ func TestCache_Remove(t *testing.T) {
 	c.Set(testKey, testValue, time.Second)
 
 	// Remove value from the cache
-	c.Remove(testKey)
+	require.True(t, c.Remove(testKey))
 
 	// Try to get deleted value by the key from the cache
 	got, ok := c.Get(testKey)
@@ -96,7 +121,7 @@ func TestCache_Remove_NoKey(t *testing.T) {
 	defer c.Shutdown()
 
 	// Remove value from the cache
-	c.Remove(testKey)
+	require.False(t, c.Remove(testKey))
 }
 
 func TestCache_Keys(t *testing.T) {
@@ -422,6 +447,30 @@ func TestCache_HGet_WrongType(t *testing.T) {
 	require.Nil(t, got)
 }
 
+func TestCache_TTL(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	c.Set(testKey, testValue, time.Minute)
+	c.Set("persistent", testValue, 0)
+	c.Set("expired", testValue, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	ttl, ok := c.TTL(testKey)
+	require.True(t, ok)
+	require.True(t, ttl > 59*time.Second && ttl <= time.Minute)
+
+	ttl, ok = c.TTL("persistent")
+	require.True(t, ok)
+	require.Equal(t, time.Duration(0), ttl)
+
+	_, ok = c.TTL("expired")
+	require.False(t, ok)
+
+	_, ok = c.TTL("missing")
+	require.False(t, ok)
+}
+
 func BenchmarkCacheGetExpiring(b *testing.B) {
 	benchmarkCacheGet(b, 30*time.Second, 10*time.Second)
 }
@@ -477,3 +526,26 @@ func BenchmarkCacheSetRemove(b *testing.B) {
 		tc.Remove("foo")
 	}
 }
+
+func TestCache_Lookup_NilValues(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	require.NoError(t, c.RPush(testKey, nil, 0))
+	v, isExist, err := c.LIndexLookup(testKey, 0)
+	require.NoError(t, err)
+	require.True(t, isExist)
+	require.Nil(t, v)
+	_, isExist, err = c.LIndexLookup(testKey, 1)
+	require.NoError(t, err)
+	require.False(t, isExist)
+
+	require.NoError(t, c.HSet("hash", map[string]interface{}{"field": nil}, 0))
+	v, isExist, err = c.HGetLookup("hash", "field")
+	require.NoError(t, err)
+	require.True(t, isExist)
+	require.Nil(t, v)
+	_, isExist, err = c.HGetLookup("hash", "unknown")
+	require.NoError(t, err)
+	require.False(t, isExist)
+}
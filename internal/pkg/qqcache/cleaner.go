@@ -4,20 +4,45 @@ import "time"
 
 // cacheCleaner runs cleaner that will delete expired keys within each
 // eviction interval.
-func (c *Cache) cacheCleaner() {
-	t := time.NewTicker(c.evictionInterval)
+func (c *Cache) cacheCleaner(interval time.Duration) {
+	t := time.NewTicker(interval)
 	defer t.Stop()
 
 	for {
 		select {
 		case <-t.C:
 			c.cleanerRound()
+		case <-c.resetCleaner:
+			c.mux.RLock()
+			interval = c.evictionInterval
+			c.mux.RUnlock()
+
+			t.Stop()
+			t = time.NewTicker(interval)
 		case <-c.stopCleaner:
 			return
 		}
 	}
 }
 
+// SetEvictionInterval method changes the eviction interval of the running cleaner,
+// the next round starts after the new interval. Non-positive intervals are ignored.
+func (c *Cache) SetEvictionInterval(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	c.mux.Lock()
+	c.evictionInterval = interval
+	c.mux.Unlock()
+
+	// The cleaner reads the latest interval, so pending reset is enough
+	select {
+	case c.resetCleaner <- struct{}{}:
+	default:
+	}
+}
+
 func (c *Cache) cleanerRound() {
 	c.mux.Lock()
 	defer c.mux.Unlock()
@@ -27,15 +52,15 @@ func (c *Cache) cleanerRound() {
 		return
 	}
 
+	started := time.Now()
+
 	// Get a slice of expired keys
 	expiredKeys := c.getExpiredKeys()
-	if len(expiredKeys) == 0 {
-		// Skip if there's no keys to delete
-		return
-	}
 
 	// Delete expired keys from the cache
 	c.deleteExpiredKeys(expiredKeys)
+
+	c.countCleanerRound(len(expiredKeys), time.Since(started))
 }
 
 // getExpiredKeys method returns all expired keys in cache.
@@ -54,5 +79,7 @@ func (c *Cache) getExpiredKeys() []string {
 func (c *Cache) deleteExpiredKeys(expiredKeys []string) {
 	for _, k := range expiredKeys {
 		delete(c.data, k)
+		c.logWrite(WriteOp{Command: CommandExpire, Entry: Entry{Key: k}})
+		c.notify(EventExpire, k)
 	}
 }
@@ -23,3 +23,22 @@ func TestRunCleaner(t *testing.T) {
 	_, ok := c.data[testKey]
 	require.False(t, ok)
 }
+
+func TestSetEvictionInterval(t *testing.T) {
+	c := New(Opts{EvictionInterval: time.Hour})
+	defer c.Shutdown()
+
+	c.Set(testKey, testValue, 10*time.Millisecond)
+
+	// Non-positive intervals are ignored
+	c.SetEvictionInterval(0)
+	require.Equal(t, time.Hour, c.Stats().EvictionInterval)
+
+	c.SetEvictionInterval(20 * time.Millisecond)
+	require.Equal(t, 20*time.Millisecond, c.Stats().EvictionInterval)
+
+	// Check that key has been deleted by cache cleaner with the new interval
+	require.Eventually(t, func() bool {
+		return c.Metrics().EvictedKeys == 1
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,252 @@
+package qqcache
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// Modes of restoring the keys that already exist in cache.
+const (
+	RestoreSkip    = "skip"
+	RestoreReplace = "replace"
+	RestoreMerge   = "merge"
+)
+
+var (
+	ErrInvalidRestoreMode = errors.New("unsupported restore mode, use one of: skip, replace, merge")
+	ErrInvalidEntry       = errors.New("unsupported type of the entry or its value doesn't match the type")
+)
+
+// Entry represents a key with its value and remaining TTL exported by Dump
+// and imported by Restore.
+type Entry struct {
+	Key  string
+	Type string
+
+	// Value is []byte for TypeBytes, []interface{} for TypeList,
+	// map[string]interface{} for TypeHash and []GeoMember for TypeGeo.
+	Value interface{}
+
+	// ContentType is set only for TypeBytes values.
+	ContentType string
+
+	// TTL is the remaining time to live, the key will never be expired if it's 0.
+	TTL time.Duration
+}
+
+// Dump method calls fn for every not expired key in cache ordered by key.
+// The cache is not locked while fn is called, so every key is copied
+// at the moment it's dumped and the keys added after Dump is called are skipped.
+// Dump stops and returns the error returned by fn.
+func (c *Cache) Dump(fn func(Entry) error) error {
+	c.countCall(CommandDump)
+
+	c.mux.RLock()
+	keys := make([]string, 0, len(c.data))
+	for k, v := range c.data {
+		if !v.isExpired() {
+			keys = append(keys, k)
+		}
+	}
+	c.mux.RUnlock()
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		e, isExist := c.dumpEntry(key)
+		if !isExist {
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dumpEntry method returns the copy of the key.
+// The second param in return will indicate if value by key exists or not.
+func (c *Cache) dumpEntry(key string) (Entry, bool) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	v, isExist := c.data[key]
+	if !isExist || v.isExpired() {
+		return Entry{}, false
+	}
+
+	e := copyEntry(key, v)
+	if v.expiredAfter > 0 {
+		e.TTL = time.Duration(v.expiredAfter - time.Now().UTC().UnixNano())
+		if e.TTL <= 0 {
+			return Entry{}, false
+		}
+	}
+
+	return e, true
+}
+
+// copyEntry returns the entry with the copy of the value of the entity, TTL is not set.
+func copyEntry(key string, v entity) Entry {
+	e := Entry{Key: key, Type: v.typeName(), ContentType: v.contentType}
+
+	// Lists and hashes are copied as they are modified in place
+	switch value := v.value.(type) {
+	case []interface{}:
+		e.Value = append(make([]interface{}, 0, len(value)), value...)
+	case map[string]interface{}:
+		hm := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			hm[k] = v
+		}
+		e.Value = hm
+	case *sortedSet:
+		members := make([]GeoMember, 0, value.len())
+		for _, item := range value.items {
+			lon, lat := geohashDecode(uint64(item.score))
+			members = append(members, GeoMember{Name: item.member, GeoPoint: GeoPoint{Longitude: lon, Latitude: lat}})
+		}
+		e.Value = members
+	default:
+		e.Value = value
+	}
+
+	return e
+}
+
+// Restore method sets the key of the entry to its value with its TTL.
+// The keys that already exist in cache are handled according to the mode:
+// RestoreSkip keeps them, RestoreReplace replaces them and RestoreMerge appends
+// the items to the lists, sets the fields of the hashes and adds the members
+// to the geo indexes keeping TTL of the existing keys, other values and
+// the keys of different types are replaced.
+// It returns false if the key has been skipped.
+func (c *Cache) Restore(e Entry, mode string) (bool, error) {
+	op := c.startOp(CommandRestore, e.Key, e.Value)
+	defer op.end()
+
+	switch mode {
+	case RestoreSkip, RestoreReplace, RestoreMerge:
+	default:
+		return false, ErrInvalidRestoreMode
+	}
+
+	value, err := restoreValue(e)
+	if err != nil {
+		return false, err
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	v, isExist := c.data[e.Key]
+	switch {
+	case !isExist || v.isExpired():
+		c.countExpired(v, isExist)
+	case mode == RestoreSkip:
+		return false, nil
+	case mode == RestoreMerge && v.merge(value):
+		c.data[e.Key] = v
+		c.logWrite(WriteOp{Command: CommandRestore, Entry: restoreEntry(e), Merge: true, ExpireAt: v.expiredAfter})
+		c.notify(EventSet, e.Key)
+
+		return true, nil
+	}
+
+	v = entity{value: value, expiredAfter: validateExpiredAfter(e.TTL)}
+	if e.Type == TypeBytes {
+		v.contentType = e.ContentType
+	}
+	c.data[e.Key] = v
+	c.logWrite(WriteOp{Command: CommandRestore, Entry: restoreEntry(e), ExpireAt: v.expiredAfter})
+	c.notify(EventSet, e.Key)
+
+	return true, nil
+}
+
+// restoreEntry returns the entry of the write operation of Restore.
+func restoreEntry(e Entry) Entry {
+	e.TTL = 0
+	if e.Type != TypeBytes {
+		e.ContentType = ""
+	}
+
+	return e
+}
+
+// restoreValue returns the value of the entry to store in cache.
+func restoreValue(e Entry) (interface{}, error) {
+	switch e.Type {
+	case TypeValue:
+		return e.Value, nil
+	case TypeBytes:
+		if data, ok := e.Value.([]byte); ok {
+			return append(make([]byte, 0, len(data)), data...), nil
+		}
+	case TypeList:
+		if list, ok := e.Value.([]interface{}); ok {
+			return append(make([]interface{}, 0, len(list)), list...), nil
+		}
+	case TypeHash:
+		if hm, ok := e.Value.(map[string]interface{}); ok {
+			value := make(map[string]interface{}, len(hm))
+			for k, v := range hm {
+				value[k] = v
+			}
+
+			return value, nil
+		}
+	case TypeGeo:
+		if members, ok := e.Value.([]GeoMember); ok {
+			set := newSortedSet()
+			for _, m := range members {
+				if !isValidGeoPoint(m.Longitude, m.Latitude) {
+					return nil, ErrInvalidGeoCoordinates
+				}
+				set.add(m.Name, float64(geohashEncode(m.Longitude, m.Latitude)))
+			}
+
+			return set, nil
+		}
+	}
+
+	return nil, ErrInvalidEntry
+}
+
+// merge method merges the value into the value of the entity if both are
+// lists, hashes or geo indexes. It returns false if the values can't be merged.
+func (e *entity) merge(value interface{}) bool {
+	switch existing := e.value.(type) {
+	case []interface{}:
+		list, ok := value.([]interface{})
+		if !ok {
+			return false
+		}
+		e.value = append(existing, list...)
+	case map[string]interface{}:
+		hm, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if existing == nil {
+			existing = make(map[string]interface{}, len(hm))
+		}
+		for k, v := range hm {
+			existing[k] = v
+		}
+		e.value = existing
+	case *sortedSet:
+		set, ok := value.(*sortedSet)
+		if !ok {
+			return false
+		}
+		for _, item := range set.items {
+			existing.add(item.member, item.score)
+		}
+	default:
+		return false
+	}
+
+	return true
+}
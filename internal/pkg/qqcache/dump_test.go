@@ -0,0 +1,182 @@
+package qqcache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func dumpAll(t *testing.T, c *Cache) []Entry {
+	var entries []Entry
+	require.NoError(t, c.Dump(func(e Entry) error {
+		entries = append(entries, e)
+
+		return nil
+	}))
+
+	return entries
+}
+
+func TestCache_Dump(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	c.Set("value", "some-value", time.Hour)
+	c.SetBytes("bytes", []byte{0, 1, 2}, "application/octet-stream", 0)
+	require.NoError(t, c.RPush("list", "item", 0))
+	require.NoError(t, c.HSet("hash", map[string]interface{}{"field": "value"}, 0))
+	_, err := c.GeoAdd("geo", getTestGeoMembers()[:1], 0)
+	require.NoError(t, err)
+	c.Set("expired", "value", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	entries := dumpAll(t, c)
+	require.Len(t, entries, 5)
+	require.Equal(t, []string{"bytes", "geo", "hash", "list", "value"},
+		[]string{entries[0].Key, entries[1].Key, entries[2].Key, entries[3].Key, entries[4].Key})
+
+	require.Equal(t, Entry{Key: "bytes", Type: TypeBytes, Value: []byte{0, 1, 2},
+		ContentType: "application/octet-stream"}, entries[0])
+	require.Equal(t, TypeGeo, entries[1].Type)
+	require.Equal(t, Entry{Key: "hash", Type: TypeHash, Value: map[string]interface{}{"field": "value"}}, entries[2])
+	require.Equal(t, Entry{Key: "list", Type: TypeList, Value: []interface{}{"item"}}, entries[3])
+	require.Equal(t, "some-value", entries[4].Value)
+	require.Equal(t, TypeValue, entries[4].Type)
+	require.True(t, entries[4].TTL > 59*time.Minute && entries[4].TTL <= time.Hour)
+
+	// Dumped values are copies
+	require.NoError(t, c.HSet("hash", map[string]interface{}{"other": "value"}, 0))
+	require.Len(t, entries[2].Value, 1)
+
+	// Dump stops on error
+	errStop := errors.New("stop")
+	calls := 0
+	err = c.Dump(func(Entry) error {
+		calls++
+
+		return errStop
+	})
+	require.Equal(t, errStop, err)
+	require.Equal(t, 1, calls)
+}
+
+func TestCache_Restore(t *testing.T) {
+	src := New(getCommonCacheOpts())
+	defer src.Shutdown()
+
+	src.Set("value", "some-value", time.Hour)
+	src.SetBytes("bytes", []byte{0, 1, 2}, "image/png", 0)
+	require.NoError(t, src.RPush("list", "item", 0))
+	require.NoError(t, src.HSet("hash", map[string]interface{}{"field": "value"}, 0))
+	_, err := src.GeoAdd("geo", getTestGeoMembers(), 0)
+	require.NoError(t, err)
+
+	dst := New(getCommonCacheOpts())
+	defer dst.Shutdown()
+
+	for _, e := range dumpAll(t, src) {
+		restored, err := dst.Restore(e, RestoreReplace)
+		require.NoError(t, err)
+		require.True(t, restored)
+	}
+	require.Equal(t, dumpAll(t, src)[:4], dumpAll(t, dst)[:4])
+
+	value, contentType, _ := dst.GetWithContentType("bytes")
+	require.Equal(t, []byte{0, 1, 2}, value)
+	require.Equal(t, "image/png", contentType)
+
+	ttl, _ := dst.TTL("value")
+	require.True(t, ttl > 59*time.Minute && ttl <= time.Hour)
+
+	// Positions of the geo members are kept
+	positions, err := src.GeoPos("geo", "Palermo", "Catania", "Rome")
+	require.NoError(t, err)
+	restoredPositions, err := dst.GeoPos("geo", "Palermo", "Catania", "Rome")
+	require.NoError(t, err)
+	require.Equal(t, positions, restoredPositions)
+}
+
+func TestCache_Restore_Modes(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	c.Set("value", "old", 0)
+	require.NoError(t, c.RPush("list", "a", time.Hour))
+	require.NoError(t, c.HSet("hash", map[string]interface{}{"a": "1", "b": "1"}, 0))
+	_, err := c.GeoAdd("geo", getTestGeoMembers()[:1], 0)
+	require.NoError(t, err)
+
+	// Existing keys are kept
+	restored, err := c.Restore(Entry{Key: "value", Type: TypeValue, Value: "new"}, RestoreSkip)
+	require.NoError(t, err)
+	require.False(t, restored)
+	value, _ := c.Get("value")
+	require.Equal(t, "old", value)
+
+	// New keys are restored in any mode
+	restored, err = c.Restore(Entry{Key: "new", Type: TypeValue, Value: "new"}, RestoreSkip)
+	require.NoError(t, err)
+	require.True(t, restored)
+
+	// Containers are merged, TTL of the existing keys is kept
+	_, err = c.Restore(Entry{Key: "list", Type: TypeList, Value: []interface{}{"b"}}, RestoreMerge)
+	require.NoError(t, err)
+	value, _ = c.Get("list")
+	require.Equal(t, []interface{}{"a", "b"}, value)
+	ttl, _ := c.TTL("list")
+	require.NotZero(t, ttl)
+
+	_, err = c.Restore(Entry{Key: "hash", Type: TypeHash, Value: map[string]interface{}{"b": "2", "c": "2"}}, RestoreMerge)
+	require.NoError(t, err)
+	value, _ = c.Get("hash")
+	require.Equal(t, map[string]interface{}{"a": "1", "b": "2", "c": "2"}, value)
+
+	_, err = c.Restore(Entry{Key: "geo", Type: TypeGeo, Value: getTestGeoMembers()[1:]}, RestoreMerge)
+	require.NoError(t, err)
+	positions, err := c.GeoPos("geo", "Palermo", "Catania", "Rome")
+	require.NoError(t, err)
+	require.NotContains(t, positions, (*GeoPoint)(nil))
+
+	// Other values and different types are replaced
+	_, err = c.Restore(Entry{Key: "value", Type: TypeValue, Value: "new"}, RestoreMerge)
+	require.NoError(t, err)
+	value, _ = c.Get("value")
+	require.Equal(t, "new", value)
+
+	_, err = c.Restore(Entry{Key: "list", Type: TypeHash, Value: map[string]interface{}{}, TTL: time.Hour}, RestoreMerge)
+	require.NoError(t, err)
+	value, _ = c.Get("list")
+	require.Equal(t, map[string]interface{}{}, value)
+
+	_, err = c.Restore(Entry{Key: "hash", Type: TypeList, Value: []interface{}{"x"}}, RestoreReplace)
+	require.NoError(t, err)
+	value, _ = c.Get("hash")
+	require.Equal(t, []interface{}{"x"}, value)
+}
+
+func TestCache_Restore_Invalid(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	_, err := c.Restore(Entry{Key: "key", Type: TypeValue, Value: "value"}, "unknown")
+	require.Equal(t, ErrInvalidRestoreMode, err)
+
+	for _, e := range []Entry{
+		{Key: "key", Type: "unknown", Value: "value"},
+		{Key: "key", Type: TypeBytes, Value: "value"},
+		{Key: "key", Type: TypeList, Value: "value"},
+		{Key: "key", Type: TypeHash, Value: []interface{}{}},
+		{Key: "key", Type: TypeGeo, Value: "value"},
+	} {
+		_, err = c.Restore(e, RestoreReplace)
+		require.Equal(t, ErrInvalidEntry, err)
+	}
+
+	_, err = c.Restore(Entry{Key: "key", Type: TypeGeo, Value: []GeoMember{
+		{Name: "member", GeoPoint: GeoPoint{Longitude: 200}},
+	}}, RestoreReplace)
+	require.Equal(t, ErrInvalidGeoCoordinates, err)
+	require.Empty(t, c.Keys())
+}
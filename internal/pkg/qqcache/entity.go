@@ -6,6 +6,25 @@ import "time"
 type entity struct {
 	value        interface{}
 	expiredAfter int64
+
+	// contentType is set only for raw bytes values.
+	contentType string
+}
+
+// typeName method returns the type of the value.
+func (e entity) typeName() string {
+	switch e.value.(type) {
+	case []byte:
+		return TypeBytes
+	case []interface{}:
+		return TypeList
+	case map[string]interface{}:
+		return TypeHash
+	case *sortedSet:
+		return TypeGeo
+	default:
+		return TypeValue
+	}
 }
 
 // isExpired method returns true if the value is expired.
@@ -0,0 +1,357 @@
+package qqcache
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+var (
+	ErrWrongTypeGeo          = errors.New("wrong type of the value to perform geo operation")
+	ErrInvalidGeoCoordinates = errors.New("invalid longitude or latitude")
+	ErrInvalidGeoUnit        = errors.New("unsupported unit, use one of: m, km, mi, ft")
+	ErrInvalidGeoShape       = errors.New("exactly one of radius or width and height must be positive")
+	ErrInvalidGeoCenter      = errors.New("exactly one of center member or center coordinates must be provided")
+	ErrInvalidGeoSort        = errors.New("unsupported sort order, use one of: asc, desc")
+	ErrGeoMemberNotFound     = errors.New("geo member not found")
+)
+
+// Supported distance units.
+const (
+	GeoUnitMeters     = "m"
+	GeoUnitKilometers = "km"
+	GeoUnitMiles      = "mi"
+	GeoUnitFeet       = "ft"
+)
+
+// Supported sort orders of geo search results.
+const (
+	GeoSortNone = ""
+	GeoSortAsc  = "asc"
+	GeoSortDesc = "desc"
+)
+
+// GeoPoint represents geographic coordinates.
+type GeoPoint struct {
+	Longitude float64
+	Latitude  float64
+}
+
+// GeoMember represents a named point stored in geospatial index.
+type GeoMember struct {
+	Name string
+	GeoPoint
+}
+
+// GeoSearchQuery represents parameters of the search in geospatial index.
+type GeoSearchQuery struct {
+	// FromMember is the name of the member to use as the center of the search.
+	FromMember string
+
+	// FromPoint is the coordinates to use as the center of the search.
+	FromPoint *GeoPoint
+
+	// Radius enables search within circular area.
+	Radius float64
+
+	// Width and Height enable search within rectangular area.
+	Width  float64
+	Height float64
+
+	// Unit is the unit of Radius, Width, Height and result distances.
+	// Meters are used if it's omitted.
+	Unit string
+
+	// Sort is the order of results by distance from the center.
+	Sort string
+
+	// Count limits the number of results if it's greater than 0.
+	Count int
+}
+
+// GeoSearchResult represents a single member found by geo search.
+type GeoSearchResult struct {
+	Name     string
+	Distance float64
+	GeoPoint
+}
+
+// GeoAdd method adds members with coordinates to the geospatial index stored at key.
+// If key does not exist, a new key holding a geospatial index is created.
+// If member already exists, its position is updated.
+// TTL param could be omitted if it's adding to the existing index.
+// It returns the number of new members added to the index.
+func (c *Cache) GeoAdd(key string, members []GeoMember, ttl time.Duration) (int, error) {
+	op := c.startOp(CommandGeoAdd, key, members)
+	defer op.end()
+
+	// Validate all members before modifying the index
+	for _, m := range members {
+		if !isValidGeoPoint(m.Longitude, m.Latitude) {
+			return 0, ErrInvalidGeoCoordinates
+		}
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	v, isExist := c.data[key]
+	isNew := !isExist || v.isExpired()
+	if isNew {
+		c.countExpired(v, isExist)
+
+		// Add new entity with geo index value
+		v = entity{
+			value:        newSortedSet(),
+			expiredAfter: validateExpiredAfter(ttl),
+		}
+	}
+
+	// Check if found value it's a sorted set
+	set, ok := v.value.(*sortedSet)
+	if !ok {
+		return 0, ErrWrongTypeGeo
+	}
+
+	added := 0
+	for _, m := range members {
+		if set.add(m.Name, float64(geohashEncode(m.Longitude, m.Latitude))) {
+			added++
+		}
+	}
+	c.data[key] = v
+	c.logWrite(WriteOp{
+		Command:  CommandGeoAdd,
+		Entry:    Entry{Key: key, Type: TypeGeo, Value: members},
+		Merge:    !isNew,
+		ExpireAt: v.expiredAfter,
+	})
+	c.notify(EventSet, key)
+
+	return added, nil
+}
+
+// GeoPos method returns positions of the members of geospatial index stored at key.
+// When member does not exist - nil value is returned at its position.
+func (c *Cache) GeoPos(key string, members ...string) ([]*GeoPoint, error) {
+	op := c.startOp(CommandGeoPos, key, members)
+	defer op.end()
+
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	set, err := c.getGeoSet(key)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*GeoPoint, len(members))
+	for i, m := range members {
+		score, isExist := set.score(m)
+		if !isExist {
+			continue
+		}
+		lon, lat := geohashDecode(uint64(score))
+		result[i] = &GeoPoint{Longitude: lon, Latitude: lat}
+	}
+
+	return result, nil
+}
+
+// GeoDist method returns the distance between two members of geospatial index
+// stored at key in given unit.
+// The second param in return will indicate if both members exist.
+func (c *Cache) GeoDist(key, member1, member2, unit string) (float64, bool, error) {
+	op := c.startOp(CommandGeoDist, key, nil)
+	defer op.end()
+
+	conversion, err := geoUnitToMeters(unit)
+	if err != nil {
+		return 0, false, err
+	}
+
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	set, err := c.getGeoSet(key)
+	if err != nil {
+		return 0, false, err
+	}
+
+	score1, isExist1 := set.score(member1)
+	score2, isExist2 := set.score(member2)
+	if !isExist1 || !isExist2 {
+		return 0, false, nil
+	}
+
+	lon1, lat1 := geohashDecode(uint64(score1))
+	lon2, lat2 := geohashDecode(uint64(score2))
+
+	return geoDistance(lon1, lat1, lon2, lat2) / conversion, true, nil
+}
+
+// GeoSearch method returns members of geospatial index stored at key
+// which are within the area specified by query.
+func (c *Cache) GeoSearch(key string, query GeoSearchQuery) ([]GeoSearchResult, error) {
+	op := c.startOp(CommandGeoSearch, key, nil)
+	defer op.end()
+
+	conversion, err := geoUnitToMeters(query.Unit)
+	if err != nil {
+		return nil, err
+	}
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	set, err := c.getGeoSet(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the center of the search
+	var center GeoPoint
+	if query.FromPoint != nil {
+		center = *query.FromPoint
+	} else {
+		score, isExist := set.score(query.FromMember)
+		if !isExist {
+			return nil, ErrGeoMemberNotFound
+		}
+		center.Longitude, center.Latitude = geohashDecode(uint64(score))
+	}
+
+	// Convert shape to meters
+	halfWidth := query.Radius * conversion
+	halfHeight := halfWidth
+	if query.Radius <= 0 {
+		halfWidth = query.Width * conversion / 2
+		halfHeight = query.Height * conversion / 2
+	}
+
+	// Check members of the cells that cover the search area
+	result := make([]GeoSearchResult, 0)
+	for _, cell := range geoCoveringCells(center.Longitude, center.Latitude, halfWidth, halfHeight) {
+		min, max := cell.scoreRange()
+		for _, item := range set.rangeByScore(min, max) {
+			lon, lat := geohashDecode(uint64(item.score))
+
+			var (
+				distance float64
+				ok       bool
+			)
+			if query.Radius > 0 {
+				distance, ok = geoDistanceInRadius(center, lon, lat, halfWidth)
+			} else {
+				distance, ok = geoDistanceInBox(center, lon, lat, halfWidth, halfHeight)
+			}
+			if !ok {
+				continue
+			}
+
+			result = append(result, GeoSearchResult{
+				Name:     item.member,
+				Distance: distance / conversion,
+				GeoPoint: GeoPoint{Longitude: lon, Latitude: lat},
+			})
+		}
+	}
+
+	// Sort by distance if needed, it's required anyway to apply count
+	// to the nearest members
+	switch {
+	case query.Sort == GeoSortDesc:
+		sort.SliceStable(result, func(i, j int) bool { return result[i].Distance > result[j].Distance })
+	case query.Sort == GeoSortAsc || query.Count > 0:
+		sort.SliceStable(result, func(i, j int) bool { return result[i].Distance < result[j].Distance })
+	}
+
+	if query.Count > 0 && len(result) > query.Count {
+		result = result[:query.Count]
+	}
+
+	return result, nil
+}
+
+// getGeoSet returns geospatial index stored at key.
+// Method must be called with read lock held.
+func (c *Cache) getGeoSet(key string) (*sortedSet, error) {
+	v, isExist := c.data[key]
+	if !isExist || v.isExpired() {
+		return nil, ErrNotFound
+	}
+
+	// Check if type is sorted set
+	set, ok := v.value.(*sortedSet)
+	if !ok {
+		return nil, ErrWrongTypeGeo
+	}
+
+	return set, nil
+}
+
+// validate checks that query has exactly one center and one shape.
+func (q GeoSearchQuery) validate() error {
+	if (q.FromMember == "") == (q.FromPoint == nil) {
+		return ErrInvalidGeoCenter
+	}
+	if q.FromPoint != nil && !isValidGeoPoint(q.FromPoint.Longitude, q.FromPoint.Latitude) {
+		return ErrInvalidGeoCoordinates
+	}
+
+	isRadius := q.Radius > 0
+	isBox := q.Width > 0 && q.Height > 0
+	if isRadius == isBox || q.Radius < 0 || q.Width < 0 || q.Height < 0 {
+		return ErrInvalidGeoShape
+	}
+
+	switch q.Sort {
+	case GeoSortNone, GeoSortAsc, GeoSortDesc:
+	default:
+		return ErrInvalidGeoSort
+	}
+
+	return nil
+}
+
+// geoDistanceInRadius returns the distance to the point if it's within radius.
+func geoDistanceInRadius(center GeoPoint, lon, lat, radius float64) (float64, bool) {
+	distance := geoDistance(center.Longitude, center.Latitude, lon, lat)
+
+	return distance, distance <= radius
+}
+
+// geoDistanceInBox returns the distance to the point if it's within
+// rectangle with given half-width and half-height centered at center.
+func geoDistanceInBox(center GeoPoint, lon, lat, halfWidth, halfHeight float64) (float64, bool) {
+	// Latitude distance is less expensive to compute
+	latDistance := 2 * earthRadiusInMeters * math.Abs(math.Sin(degToRad(lat-center.Latitude)/2))
+	if latDistance > halfHeight {
+		return 0, false
+	}
+	if geoDistance(center.Longitude, lat, lon, lat) > halfWidth {
+		return 0, false
+	}
+
+	return geoDistance(center.Longitude, center.Latitude, lon, lat), true
+}
+
+// geoUnitToMeters returns conversion factor of the unit to meters.
+func geoUnitToMeters(unit string) (float64, error) {
+	switch unit {
+	case "", GeoUnitMeters:
+		return 1, nil
+	case GeoUnitKilometers:
+		return 1000, nil
+	case GeoUnitMiles:
+		return 1609.34, nil
+	case GeoUnitFeet:
+		return 0.3048, nil
+	default:
+		return 0, ErrInvalidGeoUnit
+	}
+}
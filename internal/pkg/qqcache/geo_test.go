@@ -0,0 +1,224 @@
+package qqcache
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testGeoKey = "test-geo-key"
+
+func getTestGeoMembers() []GeoMember {
+	return []GeoMember{
+		{Name: "Palermo", GeoPoint: GeoPoint{Longitude: 13.361389, Latitude: 38.115556}},
+		{Name: "Catania", GeoPoint: GeoPoint{Longitude: 15.087269, Latitude: 37.502669}},
+		{Name: "Rome", GeoPoint: GeoPoint{Longitude: 12.496366, Latitude: 41.902782}},
+	}
+}
+
+func TestGeohashEncodeDecode(t *testing.T) {
+	for _, m := range getTestGeoMembers() {
+		lon, lat := geohashDecode(geohashEncode(m.Longitude, m.Latitude))
+		require.InDelta(t, m.Longitude, lon, 0.00001)
+		require.InDelta(t, m.Latitude, lat, 0.00001)
+	}
+}
+
+func TestGeoScore(t *testing.T) {
+	// Scores of the members are the same as scores of Redis GEO commands
+	members := getTestGeoMembers()
+	require.Equal(t, float64(3479099956230698), GeoScore(members[0].GeoPoint))
+	require.Equal(t, float64(3479447370796909), GeoScore(members[1].GeoPoint))
+}
+
+func TestCache_GeoAdd(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	added, err := c.GeoAdd(testGeoKey, getTestGeoMembers(), 0)
+	require.NoError(t, err)
+	require.Equal(t, 3, added)
+
+	// Update the position of the existing member
+	added, err = c.GeoAdd(testGeoKey, []GeoMember{
+		{Name: "Rome", GeoPoint: GeoPoint{Longitude: 12.5, Latitude: 41.9}},
+	}, 0)
+	require.NoError(t, err)
+	require.Equal(t, 0, added)
+}
+
+func TestCache_GeoAdd_InvalidCoordinates(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	_, err := c.GeoAdd(testGeoKey, []GeoMember{
+		{Name: "North Pole", GeoPoint: GeoPoint{Longitude: 0, Latitude: 90}},
+	}, 0)
+	require.True(t, errors.Is(err, ErrInvalidGeoCoordinates))
+}
+
+func TestCache_GeoAdd_WrongType(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	c.Set(testGeoKey, testValue, 0)
+
+	_, err := c.GeoAdd(testGeoKey, getTestGeoMembers(), 0)
+	require.True(t, errors.Is(err, ErrWrongTypeGeo))
+}
+
+func TestCache_GeoPos(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	_, err := c.GeoAdd(testGeoKey, getTestGeoMembers(), 0)
+	require.NoError(t, err)
+
+	got, err := c.GeoPos(testGeoKey, "Palermo", "not-existing")
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.NotNil(t, got[0])
+	require.InDelta(t, 13.361389, got[0].Longitude, 0.00001)
+	require.InDelta(t, 38.115556, got[0].Latitude, 0.00001)
+	require.Nil(t, got[1])
+}
+
+func TestCache_GeoPos_NotFound(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	_, err := c.GeoPos(testGeoKey, "Palermo")
+	require.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestCache_GeoDist(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	_, err := c.GeoAdd(testGeoKey, getTestGeoMembers(), 0)
+	require.NoError(t, err)
+
+	got, ok, err := c.GeoDist(testGeoKey, "Palermo", "Catania", GeoUnitKilometers)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.InDelta(t, 166.2742, got, 0.001)
+
+	_, ok, err = c.GeoDist(testGeoKey, "Palermo", "not-existing", "")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, _, err = c.GeoDist(testGeoKey, "Palermo", "Catania", "parsec")
+	require.True(t, errors.Is(err, ErrInvalidGeoUnit))
+}
+
+func TestCache_GeoSearch_Radius(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	_, err := c.GeoAdd(testGeoKey, getTestGeoMembers(), 0)
+	require.NoError(t, err)
+
+	got, err := c.GeoSearch(testGeoKey, GeoSearchQuery{
+		FromPoint: &GeoPoint{Longitude: 15, Latitude: 37},
+		Radius:    200,
+		Unit:      GeoUnitKilometers,
+		Sort:      GeoSortAsc,
+	})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.Equal(t, "Catania", got[0].Name)
+	require.InDelta(t, 56.4413, got[0].Distance, 0.001)
+	require.Equal(t, "Palermo", got[1].Name)
+	require.InDelta(t, 190.4424, got[1].Distance, 0.001)
+}
+
+func TestCache_GeoSearch_RadiusFromMember(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	_, err := c.GeoAdd(testGeoKey, getTestGeoMembers(), 0)
+	require.NoError(t, err)
+
+	got, err := c.GeoSearch(testGeoKey, GeoSearchQuery{
+		FromMember: "Rome",
+		Radius:     1000,
+		Unit:       GeoUnitKilometers,
+		Sort:       GeoSortDesc,
+		Count:      2,
+	})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.Equal(t, "Catania", got[0].Name)
+	require.Equal(t, "Palermo", got[1].Name)
+
+	_, err = c.GeoSearch(testGeoKey, GeoSearchQuery{FromMember: "Milan", Radius: 10})
+	require.True(t, errors.Is(err, ErrGeoMemberNotFound))
+}
+
+func TestCache_GeoSearch_Box(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	_, err := c.GeoAdd(testGeoKey, getTestGeoMembers(), 0)
+	require.NoError(t, err)
+
+	got, err := c.GeoSearch(testGeoKey, GeoSearchQuery{
+		FromPoint: &GeoPoint{Longitude: 15, Latitude: 37},
+		Width:     400,
+		Height:    400,
+		Unit:      GeoUnitKilometers,
+		Sort:      GeoSortAsc,
+	})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.Equal(t, "Catania", got[0].Name)
+	require.InDelta(t, 56.4413, got[0].Distance, 0.001)
+	require.Equal(t, "Palermo", got[1].Name)
+	require.InDelta(t, 190.4424, got[1].Distance, 0.001)
+
+	// Narrow box excludes Palermo
+	got, err = c.GeoSearch(testGeoKey, GeoSearchQuery{
+		FromPoint: &GeoPoint{Longitude: 15, Latitude: 37},
+		Width:     200,
+		Height:    400,
+		Unit:      GeoUnitKilometers,
+	})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Equal(t, "Catania", got[0].Name)
+}
+
+func TestCache_GeoSearch_Antimeridian(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	_, err := c.GeoAdd(testGeoKey, []GeoMember{
+		{Name: "east", GeoPoint: GeoPoint{Longitude: 179.99, Latitude: 0}},
+		{Name: "west", GeoPoint: GeoPoint{Longitude: -179.99, Latitude: 0}},
+	}, 0)
+	require.NoError(t, err)
+
+	got, err := c.GeoSearch(testGeoKey, GeoSearchQuery{
+		FromPoint: &GeoPoint{Longitude: 180, Latitude: 0},
+		Radius:    5,
+		Unit:      GeoUnitKilometers,
+		Sort:      GeoSortAsc,
+	})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+}
+
+func TestCache_GeoSearch_InvalidQuery(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	_, err := c.GeoSearch(testGeoKey, GeoSearchQuery{FromMember: "Rome"})
+	require.True(t, errors.Is(err, ErrInvalidGeoShape))
+
+	_, err = c.GeoSearch(testGeoKey, GeoSearchQuery{Radius: 10})
+	require.True(t, errors.Is(err, ErrInvalidGeoCenter))
+
+	_, err = c.GeoSearch(testGeoKey, GeoSearchQuery{FromMember: "Rome", Radius: 10, Sort: "random"})
+	require.True(t, errors.Is(err, ErrInvalidGeoSort))
+}
@@ -0,0 +1,231 @@
+package qqcache
+
+import "math"
+
+const (
+	// geoStepMax is the number of bits per coordinate in geohash,
+	// 26 bits for each coordinate give 52 bits hash that fits into float64 score.
+	geoStepMax = 26
+
+	geoLatMin = -85.05112878
+	geoLatMax = 85.05112878
+	geoLonMin = -180.0
+	geoLonMax = 180.0
+
+	// earthRadiusInMeters is the earth radius used to calculate distances.
+	earthRadiusInMeters = 6372797.560856
+
+	// mercatorMax is the max distance (in meters) that could be covered
+	// by the geohash cell.
+	mercatorMax = 20037726.37
+
+	// geoMaxSearchCells is the max amount of geohash cells that could be checked
+	// within a single search.
+	geoMaxSearchCells = 16
+)
+
+// geoCell represents a single geohash cell at the specific step.
+type geoCell struct {
+	latIdx uint32
+	lonIdx uint32
+	step   uint
+}
+
+// scoreRange returns the range [min, max) of 52-bit scores covered by the cell.
+func (c geoCell) scoreRange() (float64, float64) {
+	shift := 2 * (geoStepMax - c.step)
+	hash := interleave(c.latIdx, c.lonIdx)
+
+	return float64(hash << shift), float64((hash + 1) << shift)
+}
+
+// isValidGeoPoint checks that coordinates could be indexed.
+func isValidGeoPoint(lon, lat float64) bool {
+	return lon >= geoLonMin && lon <= geoLonMax && lat >= geoLatMin && lat <= geoLatMax
+}
+
+// geohashEncode returns 52-bit geohash of the given coordinates.
+func geohashEncode(lon, lat float64) uint64 {
+	return interleave(
+		coordToIndex(lat, geoLatMin, geoLatMax, geoStepMax),
+		coordToIndex(lon, geoLonMin, geoLonMax, geoStepMax),
+	)
+}
+
+// geohashDecode returns coordinates of the center of the cell
+// described by 52-bit geohash.
+func geohashDecode(hash uint64) (float64, float64) {
+	latIdx, lonIdx := deinterleave(hash)
+	cells := float64(uint64(1) << geoStepMax)
+
+	latStep := (geoLatMax - geoLatMin) / cells
+	lonStep := (geoLonMax - geoLonMin) / cells
+
+	lat := geoLatMin + (float64(latIdx)+0.5)*latStep
+	lon := geoLonMin + (float64(lonIdx)+0.5)*lonStep
+
+	return math.Max(geoLonMin, math.Min(geoLonMax, lon)),
+		math.Max(geoLatMin, math.Min(geoLatMax, lat))
+}
+
+// GeoScore returns the score of the point in geospatial index, it's 52-bit
+// geohash that is compatible with sorted sets of Redis GEO commands.
+func GeoScore(p GeoPoint) float64 {
+	return float64(geohashEncode(p.Longitude, p.Latitude))
+}
+
+// coordToIndex returns the index of the cell the coordinate belongs to
+// on the grid with 2^step cells.
+func coordToIndex(v, min, max float64, step uint) uint32 {
+	cells := uint64(1) << step
+	idx := uint64((v - min) / (max - min) * float64(cells))
+	if idx >= cells {
+		idx = cells - 1
+	}
+
+	return uint32(idx)
+}
+
+// interleave returns 64-bit value where bits of lat are placed
+// at even positions and bits of lon are placed at odd positions.
+func interleave(lat, lon uint32) uint64 {
+	return spread(lat) | spread(lon)<<1
+}
+
+// deinterleave is the reverse operation to interleave.
+func deinterleave(hash uint64) (uint32, uint32) {
+	return squash(hash), squash(hash >> 1)
+}
+
+func spread(v uint32) uint64 {
+	x := uint64(v)
+	x = (x | x<<16) & 0x0000FFFF0000FFFF
+	x = (x | x<<8) & 0x00FF00FF00FF00FF
+	x = (x | x<<4) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x<<2) & 0x3333333333333333
+	x = (x | x<<1) & 0x5555555555555555
+
+	return x
+}
+
+func squash(x uint64) uint32 {
+	x &= 0x5555555555555555
+	x = (x | x>>1) & 0x3333333333333333
+	x = (x | x>>2) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x>>4) & 0x00FF00FF00FF00FF
+	x = (x | x>>8) & 0x0000FFFF0000FFFF
+	x = (x | x>>16) & 0x00000000FFFFFFFF
+
+	return uint32(x)
+}
+
+// geoDistance returns the distance in meters between two points
+// using haversine formula.
+func geoDistance(lon1, lat1, lon2, lat2 float64) float64 {
+	lat1r := degToRad(lat1)
+	lat2r := degToRad(lat2)
+	u := math.Sin((lat2r - lat1r) / 2)
+	v := math.Sin(degToRad(lon2-lon1) / 2)
+
+	return 2 * earthRadiusInMeters * math.Asin(math.Sqrt(u*u+math.Cos(lat1r)*math.Cos(lat2r)*v*v))
+}
+
+// geoEstimateStep returns the geohash precision step that gives cells
+// comparable with the given radius.
+func geoEstimateStep(radius, lat float64) uint {
+	if radius == 0 {
+		return geoStepMax
+	}
+
+	step := 1
+	for radius < mercatorMax {
+		radius *= 2
+		step++
+	}
+
+	// Make sure the range is included in most of the base cases
+	step -= 2
+
+	// Cells are narrower towards the poles
+	if lat > 66 || lat < -66 {
+		step--
+		if lat > 80 || lat < -80 {
+			step--
+		}
+	}
+
+	if step < 1 {
+		step = 1
+	}
+	if step > geoStepMax {
+		step = geoStepMax
+	}
+
+	return uint(step)
+}
+
+// geoBoundingBox returns the bounding box (minLon, minLat, maxLon, maxLat)
+// of the area around the center within given half-width and half-height in meters.
+func geoBoundingBox(lon, lat, halfWidth, halfHeight float64) (float64, float64, float64, float64) {
+	latDelta := radToDeg(halfHeight / earthRadiusInMeters)
+	lonDeltaTop := radToDeg(halfWidth / earthRadiusInMeters / math.Cos(degToRad(lat+latDelta)))
+	lonDeltaBottom := radToDeg(halfWidth / earthRadiusInMeters / math.Cos(degToRad(lat-latDelta)))
+	lonDelta := math.Max(math.Abs(lonDeltaTop), math.Abs(lonDeltaBottom))
+
+	return lon - lonDelta, lat - latDelta, lon + lonDelta, lat + latDelta
+}
+
+// geoCoveringCells returns geohash cells that cover the area around the center
+// within given half-width and half-height in meters.
+func geoCoveringCells(lon, lat, halfWidth, halfHeight float64) []geoCell {
+	minLon, minLat, maxLon, maxLat := geoBoundingBox(lon, lat, halfWidth, halfHeight)
+	minLat = math.Max(minLat, geoLatMin)
+	maxLat = math.Min(maxLat, geoLatMax)
+
+	step := geoEstimateStep(math.Max(halfWidth, halfHeight), lat)
+	for {
+		cells := geoCellsInBox(minLon, minLat, maxLon, maxLat, step)
+		if len(cells) <= geoMaxSearchCells || step == 1 {
+			return cells
+		}
+		step--
+	}
+}
+
+// geoCellsInBox returns all cells at the given step that intersect the box.
+// Box could cross the antimeridian, in that case minLon < -180 or maxLon > 180.
+func geoCellsInBox(minLon, minLat, maxLon, maxLat float64, step uint) []geoCell {
+	cellsPerAxis := int64(1) << step
+	lonStep := (geoLonMax - geoLonMin) / float64(cellsPerAxis)
+
+	latFrom := int64(coordToIndex(minLat, geoLatMin, geoLatMax, step))
+	latTo := int64(coordToIndex(maxLat, geoLatMin, geoLatMax, step))
+
+	// Longitude indexes could be out of the grid range, they are wrapped below
+	lonFrom := int64(math.Floor((minLon - geoLonMin) / lonStep))
+	lonTo := int64(math.Floor((maxLon - geoLonMin) / lonStep))
+	if lonTo-lonFrom+1 >= cellsPerAxis {
+		lonFrom, lonTo = 0, cellsPerAxis-1
+	}
+
+	cells := make([]geoCell, 0, (latTo-latFrom+1)*(lonTo-lonFrom+1))
+	for latIdx := latFrom; latIdx <= latTo; latIdx++ {
+		for lonIdx := lonFrom; lonIdx <= lonTo; lonIdx++ {
+			cells = append(cells, geoCell{
+				latIdx: uint32(latIdx),
+				lonIdx: uint32(((lonIdx % cellsPerAxis) + cellsPerAxis) % cellsPerAxis),
+				step:   step,
+			})
+		}
+	}
+
+	return cells
+}
+
+func degToRad(v float64) float64 {
+	return v * math.Pi / 180
+}
+
+func radToDeg(v float64) float64 {
+	return v * 180 / math.Pi
+}
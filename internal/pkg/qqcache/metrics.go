@@ -0,0 +1,198 @@
+package qqcache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Commands of the cache, CommandGet, CommandLIndex and CommandHGet
+// count cache hits and misses.
+const (
+	CommandGet       = "get"
+	CommandSet       = "set"
+	CommandRemove    = "remove"
+	CommandKeys      = "keys"
+	CommandRPush     = "rpush"
+	CommandLIndex    = "lindex"
+	CommandHSet      = "hset"
+	CommandHGet      = "hget"
+	CommandGeoAdd    = "geoadd"
+	CommandGeoPos    = "geopos"
+	CommandGeoDist   = "geodist"
+	CommandGeoSearch = "geosearch"
+	CommandTTL       = "ttl"
+	CommandDump      = "dump"
+	CommandRestore   = "restore"
+)
+
+// commands contains all commands of the cache.
+var commands = []string{
+	CommandGet, CommandSet, CommandRemove, CommandKeys, CommandRPush, CommandLIndex,
+	CommandHSet, CommandHGet, CommandGeoAdd, CommandGeoPos, CommandGeoDist, CommandGeoSearch,
+	CommandTTL, CommandDump, CommandRestore,
+}
+
+// Types of the values stored in cache.
+const (
+	TypeValue = "value"
+	TypeBytes = "bytes"
+	TypeList  = "list"
+	TypeHash  = "hash"
+	TypeGeo   = "geo"
+)
+
+// counters contains cache counters updated atomically.
+// It's allocated separately to keep 64-bit alignment of the fields.
+type counters struct {
+	getHits      uint64
+	getMisses    uint64
+	lindexHits   uint64
+	lindexMisses uint64
+	hgetHits     uint64
+	hgetMisses   uint64
+
+	// expired is the number of expired keys dropped from cache
+	// either by the cleaner or by the writes to the same keys
+	expired uint64
+
+	// evicted is the number of expired keys deleted by the cleaner
+	evicted uint64
+
+	cleanerRounds         uint64
+	cleanerDurationNs     uint64
+	lastCleanerDurationNs uint64
+	lastCleanerRunNs      uint64
+	lastCleanerRemoved    uint64
+
+	// calls contains the number of calls by commands,
+	// the map is not modified after creation
+	calls map[string]*uint64
+}
+
+// newCounters returns new instance of counters with calls counters
+// allocated for all commands.
+func newCounters() *counters {
+	c := &counters{calls: make(map[string]*uint64, len(commands))}
+	for _, command := range commands {
+		c.calls[command] = new(uint64)
+	}
+
+	return c
+}
+
+// Metrics represents a snapshot of cache metrics.
+type Metrics struct {
+	// Hits and Misses contain the number of lookups by commands.
+	Hits   map[string]uint64
+	Misses map[string]uint64
+
+	// Calls contains the number of calls by commands.
+	Calls map[string]uint64
+
+	// KeysByType contains the number of not expired keys by value types.
+	KeysByType map[string]int
+
+	// ExpiredKeys is the number of expired keys dropped from cache.
+	ExpiredKeys uint64
+
+	// EvictedKeys is the number of expired keys deleted by the cleaner.
+	EvictedKeys uint64
+
+	// CleanerRounds is the number of cleaner runs and CleanerDuration
+	// is their total duration.
+	CleanerRounds   uint64
+	CleanerDuration time.Duration
+
+	// LastCleanerDuration is the duration of the last cleaner run.
+	LastCleanerDuration time.Duration
+}
+
+// Metrics method returns a snapshot of cache metrics.
+// Keys are counted with the read lock held.
+func (c *Cache) Metrics() Metrics {
+	m := c.counters.snapshot()
+
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	for _, v := range c.data {
+		if !v.isExpired() {
+			m.KeysByType[v.typeName()]++
+		}
+	}
+
+	return m
+}
+
+// snapshot method returns metrics with the counters values and
+// zero keys by types.
+func (c *counters) snapshot() Metrics {
+	return Metrics{
+		Hits: map[string]uint64{
+			CommandGet:    atomic.LoadUint64(&c.getHits),
+			CommandLIndex: atomic.LoadUint64(&c.lindexHits),
+			CommandHGet:   atomic.LoadUint64(&c.hgetHits),
+		},
+		Misses: map[string]uint64{
+			CommandGet:    atomic.LoadUint64(&c.getMisses),
+			CommandLIndex: atomic.LoadUint64(&c.lindexMisses),
+			CommandHGet:   atomic.LoadUint64(&c.hgetMisses),
+		},
+		Calls: c.callsSnapshot(),
+		KeysByType: map[string]int{
+			TypeValue: 0,
+			TypeBytes: 0,
+			TypeList:  0,
+			TypeHash:  0,
+			TypeGeo:   0,
+		},
+		ExpiredKeys:         atomic.LoadUint64(&c.expired),
+		EvictedKeys:         atomic.LoadUint64(&c.evicted),
+		CleanerRounds:       atomic.LoadUint64(&c.cleanerRounds),
+		CleanerDuration:     time.Duration(atomic.LoadUint64(&c.cleanerDurationNs)),
+		LastCleanerDuration: time.Duration(atomic.LoadUint64(&c.lastCleanerDurationNs)),
+	}
+}
+
+// callsSnapshot method returns the number of calls by commands.
+func (c *counters) callsSnapshot() map[string]uint64 {
+	calls := make(map[string]uint64, len(c.calls))
+	for command, v := range c.calls {
+		calls[command] = atomic.LoadUint64(v)
+	}
+
+	return calls
+}
+
+// countCall increases calls counter of the command.
+func (c *Cache) countCall(command string) {
+	atomic.AddUint64(c.counters.calls[command], 1)
+}
+
+// countLookup increases hit or miss counter.
+func countLookup(hits, misses *uint64, isHit bool) {
+	if isHit {
+		atomic.AddUint64(hits, 1)
+	} else {
+		atomic.AddUint64(misses, 1)
+	}
+}
+
+// countExpired increases expired keys counter if existing value is expired,
+// it's used by the methods that replace or delete the value.
+func (c *Cache) countExpired(v entity, isExist bool) {
+	if isExist && v.isExpired() {
+		atomic.AddUint64(&c.counters.expired, 1)
+	}
+}
+
+// countCleanerRound updates cleaner counters.
+func (c *Cache) countCleanerRound(evicted int, duration time.Duration) {
+	atomic.AddUint64(&c.counters.expired, uint64(evicted))
+	atomic.AddUint64(&c.counters.evicted, uint64(evicted))
+	atomic.AddUint64(&c.counters.cleanerRounds, 1)
+	atomic.AddUint64(&c.counters.cleanerDurationNs, uint64(duration))
+	atomic.StoreUint64(&c.counters.lastCleanerDurationNs, uint64(duration))
+	atomic.StoreUint64(&c.counters.lastCleanerRunNs, uint64(time.Now().UnixNano()))
+	atomic.StoreUint64(&c.counters.lastCleanerRemoved, uint64(evicted))
+}
@@ -0,0 +1,77 @@
+package qqcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_Metrics_Lookups(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	c.Set(testKey, testValue, 0)
+	require.NoError(t, c.RPush("list", testValue, 0))
+	require.NoError(t, c.HSet("hash", map[string]interface{}{"a": "b"}, 0))
+
+	c.Get(testKey)
+	c.GetWithContentType(testKey)
+	c.Get("unknown")
+	_, _ = c.LIndex("list", 0)
+	_, _ = c.LIndex("list", 1)
+	_, _ = c.LIndex("unknown", 0)
+	_, _ = c.HGet("hash", "a")
+	_, _ = c.HGet("hash", "b")
+
+	m := c.Metrics()
+	require.Equal(t, map[string]uint64{CommandGet: 2, CommandLIndex: 1, CommandHGet: 1}, m.Hits)
+	require.Equal(t, map[string]uint64{CommandGet: 1, CommandLIndex: 2, CommandHGet: 1}, m.Misses)
+}
+
+func TestCache_Metrics_KeysByType(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	c.Set(testKey, testValue, 0)
+	c.Set("number", 1.5, 0)
+	c.SetBytes("bytes", []byte{1}, "application/octet-stream", 0)
+	require.NoError(t, c.RPush("list", testValue, 0))
+	require.NoError(t, c.HSet("hash", map[string]interface{}{"a": "b"}, 0))
+	_, err := c.GeoAdd("geo", []GeoMember{{Name: "a", GeoPoint: GeoPoint{Longitude: 1, Latitude: 1}}}, 0)
+	require.NoError(t, err)
+
+	// Expired keys are not counted
+	c.Set("expired", testValue, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	require.Equal(t, map[string]int{
+		TypeValue: 2,
+		TypeBytes: 1,
+		TypeList:  1,
+		TypeHash:  1,
+		TypeGeo:   1,
+	}, c.Metrics().KeysByType)
+}
+
+func TestCache_Metrics_Expired(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	for _, k := range []string{"a", "b", "c"} {
+		c.Set(k, testValue, time.Nanosecond)
+	}
+	time.Sleep(time.Millisecond)
+
+	// Expired value is replaced before the cleaner run
+	c.Set("a", testValue, 0)
+
+	c.cleanerRound()
+
+	m := c.Metrics()
+	require.Equal(t, uint64(3), m.ExpiredKeys)
+	require.Equal(t, uint64(2), m.EvictedKeys)
+	require.Equal(t, uint64(1), m.CleanerRounds)
+	require.True(t, m.LastCleanerDuration > 0)
+	require.Equal(t, m.LastCleanerDuration, m.CleanerDuration)
+}
@@ -0,0 +1,121 @@
+package qqcache
+
+import "time"
+
+// Operation represents an executed command of the cache, it's reported
+// to the observer given to WithObserver and to the slow log.
+type Operation struct {
+	Command string
+	Key     string
+
+	// Client is the name of the client given to WithClient.
+	Client string
+
+	// Started is when the command was started and Duration is its execution
+	// time including waiting for the lock.
+	Started  time.Time
+	Duration time.Duration
+
+	// Args is the number of the command arguments, such as hash fields or
+	// geo members, and ArgsBytes is their approximate size.
+	Args      int
+	ArgsBytes int64
+
+	// IsLookup is true for the commands that count hits and misses,
+	// Hit reports whether the value was found.
+	IsLookup bool
+	Hit      bool
+
+	// ValueBytes is the approximate size of the value read by the lookup.
+	ValueBytes int64
+}
+
+// op tracks the command in progress.
+// Nil op is returned if neither the observer nor the slow log is set.
+type op struct {
+	c       *Cache
+	command string
+	key     string
+	started time.Time
+
+	// Sizes are computed while the caller holds the lock, the values
+	// could be changed by other commands when the operation ends.
+	args       int
+	argsBytes  int64
+	isLookup   bool
+	hit        bool
+	valueBytes int64
+}
+
+// WithObserver method returns the cache that reports every executed command
+// to the observer. The observer is called after the lock is released.
+func (c *Cache) WithObserver(observer func(Operation)) *Cache {
+	return &Cache{store: c.store, client: c.client, observer: observer}
+}
+
+// startOp method counts the call of the command and starts tracking it.
+func (c *Cache) startOp(command, key string, args interface{}) *op {
+	c.countCall(command)
+
+	if c.slowLog == nil && c.observer == nil {
+		return nil
+	}
+
+	o := &op{
+		c:       c,
+		command: command,
+		key:     key,
+		started: time.Now(),
+	}
+	o.args, o.argsBytes = argsSize(args)
+
+	return o
+}
+
+// lookup method records the result of the lookup,
+// it must be called while the lock is held.
+func (o *op) lookup(isHit bool, value interface{}) {
+	if o == nil {
+		return
+	}
+
+	o.isLookup = true
+	o.hit = isHit
+	if isHit {
+		o.valueBytes = approxSize(value)
+	}
+}
+
+// end method reports the command to the observer and to the slow log,
+// it's deferred by the commands.
+func (o *op) end() {
+	if o == nil {
+		return
+	}
+
+	duration := time.Since(o.started)
+	isSlow := o.c.slowLog != nil && duration >= o.c.slowLog.threshold
+	if !isSlow && o.c.observer == nil {
+		return
+	}
+
+	operation := Operation{
+		Command:    o.command,
+		Key:        o.key,
+		Client:     o.c.client,
+		Started:    o.started.UTC(),
+		Duration:   duration,
+		Args:       o.args,
+		ArgsBytes:  o.argsBytes,
+		IsLookup:   o.isLookup,
+		Hit:        o.hit,
+		ValueBytes: o.valueBytes,
+	}
+
+	if isSlow {
+		o.c.slowLog.record(operation)
+	}
+	if o.c.observer != nil {
+		o.c.observer(operation)
+	}
+}
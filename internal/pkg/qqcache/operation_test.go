@@ -0,0 +1,81 @@
+package qqcache
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_WithObserver(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	var ops []Operation
+	observed := c.WithObserver(func(o Operation) { ops = append(ops, o) }).WithClient("alice")
+
+	observed.Set(testKey, testValue, 0)
+	observed.Get(testKey)
+	observed.Get("unknown")
+	_, _ = observed.HGet(testKey, "field")
+
+	// Commands of the cache without observer are not reported
+	c.Get(testKey)
+
+	require.Len(t, ops, 4)
+
+	require.Equal(t, CommandSet, ops[0].Command)
+	require.Equal(t, testKey, ops[0].Key)
+	require.Equal(t, "alice", ops[0].Client)
+	require.Equal(t, 1, ops[0].Args)
+	require.Equal(t, approxSize(testValue), ops[0].ArgsBytes)
+	require.False(t, ops[0].IsLookup)
+	require.WithinDuration(t, time.Now(), ops[0].Started, time.Second)
+
+	require.Equal(t, CommandGet, ops[1].Command)
+	require.True(t, ops[1].IsLookup)
+	require.True(t, ops[1].Hit)
+	require.Equal(t, approxSize(testValue), ops[1].ValueBytes)
+
+	require.True(t, ops[2].IsLookup)
+	require.False(t, ops[2].Hit)
+	require.Zero(t, ops[2].ValueBytes)
+
+	// Wrong type errors are not lookups
+	require.Equal(t, CommandHGet, ops[3].Command)
+	require.False(t, ops[3].IsLookup)
+}
+
+// TestCache_WithObserver_Concurrent checks that the sizes of the values are
+// computed under the lock, it's meant to be run with -race.
+func TestCache_WithObserver_Concurrent(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	var observed uint64
+	oc := c.WithObserver(func(o Operation) { atomic.AddUint64(&observed, 1) })
+	require.NoError(t, oc.HSet(testKey, map[string]interface{}{"field": "value"}, 0))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			assert.NoError(t, oc.HSet(testKey, map[string]interface{}{strconv.Itoa(i): "value"}, 0))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			_, ok := oc.Get(testKey)
+			assert.True(t, ok)
+		}
+	}()
+	wg.Wait()
+
+	require.Equal(t, uint64(201), atomic.LoadUint64(&observed))
+}
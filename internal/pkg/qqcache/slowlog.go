@@ -0,0 +1,174 @@
+package qqcache
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultSlowLogMaxLen is used if the maximum length of the slow log is not set.
+const defaultSlowLogMaxLen = 128
+
+// SlowLogOpts represents the options of the slow log.
+type SlowLogOpts struct {
+	// Threshold is the minimum duration of the commands to be recorded.
+	// The slow log is disabled if it's equal or less than 0.
+	Threshold time.Duration
+
+	// MaxLen is the maximum number of entries, the oldest entries are dropped.
+	MaxLen int
+
+	// OnSlow is called with every recorded entry if it's set.
+	OnSlow func(SlowLogEntry)
+}
+
+// SlowLogEntry represents a command which execution exceeded the slow log threshold.
+type SlowLogEntry struct {
+	// ID is the unique increasing identifier of the entry.
+	ID uint64
+
+	// Time is when the command was started.
+	Time time.Time
+
+	// Duration is the execution time of the command including waiting for the lock.
+	Duration time.Duration
+
+	Command string
+	Key     string
+
+	// Args is the number of the command arguments, such as hash fields or
+	// geo members, and ArgsBytes is their approximate size.
+	Args      int
+	ArgsBytes int64
+
+	// Client is the name of the client given to WithClient.
+	Client string
+}
+
+// slowLog contains the latest slow commands.
+type slowLog struct {
+	threshold time.Duration
+	maxLen    int
+	onSlow    func(SlowLogEntry)
+
+	mux     sync.Mutex
+	entries []SlowLogEntry
+	lastID  uint64
+}
+
+// newSlowLog returns new instance of slowLog or nil if it's disabled.
+func newSlowLog(opts SlowLogOpts) *slowLog {
+	if opts.Threshold <= 0 {
+		return nil
+	}
+
+	maxLen := opts.MaxLen
+	if maxLen <= 0 {
+		maxLen = defaultSlowLogMaxLen
+	}
+
+	return &slowLog{
+		threshold: opts.Threshold,
+		maxLen:    maxLen,
+		onSlow:    opts.OnSlow,
+		entries:   make([]SlowLogEntry, 0, maxLen),
+	}
+}
+
+// SlowLog method returns up to n latest slow log entries, newest first.
+// All entries are returned if n is equal or less than 0.
+func (c *Cache) SlowLog(n int) []SlowLogEntry {
+	entries := make([]SlowLogEntry, 0)
+	if c.slowLog == nil {
+		return entries
+	}
+
+	c.slowLog.mux.Lock()
+	defer c.slowLog.mux.Unlock()
+
+	if n <= 0 || n > len(c.slowLog.entries) {
+		n = len(c.slowLog.entries)
+	}
+	for i := len(c.slowLog.entries) - 1; i >= len(c.slowLog.entries)-n; i-- {
+		entries = append(entries, c.slowLog.entries[i])
+	}
+
+	return entries
+}
+
+// SlowLogLen method returns the number of slow log entries.
+func (c *Cache) SlowLogLen() int {
+	if c.slowLog == nil {
+		return 0
+	}
+
+	c.slowLog.mux.Lock()
+	defer c.slowLog.mux.Unlock()
+
+	return len(c.slowLog.entries)
+}
+
+// ResetSlowLog method deletes all slow log entries.
+func (c *Cache) ResetSlowLog() {
+	if c.slowLog == nil {
+		return
+	}
+
+	c.slowLog.mux.Lock()
+	defer c.slowLog.mux.Unlock()
+
+	c.slowLog.entries = c.slowLog.entries[:0]
+}
+
+// record method adds the entry of the operation and drops the oldest one
+// if the slow log is full.
+func (l *slowLog) record(o Operation) {
+	e := SlowLogEntry{
+		Time:      o.Started,
+		Duration:  o.Duration,
+		Command:   o.Command,
+		Key:       o.Key,
+		Args:      o.Args,
+		ArgsBytes: o.ArgsBytes,
+		Client:    o.Client,
+	}
+
+	l.mux.Lock()
+	l.lastID++
+	e.ID = l.lastID
+	if len(l.entries) == l.maxLen {
+		copy(l.entries, l.entries[1:])
+		l.entries = l.entries[:len(l.entries)-1]
+	}
+	l.entries = append(l.entries, e)
+	l.mux.Unlock()
+
+	if l.onSlow != nil {
+		l.onSlow(e)
+	}
+}
+
+// argsSize returns the number and approximate size of the command arguments.
+func argsSize(args interface{}) (int, int64) {
+	switch v := args.(type) {
+	case nil:
+		return 0, 0
+	case map[string]interface{}:
+		return len(v), approxSize(v)
+	case []string:
+		var size int64
+		for _, s := range v {
+			size += int64(len(s))
+		}
+
+		return len(v), size
+	case []GeoMember:
+		size := int64(len(v)) * zsetItemOverhead
+		for _, m := range v {
+			size += int64(len(m.Name))
+		}
+
+		return len(v), size
+	default:
+		return 1, approxSize(v)
+	}
+}
@@ -0,0 +1,102 @@
+package qqcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_SlowLog(t *testing.T) {
+	var notified []SlowLogEntry
+	c := New(Opts{
+		EvictionInterval: testDefaultEviction * time.Second,
+		SlowLog: SlowLogOpts{
+			Threshold: time.Nanosecond,
+			MaxLen:    3,
+			OnSlow:    func(e SlowLogEntry) { notified = append(notified, e) },
+		},
+	})
+	defer c.Shutdown()
+
+	c.WithClient("alice").Set(testKey, testValue, 0)
+	require.NoError(t, c.HSet("hash", map[string]interface{}{"a": "b", "c": "d"}, 0))
+	c.Get(testKey)
+	c.Keys()
+
+	entries := c.SlowLog(0)
+	require.Len(t, entries, 3)
+	require.Equal(t, 3, c.SlowLogLen())
+	require.Len(t, notified, 4)
+
+	// The oldest entry is dropped, the newest is returned first
+	require.Equal(t, []string{CommandKeys, CommandGet, CommandHSet},
+		[]string{entries[0].Command, entries[1].Command, entries[2].Command})
+	require.Equal(t, []uint64{4, 3, 2}, []uint64{entries[0].ID, entries[1].ID, entries[2].ID})
+	require.Equal(t, "hash", entries[2].Key)
+	require.Equal(t, 2, entries[2].Args)
+	require.Greater(t, entries[2].ArgsBytes, int64(0))
+	require.True(t, entries[2].Duration > 0)
+	require.WithinDuration(t, time.Now(), entries[2].Time, time.Second)
+
+	// Client is reported for the commands called by the client
+	require.Equal(t, "alice", notified[0].Client)
+	require.Equal(t, CommandSet, notified[0].Command)
+	require.Equal(t, 1, notified[0].Args)
+	require.Empty(t, entries[0].Client)
+
+	require.Len(t, c.SlowLog(1), 1)
+	require.Equal(t, uint64(4), c.SlowLog(1)[0].ID)
+
+	c.ResetSlowLog()
+	require.Empty(t, c.SlowLog(0))
+
+	// IDs keep increasing after reset
+	c.Get(testKey)
+	require.Equal(t, uint64(5), c.SlowLog(0)[0].ID)
+}
+
+func TestCache_SlowLog_Threshold(t *testing.T) {
+	c := New(Opts{
+		EvictionInterval: testDefaultEviction * time.Second,
+		SlowLog:          SlowLogOpts{Threshold: time.Hour},
+	})
+	defer c.Shutdown()
+
+	c.Set(testKey, testValue, 0)
+	c.Get(testKey)
+	require.Empty(t, c.SlowLog(0))
+}
+
+func TestCache_SlowLog_Disabled(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	c.Set(testKey, testValue, 0)
+	require.Empty(t, c.SlowLog(0))
+	require.Zero(t, c.SlowLogLen())
+	c.ResetSlowLog()
+}
+
+func TestArgsSize(t *testing.T) {
+	for name, tc := range map[string]struct {
+		args  interface{}
+		count int
+		bytes int64
+	}{
+		"nil":     {args: nil},
+		"string":  {args: "abc", count: 1, bytes: valueOverhead + 3},
+		"members": {args: []string{"a", "bc"}, count: 2, bytes: 3},
+		"geo": {
+			args:  []GeoMember{{Name: "a"}, {Name: "bc"}},
+			count: 2,
+			bytes: 2*zsetItemOverhead + 3,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			count, bytes := argsSize(tc.args)
+			require.Equal(t, tc.count, count)
+			require.Equal(t, tc.bytes, bytes)
+		})
+	}
+}
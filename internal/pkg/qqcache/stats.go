@@ -0,0 +1,126 @@
+package qqcache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Approximate sizes in bytes used to estimate memory used by cache.
+const (
+	// entryOverhead is the size of the map entry with key and entity headers
+	entryOverhead = 64
+	// valueOverhead is the size of the interface value header
+	valueOverhead = 16
+	// floatSize is the size of the number value
+	floatSize = 8
+	// zsetItemOverhead is the size of the sorted set item with its score
+	// stored both in the slice and in the map
+	zsetItemOverhead = 56
+)
+
+// Stats represents a snapshot of cache internals.
+type Stats struct {
+	Metrics
+
+	// Keys is the number of not expired keys.
+	Keys int
+
+	// KeysWithTTL is the number of not expired keys with TTL and
+	// AvgTTL is their average remaining TTL.
+	KeysWithTTL int
+	AvgTTL      time.Duration
+
+	// ApproxMemoryBytes is the approximate size of keys and values in bytes.
+	ApproxMemoryBytes int64
+
+	// EvictionInterval is how often cache-cleaner deletes expired keys.
+	EvictionInterval time.Duration
+
+	// LastCleanerRun is the time of the last cleaner run and
+	// LastCleanerRemoved is the number of keys deleted by it.
+	// LastCleanerRun is zero if cleaner has not run yet.
+	LastCleanerRun     time.Time
+	LastCleanerRemoved uint64
+
+	// Watchers is the number of active watchers.
+	Watchers int
+}
+
+// Stats method returns a snapshot of cache internals.
+// Keys are counted with the read lock held, so it doesn't block readers.
+func (c *Cache) Stats() Stats {
+	s := Stats{
+		Metrics:            c.counters.snapshot(),
+		LastCleanerRemoved: atomic.LoadUint64(&c.counters.lastCleanerRemoved),
+	}
+	if ns := atomic.LoadUint64(&c.counters.lastCleanerRunNs); ns > 0 {
+		s.LastCleanerRun = time.Unix(0, int64(ns)).UTC()
+	}
+
+	c.watchers.mux.Lock()
+	s.Watchers = len(c.watchers.list)
+	c.watchers.mux.Unlock()
+
+	now := time.Now().UTC().UnixNano()
+	var totalTTL int64
+
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	// Eviction interval is changed under the lock by SetEvictionInterval
+	s.EvictionInterval = c.evictionInterval
+
+	for k, v := range c.data {
+		if v.isExpired() {
+			continue
+		}
+
+		s.Keys++
+		s.KeysByType[v.typeName()]++
+		s.ApproxMemoryBytes += int64(entryOverhead+len(k)+len(v.contentType)) + approxSize(v.value)
+
+		if v.expiredAfter > 0 {
+			s.KeysWithTTL++
+			totalTTL += v.expiredAfter - now
+		}
+	}
+
+	if s.KeysWithTTL > 0 {
+		s.AvgTTL = time.Duration(totalTTL / int64(s.KeysWithTTL))
+	}
+
+	return s
+}
+
+// approxSize returns the approximate size of the value in bytes.
+func approxSize(value interface{}) int64 {
+	switch v := value.(type) {
+	case string:
+		return valueOverhead + int64(len(v))
+	case []byte:
+		return valueOverhead + int64(len(v))
+	case []interface{}:
+		size := int64(valueOverhead)
+		for _, item := range v {
+			size += approxSize(item)
+		}
+
+		return size
+	case map[string]interface{}:
+		size := int64(valueOverhead)
+		for k, item := range v {
+			size += valueOverhead + int64(len(k)) + approxSize(item)
+		}
+
+		return size
+	case *sortedSet:
+		size := int64(valueOverhead)
+		for _, item := range v.items {
+			size += zsetItemOverhead + 2*int64(len(item.member))
+		}
+
+		return size
+	default:
+		return valueOverhead + floatSize
+	}
+}
@@ -0,0 +1,118 @@
+package qqcache
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_Stats_Keys(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	c.Set(testKey, testValue, 0)
+	c.Set("ttl-1", testValue, time.Hour)
+	c.Set("ttl-2", testValue, 3*time.Hour)
+	require.NoError(t, c.RPush("list", testValue, 0))
+
+	// Expired keys are not counted
+	c.Set("expired", testValue, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	s := c.Stats()
+	require.Equal(t, 4, s.Keys)
+	require.Equal(t, 2, s.KeysWithTTL)
+	require.InDelta(t, float64(2*time.Hour), float64(s.AvgTTL), float64(time.Minute))
+	require.Equal(t, map[string]int{
+		TypeValue: 3,
+		TypeBytes: 0,
+		TypeList:  1,
+		TypeHash:  0,
+		TypeGeo:   0,
+	}, s.KeysByType)
+	require.Equal(t, getCommonCacheOpts().EvictionInterval, s.EvictionInterval)
+	require.True(t, s.LastCleanerRun.IsZero())
+}
+
+func TestCache_Stats_Memory(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	require.Zero(t, c.Stats().ApproxMemoryBytes)
+
+	c.Set(testKey, "short", 0)
+	small := c.Stats().ApproxMemoryBytes
+	require.Greater(t, small, int64(0))
+
+	c.Set(testKey, string(make([]byte, 1024)), 0)
+	require.Equal(t, small+1024-int64(len("short")), c.Stats().ApproxMemoryBytes)
+}
+
+func TestCache_Stats_Calls(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	c.Set(testKey, testValue, 0)
+	c.SetBytes("bytes", []byte{1}, "application/octet-stream", 0)
+	c.Get(testKey)
+	c.Remove(testKey)
+	_, _ = c.GeoPos("geo", "a")
+
+	calls := c.Stats().Calls
+	require.Equal(t, uint64(2), calls[CommandSet])
+	require.Equal(t, uint64(1), calls[CommandGet])
+	require.Equal(t, uint64(1), calls[CommandRemove])
+	require.Equal(t, uint64(1), calls[CommandGeoPos])
+	require.Equal(t, uint64(0), calls[CommandHSet])
+	require.Len(t, calls, len(commands))
+}
+
+func TestCache_Stats_Cleaner(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	_, stop := c.Watch()
+	defer stop()
+
+	c.Set("expired-1", testValue, time.Nanosecond)
+	c.Set("expired-2", testValue, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	c.cleanerRound()
+
+	s := c.Stats()
+	require.False(t, s.LastCleanerRun.IsZero())
+	require.WithinDuration(t, time.Now(), s.LastCleanerRun, time.Second)
+	require.Equal(t, uint64(2), s.LastCleanerRemoved)
+	require.Equal(t, 1, s.Watchers)
+
+	c.cleanerRound()
+	require.Zero(t, c.Stats().LastCleanerRemoved)
+}
+
+// TestCache_Stats_SetEvictionInterval checks that the stats are read consistently
+// while the eviction interval is changed, it's meant to be run with -race.
+func TestCache_Stats_SetEvictionInterval(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= 100; i++ {
+			c.SetEvictionInterval(time.Duration(i) * time.Minute)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			assert.True(t, c.Stats().EvictionInterval > 0)
+		}
+	}()
+	wg.Wait()
+
+	require.Equal(t, 100*time.Minute, c.Stats().EvictionInterval)
+}
@@ -0,0 +1,107 @@
+package qqcache
+
+import "sync"
+
+// watchBufferSize is the number of events that could be queued for a single watcher.
+// Watcher is stopped if it's not able to keep up with the changes.
+const watchBufferSize = 256
+
+// EventType represents the type of the change of the key.
+type EventType int
+
+// Supported event types.
+const (
+	// EventSet means that value has been set or modified.
+	EventSet EventType = iota + 1
+
+	// EventRemove means that key has been removed.
+	EventRemove
+
+	// EventExpire means that key has been deleted by cache cleaner after expiration.
+	EventExpire
+)
+
+// Event represents a change of the key.
+type Event struct {
+	Type EventType
+	Key  string
+}
+
+// watcher represents a single subscription to the key changes.
+type watcher struct {
+	keys   map[string]struct{}
+	events chan Event
+}
+
+// watchers contains all active subscriptions.
+type watchers struct {
+	mux  sync.Mutex
+	list map[*watcher]struct{}
+}
+
+// Watch method subscribes to changes of the given keys, all keys are watched
+// if none is given.
+// It returns the channel of events and the function to stop watching.
+// The channel is closed when watching is stopped or the receiver is too slow
+// to keep up with the changes.
+func (c *Cache) Watch(keys ...string) (<-chan Event, func()) {
+	w := &watcher{
+		events: make(chan Event, watchBufferSize),
+	}
+	if len(keys) > 0 {
+		w.keys = make(map[string]struct{}, len(keys))
+		for _, k := range keys {
+			w.keys[k] = struct{}{}
+		}
+	}
+
+	c.watchers.mux.Lock()
+	if c.watchers.list == nil {
+		c.watchers.list = make(map[*watcher]struct{})
+	}
+	c.watchers.list[w] = struct{}{}
+	c.watchers.mux.Unlock()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			c.watchers.mux.Lock()
+			defer c.watchers.mux.Unlock()
+
+			c.watchers.remove(w)
+		})
+	}
+
+	return w.events, stop
+}
+
+// notify sends event to all watchers of the key.
+// It never blocks, watchers with full buffer are stopped.
+func (c *Cache) notify(t EventType, key string) {
+	c.watchers.mux.Lock()
+	defer c.watchers.mux.Unlock()
+
+	for w := range c.watchers.list {
+		if w.keys != nil {
+			if _, ok := w.keys[key]; !ok {
+				continue
+			}
+		}
+
+		select {
+		case w.events <- Event{Type: t, Key: key}:
+		default:
+			c.watchers.remove(w)
+		}
+	}
+}
+
+// remove method stops the watcher.
+// Method must be called with lock held.
+func (ws *watchers) remove(w *watcher) {
+	if _, ok := ws.list[w]; !ok {
+		return
+	}
+	delete(ws.list, w)
+	close(w.events)
+}
@@ -0,0 +1,106 @@
+package qqcache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_Watch(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	events, stop := c.Watch()
+	defer stop()
+
+	c.Set(testKey, testValue, 0)
+	require.NoError(t, c.RPush("list", testValue, 0))
+	require.NoError(t, c.HSet("hash", map[string]interface{}{"a": "b"}, 0))
+	require.True(t, c.Remove(testKey))
+
+	// Removing not existing key doesn't produce events
+	require.False(t, c.Remove(testKey))
+
+	expected := []Event{
+		{Type: EventSet, Key: testKey},
+		{Type: EventSet, Key: "list"},
+		{Type: EventSet, Key: "hash"},
+		{Type: EventRemove, Key: testKey},
+	}
+	for _, e := range expected {
+		require.Equal(t, e, <-events)
+	}
+	require.Len(t, events, 0)
+}
+
+func TestCache_Watch_Keys(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	events, stop := c.Watch(testKey)
+	defer stop()
+
+	c.Set("other-key", testValue, 0)
+	c.SetBytes(testKey, []byte(testValue), "text/plain", 0)
+
+	require.Equal(t, Event{Type: EventSet, Key: testKey}, <-events)
+	require.Len(t, events, 0)
+}
+
+func TestCache_Watch_Expire(t *testing.T) {
+	c := New(Opts{EvictionInterval: time.Second})
+
+	events, stop := c.Watch(testKey)
+	defer stop()
+
+	c.Set(testKey, testValue, time.Second)
+	require.Equal(t, Event{Type: EventSet, Key: testKey}, <-events)
+
+	select {
+	case e := <-events:
+		require.Equal(t, Event{Type: EventExpire, Key: testKey}, e)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expire event has not been received")
+	}
+
+	// Watchers are stopped on shutdown
+	c.Shutdown()
+	_, ok := <-events
+	require.False(t, ok)
+}
+
+func TestCache_Watch_Stop(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	events, stop := c.Watch()
+	stop()
+
+	// Second call is no-op
+	stop()
+
+	c.Set(testKey, testValue, 0)
+	_, ok := <-events
+	require.False(t, ok)
+}
+
+func TestCache_Watch_SlowReceiver(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	events, stop := c.Watch()
+	defer stop()
+
+	for i := 0; i <= watchBufferSize; i++ {
+		c.Set(strconv.Itoa(i), testValue, 0)
+	}
+
+	// Buffered events are delivered before the channel is closed
+	received := 0
+	for range events {
+		received++
+	}
+	require.Equal(t, watchBufferSize, received)
+}
@@ -0,0 +1,143 @@
+package qqcache
+
+import (
+	"errors"
+	"sort"
+)
+
+// CommandExpire is the command of the write operations that delete the keys
+// expired by the cache cleaner. It's not a command of the cache, so its calls
+// are not counted.
+const CommandExpire = "expire"
+
+var ErrInvalidWriteOp = errors.New("unsupported command of the write operation")
+
+// WriteOp represents the write operation applied to the cache. The operations
+// are reported to the write log in the order they are applied, so applying
+// them to another cache with Apply reproduces the data.
+type WriteOp struct {
+	// Offset is the sequence number of the operation, it's increased by one
+	// for every operation starting from 1.
+	Offset uint64
+
+	// Command is the command that has changed the key: CommandSet, CommandRemove,
+	// CommandRPush, CommandHSet, CommandGeoAdd, CommandRestore or CommandExpire.
+	Command string
+
+	// Entry contains the key and the value written by the command, TTL is not set.
+	// The value is the pushed item of RPush, the fields of HSet and the members
+	// of GeoAdd if they are merged into the existing key and the whole value
+	// of the key otherwise. The value is not set for CommandRemove and CommandExpire.
+	Entry Entry
+
+	// Merge reports whether the value is merged into the existing key
+	// as RestoreMerge does, otherwise the key is replaced.
+	Merge bool
+
+	// ExpireAt is the Unix time in nanoseconds when the key expires,
+	// the key will never be expired if it's 0.
+	ExpireAt int64
+}
+
+// SetWriteLog method sets the function that is called with every write
+// operation, the write log is disabled if fn is nil. It returns the offset
+// of the last operation, the following operations are reported to fn.
+// The function is called under the lock, so it must not block and must not
+// call the cache. The values of the operations are shared with the cache
+// and must not be kept or modified after the function returns.
+func (c *Cache) SetWriteLog(fn func(WriteOp)) uint64 {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.writeLog = fn
+
+	return c.writeOffset
+}
+
+// logWrite method assigns the offset to the write operation and reports it
+// to the write log. Method must be called with write lock held.
+func (c *Cache) logWrite(op WriteOp) {
+	c.writeOffset++
+	if c.writeLog != nil {
+		op.Offset = c.writeOffset
+		c.writeLog(op)
+	}
+}
+
+// Snapshot method returns the operations that restore all not expired keys
+// ordered by key and the offset of the last write operation included in them.
+// Unlike Dump, all keys are copied at once under the lock, so the snapshot
+// followed by the write operations after the offset reproduces the data.
+func (c *Cache) Snapshot() ([]WriteOp, uint64) {
+	c.countCall(CommandDump)
+
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	ops := make([]WriteOp, 0, len(c.data))
+	for k, v := range c.data {
+		if v.isExpired() {
+			continue
+		}
+		ops = append(ops, WriteOp{
+			Offset:   c.writeOffset,
+			Command:  CommandRestore,
+			Entry:    copyEntry(k, v),
+			ExpireAt: v.expiredAfter,
+		})
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].Entry.Key < ops[j].Entry.Key })
+
+	return ops, c.writeOffset
+}
+
+// Apply method applies the write operation reported by the write log of another
+// cache. CommandRemove and CommandExpire delete the key, other commands replace
+// the key or merge the value into it if Merge is set. The operation is reported
+// to the watchers and the write log of the cache with the offset of the cache.
+func (c *Cache) Apply(op WriteOp) error {
+	key := op.Entry.Key
+
+	switch op.Command {
+	case CommandRemove, CommandExpire:
+		c.mux.Lock()
+		defer c.mux.Unlock()
+
+		if _, isExist := c.data[key]; !isExist {
+			return nil
+		}
+		delete(c.data, key)
+		c.logWrite(op)
+		if op.Command == CommandExpire {
+			c.notify(EventExpire, key)
+		} else {
+			c.notify(EventRemove, key)
+		}
+
+		return nil
+	case CommandSet, CommandRPush, CommandHSet, CommandGeoAdd, CommandRestore:
+	default:
+		return ErrInvalidWriteOp
+	}
+
+	value, err := restoreValue(op.Entry)
+	if err != nil {
+		return err
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	v, isExist := c.data[key]
+	if !op.Merge || !isExist || v.isExpired() || !v.merge(value) {
+		v = entity{value: value, expiredAfter: op.ExpireAt}
+		if op.Entry.Type == TypeBytes {
+			v.contentType = op.Entry.ContentType
+		}
+	}
+	c.data[key] = v
+	c.logWrite(op)
+	c.notify(EventSet, key)
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package qqcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// dumpKeys returns all entries of the cache without TTL.
+func dumpKeys(t *testing.T, c *Cache) []Entry {
+	entries := dumpAll(t, c)
+	for i := range entries {
+		entries[i].TTL = 0
+	}
+
+	return entries
+}
+
+func TestCache_WriteLog(t *testing.T) {
+	leader := New(getCommonCacheOpts())
+	defer leader.Shutdown()
+	follower := New(getCommonCacheOpts())
+	defer follower.Shutdown()
+
+	var ops []WriteOp
+	leader.SetWriteLog(func(op WriteOp) {
+		ops = append(ops, WriteOp{Offset: op.Offset, Command: op.Command, Merge: op.Merge})
+		require.NoError(t, follower.Apply(op))
+	})
+
+	leader.Set("value", "some-value", time.Hour)
+	leader.SetBytes("bytes", []byte{0, 1, 2}, "application/octet-stream", 0)
+	require.NoError(t, leader.RPush("list", "first", 0))
+	require.NoError(t, leader.RPush("list", "second", 0))
+	require.NoError(t, leader.HSet("hash", map[string]interface{}{"a": "1"}, time.Hour))
+	require.NoError(t, leader.HSet("hash", map[string]interface{}{"b": "2"}, 0))
+	_, err := leader.GeoAdd("geo", getTestGeoMembers()[:1], 0)
+	require.NoError(t, err)
+	_, err = leader.GeoAdd("geo", getTestGeoMembers()[1:], 0)
+	require.NoError(t, err)
+	_, err = leader.Restore(Entry{Key: "list", Type: TypeList, Value: []interface{}{"third"}}, RestoreMerge)
+	require.NoError(t, err)
+	leader.Set("removed", "value", 0)
+	require.True(t, leader.Remove("removed"))
+	leader.Set("expired", "value", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	leader.cleanerRound()
+
+	// Failed commands are not logged
+	require.Error(t, leader.RPush("value", "item", 0))
+	require.False(t, leader.Remove("unknown"))
+
+	require.Equal(t, []WriteOp{
+		{Offset: 1, Command: CommandSet},
+		{Offset: 2, Command: CommandSet},
+		{Offset: 3, Command: CommandRPush},
+		{Offset: 4, Command: CommandRPush, Merge: true},
+		{Offset: 5, Command: CommandHSet},
+		{Offset: 6, Command: CommandHSet, Merge: true},
+		{Offset: 7, Command: CommandGeoAdd},
+		{Offset: 8, Command: CommandGeoAdd, Merge: true},
+		{Offset: 9, Command: CommandRestore, Merge: true},
+		{Offset: 10, Command: CommandSet},
+		{Offset: 11, Command: CommandRemove},
+		{Offset: 12, Command: CommandSet},
+		{Offset: 13, Command: CommandExpire},
+	}, ops)
+
+	require.Equal(t, dumpKeys(t, leader), dumpKeys(t, follower))
+	list, err := follower.LIndex("list", 2)
+	require.NoError(t, err)
+	require.Equal(t, "third", list)
+	ttl, ok := follower.TTL("hash")
+	require.True(t, ok)
+	require.True(t, ttl > 59*time.Minute && ttl <= time.Hour)
+	_, ok = follower.Get("expired")
+	require.False(t, ok)
+
+	// Disabled write log keeps counting the offsets
+	require.Equal(t, uint64(13), leader.SetWriteLog(nil))
+	leader.Set("value", "other", 0)
+	_, offset := leader.Snapshot()
+	require.Equal(t, uint64(14), offset)
+}
+
+func TestCache_Snapshot(t *testing.T) {
+	leader := New(getCommonCacheOpts())
+	defer leader.Shutdown()
+
+	leader.Set("value", "some-value", time.Hour)
+	require.NoError(t, leader.RPush("list", "first", 0))
+	leader.Set("expired", "value", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	snapshot, offset := leader.Snapshot()
+	require.Equal(t, uint64(3), offset)
+	require.Len(t, snapshot, 2)
+	require.Equal(t, "list", snapshot[0].Entry.Key)
+	require.Equal(t, "value", snapshot[1].Entry.Key)
+	require.Equal(t, CommandRestore, snapshot[1].Command)
+	require.True(t, snapshot[1].ExpireAt > 0)
+
+	// Snapshot followed by the operations after the offset reproduces the data
+	var ops []WriteOp
+	leader.SetWriteLog(func(op WriteOp) {
+		ops = append(ops, op)
+	})
+	require.NoError(t, leader.RPush("list", "second", 0))
+	require.True(t, leader.Remove("value"))
+
+	follower := New(getCommonCacheOpts())
+	defer follower.Shutdown()
+	follower.Set("value", "stale", 0)
+	for _, op := range append(snapshot, ops...) {
+		require.NoError(t, follower.Apply(op))
+	}
+	require.Equal(t, dumpKeys(t, leader), dumpKeys(t, follower))
+}
+
+func TestCache_Apply_Invalid(t *testing.T) {
+	c := New(getCommonCacheOpts())
+	defer c.Shutdown()
+
+	require.Equal(t, ErrInvalidWriteOp, c.Apply(WriteOp{Command: CommandGet, Entry: Entry{Key: testKey}}))
+	require.Equal(t, ErrInvalidEntry, c.Apply(WriteOp{Command: CommandSet,
+		Entry: Entry{Key: testKey, Type: TypeList, Value: "value"}}))
+
+	// Removing missing key is not an error
+	require.NoError(t, c.Apply(WriteOp{Command: CommandRemove, Entry: Entry{Key: testKey}}))
+}
@@ -0,0 +1,133 @@
+package qqcache
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// zsetItem represents a single member of the sorted set with its score.
+type zsetItem struct {
+	member string
+	score  float64
+}
+
+// less reports whether the item must be placed before the given one.
+// Items are ordered by score, members with equal scores are ordered lexicographically.
+func (i zsetItem) less(score float64, member string) bool {
+	if i.score != score {
+		return i.score < score
+	}
+
+	return i.member < member
+}
+
+// sortedSet represents a set of unique members ordered by their scores.
+type sortedSet struct {
+	scores map[string]float64
+	items  []zsetItem
+}
+
+// newSortedSet returns new empty instance of sortedSet.
+func newSortedSet() *sortedSet {
+	return &sortedSet{
+		scores: make(map[string]float64),
+		items:  make([]zsetItem, 0),
+	}
+}
+
+// len returns the number of members in the set.
+func (s *sortedSet) len() int {
+	return len(s.items)
+}
+
+// add method adds member with the given score to the set or updates
+// the score of the existing member.
+// It returns true if a new member has been added.
+func (s *sortedSet) add(member string, score float64) bool {
+	oldScore, isExist := s.scores[member]
+	if isExist {
+		if oldScore == score {
+			return false
+		}
+		s.removeItem(member, oldScore)
+	}
+
+	// Find the position to keep items ordered
+	i := s.search(score, member)
+	s.items = append(s.items, zsetItem{})
+	copy(s.items[i+1:], s.items[i:])
+	s.items[i] = zsetItem{member: member, score: score}
+	s.scores[member] = score
+
+	return !isExist
+}
+
+// remove method removes member from the set.
+// It returns true if the member has been found.
+func (s *sortedSet) remove(member string) bool {
+	score, isExist := s.scores[member]
+	if !isExist {
+		return false
+	}
+	s.removeItem(member, score)
+	delete(s.scores, member)
+
+	return true
+}
+
+// score method returns score of the member.
+func (s *sortedSet) score(member string) (float64, bool) {
+	score, isExist := s.scores[member]
+
+	return score, isExist
+}
+
+// rangeByScore returns members with scores within [min, max) range
+// in the ascending order.
+func (s *sortedSet) rangeByScore(min, max float64) []zsetItem {
+	from := sort.Search(len(s.items), func(i int) bool {
+		return s.items[i].score >= min
+	})
+	to := sort.Search(len(s.items), func(i int) bool {
+		return s.items[i].score >= max
+	})
+	if from >= to {
+		return nil
+	}
+
+	result := make([]zsetItem, to-from)
+	copy(result, s.items[from:to])
+
+	return result
+}
+
+// search returns the index of the first item that is not less than given
+// score and member.
+func (s *sortedSet) search(score float64, member string) int {
+	return sort.Search(len(s.items), func(i int) bool {
+		return !s.items[i].less(score, member)
+	})
+}
+
+func (s *sortedSet) removeItem(member string, score float64) {
+	i := s.search(score, member)
+	if i < len(s.items) && s.items[i].member == member {
+		s.items = append(s.items[:i], s.items[i+1:]...)
+	}
+}
+
+// MarshalJSON implements json.Marshaler, the set is represented
+// as a list of members with scores in the ascending order.
+func (s *sortedSet) MarshalJSON() ([]byte, error) {
+	type item struct {
+		Member string  `json:"member"`
+		Score  float64 `json:"score"`
+	}
+
+	items := make([]item, len(s.items))
+	for i := range s.items {
+		items[i] = item{Member: s.items[i].member, Score: s.items[i].score}
+	}
+
+	return json.Marshal(items)
+}
@@ -0,0 +1,58 @@
+package qqcache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortedSet_Add(t *testing.T) {
+	s := newSortedSet()
+
+	require.True(t, s.add("b", 2))
+	require.True(t, s.add("a", 1))
+	require.True(t, s.add("c", 2))
+
+	// Update score of the existing member
+	require.False(t, s.add("a", 3))
+
+	expected := []zsetItem{
+		{member: "b", score: 2},
+		{member: "c", score: 2},
+		{member: "a", score: 3},
+	}
+	require.Equal(t, expected, s.items)
+	require.Equal(t, 3, s.len())
+
+	score, ok := s.score("a")
+	require.True(t, ok)
+	require.Equal(t, float64(3), score)
+}
+
+func TestSortedSet_Remove(t *testing.T) {
+	s := newSortedSet()
+	s.add("a", 1)
+	s.add("b", 2)
+
+	require.True(t, s.remove("a"))
+	require.False(t, s.remove("a"))
+
+	_, ok := s.score("a")
+	require.False(t, ok)
+	require.Equal(t, []zsetItem{{member: "b", score: 2}}, s.items)
+}
+
+func TestSortedSet_RangeByScore(t *testing.T) {
+	s := newSortedSet()
+	s.add("a", 1)
+	s.add("b", 2)
+	s.add("c", 3)
+	s.add("d", 4)
+
+	expected := []zsetItem{
+		{member: "b", score: 2},
+		{member: "c", score: 3},
+	}
+	require.Equal(t, expected, s.rangeByScore(2, 4))
+	require.Empty(t, s.rangeByScore(5, 10))
+}
@@ -0,0 +1,366 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+)
+
+// blob is the cursor over the value serialized in the compact encoding.
+type blob struct {
+	data []byte
+	off  int
+}
+
+func (b *blob) next(n int) ([]byte, error) {
+	if n < 0 || n > len(b.data)-b.off {
+		return nil, fmt.Errorf("%w: truncated encoded value", ErrInvalidRDB)
+	}
+	p := b.data[b.off : b.off+n]
+	b.off += n
+
+	return p, nil
+}
+
+func (b *blob) byte() (byte, error) {
+	p, err := b.next(1)
+	if err != nil {
+		return 0, err
+	}
+
+	return p[0], nil
+}
+
+// int returns little-endian signed integer of n bytes.
+func (b *blob) int(n int) (int64, error) {
+	p, err := b.next(n)
+	if err != nil {
+		return 0, err
+	}
+
+	var v uint64
+	for i := n - 1; i >= 0; i-- {
+		v = v<<8 | uint64(p[i])
+	}
+	shift := uint(64 - 8*n)
+
+	return int64(v<<shift) >> shift, nil
+}
+
+func formatInt(v int64) []byte {
+	return strconv.AppendInt(nil, v, 10)
+}
+
+// decodeZiplist returns the entries of the ziplist, integers are returned as decimal strings.
+func decodeZiplist(data []byte) ([][]byte, error) {
+	b := &blob{data: data}
+	// zlbytes, zltail and zllen
+	if _, err := b.next(10); err != nil {
+		return nil, err
+	}
+
+	var items [][]byte
+	for {
+		prevLen, err := b.byte()
+		if err != nil {
+			return nil, err
+		}
+		if prevLen == 0xFF {
+			return items, nil
+		}
+		if prevLen == 0xFE {
+			if _, err := b.next(4); err != nil {
+				return nil, err
+			}
+		}
+
+		enc, err := b.byte()
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			n   int
+			v   int64
+			str = true
+		)
+		switch {
+		case enc>>6 == 0:
+			n = int(enc & 0x3F)
+		case enc>>6 == 1:
+			lo, err := b.byte()
+			if err != nil {
+				return nil, err
+			}
+			n = int(enc&0x3F)<<8 | int(lo)
+		case enc == 0x80:
+			p, err := b.next(4)
+			if err != nil {
+				return nil, err
+			}
+			n = int(binary.BigEndian.Uint32(p))
+		default:
+			str = false
+			switch {
+			case enc == 0xC0:
+				v, err = b.int(2)
+			case enc == 0xD0:
+				v, err = b.int(4)
+			case enc == 0xE0:
+				v, err = b.int(8)
+			case enc == 0xF0:
+				v, err = b.int(3)
+			case enc == 0xFE:
+				v, err = b.int(1)
+			case enc >= 0xF1 && enc <= 0xFD:
+				v = int64(enc&0x0F) - 1
+			default:
+				return nil, fmt.Errorf("%w: unknown ziplist encoding 0x%02x", ErrInvalidRDB, enc)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if !str {
+			items = append(items, formatInt(v))
+
+			continue
+		}
+		p, err := b.next(n)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, p)
+	}
+}
+
+// decodeListpack returns the entries of the listpack, integers are returned as decimal strings.
+func decodeListpack(data []byte) ([][]byte, error) {
+	b := &blob{data: data}
+	// Total bytes and number of elements
+	if _, err := b.next(6); err != nil {
+		return nil, err
+	}
+
+	var items [][]byte
+	for {
+		enc, err := b.byte()
+		if err != nil {
+			return nil, err
+		}
+		if enc == 0xFF {
+			return items, nil
+		}
+
+		var (
+			item []byte
+			// size is the size of the encoding and data that precedes backlen
+			size int
+		)
+		switch {
+		case enc&0x80 == 0:
+			item, size = formatInt(int64(enc&0x7F)), 1
+		case enc&0xC0 == 0x80:
+			n := int(enc & 0x3F)
+			if item, err = b.next(n); err != nil {
+				return nil, err
+			}
+			size = 1 + n
+		case enc&0xE0 == 0xC0:
+			lo, err := b.byte()
+			if err != nil {
+				return nil, err
+			}
+			v := int64(enc&0x1F)<<8 | int64(lo)
+			if v >= 1<<12 {
+				v -= 1 << 13
+			}
+			item, size = formatInt(v), 2
+		case enc&0xF0 == 0xE0:
+			lo, err := b.byte()
+			if err != nil {
+				return nil, err
+			}
+			n := int(enc&0x0F)<<8 | int(lo)
+			if item, err = b.next(n); err != nil {
+				return nil, err
+			}
+			size = 2 + n
+		case enc == 0xF0:
+			p, err := b.next(4)
+			if err != nil {
+				return nil, err
+			}
+			n := int(binary.LittleEndian.Uint32(p))
+			if item, err = b.next(n); err != nil {
+				return nil, err
+			}
+			size = 5 + n
+		case enc >= 0xF1 && enc <= 0xF4:
+			n := [...]int{2, 3, 4, 8}[enc-0xF1]
+			v, err := b.int(n)
+			if err != nil {
+				return nil, err
+			}
+			item, size = formatInt(v), 1+n
+		default:
+			return nil, fmt.Errorf("%w: unknown listpack encoding 0x%02x", ErrInvalidRDB, enc)
+		}
+
+		if _, err := b.next(backlenSize(size)); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+}
+
+// backlenSize returns the number of bytes of the listpack entry backlen.
+func backlenSize(size int) int {
+	switch {
+	case size <= 127:
+		return 1
+	case size < 16383:
+		return 2
+	case size < 2097151:
+		return 3
+	case size < 268435455:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// decodeIntset returns the integers of the intset as decimal strings.
+func decodeIntset(data []byte) ([][]byte, error) {
+	b := &blob{data: data}
+	p, err := b.next(8)
+	if err != nil {
+		return nil, err
+	}
+	size, n := int(binary.LittleEndian.Uint32(p)), int(binary.LittleEndian.Uint32(p[4:]))
+	if size != 2 && size != 4 && size != 8 {
+		return nil, fmt.Errorf("%w: unknown intset encoding %d", ErrInvalidRDB, size)
+	}
+	if n > (len(data)-8)/size {
+		return nil, fmt.Errorf("%w: truncated intset", ErrInvalidRDB)
+	}
+
+	items := make([][]byte, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := b.int(size)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, formatInt(v))
+	}
+
+	return items, nil
+}
+
+// decodeZipmap returns the keys and values of the zipmap one after another.
+func decodeZipmap(data []byte) ([][]byte, error) {
+	b := &blob{data: data}
+	// zmlen
+	if _, err := b.next(1); err != nil {
+		return nil, err
+	}
+
+	readLen := func() (int, bool, error) {
+		n, err := b.byte()
+		if err != nil || n == 0xFF {
+			return 0, false, err
+		}
+		if n < 0xFE {
+			return int(n), true, nil
+		}
+		p, err := b.next(4)
+		if err != nil {
+			return 0, false, err
+		}
+
+		return int(binary.LittleEndian.Uint32(p)), true, nil
+	}
+
+	var items [][]byte
+	for {
+		n, ok, err := readLen()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return items, nil
+		}
+		key, err := b.next(n)
+		if err != nil {
+			return nil, err
+		}
+
+		if n, ok, err = readLen(); err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: zipmap key without value", ErrInvalidRDB)
+		}
+		free, err := b.byte()
+		if err != nil {
+			return nil, err
+		}
+		value, err := b.next(n)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := b.next(int(free)); err != nil {
+			return nil, err
+		}
+		items = append(items, key, value)
+	}
+}
+
+// decompressLZF returns the data compressed by LZF algorithm, size is the length of uncompressed data.
+func decompressLZF(in []byte, size int) ([]byte, error) {
+	out := make([]byte, 0, size)
+	for i := 0; i < len(in); {
+		ctrl := int(in[i])
+		i++
+
+		if ctrl < 1<<5 {
+			// Literal run of ctrl+1 bytes
+			n := ctrl + 1
+			if i+n > len(in) || len(out)+n > size {
+				return nil, fmt.Errorf("%w: corrupted LZF data", ErrInvalidRDB)
+			}
+			out = append(out, in[i:i+n]...)
+			i += n
+
+			continue
+		}
+
+		// Back reference of n+2 bytes
+		n := ctrl >> 5
+		if n == 7 {
+			if i >= len(in) {
+				return nil, fmt.Errorf("%w: corrupted LZF data", ErrInvalidRDB)
+			}
+			n += int(in[i])
+			i++
+		}
+		if i >= len(in) {
+			return nil, fmt.Errorf("%w: corrupted LZF data", ErrInvalidRDB)
+		}
+		ref := len(out) - (ctrl&0x1F)<<8 - int(in[i]) - 1
+		i++
+		if ref < 0 || len(out)+n+2 > size {
+			return nil, fmt.Errorf("%w: corrupted LZF data", ErrInvalidRDB)
+		}
+		// The reference may overlap the copied bytes, so they are copied one by one
+		for j := 0; j < n+2; j++ {
+			out = append(out, out[ref+j])
+		}
+	}
+	if len(out) != size {
+		return nil, fmt.Errorf("%w: corrupted LZF data", ErrInvalidRDB)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,106 @@
+// Package rdb provides conversion between Redis RDB files and qqcache entries,
+// so the data could be migrated between Redis and bookish-spork.
+//
+// Strings, lists, hashes and sets of all encodings written by Redis are read,
+// sorted sets are skipped since qqcache has no equivalent type. Writer produces
+// files that Redis loads, values of qqcache are written as the closest Redis types.
+package rdb
+
+import (
+	"errors"
+	"hash/crc64"
+)
+
+const (
+	// magic starts RDB file, it's followed by 4 digits of the version.
+	magic = "REDIS"
+
+	// minVersion and maxVersion are the versions of RDB files that Reader supports.
+	minVersion = 1
+	maxVersion = 12
+
+	// writeVersion is the version of the written files, it's loaded by Redis 5.0 and newer.
+	writeVersion = 9
+
+	// checksumVersion is the first version with the checksum at the end of the file.
+	checksumVersion = 5
+)
+
+// Opcodes of RDB file.
+const (
+	opSlotInfo      = 0xF4
+	opFunction2     = 0xF5
+	opFunctionPreGA = 0xF6
+	opModuleAux     = 0xF7
+	opIdle          = 0xF8
+	opFreq          = 0xF9
+	opAux           = 0xFA
+	opResizeDB      = 0xFB
+	opExpireTimeMs  = 0xFC
+	opExpireTime    = 0xFD
+	opSelectDB      = 0xFE
+	opEOF           = 0xFF
+)
+
+// Types of the values in RDB file.
+const (
+	typeString         = 0
+	typeList           = 1
+	typeSet            = 2
+	typeZSet           = 3
+	typeHash           = 4
+	typeZSet2          = 5
+	typeHashZipmap     = 9
+	typeListZiplist    = 10
+	typeSetIntset      = 11
+	typeZSetZiplist    = 12
+	typeHashZiplist    = 13
+	typeListQuicklist  = 14
+	typeHashListpack   = 16
+	typeZSetListpack   = 17
+	typeListQuicklist2 = 18
+	typeSetListpack    = 20
+)
+
+// Special encodings of the strings.
+const (
+	encInt8  = 0
+	encInt16 = 1
+	encInt32 = 2
+	encLZF   = 3
+)
+
+// Containers of quicklist nodes.
+const (
+	quicklistNodePlain  = 1
+	quicklistNodePacked = 2
+)
+
+// Reasons of skipping the keys.
+const (
+	SkipExpired = "expired"
+	SkipOtherDB = "other database"
+	SkipZSet    = "zset"
+	SkipEmpty   = "empty"
+)
+
+var (
+	ErrInvalidRDB         = errors.New("invalid RDB file")
+	ErrUnsupportedVersion = errors.New("unsupported RDB version")
+	ErrUnsupportedType    = errors.New("unsupported type")
+	ErrChecksumMismatch   = errors.New("RDB checksum mismatch")
+)
+
+// crcTable is the table of CRC-64/Jones used by Redis, its polynomial
+// 0xad93d23594c935a9 is given in the reversed form.
+var crcTable = crc64.MakeTable(0x95ac9329ac4bc9b5)
+
+// crcUpdate returns the checksum updated with p. Unlike hash/crc64, Redis
+// neither inverts the initial value nor the result.
+func crcUpdate(crc uint64, p []byte) uint64 {
+	for _, b := range p {
+		crc = crcTable[byte(crc)^b] ^ crc>>8
+	}
+
+	return crc
+}
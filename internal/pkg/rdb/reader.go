@@ -0,0 +1,509 @@
+package rdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+)
+
+const (
+	// maxPrealloc limits the memory allocated for the strings before they are read,
+	// so a corrupted length doesn't lead to a huge allocation.
+	maxPrealloc = 64 << 10
+
+	// maxStringLen is the max length of the string, it's the limit of Redis strings.
+	maxStringLen = 512 << 20
+
+	// bytesContentType is the content type of the strings that aren't valid UTF-8.
+	bytesContentType = "application/octet-stream"
+)
+
+// Reader reads qqcache entries from RDB file.
+type Reader struct {
+	r       *bufio.Reader
+	crc     uint64
+	version int
+	db      int
+	curDB   int
+	done    bool
+	skipped map[string]int
+
+	// now returns the time to calculate TTL of the keys.
+	now func() time.Time
+}
+
+// NewReader returns the reader of the keys of the database db from RDB file.
+func NewReader(r io.Reader, db int) (*Reader, error) {
+	rr := &Reader{r: bufio.NewReader(r), db: db, skipped: make(map[string]int), now: time.Now}
+
+	header, err := rr.read(len(magic) + 4)
+	if err != nil || string(header[:len(magic)]) != magic {
+		return nil, fmt.Errorf("%w: no RDB header", ErrInvalidRDB)
+	}
+	rr.version, err = strconv.Atoi(string(header[len(magic):]))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid version %q", ErrInvalidRDB, header[len(magic):])
+	}
+	if rr.version < minVersion || rr.version > maxVersion {
+		return nil, fmt.Errorf("%w %d", ErrUnsupportedVersion, rr.version)
+	}
+
+	return rr, nil
+}
+
+// Version returns the version of RDB file.
+func (r *Reader) Version() int {
+	return r.version
+}
+
+// Skipped returns the number of the skipped keys by the reason.
+func (r *Reader) Skipped() map[string]int {
+	return r.skipped
+}
+
+// Read returns the next entry, io.EOF is returned at the end of the file.
+// Keys of other databases, expired keys and sorted sets are skipped.
+func (r *Reader) Read() (qqcache.Entry, error) {
+	if r.done {
+		return qqcache.Entry{}, io.EOF
+	}
+
+	var expireAt int64
+	for {
+		op, err := r.readByte()
+		if err != nil {
+			return qqcache.Entry{}, err
+		}
+
+		switch op {
+		case opEOF:
+			r.done = true
+
+			return qqcache.Entry{}, r.readChecksum()
+		case opSelectDB:
+			db, err := r.readLength()
+			if err != nil {
+				return qqcache.Entry{}, err
+			}
+			r.curDB = int(db)
+		case opExpireTime:
+			p, err := r.read(4)
+			if err != nil {
+				return qqcache.Entry{}, err
+			}
+			expireAt = int64(binary.LittleEndian.Uint32(p)) * 1000
+		case opExpireTimeMs:
+			p, err := r.read(8)
+			if err != nil {
+				return qqcache.Entry{}, err
+			}
+			expireAt = int64(binary.LittleEndian.Uint64(p))
+		case opResizeDB, opSlotInfo:
+			n := 2
+			if op == opSlotInfo {
+				n = 3
+			}
+			for i := 0; i < n; i++ {
+				if _, err := r.readLength(); err != nil {
+					return qqcache.Entry{}, err
+				}
+			}
+		case opAux:
+			for i := 0; i < 2; i++ {
+				if _, err := r.readString(); err != nil {
+					return qqcache.Entry{}, err
+				}
+			}
+		case opFunction2:
+			if _, err := r.readString(); err != nil {
+				return qqcache.Entry{}, err
+			}
+		case opIdle:
+			if _, err := r.readLength(); err != nil {
+				return qqcache.Entry{}, err
+			}
+		case opFreq:
+			if _, err := r.readByte(); err != nil {
+				return qqcache.Entry{}, err
+			}
+		case opModuleAux, opFunctionPreGA:
+			return qqcache.Entry{}, fmt.Errorf("%w: opcode 0x%02x of modules data", ErrUnsupportedType, op)
+		default:
+			e, skip, err := r.readEntry(op)
+			if err != nil {
+				return qqcache.Entry{}, err
+			}
+
+			switch {
+			case r.curDB != r.db:
+				skip = SkipOtherDB
+			case expireAt > 0 && skip == "":
+				e.TTL = time.Unix(0, expireAt*int64(time.Millisecond)).Sub(r.now())
+				if e.TTL <= 0 {
+					skip = SkipExpired
+				}
+			}
+			if skip != "" {
+				r.skipped[skip]++
+				expireAt = 0
+
+				continue
+			}
+
+			return e, nil
+		}
+	}
+}
+
+// readEntry reads the key and the value of the type, the reason is returned
+// if the value has no equivalent in qqcache.
+func (r *Reader) readEntry(typ byte) (qqcache.Entry, string, error) {
+	key, err := r.readString()
+	if err != nil {
+		return qqcache.Entry{}, "", err
+	}
+	e := qqcache.Entry{Key: string(key)}
+
+	var items [][]byte
+	switch typ {
+	case typeString:
+		data, err := r.readString()
+		if err != nil {
+			return e, "", keyError(e.Key, err)
+		}
+		if utf8.Valid(data) {
+			e.Type, e.Value = qqcache.TypeValue, string(data)
+		} else {
+			e.Type, e.Value, e.ContentType = qqcache.TypeBytes, data, bytesContentType
+		}
+
+		return e, "", nil
+	case typeList, typeSet:
+		items, err = r.readStrings(1)
+	case typeHash:
+		items, err = r.readStrings(2)
+	case typeListZiplist, typeHashZiplist:
+		items, err = r.readEncoded(decodeZiplist)
+	case typeHashListpack, typeSetListpack:
+		items, err = r.readEncoded(decodeListpack)
+	case typeSetIntset:
+		items, err = r.readEncoded(decodeIntset)
+	case typeHashZipmap:
+		items, err = r.readEncoded(decodeZipmap)
+	case typeListQuicklist, typeListQuicklist2:
+		items, err = r.readQuicklist(typ)
+	case typeZSet, typeZSet2, typeZSetZiplist, typeZSetListpack:
+		return e, SkipZSet, keyError(e.Key, r.skipZSet(typ))
+	default:
+		return e, "", fmt.Errorf("%w %d of key %q", ErrUnsupportedType, typ, e.Key)
+	}
+	if err != nil {
+		return e, "", keyError(e.Key, err)
+	}
+
+	switch typ {
+	case typeHash, typeHashZiplist, typeHashListpack, typeHashZipmap:
+		if len(items)%2 != 0 {
+			return e, "", keyError(e.Key, fmt.Errorf("%w: hash field without value", ErrInvalidRDB))
+		}
+		hm := make(map[string]interface{}, len(items)/2)
+		for i := 0; i < len(items); i += 2 {
+			hm[string(items[i])] = string(items[i+1])
+		}
+		e.Type, e.Value = qqcache.TypeHash, hm
+	default:
+		list := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			list = append(list, string(item))
+		}
+		// Sets are converted to lists of the sorted members
+		if typ == typeSet || typ == typeSetIntset || typ == typeSetListpack {
+			sort.Slice(list, func(i, j int) bool { return list[i].(string) < list[j].(string) })
+		}
+		e.Type, e.Value = qqcache.TypeList, list
+	}
+
+	return e, "", nil
+}
+
+func keyError(key string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return fmt.Errorf("failed to read key %q: %w", key, err)
+}
+
+// readStrings reads the length followed by n strings per item.
+func (r *Reader) readStrings(n int) ([][]byte, error) {
+	length, err := r.readLength()
+	if err != nil {
+		return nil, err
+	}
+
+	var items [][]byte
+	for i := uint64(0); i < length*uint64(n); i++ {
+		s, err := r.readString()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, s)
+	}
+
+	return items, nil
+}
+
+// readEncoded reads the string with the value in the compact encoding.
+func (r *Reader) readEncoded(decode func([]byte) ([][]byte, error)) ([][]byte, error) {
+	data, err := r.readString()
+	if err != nil {
+		return nil, err
+	}
+
+	return decode(data)
+}
+
+// readQuicklist reads the nodes of the list, nodes are ziplists in quicklist
+// and listpacks or plain items in quicklist 2.
+func (r *Reader) readQuicklist(typ byte) ([][]byte, error) {
+	n, err := r.readLength()
+	if err != nil {
+		return nil, err
+	}
+
+	var items [][]byte
+	for i := uint64(0); i < n; i++ {
+		container := uint64(quicklistNodePacked)
+		if typ == typeListQuicklist2 {
+			if container, err = r.readLength(); err != nil {
+				return nil, err
+			}
+		}
+		data, err := r.readString()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case container == quicklistNodePlain:
+			items = append(items, data)
+		case container != quicklistNodePacked:
+			return nil, fmt.Errorf("%w: unknown quicklist container %d", ErrInvalidRDB, container)
+		case typ == typeListQuicklist2:
+			node, err := decodeListpack(data)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, node...)
+		default:
+			node, err := decodeZiplist(data)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, node...)
+		}
+	}
+
+	return items, nil
+}
+
+// skipZSet reads the sorted set of the type.
+func (r *Reader) skipZSet(typ byte) error {
+	if typ == typeZSetZiplist || typ == typeZSetListpack {
+		_, err := r.readString()
+
+		return err
+	}
+
+	n, err := r.readLength()
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < n; i++ {
+		if _, err := r.readString(); err != nil {
+			return err
+		}
+
+		// Scores are binary doubles in ZSET_2 and strings with the length byte in ZSET,
+		// lengths 253-255 stand for NaN and infinities
+		size := 8
+		if typ == typeZSet {
+			b, err := r.readByte()
+			if err != nil {
+				return err
+			}
+			size = int(b)
+			if b >= 253 {
+				size = 0
+			}
+		}
+		if _, err := r.read(size); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readChecksum reads the checksum at the end of the file and compares it
+// with the checksum of the read data, zero checksum means it's disabled.
+func (r *Reader) readChecksum() error {
+	if r.version < checksumVersion {
+		return io.EOF
+	}
+
+	crc := r.crc
+	p, err := r.read(8)
+	if err != nil {
+		return err
+	}
+	if expected := binary.LittleEndian.Uint64(p); expected != 0 && expected != crc {
+		return ErrChecksumMismatch
+	}
+
+	return io.EOF
+}
+
+// read reads n bytes and updates the checksum.
+func (r *Reader) read(n int) ([]byte, error) {
+	var (
+		p   []byte
+		err error
+	)
+	if n <= maxPrealloc {
+		p = make([]byte, n)
+		_, err = io.ReadFull(r.r, p)
+	} else {
+		var buf bytes.Buffer
+		_, err = io.CopyN(&buf, r.r, int64(n))
+		p = buf.Bytes()
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRDB, err)
+	}
+	r.crc = crcUpdate(r.crc, p)
+
+	return p, nil
+}
+
+func (r *Reader) readByte() (byte, error) {
+	p, err := r.read(1)
+	if err != nil {
+		return 0, err
+	}
+
+	return p[0], nil
+}
+
+// readLength reads the length, it fails on special encodings of the strings.
+func (r *Reader) readLength() (uint64, error) {
+	n, special, err := r.readLengthOrEncoding()
+	if err == nil && special {
+		return 0, fmt.Errorf("%w: unexpected string encoding %d", ErrInvalidRDB, n)
+	}
+
+	return n, err
+}
+
+// readLengthOrEncoding reads the length or the special encoding of the string.
+func (r *Reader) readLengthOrEncoding() (uint64, bool, error) {
+	b, err := r.readByte()
+	if err != nil {
+		return 0, false, err
+	}
+
+	switch b >> 6 {
+	case 0:
+		return uint64(b & 0x3F), false, nil
+	case 1:
+		lo, err := r.readByte()
+		if err != nil {
+			return 0, false, err
+		}
+
+		return uint64(b&0x3F)<<8 | uint64(lo), false, nil
+	case 3:
+		return uint64(b & 0x3F), true, nil
+	}
+
+	switch b {
+	case 0x80:
+		p, err := r.read(4)
+		if err != nil {
+			return 0, false, err
+		}
+
+		return uint64(binary.BigEndian.Uint32(p)), false, nil
+	case 0x81:
+		p, err := r.read(8)
+		if err != nil {
+			return 0, false, err
+		}
+
+		return binary.BigEndian.Uint64(p), false, nil
+	default:
+		return 0, false, fmt.Errorf("%w: unknown length encoding 0x%02x", ErrInvalidRDB, b)
+	}
+}
+
+// readString reads the string, integers are returned as decimal strings.
+func (r *Reader) readString() ([]byte, error) {
+	n, special, err := r.readLengthOrEncoding()
+	if err != nil {
+		return nil, err
+	}
+	if !special {
+		if n > maxStringLen {
+			return nil, fmt.Errorf("%w: string length %d exceeds the limit", ErrInvalidRDB, n)
+		}
+
+		return r.read(int(n))
+	}
+
+	switch n {
+	case encInt8, encInt16, encInt32:
+		size := 1 << n
+		p, err := r.read(size)
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := (&blob{data: p}).int(size)
+		if err != nil {
+			return nil, err
+		}
+
+		return formatInt(v), nil
+	case encLZF:
+		clen, err := r.readLength()
+		if err != nil {
+			return nil, err
+		}
+		ulen, err := r.readLength()
+		if err != nil {
+			return nil, err
+		}
+		if clen > maxStringLen || ulen > maxStringLen {
+			return nil, fmt.Errorf("%w: compressed string length exceeds the limit", ErrInvalidRDB)
+		}
+		data, err := r.read(int(clen))
+		if err != nil {
+			return nil, err
+		}
+
+		return decompressLZF(data, int(ulen))
+	default:
+		return nil, fmt.Errorf("%w: unknown string encoding %d", ErrInvalidRDB, n)
+	}
+}
@@ -0,0 +1,179 @@
+package rdb
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+	"github.com/stretchr/testify/require"
+)
+
+// Fixtures are generated by testdata/gen.go.
+//go:generate go run testdata/gen.go testdata
+
+// testNow is the time of reading the fixtures, keys of the fixtures expire at 2100-01-01.
+var testNow = time.Date(2099, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// readFile returns the entries of the fixture by keys and the reader.
+func readFile(t *testing.T, name string, db int) (map[string]qqcache.Entry, *Reader) {
+	f, err := os.Open(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	defer f.Close()
+
+	r, err := NewReader(f, db)
+	require.NoError(t, err)
+	r.now = func() time.Time { return testNow }
+
+	entries := make(map[string]qqcache.Entry)
+	for {
+		e, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		entries[e.Key] = e
+	}
+
+	// Reading is stopped at the end
+	_, err = r.Read()
+	require.Equal(t, io.EOF, err)
+
+	return entries, r
+}
+
+func value(typ string, v interface{}) qqcache.Entry {
+	return qqcache.Entry{Type: typ, Value: v}
+}
+
+func requireEntries(t *testing.T, expected, actual map[string]qqcache.Entry) {
+	require.Len(t, actual, len(expected))
+	for key, e := range expected {
+		e.Key = key
+		require.Equal(t, e, actual[key], key)
+	}
+}
+
+func TestReader_Redis7(t *testing.T) {
+	// The file contains listpacks, quicklist 2 and functions of Redis 7
+	entries, r := readFile(t, "redis7.rdb", 0)
+	require.Equal(t, 11, r.Version())
+
+	requireEntries(t, map[string]qqcache.Entry{
+		"string":     value(qqcache.TypeValue, "Hello World"),
+		"int8":       value(qqcache.TypeValue, "12"),
+		"int16":      value(qqcache.TypeValue, "1234"),
+		"int32":      value(qqcache.TypeValue, "-123456"),
+		"compressed": value(qqcache.TypeValue, strings.Repeat("abc", 11)),
+		"bytes":      {Type: qqcache.TypeBytes, Value: []byte{0, 0xff, 0xfe}, ContentType: "application/octet-stream"},
+		"expiring":   {Type: qqcache.TypeValue, Value: "soon", TTL: 24 * time.Hour},
+		"list": value(qqcache.TypeList, []interface{}{"a", "5", "-5", "1000", "30000", "5000000",
+			"100000000", "1000000000000", strings.Repeat("x", 100), "plain item"}),
+		"hash":   value(qqcache.TypeHash, map[string]interface{}{"field1": "value1", "n": "42"}),
+		"set":    value(qqcache.TypeList, []interface{}{"7", "a", "b"}),
+		"intset": value(qqcache.TypeList, []interface{}{"1", "2", "3"}),
+	}, entries)
+	require.Equal(t, map[string]int{SkipExpired: 1, SkipZSet: 2, SkipOtherDB: 1}, r.Skipped())
+
+	// Keys of other database are read
+	entries, r = readFile(t, "redis7.rdb", 1)
+	requireEntries(t, map[string]qqcache.Entry{"other": value(qqcache.TypeValue, "db1")}, entries)
+	require.Equal(t, 14, r.Skipped()[SkipOtherDB])
+}
+
+func TestReader_Redis6(t *testing.T) {
+	// The file contains ziplists and quicklist with compressed node of Redis 6
+	entries, r := readFile(t, "redis6.rdb", 0)
+	require.Equal(t, 9, r.Version())
+
+	requireEntries(t, map[string]qqcache.Entry{
+		"list": value(qqcache.TypeList, []interface{}{"a", "1", "12", "-100", "1000", "-1000000",
+			"100000000", "10000000000", strings.Repeat("b", 300), "last"}),
+		"hash":     value(qqcache.TypeHash, map[string]interface{}{"field1": "value1", "n": "42"}),
+		"set":      value(qqcache.TypeList, []interface{}{"x", "y"}),
+		"intset":   value(qqcache.TypeList, []interface{}{"-1", "1099511627776"}),
+		"hash-big": value(qqcache.TypeHash, map[string]interface{}{"k1": "v1", "k2": "2"}),
+		"string":   value(qqcache.TypeValue, "value"),
+	}, entries)
+	require.Equal(t, map[string]int{SkipZSet: 1}, r.Skipped())
+}
+
+func TestReader_Redis2(t *testing.T) {
+	// The file contains zipmap, ziplist and expiration time in seconds
+	// of the files without the checksum
+	entries, r := readFile(t, "redis2.rdb", 0)
+	require.Equal(t, 4, r.Version())
+
+	requireEntries(t, map[string]qqcache.Entry{
+		"hash":       value(qqcache.TypeHash, map[string]interface{}{"field1": "value1", "f2": "v2"}),
+		"list":       value(qqcache.TypeList, []interface{}{"a", "b", "3"}),
+		"list-plain": value(qqcache.TypeList, []interface{}{"x", "7"}),
+		"expiring":   {Type: qqcache.TypeValue, Value: "soon", TTL: 24 * time.Hour},
+	}, entries)
+	require.Equal(t, map[string]int{SkipZSet: 1, SkipExpired: 1}, r.Skipped())
+}
+
+func TestReader_Invalid(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "redis7.rdb"))
+	require.NoError(t, err)
+
+	readAll := func(data []byte) error {
+		r, err := NewReader(bytes.NewReader(data), 0)
+		if err != nil {
+			return err
+		}
+		for {
+			if _, err := r.Read(); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+
+				return err
+			}
+		}
+	}
+	require.NoError(t, readAll(data))
+
+	// Checksum is verified unless it's disabled
+	corrupted := append([]byte{}, data...)
+	corrupted[len(corrupted)-1] ^= 1
+	require.Equal(t, ErrChecksumMismatch, readAll(corrupted))
+	copy(corrupted[len(corrupted)-8:], make([]byte, 8))
+	require.NoError(t, readAll(corrupted))
+
+	// Truncated file is rejected at any point
+	for i := 0; i < len(data)-1; i++ {
+		require.True(t, errors.Is(readAll(data[:i]), ErrInvalidRDB), i)
+	}
+
+	require.True(t, errors.Is(readAll([]byte("REDIS0013")), ErrUnsupportedVersion))
+	require.True(t, errors.Is(readAll([]byte("RDB")), ErrInvalidRDB))
+	require.True(t, errors.Is(readAll([]byte("REDIS00a1")), ErrInvalidRDB))
+
+	// Streams and modules are not supported
+	stream := []byte("REDIS0011\xfe\x00\x15\x06stream")
+	require.True(t, errors.Is(readAll(stream), ErrUnsupportedType))
+}
+
+func TestDecompressLZF(t *testing.T) {
+	// Literal and back reference overlapping the copied bytes
+	out, err := decompressLZF([]byte{0x01, 'a', 'b', 0x40, 0x01}, 6)
+	require.NoError(t, err)
+	require.Equal(t, "ababab", string(out))
+
+	for _, in := range [][]byte{
+		{0x05, 'a'},       // truncated literal
+		{0x00, 'a', 0x40}, // truncated reference
+		{0x40, 0x00},      // reference before the start
+		{0x00, 'a'},       // wrong length
+	} {
+		_, err := decompressLZF(in, 6)
+		require.True(t, errors.Is(err, ErrInvalidRDB), in)
+	}
+}
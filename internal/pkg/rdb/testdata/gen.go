@@ -0,0 +1,426 @@
+//go:build ignore
+// +build ignore
+
+// This program generates the RDB fixtures of the reader tests:
+//
+//	go generate ./internal/pkg/rdb
+//
+// The files are assembled byte by byte after the encodings that Redis 2.4
+// (RDB 4), 6.2 (RDB 9) and 7.2 (RDB 11) use when they save the data, so the
+// fixtures cover every encoding without running these versions. The encoders
+// below are independent of the package code on purpose.
+//
+// Real Redis doesn't give stable fixtures: keys are saved in the hash table
+// order and the encodings depend on the config. The fixtures could be checked
+// by the Redis tools instead, e.g.:
+//
+//	docker run --rm -v "$PWD/internal/pkg/rdb/testdata:/data" redis:6.2 redis-check-rdb /data/redis6.rdb
+//	docker run --rm -v "$PWD/internal/pkg/rdb/testdata:/data" redis:7.2 redis-check-rdb /data/redis7.rdb
+//
+// The fixtures are read as of 2099-12-31, so the keys that expire at
+// 2100-01-01 are alive and the keys that expired in 1970 are skipped.
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc64"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	futureMs = 4102444800000 // 2100-01-01
+	pastMs   = 1000
+)
+
+// crcTable is CRC-64/Jones used by Redis, the reflected polynomial is 0x95ac9329ac4bc9b5.
+var crcTable = crc64.MakeTable(0x95ac9329ac4bc9b5)
+
+func main() {
+	dir := "testdata"
+	if len(os.Args) > 1 {
+		dir = os.Args[1]
+	}
+
+	for name, data := range map[string][]byte{
+		"redis7.rdb": redis7(),
+		"redis6.rdb": redis6(),
+		"redis2.rdb": redis2(),
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// redis7 returns the file with listpacks, quicklist 2 and functions of Redis 7.
+func redis7() []byte {
+	var b bytes.Buffer
+	b.Write(aux("redis-ver", "7.2.4"))
+	b.Write(aux("redis-bits", 64))
+	b.Write(aux("ctime", 1700000000))
+	b.Write(aux("aof-base", 0))
+	b.WriteByte(0xf5) // function
+	b.Write(str("#!lua name=mylib\nredis.register_function('f', function() return 1 end)"))
+	b.Write(selectDB(0))
+	b.Write(resize(12, 2))
+
+	b.Write(cat([]byte{0x00}, str("string"), str("Hello World")))
+	b.Write(cat([]byte{0x00}, str("int8"), intStr(12)))
+	b.Write(cat([]byte{0x00}, str("int16"), intStr(1234)))
+	b.Write(cat([]byte{0x00}, str("int32"), intStr(-123456)))
+	// "abc" literal followed by back reference of 30 bytes at distance 3
+	b.Write(cat([]byte{0x00}, str("compressed"),
+		lzfStr([]byte(strings.Repeat("abc", 11)), []byte{0x02, 'a', 'b', 'c', 0xe0, 28 - 7, 2})))
+	b.Write(cat([]byte{0x00}, str("bytes"), str("\x00\xff\xfe")))
+	b.Write(cat(expireMs(futureMs), []byte{0x00}, str("expiring"), str("soon")))
+	b.Write(cat(expireMs(pastMs), []byte{0x00}, str("expired"), str("gone")))
+	// LFU frequency, then quicklist 2 with packed and plain nodes
+	b.Write(cat([]byte{0xf9, 0x05, 0x12}, str("list"), length(2),
+		length(2), str(string(listpack("a", 5, -5, 1000, 30000, 5000000, 100000000, 1000000000000,
+			strings.Repeat("x", 100)))),
+		length(1), str("plain item")))
+	b.Write(cat([]byte{0x10}, str("hash"), str(string(listpack("field1", "value1", "n", 42)))))
+	b.Write(cat([]byte{0x14}, str("set"), str(string(listpack("b", "a", 7)))))
+	b.Write(cat([]byte{0x0b}, str("intset"), str(string(intset(2, 3, 1, 2)))))
+	b.Write(cat([]byte{0x11}, str("zset"), str(string(listpack("member", 1)))))
+	b.Write(cat([]byte{0x05}, str("zset2"), length(1), str("member"), float64LE(1.5)))
+
+	b.Write(selectDB(1))
+	b.Write(resize(1, 0))
+	b.Write(cat([]byte{0x00}, str("other"), str("db1")))
+
+	return finish(11, b.Bytes())
+}
+
+// redis6 returns the file with ziplists and quicklist with compressed node of Redis 6.
+func redis6() []byte {
+	var b bytes.Buffer
+	b.Write(aux("redis-ver", "6.2.14"))
+	b.Write(aux("redis-bits", 64))
+	b.Write(selectDB(0))
+	b.Write(resize(7, 1))
+
+	node1 := ziplist("a", 1, 12, -100, 1000, -1000000, 100000000, 10000000000, strings.Repeat("b", 300))
+	node2 := ziplist("last")
+	b.Write(cat([]byte{0x0e}, str("list"), length(2), lzfStr(node1, lzfLiteral(node1)), str(string(node2))))
+	b.Write(cat([]byte{0x0d}, str("hash"), str(string(ziplist("field1", "value1", "n", 42)))))
+	b.Write(cat([]byte{0x02}, str("set"), length(2), str("y"), str("x")))
+	b.Write(cat([]byte{0x0b}, str("intset"), str(string(intset(8, 1<<40, -1)))))
+	b.Write(cat([]byte{0x04}, str("hash-big"), length(2), str("k1"), str("v1"), str("k2"), intStr(2)))
+	// LRU idle time before the value
+	b.Write(cat(expireMs(futureMs), []byte{0xf8}, length(100), []byte{0x0c}, str("zset"),
+		str(string(ziplist("m", 1)))))
+	b.Write(cat([]byte{0x00}, str("string"), str("value")))
+
+	return finish(9, b.Bytes())
+}
+
+// redis2 returns the file with zipmap, ziplist list, expiration time in seconds
+// and sorted set with string scores of Redis 2, the file has no checksum.
+func redis2() []byte {
+	var b bytes.Buffer
+	b.Write(selectDB(0))
+	b.Write(cat([]byte{0x09}, str("hash"), str(string(zipmap(
+		zipmapPair{"field1", "value1", 0},
+		zipmapPair{"f2", "v2", 2},
+	)))))
+	b.Write(cat([]byte{0x0a}, str("list"), str(string(ziplist("a", "b", 3)))))
+	b.Write(cat([]byte{0x01}, str("list-plain"), length(2), str("x"), intStr(7)))
+	b.Write(cat(expireS(futureMs/1000), []byte{0x00}, str("expiring"), str("soon")))
+	// Scores are strings, 254 and 253 are +inf and nan
+	b.Write(cat([]byte{0x03}, str("zset"), length(3),
+		str("a"), []byte{3}, []byte("1.5"),
+		str("b"), []byte{254},
+		str("c"), []byte{253}))
+	b.Write(cat(expireS(1), []byte{0x02}, str("expired-set"), length(1), str("x")))
+
+	return finish(4, b.Bytes())
+}
+
+// finish adds the header, EOF and the checksum of the files since RDB 5.
+func finish(version int, body []byte) []byte {
+	data := cat([]byte(fmt.Sprintf("REDIS%04d", version)), body, []byte{0xff})
+	if version >= 5 {
+		data = cat(data, uint64LE(crc64Redis(data)))
+	}
+
+	return data
+}
+
+// crc64Redis returns the checksum as Redis computes it: without the initial
+// and the final inversion of the standard CRC-64 functions.
+func crc64Redis(data []byte) uint64 {
+	crc := uint64(0)
+	for _, c := range data {
+		crc = crcTable[byte(crc)^c] ^ (crc >> 8)
+	}
+
+	return crc
+}
+
+func cat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func length(n int) []byte {
+	switch {
+	case n < 1<<6:
+		return []byte{byte(n)}
+	case n < 1<<14:
+		return []byte{0x40 | byte(n>>8), byte(n)}
+	case n <= math.MaxUint32:
+		b := []byte{0x80, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(b[1:], uint32(n))
+
+		return b
+	default:
+		b := []byte{0x81, 0, 0, 0, 0, 0, 0, 0, 0}
+		binary.BigEndian.PutUint64(b[1:], uint64(n))
+
+		return b
+	}
+}
+
+func str(s string) []byte {
+	return cat(length(len(s)), []byte(s))
+}
+
+func intStr(v int) []byte {
+	switch {
+	case v >= math.MinInt8 && v <= math.MaxInt8:
+		return []byte{0xc0, byte(int8(v))}
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		return cat([]byte{0xc1}, uint16LE(uint16(int16(v))))
+	default:
+		return cat([]byte{0xc2}, uint32LE(uint32(int32(v))))
+	}
+}
+
+// lzfLiteral returns valid LZF stream made of literal runs only.
+func lzfLiteral(data []byte) []byte {
+	var out []byte
+	for i := 0; i < len(data); i += 32 {
+		end := i + 32
+		if end > len(data) {
+			end = len(data)
+		}
+		out = append(out, byte(end-i-1))
+		out = append(out, data[i:end]...)
+	}
+
+	return out
+}
+
+func lzfStr(data, compressed []byte) []byte {
+	return cat([]byte{0xc3}, length(len(compressed)), length(len(data)), compressed)
+}
+
+func ziplistEntry(prevLen int, item interface{}) []byte {
+	var prev []byte
+	if prevLen < 254 {
+		prev = []byte{byte(prevLen)}
+	} else {
+		prev = cat([]byte{0xfe}, uint32LE(uint32(prevLen)))
+	}
+
+	var enc []byte
+	switch v := item.(type) {
+	case int:
+		switch {
+		case v >= 0 && v <= 12:
+			enc = []byte{0xf1 + byte(v)}
+		case v >= math.MinInt8 && v <= math.MaxInt8:
+			enc = []byte{0xfe, byte(int8(v))}
+		case v >= math.MinInt16 && v <= math.MaxInt16:
+			enc = cat([]byte{0xc0}, uint16LE(uint16(int16(v))))
+		case v >= -1<<23 && v < 1<<23:
+			enc = cat([]byte{0xf0}, uint32LE(uint32(int32(v)))[:3])
+		case v >= math.MinInt32 && v <= math.MaxInt32:
+			enc = cat([]byte{0xd0}, uint32LE(uint32(int32(v))))
+		default:
+			enc = cat([]byte{0xe0}, uint64LE(uint64(v)))
+		}
+	case string:
+		n := len(v)
+		switch {
+		case n < 64:
+			enc = []byte{byte(n)}
+		case n < 16384:
+			enc = []byte{0x40 | byte(n>>8), byte(n)}
+		default:
+			enc = cat([]byte{0x80}, uint32BE(uint32(n)))
+		}
+		enc = append(enc, v...)
+	}
+
+	return cat(prev, enc)
+}
+
+func ziplist(items ...interface{}) []byte {
+	var body []byte
+	prev, tail := 0, 10
+	for _, item := range items {
+		e := ziplistEntry(prev, item)
+		tail = 10 + len(body)
+		body = append(body, e...)
+		prev = len(e)
+	}
+
+	return cat(uint32LE(uint32(10+len(body)+1)), uint32LE(uint32(tail)), uint16LE(uint16(len(items))),
+		body, []byte{0xff})
+}
+
+func backlen(l int) []byte {
+	switch {
+	case l <= 127:
+		return []byte{byte(l)}
+	case l < 16383:
+		return []byte{byte(l >> 7), byte(l&127) | 128}
+	default:
+		return []byte{byte(l >> 14), byte((l>>7)&127) | 128, byte(l&127) | 128}
+	}
+}
+
+func listpackEntry(item interface{}) []byte {
+	var enc []byte
+	switch v := item.(type) {
+	case int:
+		switch {
+		case v >= 0 && v <= 127:
+			enc = []byte{byte(v)}
+		case v >= -4096 && v <= 4095:
+			u := v & 0x1fff
+			enc = []byte{0xc0 | byte(u>>8), byte(u)}
+		case v >= math.MinInt16 && v <= math.MaxInt16:
+			enc = cat([]byte{0xf1}, uint16LE(uint16(int16(v))))
+		case v >= -1<<23 && v < 1<<23:
+			enc = cat([]byte{0xf2}, uint32LE(uint32(int32(v)))[:3])
+		case v >= math.MinInt32 && v <= math.MaxInt32:
+			enc = cat([]byte{0xf3}, uint32LE(uint32(int32(v))))
+		default:
+			enc = cat([]byte{0xf4}, uint64LE(uint64(v)))
+		}
+	case string:
+		n := len(v)
+		switch {
+		case n < 64:
+			enc = []byte{0x80 | byte(n)}
+		case n < 4096:
+			enc = []byte{0xe0 | byte(n>>8), byte(n)}
+		default:
+			enc = cat([]byte{0xf0}, uint32LE(uint32(n)))
+		}
+		enc = append(enc, v...)
+	}
+
+	return cat(enc, backlen(len(enc)))
+}
+
+func listpack(items ...interface{}) []byte {
+	var body []byte
+	for _, item := range items {
+		body = append(body, listpackEntry(item)...)
+	}
+
+	return cat(uint32LE(uint32(6+len(body)+1)), uint16LE(uint16(len(items))), body, []byte{0xff})
+}
+
+func intset(enc int, values ...int) []byte {
+	sort.Ints(values)
+	b := cat(uint32LE(uint32(enc)), uint32LE(uint32(len(values))))
+	for _, v := range values {
+		switch enc {
+		case 2:
+			b = append(b, uint16LE(uint16(int16(v)))...)
+		case 4:
+			b = append(b, uint32LE(uint32(int32(v)))...)
+		default:
+			b = append(b, uint64LE(uint64(v))...)
+		}
+	}
+
+	return b
+}
+
+type zipmapPair struct {
+	key, value string
+	free       int
+}
+
+func zipmap(pairs ...zipmapPair) []byte {
+	b := []byte{byte(len(pairs))}
+	for _, p := range pairs {
+		b = append(b, byte(len(p.key)))
+		b = append(b, p.key...)
+		b = append(b, byte(len(p.value)), byte(p.free))
+		b = append(b, p.value...)
+		b = append(b, make([]byte, p.free)...)
+	}
+
+	return append(b, 0xff)
+}
+
+func aux(key string, value interface{}) []byte {
+	switch v := value.(type) {
+	case int:
+		return cat([]byte{0xfa}, str(key), intStr(v))
+	default:
+		return cat([]byte{0xfa}, str(key), str(v.(string)))
+	}
+}
+
+func expireMs(ms uint64) []byte {
+	return cat([]byte{0xfc}, uint64LE(ms))
+}
+
+func expireS(s uint64) []byte {
+	return cat([]byte{0xfd}, uint32LE(uint32(s)))
+}
+
+func selectDB(db int) []byte {
+	return cat([]byte{0xfe}, length(db))
+}
+
+func resize(size, expires int) []byte {
+	return cat([]byte{0xfb}, length(size), length(expires))
+}
+
+func float64LE(f float64) []byte {
+	return uint64LE(math.Float64bits(f))
+}
+
+func uint16LE(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.LittleEndian.PutUint16(b, v)
+
+	return b
+}
+
+func uint32LE(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+
+	return b
+}
+
+func uint32BE(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+
+	return b
+}
+
+func uint64LE(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, v)
+
+	return b
+}
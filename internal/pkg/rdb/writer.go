@@ -0,0 +1,219 @@
+package rdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+)
+
+// Writer writes qqcache entries to RDB file of a single database. Values
+// are written as strings, lists as lists, hashes as hashes and geo indexes as
+// sorted sets with geohash scores, so they are available to Redis GEO commands.
+// Strings are written as is, other JSON values are written as JSON strings.
+type Writer struct {
+	w       *bufio.Writer
+	crc     uint64
+	buf     [9]byte
+	skipped map[string]int
+
+	// now returns the time to calculate the expiration time of the keys.
+	now func() time.Time
+}
+
+// NewWriter writes the header of RDB file and returns the writer of the entries.
+func NewWriter(w io.Writer) *Writer {
+	rw := &Writer{w: bufio.NewWriter(w), skipped: make(map[string]int), now: time.Now}
+
+	rw.write([]byte(fmt.Sprintf("%s%04d", magic, writeVersion)))
+	rw.writeByte(opSelectDB)
+	rw.writeLength(0)
+
+	return rw
+}
+
+// Skipped returns the number of the skipped keys by the reason.
+// Empty lists, hashes and geo indexes are skipped since Redis doesn't keep empty keys.
+func (w *Writer) Skipped() map[string]int {
+	return w.skipped
+}
+
+// Write writes the entry.
+func (w *Writer) Write(e qqcache.Entry) error {
+	var (
+		typ   byte
+		items [][]byte
+	)
+	switch e.Type {
+	case qqcache.TypeValue:
+		data, err := text(e.Value)
+		if err != nil {
+			return err
+		}
+		typ, items = typeString, [][]byte{data}
+	case qqcache.TypeBytes:
+		data, ok := e.Value.([]byte)
+		if !ok {
+			return fmt.Errorf("%w: key %q", qqcache.ErrInvalidEntry, e.Key)
+		}
+		typ, items = typeString, [][]byte{data}
+	case qqcache.TypeList:
+		list, ok := e.Value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%w: key %q", qqcache.ErrInvalidEntry, e.Key)
+		}
+		for _, v := range list {
+			data, err := text(v)
+			if err != nil {
+				return err
+			}
+			items = append(items, data)
+		}
+		typ = typeList
+	case qqcache.TypeHash:
+		hm, ok := e.Value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%w: key %q", qqcache.ErrInvalidEntry, e.Key)
+		}
+		fields := make([]string, 0, len(hm))
+		for field := range hm {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			data, err := text(hm[field])
+			if err != nil {
+				return err
+			}
+			items = append(items, []byte(field), data)
+		}
+		typ = typeHash
+	case qqcache.TypeGeo:
+		members, ok := e.Value.([]qqcache.GeoMember)
+		if !ok {
+			return fmt.Errorf("%w: key %q", qqcache.ErrInvalidEntry, e.Key)
+		}
+		for _, m := range members {
+			score := make([]byte, 8)
+			binary.LittleEndian.PutUint64(score, math.Float64bits(qqcache.GeoScore(m.GeoPoint)))
+			items = append(items, []byte(m.Name), score)
+		}
+		typ = typeZSet2
+	default:
+		return fmt.Errorf("%w %q of key %q", ErrUnsupportedType, e.Type, e.Key)
+	}
+	if len(items) == 0 {
+		w.skipped[SkipEmpty]++
+
+		return nil
+	}
+
+	if e.TTL > 0 {
+		w.writeByte(opExpireTimeMs)
+		binary.LittleEndian.PutUint64(w.buf[:8], uint64(w.now().UnixNano()/int64(time.Millisecond)+durationToMs(e.TTL)))
+		w.write(w.buf[:8])
+	}
+	w.writeByte(typ)
+	w.writeString([]byte(e.Key))
+
+	switch typ {
+	case typeString:
+		w.writeString(items[0])
+	case typeList:
+		w.writeLength(uint64(len(items)))
+		for _, item := range items {
+			w.writeString(item)
+		}
+	default:
+		// Hash fields and values or members and binary scores of sorted set
+		w.writeLength(uint64(len(items) / 2))
+		for i := 0; i < len(items); i += 2 {
+			w.writeString(items[i])
+			if typ == typeZSet2 {
+				w.write(items[i+1])
+			} else {
+				w.writeString(items[i+1])
+			}
+		}
+	}
+
+	return nil
+}
+
+// Close writes the end of the file with the checksum and flushes the data,
+// it doesn't close the underlying writer.
+func (w *Writer) Close() error {
+	w.writeByte(opEOF)
+	binary.LittleEndian.PutUint64(w.buf[:8], w.crc)
+	w.write(w.buf[:8])
+
+	return w.w.Flush()
+}
+
+// write writes the data and updates the checksum, the error of the buffered
+// writer is returned on flush.
+func (w *Writer) write(p []byte) {
+	_, _ = w.w.Write(p)
+	w.crc = crcUpdate(w.crc, p)
+}
+
+func (w *Writer) writeByte(b byte) {
+	w.buf[0] = b
+	w.write(w.buf[:1])
+}
+
+// writeLength writes the length in the shortest encoding.
+func (w *Writer) writeLength(n uint64) {
+	switch {
+	case n < 1<<6:
+		w.writeByte(byte(n))
+	case n < 1<<14:
+		w.buf[0], w.buf[1] = 0x40|byte(n>>8), byte(n)
+		w.write(w.buf[:2])
+	case n <= math.MaxUint32:
+		w.buf[0] = 0x80
+		binary.BigEndian.PutUint32(w.buf[1:5], uint32(n))
+		w.write(w.buf[:5])
+	default:
+		w.buf[0] = 0x81
+		binary.BigEndian.PutUint64(w.buf[1:9], n)
+		w.write(w.buf[:9])
+	}
+}
+
+func (w *Writer) writeString(s []byte) {
+	w.writeLength(uint64(len(s)))
+	w.write(s)
+}
+
+// text returns strings as is and other values as JSON.
+func text(v interface{}) ([]byte, error) {
+	if s, ok := v.(string); ok {
+		return []byte(s), nil
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// durationToMs returns the duration in milliseconds rounded up, so the key doesn't expire earlier.
+func durationToMs(d time.Duration) int64 {
+	if d > math.MaxInt64-time.Millisecond {
+		return math.MaxInt64 / int64(time.Millisecond)
+	}
+
+	return int64((d + time.Millisecond - 1) / time.Millisecond)
+}
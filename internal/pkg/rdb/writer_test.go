@@ -0,0 +1,127 @@
+package rdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCRC(t *testing.T) {
+	// Check value of CRC-64/Jones used by Redis
+	require.Equal(t, uint64(0xe9c6d914c4b8d9ca), crcUpdate(0, []byte("123456789")))
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.now = func() time.Time { return testNow }
+
+	long := strings.Repeat("v", 20000)
+	for _, e := range []qqcache.Entry{
+		{Key: "string", Type: qqcache.TypeValue, Value: "some-value", TTL: time.Hour},
+		{Key: "json", Type: qqcache.TypeValue, Value: map[string]interface{}{"a": "<b>", "n": json.Number("1")}},
+		{Key: "bytes", Type: qqcache.TypeBytes, Value: []byte{0, 0xff}, ContentType: "image/png"},
+		{Key: "list", Type: qqcache.TypeList, Value: []interface{}{"a", 1.5, long}},
+		{Key: "hash", Type: qqcache.TypeHash, Value: map[string]interface{}{"k": "v", "n": nil}},
+		{Key: "empty", Type: qqcache.TypeList, Value: []interface{}{}},
+		{Key: "geo", Type: qqcache.TypeGeo, Value: []qqcache.GeoMember{
+			{Name: "Palermo", GeoPoint: qqcache.GeoPoint{Longitude: 13.361389, Latitude: 38.115556}},
+		}},
+	} {
+		require.NoError(t, w.Write(e))
+	}
+	require.NoError(t, w.Close())
+	require.Equal(t, map[string]int{SkipEmpty: 1}, w.Skipped())
+
+	data := buf.Bytes()
+	require.True(t, bytes.HasPrefix(data, []byte("REDIS0009")))
+
+	// Geo index is written as sorted set with the score of Redis GEO commands
+	score := make([]byte, 8)
+	binary.LittleEndian.PutUint64(score, math.Float64bits(3479099956230698))
+	require.True(t, bytes.Contains(data, append([]byte("\x05\x03geo\x01\x07Palermo"), score...)))
+
+	r, err := NewReader(bytes.NewReader(data), 0)
+	require.NoError(t, err)
+	r.now = func() time.Time { return testNow }
+
+	var entries []qqcache.Entry
+	for {
+		e, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		entries = append(entries, e)
+	}
+
+	// Non-string values are converted to strings, content types are lost
+	require.Equal(t, []qqcache.Entry{
+		{Key: "string", Type: qqcache.TypeValue, Value: "some-value", TTL: time.Hour},
+		{Key: "json", Type: qqcache.TypeValue, Value: `{"a":"<b>","n":1}`},
+		{Key: "bytes", Type: qqcache.TypeBytes, Value: []byte{0, 0xff}, ContentType: "application/octet-stream"},
+		{Key: "list", Type: qqcache.TypeList, Value: []interface{}{"a", "1.5", long}},
+		{Key: "hash", Type: qqcache.TypeHash, Value: map[string]interface{}{"k": "v", "n": "null"}},
+	}, entries)
+	require.Equal(t, map[string]int{SkipZSet: 1}, r.Skipped())
+}
+
+func TestWriter_RedisCheckRDB(t *testing.T) {
+	// The file is checked by the tool shipped with Redis if it's installed
+	checker, err := exec.LookPath("redis-check-rdb")
+	if err != nil {
+		t.Skip("redis-check-rdb is not found")
+	}
+
+	dir, err := ioutil.TempDir("", "rdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "dump.rdb")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	w := NewWriter(f)
+	for _, e := range []qqcache.Entry{
+		{Key: "string", Type: qqcache.TypeValue, Value: "some-value", TTL: time.Hour},
+		{Key: "json", Type: qqcache.TypeValue, Value: map[string]interface{}{"a": "<b>", "n": json.Number("1")}},
+		{Key: "bytes", Type: qqcache.TypeBytes, Value: []byte{0, 0xff}, ContentType: "image/png"},
+		{Key: "list", Type: qqcache.TypeList, Value: []interface{}{"a", 1.5, strings.Repeat("v", 20000)}},
+		{Key: "hash", Type: qqcache.TypeHash, Value: map[string]interface{}{"k": "v", "n": nil}},
+		{Key: "geo", Type: qqcache.TypeGeo, Value: []qqcache.GeoMember{
+			{Name: "Palermo", GeoPoint: qqcache.GeoPoint{Longitude: 13.361389, Latitude: 38.115556}},
+			{Name: "Catania", GeoPoint: qqcache.GeoPoint{Longitude: 15.087269, Latitude: 37.502669}},
+		}},
+	} {
+		require.NoError(t, w.Write(e))
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+
+	out, err := exec.Command(checker, path).CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+func TestWriter_Invalid(t *testing.T) {
+	w := NewWriter(ioutil.Discard)
+
+	err := w.Write(qqcache.Entry{Key: "key", Type: qqcache.TypeList, Value: "not a list"})
+	require.True(t, errors.Is(err, qqcache.ErrInvalidEntry))
+
+	err = w.Write(qqcache.Entry{Key: "key", Type: "stream"})
+	require.True(t, errors.Is(err, ErrUnsupportedType))
+}
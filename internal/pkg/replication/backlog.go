@@ -0,0 +1,104 @@
+package replication
+
+import "sync"
+
+// backlog keeps the frames of the latest write operations up to the max size
+// in bytes, the oldest frames are dropped. The last frame is kept even if
+// it's larger than the max size.
+type backlog struct {
+	mux     sync.Mutex
+	maxSize int
+
+	// frames contains the frames of consecutive offsets starting from first
+	frames [][]byte
+	first  uint64
+	size   int
+
+	// changed is closed and replaced when frames are appended or dropped
+	changed chan struct{}
+}
+
+func newBacklog(maxSize int) *backlog {
+	return &backlog{maxSize: maxSize, first: 1, changed: make(chan struct{})}
+}
+
+// append method adds the frame of the operation with the offset.
+// The backlog is reset if the offset doesn't follow the last one,
+// so the followers behind it do the full sync.
+func (b *backlog) append(offset uint64, data []byte) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if offset != b.first+uint64(len(b.frames)) {
+		b.resetLocked(offset)
+	}
+
+	b.frames = append(b.frames, data)
+	b.size += len(data)
+	for b.size > b.maxSize && len(b.frames) > 1 {
+		b.size -= len(b.frames[0])
+		// Release the data of the dropped frame as the array is still referenced
+		b.frames[0] = nil
+		b.frames = b.frames[1:]
+		b.first++
+	}
+
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// reset method drops all frames, the next frame is expected to have the offset.
+func (b *backlog) reset(offset uint64) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.resetLocked(offset)
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// start method sets the offset of the next frame if no frames have been appended yet.
+func (b *backlog) start(offset uint64) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if len(b.frames) == 0 {
+		b.first = offset
+	}
+}
+
+func (b *backlog) resetLocked(offset uint64) {
+	b.frames = nil
+	b.size = 0
+	b.first = offset
+}
+
+// has method reports whether the operations following the offset could be read.
+func (b *backlog) has(offset uint64) bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	return offset+1 >= b.first && offset < b.first+uint64(len(b.frames))
+}
+
+// read method appends the frames of the operations following the offset to dst.
+// It returns the channel that is closed when the backlog changes and false
+// if the operations following the offset have been dropped.
+func (b *backlog) read(offset uint64, dst [][]byte) ([][]byte, <-chan struct{}, bool) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if offset+1 < b.first || offset >= b.first+uint64(len(b.frames)) {
+		return dst, b.changed, false
+	}
+
+	return append(dst, b.frames[offset+1-b.first:]...), b.changed, true
+}
+
+// status method returns the offset of the last operation and the size of the backlog.
+func (b *backlog) status() (uint64, int) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	return b.first + uint64(len(b.frames)) - 1, b.size
+}
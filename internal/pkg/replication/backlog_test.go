@@ -0,0 +1,62 @@
+package replication
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBacklog(t *testing.T) {
+	b := newBacklog(4)
+	b.start(3)
+	require.True(t, b.has(2))
+	require.False(t, b.has(3))
+
+	changed := func() <-chan struct{} {
+		_, ch, _ := b.read(2, nil)
+
+		return ch
+	}()
+	b.append(3, []byte("a"))
+	b.append(4, []byte("bc"))
+	select {
+	case <-changed:
+	default:
+		t.Fatal("changed channel is not closed")
+	}
+
+	frames, _, ok := b.read(2, nil)
+	require.True(t, ok)
+	require.Equal(t, [][]byte{[]byte("a"), []byte("bc")}, frames)
+	frames, _, ok = b.read(4, nil)
+	require.True(t, ok)
+	require.Empty(t, frames)
+	_, _, ok = b.read(5, nil)
+	require.False(t, ok)
+
+	// The oldest frames are dropped
+	b.append(5, []byte("de"))
+	require.False(t, b.has(2))
+	require.True(t, b.has(3))
+	offset, size := b.status()
+	require.Equal(t, uint64(5), offset)
+	require.Equal(t, 4, size)
+
+	// The last frame is kept even if it's larger than the max size
+	b.append(6, []byte("fghij"))
+	frames, _, ok = b.read(5, nil)
+	require.True(t, ok)
+	require.Equal(t, [][]byte{[]byte("fghij")}, frames)
+	require.False(t, b.has(4))
+
+	// Gap in the offsets resets the backlog
+	b.append(8, []byte("k"))
+	require.False(t, b.has(6))
+	require.True(t, b.has(7))
+	b.reset(9)
+	require.False(t, b.has(7))
+	require.True(t, b.has(8))
+	offset, size = b.status()
+	require.Equal(t, uint64(8), offset)
+	require.Equal(t, 0, size)
+}
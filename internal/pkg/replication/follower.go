@@ -0,0 +1,343 @@
+package replication
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultTimeout           = 10 * time.Second
+	defaultReconnectInterval = time.Second
+
+	// maxErrorLen limits the error message read from the leader response
+	maxErrorLen = 1024
+)
+
+var ErrTimeout = errors.New("leader has not sent anything within the timeout")
+
+// FollowerOpts represents the options to create new instance of Follower.
+type FollowerOpts struct {
+	// Cache is the cache the operations of the leader are applied to.
+	Cache *qqcache.Cache
+
+	// Leader is the URL of the service API of the leader.
+	Leader string
+
+	// Client is used to connect to the leader, http.DefaultClient is used
+	// if it's not set. It must not have a timeout as the stream is endless.
+	Client *http.Client
+
+	// Timeout is the max time to wait for the response headers and
+	// the frames of the stream, 10 seconds are used if it's not set.
+	Timeout time.Duration
+
+	// ReconnectInterval is the delay before reconnecting after a failure,
+	// a second is used if it's not set.
+	ReconnectInterval time.Duration
+
+	// Log is used to report the syncs and the failures, it's optional.
+	Log *zap.Logger
+}
+
+// Follower applies the write operations streamed by the leader to the cache.
+type Follower struct {
+	opts FollowerOpts
+
+	mux          sync.Mutex
+	state        string
+	id           string
+	offset       uint64
+	leaderOffset uint64
+	lastFrame    time.Time
+	fullSyncs    uint64
+	partialSyncs uint64
+
+	// synced is closed when the first full sync is completed
+	synced     chan struct{}
+	syncedOnce sync.Once
+}
+
+// NewFollower returns new instance of Follower.
+func NewFollower(opts FollowerOpts) *Follower {
+	opts.Leader = strings.TrimSuffix(opts.Leader, "/")
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.ReconnectInterval <= 0 {
+		opts.ReconnectInterval = defaultReconnectInterval
+	}
+	if opts.Log == nil {
+		opts.Log = zap.NewNop()
+	}
+
+	return &Follower{opts: opts, state: StateConnecting, synced: make(chan struct{})}
+}
+
+// Synced method returns the channel that is closed when the first full sync
+// is completed, the cache contains the data of the leader after that.
+func (f *Follower) Synced() <-chan struct{} {
+	return f.synced
+}
+
+// Run method syncs the cache with the leader until the context is done.
+// The follower reconnects immediately if the leader ends the stream and
+// after the reconnect interval if the sync fails.
+func (f *Follower) Run(ctx context.Context) {
+	for {
+		err := f.sync(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		f.mux.Lock()
+		f.state = StateConnecting
+		f.mux.Unlock()
+
+		if err == nil {
+			continue
+		}
+		f.opts.Log.Warn("replication from leader failed", zap.String("leader", f.opts.Leader), zap.Error(err))
+
+		t := time.NewTimer(f.opts.ReconnectInterval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// sync method connects to the leader and applies the stream until it ends.
+// It returns nil if the leader ends the stream between the frames.
+func (f *Follower) sync(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The connection is canceled if the leader sends nothing within the timeout
+	var timedOut int32
+	idle := time.AfterFunc(f.opts.Timeout, func() {
+		atomic.StoreInt32(&timedOut, 1)
+		cancel()
+	})
+	defer idle.Stop()
+
+	err := f.stream(ctx, func() {
+		idle.Reset(f.opts.Timeout)
+
+		f.mux.Lock()
+		f.lastFrame = time.Now()
+		f.mux.Unlock()
+	})
+	if err != nil && atomic.LoadInt32(&timedOut) == 1 {
+		return ErrTimeout
+	}
+
+	return err
+}
+
+// stream method requests the stream from the offset of the last applied
+// operation and applies the frames, touch is called on every frame.
+func (f *Follower) stream(ctx context.Context, touch func()) error {
+	f.mux.Lock()
+	id, offset := f.id, f.offset
+	f.mux.Unlock()
+
+	query := url.Values{idQuery: {id}, offsetQuery: {strconv.FormatUint(offset, 10)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.opts.Leader+SyncPath+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := f.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorLen))
+
+		return fmt.Errorf("got the %d status code from the leader: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	mode := resp.Header.Get(syncHeader)
+	leaderID := resp.Header.Get(idHeader)
+	leaderOffset, err := strconv.ParseUint(resp.Header.Get(offsetHeader), 10, 64)
+	switch {
+	case err != nil || leaderID == "":
+		return fmt.Errorf("%w: missing replication headers", ErrInvalidStream)
+	case mode == SyncPartial && (leaderID != id || leaderOffset != offset):
+		return fmt.Errorf("%w: partial resync from unexpected offset %d", ErrInvalidStream, leaderOffset)
+	case mode != SyncFull && mode != SyncPartial:
+		return fmt.Errorf("%w: unknown sync mode %q", ErrInvalidStream, mode)
+	}
+
+	r := bufio.NewReader(resp.Body)
+	if err := readHeader(r); err != nil {
+		return err
+	}
+	touch()
+
+	log := f.opts.Log.With(zap.String("leader", f.opts.Leader), zap.String("sync", mode),
+		zap.Uint64("offset", leaderOffset))
+
+	// snapshot contains the keys of the snapshot until it's completed,
+	// they are staged to leave the cache intact if the sync is interrupted
+	var snapshot []qqcache.WriteOp
+	f.mux.Lock()
+	if mode == SyncFull {
+		f.state = StateSyncing
+		snapshot = []qqcache.WriteOp{}
+	} else {
+		f.state = StateConnected
+		f.leaderOffset = leaderOffset
+		f.partialSyncs++
+	}
+	f.mux.Unlock()
+	log.Info("syncing with leader")
+
+	for {
+		fr, err := readFrame(r)
+		if err == io.EOF {
+			if snapshot != nil {
+				return fmt.Errorf("%w: snapshot is incomplete", ErrInvalidStream)
+			}
+
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		touch()
+
+		switch {
+		case fr.typ == frameSnapshot && snapshot != nil:
+			snapshot = append(snapshot, fr.op)
+		case fr.typ == frameSynced && snapshot != nil:
+			removed, err := f.applySnapshot(snapshot)
+			if err != nil {
+				// The data is inconsistent now, so it's restored by the full sync only
+				f.mux.Lock()
+				f.id = ""
+				f.mux.Unlock()
+
+				return err
+			}
+			log.Info("full sync completed", zap.Int("keys", len(snapshot)), zap.Int("removed", removed))
+
+			snapshot = nil
+			offset = leaderOffset
+			f.mux.Lock()
+			f.id, f.offset, f.leaderOffset = leaderID, offset, offset
+			f.state = StateConnected
+			f.fullSyncs++
+			f.mux.Unlock()
+			f.syncedOnce.Do(func() { close(f.synced) })
+		case fr.typ == frameOp && snapshot == nil:
+			if fr.op.Offset != offset+1 {
+				return fmt.Errorf("%w: got offset %d after %d", ErrInvalidStream, fr.op.Offset, offset)
+			}
+			if err := f.opts.Cache.Apply(fr.op); err != nil {
+				// The operation is skipped, so the data could be restored by the full sync only
+				f.mux.Lock()
+				f.id = ""
+				f.mux.Unlock()
+
+				return fmt.Errorf("failed to apply %s of key %q: %w", fr.op.Command, fr.op.Entry.Key, err)
+			}
+			offset = fr.op.Offset
+			f.mux.Lock()
+			f.offset = offset
+			f.mux.Unlock()
+		case fr.typ == frameHeartbeat && snapshot == nil:
+			if fr.offset != offset {
+				return fmt.Errorf("%w: heartbeat offset %d differs from %d", ErrInvalidStream, fr.offset, offset)
+			}
+			f.mux.Lock()
+			f.leaderOffset = fr.leaderOffset
+			f.mux.Unlock()
+		default:
+			return fmt.Errorf("%w: unexpected frame type %d", ErrInvalidStream, fr.typ)
+		}
+	}
+}
+
+// applySnapshot method applies the received snapshot and removes the keys
+// missing in it, they have been removed on the leader. It returns the number
+// of the removed keys.
+//
+// The keys are applied one by one, so the reads that are done meanwhile could
+// get the keys of the snapshot along with the previous data. The follower
+// reports readiness after the first full sync only, so this window exists
+// when the follower does the full sync again, e.g. after the leader restart.
+func (f *Follower) applySnapshot(snapshot []qqcache.WriteOp) (int, error) {
+	keys := make(map[string]struct{}, len(snapshot))
+	for _, op := range snapshot {
+		if err := f.opts.Cache.Apply(op); err != nil {
+			return 0, fmt.Errorf("failed to apply snapshot key %q: %w", op.Entry.Key, err)
+		}
+		keys[op.Entry.Key] = struct{}{}
+	}
+
+	removed := 0
+	for _, key := range f.opts.Cache.Keys() {
+		if _, ok := keys[key]; ok {
+			continue
+		}
+		if err := f.opts.Cache.Apply(qqcache.WriteOp{
+			Command: qqcache.CommandRemove,
+			Entry:   qqcache.Entry{Key: key},
+		}); err != nil {
+			return 0, err
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+// Status method returns the status of the follower.
+func (f *Follower) Status() Status {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	s := Status{
+		Role:         RoleFollower,
+		ID:           f.id,
+		Offset:       f.offset,
+		Leader:       f.opts.Leader,
+		State:        f.state,
+		FullSyncs:    f.fullSyncs,
+		PartialSyncs: f.partialSyncs,
+	}
+	if f.state == StateConnected {
+		// Operations written after the heartbeat could be applied already
+		lag := uint64(0)
+		if f.leaderOffset > f.offset {
+			lag = f.leaderOffset - f.offset
+		}
+		lastFrame := time.Since(f.lastFrame).Seconds()
+		s.LagOperations, s.LastFrameSeconds = &lag, &lastFrame
+	}
+
+	return s
+}
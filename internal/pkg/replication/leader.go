@@ -0,0 +1,308 @@
+package replication
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultBacklogSize       = 1 << 20
+	defaultHeartbeatInterval = time.Second
+)
+
+// LeaderOpts represents the options to create new instance of Leader.
+type LeaderOpts struct {
+	// Cache is the cache to replicate.
+	Cache *qqcache.Cache
+
+	// BacklogSize is the max size in bytes of the latest write operations kept
+	// for partial resync, 1 MiB is used if it's not set.
+	BacklogSize int
+
+	// HeartbeatInterval is how often the heartbeats are sent to the followers,
+	// a second is used if it's not set.
+	HeartbeatInterval time.Duration
+
+	// MaxStreamDuration is the duration after which the stream is ended to let
+	// the follower reconnect before the write timeout of the service API.
+	// It's not limited if it's 0.
+	MaxStreamDuration time.Duration
+
+	// Log is used to report the followers, it's optional.
+	Log *zap.Logger
+}
+
+// Leader streams the write operations of the cache to the followers.
+// The write operations are kept in the backlog since the first follower connects.
+type Leader struct {
+	opts    LeaderOpts
+	id      string
+	backlog *backlog
+	enabled sync.Once
+
+	// encoder is used by the write log under the cache lock
+	encoder frameEncoder
+
+	mux       sync.Mutex
+	followers map[*followerStream]struct{}
+
+	// done is closed by Close to end the streams
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// followerStream represents the stream of the connected follower.
+type followerStream struct {
+	addr      string
+	sync      string
+	connected time.Time
+	offset    uint64
+}
+
+// NewLeader returns new instance of Leader.
+func NewLeader(opts LeaderOpts) *Leader {
+	if opts.BacklogSize <= 0 {
+		opts.BacklogSize = defaultBacklogSize
+	}
+	if opts.HeartbeatInterval <= 0 {
+		opts.HeartbeatInterval = defaultHeartbeatInterval
+	}
+	if opts.Log == nil {
+		opts.Log = zap.NewNop()
+	}
+
+	return &Leader{
+		opts:      opts,
+		id:        newID(),
+		backlog:   newBacklog(opts.BacklogSize),
+		followers: make(map[*followerStream]struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Close method ends the streams of the connected followers, so they reconnect.
+// It should be called on shutdown of the service API as the streams are not ended
+// by the server.
+func (l *Leader) Close() {
+	l.closeOnce.Do(func() {
+		close(l.done)
+	})
+}
+
+// newID returns random replication ID.
+func newID() string {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		// Unique ID is still generated from the current time
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+
+	return hex.EncodeToString(buf)
+}
+
+// Register adds the endpoints to the mux.
+func (l *Leader) Register(mux *http.ServeMux) {
+	mux.HandleFunc(SyncPath, l.Sync)
+}
+
+// enable method starts keeping the write operations in the backlog.
+func (l *Leader) enable() {
+	l.enabled.Do(func() {
+		offset := l.opts.Cache.SetWriteLog(l.logWrite)
+		l.backlog.start(offset + 1)
+	})
+}
+
+// logWrite method appends the write operation to the backlog, it's called
+// under the cache lock. The backlog is reset if the operation could not be
+// encoded, so the followers do the full sync.
+func (l *Leader) logWrite(op qqcache.WriteOp) {
+	data, err := l.encoder.op(op)
+	if err != nil {
+		l.opts.Log.Error("failed to replicate write operation, followers will do full sync",
+			zap.String("key", op.Entry.Key), zap.String("command", op.Command), zap.Error(err))
+		l.backlog.reset(op.Offset + 1)
+
+		return
+	}
+	l.backlog.append(op.Offset, data)
+}
+
+// Sync streams the write operations following the offset given by the offset
+// query parameter if the id query parameter matches the replication ID and
+// the operations are still in the backlog. Otherwise, the snapshot of all keys
+// is sent first. The stream is aborted if the follower falls behind the backlog.
+func (l *Leader) Sync(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+
+		return
+	}
+
+	select {
+	case <-l.done:
+		http.Error(w, "leader is shutting down", http.StatusServiceUnavailable)
+
+		return
+	default:
+	}
+
+	l.enable()
+
+	query := req.URL.Query()
+	offset, err := strconv.ParseUint(query.Get(offsetQuery), 10, 64)
+	stream := &followerStream{addr: req.RemoteAddr, sync: SyncPartial, connected: time.Now()}
+	var snapshot []qqcache.WriteOp
+	if query.Get(idQuery) != l.id || err != nil || !l.backlog.has(offset) {
+		stream.sync = SyncFull
+		snapshot, offset = l.opts.Cache.Snapshot()
+	}
+	stream.offset = offset
+
+	l.mux.Lock()
+	l.followers[stream] = struct{}{}
+	l.mux.Unlock()
+	defer func() {
+		l.mux.Lock()
+		delete(l.followers, stream)
+		l.mux.Unlock()
+	}()
+
+	log := l.opts.Log.With(zap.String("follower", req.RemoteAddr), zap.String("sync", stream.sync),
+		zap.Uint64("offset", offset))
+	log.Info("follower connected", zap.Int("keys", len(snapshot)))
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set(idHeader, l.id)
+	w.Header().Set(offsetHeader, strconv.FormatUint(offset, 10))
+	w.Header().Set(syncHeader, stream.sync)
+	w.WriteHeader(http.StatusOK)
+
+	if err := l.stream(req, bufio.NewWriter(w), flusher, stream, snapshot); err != nil {
+		log.Warn("replication stream aborted", zap.Error(err))
+
+		// Abort the response to let the follower know the stream is incomplete
+		panic(http.ErrAbortHandler)
+	}
+	log.Info("follower disconnected")
+}
+
+// stream method writes the snapshot if it's given and the write operations
+// until the request is canceled, the leader is closed or the max duration
+// of the stream is reached.
+func (l *Leader) stream(req *http.Request, bw *bufio.Writer, flusher http.Flusher,
+	stream *followerStream, snapshot []qqcache.WriteOp) error {
+	_, _ = bw.Write(streamMagic)
+	_ = bw.WriteByte(streamVersion)
+
+	var enc frameEncoder
+	if snapshot != nil {
+		for _, op := range snapshot {
+			data, err := enc.snapshot(op)
+			if err != nil {
+				return err
+			}
+			if _, err := bw.Write(data); err != nil {
+				return err
+			}
+		}
+		_ = bw.WriteByte(frameSynced)
+	}
+
+	var deadline <-chan time.Time
+	if l.opts.MaxStreamDuration > 0 {
+		timer := time.NewTimer(l.opts.MaxStreamDuration)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	heartbeat := time.NewTicker(l.opts.HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	offset := stream.offset
+	lastHeartbeat := time.Time{}
+	var frames [][]byte
+	for {
+		var (
+			changed <-chan struct{}
+			ok      bool
+		)
+		frames, changed, ok = l.backlog.read(offset, frames[:0])
+		if !ok {
+			return ErrBacklogOverflow
+		}
+		for _, data := range frames {
+			if _, err := bw.Write(data); err != nil {
+				return err
+			}
+		}
+		offset += uint64(len(frames))
+
+		// Heartbeats are sent even if the operations are streamed continuously
+		if time.Since(lastHeartbeat) >= l.opts.HeartbeatInterval {
+			lastHeartbeat = time.Now()
+			leaderOffset, _ := l.backlog.status()
+			_, _ = bw.Write(enc.heartbeat(offset, leaderOffset))
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		flusher.Flush()
+
+		l.mux.Lock()
+		stream.offset = offset
+		l.mux.Unlock()
+
+		select {
+		case <-changed:
+		case <-heartbeat.C:
+		case <-deadline:
+			return nil
+		case <-l.done:
+			return nil
+		case <-req.Context().Done():
+			return nil
+		}
+	}
+}
+
+// Status method returns the status of the leader and the connected followers.
+func (l *Leader) Status() Status {
+	offset, size := l.backlog.status()
+	s := Status{
+		Role:         RoleLeader,
+		ID:           l.id,
+		Offset:       offset,
+		BacklogBytes: size,
+	}
+
+	l.mux.Lock()
+	for f := range l.followers {
+		s.Followers = append(s.Followers, FollowerStatus{
+			Address:     f.addr,
+			Sync:        f.sync,
+			ConnectedAt: f.connected,
+			Offset:      f.offset,
+		})
+	}
+	l.mux.Unlock()
+	sort.Slice(s.Followers, func(i, j int) bool { return s.Followers[i].Address < s.Followers[j].Address })
+
+	return s
+}
@@ -0,0 +1,332 @@
+// Package replication provides asynchronous leader-follower replication
+// of the cache over the service API.
+//
+// Leader keeps the latest write operations of its cache in the backlog.
+// Follower connects to the sync endpoint of the leader with the replication ID
+// and the offset of the last applied operation. The leader continues from
+// the offset if the following operations are still in the backlog (partial
+// resync), otherwise it sends the snapshot of all keys first (full sync).
+// Then the leader streams the write operations as they are applied along with
+// the heartbeats every second. The follower reconnects if the stream ends.
+//
+// Replication ID changes on every start of the leader, so the followers of
+// the restarted leader do the full sync.
+package replication
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/dump"
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+)
+
+// SyncPath is the path of the sync endpoint on the service API of the leader.
+const SyncPath = "/replication/sync"
+
+// Query parameters of the sync endpoint.
+const (
+	idQuery     = "id"
+	offsetQuery = "offset"
+)
+
+// Headers of the sync endpoint response.
+const (
+	idHeader     = "X-Replication-Id"
+	offsetHeader = "X-Replication-Offset"
+	syncHeader   = "X-Replication-Sync"
+)
+
+// Roles of the servers.
+const (
+	RoleLeader   = "leader"
+	RoleFollower = "follower"
+)
+
+// Modes of the synchronization.
+const (
+	SyncFull    = "full"
+	SyncPartial = "partial"
+)
+
+// States of the follower connection.
+const (
+	StateConnecting = "connecting"
+	StateSyncing    = "syncing"
+	StateConnected  = "connected"
+)
+
+var (
+	ErrInvalidStream   = errors.New("invalid replication stream")
+	ErrBacklogOverflow = errors.New("follower has fallen behind the backlog")
+)
+
+// Status represents the replication status of the server.
+type Status struct {
+	Role string `json:"role"`
+
+	// ID is the replication ID of the leader, it's empty on the follower
+	// until the first full sync is completed.
+	ID string `json:"id,omitempty"`
+
+	// Offset is the offset of the last write operation of the leader
+	// or the last operation applied by the follower.
+	Offset uint64 `json:"offset"`
+
+	// BacklogBytes is the size of the backlog and Followers contains
+	// the connected followers, they are reported by the leader.
+	BacklogBytes int              `json:"backlog_bytes,omitempty"`
+	Followers    []FollowerStatus `json:"followers,omitempty"`
+
+	// Leader is the URL of the leader, State is the state of the connection
+	// to it, they are reported by the follower.
+	Leader string `json:"leader,omitempty"`
+	State  string `json:"state,omitempty"`
+
+	// LagOperations is the number of the operations the leader had written
+	// by the last heartbeat that the follower has not applied yet.
+	// LastFrameSeconds is the time since the follower has received the last
+	// frame from the leader, it's measured by the follower clock only.
+	// They are not set until the follower is connected.
+	LagOperations    *uint64  `json:"lag_operations,omitempty"`
+	LastFrameSeconds *float64 `json:"last_frame_seconds,omitempty"`
+
+	// FullSyncs and PartialSyncs are the numbers of the syncs of the follower.
+	FullSyncs    uint64 `json:"full_syncs,omitempty"`
+	PartialSyncs uint64 `json:"partial_syncs,omitempty"`
+}
+
+// FollowerStatus represents the follower connected to the leader.
+type FollowerStatus struct {
+	Address     string    `json:"address"`
+	Sync        string    `json:"sync"`
+	ConnectedAt time.Time `json:"connected_at"`
+
+	// Offset is the offset of the last operation sent to the follower.
+	Offset uint64 `json:"offset"`
+}
+
+// Stream starts with the magic and the version byte followed by the frames.
+// Every frame consists of its type byte and the payload:
+//   - snapshot: expiration time and the entry of the key;
+//   - synced: no payload, it follows the last key of the snapshot;
+//   - op: offset, command, flags, expiration time and the entry of the key
+//     or the key only for remove and expire commands;
+//   - heartbeat: offset of the stream and the latest offset of the leader.
+//
+// Offsets and lengths are unsigned varints, times are signed varints of Unix
+// nanoseconds, entries are encoded as in the binary dump with zero TTL.
+var streamMagic = []byte("BSREPL")
+
+const streamVersion = 1
+
+// Types of the frames.
+const (
+	frameSnapshot byte = iota + 1
+	frameSynced
+	frameOp
+	frameHeartbeat
+)
+
+// flagMerge is set in the flags of the merged operations.
+const flagMerge = 1
+
+// maxKeyLen limits the memory allocated for the keys of remove and expire operations.
+const maxKeyLen = 64 << 20
+
+// frameEncoder encodes the frames.
+type frameEncoder struct {
+	buf bytes.Buffer
+	tmp [binary.MaxVarintLen64]byte
+}
+
+// snapshot method returns the frame of the key of the snapshot.
+func (e *frameEncoder) snapshot(op qqcache.WriteOp) ([]byte, error) {
+	e.buf.Reset()
+	e.buf.WriteByte(frameSnapshot)
+	e.writeVarint(op.ExpireAt)
+	if err := dump.EncodeEntry(&e.buf, op.Entry); err != nil {
+		return nil, err
+	}
+
+	return e.buf.Bytes(), nil
+}
+
+// op method returns the frame of the write operation, the data is copied
+// as the frames are kept in the backlog.
+func (e *frameEncoder) op(op qqcache.WriteOp) ([]byte, error) {
+	e.buf.Reset()
+	e.buf.WriteByte(frameOp)
+	e.writeUvarint(op.Offset)
+	e.writeString(op.Command)
+	var flags byte
+	if op.Merge {
+		flags |= flagMerge
+	}
+	e.buf.WriteByte(flags)
+	e.writeVarint(op.ExpireAt)
+
+	switch op.Command {
+	case qqcache.CommandRemove, qqcache.CommandExpire:
+		e.writeString(op.Entry.Key)
+	default:
+		if err := dump.EncodeEntry(&e.buf, op.Entry); err != nil {
+			return nil, err
+		}
+	}
+
+	return append([]byte(nil), e.buf.Bytes()...), nil
+}
+
+// heartbeat method returns the frame of the heartbeat, the offset is the offset
+// of the last operation of the stream and the leader offset is the offset of
+// the last operation written by the leader.
+func (e *frameEncoder) heartbeat(offset, leaderOffset uint64) []byte {
+	e.buf.Reset()
+	e.buf.WriteByte(frameHeartbeat)
+	e.writeUvarint(offset)
+	e.writeUvarint(leaderOffset)
+
+	return e.buf.Bytes()
+}
+
+func (e *frameEncoder) writeUvarint(v uint64) {
+	n := binary.PutUvarint(e.tmp[:], v)
+	e.buf.Write(e.tmp[:n])
+}
+
+func (e *frameEncoder) writeVarint(v int64) {
+	n := binary.PutVarint(e.tmp[:], v)
+	e.buf.Write(e.tmp[:n])
+}
+
+func (e *frameEncoder) writeString(s string) {
+	e.writeUvarint(uint64(len(s)))
+	e.buf.WriteString(s)
+}
+
+// frame represents the decoded frame.
+type frame struct {
+	typ byte
+
+	// op is set for snapshot and op frames, offsets for heartbeats
+	op           qqcache.WriteOp
+	offset       uint64
+	leaderOffset uint64
+}
+
+// readHeader reads the magic and the version of the stream.
+func readHeader(r *bufio.Reader) error {
+	header := make([]byte, len(streamMagic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidStream, err)
+	}
+	if !bytes.Equal(header[:len(streamMagic)], streamMagic) {
+		return fmt.Errorf("%w: unknown format", ErrInvalidStream)
+	}
+	if version := header[len(streamMagic)]; version != streamVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidStream, version)
+	}
+
+	return nil
+}
+
+// readFrame reads the frame, io.EOF is returned if the stream ends between the frames.
+func readFrame(r *bufio.Reader) (frame, error) {
+	typ, err := r.ReadByte()
+	if err != nil {
+		return frame{}, err
+	}
+
+	f, err := decodeFrame(r, typ)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return frame{}, fmt.Errorf("%w: %s", ErrInvalidStream, err)
+	}
+
+	return f, nil
+}
+
+func decodeFrame(r *bufio.Reader, typ byte) (frame, error) {
+	f := frame{typ: typ}
+
+	var err error
+	switch typ {
+	case frameSnapshot:
+		f.op.Command = qqcache.CommandRestore
+		if f.op.ExpireAt, err = binary.ReadVarint(r); err != nil {
+			return frame{}, err
+		}
+		f.op.Entry, err = dump.DecodeEntry(r)
+	case frameSynced:
+	case frameOp:
+		f.op, err = decodeOp(r)
+	case frameHeartbeat:
+		if f.offset, err = binary.ReadUvarint(r); err != nil {
+			return frame{}, err
+		}
+		f.leaderOffset, err = binary.ReadUvarint(r)
+	default:
+		return frame{}, fmt.Errorf("unsupported frame type %d", typ)
+	}
+	if err != nil {
+		return frame{}, err
+	}
+
+	return f, nil
+}
+
+func decodeOp(r *bufio.Reader) (qqcache.WriteOp, error) {
+	var (
+		op  qqcache.WriteOp
+		err error
+	)
+	if op.Offset, err = binary.ReadUvarint(r); err != nil {
+		return op, err
+	}
+	if op.Command, err = readString(r); err != nil {
+		return op, err
+	}
+	flags, err := r.ReadByte()
+	if err != nil {
+		return op, err
+	}
+	op.Merge = flags&flagMerge != 0
+	if op.ExpireAt, err = binary.ReadVarint(r); err != nil {
+		return op, err
+	}
+
+	switch op.Command {
+	case qqcache.CommandRemove, qqcache.CommandExpire:
+		op.Entry.Key, err = readString(r)
+	default:
+		op.Entry, err = dump.DecodeEntry(r)
+	}
+
+	return op, err
+}
+
+func readString(r *bufio.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	if n > maxKeyLen {
+		return "", errors.New("length is out of range")
+	}
+
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+
+	return string(buf), nil
+}
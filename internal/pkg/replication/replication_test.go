@@ -0,0 +1,367 @@
+package replication
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCache(t *testing.T) *qqcache.Cache {
+	c := qqcache.New(qqcache.Opts{EvictionInterval: time.Minute})
+	t.Cleanup(c.Shutdown)
+
+	return c
+}
+
+// newTestLeader returns the leader serving the sync endpoint.
+func newTestLeader(t *testing.T, opts LeaderOpts) (*Leader, *httptest.Server) {
+	l := NewLeader(opts)
+	mux := http.NewServeMux()
+	l.Register(mux)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(func() {
+		l.Close()
+		srv.Close()
+	})
+
+	return l, srv
+}
+
+// runFollower runs the follower until the returned function is called.
+func runFollower(f *Follower) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		f.Run(ctx)
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// encodeKeys returns the snapshot frames of all keys of the cache,
+// so the values of different Go types are compared as they are replicated.
+func encodeKeys(t *testing.T, c *qqcache.Cache) []byte {
+	ops, _ := c.Snapshot()
+
+	var (
+		buf bytes.Buffer
+		e   frameEncoder
+	)
+	for _, op := range ops {
+		data, err := e.snapshot(op)
+		require.NoError(t, err)
+		buf.Write(data)
+	}
+
+	return buf.Bytes()
+}
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func requireSynced(t *testing.T, leader, follower *qqcache.Cache) {
+	require.Eventually(t, func() bool {
+		return bytes.Equal(encodeKeys(t, leader), encodeKeys(t, follower))
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestFrames(t *testing.T) {
+	ops := []qqcache.WriteOp{
+		{Offset: 1, Command: qqcache.CommandSet, Entry: qqcache.Entry{Key: "value", Type: qqcache.TypeValue,
+			Value: "some-value"}, ExpireAt: time.Now().UnixNano()},
+		{Offset: 2, Command: qqcache.CommandRPush, Entry: qqcache.Entry{Key: "list", Type: qqcache.TypeList,
+			Value: []interface{}{"item"}}, Merge: true},
+		{Offset: 3, Command: qqcache.CommandRemove, Entry: qqcache.Entry{Key: "value"}},
+		{Offset: 4, Command: qqcache.CommandExpire, Entry: qqcache.Entry{Key: "list"}},
+	}
+	var (
+		buf bytes.Buffer
+		e   frameEncoder
+	)
+	buf.Write(streamMagic)
+	buf.WriteByte(streamVersion)
+	data, err := e.snapshot(qqcache.WriteOp{Command: qqcache.CommandRestore, Entry: ops[0].Entry, ExpireAt: 42})
+	require.NoError(t, err)
+	buf.Write(data)
+	buf.WriteByte(frameSynced)
+	for _, op := range ops {
+		data, err := e.op(op)
+		require.NoError(t, err)
+		buf.Write(data)
+	}
+	buf.Write(e.heartbeat(4, 6))
+
+	r := bufio.NewReader(&buf)
+	require.NoError(t, readHeader(r))
+
+	f, err := readFrame(r)
+	require.NoError(t, err)
+	require.Equal(t, frame{typ: frameSnapshot, op: qqcache.WriteOp{Command: qqcache.CommandRestore,
+		Entry: ops[0].Entry, ExpireAt: 42}}, f)
+	f, err = readFrame(r)
+	require.NoError(t, err)
+	require.Equal(t, frameSynced, f.typ)
+	for _, op := range ops {
+		f, err = readFrame(r)
+		require.NoError(t, err)
+		require.Equal(t, frame{typ: frameOp, op: op}, f)
+	}
+	f, err = readFrame(r)
+	require.NoError(t, err)
+	require.Equal(t, frame{typ: frameHeartbeat, offset: 4, leaderOffset: 6}, f)
+	_, err = readFrame(r)
+	require.Equal(t, io.EOF, err)
+
+	// Truncated frame and unknown types are invalid
+	data, err = e.op(ops[0])
+	require.NoError(t, err)
+	_, err = readFrame(bufio.NewReader(bytes.NewReader(data[:len(data)-1])))
+	require.True(t, errors.Is(err, ErrInvalidStream))
+	_, err = readFrame(bufio.NewReader(bytes.NewReader([]byte{42})))
+	require.True(t, errors.Is(err, ErrInvalidStream))
+	require.True(t, errors.Is(readHeader(bufio.NewReader(bytes.NewReader([]byte("BSDUMP\x01")))), ErrInvalidStream))
+
+	// Entries of unsupported types could not be encoded
+	_, err = e.op(qqcache.WriteOp{Command: qqcache.CommandSet, Entry: qqcache.Entry{Key: "key", Type: "unknown"}})
+	require.Error(t, err)
+}
+
+func TestReplication(t *testing.T) {
+	leaderCache := newTestCache(t)
+	leaderCache.Set("value", "some-value", time.Hour)
+	leaderCache.Set("removed", "some-value", 0)
+	require.NoError(t, leaderCache.HSet("hash", map[string]interface{}{"a": "1"}, 0))
+
+	leader, srv := newTestLeader(t, LeaderOpts{
+		Cache:             leaderCache,
+		BacklogSize:       256,
+		HeartbeatInterval: 10 * time.Millisecond,
+	})
+	require.Equal(t, Status{Role: RoleLeader, ID: leader.id}, leader.Status())
+
+	followerCache := newTestCache(t)
+	followerCache.Set("stale", "value", 0)
+	follower := NewFollower(FollowerOpts{
+		Cache:             followerCache,
+		Leader:            srv.URL + "/",
+		ReconnectInterval: 10 * time.Millisecond,
+	})
+	require.False(t, isClosed(follower.Synced()))
+	stop := runFollower(follower)
+
+	// Full sync
+	requireSynced(t, leaderCache, followerCache)
+	require.Eventually(t, func() bool {
+		return isClosed(follower.Synced())
+	}, 5*time.Second, 10*time.Millisecond)
+	_, ok := followerCache.Get("stale")
+	require.False(t, ok)
+
+	// Stream of the write operations
+	require.True(t, leaderCache.Remove("removed"))
+	require.NoError(t, leaderCache.RPush("list", "first", 0))
+	require.NoError(t, leaderCache.RPush("list", "second", 0))
+	require.NoError(t, leaderCache.HSet("hash", map[string]interface{}{"b": "2"}, 0))
+	leaderCache.SetBytes("bytes", []byte{0, 1}, "image/png", time.Hour)
+	requireSynced(t, leaderCache, followerCache)
+
+	require.Eventually(t, func() bool {
+		lag := follower.Status().LagOperations
+
+		return lag != nil && *lag == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	s := follower.Status()
+	require.Equal(t, RoleFollower, s.Role)
+	require.Equal(t, leader.id, s.ID)
+	require.Equal(t, uint64(8), s.Offset)
+	require.Equal(t, srv.URL, s.Leader)
+	require.Equal(t, StateConnected, s.State)
+	require.Equal(t, uint64(1), s.FullSyncs)
+
+	ls := leader.Status()
+	require.Equal(t, uint64(8), ls.Offset)
+	require.True(t, ls.BacklogBytes > 0 && ls.BacklogBytes <= 256)
+	require.Len(t, ls.Followers, 1)
+	require.Equal(t, SyncFull, ls.Followers[0].Sync)
+
+	// Reconnected follower continues from the backlog
+	stop()
+	leaderCache.Set("value", "other-value", 0)
+	stop = runFollower(follower)
+	requireSynced(t, leaderCache, followerCache)
+	require.Eventually(t, func() bool {
+		return follower.Status().PartialSyncs == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, uint64(1), follower.Status().FullSyncs)
+
+	// Follower that has fallen behind the backlog does the full sync
+	stop()
+	require.True(t, leaderCache.Remove("value"))
+	leaderCache.SetBytes("bytes", make([]byte, 512), "image/png", 0)
+	stop = runFollower(follower)
+	defer stop()
+	requireSynced(t, leaderCache, followerCache)
+	require.Eventually(t, func() bool {
+		return follower.Status().FullSyncs == 2
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestFollower_Status(t *testing.T) {
+	f := NewFollower(FollowerOpts{Cache: newTestCache(t), Leader: "http://127.0.0.1:63101"})
+	s := f.Status()
+	require.Nil(t, s.LagOperations)
+	require.Nil(t, s.LastFrameSeconds)
+
+	// Lag is the difference of the offsets, the clock of the leader is not used
+	f.state, f.offset, f.leaderOffset = StateConnected, 5, 8
+	f.lastFrame = time.Now().Add(-2 * time.Second)
+	s = f.Status()
+	require.Equal(t, uint64(3), *s.LagOperations)
+	require.True(t, *s.LastFrameSeconds >= 2)
+
+	// Operations written after the heartbeat have been applied
+	f.offset = 10
+	require.Equal(t, uint64(0), *f.Status().LagOperations)
+}
+
+func TestReplication_MaxStreamDuration(t *testing.T) {
+	leaderCache := newTestCache(t)
+	_, srv := newTestLeader(t, LeaderOpts{
+		Cache:             leaderCache,
+		BacklogSize:       1 << 10,
+		MaxStreamDuration: 50 * time.Millisecond,
+	})
+
+	followerCache := newTestCache(t)
+	follower := NewFollower(FollowerOpts{Cache: followerCache, Leader: srv.URL})
+	defer runFollower(follower)()
+
+	// Streams are ended by the leader and resumed by the follower
+	for i := 0; i < 20; i++ {
+		require.NoError(t, leaderCache.RPush("list", strconv.Itoa(i), 0))
+		time.Sleep(10 * time.Millisecond)
+	}
+	requireSynced(t, leaderCache, followerCache)
+	require.Eventually(t, func() bool {
+		return follower.Status().PartialSyncs > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, uint64(1), follower.Status().FullSyncs)
+}
+
+func TestFollower_Errors(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Query().Get(idQuery) {
+		case "status":
+			http.Error(w, "forbidden", http.StatusForbidden)
+		case "format":
+			w.Header().Set(idHeader, "id")
+			w.Header().Set(offsetHeader, "0")
+			w.Header().Set(syncHeader, SyncFull)
+			_, _ = w.Write([]byte("BSDUMP\x01"))
+		case "incomplete":
+			w.Header().Set(idHeader, "id")
+			w.Header().Set(offsetHeader, "0")
+			w.Header().Set(syncHeader, SyncFull)
+			_, _ = w.Write(append(streamMagic, streamVersion))
+		case "timeout":
+			<-req.Context().Done()
+		}
+	})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	follower := NewFollower(FollowerOpts{
+		Cache:   newTestCache(t),
+		Leader:  srv.URL,
+		Timeout: 50 * time.Millisecond,
+	})
+
+	tests := []struct {
+		id    string
+		check func(error) bool
+	}{
+		{id: "status", check: func(err error) bool {
+			return err != nil && err.Error() == "got the 403 status code from the leader: forbidden"
+		}},
+		{id: "headers", check: func(err error) bool { return errors.Is(err, ErrInvalidStream) }},
+		{id: "format", check: func(err error) bool { return errors.Is(err, ErrInvalidStream) }},
+		{id: "incomplete", check: func(err error) bool { return errors.Is(err, ErrInvalidStream) }},
+		{id: "timeout", check: func(err error) bool { return err == ErrTimeout }},
+	}
+	for _, test := range tests {
+		t.Run(test.id, func(t *testing.T) {
+			follower.id = test.id
+			err := follower.sync(context.Background())
+			require.True(t, test.check(err), err)
+		})
+	}
+}
+
+func TestFollower_InterruptedSnapshot(t *testing.T) {
+	var e frameEncoder
+	data, err := e.snapshot(qqcache.WriteOp{Command: qqcache.CommandRestore,
+		Entry: qqcache.Entry{Key: "new", Type: qqcache.TypeValue, Value: "value"}})
+	require.NoError(t, err)
+
+	// The leader ends the stream before the end of the snapshot
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set(idHeader, "id")
+		w.Header().Set(offsetHeader, "10")
+		w.Header().Set(syncHeader, SyncFull)
+		_, _ = w.Write(append(append(streamMagic, streamVersion), data...))
+	}))
+	defer srv.Close()
+
+	cache := newTestCache(t)
+	cache.Set("old", "value", 0)
+	follower := NewFollower(FollowerOpts{Cache: cache, Leader: srv.URL})
+	follower.id, follower.offset = "previous", 5
+
+	err = follower.sync(context.Background())
+	require.True(t, errors.Is(err, ErrInvalidStream), err)
+
+	// Staged keys are not applied and the follower could continue from the offset
+	require.Equal(t, []string{"old"}, cache.Keys())
+	require.False(t, isClosed(follower.Synced()))
+	s := follower.Status()
+	require.Equal(t, "previous", s.ID)
+	require.Equal(t, uint64(5), s.Offset)
+	require.Equal(t, uint64(0), s.FullSyncs)
+}
+
+func TestLeader_Sync(t *testing.T) {
+	leader, srv := newTestLeader(t, LeaderOpts{Cache: newTestCache(t)})
+
+	resp, err := http.Post(srv.URL+SyncPath, "text/plain", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	leader.Close()
+	resp, err = http.Get(srv.URL + SyncPath)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
@@ -13,17 +13,16 @@ const (
 	accTestDisabledMsg = `Acceptance tests suite is disabled, you can enable it with ACC_TESTS=1`
 )
 
-// InitTestConfig initializes application configuration for tests.
-func InitTestConfig() {
-	config.Config = &config.AppConfig{
-		Log: config.LogConfig{
-			UseStdout: true,
-			Debug:     true,
-		},
-		Cache: config.CacheConfig{
-			EvictionInterval: 60,
-		},
+// NewTestConfig returns application configuration for tests.
+func NewTestConfig() *config.AppConfig {
+	cfg, err := config.Load(config.Sources{})
+	if err != nil {
+		panic(err)
 	}
+	cfg.Log.UseStdout = true
+	cfg.Log.Debug = true
+
+	return cfg
 }
 
 // IsAccTestEnabled checks if aceptance tests are enabled.
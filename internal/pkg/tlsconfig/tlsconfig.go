@@ -0,0 +1,319 @@
+// Package tlsconfig provides TLS configuration of the API servers
+// with certificates reloaded on file change.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrCertRequired        = errors.New("both certificate and key files are required")
+	ErrClientCARequired    = errors.New("client CA file is required to verify client certificates")
+	ErrNoClientCACerts     = errors.New("no certificates found in client CA file")
+	ErrHTTP2CipherRequired = errors.New("cipher suites must contain TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 " +
+		"or TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 required by HTTP/2")
+)
+
+// Client certificate verification modes.
+const (
+	ClientAuthNone          = "none"
+	ClientAuthRequest       = "request"
+	ClientAuthVerifyIfGiven = "verify_if_given"
+	ClientAuthRequire       = "require"
+)
+
+const defaultReloadInterval = 10 * time.Second
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	ClientAuthNone:          tls.NoClientCert,
+	ClientAuthRequest:       tls.RequestClientCert,
+	ClientAuthVerifyIfGiven: tls.VerifyClientCertIfGiven,
+	ClientAuthRequire:       tls.RequireAndVerifyClientCert,
+}
+
+// nextProtos are the protocols negotiated by ALPN, HTTP/2 is preferred.
+var nextProtos = []string{"h2", "http/1.1"}
+
+// Opts represents the options to create new instance of Reloader.
+type Opts struct {
+	// CertFile and KeyFile are the paths to PEM-encoded certificate and private key.
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFile is the path to PEM-encoded CA certificates used to verify
+	// client certificates.
+	ClientCAFile string
+
+	// ClientAuth is the client certificate verification mode, it's "require"
+	// by default if ClientCAFile is set and "none" otherwise.
+	ClientAuth string
+
+	// MinVersion is the minimum TLS version, "1.2" by default.
+	MinVersion string
+
+	// CipherSuites contains the names of allowed cipher suites for TLS 1.2 and below,
+	// Go defaults are used if empty. TLS 1.3 cipher suites are not configurable.
+	CipherSuites []string
+
+	// ReloadInterval is how often files are checked for changes.
+	ReloadInterval time.Duration
+
+	Log *zap.Logger
+}
+
+// Reloader provides TLS configuration with certificate and client CA
+// reloaded from the files when they are changed.
+type Reloader struct {
+	log *zap.Logger
+
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	clientAuth   tls.ClientAuthType
+	minVersion   uint16
+	cipherSuites []uint16
+
+	mux    sync.RWMutex
+	files  map[string]fileState
+	config *tls.Config
+
+	stopReload chan struct{}
+	stopOnce   sync.Once
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// New returns new instance of Reloader.
+// Files are checked for changes and reloaded within the reload interval
+// until Close is called.
+func New(opts Opts) (*Reloader, error) {
+	if opts.CertFile == "" || opts.KeyFile == "" {
+		return nil, ErrCertRequired
+	}
+
+	r := &Reloader{
+		log:          opts.Log,
+		certFile:     opts.CertFile,
+		keyFile:      opts.KeyFile,
+		clientCAFile: opts.ClientCAFile,
+		minVersion:   tls.VersionTLS12,
+		stopReload:   make(chan struct{}),
+	}
+	if r.log == nil {
+		r.log = zap.NewNop()
+	}
+
+	if opts.MinVersion != "" {
+		v, ok := tlsVersions[opts.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unknown TLS version %q", opts.MinVersion)
+		}
+		r.minVersion = v
+	}
+
+	clientAuth := opts.ClientAuth
+	if clientAuth == "" {
+		clientAuth = ClientAuthNone
+		if opts.ClientCAFile != "" {
+			clientAuth = ClientAuthRequire
+		}
+	}
+	ca, ok := clientAuthTypes[clientAuth]
+	if !ok {
+		return nil, fmt.Errorf("unknown client auth mode %q", clientAuth)
+	}
+	if (ca == tls.VerifyClientCertIfGiven || ca == tls.RequireAndVerifyClientCert) && opts.ClientCAFile == "" {
+		return nil, ErrClientCARequired
+	}
+	r.clientAuth = ca
+
+	var err error
+	if r.cipherSuites, err = parseCipherSuites(opts.CipherSuites); err != nil {
+		return nil, err
+	}
+
+	if _, err := r.reload(true); err != nil {
+		return nil, err
+	}
+
+	reloadInterval := opts.ReloadInterval
+	if reloadInterval <= 0 {
+		reloadInterval = defaultReloadInterval
+	}
+	go r.reloader(reloadInterval)
+
+	return r, nil
+}
+
+// TLSConfig method returns TLS configuration for the server.
+// Every handshake uses the latest loaded certificate and client CA.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:   r.minVersion,
+		CipherSuites: r.cipherSuites,
+		NextProtos:   nextProtos,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &r.current().Certificates[0], nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current(), nil
+		},
+	}
+}
+
+// Close stops reloading of the files.
+func (r *Reloader) Close() {
+	r.stopOnce.Do(func() {
+		close(r.stopReload)
+	})
+}
+
+// Reload method reads certificate, key and client CA files if any of them has been changed.
+// Previous configuration is kept if the files could not be loaded.
+func (r *Reloader) Reload() error {
+	isReloaded, err := r.reload(false)
+	if err != nil {
+		return err
+	}
+	if isReloaded {
+		r.log.Info("TLS certificates reloaded", zap.String("cert_file", r.certFile))
+	}
+
+	return nil
+}
+
+func (r *Reloader) reloader(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			if err := r.Reload(); err != nil {
+				r.log.Warn("failed to reload TLS certificates", zap.Error(err))
+			}
+		case <-r.stopReload:
+			return
+		}
+	}
+}
+
+func (r *Reloader) current() *tls.Config {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	return r.config
+}
+
+// reload method loads the files if any of them has been changed since the last load
+// or force is true.
+// It returns true if configuration has been reloaded.
+func (r *Reloader) reload(force bool) (bool, error) {
+	paths := []string{r.certFile, r.keyFile}
+	if r.clientCAFile != "" {
+		paths = append(paths, r.clientCAFile)
+	}
+
+	files := make(map[string]fileState, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return false, err
+		}
+		files[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+	}
+
+	r.mux.RLock()
+	isChanged := false
+	for path, state := range files {
+		if prev, ok := r.files[path]; !ok || !prev.modTime.Equal(state.modTime) || prev.size != state.size {
+			isChanged = true
+		}
+	}
+	r.mux.RUnlock()
+	if !isChanged && !force {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, err
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   r.clientAuth,
+		MinVersion:   r.minVersion,
+		CipherSuites: r.cipherSuites,
+		NextProtos:   nextProtos,
+	}
+	if r.clientCAFile != "" {
+		data, err := ioutil.ReadFile(r.clientCAFile)
+		if err != nil {
+			return false, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return false, fmt.Errorf("%s: %w", r.clientCAFile, ErrNoClientCACerts)
+		}
+		config.ClientCAs = pool
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.files = files
+	r.config = config
+
+	return true, nil
+}
+
+// parseCipherSuites returns IDs of the cipher suites by their names.
+// Only the cipher suites considered secure by Go are allowed.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, s := range tls.CipherSuites() {
+		known[s.Name] = s.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	hasHTTP2Cipher := false
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+		}
+		if id == tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 || id == tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 {
+			hasHTTP2Cipher = true
+		}
+		ids = append(ids, id)
+	}
+	if !hasHTTP2Cipher {
+		return nil, ErrHTTP2CipherRequired
+	}
+
+	return ids, nil
+}
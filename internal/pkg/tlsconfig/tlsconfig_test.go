@@ -0,0 +1,246 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// startTestServer serves TLS with the configuration of the reloader,
+// the handler responds with the common name of the client certificate.
+func startTestServer(t *testing.T, r *Reloader) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := &http.Server{
+		TLSConfig: r.TLSConfig(),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if len(req.TLS.VerifiedChains) > 0 {
+				_, _ = w.Write([]byte(req.TLS.VerifiedChains[0][0].Subject.CommonName))
+			}
+		}),
+	}
+	go func() {
+		_ = srv.ServeTLS(l, "", "")
+	}()
+
+	return "https://" + l.Addr().String(), func() {
+		srv.Close()
+	}
+}
+
+func newTestClient(ca *testutils.TestCert, cert *testutils.TestCert) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Cert)
+	cfg := &tls.Config{RootCAs: pool, ServerName: "localhost"}
+	if cert != nil {
+		c, _ := tls.LoadX509KeyPair(cert.CertFile, cert.KeyFile)
+		cfg.Certificates = []tls.Certificate{c}
+	}
+
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg, ForceAttemptHTTP2: true}}
+}
+
+func TestReloader_HTTP2(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsconfig")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := testutils.NewTestCA(t, dir)
+	server := ca.IssueServerCert(t, dir, "server")
+
+	r, err := New(Opts{CertFile: server.CertFile, KeyFile: server.KeyFile})
+	require.NoError(t, err)
+	defer r.Close()
+
+	url, stop := startTestServer(t, r)
+	defer stop()
+
+	resp, err := newTestClient(ca, nil).Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 2, resp.ProtoMajor)
+	require.Equal(t, uint16(tls.VersionTLS13), resp.TLS.Version)
+}
+
+func TestReloader_ClientCert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsconfig")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := testutils.NewTestCA(t, dir)
+	server := ca.IssueServerCert(t, dir, "server")
+	client := ca.IssueClientCert(t, dir, "reporting-service")
+
+	r, err := New(Opts{CertFile: server.CertFile, KeyFile: server.KeyFile, ClientCAFile: ca.CertFile})
+	require.NoError(t, err)
+	defer r.Close()
+
+	url, stop := startTestServer(t, r)
+	defer stop()
+
+	// Client certificate is required by default if client CA is set
+	_, err = newTestClient(ca, nil).Get(url)
+	require.Error(t, err)
+
+	resp, err := newTestClient(ca, client).Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "reporting-service", string(body))
+}
+
+func TestReloader_Reload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsconfig")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := testutils.NewTestCA(t, dir)
+	server := ca.IssueServerCert(t, dir, "server")
+
+	r, err := New(Opts{CertFile: server.CertFile, KeyFile: server.KeyFile, ReloadInterval: time.Hour})
+	require.NoError(t, err)
+	defer r.Close()
+
+	url, stop := startTestServer(t, r)
+	defer stop()
+
+	serialOf := func() string {
+		// New connection is used for every request to get the current certificate
+		client := newTestClient(ca, nil)
+		client.Transport.(*http.Transport).DisableKeepAlives = true
+		resp, err := client.Get(url)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		return resp.TLS.PeerCertificates[0].SerialNumber.String()
+	}
+	require.Equal(t, server.Cert.SerialNumber.String(), serialOf())
+
+	// Not changed files are not reloaded
+	require.NoError(t, r.Reload())
+	require.Equal(t, server.Cert.SerialNumber.String(), serialOf())
+
+	// Certificate is reissued into the same files
+	renewed := ca.IssueServerCert(t, dir, "server")
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(renewed.CertFile, future, future))
+	require.NoError(t, r.Reload())
+	require.Equal(t, renewed.Cert.SerialNumber.String(), serialOf())
+
+	// Previous certificate is kept if the files are broken
+	require.NoError(t, ioutil.WriteFile(renewed.KeyFile, []byte("broken"), 0o600))
+	require.Error(t, r.Reload())
+	require.Equal(t, renewed.Cert.SerialNumber.String(), serialOf())
+}
+
+func TestNew_Errors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsconfig")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := testutils.NewTestCA(t, dir)
+	server := ca.IssueServerCert(t, dir, "server")
+
+	testCases := []struct {
+		name string
+		opts Opts
+		err  string
+	}{
+		{
+			name: "no key",
+			opts: Opts{CertFile: server.CertFile},
+			err:  ErrCertRequired.Error(),
+		},
+		{
+			name: "unknown version",
+			opts: Opts{CertFile: server.CertFile, KeyFile: server.KeyFile, MinVersion: "1.4"},
+			err:  `unknown TLS version "1.4"`,
+		},
+		{
+			name: "unknown client auth",
+			opts: Opts{CertFile: server.CertFile, KeyFile: server.KeyFile, ClientAuth: "optional"},
+			err:  `unknown client auth mode "optional"`,
+		},
+		{
+			name: "client CA required",
+			opts: Opts{CertFile: server.CertFile, KeyFile: server.KeyFile, ClientAuth: ClientAuthRequire},
+			err:  ErrClientCARequired.Error(),
+		},
+		{
+			name: "insecure cipher",
+			opts: Opts{
+				CertFile:     server.CertFile,
+				KeyFile:      server.KeyFile,
+				CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"},
+			},
+			err: `unknown or insecure cipher suite "TLS_RSA_WITH_RC4_128_SHA"`,
+		},
+		{
+			name: "no HTTP/2 cipher",
+			opts: Opts{
+				CertFile:     server.CertFile,
+				KeyFile:      server.KeyFile,
+				CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"},
+			},
+			err: ErrHTTP2CipherRequired.Error(),
+		},
+		{
+			name: "invalid client CA",
+			opts: Opts{CertFile: server.CertFile, KeyFile: server.KeyFile, ClientCAFile: server.KeyFile},
+			err:  server.KeyFile + ": " + ErrNoClientCACerts.Error(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(tc.opts)
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestReloader_CipherSuites(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsconfig")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := testutils.NewTestCA(t, dir)
+	server := ca.IssueServerCert(t, dir, "server")
+
+	r, err := New(Opts{
+		CertFile:     server.CertFile,
+		KeyFile:      server.KeyFile,
+		MinVersion:   "1.2",
+		CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"},
+	})
+	require.NoError(t, err)
+	defer r.Close()
+
+	url, stop := startTestServer(t, r)
+	defer stop()
+
+	client := newTestClient(ca, nil)
+	client.Transport.(*http.Transport).TLSClientConfig.MaxVersion = tls.VersionTLS12
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, resp.TLS.CipherSuite)
+
+	// TLS 1.1 is rejected
+	client = newTestClient(ca, nil)
+	client.Transport.(*http.Transport).TLSClientConfig.MinVersion = tls.VersionTLS10
+	client.Transport.(*http.Transport).TLSClientConfig.MaxVersion = tls.VersionTLS11
+	_, err = client.Get(url)
+	require.Error(t, err)
+}
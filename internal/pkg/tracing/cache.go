@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"context"
+	"hash/fnv"
+	"strconv"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+)
+
+// CacheObserver returns the observer of the cache commands that records
+// them as the child spans of the span from the context.
+// It returns nil if the context has no recording span.
+func CacheObserver(ctx context.Context) func(qqcache.Operation) {
+	parent := SpanFromContext(ctx)
+	if !parent.IsRecording() {
+		return nil
+	}
+
+	return func(op qqcache.Operation) {
+		_, span := parent.tracer.StartAt(ctx, "qqcache."+op.Command, KindInternal, op.Started)
+		span.SetAttributes(
+			String("cache.command", op.Command),
+			Int64("cache.args", int64(op.Args)),
+		)
+		if op.Key != "" {
+			span.SetAttributes(String("cache.key_hash", KeyHash(op.Key)))
+		}
+		if op.IsLookup {
+			span.SetAttributes(Bool("cache.hit", op.Hit), Int64("cache.value_size", op.ValueBytes))
+		} else {
+			span.SetAttributes(Int64("cache.value_size", op.ArgsBytes))
+		}
+		span.EndAt(op.Started.Add(op.Duration))
+	}
+}
+
+// KeyHash returns hex-encoded FNV-1a hash of the key,
+// it's used instead of the keys that may contain sensitive data.
+func KeyHash(key string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+
+	return strconv.FormatUint(h.Sum64(), 16)
+}
@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/httputil"
+)
+
+// Middleware returns middleware that starts server span of every request.
+// The span is a child of the span from incoming traceparent header.
+func Middleware(t *Tracer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx := req.Context()
+			if sc, ok := Extract(req.Header); ok {
+				ctx = ContextWithRemoteSpanContext(ctx, sc)
+			}
+			ctx, span := t.Start(ctx, "HTTP "+req.Method, KindServer)
+			defer span.End()
+
+			rw := httputil.NewResponseWriter(w)
+			next.ServeHTTP(rw, req.WithContext(ctx))
+
+			route := httputil.Route(req)
+			status := rw.Status()
+
+			span.SetName(req.Method + " " + route)
+			span.SetAttributes(
+				String("http.method", req.Method),
+				String("http.route", route),
+				Int64("http.status_code", int64(status)),
+			)
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(StatusError, http.StatusText(status))
+			}
+		})
+	}
+}
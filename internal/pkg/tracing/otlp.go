@@ -0,0 +1,219 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TracesPath is the path of OTLP/HTTP traces endpoint of the collector.
+const TracesPath = "/v1/traces"
+
+const (
+	defaultOTLPTimeout = 10 * time.Second
+	defaultServiceName = "bookish-spork"
+
+	// scopeName is the name of the instrumentation scope of the spans
+	scopeName = "github.com/dstdfx/bookish-spork"
+)
+
+// OTLPOpts represents the options to create new instance of OTLPExporter.
+type OTLPOpts struct {
+	// Endpoint is the base URL of the collector, e.g. http://localhost:4318,
+	// spans are sent to its TracesPath.
+	Endpoint string
+
+	// Headers are added to the export requests, e.g. for authentication.
+	Headers map[string]string
+
+	// ServiceName is the name of the service in the resource of the spans.
+	ServiceName string
+
+	// Timeout is the timeout of the export request.
+	Timeout time.Duration
+}
+
+// OTLPExporter exports spans to the collector using OTLP/HTTP with JSON encoding.
+type OTLPExporter struct {
+	url         string
+	headers     map[string]string
+	serviceName string
+	client      *http.Client
+}
+
+// NewOTLPExporter returns new instance of OTLPExporter.
+func NewOTLPExporter(opts OTLPOpts) *OTLPExporter {
+	e := &OTLPExporter{
+		url:         strings.TrimSuffix(opts.Endpoint, "/"),
+		headers:     opts.Headers,
+		serviceName: opts.ServiceName,
+		client:      &http.Client{Timeout: opts.Timeout},
+	}
+	if !strings.HasSuffix(e.url, TracesPath) {
+		e.url += TracesPath
+	}
+	if e.serviceName == "" {
+		e.serviceName = defaultServiceName
+	}
+	if e.client.Timeout <= 0 {
+		e.client.Timeout = defaultOTLPTimeout
+	}
+
+	return e
+}
+
+// ExportSpans sends the spans to the collector.
+func (e *OTLPExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("collector responded with %d status code", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// ExportTraceServiceRequest represents the body of OTLP/HTTP traces request
+// in JSON encoding.
+type ExportTraceServiceRequest struct {
+	ResourceSpans []ResourceSpans `json:"resourceSpans"`
+}
+
+// ResourceSpans contains spans of the resource.
+type ResourceSpans struct {
+	Resource   Resource     `json:"resource"`
+	ScopeSpans []ScopeSpans `json:"scopeSpans"`
+}
+
+// Resource describes the service that produced the spans.
+type Resource struct {
+	Attributes []KeyValue `json:"attributes"`
+}
+
+// ScopeSpans contains spans of the instrumentation scope.
+type ScopeSpans struct {
+	Scope Scope      `json:"scope"`
+	Spans []OTLPSpan `json:"spans"`
+}
+
+// Scope describes the instrumentation scope.
+type Scope struct {
+	Name string `json:"name"`
+}
+
+// OTLPSpan represents a span in OTLP JSON encoding, IDs are hex-encoded
+// and 64-bit integers are encoded as strings.
+type OTLPSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	Status            Status     `json:"status"`
+}
+
+// Status represents the status of the span.
+type Status struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// KeyValue represents an attribute.
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue represents the value of an attribute, only one of the fields is set.
+type AnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+// request method converts the spans to the export request.
+func (e *OTLPExporter) request(spans []SpanData) ExportTraceServiceRequest {
+	otlpSpans := make([]OTLPSpan, 0, len(spans))
+	for _, s := range spans {
+		span := OTLPSpan{
+			TraceID:           s.SpanContext.TraceID.String(),
+			SpanID:            s.SpanContext.SpanID.String(),
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        keyValues(s.Attributes),
+			Status:            Status{Code: s.Status, Message: s.StatusMessage},
+		}
+		if s.Parent.IsValid() {
+			span.ParentSpanID = s.Parent.String()
+		}
+		otlpSpans = append(otlpSpans, span)
+	}
+
+	return ExportTraceServiceRequest{
+		ResourceSpans: []ResourceSpans{{
+			Resource: Resource{
+				Attributes: keyValues([]Attribute{String("service.name", e.serviceName)}),
+			},
+			ScopeSpans: []ScopeSpans{{
+				Scope: Scope{Name: scopeName},
+				Spans: otlpSpans,
+			}},
+		}},
+	}
+}
+
+func keyValues(attrs []Attribute) []KeyValue {
+	kvs := make([]KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		var v AnyValue
+		switch value := a.Value.(type) {
+		case string:
+			v.StringValue = &value
+		case int64:
+			s := strconv.FormatInt(value, 10)
+			v.IntValue = &s
+		case float64:
+			v.DoubleValue = &value
+		case bool:
+			v.BoolValue = &value
+		default:
+			s := fmt.Sprint(value)
+			v.StringValue = &s
+		}
+		kvs = append(kvs, KeyValue{Key: a.Key, Value: v})
+	}
+
+	return kvs
+}
@@ -0,0 +1,151 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// TraceparentHeader is the W3C Trace Context header.
+const TraceparentHeader = "traceparent"
+
+// traceparentVersion is the supported version of traceparent header.
+const traceparentVersion = "00"
+
+// flagSampled is the sampled bit of the trace flags.
+const flagSampled = 0x01
+
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+// String returns hex-encoded trace ID.
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// IsValid reports whether trace ID is not zero.
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// String returns hex-encoded span ID.
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// IsValid reports whether span ID is not zero.
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+// SpanContext identifies a span and is propagated across services.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid reports whether both trace and span IDs are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent returns the value of traceparent header.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+
+	return traceparentVersion + "-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceparent parses the value of traceparent header.
+func ParseTraceparent(s string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	// Future versions may append fields, version 00 must have exactly 4 fields
+	if parts[0] == traceparentVersion && len(parts) != 4 {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+
+	var sc SpanContext
+	var flags [1]byte
+	if !decodeHex(sc.TraceID[:], parts[1]) || !decodeHex(sc.SpanID[:], parts[2]) ||
+		!decodeHex(flags[:], parts[3]) || !sc.IsValid() {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	sc.Sampled = flags[0]&flagSampled != 0
+
+	return sc, nil
+}
+
+// decodeHex decodes lowercase hex string of exactly len(dst) bytes.
+func decodeHex(dst []byte, s string) bool {
+	if len(s) != hex.EncodedLen(len(dst)) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+
+	return err == nil
+}
+
+// Extract returns span context of traceparent header.
+func Extract(header http.Header) (SpanContext, bool) {
+	sc, err := ParseTraceparent(header.Get(TraceparentHeader))
+
+	return sc, err == nil
+}
+
+// Inject sets traceparent header to the span context from the context.
+// The header is not set if the context has no span context.
+func Inject(ctx context.Context, header http.Header) {
+	if sc := SpanContextFromContext(ctx); sc.IsValid() {
+		header.Set(TraceparentHeader, sc.Traceparent())
+	}
+}
+
+type ctxKey int
+
+const (
+	ctxSpan ctxKey = iota
+	ctxRemoteSpanContext
+)
+
+// ContextWithSpan returns a copy of the context with the span.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, ctxSpan, span)
+}
+
+// SpanFromContext returns the span from the context or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(ctxSpan).(*Span)
+
+	return span
+}
+
+// ContextWithRemoteSpanContext returns a copy of the context with the span
+// context received from another service, it's used as the parent of new spans.
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, ctxRemoteSpanContext, sc)
+}
+
+// SpanContextFromContext returns the span context of the span from the context
+// or the remote span context if there is no span.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+	sc, _ := ctx.Value(ctxRemoteSpanContext).(SpanContext)
+
+	return sc
+}
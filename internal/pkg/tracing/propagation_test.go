@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceparent(t *testing.T) {
+	sc, err := ParseTraceparent(testTraceparent)
+	require.NoError(t, err)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	require.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	require.True(t, sc.Sampled)
+	require.Equal(t, testTraceparent, sc.Traceparent())
+
+	sc, err = ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	require.NoError(t, err)
+	require.False(t, sc.Sampled)
+
+	// Future versions may have more fields
+	_, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+	require.NoError(t, err)
+
+	for _, s := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902bz-01",
+	} {
+		_, err := ParseTraceparent(s)
+		require.True(t, errors.Is(err, ErrInvalidTraceparent), s)
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	header := http.Header{}
+	Inject(context.Background(), header)
+	require.Empty(t, header.Get(TraceparentHeader))
+
+	header.Set(TraceparentHeader, testTraceparent)
+	sc, ok := Extract(header)
+	require.True(t, ok)
+
+	// Remote span context is propagated as is
+	out := http.Header{}
+	Inject(ContextWithRemoteSpanContext(context.Background(), sc), out)
+	require.Equal(t, testTraceparent, out.Get(TraceparentHeader))
+
+	// Span from the context takes precedence over the remote span context
+	tracer := New(Opts{})
+	defer tracer.Close()
+	ctx, span := tracer.Start(ContextWithRemoteSpanContext(context.Background(), sc), "a", KindInternal)
+	Inject(ctx, out)
+	require.Equal(t, span.SpanContext().Traceparent(), out.Get(TraceparentHeader))
+	require.Equal(t, sc.TraceID, span.SpanContext().TraceID)
+}
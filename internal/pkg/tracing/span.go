@@ -0,0 +1,137 @@
+package tracing
+
+import (
+	"sync"
+	"time"
+)
+
+// Attribute represents a key-value attribute of the span,
+// the value is string, int64, float64 or bool.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns string attribute.
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int64 returns integer attribute.
+func Int64(key string, value int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool returns boolean attribute.
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// SpanData represents a finished span.
+type SpanData struct {
+	Name          string
+	Kind          int
+	SpanContext   SpanContext
+	Parent        SpanID
+	Start         time.Time
+	End           time.Time
+	Attributes    []Attribute
+	Status        int
+	StatusMessage string
+}
+
+// Span represents an operation within a trace.
+// Not sampled spans are propagated but not exported.
+// All methods of nil Span are no-op.
+type Span struct {
+	tracer *Tracer
+
+	mux   sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SpanContext returns the span context of the span.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+
+	return s.data.SpanContext
+}
+
+// IsRecording reports whether the span is sampled and not ended.
+func (s *Span) IsRecording() bool {
+	if s == nil {
+		return false
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.data.SpanContext.Sampled && !s.ended
+}
+
+// SetName changes the name of the span.
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.data.Name = name
+}
+
+// SetAttributes adds the attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+}
+
+// SetStatus sets the status of the span.
+func (s *Span) SetStatus(code int, message string) {
+	if s == nil {
+		return
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.data.Status = code
+	s.data.StatusMessage = message
+}
+
+// End ends the span and queues it for export.
+func (s *Span) End() {
+	s.EndAt(time.Now())
+}
+
+// EndAt ends the span with the given end time, see End.
+func (s *Span) EndAt(end time.Time) {
+	if s == nil {
+		return
+	}
+
+	s.mux.Lock()
+	if s.ended {
+		s.mux.Unlock()
+
+		return
+	}
+	s.ended = true
+	s.data.End = end
+	data := s.data
+	s.mux.Unlock()
+
+	if data.SpanContext.Sampled {
+		s.tracer.export(data)
+	}
+}
@@ -0,0 +1,229 @@
+// Package tracing provides spans of the public API requests and cache commands
+// with W3C Trace Context propagation and export in OTLP/HTTP format.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultQueueSize     = 2048
+	defaultBatchSize     = 512
+	defaultFlushInterval = 5 * time.Second
+)
+
+// Kinds of the spans, the values match OTLP.
+const (
+	KindInternal = 1
+	KindServer   = 2
+	KindClient   = 3
+)
+
+// Status codes of the spans, the values match OTLP.
+const (
+	StatusUnset = 0
+	StatusOK    = 1
+	StatusError = 2
+)
+
+// Exporter sends finished spans to the collector.
+type Exporter interface {
+	ExportSpans(ctx context.Context, spans []SpanData) error
+}
+
+// Opts represents the options to create new instance of Tracer.
+type Opts struct {
+	Exporter Exporter
+
+	// QueueSize is the max number of the spans waiting for export,
+	// the spans are dropped when the queue is full.
+	QueueSize int
+
+	// BatchSize is the max number of the spans exported at once.
+	BatchSize int
+
+	// FlushInterval is how often the queued spans are exported.
+	FlushInterval time.Duration
+
+	Log *zap.Logger
+}
+
+// Tracer creates spans and exports them in batches.
+// Nil Tracer creates no spans.
+type Tracer struct {
+	exporter      Exporter
+	batchSize     int
+	flushInterval time.Duration
+	log           *zap.Logger
+
+	queue   chan SpanData
+	dropped uint64
+
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// New returns new instance of Tracer.
+func New(opts Opts) *Tracer {
+	t := &Tracer{
+		exporter:      opts.Exporter,
+		batchSize:     opts.BatchSize,
+		flushInterval: opts.FlushInterval,
+		log:           opts.Log,
+		stop:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	queueSize := opts.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	t.queue = make(chan SpanData, queueSize)
+	if t.batchSize <= 0 {
+		t.batchSize = defaultBatchSize
+	}
+	if t.flushInterval <= 0 {
+		t.flushInterval = defaultFlushInterval
+	}
+	if t.log == nil {
+		t.log = zap.NewNop()
+	}
+
+	go t.exportLoop()
+
+	return t
+}
+
+// Close exports queued spans and stops the tracer.
+func (t *Tracer) Close() {
+	if t == nil {
+		return
+	}
+	t.stopOnce.Do(func() {
+		close(t.stop)
+		<-t.stopped
+	})
+}
+
+// Dropped returns the number of spans dropped because the queue was full.
+func (t *Tracer) Dropped() uint64 {
+	if t == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&t.dropped)
+}
+
+// Start starts new span that is a child of the span or the remote span context
+// from the context. It returns a copy of the context with the new span.
+func (t *Tracer) Start(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	return t.StartAt(ctx, name, kind, time.Now())
+}
+
+// StartAt starts new span with the given start time, see Start.
+func (t *Tracer) StartAt(ctx context.Context, name string, kind int, start time.Time) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	parent := SpanContextFromContext(ctx)
+	sc := SpanContext{TraceID: parent.TraceID, SpanID: newSpanID(), Sampled: true}
+	if parent.IsValid() {
+		// Sampling decision of the parent is respected
+		sc.Sampled = parent.Sampled
+	} else {
+		sc.TraceID = newTraceID()
+	}
+
+	span := &Span{
+		tracer: t,
+		data: SpanData{
+			Name:        name,
+			Kind:        kind,
+			SpanContext: sc,
+			Parent:      parent.SpanID,
+			Start:       start,
+		},
+	}
+
+	return ContextWithSpan(ctx, span), span
+}
+
+// export method queues the finished span for export.
+func (t *Tracer) export(data SpanData) {
+	select {
+	case t.queue <- data:
+	default:
+		atomic.AddUint64(&t.dropped, 1)
+	}
+}
+
+func (t *Tracer) exportLoop() {
+	defer close(t.stopped)
+
+	ticker := time.NewTicker(t.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, t.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if t.exporter != nil {
+			if err := t.exporter.ExportSpans(context.Background(), batch); err != nil {
+				t.log.Warn("failed to export spans", zap.Int("spans", len(batch)), zap.Error(err))
+			}
+		}
+		batch = make([]SpanData, 0, t.batchSize)
+	}
+
+	for {
+		select {
+		case data := <-t.queue:
+			batch = append(batch, data)
+			if len(batch) >= t.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-t.stop:
+			for {
+				select {
+				case data := <-t.queue:
+					batch = append(batch, data)
+					if len(batch) >= t.batchSize {
+						flush()
+					}
+				default:
+					flush()
+
+					return
+				}
+			}
+		}
+	}
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+
+	return id
+}
@@ -0,0 +1,193 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+// testCollector is a stand-in OTLP/HTTP collector.
+type testCollector struct {
+	*httptest.Server
+
+	mux      sync.Mutex
+	spans    []OTLPSpan
+	resource Resource
+	headers  http.Header
+}
+
+func newTestCollector(t *testing.T) *testCollector {
+	c := &testCollector{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, TracesPath, req.URL.Path)
+		require.Equal(t, "application/json", req.Header.Get("Content-Type"))
+
+		var body ExportTraceServiceRequest
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+
+		c.mux.Lock()
+		defer c.mux.Unlock()
+		c.headers = req.Header
+		for _, rs := range body.ResourceSpans {
+			c.resource = rs.Resource
+			for _, ss := range rs.ScopeSpans {
+				c.spans = append(c.spans, ss.Spans...)
+			}
+		}
+	}))
+	t.Cleanup(c.Close)
+
+	return c
+}
+
+func (c *testCollector) receivedSpans() []OTLPSpan {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.spans
+}
+
+func attributes(kvs []KeyValue) map[string]interface{} {
+	attrs := make(map[string]interface{}, len(kvs))
+	for _, kv := range kvs {
+		switch {
+		case kv.Value.StringValue != nil:
+			attrs[kv.Key] = *kv.Value.StringValue
+		case kv.Value.IntValue != nil:
+			attrs[kv.Key] = *kv.Value.IntValue
+		case kv.Value.BoolValue != nil:
+			attrs[kv.Key] = *kv.Value.BoolValue
+		case kv.Value.DoubleValue != nil:
+			attrs[kv.Key] = *kv.Value.DoubleValue
+		}
+	}
+
+	return attrs
+}
+
+func newTestRouter(tracer *Tracer, cache *qqcache.Cache) http.Handler {
+	r := chi.NewRouter()
+	r.Use(Middleware(tracer))
+	r.Get("/v2/keys/{key}", func(w http.ResponseWriter, req *http.Request) {
+		c := cache.WithObserver(CacheObserver(req.Context()))
+		if _, ok := c.Get(chi.URLParam(req, "key")); !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	return r
+}
+
+func TestTracer_Export(t *testing.T) {
+	collector := newTestCollector(t)
+	tracer := New(Opts{
+		Exporter: NewOTLPExporter(OTLPOpts{
+			Endpoint:    collector.URL,
+			Headers:     map[string]string{"Authorization": "Bearer token"},
+			ServiceName: "test-service",
+		}),
+		FlushInterval: time.Hour,
+	})
+
+	cache := qqcache.New(qqcache.Opts{EvictionInterval: time.Minute})
+	defer cache.Shutdown()
+	cache.Set("key", "value", 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/keys/key", nil)
+	req.Header.Set(TraceparentHeader, testTraceparent)
+	newTestRouter(tracer, cache).ServeHTTP(httptest.NewRecorder(), req)
+
+	// Spans are exported on close
+	tracer.Close()
+	spans := collector.receivedSpans()
+	require.Len(t, spans, 2)
+	require.Equal(t, "Bearer token", collector.headers.Get("Authorization"))
+	require.Equal(t, map[string]interface{}{"service.name": "test-service"}, attributes(collector.resource.Attributes))
+
+	// Cache span ends first
+	cacheSpan, serverSpan := spans[0], spans[1]
+
+	require.Equal(t, "GET /v2/keys/{key}", serverSpan.Name)
+	require.Equal(t, KindServer, serverSpan.Kind)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", serverSpan.TraceID)
+	require.Equal(t, "00f067aa0ba902b7", serverSpan.ParentSpanID)
+	require.Equal(t, map[string]interface{}{
+		"http.method":      "GET",
+		"http.route":       "/v2/keys/{key}",
+		"http.status_code": "200",
+	}, attributes(serverSpan.Attributes))
+
+	require.Equal(t, "qqcache.get", cacheSpan.Name)
+	require.Equal(t, KindInternal, cacheSpan.Kind)
+	require.Equal(t, serverSpan.TraceID, cacheSpan.TraceID)
+	require.Equal(t, serverSpan.SpanID, cacheSpan.ParentSpanID)
+	require.Equal(t, map[string]interface{}{
+		"cache.command":    "get",
+		"cache.args":       "0",
+		"cache.key_hash":   KeyHash("key"),
+		"cache.hit":        true,
+		"cache.value_size": "21",
+	}, attributes(cacheSpan.Attributes))
+	require.NotEqual(t, cacheSpan.StartTimeUnixNano, cacheSpan.EndTimeUnixNano)
+}
+
+func TestTracer_NotSampled(t *testing.T) {
+	collector := newTestCollector(t)
+	tracer := New(Opts{Exporter: NewOTLPExporter(OTLPOpts{Endpoint: collector.URL})})
+
+	cache := qqcache.New(qqcache.Opts{EvictionInterval: time.Minute})
+	defer cache.Shutdown()
+
+	// Sampling decision of the caller is respected
+	req := httptest.NewRequest(http.MethodGet, "/v2/keys/key", nil)
+	req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	newTestRouter(tracer, cache).ServeHTTP(httptest.NewRecorder(), req)
+
+	tracer.Close()
+	require.Empty(t, collector.receivedSpans())
+}
+
+func TestTracer_Errors(t *testing.T) {
+	var tracer *Tracer
+	ctx, span := tracer.Start(context.Background(), "a", KindInternal)
+	require.Nil(t, span)
+	require.Nil(t, CacheObserver(ctx))
+	span.SetAttributes(String("a", "b"))
+	span.End()
+	tracer.Close()
+
+	// Spans are dropped when the queue is full
+	tracer = New(Opts{QueueSize: 1, FlushInterval: time.Hour, BatchSize: 10})
+	defer tracer.Close()
+	tracer.stopOnce.Do(func() {
+		close(tracer.stop)
+		<-tracer.stopped
+	})
+	for i := 0; i < 3; i++ {
+		_, span := tracer.Start(context.Background(), "a", KindInternal)
+		span.End()
+	}
+	require.Equal(t, uint64(2), tracer.Dropped())
+}
+
+func TestOTLPExporter_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	err := NewOTLPExporter(OTLPOpts{Endpoint: server.URL + TracesPath}).
+		ExportSpans(context.Background(), []SpanData{{Name: "a"}})
+	require.EqualError(t, err, "collector responded with 503 status code")
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/config"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/auth"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/ratelimit"
+	"github.com/dstdfx/bookish-spork/internal/pkg/log"
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+	"github.com/dstdfx/bookish-spork/internal/pkg/tlsconfig"
+	"go.uber.org/zap"
+)
+
+// ReloadPath is the path of the config reload endpoint on the service API.
+const ReloadPath = "/reload"
+
+const rateLimitPathPrefix = "public_api.rate_limit."
+
+// ReloadResult represents the outcome of the config reload.
+type ReloadResult struct {
+	// Applied contains YAML paths of the changed parameters applied at runtime.
+	Applied []string `json:"applied"`
+
+	// RestartRequired contains YAML paths of the changed parameters
+	// that are applied on restart only.
+	RestartRequired []string `json:"restart_required"`
+
+	// Warnings contains the errors of reloading credentials and certificates,
+	// the previous ones are kept in this case.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// reloaderOpts represents the options to create new instance of reloader.
+type reloaderOpts struct {
+	Config *config.AppConfig
+
+	// Load returns the config to apply.
+	Load func() (*config.AppConfig, error)
+
+	// LogLevel is the level of the application logger, it's not reloaded if it's not set.
+	LogLevel zap.AtomicLevel
+
+	Cache          *qqcache.Cache
+	Limiter        *ratelimit.Limiter
+	Authenticators []*auth.Authenticator
+	TLS            []*tlsconfig.Reloader
+	Log            *zap.Logger
+}
+
+// reloader re-reads the config and applies the parameters that could be
+// changed at runtime: log level, eviction interval and rate limits.
+// Credentials and certificates files are re-read as well.
+type reloader struct {
+	opts reloaderOpts
+
+	mux sync.Mutex
+	// cfg is the config the service is running with
+	cfg *config.AppConfig
+}
+
+func newReloader(opts reloaderOpts) *reloader {
+	return &reloader{opts: opts, cfg: opts.Config}
+}
+
+// Config method returns the config the service is running with.
+func (r *reloader) Config() interface{} {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	return r.cfg
+}
+
+// Reload method reads and validates the config and applies the changed parameters.
+// Nothing is applied if the config is invalid.
+func (r *reloader) Reload() (ReloadResult, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	cfg, err := r.opts.Load()
+	if err != nil {
+		r.opts.Log.Warn("config reload failed", zap.Error(err))
+
+		return ReloadResult{}, err
+	}
+
+	result := ReloadResult{Applied: []string{}, RestartRequired: []string{}}
+	running := *r.cfg
+
+	var (
+		applyPaths     []string
+		rateLimitPaths []string
+	)
+	for _, path := range config.Diff(r.cfg, cfg) {
+		switch {
+		case path == "log.debug" && r.opts.LogLevel != (zap.AtomicLevel{}),
+			path == "cache.eviction_interval":
+			applyPaths = append(applyPaths, path)
+		case strings.HasPrefix(path, rateLimitPathPrefix):
+			rateLimitPaths = append(rateLimitPaths, path)
+		default:
+			result.RestartRequired = append(result.RestartRequired, path)
+		}
+	}
+
+	// Rate limits are validated before anything is applied,
+	// rate limiting could not be enabled or disabled at runtime
+	applyRateLimit := false
+	if len(rateLimitPaths) > 0 {
+		err := ratelimit.ErrNoLimits
+		if r.opts.Limiter != nil {
+			err = ratelimit.Validate(rateLimitOpts(cfg.PublicAPI.RateLimit, r.opts.Log))
+		}
+		switch {
+		case err == nil:
+			applyRateLimit = true
+		case errors.Is(err, ratelimit.ErrNoLimits):
+			result.RestartRequired = append(result.RestartRequired, rateLimitPaths...)
+		default:
+			r.opts.Log.Warn("config reload failed", zap.Error(err))
+
+			return ReloadResult{}, err
+		}
+	}
+
+	if applyRateLimit {
+		// Limiter is updated first, it's the only step that could fail
+		if err := r.opts.Limiter.Update(rateLimitOpts(cfg.PublicAPI.RateLimit, r.opts.Log)); err != nil {
+			return ReloadResult{}, err
+		}
+		running.PublicAPI.RateLimit = cfg.PublicAPI.RateLimit
+	}
+
+	for _, path := range applyPaths {
+		switch path {
+		case "log.debug":
+			r.opts.LogLevel.SetLevel(log.Level(cfg.Log.Debug))
+			running.Log.Debug = cfg.Log.Debug
+		case "cache.eviction_interval":
+			r.opts.Cache.SetEvictionInterval(time.Duration(cfg.Cache.EvictionInterval) * time.Second)
+			running.Cache.EvictionInterval = cfg.Cache.EvictionInterval
+		}
+	}
+	result.Applied = append(result.Applied, applyPaths...)
+	if applyRateLimit {
+		result.Applied = append(result.Applied, rateLimitPaths...)
+	}
+
+	for _, a := range r.opts.Authenticators {
+		if err := a.Reload(); err != nil {
+			result.Warnings = append(result.Warnings, err.Error())
+		}
+	}
+	for _, t := range r.opts.TLS {
+		if err := t.Reload(); err != nil {
+			result.Warnings = append(result.Warnings, err.Error())
+		}
+	}
+
+	r.cfg = &running
+
+	r.opts.Log.Info("config reloaded",
+		zap.Strings("applied", result.Applied),
+		zap.Strings("restart_required", result.RestartRequired),
+		zap.Strings("warnings", result.Warnings))
+
+	return result, nil
+}
+
+// ServeHTTP reloads the config on POST and responds with ReloadResult.
+func (r *reloader) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	result, err := r.Reload()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(result)
+}
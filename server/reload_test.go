@@ -0,0 +1,171 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/internal/pkg/config"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/ratelimit"
+	"github.com/dstdfx/bookish-spork/internal/pkg/qqcache"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testReloadConfig = `
+log:
+  debug: false
+public_api:
+  rate_limit:
+    default:
+      rate: 10
+cache:
+  eviction_interval: 60
+`
+
+func newTestReloader(t *testing.T) (*reloader, string) {
+	dir, err := ioutil.TempDir("", "reload")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "bookish-spork.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(testReloadConfig), 0600))
+
+	src := config.Sources{File: path}
+	cfg, err := config.Load(src)
+	require.NoError(t, err)
+
+	cache := qqcache.New(qqcache.Opts{EvictionInterval: time.Minute})
+	t.Cleanup(cache.Shutdown)
+
+	limiter, err := ratelimit.New(rateLimitOpts(cfg.PublicAPI.RateLimit, nil))
+	require.NoError(t, err)
+	t.Cleanup(limiter.Close)
+
+	return newReloader(reloaderOpts{
+		Config:   cfg,
+		Load:     func() (*config.AppConfig, error) { return config.Load(src) },
+		LogLevel: zap.NewAtomicLevel(),
+		Cache:    cache,
+		Limiter:  limiter,
+		Log:      zap.NewNop(),
+	}), path
+}
+
+func TestReloader_Reload(t *testing.T) {
+	r, path := newTestReloader(t)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`
+log:
+  debug: true
+public_api:
+  server_port: 8080
+  rate_limit:
+    default:
+      rate: 20
+    classes:
+      write:
+        rate: 1
+cache:
+  eviction_interval: 30
+`), 0600))
+
+	result, err := r.Reload()
+	require.NoError(t, err)
+	require.Equal(t, ReloadResult{
+		Applied: []string{
+			"log.debug",
+			"cache.eviction_interval",
+			"public_api.rate_limit.default.rate",
+			"public_api.rate_limit.classes",
+		},
+		RestartRequired: []string{"public_api.server_port"},
+	}, result)
+
+	require.Equal(t, zap.DebugLevel, r.opts.LogLevel.Level())
+	require.Equal(t, 30*time.Second, r.opts.Cache.Stats().EvictionInterval)
+
+	// The running config contains applied parameters only
+	cfg := r.Config().(*config.AppConfig)
+	require.True(t, cfg.Log.Debug)
+	require.Equal(t, 30, cfg.Cache.EvictionInterval)
+	require.Equal(t, 20.0, cfg.PublicAPI.RateLimit.Default.Rate)
+	require.Equal(t, 63100, cfg.PublicAPI.ServerPort)
+
+	// Nothing is applied again
+	result, err = r.Reload()
+	require.NoError(t, err)
+	require.Empty(t, result.Applied)
+	require.Equal(t, []string{"public_api.server_port"}, result.RestartRequired)
+
+	// Rate limiting could not be disabled
+	require.NoError(t, ioutil.WriteFile(path, []byte("cache:\n  eviction_interval: 30\n"), 0600))
+	result, err = r.Reload()
+	require.NoError(t, err)
+	require.Equal(t, []string{"log.debug"}, result.Applied)
+	require.Equal(t, []string{
+		"public_api.rate_limit.default.rate",
+		"public_api.rate_limit.classes",
+	}, result.RestartRequired)
+}
+
+func TestReloader_ReloadInvalid(t *testing.T) {
+	r, path := newTestReloader(t)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("log:\n  debug: true\ncache:\n  evicton_interval: 1\n"), 0600))
+	_, err := r.Reload()
+	require.EqualError(t, err, "invalid config: cache.evicton_interval: unknown field")
+
+	// Nothing is applied
+	require.Equal(t, zap.InfoLevel, r.opts.LogLevel.Level())
+	require.False(t, r.Config().(*config.AppConfig).Log.Debug)
+}
+
+func TestReloader_ReloadInvalidRateLimit(t *testing.T) {
+	r, _ := newTestReloader(t)
+
+	// Rate limits are checked by the limiter before the other parameters are applied
+	cfg := *r.Config().(*config.AppConfig)
+	cfg.Log.Debug = true
+	cfg.Cache.EvictionInterval = 30
+	cfg.PublicAPI.RateLimit.KeyBy = "token"
+	r.opts.Load = func() (*config.AppConfig, error) { return &cfg, nil }
+
+	_, err := r.Reload()
+	require.EqualError(t, err, `ratelimit: unknown key "token"`)
+
+	// Nothing is applied
+	require.Equal(t, zap.InfoLevel, r.opts.LogLevel.Level())
+	require.Equal(t, time.Minute, r.opts.Cache.Stats().EvictionInterval)
+	running := r.Config().(*config.AppConfig)
+	require.False(t, running.Log.Debug)
+	require.Equal(t, 60, running.Cache.EvictionInterval)
+	require.Equal(t, ratelimit.KeyByIdentity, running.PublicAPI.RateLimit.KeyBy)
+}
+
+func TestReloader_ServeHTTP(t *testing.T) {
+	r, path := newTestReloader(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, ReloadPath, nil))
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	require.Equal(t, http.MethodPost, w.Header().Get("Allow"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, ReloadPath, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var result ReloadResult
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	require.Equal(t, ReloadResult{Applied: []string{}, RestartRequired: []string{}}, result)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("public_api:\n  server_port: 70000\n"), 0600))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, ReloadPath, nil))
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	require.Equal(t, "invalid config: public_api.server_port: port must be from 1 to 65535, got 70000\n", w.Body.String())
+}
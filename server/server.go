@@ -0,0 +1,716 @@
+// Package server contains bookish-spork server that could be embedded
+// into other Go programs.
+//
+// Server is created from an explicit config and serves the public, service
+// and gRPC APIs until the context passed to Serve is done:
+//
+//	cfg := server.DefaultConfig()
+//	srv, err := server.New(cfg, server.Opts{})
+//	if err != nil {
+//		return err
+//	}
+//	publicAPI, err := net.Listen("tcp", "127.0.0.1:0")
+//	...
+//	go srv.Serve(ctx, server.Listeners{PublicAPI: publicAPI})
+//	<-srv.Ready()
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/httpclient"
+	"github.com/dstdfx/bookish-spork/internal/pkg/backend"
+	"github.com/dstdfx/bookish-spork/internal/pkg/config"
+	"github.com/dstdfx/bookish-spork/internal/pkg/dump"
+	grpcapi "github.com/dstdfx/bookish-spork/internal/pkg/grpc"
+	"github.com/dstdfx/bookish-spork/internal/pkg/health"
+	public "github.com/dstdfx/bookish-spork/internal/pkg/http"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/accesslog"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/auth"
+	"github.com/dstdfx/bookish-spork/internal/pkg/http/ratelimit"
+	"github.com/dstdfx/bookish-spork/internal/pkg/info"
+	"github.com/dstdfx/bookish-spork/internal/pkg/metrics"
+	"github.com/dstdfx/bookish-spork/internal/pkg/replication"
+	"github.com/dstdfx/bookish-spork/internal/pkg/tlsconfig"
+	"github.com/dstdfx/bookish-spork/internal/pkg/tracing"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+const (
+	pprofIndexPath   = "/debug/pprof/"
+	pprofCmdlinePath = "/debug/pprof/cmdline"
+	pprofProfilePath = "/debug/pprof/profile"
+	pprofSymbolPath  = "/debug/pprof/symbol"
+	pprofTracePath   = "/debug/pprof/trace"
+
+	gracefulShutdownTimeout = 5 * time.Second
+)
+
+var (
+	ErrNoConfig       = errors.New("config is required")
+	ErrServed         = errors.New("server has already been served")
+	ErrReloadDisabled = errors.New("config reload is disabled")
+
+	ErrServiceAPIAuthRequired = errors.New("service API authentication is required on non-loopback address")
+)
+
+// Config is the configuration of the server, see bookish-spork.example.yaml
+// for the description of the parameters.
+type Config = config.AppConfig
+
+// BuildInfo contains the build details reported by the version endpoint.
+type BuildInfo = health.BuildInfo
+
+// DefaultConfig returns the config with default values of all parameters.
+func DefaultConfig() *Config {
+	cfg, err := config.Load(config.Sources{})
+	if err != nil {
+		// Defaults are always valid
+		panic(err)
+	}
+
+	return cfg
+}
+
+// Opts represents the options to create new instance of Server.
+type Opts struct {
+	// Log is the logger of the server, logging is disabled if it's not set.
+	Log *zap.Logger
+
+	// LogLevel is the level of the logger changed by Reload,
+	// the level is not reloaded if it's not set.
+	LogLevel zap.AtomicLevel
+
+	// ReloadConfig returns the config applied by Reload,
+	// the reload is disabled if it's not set.
+	ReloadConfig func() (*Config, error)
+
+	// Build is reported by the version endpoint of the service API.
+	Build BuildInfo
+}
+
+// Listeners contains the listeners of the APIs, the addresses from the config
+// are listened for the omitted ones.
+type Listeners struct {
+	PublicAPI  net.Listener
+	ServiceAPI net.Listener
+	GRPCAPI    net.Listener
+}
+
+// Server serves bookish-spork APIs.
+type Server struct {
+	cfg *Config
+	log *zap.Logger
+
+	backend          *backend.Backend
+	health           *health.Health
+	publicAPIServer  *http.Server
+	serviceAPIServer *http.Server
+	serviceAPIAuth   *auth.Authenticator
+	grpcAPIServer    *grpc.Server
+	reloader         *reloader
+
+	// Either leader or follower of the replication is set
+	leader   *replication.Leader
+	follower *replication.Follower
+
+	// closers release the resources of the server in reverse order
+	closers   []func()
+	closeOnce sync.Once
+
+	served chan struct{}
+	ready  chan struct{}
+}
+
+// New returns new instance of Server, the config is validated.
+func New(cfg *Config, opts Opts) (*Server, error) {
+	if cfg == nil {
+		return nil, ErrNoConfig
+	}
+	if err := config.Validate(cfg); err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		cfg:    cfg,
+		log:    opts.Log,
+		served: make(chan struct{}, 1),
+		ready:  make(chan struct{}),
+	}
+	if s.log == nil {
+		s.log = zap.NewNop()
+	}
+
+	if err := s.init(opts); err != nil {
+		s.Close()
+
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// init creates the components of the server.
+func (s *Server) init(opts Opts) error {
+	cfg, log := s.cfg, s.log
+
+	// Init caching backend
+	s.backend = backend.New(cfg.Cache, log)
+	s.closers = append(s.closers, s.backend.Shutdown)
+
+	// Register service API handler
+	httpMux := http.NewServeMux()
+
+	// Register pprof handlers
+	httpMux.HandleFunc(pprofIndexPath, pprof.Index)
+	httpMux.HandleFunc(pprofCmdlinePath, pprof.Cmdline)
+	httpMux.HandleFunc(pprofProfilePath, pprof.Profile)
+	httpMux.HandleFunc(pprofSymbolPath, pprof.Symbol)
+	httpMux.HandleFunc(pprofTracePath, pprof.Trace)
+
+	// Register health handlers
+	s.health = health.New(opts.Build)
+	s.health.Register(httpMux)
+
+	// Register metrics handler
+	registry := metrics.NewRegistry()
+	httpMetrics := metrics.NewHTTPMetrics()
+	registry.Register(httpMetrics, metrics.CacheCollector(s.backend.Cache), metrics.RuntimeCollector())
+	httpMux.Handle(metrics.Path, registry)
+
+	// Configure Service API server
+	s.serviceAPIServer = &http.Server{
+		Addr:         address(cfg.ServiceAPI.ServerAddress, cfg.ServiceAPI.ServerPort),
+		ReadTimeout:  time.Duration(cfg.ServiceAPI.ReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(cfg.ServiceAPI.WriteTimeout) * time.Second,
+		IdleTimeout:  time.Duration(cfg.ServiceAPI.IdleTimeout) * time.Second,
+		Handler:      httpMux,
+	}
+	serviceAPITLS, err := newTLSReloader(cfg.ServiceAPI.TLS, log)
+	if err != nil {
+		return fmt.Errorf("failed to init service API TLS: %w", err)
+	}
+	if serviceAPITLS != nil {
+		s.closers = append(s.closers, serviceAPITLS.Close)
+		s.serviceAPIServer.TLSConfig = serviceAPITLS.TLSConfig()
+	}
+
+	// Init service API authentication of the endpoints that read or change all data
+	s.serviceAPIAuth, err = auth.New(auth.Opts{
+		TokensFile:     cfg.ServiceAPI.Auth.TokensFile,
+		MTLS:           cfg.ServiceAPI.TLS.ClientCAFile != "",
+		ReloadInterval: time.Duration(cfg.ServiceAPI.Auth.ReloadInterval) * time.Second,
+		Log:            log,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to init service API authentication: %w", err)
+	}
+	s.closers = append(s.closers, s.serviceAPIAuth.Close)
+	protectedMux := http.NewServeMux()
+	var protected http.Handler = protectedMux
+	if s.serviceAPIAuth.Enabled() {
+		protected = s.serviceAPIAuth.Middleware(writeServiceAPIError)(protectedMux)
+	}
+	for _, path := range []string{dump.Path, dump.RestorePath, replication.SyncPath, ReloadPath} {
+		httpMux.Handle(path, protected)
+	}
+
+	// Init replication
+	if err := s.initReplication(protectedMux); err != nil {
+		return err
+	}
+	registry.Register(metrics.ReplicationCollector(s.replicationStatus))
+
+	// Init public API authentication
+	authenticator, err := auth.New(auth.Opts{
+		TokensFile:       cfg.PublicAPI.Auth.TokensFile,
+		HMACKeysFile:     cfg.PublicAPI.Auth.HMACKeysFile,
+		HMACMaxClockSkew: time.Duration(cfg.PublicAPI.Auth.HMACMaxClockSkew) * time.Second,
+		MTLS:             cfg.PublicAPI.Auth.MTLS,
+		ReloadInterval:   time.Duration(cfg.PublicAPI.Auth.ReloadInterval) * time.Second,
+		Log:              log,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to init public API authentication: %w", err)
+	}
+	s.closers = append(s.closers, authenticator.Close)
+	if !authenticator.Enabled() {
+		log.Warn("public API authentication is disabled")
+	}
+
+	// Init public API access control
+	acl, err := auth.NewACL(aclOpts(cfg.PublicAPI.ACL))
+	if err != nil {
+		return fmt.Errorf("failed to init public API access control: %w", err)
+	}
+
+	// Init public API rate limiting
+	limiter, err := ratelimit.New(rateLimitOpts(cfg.PublicAPI.RateLimit, log))
+	if err != nil {
+		return fmt.Errorf("failed to init public API rate limiting: %w", err)
+	}
+	s.closers = append(s.closers, limiter.Close)
+	registry.Register(metrics.ThrottledCollector(limiter.Throttled))
+
+	// Init public API access log
+	accessLog, err := accesslog.New(accesslog.Opts{
+		Level:      cfg.Log.Access.Level,
+		SampleRate: cfg.Log.Access.SampleRate,
+		Routes:     cfg.Log.Access.Routes,
+		Log:        log.Named("access"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to init public API access log: %w", err)
+	}
+
+	// Init public API tracing
+	tracer := newTracer(cfg.Tracing, log)
+	s.closers = append(s.closers, tracer.Close)
+
+	// Configure Public API server
+	s.publicAPIServer = &http.Server{
+		Addr:         address(cfg.PublicAPI.ServerAddress, cfg.PublicAPI.ServerPort),
+		ReadTimeout:  time.Duration(cfg.PublicAPI.ReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(cfg.PublicAPI.WriteTimeout) * time.Second,
+		IdleTimeout:  time.Duration(cfg.PublicAPI.IdleTimeout) * time.Second,
+		Handler: public.InitAPIRouter(s.backend,
+			public.WithAuth(authenticator),
+			public.WithACL(acl),
+			public.WithRateLimit(limiter),
+			public.WithMetrics(httpMetrics),
+			public.WithAccessLog(accessLog),
+			public.WithTracing(tracer),
+			public.WithMaxBodySize(int64(cfg.PublicAPI.MaxBodySize)),
+		),
+	}
+	publicAPITLS, err := newTLSReloader(cfg.PublicAPI.TLS, log)
+	if err != nil {
+		return fmt.Errorf("failed to init public API TLS: %w", err)
+	}
+	if publicAPITLS != nil {
+		s.closers = append(s.closers, publicAPITLS.Close)
+		s.publicAPIServer.TLSConfig = publicAPITLS.TLSConfig()
+	}
+
+	// Register config reload handler
+	var tlsReloaders []*tlsconfig.Reloader
+	for _, r := range []*tlsconfig.Reloader{serviceAPITLS, publicAPITLS} {
+		if r != nil {
+			tlsReloaders = append(tlsReloaders, r)
+		}
+	}
+	s.reloader = newReloader(reloaderOpts{
+		Config:         cfg,
+		Load:           opts.ReloadConfig,
+		LogLevel:       opts.LogLevel,
+		Cache:          s.backend.Cache,
+		Limiter:        limiter,
+		Authenticators: []*auth.Authenticator{authenticator, s.serviceAPIAuth},
+		TLS:            tlsReloaders,
+		Log:            log,
+	})
+	if opts.ReloadConfig != nil {
+		protectedMux.Handle(ReloadPath, s.reloader)
+	}
+
+	// Register info handler
+	publicAPIConns := info.NewConnCounter()
+	s.publicAPIServer.ConnState = publicAPIConns.ConnState
+	grpcAPIConns := info.NewConnCounter()
+	info.New(info.Opts{
+		Cache:  s.backend.Cache,
+		Health: s.health,
+		Clients: map[string]*info.ConnCounter{
+			"public_api": publicAPIConns,
+			"grpc_api":   grpcAPIConns,
+		},
+		Replication: s.replicationStatus,
+		Config:      s.reloader.Config,
+	}).Register(httpMux)
+
+	// Register dump and restore handlers
+	dump.New(dump.Opts{Cache: s.backend.Cache, Log: log, ReadOnly: s.backend.ReadOnly}).Register(protectedMux)
+
+	// Configure gRPC API server with the same access control as the public API
+	grpcOpts := grpcapi.WithAccess(grpcapi.AccessOpts{
+		Auth:    authenticator,
+		ACL:     acl,
+		Limiter: limiter,
+		Log:     log.Named("grpc_api"),
+	})
+	grpcOpts = append(grpcOpts, grpc.StatsHandler(grpcAPIConns.GRPCStatsHandler()))
+	s.grpcAPIServer = grpcapi.NewServer(s.backend, grpcOpts...)
+
+	return nil
+}
+
+// initReplication method creates the follower of the leader from the config,
+// the server is the leader if it's not set.
+func (s *Server) initReplication(mux *http.ServeMux) error {
+	cfg := s.cfg.Replication
+	log := s.log.Named("replication")
+
+	if cfg.Leader == "" {
+		s.leader = replication.NewLeader(replication.LeaderOpts{
+			Cache:       s.backend.Cache,
+			BacklogSize: cfg.BacklogSize,
+			// Streams are ended before the service API server cuts them off
+			MaxStreamDuration: s.serviceAPIServer.WriteTimeout * 9 / 10,
+			Log:               log,
+		})
+		s.leader.Register(mux)
+		s.serviceAPIServer.RegisterOnShutdown(s.leader.Close)
+
+		return nil
+	}
+
+	client := &http.Client{}
+	if cfg.CAFile != "" || cfg.CertFile != "" {
+		tlsConfig, err := httpclient.LoadTLSConfig(cfg.CertFile, cfg.KeyFile, cfg.CAFile)
+		if err != nil {
+			return fmt.Errorf("failed to init replication TLS: %w", err)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		client.Transport = transport
+	}
+
+	// Writes are accepted by the leader only
+	s.backend.ReadOnly = true
+	s.follower = replication.NewFollower(replication.FollowerOpts{
+		Cache:             s.backend.Cache,
+		Leader:            cfg.Leader,
+		Client:            client,
+		Timeout:           time.Duration(cfg.Timeout) * time.Second,
+		ReconnectInterval: time.Duration(cfg.ReconnectInterval) * time.Second,
+		Log:               log,
+	})
+
+	return nil
+}
+
+// replicationStatus method returns the status of the leader or the follower.
+func (s *Server) replicationStatus() replication.Status {
+	if s.follower != nil {
+		return s.follower.Status()
+	}
+
+	return s.leader.Status()
+}
+
+// Ready method returns the channel that is closed when the server is ready to serve requests.
+func (s *Server) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Reload method reads the config with Opts.ReloadConfig and applies the changed
+// parameters that could be changed at runtime.
+func (s *Server) Reload() (ReloadResult, error) {
+	if s.reloader.opts.Load == nil {
+		return ReloadResult{}, ErrReloadDisabled
+	}
+
+	return s.reloader.Reload()
+}
+
+// Serve method serves the APIs until the context is done or any of the servers fails,
+// the listeners are closed on return. Serve could be called once.
+func (s *Server) Serve(ctx context.Context, l Listeners) error {
+	select {
+	case s.served <- struct{}{}:
+	default:
+		return ErrServed
+	}
+	defer s.Close()
+
+	// Listen all addresses before reporting readiness
+	if err := s.listen(&l); err != nil {
+		return err
+	}
+	if !s.serviceAPIAuth.Enabled() && !isLoopback(l.ServiceAPI.Addr()) {
+		for _, listener := range []net.Listener{l.PublicAPI, l.ServiceAPI, l.GRPCAPI} {
+			listener.Close()
+		}
+
+		return ErrServiceAPIAuthRequired
+	}
+
+	errs := make(chan error, 3)
+
+	// Serve service API
+	go func() {
+		s.log.Info("running service API server",
+			zap.Stringer("addr", l.ServiceAPI.Addr()),
+			zap.Bool("tls", s.serviceAPIServer.TLSConfig != nil))
+		if err := serve(s.serviceAPIServer, l.ServiceAPI); err != nil && err != http.ErrServerClosed {
+			errs <- fmt.Errorf("failed to serve service API: %w", err)
+		}
+	}()
+
+	// Serve public API
+	go func() {
+		s.log.Info("running public API server",
+			zap.Stringer("addr", l.PublicAPI.Addr()),
+			zap.Bool("tls", s.publicAPIServer.TLSConfig != nil))
+		if err := serve(s.publicAPIServer, l.PublicAPI); err != nil && err != http.ErrServerClosed {
+			errs <- fmt.Errorf("failed to serve public API: %w", err)
+		}
+	}()
+
+	// Serve gRPC API
+	go func() {
+		s.log.Info("running gRPC API server", zap.Stringer("addr", l.GRPCAPI.Addr()))
+		if err := s.grpcAPIServer.Serve(l.GRPCAPI); err != nil && err != grpc.ErrServerStopped {
+			errs <- fmt.Errorf("failed to serve gRPC API: %w", err)
+		}
+	}()
+
+	// Run replication follower until the servers are shut down
+	if s.follower != nil {
+		followerCtx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+
+			s.log.Info("running replication follower", zap.String("leader", s.cfg.Replication.Leader))
+			s.follower.Run(followerCtx)
+		}()
+		defer func() {
+			cancel()
+			<-done
+		}()
+	}
+
+	// Follower is ready after the first full sync, its cache is empty or stale before that
+	var ready <-chan struct{}
+	if s.follower != nil {
+		ready = s.follower.Synced()
+		s.log.Info("waiting for the first sync with leader")
+	} else {
+		started := make(chan struct{})
+		close(started)
+		ready = started
+	}
+
+	var err error
+	for running := true; running; {
+		select {
+		case <-ready:
+			ready = nil
+			s.health.SetReady()
+			close(s.ready)
+			s.log.Info("service is ready")
+		case <-ctx.Done():
+			running = false
+		case err = <-errs:
+			s.log.Error("server failed", zap.Error(err))
+			running = false
+		}
+	}
+
+	s.shutdown()
+
+	return err
+}
+
+// listen method listens the addresses of the omitted listeners.
+func (s *Server) listen(l *Listeners) error {
+	listeners := []struct {
+		l    *net.Listener
+		addr string
+		name string
+	}{
+		{&l.GRPCAPI, address(s.cfg.GRPCAPI.ServerAddress, s.cfg.GRPCAPI.ServerPort), "gRPC API"},
+		{&l.ServiceAPI, s.serviceAPIServer.Addr, "service API"},
+		{&l.PublicAPI, s.publicAPIServer.Addr, "public API"},
+	}
+
+	for i, item := range listeners {
+		if *item.l != nil {
+			continue
+		}
+
+		var err error
+		if *item.l, err = net.Listen("tcp", item.addr); err != nil {
+			for _, opened := range listeners[:i] {
+				(*opened.l).Close()
+			}
+
+			return fmt.Errorf("failed to listen %s address: %w", item.name, err)
+		}
+	}
+
+	return nil
+}
+
+// shutdown method gracefully stops the servers.
+func (s *Server) shutdown() {
+	// Fail readiness checks first to let load balancers drain the connections
+	s.health.SetShuttingDown()
+	if delay := time.Duration(s.cfg.ServiceAPI.ShutdownDelay) * time.Second; delay > 0 {
+		s.log.Info("waiting for load balancers to drain connections", zap.Duration("delay", delay))
+		time.Sleep(delay)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	// Shutdown gRPC API server, streams are interrupted after the timeout
+	go func() {
+		defer wg.Done()
+
+		time.AfterFunc(gracefulShutdownTimeout, s.grpcAPIServer.Stop)
+		s.grpcAPIServer.GracefulStop()
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		// Context to shutdown public API-server
+		ctx, cancel := context.WithTimeout(context.Background(), gracefulShutdownTimeout)
+		defer cancel()
+
+		// Shutdown public API-server
+		if err := s.publicAPIServer.Shutdown(ctx); err != nil {
+			s.log.Warn("public API server shutdown failed", zap.Error(err))
+		}
+	}()
+
+	wg.Wait()
+
+	// Service API is shut down last to report readiness and metrics
+	// until the public APIs are stopped
+	ctx, cancel := context.WithTimeout(context.Background(), gracefulShutdownTimeout)
+	defer cancel()
+
+	// Shutdown service API-server
+	if err := s.serviceAPIServer.Shutdown(ctx); err != nil {
+		s.log.Warn("service API server shutdown failed", zap.Error(err))
+	}
+}
+
+// Close method releases the resources of the server, it's called by Serve on return.
+// It should be called if the server is not served.
+func (s *Server) Close() {
+	s.closeOnce.Do(func() {
+		for i := len(s.closers) - 1; i >= 0; i-- {
+			s.closers[i]()
+		}
+	})
+}
+
+func address(host string, port int) string {
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// isLoopback checks if the listener address is accessible from the local host only.
+func isLoopback(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+
+	return !ok || tcpAddr.IP.IsLoopback()
+}
+
+// writeServiceAPIError writes the error of the service API request.
+func writeServiceAPIError(w http.ResponseWriter, status int, message string) {
+	http.Error(w, message, status)
+}
+
+// newTLSReloader returns TLS configuration reloader of the server.
+// It returns nil if TLS is not configured.
+func newTLSReloader(cfg config.TLSConfig, log *zap.Logger) (*tlsconfig.Reloader, error) {
+	if cfg.CertFile == "" {
+		return nil, nil
+	}
+
+	return tlsconfig.New(tlsconfig.Opts{
+		CertFile:       cfg.CertFile,
+		KeyFile:        cfg.KeyFile,
+		ClientCAFile:   cfg.ClientCAFile,
+		ClientAuth:     cfg.ClientAuth,
+		MinVersion:     cfg.MinVersion,
+		CipherSuites:   cfg.CipherSuites,
+		ReloadInterval: time.Duration(cfg.ReloadInterval) * time.Second,
+		Log:            log,
+	})
+}
+
+// newTracer returns tracer that exports spans to the collector.
+// It returns nil if tracing is not configured.
+func newTracer(cfg config.TracingConfig, log *zap.Logger) *tracing.Tracer {
+	if cfg.Endpoint == "" {
+		return nil
+	}
+
+	return tracing.New(tracing.Opts{
+		Exporter: tracing.NewOTLPExporter(tracing.OTLPOpts{
+			Endpoint:    cfg.Endpoint,
+			Headers:     cfg.Headers,
+			ServiceName: cfg.ServiceName,
+			Timeout:     time.Duration(cfg.Timeout) * time.Second,
+		}),
+		FlushInterval: time.Duration(cfg.FlushInterval) * time.Second,
+		Log:           log,
+	})
+}
+
+// serve serves HTTPS with HTTP/2 support if TLS is configured
+// for the server and plain HTTP otherwise.
+func serve(srv *http.Server, l net.Listener) error {
+	if srv.TLSConfig != nil {
+		return srv.ServeTLS(l, "", "")
+	}
+
+	return srv.Serve(l)
+}
+
+// aclOpts converts ACL configuration to the options of auth.ACL.
+func aclOpts(cfg config.ACLConfig) auth.ACLOpts {
+	opts := auth.ACLOpts{
+		Roles:        make(map[string][]auth.ACLRule, len(cfg.Roles)),
+		Users:        cfg.Users,
+		DefaultRoles: cfg.DefaultRoles,
+	}
+	for name, rules := range cfg.Roles {
+		for _, r := range rules {
+			opts.Roles[name] = append(opts.Roles[name], auth.ACLRule{
+				Keys:        r.Keys,
+				Permissions: r.Permissions,
+				Commands:    r.Commands,
+			})
+		}
+	}
+
+	return opts
+}
+
+// rateLimitOpts converts rate limiting configuration to the options of ratelimit.Limiter.
+func rateLimitOpts(cfg config.RateLimitConfig, log *zap.Logger) ratelimit.Opts {
+	opts := ratelimit.Opts{
+		KeyBy:   cfg.KeyBy,
+		Default: ratelimit.Limit{Rate: cfg.Default.Rate, Burst: cfg.Default.Burst},
+		Classes: make(map[string]ratelimit.Limit, len(cfg.Classes)),
+		Routes:  make(map[string]ratelimit.Limit, len(cfg.Routes)),
+		AuthFailures: ratelimit.Limit{
+			Rate:  cfg.AuthFailures.Rate,
+			Burst: cfg.AuthFailures.Burst,
+		},
+		Log: log,
+	}
+	for class, limit := range cfg.Classes {
+		opts.Classes[class] = ratelimit.Limit{Rate: limit.Rate, Burst: limit.Burst}
+	}
+	for route, limit := range cfg.Routes {
+		opts.Routes[route] = ratelimit.Limit{Rate: limit.Rate, Burst: limit.Burst}
+	}
+
+	return opts
+}
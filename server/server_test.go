@@ -0,0 +1,287 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/bookish-spork/grpcclient"
+	"github.com/dstdfx/bookish-spork/httpclient"
+	"github.com/dstdfx/bookish-spork/internal/pkg/replication"
+	"github.com/dstdfx/bookish-spork/internal/pkg/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// testServer is a server running on ephemeral ports.
+type testServer struct {
+	*Server
+
+	publicURL  string
+	serviceURL string
+	grpcAddr   string
+
+	cancel func()
+	served chan error
+}
+
+func listen(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	return l
+}
+
+func startTestServer(t *testing.T) *testServer {
+	return startTestServerWithConfig(t, DefaultConfig())
+}
+
+func startTestServerWithConfig(t *testing.T, cfg *Config) *testServer {
+	srv, err := New(cfg, Opts{})
+	require.NoError(t, err)
+
+	l := Listeners{PublicAPI: listen(t), ServiceAPI: listen(t), GRPCAPI: listen(t)}
+	ctx, cancel := context.WithCancel(context.Background())
+	ts := &testServer{
+		Server:     srv,
+		publicURL:  "http://" + l.PublicAPI.Addr().String() + "/v1",
+		serviceURL: "http://" + l.ServiceAPI.Addr().String(),
+		grpcAddr:   l.GRPCAPI.Addr().String(),
+		cancel:     cancel,
+		served:     make(chan error, 1),
+	}
+	go func() {
+		ts.served <- srv.Serve(ctx, l)
+	}()
+
+	select {
+	case <-srv.Ready():
+	case err := <-ts.served:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server is not ready")
+	}
+
+	return ts
+}
+
+// stop stops the server and waits for Serve to return.
+func (ts *testServer) stop(t *testing.T) {
+	ts.cancel()
+	require.NoError(t, <-ts.served)
+}
+
+func TestServer_Serve(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	// Instances in one process don't share the data
+	first, second := startTestServer(t), startTestServer(t)
+	defer second.stop(t)
+
+	ctx := context.Background()
+	_, err := httpclient.NewClient(first.publicURL).Set(ctx, httpclient.SetBody{Key: "a", Value: "1"})
+	require.NoError(t, err)
+
+	value, _, err := httpclient.NewClient(first.publicURL).Get(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, "1", value)
+
+	_, _, err = httpclient.NewClient(second.publicURL).Get(ctx, "a")
+	require.Error(t, err)
+
+	grpcClient, err := grpcclient.NewClient(first.grpcAddr)
+	require.NoError(t, err)
+	defer grpcClient.Close()
+	resp, err := grpcClient.Get(ctx, &grpcclient.GetRequest{Key: "a"})
+	require.NoError(t, err)
+	require.Equal(t, "1", resp.GetValue().GetJson().GetStringValue())
+
+	readyz, err := http.Get(first.serviceURL + "/readyz")
+	require.NoError(t, err)
+	readyz.Body.Close()
+	require.Equal(t, http.StatusOK, readyz.StatusCode)
+
+	// Server is stopped by the context
+	first.stop(t)
+	_, err = http.Get(first.publicURL + "/v1/keys")
+	require.Error(t, err)
+
+	require.Equal(t, ErrServed, first.Serve(ctx, Listeners{}))
+}
+
+func TestServer_Replication(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	leader := startTestServer(t)
+	defer leader.stop(t)
+
+	ctx := context.Background()
+	leaderClient := httpclient.NewClient(leader.publicURL)
+	_, err := leaderClient.Set(ctx, httpclient.SetBody{Key: "a", Value: "1"})
+	require.NoError(t, err)
+
+	cfg := DefaultConfig()
+	cfg.Replication.Leader = leader.serviceURL
+	follower := startTestServerWithConfig(t, cfg)
+	defer follower.stop(t)
+	followerClient := httpclient.NewClient(follower.publicURL)
+
+	// Keys written before and after the follower has connected are replicated
+	requireValue := func(key, expected string) {
+		require.Eventually(t, func() bool {
+			value, _, err := followerClient.Get(ctx, key)
+
+			return err == nil && value == expected
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+	// Follower is ready after the full sync
+	value, _, err := followerClient.Get(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, "1", value)
+	_, err = leaderClient.Set(ctx, httpclient.SetBody{Key: "b", Value: "2"})
+	require.NoError(t, err)
+	requireValue("b", "2")
+
+	// Follower rejects writes
+	_, err = followerClient.Set(ctx, httpclient.SetBody{Key: "c", Value: "3"})
+	require.Error(t, err)
+	_, ok := follower.backend.Cache.Get("c")
+	require.False(t, ok)
+
+	status := follower.replicationStatus()
+	require.Equal(t, replication.RoleFollower, status.Role)
+	require.Equal(t, replication.StateConnected, status.State)
+	require.Equal(t, uint64(2), status.Offset)
+	require.Equal(t, uint64(1), status.FullSyncs)
+
+	status = leader.replicationStatus()
+	require.Equal(t, replication.RoleLeader, status.Role)
+	require.Equal(t, uint64(2), status.Offset)
+	require.Len(t, status.Followers, 1)
+}
+
+func TestServer_FollowerReady(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	unavailable := listen(t)
+	require.NoError(t, unavailable.Close())
+
+	cfg := DefaultConfig()
+	cfg.Replication.Leader = "http://" + unavailable.Addr().String()
+	srv, err := New(cfg, Opts{})
+	require.NoError(t, err)
+
+	l := Listeners{PublicAPI: listen(t), ServiceAPI: listen(t), GRPCAPI: listen(t)}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(ctx, l)
+	}()
+
+	// Follower is not ready until the first sync with leader
+	require.Eventually(t, func() bool {
+		resp, err := http.Get("http://" + l.ServiceAPI.Addr().String() + "/readyz")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+
+		return resp.StatusCode == http.StatusServiceUnavailable
+	}, 5*time.Second, 10*time.Millisecond)
+	select {
+	case <-srv.Ready():
+		t.Fatal("follower is ready before the sync")
+	default:
+	}
+
+	cancel()
+	require.NoError(t, <-served)
+}
+
+func TestServer_ServiceAPIAuth(t *testing.T) {
+	// Check acceptance test flag
+	if !testutils.IsAccTestEnabled(t) {
+		return
+	}
+
+	tokensFile := filepath.Join(t.TempDir(), "service_tokens")
+	require.NoError(t, ioutil.WriteFile(tokensFile, []byte("ops:secret\n"), 0600))
+	cfg := DefaultConfig()
+	cfg.ServiceAPI.Auth.TokensFile = tokensFile
+	srv := startTestServerWithConfig(t, cfg)
+	defer srv.stop(t)
+
+	get := func(path, token string) int {
+		req, err := http.NewRequest(http.MethodGet, srv.serviceURL+path, nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	// Endpoints that read or change all data require authentication
+	for _, path := range []string{"/dump", "/restore", "/reload", replication.SyncPath} {
+		require.Equal(t, http.StatusUnauthorized, get(path, ""), path)
+		require.Equal(t, http.StatusUnauthorized, get(path, "invalid"), path)
+	}
+	require.Equal(t, http.StatusOK, get("/dump", "secret"))
+	require.Equal(t, http.StatusMethodNotAllowed, get("/restore", "secret"))
+
+	// Other endpoints are not protected
+	require.Equal(t, http.StatusOK, get("/readyz", ""))
+	require.Equal(t, http.StatusOK, get("/info", ""))
+}
+
+func TestServer_ServeNonLoopback(t *testing.T) {
+	srv, err := New(DefaultConfig(), Opts{})
+	require.NoError(t, err)
+
+	serviceAPI, err := net.Listen("tcp", "0.0.0.0:0")
+	require.NoError(t, err)
+	l := Listeners{PublicAPI: listen(t), ServiceAPI: serviceAPI, GRPCAPI: listen(t)}
+	require.Equal(t, ErrServiceAPIAuthRequired, srv.Serve(context.Background(), l))
+
+	// Listeners are closed
+	_, err = serviceAPI.Accept()
+	require.Error(t, err)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(nil, Opts{})
+	require.Equal(t, ErrNoConfig, err)
+
+	cfg := DefaultConfig()
+	cfg.ServiceAPI.ServerPort = cfg.PublicAPI.ServerPort
+	_, err = New(cfg, Opts{})
+	require.EqualError(t, err, "invalid config: service_api.server_port: port 63100 is already used by public_api")
+
+	srv, err := New(DefaultConfig(), Opts{})
+	require.NoError(t, err)
+	defer srv.Close()
+
+	_, err = srv.Reload()
+	require.Equal(t, ErrReloadDisabled, err)
+
+	cfg = DefaultConfig()
+	cfg.ServiceAPI.ServerAddress = "0.0.0.0"
+	_, err = New(cfg, Opts{})
+	require.EqualError(t, err, `invalid config: service_api.auth: tokens_file or service_api.tls.client_ca_file `+
+		`is required on non-loopback address "0.0.0.0"`)
+}